@@ -0,0 +1,444 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"podmangr-backend/internal/database"
+	"podmangr-backend/internal/models"
+	"podmangr-backend/internal/system"
+)
+
+// backupTransferTimeout limits uploads and downloads of a single backup
+const backupTransferTimeout = 2 * time.Hour
+
+var backupDestinationRepo *database.BackupDestinationRepo
+
+// InitBackupDestinations initializes the backup destination repository
+func InitBackupDestinations() {
+	backupDestinationRepo = database.NewBackupDestinationRepo()
+}
+
+// backupDestinationLookupError writes the response for a failed destination lookup
+func backupDestinationLookupError(c echo.Context, err error) error {
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Backup destination not found",
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": "Failed to get backup destination: " + err.Error(),
+	})
+}
+
+// openBackupRemote looks up the destination named by the :id parameter and
+// prepares access to it. On failure the error response has been written.
+func openBackupRemote(c echo.Context) (*models.BackupDestination, *system.BackupRemote, bool) {
+	dest, err := backupDestinationRepo.GetByID(c.Param("id"))
+	if err != nil {
+		backupDestinationLookupError(c, err)
+		return nil, nil, false
+	}
+	remote, err := system.OpenBackupRemote(dest)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+		return nil, nil, false
+	}
+	return dest, remote, true
+}
+
+// saveDestinationSecret stores the credential of a destination in the secrets
+// store, replacing the one it had
+func saveDestinationSecret(dest *models.BackupDestination, value string, userID int64) error {
+	encrypted, err := secretsEncryption.Encrypt(value)
+	if err != nil {
+		return err
+	}
+
+	secretType := models.SecretTypeAPIKey
+	if dest.Type != models.BackupDestinationS3 {
+		secretType = models.SecretTypeCertificate
+	}
+	category := models.BackupDestinationSecretCategory
+	description := "Credential of backup destination " + dest.Name
+
+	if dest.SecretID != "" {
+		if secret, err := secretsRepo.GetByID(dest.SecretID); err == nil {
+			secret.ValueEncrypted = encrypted
+			secret.SecretType = secretType
+			secret.Description = &description
+			return secretsRepo.Update(secret)
+		}
+	}
+
+	secret := &models.Secret{
+		Name:           "Backup destination " + dest.Name,
+		ValueEncrypted: encrypted,
+		SecretType:     secretType,
+		Category:       &category,
+		Description:    &description,
+		CreatedBy:      &userID,
+	}
+	if err := secretsRepo.Create(secret); err != nil {
+		return err
+	}
+	dest.SecretID = secret.ID
+	dest.HasSecret = true
+	return nil
+}
+
+// applyDestinationRequest copies a destination definition from a request
+func applyDestinationRequest(dest *models.BackupDestination, req *models.BackupDestinationRequest) {
+	dest.Name = req.Name
+	dest.Type = req.Type
+	dest.Endpoint = req.Endpoint
+	dest.Region = req.Region
+	dest.Bucket = req.Bucket
+	dest.AccessKeyID = req.AccessKeyID
+	dest.PathStyle = req.PathStyle
+	dest.Host = req.Host
+	dest.Port = req.Port
+	dest.Username = req.Username
+	dest.Path = req.Path
+	dest.Encrypt = req.Encrypt
+}
+
+// listBackupDestinationsHandler returns all backup destinations
+func listBackupDestinationsHandler(c echo.Context) error {
+	destinations, err := backupDestinationRepo.List()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to list backup destinations: " + err.Error(),
+		})
+	}
+	if destinations == nil {
+		destinations = []models.BackupDestination{}
+	}
+	return c.JSON(http.StatusOK, destinations)
+}
+
+// getBackupDestinationHandler returns a single backup destination
+func getBackupDestinationHandler(c echo.Context) error {
+	dest, err := backupDestinationRepo.GetByID(c.Param("id"))
+	if err != nil {
+		return backupDestinationLookupError(c, err)
+	}
+	return c.JSON(http.StatusOK, dest)
+}
+
+// createBackupDestinationHandler creates a backup destination
+func createBackupDestinationHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+
+	var req models.BackupDestinationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	dest := &models.BackupDestination{CreatedBy: &user.ID}
+	applyDestinationRequest(dest, &req)
+	if err := system.ValidateBackupDestination(dest); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	if req.Secret != "" {
+		if err := saveDestinationSecret(dest, req.Secret, user.ID); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to store credential: " + err.Error(),
+			})
+		}
+	}
+
+	if err := backupDestinationRepo.Create(dest); err != nil {
+		if dest.SecretID != "" {
+			secretsRepo.Delete(dest.SecretID)
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to create backup destination: " + err.Error(),
+		})
+	}
+
+	logAudit(user, "backup.destination.create", dest.Name, map[string]interface{}{
+		"type":    dest.Type,
+		"encrypt": dest.Encrypt,
+	})
+
+	return c.JSON(http.StatusCreated, dest)
+}
+
+// updateBackupDestinationHandler replaces the definition of a backup
+// destination. The credential is only replaced if one is given.
+func updateBackupDestinationHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+
+	dest, err := backupDestinationRepo.GetByID(c.Param("id"))
+	if err != nil {
+		return backupDestinationLookupError(c, err)
+	}
+
+	var req models.BackupDestinationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	applyDestinationRequest(dest, &req)
+	if err := system.ValidateBackupDestination(dest); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	if req.Secret != "" {
+		if err := saveDestinationSecret(dest, req.Secret, user.ID); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to store credential: " + err.Error(),
+			})
+		}
+	}
+
+	if err := backupDestinationRepo.Update(dest); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to update backup destination: " + err.Error(),
+		})
+	}
+
+	logAudit(user, "backup.destination.update", dest.Name, map[string]interface{}{
+		"type":               dest.Type,
+		"encrypt":            dest.Encrypt,
+		"credential_changed": req.Secret != "",
+	})
+
+	return c.JSON(http.StatusOK, dest)
+}
+
+// deleteBackupDestinationHandler deletes a backup destination and its
+// credential. Backups already stored there are left alone.
+func deleteBackupDestinationHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+
+	dest, err := backupDestinationRepo.GetByID(c.Param("id"))
+	if err != nil {
+		return backupDestinationLookupError(c, err)
+	}
+
+	jobs, err := backupJobRepo.List()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to list backup jobs: " + err.Error(),
+		})
+	}
+	for _, job := range jobs {
+		if job.Destination == dest.ID {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Backup destination is used by backup job " + job.Name,
+			})
+		}
+	}
+
+	if err := backupDestinationRepo.Delete(dest.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to delete backup destination: " + err.Error(),
+		})
+	}
+	if dest.SecretID != "" {
+		if err := secretsRepo.Delete(dest.SecretID); err != nil {
+			c.Logger().Error("Failed to delete backup destination credential: ", err)
+		}
+	}
+
+	logAudit(user, "backup.destination.delete", dest.Name, nil)
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"status": "deleted",
+	})
+}
+
+// testBackupDestinationHandler checks that a destination can be reached
+func testBackupDestinationHandler(c echo.Context) error {
+	_, remote, ok := openBackupRemote(c)
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Minute)
+	defer cancel()
+
+	if err := remote.Check(ctx); err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]string{
+			"error": "Backup destination is not reachable: " + err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]string{
+		"status": "ok",
+	})
+}
+
+// listRemoteBackupsHandler returns the backups stored at a destination
+func listRemoteBackupsHandler(c echo.Context) error {
+	_, remote, ok := openBackupRemote(c)
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 5*time.Minute)
+	defer cancel()
+
+	backups, err := remote.List(ctx)
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]string{
+			"error": "Failed to list remote backups: " + err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, backups)
+}
+
+// uploadBackupHandler copies a local backup to a destination
+func uploadBackupHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+
+	var req struct {
+		BackupID string `json:"backup_id"`
+	}
+	if err := c.Bind(&req); err != nil || req.BackupID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "backup_id is required",
+		})
+	}
+
+	dest, remote, ok := openBackupRemote(c)
+	if !ok {
+		return nil
+	}
+
+	_, backupBasePath, err := system.FindBackup(req.BackupID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Backup not found: " + err.Error(),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), backupTransferTimeout)
+	defer cancel()
+
+	uploaded, err := remote.Upload(ctx, backupBasePath, req.BackupID)
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	logAudit(user, "backup.upload", uploaded.ContainerName, map[string]interface{}{
+		"backup_id":   req.BackupID,
+		"destination": dest.Name,
+		"encrypted":   uploaded.Encrypted,
+	})
+
+	return c.JSON(http.StatusCreated, uploaded)
+}
+
+// deleteRemoteBackupHandler removes a backup from a destination
+func deleteRemoteBackupHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+	backupID := c.Param("backup_id")
+
+	dest, remote, ok := openBackupRemote(c)
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 5*time.Minute)
+	defer cancel()
+
+	if err := remote.Delete(ctx, backupID); err != nil {
+		if os.IsNotExist(err) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Backup not found",
+			})
+		}
+		return c.JSON(http.StatusBadGateway, map[string]string{
+			"error": "Failed to delete remote backup: " + err.Error(),
+		})
+	}
+
+	logAudit(user, "backup.remote.delete", backupID, map[string]interface{}{
+		"destination": dest.Name,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"status": "deleted",
+	})
+}
+
+// downloadRemoteBackupHandler copies a backup from a destination into the
+// default backup path, where it shows up with the local backups
+func downloadRemoteBackupHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+	backupID := c.Param("backup_id")
+
+	dest, remote, ok := openBackupRemote(c)
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), backupTransferTimeout)
+	defer cancel()
+
+	backup, err := remote.Download(ctx, backupID)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Backup not found",
+			})
+		}
+		return c.JSON(http.StatusBadGateway, map[string]string{
+			"error": "Failed to download backup: " + err.Error(),
+		})
+	}
+
+	logAudit(user, "backup.download", backup.ContainerName, map[string]interface{}{
+		"backup_id":   backupID,
+		"destination": dest.Name,
+	})
+
+	return c.JSON(http.StatusOK, backup)
+}
+
+// restoreRemoteBackupHandler downloads a backup from a destination and
+// restores it, like restoring a local backup
+func restoreRemoteBackupHandler(c echo.Context) error {
+	backupID := c.Param("backup_id")
+
+	_, remote, ok := openBackupRemote(c)
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), backupTransferTimeout)
+	defer cancel()
+
+	if _, err := remote.Download(ctx, backupID); err != nil {
+		if os.IsNotExist(err) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Backup not found",
+			})
+		}
+		return c.JSON(http.StatusBadGateway, map[string]string{
+			"error": "Failed to download backup: " + err.Error(),
+		})
+	}
+
+	return restoreContainerBackupHandler(c)
+}
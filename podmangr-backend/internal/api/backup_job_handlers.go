@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"podmangr-backend/internal/database"
+	"podmangr-backend/internal/models"
+	"podmangr-backend/internal/system"
+)
+
+var (
+	backupScheduler *system.BackupScheduler
+	backupJobRepo   *database.BackupJobRepo
+)
+
+// StartBackupScheduler starts running backup jobs on their schedules
+func StartBackupScheduler() {
+	backupJobRepo = database.NewBackupJobRepo()
+	backupScheduler = system.NewBackupScheduler()
+	backupScheduler.Start()
+}
+
+// withNextRun fills in when a job runs next
+func withNextRun(job *models.BackupJob) *models.BackupJob {
+	if next := system.NextBackupRun(job); job.Enabled && !next.IsZero() {
+		job.NextRunAt = &next
+	}
+	return job
+}
+
+// bindBackupJob reads a backup job definition from the request body into job
+// and validates it. The returned error is meant for the client.
+func bindBackupJob(c echo.Context, job *models.BackupJob) error {
+	var req models.BackupJobRequest
+	if err := c.Bind(&req); err != nil {
+		return fmt.Errorf("Invalid request body: %w", err)
+	}
+
+	job.Name = req.Name
+	job.TargetType = req.TargetType
+	job.TargetID = req.TargetID
+	job.Schedule = req.Schedule
+	job.Destination = req.Destination
+	if job.Destination == "" {
+		job.Destination = models.BackupDestinationDefault
+	}
+	job.Retention = req.Retention
+	job.Enabled = req.Enabled
+
+	return backupScheduler.ValidateBackupJob(job)
+}
+
+// backupJobLookupError writes the response for a failed backup job lookup
+func backupJobLookupError(c echo.Context, err error) error {
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Backup job not found",
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": "Failed to get backup job: " + err.Error(),
+	})
+}
+
+// listBackupJobsHandler returns all backup jobs
+func listBackupJobsHandler(c echo.Context) error {
+	jobs, err := backupJobRepo.List()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to list backup jobs: " + err.Error(),
+		})
+	}
+
+	result := make([]models.BackupJob, 0, len(jobs))
+	for i := range jobs {
+		result = append(result, *withNextRun(&jobs[i]))
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// getBackupJobHandler returns a single backup job
+func getBackupJobHandler(c echo.Context) error {
+	job, err := backupJobRepo.GetByID(c.Param("id"))
+	if err != nil {
+		return backupJobLookupError(c, err)
+	}
+	return c.JSON(http.StatusOK, withNextRun(job))
+}
+
+// createBackupJobHandler creates a backup job
+func createBackupJobHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+
+	job := &models.BackupJob{CreatedBy: &user.ID}
+	if err := bindBackupJob(c, job); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	if err := backupJobRepo.Create(job); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to create backup job: " + err.Error(),
+		})
+	}
+
+	logAudit(user, "backup.job.create", job.Name, map[string]interface{}{
+		"target_type": job.TargetType,
+		"target_id":   job.TargetID,
+		"schedule":    job.Schedule,
+	})
+
+	return c.JSON(http.StatusCreated, withNextRun(job))
+}
+
+// updateBackupJobHandler replaces the definition of a backup job
+func updateBackupJobHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+
+	job, err := backupJobRepo.GetByID(c.Param("id"))
+	if err != nil {
+		return backupJobLookupError(c, err)
+	}
+
+	if err := bindBackupJob(c, job); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	if err := backupJobRepo.Update(job); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to update backup job: " + err.Error(),
+		})
+	}
+
+	logAudit(user, "backup.job.update", job.Name, map[string]interface{}{
+		"target_type": job.TargetType,
+		"target_id":   job.TargetID,
+		"schedule":    job.Schedule,
+		"enabled":     job.Enabled,
+	})
+
+	return c.JSON(http.StatusOK, withNextRun(job))
+}
+
+// deleteBackupJobHandler deletes a backup job and its run history.
+// The backups it made are kept.
+func deleteBackupJobHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+
+	job, err := backupJobRepo.GetByID(c.Param("id"))
+	if err != nil {
+		return backupJobLookupError(c, err)
+	}
+
+	if err := backupJobRepo.Delete(job.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to delete backup job: " + err.Error(),
+		})
+	}
+
+	logAudit(user, "backup.job.delete", job.Name, nil)
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"status": "deleted",
+	})
+}
+
+// runBackupJobHandler starts a backup job now. The run continues in the
+// background; its outcome shows up in the job's runs.
+func runBackupJobHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+
+	job, err := backupJobRepo.GetByID(c.Param("id"))
+	if err != nil {
+		return backupJobLookupError(c, err)
+	}
+
+	if backupScheduler.IsRunning(job.ID) {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": system.ErrBackupJobRunning.Error(),
+		})
+	}
+
+	go func() {
+		if _, err := backupScheduler.RunJob(context.Background(), job, "manual"); err != nil {
+			log.Printf("Backup job %s failed: %v", job.Name, err)
+		}
+	}()
+
+	logAudit(user, "backup.job.run", job.Name, nil)
+
+	return c.JSON(http.StatusAccepted, map[string]string{
+		"status": "started",
+		"job_id": job.ID,
+	})
+}
+
+// listBackupJobRunsHandler returns the run history of a backup job
+func listBackupJobRunsHandler(c echo.Context) error {
+	job, err := backupJobRepo.GetByID(c.Param("id"))
+	if err != nil {
+		return backupJobLookupError(c, err)
+	}
+
+	limit := 50
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	runs, err := backupJobRepo.ListRuns(job.ID, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to list backup runs: " + err.Error(),
+		})
+	}
+	if runs == nil {
+		runs = []models.BackupJobRun{}
+	}
+
+	return c.JSON(http.StatusOK, runs)
+}
@@ -26,7 +26,7 @@ var (
 	templateRepo  *database.TemplateRepo
 	metricsRepo   *database.ContainerMetricsRepo
 	envVarRepo    *database.ContainerEnvVarRepo
-	podmanService *system.PodmanService
+	updateRepo    *database.ContainerUpdateRepo
 )
 
 // InitContainerRepos initializes container-related repositories
@@ -35,11 +35,12 @@ func InitContainerRepos() {
 	templateRepo = database.NewTemplateRepo()
 	metricsRepo = database.NewContainerMetricsRepo()
 	envVarRepo = database.NewContainerEnvVarRepo()
-	podmanService = system.NewPodmanService()
+	updateRepo = database.NewContainerUpdateRepo()
 }
 
 // checkPodmanHandler verifies Podman is available
 func checkPodmanHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 5*time.Second)
 	defer cancel()
 
@@ -58,13 +59,14 @@ func checkPodmanHandler(c echo.Context) error {
 		})
 	}
 
-	// Check if podman-compose is available
-	composeAvailable := podmanService.CheckPodmanCompose(ctx)
+	// Stacks run on the built-in engine; podman-compose is only reported
+	podmanComposeInstalled := podmanService.CheckPodmanCompose(ctx)
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"available":         true,
 		"version":           version,
-		"compose_available": composeAvailable,
+		"compose_available": true,
+		"podman_compose":    podmanComposeInstalled,
 		"mode":              podmanService.GetMode(),
 		"target_user":       podmanService.GetTargetUser(),
 		"running_as_root":   podmanService.IsRunningAsRoot(),
@@ -73,6 +75,7 @@ func checkPodmanHandler(c echo.Context) error {
 
 // installPodmanHandler installs Podman and related packages via WebSocket for streaming output
 func installPodmanHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	// Upgrade to WebSocket
 	upgrader := websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool { return true },
@@ -178,6 +181,7 @@ func installPodmanHandler(c echo.Context) error {
 
 // listContainersHandler returns all containers
 func listContainersHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
@@ -223,6 +227,7 @@ func listContainersHandler(c echo.Context) error {
 
 // getContainerHandler returns details for a specific container
 func getContainerHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 5*time.Second)
 	defer cancel()
@@ -276,6 +281,11 @@ func getContainerHandler(c echo.Context) error {
 		"networks":      inspect.NetworkSettings.Networks,
 	}
 
+	// Containers run by a systemd unit (e.g. installed via Quadlet) report its state
+	if unitStatus, err := podmanService.ContainerUnitStatus(inspect); err == nil && unitStatus != nil {
+		response["systemd"] = unitStatus
+	}
+
 	// Add Podmangr metadata if available
 	if dbContainer != nil {
 		response["id"] = dbContainer.ID
@@ -302,6 +312,7 @@ type ValidationResult struct {
 
 // validateContainerHandler validates container configuration before creation
 func validateContainerHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	var req models.CreateContainerRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -460,6 +471,7 @@ func validateContainerHandler(c echo.Context) error {
 
 // deployContainerHandler creates and starts a container with WebSocket streaming
 func deployContainerHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	// Upgrade to WebSocket
 	upgrader := websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool { return true },
@@ -553,6 +565,7 @@ func deployContainerHandler(c echo.Context) error {
 		}
 
 		sendStatus("pull", "Image pulled successfully", false, map[string]interface{}{"complete": true})
+		scanPulledImage(podmanService, req.Image)
 	}
 
 	// Step 3: Create volume directories
@@ -645,6 +658,7 @@ func deployContainerHandler(c echo.Context) error {
 
 // createContainerHandler creates a new container
 func createContainerHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	var req models.CreateContainerRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -713,6 +727,7 @@ func createContainerHandler(c echo.Context) error {
 
 // startContainerHandler starts a container
 func startContainerHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
 	defer cancel()
@@ -761,6 +776,7 @@ func startContainerHandler(c echo.Context) error {
 
 // stopContainerHandler stops a container
 func stopContainerHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 	timeout, _ := strconv.Atoi(c.QueryParam("timeout"))
 	if timeout == 0 {
@@ -810,6 +826,7 @@ func stopContainerHandler(c echo.Context) error {
 
 // restartContainerHandler restarts a container
 func restartContainerHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 	timeout, _ := strconv.Atoi(c.QueryParam("timeout"))
 	if timeout == 0 {
@@ -852,6 +869,7 @@ func restartContainerHandler(c echo.Context) error {
 
 // removeContainerHandler removes a container
 func removeContainerHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 	force := c.QueryParam("force") == "true"
 
@@ -882,6 +900,7 @@ func removeContainerHandler(c echo.Context) error {
 // adoptContainerHandler adopts an existing Podman container into Podmangr's database
 // This allows containers created outside Podmangr to be managed
 func adoptContainerHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	var req models.AdoptContainerRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -1041,6 +1060,7 @@ func updateContainerHandler(c echo.Context) error {
 
 // getContainerLogsRESTHandler returns container logs as JSON (REST endpoint)
 func getContainerLogsRESTHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 	tail := c.QueryParam("tail")
 	if tail == "" {
@@ -1067,6 +1087,7 @@ func getContainerLogsRESTHandler(c echo.Context) error {
 
 // execContainerHandler provides a WebSocket terminal to a container
 func execContainerHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 	containerID := resolveContainerID(id)
 
@@ -1154,6 +1175,7 @@ func execContainerHandler(c echo.Context) error {
 
 // getContainerLogsHandler streams container logs via WebSocket
 func getContainerLogsHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 	tail, _ := strconv.Atoi(c.QueryParam("tail"))
 	if tail == 0 {
@@ -1204,6 +1226,7 @@ func getContainerLogsHandler(c echo.Context) error {
 
 // inspectContainerHandler returns detailed container inspection data from podman
 func inspectContainerHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
 	defer cancel()
@@ -1238,6 +1261,7 @@ func inspectContainerHandler(c echo.Context) error {
 
 // getContainerStatsHandler returns real-time stats
 func getContainerStatsHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
@@ -1256,13 +1280,23 @@ func getContainerStatsHandler(c echo.Context) error {
 
 // getContainerMetricsHandler returns historical metrics
 func getContainerMetricsHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 	hours, _ := strconv.Atoi(c.QueryParam("hours"))
 	if hours == 0 {
 		hours = 24
 	}
 
-	metrics, err := metricsRepo.GetRecent(id, hours)
+	// Metrics are stored under the full Podman ID; accept names and short IDs too.
+	// If the container no longer exists, fall back to the ID as given.
+	containerID := resolveContainerID(id)
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 5*time.Second)
+	defer cancel()
+	if inspect, err := podmanService.InspectContainer(ctx, containerID); err == nil {
+		containerID = inspect.ID
+	}
+
+	metrics, err := metricsRepo.GetRecent(containerID, hours)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to get metrics: " + err.Error(),
@@ -1277,6 +1311,7 @@ func getContainerMetricsHandler(c echo.Context) error {
 // inspectImageHandler returns configuration hints for an image
 // It can optionally pull the image if not found locally
 func inspectImageHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	image := c.QueryParam("image")
 	if image == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -1312,6 +1347,7 @@ func inspectImageHandler(c echo.Context) error {
 
 // inspectImageWSHandler inspects an image with WebSocket streaming for pull progress
 func inspectImageWSHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	// Upgrade to WebSocket
 	upgrader := websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool { return true },
@@ -1395,6 +1431,7 @@ func inspectImageWSHandler(c echo.Context) error {
 			"status":  "pulled",
 			"message": "Image pulled successfully",
 		})
+		scanPulledImage(podmanService, req.Image)
 	}
 
 	// Now inspect the image
@@ -1424,6 +1461,7 @@ func inspectImageWSHandler(c echo.Context) error {
 
 // listImagesHandler returns all images
 func listImagesHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
@@ -1434,11 +1472,14 @@ func listImagesHandler(c echo.Context) error {
 		})
 	}
 
+	attachVulnerabilitySummaries(images)
+
 	return c.JSON(http.StatusOK, images)
 }
 
 // pullImageHandler pulls an image
 func pullImageHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	var req models.PullImageRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -1457,6 +1498,7 @@ func pullImageHandler(c echo.Context) error {
 
 	user := c.Get("user").(*models.User)
 	logAudit(user, models.ActionImagePull, req.Image, nil)
+	scanPulledImage(podmanService, req.Image)
 
 	return c.JSON(http.StatusOK, map[string]string{
 		"status": "pulled",
@@ -1466,6 +1508,7 @@ func pullImageHandler(c echo.Context) error {
 
 // removeImageHandler removes an image
 func removeImageHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 	force := c.QueryParam("force") == "true"
 
@@ -1499,6 +1542,7 @@ type BindMount struct {
 
 // listBindMountsHandler returns all bind mounts across all containers
 func listBindMountsHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
 	defer cancel()
 
@@ -1538,6 +1582,7 @@ func listBindMountsHandler(c echo.Context) error {
 
 // listVolumesHandler returns all volumes
 func listVolumesHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
@@ -1553,6 +1598,7 @@ func listVolumesHandler(c echo.Context) error {
 
 // createVolumeHandler creates a new volume
 func createVolumeHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	var req models.CreateVolumeRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -1580,6 +1626,7 @@ func createVolumeHandler(c echo.Context) error {
 
 // removeVolumeHandler removes a volume
 func removeVolumeHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	name := c.Param("name")
 	force := c.QueryParam("force") == "true"
 
@@ -1604,6 +1651,7 @@ func removeVolumeHandler(c echo.Context) error {
 
 // listPodmanNetworksHandler returns all Podman networks
 func listPodmanNetworksHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
@@ -1619,6 +1667,7 @@ func listPodmanNetworksHandler(c echo.Context) error {
 
 // createPodmanNetworkHandler creates a new network
 func createPodmanNetworkHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	var req models.CreateNetworkRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -1646,6 +1695,7 @@ func createPodmanNetworkHandler(c echo.Context) error {
 
 // removePodmanNetworkHandler removes a network
 func removePodmanNetworkHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	name := c.Param("name")
 	force := c.QueryParam("force") == "true"
 
@@ -2006,6 +2056,7 @@ func importTemplateHandler(c echo.Context) error {
 
 // Desktop apps handler - returns containers with web UIs for desktop icons
 func listDesktopAppsHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
@@ -2228,6 +2279,7 @@ func logAudit(user *models.User, action, target string, details map[string]inter
 
 // getStorageConfigHandler returns current Podman storage configuration
 func getStorageConfigHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
@@ -2248,6 +2300,7 @@ type UpdateStorageConfigRequest struct {
 
 // updateStorageConfigHandler updates Podman storage configuration
 func updateStorageConfigHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	var req UpdateStorageConfigRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -2321,6 +2374,7 @@ func updateStorageConfigHandler(c echo.Context) error {
 
 // getContainerConfigHandler returns the full configuration of a container
 func getContainerConfigHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
@@ -2350,6 +2404,7 @@ func getContainerConfigHandler(c echo.Context) error {
 
 // listContainerBackupsHandler lists backups for a container
 func listContainerBackupsHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 	containerID := resolveContainerID(id)
 
@@ -2366,18 +2421,16 @@ func listContainerBackupsHandler(c echo.Context) error {
 
 	containerName := strings.TrimPrefix(inspect.Name, "/")
 
-	// Default backup path
-	backupPath := os.Getenv("PODMANGR_BACKUP_PATH")
-	if backupPath == "" {
-		homeDir, _ := os.UserHomeDir()
-		backupPath = filepath.Join(homeDir, ".podmangr", "backups")
-	}
-
-	backups, err := podmanService.ListBackups(backupPath, containerName)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to list backups: " + err.Error(),
-		})
+	// Pre-update backups and those of backup jobs may live in different places
+	backups := make([]models.ContainerBackup, 0)
+	for _, backupPath := range system.BackupPaths() {
+		found, err := podmanService.ListBackups(backupPath, containerName)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to list backups: " + err.Error(),
+			})
+		}
+		backups = append(backups, found...)
 	}
 
 	return c.JSON(http.StatusOK, backups)
@@ -2385,6 +2438,7 @@ func listContainerBackupsHandler(c echo.Context) error {
 
 // checkContainerUpdateHandler checks if an update is available for a container's image
 func checkContainerUpdateHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 2*time.Minute)
 	defer cancel()
@@ -2409,16 +2463,27 @@ func checkContainerUpdateHandler(c echo.Context) error {
 		})
 	}
 
+	// Version tags may have newer versions under other tags
+	newerTags, err := system.NewerImageTags(ctx, currentImage, podmanService.RegistrySocketID())
+	if err != nil {
+		c.Logger().Warn("Failed to list newer tags: ", err)
+	}
+	if newerTags == nil {
+		newerTags = []string{}
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"has_update":    hasUpdate,
 		"current_image": currentImage,
 		"local_digest":  localDigest,
 		"remote_digest": remoteDigest,
+		"newer_tags":    newerTags,
 	})
 }
 
 // updateContainerImageHandler handles the container update workflow via WebSocket
 func updateContainerImageHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	// Upgrade to WebSocket
 	upgrader := websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool { return true },
@@ -2461,342 +2526,144 @@ func updateContainerImageHandler(c echo.Context) error {
 		ws.WriteJSON(payload)
 	}
 
-	ctx := context.Background()
-
-	// Resolve container ID
-	containerID := resolveContainerID(req.ContainerID)
-
-	// Step 1: Get current container configuration
-	sendStatus("config", "Reading container configuration...", false, 5, nil)
-
-	config, err := podmanService.GetContainerConfig(ctx, containerID)
-	if err != nil {
-		sendStatus("config", "Failed to read container config: "+err.Error(), true, 0, nil)
+	// The update runs to completion even if the client disconnects,
+	// so a rollback is never left half done
+	req.ContainerID = resolveContainerID(req.ContainerID)
+	record, _ := podmanService.UpdateContainer(context.Background(), &req, &user.ID, sendStatus)
+	if record == nil {
 		return nil
 	}
 
-	// Enrich with database metadata
-	var dbContainer *models.Container
-	if dc, err := containerRepo.GetByContainerID(containerID); err == nil {
-		dbContainer = dc
-		config.HasWebUI = dc.HasWebUI
-		config.WebUIPort = dc.WebUIPort
-		config.WebUIPath = dc.WebUIPath
-		config.Icon = dc.Icon
-		config.IconLight = dc.IconLight
-		config.IconDark = dc.IconDark
-		config.AutoStart = dc.AutoStart
-	}
-
-	// Determine new image
-	newImage := req.NewImage
-	if newImage == "" {
-		newImage = config.Image // Use same image (will pull latest)
-	}
-
-	sendStatus("config", "Configuration read successfully", false, 10, map[string]interface{}{
-		"container_name": config.Name,
-		"current_image":  config.Image,
-		"new_image":      newImage,
-		"has_volumes":    len(config.Volumes) > 0,
+	// Audit log
+	logAudit(user, models.ActionContainerUpdate, record.ContainerName, map[string]interface{}{
+		"old_image":        record.OldImage,
+		"new_image":        record.NewImage,
+		"old_container_id": record.OldContainerID,
+		"new_container_id": record.NewContainerID,
+		"status":           record.Status,
+		"backup_id":        record.BackupID,
+		"reason":           record.Reason,
 	})
 
-	// Step 2: Backup volumes if requested and volumes exist
-	var backup *models.ContainerBackup
-	hasBindMounts := false
-	for _, vol := range config.Volumes {
-		if vol.Type == "bind" {
-			hasBindMounts = true
-			break
-		}
-	}
-
-	if req.CreateBackup && hasBindMounts {
-		sendStatus("backup", "Creating backup of bind mounts...", false, 15, nil)
-
-		// Determine backup path
-		backupPath := req.BackupPath
-		if backupPath == "" {
-			backupPath = os.Getenv("PODMANGR_BACKUP_PATH")
-			if backupPath == "" {
-				homeDir, _ := os.UserHomeDir()
-				backupPath = filepath.Join(homeDir, ".podmangr", "backups")
-			}
-		}
-
-		// Create backup directory
-		if err := os.MkdirAll(backupPath, 0755); err != nil {
-			sendStatus("backup", "Failed to create backup directory: "+err.Error(), true, 0, nil)
-			return nil
-		}
-
-		// Create progress channel for backup
-		progressChan := make(chan string, 10)
-		backupDone := make(chan error, 1)
-
-		go func() {
-			var backupErr error
-			backup, backupErr = podmanService.BackupBindMounts(ctx, containerID, backupPath, req.OverwriteBackup, progressChan)
-			close(progressChan)
-			backupDone <- backupErr
-		}()
-
-		// Stream backup progress
-		for msg := range progressChan {
-			sendStatus("backup", msg, false, 20, nil)
-		}
-
-		if err := <-backupDone; err != nil {
-			sendStatus("backup", "Backup failed: "+err.Error(), true, 0, nil)
-			return nil
-		}
+	return nil
+}
 
-		sendStatus("backup", fmt.Sprintf("Backup created: %s (%.2f MB)", backup.ID, float64(backup.SizeBytes)/(1024*1024)), false, 25, map[string]interface{}{
-			"backup_id":   backup.ID,
-			"backup_path": backup.BackupPath,
-			"backup_size": backup.SizeBytes,
-		})
+// listContainerUpdatesHandler returns the update history of a container.
+// History is kept by name since every update replaces the container ID.
+func listContainerUpdatesHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
+	id := c.Param("id")
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 5*time.Second)
+	defer cancel()
 
-		logAudit(user, models.ActionContainerBackup, config.Name, map[string]interface{}{
-			"backup_id":   backup.ID,
-			"backup_path": backup.BackupPath,
-		})
-	} else if req.CreateBackup && !hasBindMounts {
-		sendStatus("backup", "No bind mounts to backup, skipping...", false, 25, nil)
+	name := id
+	if inspect, err := podmanService.InspectContainer(ctx, resolveContainerID(id)); err == nil {
+		name = strings.TrimPrefix(inspect.Name, "/")
+	} else if dbContainer, err := containerRepo.GetByID(id); err == nil {
+		name = dbContainer.Name
 	}
 
-	// Step 3: Pull new image
-	sendStatus("pull", "Pulling new image: "+newImage, false, 30, nil)
-
-	pullChan := make(chan string, 100)
-	pullDone := make(chan error, 1)
-
-	go func() {
-		pullDone <- podmanService.PullImageWithProgress(ctx, newImage, pullChan)
-	}()
-
-	for line := range pullChan {
-		sendStatus("pull", line, false, 35, map[string]interface{}{"output": true})
+	limit := 50
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 {
+		limit = l
 	}
 
-	if err := <-pullDone; err != nil {
-		sendStatus("pull", "Failed to pull image: "+err.Error(), true, 0, nil)
-		return nil
+	updates, err := updateRepo.ListByContainerName(name, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to list updates: " + err.Error(),
+		})
 	}
-
-	sendStatus("pull", "Image pulled successfully", false, 45, nil)
-
-	// Step 4: Stop current container
-	stopTimeout := req.StopTimeout
-	if stopTimeout == 0 {
-		stopTimeout = 30
+	if updates == nil {
+		updates = []models.ContainerUpdateRecord{}
 	}
 
-	sendStatus("stop", "Stopping current container...", false, 50, nil)
-
-	if err := podmanService.StopContainer(ctx, containerID, stopTimeout); err != nil {
-		// Container might already be stopped, that's okay
-		sendStatus("stop", "Container stopped (or was already stopped)", false, 55, nil)
-	} else {
-		sendStatus("stop", "Container stopped", false, 55, nil)
-	}
+	return c.JSON(http.StatusOK, updates)
+}
 
-	// Step 5: Rename old container
-	backupContainerName := fmt.Sprintf("%s_backup_%s", config.Name, time.Now().Format("20060102_150405"))
-	sendStatus("rename", "Renaming old container to: "+backupContainerName, false, 60, nil)
+// deleteContainerBackupHandler deletes a backup
+func deleteContainerBackupHandler(c echo.Context) error {
+	backupID := c.Param("backup_id")
 
-	if err := podmanService.RenameContainer(ctx, containerID, backupContainerName); err != nil {
-		sendStatus("rename", "Failed to rename container: "+err.Error(), true, 0, nil)
-		return nil
+	// Deleting also frees the chunks only this backup used
+	_, backupPath, err := system.FindBackup(backupID)
+	if err == nil {
+		err = system.DeleteBackup(backupPath, backupID)
 	}
-
-	sendStatus("rename", "Old container renamed", false, 65, nil)
-
-	// Step 6: Create new container with updated image
-	sendStatus("create", "Creating new container with updated image...", false, 70, nil)
-
-	createReq := &models.CreateContainerRequest{
-		Name:          config.Name,
-		Image:         newImage,
-		Ports:         config.Ports,
-		Volumes:       config.Volumes,
-		Environment:   config.Environment,
-		Labels:        config.Labels,
-		RestartPolicy: config.RestartPolicy,
-		NetworkMode:   config.NetworkMode,
-		Hostname:      config.Hostname,
-		User:          config.User,
-		WorkDir:       config.WorkDir,
-		Entrypoint:    config.Entrypoint,
-		Command:       config.Command,
-		CPULimit:      config.CPULimit,
-		MemoryLimit:   config.MemoryLimit,
-		HasWebUI:      config.HasWebUI,
-		WebUIPort:     config.WebUIPort,
-		WebUIPath:     config.WebUIPath,
-		Icon:          config.Icon,
-		IconLight:     config.IconLight,
-		IconDark:      config.IconDark,
-		AutoStart:     config.AutoStart,
-	}
-
-	newContainerID, err := podmanService.CreateContainer(ctx, createReq)
 	if err != nil {
-		// Rollback: rename the backup container back
-		sendStatus("create", "Failed to create new container, rolling back...", true, 0, nil)
-		podmanService.RenameContainer(ctx, backupContainerName, config.Name)
-		sendStatus("create", "Rollback complete. Original container restored.", true, 0, nil)
-		return nil
-	}
-
-	sendStatus("create", "New container created", false, 80, map[string]interface{}{
-		"new_container_id": newContainerID,
-	})
-
-	// Step 7: Start new container
-	sendStatus("start", "Starting new container...", false, 85, nil)
-
-	if err := podmanService.StartContainer(ctx, newContainerID); err != nil {
-		// Rollback: remove new container and rename backup back
-		sendStatus("start", "Failed to start new container, rolling back...", true, 0, nil)
-		podmanService.RemoveContainer(ctx, newContainerID, true)
-		podmanService.RenameContainer(ctx, backupContainerName, config.Name)
-		sendStatus("start", "Rollback complete. Original container restored.", true, 0, nil)
-		return nil
-	}
-
-	sendStatus("start", "New container started", false, 90, nil)
-
-	// Step 8: Update database record
-	if dbContainer != nil {
-		dbContainer.ContainerID = newContainerID
-		dbContainer.Image = newImage
-		dbContainer.Status = models.ContainerStatusRunning
-		containerRepo.Update(dbContainer)
-	}
-
-	// Step 9: Optionally remove old container
-	if req.RemoveOld {
-		sendStatus("cleanup", "Removing old container backup...", false, 95, nil)
-		if err := podmanService.RemoveContainer(ctx, backupContainerName, true); err != nil {
-			sendStatus("cleanup", "Warning: Failed to remove old container: "+err.Error(), false, 95, nil)
-		} else {
-			sendStatus("cleanup", "Old container removed", false, 97, nil)
+		if os.IsNotExist(err) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Backup not found",
+			})
 		}
-	} else {
-		sendStatus("cleanup", fmt.Sprintf("Old container kept as: %s", backupContainerName), false, 97, nil)
-	}
-
-	// Final success
-	sendStatus("complete", "Container updated successfully!", false, 100, map[string]interface{}{
-		"new_container_id":    newContainerID,
-		"new_image":           newImage,
-		"backup_container":    backupContainerName,
-		"backup_removed":      req.RemoveOld,
-		"volume_backup_id":    "",
-		"volume_backup_path":  "",
-		"complete":            true,
-	})
-
-	// Update details if backup was created
-	if backup != nil {
-		ws.WriteJSON(map[string]interface{}{
-			"step":              "complete",
-			"message":           "Container updated successfully!",
-			"progress":          100,
-			"complete":          true,
-			"new_container_id":  newContainerID,
-			"new_image":         newImage,
-			"backup_container":  backupContainerName,
-			"backup_removed":    req.RemoveOld,
-			"volume_backup_id":  backup.ID,
-			"volume_backup_path": backup.BackupPath,
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to delete backup: " + err.Error(),
 		})
 	}
 
-	// Audit log
-	logAudit(user, models.ActionContainerUpdate, config.Name, map[string]interface{}{
-		"old_image":        config.Image,
-		"new_image":        newImage,
-		"old_container_id": containerID,
-		"new_container_id": newContainerID,
-		"backup_created":   backup != nil,
-	})
+	user := c.Get("user").(*models.User)
+	logAudit(user, "container.backup.delete", backupID, nil)
 
-	return nil
+	return c.JSON(http.StatusOK, map[string]string{
+		"status": "deleted",
+	})
 }
 
-// deleteContainerBackupHandler deletes a backup
-func deleteContainerBackupHandler(c echo.Context) error {
+// verifyContainerBackupHandler checks a backup against the checksums in its manifest
+func verifyContainerBackupHandler(c echo.Context) error {
 	backupID := c.Param("backup_id")
 
-	// Default backup path
-	backupPath := os.Getenv("PODMANGR_BACKUP_PATH")
-	if backupPath == "" {
-		homeDir, _ := os.UserHomeDir()
-		backupPath = filepath.Join(homeDir, ".podmangr", "backups")
-	}
-
-	// Find and delete the backup
-	fullPath := filepath.Join(backupPath, backupID)
-	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+	_, backupPath, err := system.FindBackup(backupID)
+	if err != nil {
 		return c.JSON(http.StatusNotFound, map[string]string{
 			"error": "Backup not found",
 		})
 	}
 
-	if err := os.RemoveAll(fullPath); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to delete backup: " + err.Error(),
+	result, err := system.VerifyBackup(backupPath, backupID)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Backup not found",
+			})
+		}
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
 		})
 	}
 
 	user := c.Get("user").(*models.User)
-	logAudit(user, "container.backup.delete", backupID, nil)
-
-	return c.JSON(http.StatusOK, map[string]string{
-		"status": "deleted",
+	logAudit(user, "container.backup.verify", backupID, map[string]interface{}{
+		"valid":  result.Valid,
+		"errors": len(result.Errors),
 	})
+
+	return c.JSON(http.StatusOK, result)
 }
 
 // restoreContainerBackupHandler restores a container from a backup
 func restoreContainerBackupHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	backupID := c.Param("backup_id")
 	user := c.Get("user").(*models.User)
 
-	// Default backup path
-	backupPath := os.Getenv("PODMANGR_BACKUP_PATH")
-	if backupPath == "" {
-		homeDir, _ := os.UserHomeDir()
-		backupPath = filepath.Join(homeDir, ".podmangr", "backups")
-	}
-
 	// Find the backup metadata
-	backupDir := filepath.Join(backupPath, backupID)
-	metadataPath := filepath.Join(backupDir, "backup.json")
-
-	data, err := os.ReadFile(metadataPath)
+	backup, _, err := system.FindBackup(backupID)
 	if err != nil {
 		return c.JSON(http.StatusNotFound, map[string]string{
 			"error": "Backup not found: " + err.Error(),
 		})
 	}
 
-	var backup models.ContainerBackup
-	if err := json.Unmarshal(data, &backup); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to parse backup metadata: " + err.Error(),
-		})
-	}
-
 	// Create progress channel
 	progressChan := make(chan string, 10)
 	ctx := c.Request().Context()
 
 	// Restore in background and collect progress
-	var restoreErr error
+	restoreDone := make(chan error, 1)
 	go func() {
-		restoreErr = podmanService.RestoreBindMounts(ctx, &backup, progressChan)
+		restoreDone <- podmanService.RestoreMounts(ctx, backup, progressChan)
+		close(progressChan)
 	}()
 
 	// Wait for restore to complete
@@ -2804,7 +2671,7 @@ func restoreContainerBackupHandler(c echo.Context) error {
 		// Just consume progress messages
 	}
 
-	if restoreErr != nil {
+	if restoreErr := <-restoreDone; restoreErr != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to restore backup: " + restoreErr.Error(),
 		})
@@ -2812,7 +2679,7 @@ func restoreContainerBackupHandler(c echo.Context) error {
 
 	logAudit(user, models.ActionContainerRestore, backup.ContainerName, map[string]interface{}{
 		"backup_id":   backupID,
-		"backup_path": backupDir,
+		"backup_path": backup.BackupPath,
 	})
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -2823,52 +2690,28 @@ func restoreContainerBackupHandler(c echo.Context) error {
 	})
 }
 
-// BackupSettings represents global backup configuration
-type BackupSettings struct {
-	DefaultPath      string `json:"default_path"`       // Default backup directory
-	ExternalDrivePath string `json:"external_drive_path"` // Path to external drive for large backups
-	AutoBackupEnabled bool   `json:"auto_backup_enabled"` // Auto-backup before updates
-	MaxBackupsPerContainer int `json:"max_backups_per_container"` // Max backups to keep (0 = unlimited)
-}
-
 // getBackupSettingsHandler returns the current backup settings
 func getBackupSettingsHandler(c echo.Context) error {
-	homeDir, _ := os.UserHomeDir()
-	configPath := filepath.Join(homeDir, ".podmangr", "backup_settings.json")
-
-	// Default settings
-	settings := BackupSettings{
-		DefaultPath:           filepath.Join(homeDir, ".podmangr", "backups"),
-		ExternalDrivePath:     "",
-		AutoBackupEnabled:     true,
-		MaxBackupsPerContainer: 3,
-	}
-
-	// Override with env var if set
-	if envPath := os.Getenv("PODMANGR_BACKUP_PATH"); envPath != "" {
-		settings.DefaultPath = envPath
-	}
-
-	// Try to read config file
-	data, err := os.ReadFile(configPath)
-	if err == nil {
-		json.Unmarshal(data, &settings)
-	}
-
-	return c.JSON(http.StatusOK, settings)
+	return c.JSON(http.StatusOK, system.LoadBackupSettings())
 }
 
 // updateBackupSettingsHandler updates the backup settings
 func updateBackupSettingsHandler(c echo.Context) error {
 	user := c.Get("user").(*models.User)
 
-	var settings BackupSettings
+	var settings system.BackupSettings
 	if err := c.Bind(&settings); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "Invalid request body: " + err.Error(),
 		})
 	}
 
+	if settings.MaxBackupsPerContainer < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "max_backups_per_container must not be negative",
+		})
+	}
+
 	// Validate paths if provided
 	if settings.DefaultPath != "" {
 		// Expand ~ to home directory
@@ -2901,23 +2744,7 @@ func updateBackupSettingsHandler(c echo.Context) error {
 	}
 
 	// Save settings
-	homeDir, _ := os.UserHomeDir()
-	configDir := filepath.Join(homeDir, ".podmangr")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to create config directory: " + err.Error(),
-		})
-	}
-
-	configPath := filepath.Join(configDir, "backup_settings.json")
-	data, err := json.MarshalIndent(settings, "", "  ")
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to serialize settings: " + err.Error(),
-		})
-	}
-
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
+	if err := system.SaveBackupSettings(settings); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to save settings: " + err.Error(),
 		})
@@ -2928,26 +2755,49 @@ func updateBackupSettingsHandler(c echo.Context) error {
 		"external_drive":    settings.ExternalDrivePath,
 		"auto_backup":       settings.AutoBackupEnabled,
 		"max_backups":       settings.MaxBackupsPerContainer,
+		"deduplicate":       settings.Deduplicate,
 	})
 
 	return c.JSON(http.StatusOK, settings)
 }
 
+// pruneBackupsHandler applies the retention limit to the backups of every container
+func pruneBackupsHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+	settings := system.LoadBackupSettings()
+
+	removed, err := system.PruneAllBackups(system.DefaultBackupPath(), settings.MaxBackupsPerContainer)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error":   "Failed to prune backups: " + err.Error(),
+			"removed": removed,
+		})
+	}
+
+	logAudit(user, "backup.prune", "backups", map[string]interface{}{
+		"max_backups": settings.MaxBackupsPerContainer,
+		"removed":     len(removed),
+	})
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"removed": removed,
+	})
+}
+
 // listAllBackupsHandler lists all backups across all containers
 func listAllBackupsHandler(c echo.Context) error {
-	// Default backup path
-	backupPath := os.Getenv("PODMANGR_BACKUP_PATH")
-	if backupPath == "" {
-		homeDir, _ := os.UserHomeDir()
-		backupPath = filepath.Join(homeDir, ".podmangr", "backups")
-	}
+	podmanService := getPodmanService(c)
 
 	// List all backups (empty container name = all containers)
-	backups, err := podmanService.ListBackups(backupPath, "")
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to list backups: " + err.Error(),
-		})
+	backups := make([]models.ContainerBackup, 0)
+	for _, backupPath := range system.BackupPaths() {
+		found, err := podmanService.ListBackups(backupPath, "")
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to list backups: " + err.Error(),
+			})
+		}
+		backups = append(backups, found...)
 	}
 
 	return c.JSON(http.StatusOK, backups)
@@ -3,7 +3,9 @@ package api
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -202,6 +204,85 @@ func deleteDatabaseServerHandler(c echo.Context) error {
 	})
 }
 
+// updateDatabaseServerHandler changes the engine parameters and resource
+// limits of a database server, recreating its container
+// PUT /api/database-servers/:id
+func updateDatabaseServerHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Minute) // Image pull and server start
+	defer cancel()
+	user := c.Get("user").(*models.User)
+
+	var req models.UpdateDatabaseServerRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	server, err := dbService.UpdateServer(ctx, c.Param("id"), &req)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Server not found",
+			})
+		}
+		c.Logger().Error("Failed to update database server: ", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to update database server: " + err.Error(),
+		})
+	}
+
+	logAudit(user, "database.server.update", server.Name, map[string]interface{}{
+		"config":       server.Config,
+		"memory_limit": server.MemoryLimit,
+		"cpu_limit":    server.CPULimit,
+	})
+
+	return c.JSON(http.StatusOK, server)
+}
+
+// upgradeDatabaseServerHandler moves a database server to another engine version
+// POST /api/database-servers/:id/upgrade
+func upgradeDatabaseServerHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), backupTransferTimeout)
+	defer cancel()
+	user := c.Get("user").(*models.User)
+
+	var req models.UpgradeDatabaseServerRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+	if req.Version == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Version is required",
+		})
+	}
+
+	result, err := dbService.UpgradeServer(ctx, c.Param("id"), &req, system.LoadBackupSettings().DefaultPath)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Server not found",
+			})
+		}
+		c.Logger().Error("Failed to upgrade database server: ", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to upgrade database server: " + err.Error(),
+		})
+	}
+
+	logAudit(user, "database.server.upgrade", result.Server.Name, map[string]interface{}{
+		"from_version": result.FromVersion,
+		"to_version":   result.Server.Version,
+		"strategy":     result.Strategy,
+		"backup_id":    result.BackupID,
+	})
+
+	return c.JSON(http.StatusOK, result)
+}
+
 // listDatabasesHandler returns all databases in a server
 // GET /api/database-servers/:id/databases
 func listDatabasesHandler(c echo.Context) error {
@@ -332,3 +413,293 @@ func getDatabaseConnectionHandler(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, conn)
 }
+
+// listDatabaseUsersHandler returns the users of a database
+// GET /api/database-servers/:id/databases/:dbId/users
+func listDatabaseUsersHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	users, err := dbService.ListDatabaseUsers(ctx, c.Param("dbId"))
+	if err != nil {
+		return databaseUserError(c, "Failed to list database users", err)
+	}
+	return c.JSON(http.StatusOK, users)
+}
+
+// createDatabaseUserHandler adds a user with a role to a database
+// POST /api/database-servers/:id/databases/:dbId/users
+func createDatabaseUserHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
+	defer cancel()
+	user := c.Get("user").(*models.User)
+
+	var req models.CreateDatabaseUserRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	dbUser, password, err := dbService.CreateDatabaseUser(ctx, c.Param("dbId"), &req, &user.ID)
+	if err != nil {
+		return databaseUserError(c, "Failed to create database user", err)
+	}
+
+	logAudit(user, "database.user.create", dbUser.Username, map[string]interface{}{
+		"database_id": dbUser.DatabaseID,
+		"role":        dbUser.Role,
+	})
+
+	// Return the user with the password (only shown once)
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"user":     dbUser,
+		"password": password,
+		"message":  "User created successfully. Save the password - it will not be shown again.",
+	})
+}
+
+// deleteDatabaseUserHandler removes an additional user from a database
+// DELETE /api/database-servers/:id/databases/:dbId/users/:username
+func deleteDatabaseUserHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
+	defer cancel()
+	user := c.Get("user").(*models.User)
+
+	if err := dbService.DeleteDatabaseUser(ctx, c.Param("dbId"), c.Param("username")); err != nil {
+		return databaseUserError(c, "Failed to delete database user", err)
+	}
+
+	logAudit(user, "database.user.delete", c.Param("username"), map[string]interface{}{
+		"database_id": c.Param("dbId"),
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Database user deleted successfully",
+	})
+}
+
+// rotateDatabasePasswordHandler sets a new password for a database user
+// POST /api/database-servers/:id/databases/:dbId/users/:username/rotate-password
+func rotateDatabasePasswordHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
+	defer cancel()
+	user := c.Get("user").(*models.User)
+
+	var req models.RotatePasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	password, err := dbService.RotateDatabasePassword(ctx, c.Param("dbId"), c.Param("username"), req.Password, &user.ID)
+	if err != nil {
+		return databaseUserError(c, "Failed to rotate password", err)
+	}
+
+	logAudit(user, "database.password.rotate", c.Param("username"), map[string]interface{}{
+		"database_id": c.Param("dbId"),
+	})
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"password": password,
+		"message":  "Password rotated successfully. Save the password - it will not be shown again.",
+	})
+}
+
+// listDatabaseSessionsHandler returns the live connections to a database
+// GET /api/database-servers/:id/databases/:dbId/sessions
+func listDatabaseSessionsHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
+	defer cancel()
+
+	sessions, err := dbService.ListDatabaseSessions(ctx, c.Param("dbId"))
+	if err != nil {
+		return databaseUserError(c, "Failed to list sessions", err)
+	}
+	return c.JSON(http.StatusOK, sessions)
+}
+
+// getDatabaseStatsHandler returns the size of a database and its tables
+// GET /api/database-servers/:id/databases/:dbId/stats
+func getDatabaseStatsHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 60*time.Second)
+	defer cancel()
+
+	stats, err := dbService.GetDatabaseStats(ctx, c.Param("dbId"))
+	if err != nil {
+		return databaseUserError(c, "Failed to get database size", err)
+	}
+	return c.JSON(http.StatusOK, stats)
+}
+
+// databaseUserError maps the error of a database user or insight operation
+// to a response
+func databaseUserError(c echo.Context, message string, err error) error {
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Database or user not found",
+		})
+	}
+	c.Logger().Error(message+": ", err)
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": message + ": " + err.Error(),
+	})
+}
+
+// listDatabaseServerBackupsHandler returns the dumps of a whole database server
+// GET /api/database-servers/:id/backups
+func listDatabaseServerBackupsHandler(c echo.Context) error {
+	backups, err := system.ListDatabaseBackups(c.Param("id"), "")
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to list backups: " + err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, backups)
+}
+
+// createDatabaseServerBackupHandler dumps every database of a server
+// POST /api/database-servers/:id/backups
+func createDatabaseServerBackupHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), backupTransferTimeout)
+	defer cancel()
+	user := c.Get("user").(*models.User)
+
+	backup, err := dbService.DumpServer(ctx, c.Param("id"), system.LoadBackupSettings().DefaultPath, nil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Server not found",
+			})
+		}
+		c.Logger().Error("Failed to dump database server: ", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to back up database server: " + err.Error(),
+		})
+	}
+
+	logAudit(user, "database.backup", backup.ContainerName, map[string]interface{}{
+		"backup_id": backup.ID,
+	})
+
+	return c.JSON(http.StatusCreated, backup)
+}
+
+// restoreDatabaseServerBackupHandler restores a dump of a whole database server
+// POST /api/database-servers/:id/backups/restore
+func restoreDatabaseServerBackupHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), backupTransferTimeout)
+	defer cancel()
+	user := c.Get("user").(*models.User)
+
+	var req models.RestoreDatabaseRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+	if req.BackupID == "" && req.LatestBefore == nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "backup_id or latest_before is required",
+		})
+	}
+
+	result, err := dbService.RestoreServer(ctx, c.Param("id"), &req)
+	if err != nil {
+		return databaseRestoreError(c, err)
+	}
+
+	logAudit(user, "database.restore", c.Param("id"), map[string]interface{}{
+		"backup_id": result.BackupID,
+	})
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// listDatabaseBackupsHandler returns the dumps of a database
+// GET /api/database-servers/:id/databases/:dbId/backups
+func listDatabaseBackupsHandler(c echo.Context) error {
+	backups, err := system.ListDatabaseBackups(c.Param("id"), c.Param("dbId"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to list backups: " + err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, backups)
+}
+
+// createDatabaseBackupHandler dumps a database
+// POST /api/database-servers/:id/databases/:dbId/backups
+func createDatabaseBackupHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), backupTransferTimeout)
+	defer cancel()
+	user := c.Get("user").(*models.User)
+
+	backup, err := dbService.DumpDatabase(ctx, c.Param("dbId"), system.LoadBackupSettings().DefaultPath, nil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Database not found",
+			})
+		}
+		c.Logger().Error("Failed to dump database: ", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to back up database: " + err.Error(),
+		})
+	}
+
+	logAudit(user, "database.backup", backup.ContainerName, map[string]interface{}{
+		"backup_id": backup.ID,
+		"database":  backup.Mounts[0].Source,
+	})
+
+	return c.JSON(http.StatusCreated, backup)
+}
+
+// restoreDatabaseBackupHandler restores a dump of a database into the same
+// or another database of the server
+// POST /api/database-servers/:id/databases/:dbId/backups/restore
+func restoreDatabaseBackupHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), backupTransferTimeout)
+	defer cancel()
+	user := c.Get("user").(*models.User)
+
+	var req models.RestoreDatabaseRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+	if req.BackupID == "" && req.LatestBefore == nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "backup_id or latest_before is required",
+		})
+	}
+
+	result, err := dbService.RestoreDatabase(ctx, c.Param("dbId"), &req, &user.ID)
+	if err != nil {
+		return databaseRestoreError(c, err)
+	}
+
+	logAudit(user, "database.restore", result.Database.Name, map[string]interface{}{
+		"backup_id": result.BackupID,
+		"server_id": result.Database.ServerID,
+	})
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// databaseRestoreError maps the error of a database restore to a response
+func databaseRestoreError(c echo.Context, err error) error {
+	if err == sql.ErrNoRows || errors.Is(err, os.ErrNotExist) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	c.Logger().Error("Failed to restore database backup: ", err)
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": "Failed to restore backup: " + err.Error(),
+	})
+}
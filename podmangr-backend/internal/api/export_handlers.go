@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"podmangr-backend/internal/models"
+	"podmangr-backend/internal/system"
+	"podmangr-backend/internal/translator"
+)
+
+// exportRequest is the body of the multi-container export endpoint
+type exportRequest struct {
+	Containers     []string `json:"containers"`
+	Project        string   `json:"project"`
+	Format         string   `json:"format"`          // "compose" (default), "kube", "quadlet"
+	IncludeSecrets bool     `json:"include_secrets"` // Keep sensitive environment values (admin only)
+}
+
+// exportResponse is a translation result plus the redacted variables
+type exportResponse struct {
+	*translator.TranslationResult
+	Redacted []string `json:"redacted"`
+}
+
+// parseExportFormat maps the format parameter to a translator format
+func parseExportFormat(format string) (translator.OutputFormat, bool) {
+	switch format {
+	case "", "compose", "podman-compose":
+		return translator.FormatPodmanCompose, true
+	case "kube":
+		return translator.FormatKube, true
+	case "quadlet":
+		return translator.FormatQuadlet, true
+	}
+	return "", false
+}
+
+// exportQueryOptions reads the export options of the GET endpoints
+func exportQueryOptions(c echo.Context) system.ExportOptions {
+	return system.ExportOptions{
+		Project:        c.QueryParam("project"),
+		IncludeSecrets: c.QueryParam("include_secrets") == "true",
+	}
+}
+
+// invalidExportFormat is the response for an unknown format
+func invalidExportFormat(c echo.Context) error {
+	return c.JSON(http.StatusBadRequest, map[string]string{
+		"error": "Invalid format. Supported: compose, kube, quadlet",
+	})
+}
+
+// respondExport renders an export in the requested format
+func respondExport(c echo.Context, export *system.ExportResult, format translator.OutputFormat, err error) error {
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to export: " + err.Error(),
+		})
+	}
+
+	result, err := translator.NewTranslator().Render(export.Compose, format, translator.Options{})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to render export: " + err.Error(),
+		})
+	}
+	result.Warnings = append(export.Warnings, result.Warnings...)
+
+	return c.JSON(http.StatusOK, exportResponse{TranslationResult: result, Redacted: export.Redacted})
+}
+
+// canExportSecrets reports whether the user may include secret values
+func canExportSecrets(c echo.Context, include bool) bool {
+	user := c.Get("user").(*models.User)
+	return !include || user.IsAdmin()
+}
+
+// secretsForbidden is the response when a non-admin asks for secret values
+func secretsForbidden(c echo.Context) error {
+	return c.JSON(http.StatusForbidden, map[string]string{
+		"error": "Only admins can export secret values",
+	})
+}
+
+// exportContainerHandler exports a single container
+func exportContainerHandler(c echo.Context) error {
+	format, ok := parseExportFormat(c.QueryParam("format"))
+	if !ok {
+		return invalidExportFormat(c)
+	}
+	opts := exportQueryOptions(c)
+	if !canExportSecrets(c, opts.IncludeSecrets) {
+		return secretsForbidden(c)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
+	defer cancel()
+
+	id := resolveContainerID(c.Param("id"))
+	export, err := getPodmanService(c).ExportContainers(ctx, []string{id}, opts)
+	return respondExport(c, export, format, err)
+}
+
+// exportContainersHandler exports a set of containers as one project
+func exportContainersHandler(c echo.Context) error {
+	var req exportRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+	if len(req.Containers) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "At least one container is required",
+		})
+	}
+	format, ok := parseExportFormat(req.Format)
+	if !ok {
+		return invalidExportFormat(c)
+	}
+	if !canExportSecrets(c, req.IncludeSecrets) {
+		return secretsForbidden(c)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 60*time.Second)
+	defer cancel()
+
+	ids := make([]string, 0, len(req.Containers))
+	for _, id := range req.Containers {
+		ids = append(ids, resolveContainerID(id))
+	}
+	export, err := getPodmanService(c).ExportContainers(ctx, ids, system.ExportOptions{
+		Project:        req.Project,
+		IncludeSecrets: req.IncludeSecrets,
+	})
+	return respondExport(c, export, format, err)
+}
+
+// exportPodHandler exports the containers of a pod
+func exportPodHandler(c echo.Context) error {
+	format, ok := parseExportFormat(c.QueryParam("format"))
+	if !ok {
+		return invalidExportFormat(c)
+	}
+	opts := exportQueryOptions(c)
+	if !canExportSecrets(c, opts.IncludeSecrets) {
+		return secretsForbidden(c)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 60*time.Second)
+	defer cancel()
+
+	export, err := getPodmanService(c).ExportPod(ctx, c.Param("id"), opts)
+	return respondExport(c, export, format, err)
+}
+
+// exportStackHandler exports the running containers of a stack
+func exportStackHandler(c echo.Context) error {
+	format, ok := parseExportFormat(c.QueryParam("format"))
+	if !ok {
+		return invalidExportFormat(c)
+	}
+	opts := exportQueryOptions(c)
+	if !canExportSecrets(c, opts.IncludeSecrets) {
+		return secretsForbidden(c)
+	}
+
+	stack, err := stackRepo.GetByID(c.Param("id"))
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Stack not found",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get stack: " + err.Error(),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 60*time.Second)
+	defer cancel()
+
+	export, err := getPodmanService(c).ExportStack(ctx, stack.Name, opts)
+	return respondExport(c, export, format, err)
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+
+	"podmangr-backend/internal/models"
+	"podmangr-backend/internal/system"
+)
+
+// buildTimeout bounds a single image build
+const buildTimeout = time.Hour
+
+// uploadBuildContextHandler stores a tar or tar.gz build context for a later
+// build, sent as the request body or as the "context" field of a form
+// POST /api/images/build/context
+func uploadBuildContextHandler(c echo.Context) error {
+	var body io.Reader = c.Request().Body
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		file, err := c.FormFile("context")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Missing context file: " + err.Error(),
+			})
+		}
+		src, err := file.Open()
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to read context file: " + err.Error(),
+			})
+		}
+		defer src.Close()
+		body = src
+	}
+
+	buildContext, err := system.SaveBuildContext(body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Failed to store build context: " + err.Error(),
+		})
+	}
+	return c.JSON(http.StatusCreated, buildContext)
+}
+
+// buildImageWSHandler builds an image via WebSocket, streaming the build output
+// GET /api/images/build/ws
+func buildImageWSHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+	ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	// The first message is the build request
+	_, message, err := ws.ReadMessage()
+	if err != nil {
+		return err
+	}
+
+	var req models.BuildImageRequest
+	if err := json.Unmarshal(message, &req); err != nil {
+		ws.WriteJSON(map[string]interface{}{
+			"step":    "error",
+			"message": "Invalid build request: " + err.Error(),
+			"error":   true,
+		})
+		return nil
+	}
+
+	user := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), buildTimeout)
+	defer cancel()
+
+	ws.WriteJSON(map[string]interface{}{
+		"step":    "build",
+		"message": "Building image...",
+		"error":   false,
+	})
+
+	outputChan := make(chan string, 100)
+	type buildResult struct {
+		id  string
+		err error
+	}
+	resultChan := make(chan buildResult, 1)
+	go func() {
+		id, err := podmanService.BuildImage(ctx, &req, outputChan)
+		resultChan <- buildResult{id, err}
+	}()
+
+	for line := range outputChan {
+		ws.WriteJSON(map[string]interface{}{
+			"step":    "build",
+			"message": line,
+			"error":   false,
+			"output":  true,
+		})
+	}
+
+	result := <-resultChan
+	if result.err != nil {
+		ws.WriteJSON(map[string]interface{}{
+			"step":     "build",
+			"message":  "Build failed: " + result.err.Error(),
+			"error":    true,
+			"complete": true,
+			"success":  false,
+		})
+		return nil
+	}
+
+	logAudit(user, models.ActionImageBuild, strings.Join(req.Tags, ", "), map[string]interface{}{
+		"image_id": result.id,
+		"git_url":  req.GitURL,
+		"context":  req.ContextDir,
+	})
+	scanPulledImage(podmanService, result.id)
+
+	ws.WriteJSON(map[string]interface{}{
+		"step":     "build",
+		"message":  "Image built successfully",
+		"error":    false,
+		"complete": true,
+		"success":  true,
+		"image_id": result.id,
+	})
+	return nil
+}
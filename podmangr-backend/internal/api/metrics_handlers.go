@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"podmangr-backend/internal/database"
+	"podmangr-backend/internal/models"
+	"podmangr-backend/internal/system"
+)
+
+var metricsCollector *system.MetricsCollector
+
+// StartMetricsCollector starts the background container metrics sampler
+func StartMetricsCollector() {
+	metricsCollector = system.NewMetricsCollector()
+	metricsCollector.Start()
+}
+
+// MetricsSettings represents the metrics collection configuration
+type MetricsSettings struct {
+	Enabled         bool `json:"enabled"`
+	IntervalSeconds int  `json:"interval_seconds"`
+	RetentionHours  int  `json:"retention_hours"`
+}
+
+// loadMetricsSettings reads the metrics settings, applying defaults for missing keys
+func loadMetricsSettings() MetricsSettings {
+	settingsRepo := database.NewSettingsRepo()
+
+	settings := MetricsSettings{
+		Enabled:         true,
+		IntervalSeconds: 60,
+		RetentionHours:  720,
+	}
+	if _, err := settingsRepo.Get(database.SettingMetricsEnabled); err == nil {
+		settings.Enabled, _ = settingsRepo.GetBool(database.SettingMetricsEnabled)
+	}
+	if v, err := settingsRepo.GetInt(database.SettingMetricsInterval); err == nil && v > 0 {
+		settings.IntervalSeconds = v
+	}
+	if v, err := settingsRepo.GetInt(database.SettingMetricsRetention); err == nil && v > 0 {
+		settings.RetentionHours = v
+	}
+	return settings
+}
+
+// getMetricsSettingsHandler returns the metrics collection settings
+func getMetricsSettingsHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, loadMetricsSettings())
+}
+
+// updateMetricsSettingsHandler updates the metrics collection settings
+func updateMetricsSettingsHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+
+	var settings MetricsSettings
+	if err := c.Bind(&settings); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	if settings.IntervalSeconds < 10 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "interval_seconds must be at least 10",
+		})
+	}
+	if settings.RetentionHours < 1 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "retention_hours must be at least 1",
+		})
+	}
+
+	settingsRepo := database.NewSettingsRepo()
+	values := map[string]string{
+		database.SettingMetricsEnabled:   strconv.FormatBool(settings.Enabled),
+		database.SettingMetricsInterval:  strconv.Itoa(settings.IntervalSeconds),
+		database.SettingMetricsRetention: strconv.Itoa(settings.RetentionHours),
+	}
+	for key, value := range values {
+		if err := settingsRepo.Set(key, value); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to save settings: " + err.Error(),
+			})
+		}
+	}
+
+	logAudit(user, "metrics.settings.update", "metrics_settings", map[string]interface{}{
+		"enabled":          settings.Enabled,
+		"interval_seconds": settings.IntervalSeconds,
+		"retention_hours":  settings.RetentionHours,
+	})
+
+	return c.JSON(http.StatusOK, settings)
+}
@@ -10,6 +10,7 @@ import (
 
 // listPodsHandler returns all pods
 func listPodsHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
@@ -25,6 +26,7 @@ func listPodsHandler(c echo.Context) error {
 
 // createPodHandler creates a new pod
 func createPodHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	var req struct {
 		Name         string            `json:"name"`
 		PortMappings []string          `json:"port_mappings"`
@@ -61,6 +63,7 @@ func createPodHandler(c echo.Context) error {
 
 // startPodHandler starts a pod
 func startPodHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	podID := c.Param("id")
 	if podID == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -84,6 +87,7 @@ func startPodHandler(c echo.Context) error {
 
 // stopPodHandler stops a pod
 func stopPodHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	podID := c.Param("id")
 	if podID == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -107,6 +111,7 @@ func stopPodHandler(c echo.Context) error {
 
 // restartPodHandler restarts a pod
 func restartPodHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	podID := c.Param("id")
 	if podID == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -130,6 +135,7 @@ func restartPodHandler(c echo.Context) error {
 
 // removePodHandler removes a pod
 func removePodHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	podID := c.Param("id")
 	if podID == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -155,6 +161,7 @@ func removePodHandler(c echo.Context) error {
 
 // inspectPodHandler returns detailed pod information
 func inspectPodHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	podID := c.Param("id")
 	if podID == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -177,6 +184,7 @@ func inspectPodHandler(c echo.Context) error {
 
 // pausePodHandler pauses all containers in a pod
 func pausePodHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	podID := c.Param("id")
 	if podID == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -200,6 +208,7 @@ func pausePodHandler(c echo.Context) error {
 
 // unpausePodHandler unpauses all containers in a pod
 func unpausePodHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	podID := c.Param("id")
 	if podID == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"podmangr-backend/internal/database"
+	"podmangr-backend/internal/models"
+	"podmangr-backend/internal/system"
+)
+
+const (
+	// podmanSocketHeader selects the Podman socket for a single request
+	podmanSocketHeader = "X-Podman-Socket"
+	// podmanSocketQueryParam selects the socket where headers can't be set (WebSockets)
+	podmanSocketQueryParam = "socket"
+	// podmanSocketPreference is the user_preferences key holding the user's chosen socket
+	podmanSocketPreference = "podman_socket"
+
+	contextKeyPodmanService  = "podman_service"
+	contextKeyPodmanSocketID = "podman_socket_id"
+)
+
+// PodmanContext resolves which Podman socket a request operates on and stores
+// the matching service in the echo context. The socket is taken from, in order:
+// the X-Podman-Socket header, the ?socket= query parameter, the user's saved
+// preference and finally the registry default. Only admins may select a
+// rootful socket other than the default (see canUseSocket). Must be used
+// after RequireAuth.
+func PodmanContext() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			registry := system.GetSocketRegistry()
+			user, _ := c.Get("user").(*models.User)
+
+			// An explicitly requested socket must exist
+			socketID := c.Request().Header.Get(podmanSocketHeader)
+			if socketID == "" {
+				socketID = c.QueryParam(podmanSocketQueryParam)
+			}
+			if socketID != "" {
+				svc, err := registry.GetService(socketID)
+				if err != nil {
+					return c.JSON(http.StatusBadRequest, map[string]string{
+						"error": err.Error(),
+					})
+				}
+				if !canUseSocket(user, socketID, svc) {
+					return c.JSON(http.StatusForbidden, map[string]string{
+						"error": "Insufficient permissions for socket " + socketID,
+					})
+				}
+				c.Set(contextKeyPodmanService, svc)
+				c.Set(contextKeyPodmanSocketID, socketID)
+				return next(c)
+			}
+
+			// A stale preference (socket went away or is no longer allowed)
+			// silently falls back to the default
+			if user != nil {
+				if preferred := getPreferredSocket(user.ID); preferred != "" {
+					if svc, err := registry.GetService(preferred); err == nil && canUseSocket(user, preferred, svc) {
+						c.Set(contextKeyPodmanService, svc)
+						c.Set(contextKeyPodmanSocketID, preferred)
+						return next(c)
+					}
+				}
+			}
+
+			c.Set(contextKeyPodmanService, registry.GetCurrentService())
+			c.Set(contextKeyPodmanSocketID, registry.GetCurrentSocketID())
+			return next(c)
+		}
+	}
+}
+
+// canUseSocket reports whether a user may select a socket. Rootful Podman
+// acts as root on the host, so only admins may pick a rootful socket; others
+// may pick rootless sockets and the server default an admin chose for them.
+func canUseSocket(user *models.User, socketID string, svc *system.PodmanService) bool {
+	if user != nil && user.IsAdmin() {
+		return true
+	}
+	return svc.GetMode() == "rootless" || socketID == system.GetSocketRegistry().GetCurrentSocketID()
+}
+
+// getPodmanService returns the Podman service selected for this request
+func getPodmanService(c echo.Context) *system.PodmanService {
+	if svc, ok := c.Get(contextKeyPodmanService).(*system.PodmanService); ok && svc != nil {
+		return svc
+	}
+	return system.GetSocketRegistry().GetCurrentService()
+}
+
+// getPodmanSocketID returns the socket ID selected for this request
+func getPodmanSocketID(c echo.Context) string {
+	if id, ok := c.Get(contextKeyPodmanSocketID).(string); ok && id != "" {
+		return id
+	}
+	return system.GetSocketRegistry().GetCurrentSocketID()
+}
+
+// getPreferredSocket reads the user's saved Podman socket from user_preferences
+func getPreferredSocket(userID int64) string {
+	var prefsJSON string
+	err := database.DB.QueryRow(
+		"SELECT preferences FROM user_preferences WHERE user_id = ?",
+		userID,
+	).Scan(&prefsJSON)
+	if err != nil {
+		return ""
+	}
+
+	var prefs map[string]interface{}
+	if err := json.Unmarshal([]byte(prefsJSON), &prefs); err != nil {
+		return ""
+	}
+
+	socketID, _ := prefs[podmanSocketPreference].(string)
+	return socketID
+}
+
+// setPreferredSocket stores the user's Podman socket in user_preferences,
+// merging it with any other saved preferences
+func setPreferredSocket(userID int64, socketID string) error {
+	var existingJSON string
+	err := database.DB.QueryRow(
+		"SELECT preferences FROM user_preferences WHERE user_id = ?",
+		userID,
+	).Scan(&existingJSON)
+
+	prefs := make(map[string]interface{})
+	if err == nil {
+		json.Unmarshal([]byte(existingJSON), &prefs)
+	}
+
+	if socketID == "" {
+		delete(prefs, podmanSocketPreference)
+	} else {
+		prefs[podmanSocketPreference] = socketID
+	}
+
+	prefsJSON, err := json.Marshal(prefs)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.DB.Exec(`
+		INSERT INTO user_preferences (user_id, preferences, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id) DO UPDATE SET
+			preferences = excluded.preferences,
+			updated_at = CURRENT_TIMESTAMP
+	`, userID, string(prefsJSON))
+	return err
+}
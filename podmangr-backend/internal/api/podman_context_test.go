@@ -0,0 +1,105 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"podmangr-backend/internal/models"
+	"podmangr-backend/internal/system"
+)
+
+func TestPodmanContextExplicitSocket(t *testing.T) {
+	e := echo.New()
+	registry := system.GetSocketRegistry()
+	defaultID := registry.GetCurrentSocketID()
+
+	var gotID string
+	var gotService *system.PodmanService
+	handler := PodmanContext()(func(c echo.Context) error {
+		gotID = getPodmanSocketID(c)
+		gotService = getPodmanService(c)
+		return c.NoContent(http.StatusOK)
+	})
+
+	// Header selection
+	req := httptest.NewRequest(http.MethodGet, "/api/containers", nil)
+	req.Header.Set(podmanSocketHeader, defaultID)
+	rec := httptest.NewRecorder()
+	if err := handler(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if gotID != defaultID || gotService == nil {
+		t.Errorf("Expected socket %q to be selected, got %q", defaultID, gotID)
+	}
+
+	// Query parameter selection (used by WebSocket endpoints)
+	gotID = ""
+	req = httptest.NewRequest(http.MethodGet, "/api/containers?socket="+defaultID, nil)
+	rec = httptest.NewRecorder()
+	if err := handler(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if gotID != defaultID {
+		t.Errorf("Expected socket %q from query param, got %q", defaultID, gotID)
+	}
+}
+
+func TestPodmanContextUnknownSocket(t *testing.T) {
+	e := echo.New()
+
+	called := false
+	handler := PodmanContext()(func(c echo.Context) error {
+		called = true
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/containers", nil)
+	req.Header.Set(podmanSocketHeader, "user:does-not-exist")
+	rec := httptest.NewRecorder()
+	if err := handler(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+
+	if called {
+		t.Error("Handler should not run for an unknown socket")
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestCanUseSocket(t *testing.T) {
+	admin := &models.User{Role: models.RoleAdmin}
+	operator := &models.User{Role: models.RoleOperator}
+	viewer := &models.User{Role: models.RoleViewer}
+	rootful := system.NewPodmanServiceWithSocket("", "/run/podman/podman.sock")
+	rootless := system.NewPodmanServiceWithSocket("alice", "/run/user/1000/podman/podman.sock")
+	defaultID := system.GetSocketRegistry().GetCurrentSocketID()
+
+	tests := []struct {
+		name     string
+		user     *models.User
+		socketID string
+		svc      *system.PodmanService
+		want     bool
+	}{
+		{"admin rootful", admin, "test-root", rootful, true},
+		{"operator rootful", operator, "test-root", rootful, false},
+		{"viewer rootful", viewer, "test-root", rootful, false},
+		{"operator rootless", operator, "user:alice", rootless, true},
+		{"viewer default", viewer, defaultID, rootful, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canUseSocket(tt.user, tt.socketID, tt.svc); got != tt.want {
+				t.Errorf("canUseSocket = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
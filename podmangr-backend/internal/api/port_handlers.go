@@ -18,6 +18,7 @@ type PortInfo struct {
 
 // listUsedPortsHandler returns all ports currently in use by containers
 func listUsedPortsHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 	defer cancel()
 
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"podmangr-backend/internal/models"
+	"podmangr-backend/internal/system"
+)
+
+var pruneScheduler *system.PruneScheduler
+
+// StartPruneScheduler starts the scheduled prunes of the prune policy
+func StartPruneScheduler() {
+	pruneScheduler = system.NewPruneScheduler()
+	pruneScheduler.Start()
+}
+
+// diskUsageHandler returns podman system df style storage accounting
+// GET /api/disk-usage
+func diskUsageHandler(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Minute)
+	defer cancel()
+
+	usage, err := getPodmanService(c).DiskUsage(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get disk usage: " + err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, usage)
+}
+
+// runPrune validates and runs a prune, auditing it unless it is a dry run
+func runPrune(c echo.Context, req models.PruneRequest) error {
+	if err := system.ValidatePruneRequest(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Minute)
+	defer cancel()
+
+	result, err := getPodmanService(c).Prune(ctx, req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to prune: " + err.Error(),
+		})
+	}
+
+	if !req.DryRun {
+		user := c.Get("user").(*models.User)
+		logAudit(user, "system.prune", "storage", map[string]interface{}{
+			"images":        req.Images,
+			"keep_versions": req.KeepVersions,
+			"containers":    req.Containers,
+			"volumes":       req.Volumes,
+			"networks":      req.Networks,
+			"removed":       len(result.Items) - result.Failed,
+			"reclaimed":     result.Reclaimed,
+		})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// pruneHandler prunes any combination of images, containers, volumes and networks
+// POST /api/prune
+func pruneHandler(c echo.Context) error {
+	var req models.PruneRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+	return runPrune(c, req)
+}
+
+// pruneImagesHandler removes dangling images, or every unused one with
+// all=true, and with keep=N the unused versions beyond the N newest
+// POST /api/images/prune?dry_run=true&all=true&keep=3
+func pruneImagesHandler(c echo.Context) error {
+	req := models.PruneRequest{
+		Images: models.PruneImagesDangling,
+		DryRun: c.QueryParam("dry_run") == "true",
+	}
+	if c.QueryParam("all") == "true" {
+		req.Images = models.PruneImagesUnused
+	}
+	if keep := c.QueryParam("keep"); keep != "" {
+		n, err := strconv.Atoi(keep)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "keep must be a number",
+			})
+		}
+		req.KeepVersions = n
+	}
+	return runPrune(c, req)
+}
+
+// pruneContainersHandler removes stopped containers
+// POST /api/containers/prune?dry_run=true
+func pruneContainersHandler(c echo.Context) error {
+	return runPrune(c, models.PruneRequest{Containers: true, DryRun: c.QueryParam("dry_run") == "true"})
+}
+
+// pruneVolumesHandler removes volumes no container uses
+// POST /api/volumes/prune?dry_run=true
+func pruneVolumesHandler(c echo.Context) error {
+	return runPrune(c, models.PruneRequest{Volumes: true, DryRun: c.QueryParam("dry_run") == "true"})
+}
+
+// pruneNetworksHandler removes networks no container uses
+// POST /api/podman-networks/prune?dry_run=true
+func pruneNetworksHandler(c echo.Context) error {
+	return runPrune(c, models.PruneRequest{Networks: true, DryRun: c.QueryParam("dry_run") == "true"})
+}
+
+// getPrunePolicyHandler returns the scheduled prune policy
+func getPrunePolicyHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, system.LoadPrunePolicy())
+}
+
+// updatePrunePolicyHandler updates the scheduled prune policy
+func updatePrunePolicyHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+
+	var policy models.PrunePolicy
+	if err := c.Bind(&policy); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+	if err := system.ValidatePrunePolicy(policy); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	if err := system.SavePrunePolicy(policy); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to save settings: " + err.Error(),
+		})
+	}
+
+	logAudit(user, "prune.policy.update", "prune_policy", map[string]interface{}{
+		"enabled":       policy.Enabled,
+		"schedule":      policy.Schedule,
+		"images":        policy.Images,
+		"keep_versions": policy.KeepVersions,
+		"containers":    policy.Containers,
+		"volumes":       policy.Volumes,
+		"networks":      policy.Networks,
+	})
+
+	return c.JSON(http.StatusOK, system.LoadPrunePolicy())
+}
@@ -0,0 +1,260 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"podmangr-backend/internal/models"
+	"podmangr-backend/internal/quadlet"
+)
+
+// quadletBundleResponse is the file bundle returned by the Quadlet endpoints
+type quadletBundleResponse struct {
+	Files []quadlet.File `json:"files"`
+}
+
+// respondQuadlet writes a generated Quadlet bundle or the generation error
+func respondQuadlet(c echo.Context, files []quadlet.File, err error) error {
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to generate Quadlet units: " + err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, quadletBundleResponse{Files: files})
+}
+
+// getContainerQuadletHandler returns Quadlet units that recreate a container
+func getContainerQuadletHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
+	id := resolveContainerID(c.Param("id"))
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
+	defer cancel()
+
+	files, err := podmanService.GenerateContainerQuadlet(ctx, id)
+	return respondQuadlet(c, files, err)
+}
+
+// getPodQuadletHandler returns Quadlet units that recreate a pod and its containers
+func getPodQuadletHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
+	podID := c.Param("id")
+	if podID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Pod ID is required",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
+	defer cancel()
+
+	files, err := podmanService.GeneratePodQuadlet(ctx, podID)
+	return respondQuadlet(c, files, err)
+}
+
+// getVolumeQuadletHandler returns the Quadlet unit for a named volume
+func getVolumeQuadletHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
+	name := c.Param("name")
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	files, err := podmanService.GenerateVolumeQuadlet(ctx, name)
+	return respondQuadlet(c, files, err)
+}
+
+// getNetworkQuadletHandler returns the Quadlet unit for a Podman network
+func getNetworkQuadletHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
+	name := c.Param("name")
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	files, err := podmanService.GenerateNetworkQuadlet(ctx, name)
+	return respondQuadlet(c, files, err)
+}
+
+// persistRequest is the body of the "make persistent" endpoints. Files may
+// carry an edited preview; when empty the units are generated fresh.
+type persistRequest struct {
+	Files []quadlet.File `json:"files"`
+	Start *bool          `json:"start"` // Start the services after installing (default true)
+}
+
+// installQuadletFiles installs a bundle through the request's Podman service
+func installQuadletFiles(c echo.Context, target string, files []quadlet.File, start *bool) error {
+	podmanService := getPodmanService(c)
+	user := c.Get("user").(*models.User)
+
+	result, err := podmanService.InstallQuadlet(files, start == nil || *start)
+	if err != nil {
+		response := map[string]interface{}{
+			"error": "Failed to install Quadlet units: " + err.Error(),
+		}
+		if result != nil {
+			// Files were written; report them so the caller can retry or clean up
+			response["result"] = result
+		}
+		return c.JSON(http.StatusInternalServerError, response)
+	}
+
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	logAudit(user, models.ActionQuadletInstall, target, map[string]interface{}{
+		"files":     names,
+		"directory": result.Directory,
+	})
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// persistContainerHandler installs Quadlet units for a container so systemd manages it
+func persistContainerHandler(c echo.Context) error {
+	var req persistRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	id := resolveContainerID(c.Param("id"))
+	if len(req.Files) == 0 {
+		ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
+		defer cancel()
+
+		files, err := getPodmanService(c).GenerateContainerQuadlet(ctx, id)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to generate Quadlet units: " + err.Error(),
+			})
+		}
+		req.Files = files
+	}
+
+	return installQuadletFiles(c, id, req.Files, req.Start)
+}
+
+// persistPodHandler installs Quadlet units for a pod and its containers
+func persistPodHandler(c echo.Context) error {
+	var req persistRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	podID := c.Param("id")
+	if len(req.Files) == 0 {
+		ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
+		defer cancel()
+
+		files, err := getPodmanService(c).GeneratePodQuadlet(ctx, podID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to generate Quadlet units: " + err.Error(),
+			})
+		}
+		req.Files = files
+	}
+
+	return installQuadletFiles(c, podID, req.Files, req.Start)
+}
+
+// installQuadletHandler installs an arbitrary bundle of Quadlet units
+func installQuadletHandler(c echo.Context) error {
+	var req persistRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	if len(req.Files) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "At least one unit file is required",
+		})
+	}
+
+	return installQuadletFiles(c, "quadlet", req.Files, req.Start)
+}
+
+// listQuadletsHandler lists installed Quadlet units with their service status
+func listQuadletsHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
+
+	units, err := podmanService.ListQuadlets()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to list Quadlet units: " + err.Error(),
+		})
+	}
+
+	dir, _ := podmanService.QuadletDir()
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"directory": dir,
+		"scope":     podmanService.SystemdScope(),
+		"units":     units,
+	})
+}
+
+// getQuadletHandler returns the service status and journal of an installed unit
+func getQuadletHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
+
+	status, err := podmanService.GetQuadletStatus(c.Param("file"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+// quadletActionHandler starts, stops or restarts the service of an installed unit
+func quadletActionHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
+	user := c.Get("user").(*models.User)
+	file := c.Param("file")
+	action := c.Param("action")
+
+	if err := podmanService.QuadletAction(file, action); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	logAudit(user, models.ActionQuadletControl, file, map[string]interface{}{
+		"action": action,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"status": action,
+	})
+}
+
+// removeQuadletHandler stops an installed unit and deletes its file
+func removeQuadletHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
+	user := c.Get("user").(*models.User)
+	file := c.Param("file")
+
+	if err := podmanService.RemoveQuadlet(file); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	logAudit(user, models.ActionQuadletRemove, file, nil)
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"status": "removed",
+	})
+}
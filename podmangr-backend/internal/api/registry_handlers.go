@@ -0,0 +1,382 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"podmangr-backend/internal/database"
+	"podmangr-backend/internal/models"
+	"podmangr-backend/internal/system"
+)
+
+var registryCredentialRepo *database.RegistryCredentialRepo
+
+// InitRegistryCredentials initializes the registry credential repository
+func InitRegistryCredentials() {
+	registryCredentialRepo = database.NewRegistryCredentialRepo()
+}
+
+// saveRegistrySecret stores the password or token of a registry login in the
+// secrets store, replacing the one it had
+func saveRegistrySecret(cred *models.RegistryCredential, value string, userID int64) error {
+	encrypted, err := secretsEncryption.Encrypt(value)
+	if err != nil {
+		return err
+	}
+
+	description := "Login of " + cred.Username + " at registry " + cred.Registry
+
+	if cred.SecretID != "" {
+		if secret, err := secretsRepo.GetByID(cred.SecretID); err == nil {
+			secret.ValueEncrypted = encrypted
+			secret.Description = &description
+			return secretsRepo.Update(secret)
+		}
+	}
+
+	category := models.RegistryCredentialSecretCategory
+	secret := &models.Secret{
+		Name:           "Registry " + cred.Registry,
+		ValueEncrypted: encrypted,
+		SecretType:     models.SecretTypeAPIKey,
+		Category:       &category,
+		Description:    &description,
+		CreatedBy:      &userID,
+	}
+	if err := secretsRepo.Create(secret); err != nil {
+		return err
+	}
+	cred.SecretID = secret.ID
+	cred.HasSecret = true
+	return nil
+}
+
+// bindRegistryLogin reads and normalizes a registry login request. On failure
+// the error response has been written.
+func bindRegistryLogin(c echo.Context) (*models.RegistryLoginRequest, bool) {
+	var req models.RegistryLoginRequest
+	if err := c.Bind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+		return nil, false
+	}
+	req.Registry = system.NormalizeRegistry(req.Registry)
+	if err := system.ValidateRegistry(req.Registry); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+	return &req, true
+}
+
+// registryLoginError writes the response for a rejected or failed login check
+func registryLoginError(c echo.Context, err error) error {
+	if errors.Is(err, system.ErrRegistryUnauthorized) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Login failed: " + err.Error(),
+		})
+	}
+	return c.JSON(http.StatusBadGateway, map[string]string{
+		"error": "Login failed: " + err.Error(),
+	})
+}
+
+// listRegistryCredentialsHandler returns the registry logins stored for the
+// selected Podman socket
+// GET /api/registries
+func listRegistryCredentialsHandler(c echo.Context) error {
+	credentials, err := registryCredentialRepo.List(getPodmanService(c).RegistrySocketID())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to list registry credentials: " + err.Error(),
+		})
+	}
+	if credentials == nil {
+		credentials = []models.RegistryCredential{}
+	}
+	return c.JSON(http.StatusOK, credentials)
+}
+
+// registryLoginHandler checks a login against a registry and stores it, so
+// pulls from that registry use it on the selected Podman socket
+// POST /api/registries/login
+func registryLoginHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+
+	req, ok := bindRegistryLogin(c)
+	if !ok {
+		return nil
+	}
+	if req.Username == "" || req.Password == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Username and password are required",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Minute)
+	defer cancel()
+	if err := system.CheckRegistryLogin(ctx, req.Registry, req.Username, req.Password, req.Insecure); err != nil {
+		return registryLoginError(c, err)
+	}
+
+	socketID := getPodmanService(c).RegistrySocketID()
+	cred, err := registryCredentialRepo.GetByRegistry(socketID, req.Registry)
+	created := err == sql.ErrNoRows
+	if created {
+		cred = &models.RegistryCredential{SocketID: socketID, Registry: req.Registry, CreatedBy: &user.ID}
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get registry credential: " + err.Error(),
+		})
+	}
+	cred.Username = req.Username
+	cred.Insecure = req.Insecure
+
+	if err := saveRegistrySecret(cred, req.Password, user.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to store credential: " + err.Error(),
+		})
+	}
+	if created {
+		err = registryCredentialRepo.Create(cred)
+	} else {
+		err = registryCredentialRepo.Update(cred)
+	}
+	if err != nil {
+		if created {
+			secretsRepo.Delete(cred.SecretID)
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to save registry credential: " + err.Error(),
+		})
+	}
+
+	if err := system.RefreshRegistryAuthFiles(); err != nil {
+		c.Logger().Error("Failed to refresh registry auth files: ", err)
+	}
+
+	logAudit(user, "registry.login", cred.Registry, map[string]interface{}{
+		"socket":   cred.SocketID,
+		"username": cred.Username,
+		"insecure": cred.Insecure,
+	})
+
+	return c.JSON(http.StatusOK, cred)
+}
+
+// testRegistryLoginHandler checks a login against a registry without storing
+// it. Without a password, the login stored for the selected socket is checked.
+// POST /api/registries/test
+func testRegistryLoginHandler(c echo.Context) error {
+	req, ok := bindRegistryLogin(c)
+	if !ok {
+		return nil
+	}
+
+	username, password, insecure := req.Username, req.Password, req.Insecure
+	if password == "" {
+		cred, err := registryCredentialRepo.GetByRegistry(getPodmanService(c).RegistrySocketID(), req.Registry)
+		if err != nil || cred.SecretID == "" {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "No stored login for " + req.Registry,
+			})
+		}
+		secret, err := secretsRepo.GetByID(cred.SecretID)
+		if err == nil {
+			password, err = secretsEncryption.Decrypt(secret.ValueEncrypted)
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to load stored credential: " + err.Error(),
+			})
+		}
+		username, insecure = cred.Username, cred.Insecure
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Minute)
+	defer cancel()
+	if err := system.CheckRegistryLogin(ctx, req.Registry, username, password, insecure); err != nil {
+		return registryLoginError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]string{
+		"status": "ok",
+	})
+}
+
+// registryLogoutHandler removes the login of a registry stored for the
+// selected Podman socket and its token
+// POST /api/registries/logout
+func registryLogoutHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+
+	var req models.RegistryLogoutRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	cred, err := registryCredentialRepo.GetByRegistry(getPodmanService(c).RegistrySocketID(), system.NormalizeRegistry(req.Registry))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Not logged in to " + req.Registry,
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get registry credential: " + err.Error(),
+		})
+	}
+
+	if err := registryCredentialRepo.Delete(cred.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to delete registry credential: " + err.Error(),
+		})
+	}
+	if cred.SecretID != "" {
+		if err := secretsRepo.Delete(cred.SecretID); err != nil {
+			c.Logger().Error("Failed to delete registry credential secret: ", err)
+		}
+	}
+	if err := system.RefreshRegistryAuthFiles(); err != nil {
+		c.Logger().Error("Failed to refresh registry auth files: ", err)
+	}
+
+	logAudit(user, "registry.logout", cred.Registry, map[string]interface{}{
+		"socket": cred.SocketID,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"status": "logged out",
+	})
+}
+
+// registryBrowseError writes the response for a failed registry lookup
+func registryBrowseError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, system.ErrRegistryNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Not found in registry",
+		})
+	case errors.Is(err, system.ErrRegistryUnauthorized):
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "Registry denied access; log in to it first",
+		})
+	}
+	return c.JSON(http.StatusBadGateway, map[string]string{
+		"error": "Registry request failed: " + err.Error(),
+	})
+}
+
+// registryClientForImage parses the image query parameter and returns a
+// client for its registry. On failure the error response has been written.
+func registryClientForImage(c echo.Context) (*system.RegistryClient, string, string, bool) {
+	image := c.QueryParam("image")
+	if image == "" {
+		c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "image parameter is required",
+		})
+		return nil, "", "", false
+	}
+	registry, repository, reference := system.ParseImageReference(image)
+	client, err := system.NewRegistryClient(registry, getPodmanService(c).RegistrySocketID())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return nil, "", "", false
+	}
+	return client, repository, reference, true
+}
+
+// listRegistryTagsHandler lists the tags of an image's repository, with the
+// higher versions of the image's own tag
+// GET /api/registries/tags?image=nginx:1.27
+func listRegistryTagsHandler(c echo.Context) error {
+	client, repository, reference, ok := registryClientForImage(c)
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Minute)
+	defer cancel()
+	tags, err := client.ListTags(ctx, repository)
+	if err != nil {
+		return registryBrowseError(c, err)
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+
+	result := models.RegistryTags{
+		Registry:   client.Registry(),
+		Repository: repository,
+		Tags:       tags,
+	}
+	if !strings.Contains(reference, ":") {
+		result.Current = reference
+		result.NewerTags = system.NewerTags(reference, tags)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// inspectRegistryImageHandler describes a tag or digest in a registry: its
+// digest and each platform's image with creation date and size
+// GET /api/registries/image?image=nginx:1.27
+func inspectRegistryImageHandler(c echo.Context) error {
+	client, repository, reference, ok := registryClientForImage(c)
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Minute)
+	defer cancel()
+	image, err := client.InspectImage(ctx, repository, reference)
+	if err != nil {
+		return registryBrowseError(c, err)
+	}
+	return c.JSON(http.StatusOK, image)
+}
+
+// searchRegistryHandler searches the catalog of a registry. Docker Hub has
+// no catalog, so its searches go to the Docker Hub search API.
+// GET /api/registries/search?registry=ghcr.io&query=app
+func searchRegistryHandler(c echo.Context) error {
+	registry := system.NormalizeRegistry(c.QueryParam("registry"))
+	if registry == "" || registry == "docker.io" {
+		return searchDockerHubHandler(c)
+	}
+	query := c.QueryParam("query")
+	if query == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "query parameter is required",
+		})
+	}
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 25
+	}
+
+	client, err := system.NewRegistryClient(registry, getPodmanService(c).RegistrySocketID())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Minute)
+	defer cancel()
+	results, err := client.SearchCatalog(ctx, query, limit)
+	if err != nil {
+		return registryBrowseError(c, err)
+	}
+	return c.JSON(http.StatusOK, results)
+}
@@ -15,6 +15,25 @@ func RegisterRoutes(api *echo.Group, authSvc *auth.Service) {
 	InitContainerRepos()
 	InitStackRepo()
 
+	// Background sampler feeding /containers/:id/metrics
+	StartMetricsCollector()
+
+	// Scheduled image update checks and automatic updates
+	StartUpdateScheduler()
+
+	// Scheduled backup jobs with retention policies, optionally stored off-host
+	InitBackupDestinations()
+	StartBackupScheduler()
+
+	// Image vulnerability scans after pulls and on a schedule
+	StartVulnerabilityScanner()
+
+	// Scheduled prunes of unused images, containers, volumes and networks
+	StartPruneScheduler()
+
+	// Private registry logins, written to an auth file per Podman socket user
+	InitRegistryCredentials()
+
 	// Initialize database service (for two-tier database management)
 	if err := InitDatabaseService(); err != nil {
 		// Log warning but don't fail - database management is optional
@@ -145,12 +164,26 @@ func RegisterRoutes(api *echo.Group, authSvc *auth.Service) {
 	// Docker Hub image search (public endpoint with auth)
 	api.GET("/dockerhub/search", searchDockerHubHandler, auth.RequireAuth(authSvc))
 
+	// Podman-backed routes resolve their socket per request (header, ?socket=,
+	// user preference, then server default) so users don't affect each other
+	podmanCtx := PodmanContext()
+
+	// Registry logins of the selected socket, applied to its pulls and update checks (read: all, write: admin)
+	api.GET("/registries", listRegistryCredentialsHandler, auth.RequireAuth(authSvc), podmanCtx)
+	api.GET("/registries/search", searchRegistryHandler, auth.RequireAuth(authSvc), podmanCtx)
+	api.GET("/registries/tags", listRegistryTagsHandler, auth.RequireAuth(authSvc), podmanCtx)
+	api.GET("/registries/image", inspectRegistryImageHandler, auth.RequireAuth(authSvc), podmanCtx)
+	api.POST("/registries/login", registryLoginHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin), podmanCtx)
+	api.POST("/registries/test", testRegistryLoginHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin), podmanCtx)
+	api.POST("/registries/logout", registryLogoutHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin), podmanCtx)
+
 	// Port information endpoint (requires auth)
-	api.GET("/ports/used", listUsedPortsHandler, auth.RequireAuth(authSvc))
+	api.GET("/ports/used", listUsedPortsHandler, auth.RequireAuth(authSvc), podmanCtx)
 
 	// Container management routes (Phase 2B)
 	containers := api.Group("/containers")
 	containers.Use(auth.RequireAuth(authSvc))
+	containers.Use(podmanCtx)
 
 	// Podman availability check
 	containers.GET("/check", checkPodmanHandler)
@@ -164,6 +197,7 @@ func RegisterRoutes(api *echo.Group, authSvc *auth.Service) {
 	containers.POST("", createContainerHandler, auth.RequireRole(models.RoleAdmin))
 	containers.POST("/adopt", adoptContainerHandler, auth.RequireRole(models.RoleAdmin)) // Adopt existing containers
 	containers.POST("/validate", validateContainerHandler, auth.RequireRole(models.RoleAdmin))
+	containers.POST("/prune", pruneContainersHandler, auth.RequireRole(models.RoleAdmin)) // Remove stopped containers
 	containers.GET("/deploy", deployContainerHandler, auth.RequireRole(models.RoleAdmin)) // WebSocket
 	containers.PUT("/:id", updateContainerHandler, auth.RequireRole(models.RoleAdmin))
 	containers.DELETE("/:id", removeContainerHandler, auth.RequireRole(models.RoleAdmin))
@@ -176,19 +210,67 @@ func RegisterRoutes(api *echo.Group, authSvc *auth.Service) {
 	containers.GET("/:id/exec", execContainerHandler)              // WebSocket: terminal shell
 	containers.GET("/:id/stats", getContainerStatsHandler)
 	containers.GET("/:id/metrics", getContainerMetricsHandler)
+	containers.GET("/:id/quadlet", getContainerQuadletHandler)                                 // Generate Quadlet units
+	containers.POST("/:id/persist", persistContainerHandler, auth.RequireRole(models.RoleAdmin)) // Install Quadlet units
+	containers.GET("/:id/export", exportContainerHandler)                                        // Export as compose, kube or quadlet
+	containers.POST("/export", exportContainersHandler)                                          // Export several containers as one project
+
+	// Metrics collection settings (read: all, write: admin)
+	api.GET("/metrics/settings", getMetricsSettingsHandler, auth.RequireAuth(authSvc))
+	api.PUT("/metrics/settings", updateMetricsSettingsHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
 
 	// Container update & backup routes
 	containers.GET("/:id/config", getContainerConfigHandler)                                        // Get full container config
 	containers.GET("/:id/backups", listContainerBackupsHandler)                                     // List backups
 	containers.GET("/:id/check-update", checkContainerUpdateHandler)                                // Check for image updates
 	containers.GET("/:id/update", updateContainerImageHandler, auth.RequireRole(models.RoleAdmin))  // WebSocket: update container
+	containers.GET("/:id/updates", listContainerUpdatesHandler)                                     // Update history
+	containers.PUT("/:id/update-policy", setContainerUpdatePolicyHandler, auth.RequireRole(models.RoleAdmin)) // Scheduled update policy
 	containers.DELETE("/backups/:backup_id", deleteContainerBackupHandler, auth.RequireRole(models.RoleAdmin)) // Delete backup
 	containers.POST("/backups/:backup_id/restore", restoreContainerBackupHandler, auth.RequireRole(models.RoleAdmin)) // Restore backup
+	containers.POST("/backups/:backup_id/verify", verifyContainerBackupHandler, auth.RequireRole(models.RoleAdmin))   // Verify checksums
 
 	// Global backup management (admin only)
-	api.GET("/backups", listAllBackupsHandler, auth.RequireAuth(authSvc))                           // List all backups
+	api.GET("/backups", listAllBackupsHandler, auth.RequireAuth(authSvc), podmanCtx)                // List all backups
 	api.GET("/backups/settings", getBackupSettingsHandler, auth.RequireAuth(authSvc))               // Get backup settings
 	api.PUT("/backups/settings", updateBackupSettingsHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin)) // Update backup settings
+	api.POST("/backups/prune", pruneBackupsHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))           // Apply retention
+
+	// Scheduled backup jobs (read: all, write: admin)
+	api.GET("/backups/jobs", listBackupJobsHandler, auth.RequireAuth(authSvc))
+	api.POST("/backups/jobs", createBackupJobHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
+	api.GET("/backups/jobs/:id", getBackupJobHandler, auth.RequireAuth(authSvc))
+	api.PUT("/backups/jobs/:id", updateBackupJobHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
+	api.DELETE("/backups/jobs/:id", deleteBackupJobHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
+	api.POST("/backups/jobs/:id/run", runBackupJobHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
+	api.GET("/backups/jobs/:id/runs", listBackupJobRunsHandler, auth.RequireAuth(authSvc))
+
+	// Off-host backup destinations (read: all, write and transfers: admin)
+	api.GET("/backups/destinations", listBackupDestinationsHandler, auth.RequireAuth(authSvc))
+	api.POST("/backups/destinations", createBackupDestinationHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
+	api.GET("/backups/destinations/:id", getBackupDestinationHandler, auth.RequireAuth(authSvc))
+	api.PUT("/backups/destinations/:id", updateBackupDestinationHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
+	api.DELETE("/backups/destinations/:id", deleteBackupDestinationHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
+	api.POST("/backups/destinations/:id/test", testBackupDestinationHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
+	api.GET("/backups/destinations/:id/backups", listRemoteBackupsHandler, auth.RequireAuth(authSvc))
+	api.POST("/backups/destinations/:id/backups", uploadBackupHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
+	api.DELETE("/backups/destinations/:id/backups/:backup_id", deleteRemoteBackupHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
+	api.POST("/backups/destinations/:id/backups/:backup_id/download", downloadRemoteBackupHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
+	api.POST("/backups/destinations/:id/backups/:backup_id/restore", restoreRemoteBackupHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin), podmanCtx)
+
+	// Scheduled image update checks (read: all, write: admin)
+	api.GET("/image-updates", listUpdateStatesHandler, auth.RequireAuth(authSvc))
+	api.POST("/image-updates/check", runUpdateCheckHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
+	api.GET("/image-updates/settings", getUpdateSettingsHandler, auth.RequireAuth(authSvc))
+	api.PUT("/image-updates/settings", updateUpdateSettingsHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
+
+	// Vulnerability database and scan reports
+	api.GET("/vulnerabilities/reports", listVulnerabilityReportsHandler, auth.RequireAuth(authSvc))
+	api.POST("/vulnerabilities/scan", runVulnerabilityScanHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
+	api.GET("/vulnerabilities/database", getVulnerabilityDatabaseHandler, auth.RequireAuth(authSvc))
+	api.POST("/vulnerabilities/database/import", importVulnerabilityDatabaseHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
+	api.GET("/vulnerabilities/settings", getScanSettingsHandler, auth.RequireAuth(authSvc))
+	api.PUT("/vulnerabilities/settings", updateScanSettingsHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
 
 	// Container web UI proxy (proxies to container's web interface)
 	containers.Any("/:id/proxy", proxyContainerWebUIHandler)
@@ -197,39 +279,61 @@ func RegisterRoutes(api *echo.Group, authSvc *auth.Service) {
 	// Image management (read: all, write: admin)
 	images := api.Group("/images")
 	images.Use(auth.RequireAuth(authSvc))
+	images.Use(podmanCtx)
 	images.GET("", listImagesHandler)
 	images.GET("/inspect", inspectImageHandler)      // Check if image exists and get config
 	images.GET("/inspect/ws", inspectImageWSHandler) // WebSocket: pull + inspect with progress
 	images.POST("/pull", pullImageHandler, auth.RequireRole(models.RoleAdmin))
+	images.POST("/prune", pruneImagesHandler, auth.RequireRole(models.RoleAdmin)) // Remove dangling, unused or old versions
+	images.POST("/build/context", uploadBuildContextHandler, auth.RequireRole(models.RoleAdmin))
+	images.GET("/build/ws", buildImageWSHandler, auth.RequireRole(models.RoleAdmin)) // WebSocket: build with streamed output
 	images.DELETE("/:id", removeImageHandler, auth.RequireRole(models.RoleAdmin))
+	images.GET("/:id/vulnerabilities", getImageVulnerabilitiesHandler)
+	images.POST("/:id/scan", scanImageHandler, auth.RequireRole(models.RoleAdmin))
 
 	// Volume management (read: all, write: admin)
 	volumes := api.Group("/volumes")
 	volumes.Use(auth.RequireAuth(authSvc))
+	volumes.Use(podmanCtx)
 	volumes.GET("", listVolumesHandler)
 	volumes.POST("", createVolumeHandler, auth.RequireRole(models.RoleAdmin))
+	volumes.POST("/prune", pruneVolumesHandler, auth.RequireRole(models.RoleAdmin))
 	volumes.DELETE("/:name", removeVolumeHandler, auth.RequireRole(models.RoleAdmin))
+	volumes.GET("/:name/quadlet", getVolumeQuadletHandler)
 
 	// Podman storage configuration (admin only)
-	api.GET("/storage-config", getStorageConfigHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
-	api.PUT("/storage-config", updateStorageConfigHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
+	api.GET("/storage-config", getStorageConfigHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin), podmanCtx)
+	api.PUT("/storage-config", updateStorageConfigHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin), podmanCtx)
+
+	// Storage accounting and pruning (prune: admin only, ?dry_run=true previews)
+	api.GET("/disk-usage", diskUsageHandler, auth.RequireAuth(authSvc), podmanCtx)
+	api.POST("/prune", pruneHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin), podmanCtx)
+	api.GET("/prune/policy", getPrunePolicyHandler, auth.RequireAuth(authSvc))
+	api.PUT("/prune/policy", updatePrunePolicyHandler, auth.RequireAuth(authSvc), auth.RequireRole(models.RoleAdmin))
 
 	// Bind mounts endpoint (aggregates bind mounts from all containers)
-	api.GET("/bind-mounts", listBindMountsHandler, auth.RequireAuth(authSvc))
+	api.GET("/bind-mounts", listBindMountsHandler, auth.RequireAuth(authSvc), podmanCtx)
 
 	// Podman network management (read: all, write: admin)
 	podmanNetworks := api.Group("/podman-networks")
 	podmanNetworks.Use(auth.RequireAuth(authSvc))
+	podmanNetworks.Use(podmanCtx)
 	podmanNetworks.GET("", listPodmanNetworksHandler)
 	podmanNetworks.POST("", createPodmanNetworkHandler, auth.RequireRole(models.RoleAdmin))
+	podmanNetworks.POST("/prune", pruneNetworksHandler, auth.RequireRole(models.RoleAdmin))
 	podmanNetworks.DELETE("/:name", removePodmanNetworkHandler, auth.RequireRole(models.RoleAdmin))
+	podmanNetworks.GET("/:name/quadlet", getNetworkQuadletHandler)
 
 	// Pod management (read: all, write: admin)
 	pods := api.Group("/pods")
 	pods.Use(auth.RequireAuth(authSvc))
+	pods.Use(podmanCtx)
 	pods.GET("", listPodsHandler)
 	pods.POST("", createPodHandler, auth.RequireRole(models.RoleAdmin))
 	pods.GET("/:id/inspect", inspectPodHandler)
+	pods.GET("/:id/quadlet", getPodQuadletHandler)
+	pods.POST("/:id/persist", persistPodHandler, auth.RequireRole(models.RoleAdmin))
+	pods.GET("/:id/export", exportPodHandler)
 	pods.POST("/:id/start", startPodHandler, auth.RequireRole(models.RoleAdmin))
 	pods.POST("/:id/stop", stopPodHandler, auth.RequireRole(models.RoleAdmin))
 	pods.POST("/:id/restart", restartPodHandler, auth.RequireRole(models.RoleAdmin))
@@ -237,11 +341,24 @@ func RegisterRoutes(api *echo.Group, authSvc *auth.Service) {
 	pods.POST("/:id/unpause", unpausePodHandler, auth.RequireRole(models.RoleAdmin))
 	pods.DELETE("/:id", removePodHandler, auth.RequireRole(models.RoleAdmin))
 
-	// Podman socket/context management (read: all, switch: admin)
+	// Quadlet units managed through systemd (read: all, write: admin)
+	quadlets := api.Group("/quadlets")
+	quadlets.Use(auth.RequireAuth(authSvc))
+	quadlets.Use(podmanCtx)
+	quadlets.GET("", listQuadletsHandler)
+	quadlets.POST("", installQuadletHandler, auth.RequireRole(models.RoleAdmin))
+	quadlets.GET("/:file", getQuadletHandler)
+	quadlets.POST("/:file/:action", quadletActionHandler, auth.RequireRole(models.RoleAdmin))
+	quadlets.DELETE("/:file", removeQuadletHandler, auth.RequireRole(models.RoleAdmin))
+
+	// Podman socket/context management (read/switch own view: all, rootful sockets
+	// other than the default: admin, server default: admin)
 	sockets := api.Group("/podman-sockets")
 	sockets.Use(auth.RequireAuth(authSvc))
+	sockets.Use(podmanCtx)
 	sockets.GET("", listSocketsHandler)
-	sockets.POST("/switch", switchSocketHandler, auth.RequireRole(models.RoleAdmin))
+	sockets.POST("/switch", switchSocketHandler)
+	sockets.POST("/default", setDefaultSocketHandler, auth.RequireRole(models.RoleAdmin))
 	sockets.POST("/refresh", refreshSocketsHandler, auth.RequireRole(models.RoleAdmin))
 
 	// Template management (read: all, write: admin)
@@ -259,9 +376,12 @@ func RegisterRoutes(api *echo.Group, authSvc *auth.Service) {
 	// Stack management (compose-based deployments)
 	stacks := api.Group("/stacks")
 	stacks.Use(auth.RequireAuth(authSvc))
+	stacks.Use(podmanCtx)
 	stacks.GET("", listStacksHandler)
 	stacks.GET("/:id", getStackHandler)
 	stacks.GET("/:id/containers", getStackContainersHandler)
+	stacks.GET("/:id/config", getStackConfigHandler) // Resolved compose file
+	stacks.GET("/:id/export", exportStackHandler) // Compose/kube/quadlet from the running containers
 	stacks.POST("", createStackHandler, auth.RequireRole(models.RoleAdmin))
 	stacks.PUT("/:id", updateStackHandler, auth.RequireRole(models.RoleAdmin))
 	stacks.DELETE("/:id", deleteStackHandler, auth.RequireRole(models.RoleAdmin))
@@ -272,7 +392,7 @@ func RegisterRoutes(api *echo.Group, authSvc *auth.Service) {
 	stacks.GET("/:id/pull", pullStackHandler, auth.RequireRole(models.RoleAdmin)) // WebSocket
 
 	// Desktop apps endpoint (containers with web UIs)
-	api.GET("/desktop-apps", listDesktopAppsHandler, auth.RequireAuth(authSvc))
+	api.GET("/desktop-apps", listDesktopAppsHandler, auth.RequireAuth(authSvc), podmanCtx)
 
 	// Translation engine (Docker Compose -> Podman formats)
 	translate := api.Group("/translate")
@@ -295,6 +415,16 @@ func RegisterRoutes(api *echo.Group, authSvc *auth.Service) {
 	dbServers.POST("/:id/start", startDatabaseServerHandler, auth.RequireRole(models.RoleAdmin))
 	dbServers.POST("/:id/stop", stopDatabaseServerHandler, auth.RequireRole(models.RoleAdmin))
 	dbServers.DELETE("/:id", deleteDatabaseServerHandler, auth.RequireRole(models.RoleAdmin))
+	dbServers.PUT("/:id", updateDatabaseServerHandler, auth.RequireRole(models.RoleAdmin))
+	dbServers.POST("/:id/upgrade", upgradeDatabaseServerHandler, auth.RequireRole(models.RoleAdmin))
+
+	// Logical dumps of whole servers and single databases (admin)
+	dbServers.GET("/:id/backups", listDatabaseServerBackupsHandler, auth.RequireRole(models.RoleAdmin))
+	dbServers.POST("/:id/backups", createDatabaseServerBackupHandler, auth.RequireRole(models.RoleAdmin))
+	dbServers.POST("/:id/backups/restore", restoreDatabaseServerBackupHandler, auth.RequireRole(models.RoleAdmin))
+	dbServers.GET("/:id/databases/:dbId/backups", listDatabaseBackupsHandler, auth.RequireRole(models.RoleAdmin))
+	dbServers.POST("/:id/databases/:dbId/backups", createDatabaseBackupHandler, auth.RequireRole(models.RoleAdmin))
+	dbServers.POST("/:id/databases/:dbId/backups/restore", restoreDatabaseBackupHandler, auth.RequireRole(models.RoleAdmin))
 
 	// Database operations within servers (read: all, write: admin)
 	dbServers.GET("/:id/databases", listDatabasesHandler)
@@ -302,6 +432,14 @@ func RegisterRoutes(api *echo.Group, authSvc *auth.Service) {
 	dbServers.DELETE("/:id/databases/:dbId", deleteDatabaseHandler, auth.RequireRole(models.RoleAdmin))
 	dbServers.GET("/:id/databases/:dbId/connection", getDatabaseConnectionHandler)
 
+	// Database users and live insight (admin only)
+	dbServers.GET("/:id/databases/:dbId/users", listDatabaseUsersHandler, auth.RequireRole(models.RoleAdmin))
+	dbServers.POST("/:id/databases/:dbId/users", createDatabaseUserHandler, auth.RequireRole(models.RoleAdmin))
+	dbServers.DELETE("/:id/databases/:dbId/users/:username", deleteDatabaseUserHandler, auth.RequireRole(models.RoleAdmin))
+	dbServers.POST("/:id/databases/:dbId/users/:username/rotate-password", rotateDatabasePasswordHandler, auth.RequireRole(models.RoleAdmin))
+	dbServers.GET("/:id/databases/:dbId/sessions", listDatabaseSessionsHandler, auth.RequireRole(models.RoleAdmin))
+	dbServers.GET("/:id/databases/:dbId/stats", getDatabaseStatsHandler, auth.RequireRole(models.RoleAdmin))
+
 	// Secrets Store (encrypted credentials for containers)
 	if err := InitSecretsService(); err != nil {
 		println("Warning: Failed to initialize secrets service:", err.Error())
@@ -5,29 +5,42 @@ import (
 
 	"github.com/labstack/echo/v4"
 
+	"podmangr-backend/internal/models"
 	"podmangr-backend/internal/system"
 )
 
-// listSocketsHandler returns all available Podman sockets
+// socketSummary describes the socket a request is using
+func socketSummary(socketID string, svc *system.PodmanService) map[string]interface{} {
+	return map[string]interface{}{
+		"id":              socketID,
+		"mode":            svc.GetMode(),
+		"target_user":     svc.GetTargetUser(),
+		"running_as_root": svc.IsRunningAsRoot(),
+		"api_socket":      svc.GetSocketPath(),
+	}
+}
+
+// listSocketsHandler returns the available Podman sockets the user may select
 func listSocketsHandler(c echo.Context) error {
 	registry := system.GetSocketRegistry()
-	sockets := registry.ListSockets()
+	sockets := allowedSockets(c.Get("user").(*models.User), registry.ListSockets())
 
-	currentID := registry.GetCurrentSocketID()
-	currentService := registry.GetCurrentService()
+	// Active marks the socket this user is looking at, not the server default
+	currentID := getPodmanSocketID(c)
+	for i := range sockets {
+		sockets[i].Active = sockets[i].ID == currentID
+	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"sockets": sockets,
-		"current": map[string]interface{}{
-			"id":           currentID,
-			"mode":         currentService.GetMode(),
-			"target_user":  currentService.GetTargetUser(),
-			"running_as_root": currentService.IsRunningAsRoot(),
-		},
+		"sockets":    sockets,
+		"current":    socketSummary(currentID, getPodmanService(c)),
+		"default_id": registry.GetCurrentSocketID(),
 	})
 }
 
-// switchSocketHandler changes the active Podman socket
+// switchSocketHandler changes the Podman socket used by the current user.
+// The choice is stored as a user preference and does not affect other users.
+// An empty socket_id clears the preference so the server default is used.
 func switchSocketHandler(c echo.Context) error {
 	var req struct {
 		SocketID string `json:"socket_id"`
@@ -39,6 +52,53 @@ func switchSocketHandler(c echo.Context) error {
 		})
 	}
 
+	user := c.Get("user").(*models.User)
+	registry := system.GetSocketRegistry()
+
+	svc := registry.GetCurrentService()
+	socketID := registry.GetCurrentSocketID()
+	if req.SocketID != "" {
+		var err error
+		svc, err = registry.GetService(req.SocketID)
+		if err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		if !canUseSocket(user, req.SocketID, svc) {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Insufficient permissions for socket " + req.SocketID,
+			})
+		}
+		socketID = req.SocketID
+	}
+
+	if err := setPreferredSocket(user.ID, req.SocketID); err != nil {
+		c.Logger().Error("failed to save socket preference: ", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to save socket preference",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Socket switched successfully",
+		"current": socketSummary(socketID, svc),
+	})
+}
+
+// setDefaultSocketHandler changes the server-wide default socket, used by
+// requests that don't select one and users without a saved preference
+func setDefaultSocketHandler(c echo.Context) error {
+	var req struct {
+		SocketID string `json:"socket_id"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
 	if req.SocketID == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "socket_id is required",
@@ -53,19 +113,9 @@ func switchSocketHandler(c echo.Context) error {
 		})
 	}
 
-	// Update the global podmanService to use the new socket
-	podmanService = registry.GetCurrentService()
-
-	currentService := registry.GetCurrentService()
-
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "Socket switched successfully",
-		"current": map[string]interface{}{
-			"id":           req.SocketID,
-			"mode":         currentService.GetMode(),
-			"target_user":  currentService.GetTargetUser(),
-			"running_as_root": currentService.IsRunningAsRoot(),
-		},
+		"message": "Default socket changed successfully",
+		"default": socketSummary(req.SocketID, registry.GetCurrentService()),
 	})
 }
 
@@ -74,21 +124,38 @@ func refreshSocketsHandler(c echo.Context) error {
 	registry := system.GetSocketRegistry()
 	registry.RefreshSockets()
 
-	// Update the global podmanService
-	podmanService = registry.GetCurrentService()
+	// Resolve again: the socket chosen by the middleware may have gone away
+	currentID := getPodmanSocketID(c)
+	currentService, err := registry.GetService(currentID)
+	if err != nil {
+		currentID = registry.GetCurrentSocketID()
+		currentService = registry.GetCurrentService()
+	}
 
 	sockets := registry.ListSockets()
-	currentID := registry.GetCurrentSocketID()
-	currentService := registry.GetCurrentService()
+	for i := range sockets {
+		sockets[i].Active = sockets[i].ID == currentID
+	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "Sockets refreshed",
-		"sockets": sockets,
-		"current": map[string]interface{}{
-			"id":           currentID,
-			"mode":         currentService.GetMode(),
-			"target_user":  currentService.GetTargetUser(),
-			"running_as_root": currentService.IsRunningAsRoot(),
-		},
+		"message":    "Sockets refreshed",
+		"sockets":    sockets,
+		"current":    socketSummary(currentID, currentService),
+		"default_id": registry.GetCurrentSocketID(),
 	})
 }
+
+// allowedSockets filters sockets down to those the user may select
+func allowedSockets(user *models.User, sockets []system.PodmanSocket) []system.PodmanSocket {
+	if user.IsAdmin() {
+		return sockets
+	}
+	defaultID := system.GetSocketRegistry().GetCurrentSocketID()
+	allowed := make([]system.PodmanSocket, 0, len(sockets))
+	for _, s := range sockets {
+		if s.Mode == "rootless" || s.ID == defaultID {
+			allowed = append(allowed, s)
+		}
+	}
+	return allowed
+}
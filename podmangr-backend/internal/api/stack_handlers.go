@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -14,6 +15,8 @@ import (
 
 	"podmangr-backend/internal/database"
 	"podmangr-backend/internal/models"
+	"podmangr-backend/internal/system"
+	"podmangr-backend/internal/translator"
 )
 
 var stackRepo *database.StackRepo
@@ -35,8 +38,8 @@ func ensureStackDir(stackName string) (string, error) {
 	return dir, nil
 }
 
-// writeComposeFiles writes the compose and env files to the stack directory
-func writeComposeFiles(dir, composeContent, envContent string) error {
+// writeComposeFiles writes the compose, override and env files to the stack directory
+func writeComposeFiles(dir, composeContent, envContent, overrideContent string) error {
 	// Write docker-compose.yml
 	composePath := filepath.Join(dir, "docker-compose.yml")
 	if err := os.WriteFile(composePath, []byte(composeContent), 0644); err != nil {
@@ -51,11 +54,98 @@ func writeComposeFiles(dir, composeContent, envContent string) error {
 		}
 	}
 
+	// Write docker-compose.override.yml, removing a stale one
+	overridePath := filepath.Join(dir, stackOverrideFile)
+	if overrideContent != "" {
+		if err := os.WriteFile(overridePath, []byte(overrideContent), 0644); err != nil {
+			return fmt.Errorf("failed to write override file: %w", err)
+		}
+	} else if err := os.Remove(overridePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove override file: %w", err)
+	}
+
 	return nil
 }
 
+const stackOverrideFile = "docker-compose.override.yml"
+
+// planStack loads a stack's compose files and plans its Podman objects
+func planStack(stack *models.Stack) (*translator.Plan, []string, error) {
+	compose, warnings, err := loadStackCompose(stack)
+	if err != nil {
+		return nil, warnings, err
+	}
+	plan, planWarnings, err := translator.BuildPlan(compose, stack.Path)
+	return plan, append(warnings, planWarnings...), err
+}
+
+// streamStackEvents runs a stack operation and forwards its events to the
+// WebSocket, with a readable output line for clients that only show text
+func streamStackEvents(ws *websocket.Conn, run func(events chan<- system.StackEvent) error) error {
+	events := make(chan system.StackEvent, 100)
+	done := make(chan error, 1)
+	go func() {
+		done <- run(events)
+		close(events)
+	}()
+
+	for ev := range events {
+		line := fmt.Sprintf("[%s] %s: %s", ev.Step, ev.Resource, ev.Status)
+		if ev.Message != "" {
+			line += " (" + ev.Message + ")"
+		}
+		ws.WriteJSON(map[string]interface{}{
+			"event":  ev,
+			"output": line,
+		})
+	}
+	return <-done
+}
+
+// loadStackCompose resolves a stack's compose and override files with its
+// .env variables. Files referenced by include and extends are read from the
+// stack directory and may not leave it.
+func loadStackCompose(stack *models.Stack) (*translator.ComposeFile, []string, error) {
+	files := []translator.ConfigFile{{Path: "docker-compose.yml", Content: stack.ComposeContent}}
+	if stack.OverrideContent != "" {
+		files = append(files, translator.ConfigFile{Path: stackOverrideFile, Content: stack.OverrideContent})
+	}
+	dir := stack.Path
+	if dir == "" {
+		dir = filepath.Join(stacksBaseDir, stack.Name)
+	}
+	return translator.Load(files, translator.LoadOptions{
+		Project: stack.Name,
+		Env:     translator.ParseEnv(stack.EnvContent),
+		ReadFile: func(path string) ([]byte, error) {
+			return readStackFile(dir, path)
+		},
+	})
+}
+
+// readStackFile reads a file below a stack directory. Paths leading out of
+// the directory are rejected, also through symlinks.
+func readStackFile(dir, path string) ([]byte, error) {
+	path = filepath.Clean(path)
+	if !filepath.IsLocal(path) {
+		return nil, fmt.Errorf("%s is outside the stack directory", path)
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+	f, err := root.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
 // listStacksHandler returns all stacks
 func listStacksHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	stacks, err := stackRepo.List()
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
@@ -97,6 +187,7 @@ func listStacksHandler(c echo.Context) error {
 
 // getStackHandler returns a stack by ID
 func getStackHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 
 	stack, err := stackRepo.GetByID(id)
@@ -130,6 +221,7 @@ func getStackHandler(c echo.Context) error {
 
 // getStackContainersHandler returns containers belonging to a stack
 func getStackContainersHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 
 	stack, err := stackRepo.GetByID(id)
@@ -157,6 +249,43 @@ func getStackContainersHandler(c echo.Context) error {
 	return c.JSON(http.StatusOK, containers)
 }
 
+// getStackConfigHandler returns the stack's compose file as it will be
+// deployed: overrides merged, variables interpolated, profiles applied
+func getStackConfigHandler(c echo.Context) error {
+	stack, err := stackRepo.GetByID(c.Param("id"))
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Stack not found",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get stack: " + err.Error(),
+		})
+	}
+
+	compose, warnings, err := loadStackCompose(stack)
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":    "Invalid compose file: " + err.Error(),
+			"warnings": warnings,
+		})
+	}
+
+	result, err := translator.NewTranslator().Render(compose, translator.FormatPodmanCompose, translator.Options{})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to render compose file: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"compose":  result.Output,
+		"services": compose.ServiceNames(),
+		"warnings": warnings,
+	})
+}
+
 // createStackHandler creates a new stack
 func createStackHandler(c echo.Context) error {
 	var req models.CreateStackRequest
@@ -180,6 +309,25 @@ func createStackHandler(c echo.Context) error {
 		})
 	}
 
+	user := c.Get("user").(*models.User)
+
+	stack := &models.Stack{
+		Name:            req.Name,
+		Description:     req.Description,
+		ComposeContent:  req.ComposeContent,
+		EnvContent:      req.EnvContent,
+		OverrideContent: req.OverrideContent,
+		Status:          models.StackStatusStopped,
+		CreatedBy:       &user.ID,
+	}
+
+	// Reject compose files that don't resolve, e.g. a missing required variable
+	if _, _, err := loadStackCompose(stack); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid compose file: " + err.Error(),
+		})
+	}
+
 	// Create stack directory and write files
 	dir, err := ensureStackDir(req.Name)
 	if err != nil {
@@ -187,25 +335,14 @@ func createStackHandler(c echo.Context) error {
 			"error": err.Error(),
 		})
 	}
+	stack.Path = dir
 
-	if err := writeComposeFiles(dir, req.ComposeContent, req.EnvContent); err != nil {
+	if err := writeComposeFiles(dir, req.ComposeContent, req.EnvContent, req.OverrideContent); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
 		})
 	}
 
-	user := c.Get("user").(*models.User)
-
-	stack := &models.Stack{
-		Name:           req.Name,
-		Description:    req.Description,
-		ComposeContent: req.ComposeContent,
-		EnvContent:     req.EnvContent,
-		Status:         models.StackStatusStopped,
-		Path:           dir,
-		CreatedBy:      &user.ID,
-	}
-
 	if err := stackRepo.Create(stack); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to create stack: " + err.Error(),
@@ -253,10 +390,18 @@ func updateStackHandler(c echo.Context) error {
 	if req.EnvContent != nil {
 		stack.EnvContent = *req.EnvContent
 	}
+	if req.OverrideContent != nil {
+		stack.OverrideContent = *req.OverrideContent
+	}
 
 	// Write updated files
-	if req.ComposeContent != nil || req.EnvContent != nil {
-		if err := writeComposeFiles(stack.Path, stack.ComposeContent, stack.EnvContent); err != nil {
+	if req.ComposeContent != nil || req.EnvContent != nil || req.OverrideContent != nil {
+		if _, _, err := loadStackCompose(stack); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid compose file: " + err.Error(),
+			})
+		}
+		if err := writeComposeFiles(stack.Path, stack.ComposeContent, stack.EnvContent, stack.OverrideContent); err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]string{
 				"error": err.Error(),
 			})
@@ -277,6 +422,7 @@ func updateStackHandler(c echo.Context) error {
 
 // deleteStackHandler deletes a stack
 func deleteStackHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 	removeVolumes := c.QueryParam("volumes") == "true"
 
@@ -296,9 +442,7 @@ func deleteStackHandler(c echo.Context) error {
 	defer cancel()
 
 	// Stop and remove containers
-	if stack.Path != "" {
-		podmanService.ComposeDown(ctx, stack.Path, stack.Name, removeVolumes, nil)
-	}
+	podmanService.StackDown(ctx, stack.Name, removeVolumes, nil)
 
 	// Remove stack directory
 	if stack.Path != "" {
@@ -322,6 +466,7 @@ func deleteStackHandler(c echo.Context) error {
 
 // deployStackHandler deploys a stack via WebSocket for streaming output
 func deployStackHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 
 	stack, err := stackRepo.GetByID(id)
@@ -346,7 +491,7 @@ func deployStackHandler(c echo.Context) error {
 					"error": "Failed to create stack directory: " + err.Error(),
 				})
 			}
-			if err := writeComposeFiles(stack.Path, stack.ComposeContent, stack.EnvContent); err != nil {
+			if err := writeComposeFiles(stack.Path, stack.ComposeContent, stack.EnvContent, stack.OverrideContent); err != nil {
 				return c.JSON(http.StatusInternalServerError, map[string]string{
 					"error": "Failed to write compose files: " + err.Error(),
 				})
@@ -358,6 +503,13 @@ func deployStackHandler(c echo.Context) error {
 		}
 	}
 
+	plan, warnings, err := planStack(stack)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid compose file: " + err.Error(),
+		})
+	}
+
 	// Upgrade to WebSocket
 	upgrader := websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool { return true },
@@ -381,24 +533,20 @@ func deployStackHandler(c echo.Context) error {
 		})
 	}
 
-	ctx := c.Request().Context()
-	outputChan := make(chan string, 100)
-
-	// Start goroutine to send output
-	done := make(chan error, 1)
-	go func() {
-		done <- podmanService.ComposeUp(ctx, stack.Path, stack.Name, outputChan)
-		close(outputChan)
-	}()
-
-	// Stream output
-	for line := range outputChan {
+	for _, w := range warnings {
 		ws.WriteJSON(map[string]interface{}{
-			"output": line,
+			"output": "warning: " + w,
 		})
 	}
 
-	deployErr := <-done
+	ctx := c.Request().Context()
+	opts := system.StackUpOptions{
+		RemoveOrphans: true,
+		Build:         c.QueryParam("build") == "true", // Rebuild images of services with a build section
+	}
+	deployErr := streamStackEvents(ws, func(events chan<- system.StackEvent) error {
+		return podmanService.StackUp(ctx, plan, opts, events)
+	})
 
 	if deployErr != nil {
 		stackRepo.UpdateStatus(stack.ID, models.StackStatusError)
@@ -425,6 +573,7 @@ func deployStackHandler(c echo.Context) error {
 
 // stopStackHandler stops a stack
 func stopStackHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 
 	stack, err := stackRepo.GetByID(id)
@@ -442,7 +591,7 @@ func stopStackHandler(c echo.Context) error {
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 60*time.Second)
 	defer cancel()
 
-	if err := podmanService.ComposeStop(ctx, stack.Path, stack.Name); err != nil {
+	if err := podmanService.StackStop(ctx, stack.Name); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to stop stack: " + err.Error(),
 		})
@@ -460,6 +609,7 @@ func stopStackHandler(c echo.Context) error {
 
 // startStackHandler starts a stopped stack
 func startStackHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 
 	stack, err := stackRepo.GetByID(id)
@@ -477,7 +627,7 @@ func startStackHandler(c echo.Context) error {
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 60*time.Second)
 	defer cancel()
 
-	if err := podmanService.ComposeStart(ctx, stack.Path, stack.Name); err != nil {
+	if err := podmanService.StackStart(ctx, stack.Name); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to start stack: " + err.Error(),
 		})
@@ -495,6 +645,7 @@ func startStackHandler(c echo.Context) error {
 
 // restartStackHandler restarts a stack
 func restartStackHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 
 	stack, err := stackRepo.GetByID(id)
@@ -512,7 +663,7 @@ func restartStackHandler(c echo.Context) error {
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 120*time.Second)
 	defer cancel()
 
-	if err := podmanService.ComposeRestart(ctx, stack.Path, stack.Name); err != nil {
+	if err := podmanService.StackRestart(ctx, stack.Name); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to restart stack: " + err.Error(),
 		})
@@ -530,6 +681,7 @@ func restartStackHandler(c echo.Context) error {
 
 // pullStackHandler pulls latest images for a stack via WebSocket
 func pullStackHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
 	id := c.Param("id")
 
 	stack, err := stackRepo.GetByID(id)
@@ -544,6 +696,13 @@ func pullStackHandler(c echo.Context) error {
 		})
 	}
 
+	plan, _, err := planStack(stack)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid compose file: " + err.Error(),
+		})
+	}
+
 	// Upgrade to WebSocket
 	upgrader := websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool { return true },
@@ -555,21 +714,9 @@ func pullStackHandler(c echo.Context) error {
 	defer ws.Close()
 
 	ctx := c.Request().Context()
-	outputChan := make(chan string, 100)
-
-	done := make(chan error, 1)
-	go func() {
-		done <- podmanService.ComposePull(ctx, stack.Path, stack.Name, outputChan)
-		close(outputChan)
-	}()
-
-	for line := range outputChan {
-		ws.WriteJSON(map[string]interface{}{
-			"output": line,
-		})
-	}
-
-	pullErr := <-done
+	pullErr := streamStackEvents(ws, func(events chan<- system.StackEvent) error {
+		return podmanService.StackPull(ctx, plan, events)
+	})
 
 	if pullErr != nil {
 		ws.WriteJSON(map[string]interface{}{
@@ -579,6 +726,11 @@ func pullStackHandler(c echo.Context) error {
 		})
 		return nil
 	}
+	for _, service := range plan.Services {
+		if service.Build == nil {
+			scanPulledImage(podmanService, service.Image)
+		}
+	}
 
 	ws.WriteJSON(map[string]interface{}{
 		"complete": true,
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadStackFile(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "shop")
+	os.MkdirAll(filepath.Join(dir, "common"), 0755)
+	os.WriteFile(filepath.Join(dir, "common", "base.yml"), []byte("services: {}\n"), 0644)
+	os.WriteFile(filepath.Join(base, "secret.yml"), []byte("secret\n"), 0644)
+	os.Symlink(filepath.Join("common", "base.yml"), filepath.Join(dir, "link.yml"))
+	os.Symlink(filepath.Join(base, "secret.yml"), filepath.Join(dir, "escape.yml"))
+
+	for _, path := range []string{"common/base.yml", "common/../link.yml"} {
+		if _, err := readStackFile(dir, path); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+	for _, path := range []string{"../secret.yml", "common/../../secret.yml", "/etc/passwd", "escape.yml"} {
+		if _, err := readStackFile(dir, path); err == nil {
+			t.Errorf("%s: read outside the stack directory", path)
+		}
+	}
+}
@@ -10,8 +10,24 @@ import (
 
 // translateRequest is the request body for translation
 type translateRequest struct {
-	Input  string `json:"input"`
-	Format string `json:"format"` // "podman-compose", "quadlet", "kube"
+	Input     string            `json:"input"`
+	Format    string            `json:"format"`              // "podman-compose", "quadlet", "kube"
+	Project   string            `json:"project,omitempty"`   // Name for the pod, volumes and networks
+	Files     map[string]string `json:"files,omitempty"`     // Contents of referenced env, secret, include and extends files
+	Env       string            `json:"env,omitempty"`       // .env content used for ${VAR} interpolation
+	Profiles  []string          `json:"profiles,omitempty"`  // Active compose profiles
+	Overrides []string          `json:"overrides,omitempty"` // Override files merged over the input, in order
+}
+
+// options builds the translator options of a request
+func (req *translateRequest) options() translator.Options {
+	return translator.Options{
+		Project:   req.Project,
+		Files:     req.Files,
+		Env:       translator.ParseEnv(req.Env),
+		Profiles:  req.Profiles,
+		Overrides: req.Overrides,
+	}
 }
 
 // translateHandler translates Docker Compose to Podman formats
@@ -50,7 +66,7 @@ func translateHandler(c echo.Context) error {
 
 	// Create translator and translate
 	t := translator.NewTranslator()
-	result, err := t.Translate(req.Input, format)
+	result, err := t.TranslateWithOptions(req.Input, format, req.options())
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]interface{}{
 			"error":    err.Error(),
@@ -64,9 +80,7 @@ func translateHandler(c echo.Context) error {
 
 // validateComposeHandler validates a Docker Compose file without translating
 func validateComposeHandler(c echo.Context) error {
-	var req struct {
-		Input string `json:"input"`
-	}
+	var req translateRequest
 
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -80,21 +94,23 @@ func validateComposeHandler(c echo.Context) error {
 		})
 	}
 
-	t := translator.NewTranslator()
-	compose, err := t.Parse(req.Input)
+	compose, warnings, err := req.options().Load(req.Input)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"valid":   false,
-			"error":   err.Error(),
+			"valid":    false,
+			"error":    err.Error(),
+			"warnings": warnings,
 		})
 	}
 
 	// Count services, networks, volumes
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"valid":    true,
-		"services": len(compose.Services),
-		"networks": len(compose.Networks),
-		"volumes":  len(compose.Volumes),
+		"valid":         true,
+		"services":      len(compose.Services),
+		"networks":      len(compose.Networks),
+		"volumes":       len(compose.Volumes),
+		"service_names": compose.ServiceNames(),
+		"warnings":      warnings,
 	})
 }
 
@@ -132,12 +148,12 @@ func getTransformRulesHandler(c echo.Context) error {
 		{
 			"id":          "quadlet",
 			"name":        "Quadlet",
-			"description": "Systemd Quadlet .container files for native systemd integration",
+			"description": "Systemd Quadlet .container, .volume and .network units for native systemd integration",
 		},
 		{
 			"id":          "kube",
 			"name":        "Kubernetes YAML",
-			"description": "Kubernetes Pod manifest for podman kube play",
+			"description": "Kubernetes Pod or Deployment with volume claims, ConfigMaps and Secrets for podman kube play",
 		},
 	}
 
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"podmangr-backend/internal/database"
+	"podmangr-backend/internal/models"
+	"podmangr-backend/internal/system"
+)
+
+var updateScheduler *system.UpdateScheduler
+
+// StartUpdateScheduler starts the background image update checks
+func StartUpdateScheduler() {
+	updateScheduler = system.NewUpdateScheduler()
+	updateScheduler.Start()
+}
+
+// UpdateSettings represents the scheduled update check configuration
+type UpdateSettings struct {
+	Enabled       bool   `json:"enabled"`
+	Schedule      string `json:"schedule"`       // Cron expression, e.g. "0 4 * * *"
+	DefaultPolicy string `json:"default_policy"` // Policy of managed containers without their own
+	NextRun       string `json:"next_run,omitempty"`
+}
+
+// loadUpdateSettings reads the update settings, applying defaults for missing keys
+func loadUpdateSettings() UpdateSettings {
+	settingsRepo := database.NewSettingsRepo()
+
+	settings := UpdateSettings{
+		Enabled:       true,
+		Schedule:      system.DefaultUpdateSchedule,
+		DefaultPolicy: models.UpdatePolicyNotify,
+	}
+	if _, err := settingsRepo.Get(database.SettingUpdatesEnabled); err == nil {
+		settings.Enabled, _ = settingsRepo.GetBool(database.SettingUpdatesEnabled)
+	}
+	if v, err := settingsRepo.Get(database.SettingUpdatesSchedule); err == nil && strings.TrimSpace(v) != "" {
+		settings.Schedule = v
+	}
+	if v, err := settingsRepo.Get(database.SettingUpdatesPolicy); err == nil && system.ValidUpdatePolicy(v) {
+		settings.DefaultPolicy = v
+	}
+	if schedule, err := system.ParseSchedule(settings.Schedule); err == nil && settings.Enabled {
+		settings.NextRun = schedule.Next(time.Now()).Format(time.RFC3339)
+	}
+	return settings
+}
+
+// getUpdateSettingsHandler returns the scheduled update check settings
+func getUpdateSettingsHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, loadUpdateSettings())
+}
+
+// updateUpdateSettingsHandler updates the scheduled update check settings
+func updateUpdateSettingsHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+
+	var settings UpdateSettings
+	if err := c.Bind(&settings); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	if _, err := system.ParseSchedule(settings.Schedule); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid schedule: " + err.Error(),
+		})
+	}
+	if !system.ValidUpdatePolicy(settings.DefaultPolicy) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "default_policy must be disabled, notify, patch or auto",
+		})
+	}
+
+	settingsRepo := database.NewSettingsRepo()
+	values := map[string]string{
+		database.SettingUpdatesEnabled:  strconv.FormatBool(settings.Enabled),
+		database.SettingUpdatesSchedule: settings.Schedule,
+		database.SettingUpdatesPolicy:   settings.DefaultPolicy,
+	}
+	for key, value := range values {
+		if err := settingsRepo.Set(key, value); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to save settings: " + err.Error(),
+			})
+		}
+	}
+
+	logAudit(user, "updates.settings.update", "update_settings", map[string]interface{}{
+		"enabled":        settings.Enabled,
+		"schedule":       settings.Schedule,
+		"default_policy": settings.DefaultPolicy,
+	})
+
+	return c.JSON(http.StatusOK, loadUpdateSettings())
+}
+
+// listUpdateStatesHandler returns the result of the latest update check of each container
+func listUpdateStatesHandler(c echo.Context) error {
+	states, err := updateRepo.ListStates()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to list update states: " + err.Error(),
+		})
+	}
+	if states == nil {
+		states = []models.ContainerUpdateState{}
+	}
+	return c.JSON(http.StatusOK, states)
+}
+
+// runUpdateCheckHandler checks all containers now instead of waiting for the schedule
+func runUpdateCheckHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+
+	// Automatic updates must not be cut short by the client going away
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	states, err := updateScheduler.CheckAll(ctx)
+	logAudit(user, "updates.check", "containers", map[string]interface{}{
+		"checked": len(states),
+	})
+
+	result := map[string]interface{}{
+		"states": states,
+	}
+	if err != nil {
+		result["error"] = err.Error()
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// setContainerUpdatePolicyHandler sets the update policy of a container
+func setContainerUpdatePolicyHandler(c echo.Context) error {
+	podmanService := getPodmanService(c)
+	user := c.Get("user").(*models.User)
+	id := c.Param("id")
+
+	var req struct {
+		Policy string `json:"policy"` // Empty restores the label or default policy
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+	if req.Policy != "" && !system.ValidUpdatePolicy(req.Policy) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "policy must be disabled, notify, patch, auto or empty",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 5*time.Second)
+	defer cancel()
+
+	inspect, err := podmanService.InspectContainer(ctx, resolveContainerID(id))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Container not found",
+		})
+	}
+	name := strings.TrimPrefix(inspect.Name, "/")
+
+	// Policies are kept by socket and name so they survive updates replacing the container
+	if err := updateRepo.SetPolicy(getPodmanSocketID(c), name, req.Policy); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to set update policy: " + err.Error(),
+		})
+	}
+
+	logAudit(user, "container.update_policy", name, map[string]interface{}{
+		"policy": req.Policy,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"container_name": name,
+		"policy":         req.Policy,
+	})
+}
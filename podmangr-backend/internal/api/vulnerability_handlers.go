@@ -0,0 +1,289 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"podmangr-backend/internal/database"
+	"podmangr-backend/internal/models"
+	"podmangr-backend/internal/system"
+)
+
+var (
+	vulnerabilityScanner *system.VulnerabilityScanner
+	vulnerabilityRepo    *database.VulnerabilityRepo
+)
+
+// StartVulnerabilityScanner starts scanning pulled images and the scheduled scans
+func StartVulnerabilityScanner() {
+	vulnerabilityRepo = database.NewVulnerabilityRepo()
+	vulnerabilityScanner = system.NewVulnerabilityScanner()
+	vulnerabilityScanner.Start()
+}
+
+// scanPulledImage queues a scan of an image that was just pulled or built
+func scanPulledImage(podmanService *system.PodmanService, image string) {
+	if vulnerabilityScanner != nil {
+		vulnerabilityScanner.Enqueue(podmanService, image)
+	}
+}
+
+// attachVulnerabilitySummaries adds the summary of each image's latest scan
+func attachVulnerabilitySummaries(images []models.Image) {
+	reports, err := vulnerabilityRepo.ListReports()
+	if err != nil {
+		log.Printf("Failed to list vulnerability reports: %v", err)
+		return
+	}
+	byKey := make(map[string]*models.VulnerabilitySummary, len(reports)*2)
+	for i := range reports {
+		if reports[i].Error != "" {
+			continue
+		}
+		byKey[reports[i].Digest] = &reports[i].Summary
+		byKey[reports[i].ImageID] = &reports[i].Summary
+	}
+	for i := range images {
+		if summary, ok := byKey[images[i].Digest]; ok && images[i].Digest != "" {
+			images[i].Vulnerabilities = summary
+		} else if summary, ok := byKey[images[i].ID]; ok {
+			images[i].Vulnerabilities = summary
+		}
+	}
+}
+
+// getImageVulnerabilitiesHandler returns the latest scan report of an image
+// GET /api/images/:id/vulnerabilities
+func getImageVulnerabilitiesHandler(c echo.Context) error {
+	id := c.Param("id")
+
+	report, err := vulnerabilityRepo.GetReport(id)
+	if err == sql.ErrNoRows {
+		// Short IDs and references are resolved to the stored digest
+		ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+		defer cancel()
+		if _, digest, idErr := getPodmanService(c).ImageIdentity(ctx, id); idErr == nil {
+			report, err = vulnerabilityRepo.GetReport(digest)
+		}
+	}
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Image has not been scanned",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get vulnerability report: " + err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// scanImageHandler inventories an image again and matches it now
+// POST /api/images/:id/scan
+func scanImageHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+	id := c.Param("id")
+
+	report, err := vulnerabilityScanner.ScanImage(c.Request().Context(), getPodmanService(c), id, true)
+	logAudit(user, models.ActionImageScan, id, map[string]interface{}{
+		"success": err == nil,
+	})
+	if err != nil && report == nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to scan image: " + err.Error(),
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// listVulnerabilityReportsHandler returns the summaries of all scanned images
+// GET /api/vulnerabilities/reports
+func listVulnerabilityReportsHandler(c echo.Context) error {
+	reports, err := vulnerabilityRepo.ListReports()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to list vulnerability reports: " + err.Error(),
+		})
+	}
+	if reports == nil {
+		reports = []models.ImageVulnerabilityReport{}
+	}
+	return c.JSON(http.StatusOK, reports)
+}
+
+// runVulnerabilityScanHandler scans all images now instead of waiting for the schedule
+// POST /api/vulnerabilities/scan
+func runVulnerabilityScanHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	reports, err := vulnerabilityScanner.ScanAll(ctx)
+	logAudit(user, "vulnerabilities.scan", "images", map[string]interface{}{
+		"images": len(reports),
+	})
+
+	response := map[string]interface{}{"reports": reports}
+	if err != nil {
+		response["error"] = err.Error()
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// getVulnerabilityDatabaseHandler describes the imported advisories
+// GET /api/vulnerabilities/database
+func getVulnerabilityDatabaseHandler(c echo.Context) error {
+	status, err := vulnerabilityRepo.Status()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get vulnerability database status: " + err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, status)
+}
+
+// importVulnerabilityDatabaseHandler imports OSV advisories, sent as the
+// request body or as the "file" field of a form, so hosts without internet
+// access can be kept up to date. Stored inventories are re-matched afterwards.
+// POST /api/vulnerabilities/database/import
+func importVulnerabilityDatabaseHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+
+	var body io.Reader = c.Request().Body
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Missing database file: " + err.Error(),
+			})
+		}
+		src, err := file.Open()
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Failed to read database file: " + err.Error(),
+			})
+		}
+		defer src.Close()
+		body = src
+	}
+
+	// Zip archives need random access, so the upload is spooled to disk
+	tmp, err := os.CreateTemp("", "podmangr-osv-*")
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to store upload: " + err.Error(),
+		})
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, body)
+	tmp.Close()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Failed to receive upload: " + err.Error(),
+		})
+	}
+
+	result, err := system.ImportVulnerabilityDatabase(tmp.Name())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Failed to import vulnerability database: " + err.Error(),
+		})
+	}
+
+	logAudit(user, "vulnerabilities.import", "vulnerability_database", map[string]interface{}{
+		"imported":  result.Imported,
+		"withdrawn": result.Withdrawn,
+		"skipped":   result.Skipped,
+	})
+
+	go func() {
+		if err := vulnerabilityScanner.RematchAll(); err != nil {
+			log.Printf("Failed to re-match vulnerability reports: %v", err)
+		}
+	}()
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// ScanSettings represents the scheduled vulnerability scan configuration
+type ScanSettings struct {
+	Enabled  bool   `json:"enabled"`  // Also controls scans after pulls
+	Schedule string `json:"schedule"` // Cron expression, e.g. "0 3 * * *"
+	NextRun  string `json:"next_run,omitempty"`
+}
+
+// loadScanSettings reads the scan settings, applying defaults for missing keys
+func loadScanSettings() ScanSettings {
+	settingsRepo := database.NewSettingsRepo()
+
+	settings := ScanSettings{
+		Enabled:  true,
+		Schedule: system.DefaultScanSchedule,
+	}
+	if _, err := settingsRepo.Get(database.SettingScanEnabled); err == nil {
+		settings.Enabled, _ = settingsRepo.GetBool(database.SettingScanEnabled)
+	}
+	if v, err := settingsRepo.Get(database.SettingScanSchedule); err == nil && strings.TrimSpace(v) != "" {
+		settings.Schedule = v
+	}
+	if schedule, err := system.ParseSchedule(settings.Schedule); err == nil && settings.Enabled {
+		settings.NextRun = schedule.Next(time.Now()).Format(time.RFC3339)
+	}
+	return settings
+}
+
+// getScanSettingsHandler returns the vulnerability scan settings
+func getScanSettingsHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, loadScanSettings())
+}
+
+// updateScanSettingsHandler updates the vulnerability scan settings
+func updateScanSettingsHandler(c echo.Context) error {
+	user := c.Get("user").(*models.User)
+
+	var settings ScanSettings
+	if err := c.Bind(&settings); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+	if _, err := system.ParseSchedule(settings.Schedule); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid schedule: " + err.Error(),
+		})
+	}
+
+	settingsRepo := database.NewSettingsRepo()
+	values := map[string]string{
+		database.SettingScanEnabled:  strconv.FormatBool(settings.Enabled),
+		database.SettingScanSchedule: settings.Schedule,
+	}
+	for key, value := range values {
+		if err := settingsRepo.Set(key, value); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to save settings: " + err.Error(),
+			})
+		}
+	}
+
+	logAudit(user, "vulnerabilities.settings.update", "scan_settings", map[string]interface{}{
+		"enabled":  settings.Enabled,
+		"schedule": settings.Schedule,
+	})
+
+	return c.JSON(http.StatusOK, loadScanSettings())
+}
@@ -1,11 +1,13 @@
 package auth
 
 import (
+	"bufio"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -161,6 +163,186 @@ func (e *EncryptionService) Decrypt(encryptedText string) (string, error) {
 	return string(plaintext), nil
 }
 
+// Stream encryption splits data into segments that are sealed separately, so
+// files of any size are encrypted without holding them in memory. Each nonce
+// is a random prefix, the segment counter and a flag marking the last segment,
+// which makes reordered, dropped or truncated segments fail to decrypt.
+const (
+	streamMagic       = "PMGRENC1"
+	streamSegmentSize = 64 * 1024
+	streamPrefixSize  = 7
+)
+
+// NewEncryptWriter returns a writer that encrypts everything written to it
+// into w using AES-256-GCM. Close must be called to write the final segment;
+// it does not close w.
+func (e *EncryptionService) NewEncryptWriter(w io.Writer) (io.WriteCloser, error) {
+	aesGCM, err := e.newGCM()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, streamPrefixSize)
+	if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	if _, err := w.Write(append([]byte(streamMagic), prefix...)); err != nil {
+		return nil, err
+	}
+
+	return &encryptWriter{
+		w:      w,
+		aead:   aesGCM,
+		prefix: prefix,
+		buf:    make([]byte, 0, streamSegmentSize),
+	}, nil
+}
+
+// NewDecryptReader returns a reader that decrypts a stream written by
+// NewEncryptWriter. Reads fail if the stream was modified or cut short.
+func (e *EncryptionService) NewDecryptReader(r io.Reader) (io.Reader, error) {
+	aesGCM, err := e.newGCM()
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, len(streamMagic)+streamPrefixSize)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:len(streamMagic)]) != streamMagic {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return &decryptReader{
+		r:      bufio.NewReaderSize(r, streamSegmentSize+aesGCM.Overhead()+1),
+		aead:   aesGCM,
+		prefix: header[len(streamMagic):],
+		seg:    make([]byte, streamSegmentSize+aesGCM.Overhead()),
+	}, nil
+}
+
+func (e *EncryptionService) newGCM() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(e.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aesGCM, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return aesGCM, nil
+}
+
+// streamNonce builds the nonce of a segment
+func streamNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, 0, streamPrefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	closed  bool
+}
+
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to closed encryption stream")
+	}
+	written := 0
+	for len(p) > 0 {
+		// A full segment is only sealed once more data follows, since the
+		// last segment has to be sealed as such
+		if len(w.buf) == streamSegmentSize {
+			if err := w.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(w.buf[len(w.buf):streamSegmentSize], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (w *encryptWriter) seal(last bool) error {
+	if w.counter == ^uint32(0) {
+		return errors.New("encryption stream too long")
+	}
+	out := w.aead.Seal(nil, streamNonce(w.prefix, w.counter, last), w.buf, nil)
+	w.counter++
+	w.buf = w.buf[:0]
+	_, err := w.w.Write(out)
+	return err
+}
+
+func (w *encryptWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.seal(true)
+}
+
+type decryptReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	seg     []byte
+	plain   []byte
+	done    bool
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+// open decrypts the next segment. A segment is the last one if nothing follows it.
+func (r *decryptReader) open() error {
+	n, err := io.ReadFull(r.r, r.seg)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	last := n < len(r.seg)
+	if !last {
+		if _, err := r.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	plain, err := r.aead.Open(r.seg[:0], streamNonce(r.prefix, r.counter, last), r.seg[:n], nil)
+	if err != nil {
+		return ErrInvalidCiphertext
+	}
+	r.counter++
+	r.plain = plain
+	r.done = last
+	return nil
+}
+
 // GeneratePassword creates a cryptographically secure random password
 // Length should be at least 16 for security
 func GeneratePassword(length int) (string, error) {
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+func TestEncryptStream(t *testing.T) {
+	e := &EncryptionService{key: make([]byte, 32)}
+
+	for _, size := range []int{0, 1, streamSegmentSize, 3*streamSegmentSize + 17} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		var sealed bytes.Buffer
+		w, err := e.NewEncryptWriter(&sealed)
+		if err != nil {
+			t.Fatalf("NewEncryptWriter failed: %v", err)
+		}
+		// Odd write sizes cross segment boundaries
+		for rest := plain; len(rest) > 0; {
+			n := min(len(rest), 1000)
+			w.Write(rest[:n])
+			rest = rest[n:]
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		r, err := e.NewDecryptReader(bytes.NewReader(sealed.Bytes()))
+		if err != nil {
+			t.Fatalf("NewDecryptReader failed: %v", err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("size %d: decryption failed: %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Errorf("size %d: decrypted data differs", size)
+		}
+
+		// Cutting the stream at a segment boundary must not go unnoticed
+		if size > streamSegmentSize {
+			cut := len(streamMagic) + streamPrefixSize + streamSegmentSize + 16
+			r, _ := e.NewDecryptReader(bytes.NewReader(sealed.Bytes()[:cut]))
+			if _, err := io.ReadAll(r); err != ErrInvalidCiphertext {
+				t.Errorf("size %d: truncated stream gave %v, want ErrInvalidCiphertext", size, err)
+			}
+		}
+
+		tampered := bytes.Clone(sealed.Bytes())
+		tampered[len(tampered)-1] ^= 1
+		r, _ = e.NewDecryptReader(bytes.NewReader(tampered))
+		if _, err := io.ReadAll(r); err != ErrInvalidCiphertext {
+			t.Errorf("size %d: tampered stream gave %v, want ErrInvalidCiphertext", size, err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+
+	"podmangr-backend/internal/models"
+)
+
+// BackupDestinationRepo handles off-host backup destinations
+type BackupDestinationRepo struct {
+	db *sql.DB
+}
+
+// NewBackupDestinationRepo creates a new backup destination repository
+func NewBackupDestinationRepo() *BackupDestinationRepo {
+	return &BackupDestinationRepo{db: DB}
+}
+
+const backupDestinationColumns = `
+	id, name, type, endpoint, region, bucket, access_key_id, path_style,
+	host, port, username, path, encrypt, COALESCE(secret_id, ''),
+	created_at, updated_at, created_by
+`
+
+// Create adds a new backup destination
+func (r *BackupDestinationRepo) Create(d *models.BackupDestination) error {
+	if d.ID == "" {
+		d.ID = uuid.New().String()
+	}
+	d.CreatedAt = time.Now()
+	d.UpdatedAt = d.CreatedAt
+
+	_, err := r.db.Exec(`
+		INSERT INTO backup_destinations (
+			id, name, type, endpoint, region, bucket, access_key_id, path_style,
+			host, port, username, path, encrypt, secret_id,
+			created_at, updated_at, created_by
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		d.ID, d.Name, d.Type, d.Endpoint, d.Region, d.Bucket, d.AccessKeyID, d.PathStyle,
+		d.Host, d.Port, d.Username, d.Path, d.Encrypt, nullString(d.SecretID),
+		d.CreatedAt, d.UpdatedAt, d.CreatedBy,
+	)
+	return err
+}
+
+// GetByID retrieves a backup destination by ID
+func (r *BackupDestinationRepo) GetByID(id string) (*models.BackupDestination, error) {
+	destinations, err := r.query("SELECT "+backupDestinationColumns+" FROM backup_destinations WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(destinations) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &destinations[0], nil
+}
+
+// List returns all backup destinations ordered by name
+func (r *BackupDestinationRepo) List() ([]models.BackupDestination, error) {
+	return r.query("SELECT " + backupDestinationColumns + " FROM backup_destinations ORDER BY name")
+}
+
+func (r *BackupDestinationRepo) query(query string, args ...interface{}) ([]models.BackupDestination, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var destinations []models.BackupDestination
+	for rows.Next() {
+		var d models.BackupDestination
+		var createdBy sql.NullInt64
+		if err := rows.Scan(
+			&d.ID, &d.Name, &d.Type, &d.Endpoint, &d.Region, &d.Bucket, &d.AccessKeyID, &d.PathStyle,
+			&d.Host, &d.Port, &d.Username, &d.Path, &d.Encrypt, &d.SecretID,
+			&d.CreatedAt, &d.UpdatedAt, &createdBy,
+		); err != nil {
+			return nil, err
+		}
+		d.HasSecret = d.SecretID != ""
+		if createdBy.Valid {
+			d.CreatedBy = &createdBy.Int64
+		}
+		destinations = append(destinations, d)
+	}
+
+	return destinations, rows.Err()
+}
+
+// Update saves a backup destination
+func (r *BackupDestinationRepo) Update(d *models.BackupDestination) error {
+	d.UpdatedAt = time.Now()
+
+	_, err := r.db.Exec(`
+		UPDATE backup_destinations SET
+			name = ?, type = ?, endpoint = ?, region = ?, bucket = ?, access_key_id = ?, path_style = ?,
+			host = ?, port = ?, username = ?, path = ?, encrypt = ?, secret_id = ?,
+			updated_at = ?
+		WHERE id = ?
+	`,
+		d.Name, d.Type, d.Endpoint, d.Region, d.Bucket, d.AccessKeyID, d.PathStyle,
+		d.Host, d.Port, d.Username, d.Path, d.Encrypt, nullString(d.SecretID),
+		d.UpdatedAt, d.ID,
+	)
+	return err
+}
+
+// Delete removes a backup destination
+func (r *BackupDestinationRepo) Delete(id string) error {
+	_, err := r.db.Exec("DELETE FROM backup_destinations WHERE id = ?", id)
+	return err
+}
+
+// nullString stores empty strings as NULL, as foreign keys require
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
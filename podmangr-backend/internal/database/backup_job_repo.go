@@ -0,0 +1,207 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"podmangr-backend/internal/models"
+)
+
+// BackupJobRepo handles scheduled backup jobs and their runs
+type BackupJobRepo struct {
+	db *sql.DB
+}
+
+// NewBackupJobRepo creates a new backup job repository
+func NewBackupJobRepo() *BackupJobRepo {
+	return &BackupJobRepo{db: DB}
+}
+
+const backupJobColumns = `
+	id, name, target_type, target_id, schedule, destination,
+	keep_last, keep_daily, keep_weekly, keep_monthly, enabled,
+	last_run_at, COALESCE(last_status, ''), created_at, updated_at, created_by
+`
+
+// Create adds a new backup job
+func (r *BackupJobRepo) Create(j *models.BackupJob) error {
+	if j.ID == "" {
+		j.ID = uuid.New().String()
+	}
+	j.CreatedAt = time.Now()
+	j.UpdatedAt = j.CreatedAt
+
+	_, err := r.db.Exec(`
+		INSERT INTO backup_jobs (
+			id, name, target_type, target_id, schedule, destination,
+			keep_last, keep_daily, keep_weekly, keep_monthly, enabled,
+			created_at, updated_at, created_by
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		j.ID, j.Name, j.TargetType, j.TargetID, j.Schedule, j.Destination,
+		j.Retention.KeepLast, j.Retention.KeepDaily, j.Retention.KeepWeekly, j.Retention.KeepMonthly, j.Enabled,
+		j.CreatedAt, j.UpdatedAt, j.CreatedBy,
+	)
+	return err
+}
+
+// GetByID retrieves a backup job by ID
+func (r *BackupJobRepo) GetByID(id string) (*models.BackupJob, error) {
+	jobs, err := r.query("SELECT "+backupJobColumns+" FROM backup_jobs WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &jobs[0], nil
+}
+
+// List returns all backup jobs ordered by name
+func (r *BackupJobRepo) List() ([]models.BackupJob, error) {
+	return r.query("SELECT " + backupJobColumns + " FROM backup_jobs ORDER BY name")
+}
+
+func (r *BackupJobRepo) query(query string, args ...interface{}) ([]models.BackupJob, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []models.BackupJob
+	for rows.Next() {
+		var j models.BackupJob
+		var lastRunAt sql.NullTime
+		var createdBy sql.NullInt64
+		if err := rows.Scan(
+			&j.ID, &j.Name, &j.TargetType, &j.TargetID, &j.Schedule, &j.Destination,
+			&j.Retention.KeepLast, &j.Retention.KeepDaily, &j.Retention.KeepWeekly, &j.Retention.KeepMonthly, &j.Enabled,
+			&lastRunAt, &j.LastStatus, &j.CreatedAt, &j.UpdatedAt, &createdBy,
+		); err != nil {
+			return nil, err
+		}
+		if lastRunAt.Valid {
+			j.LastRunAt = &lastRunAt.Time
+		}
+		if createdBy.Valid {
+			j.CreatedBy = &createdBy.Int64
+		}
+		jobs = append(jobs, j)
+	}
+
+	return jobs, rows.Err()
+}
+
+// Update saves the definition of a backup job
+func (r *BackupJobRepo) Update(j *models.BackupJob) error {
+	j.UpdatedAt = time.Now()
+
+	_, err := r.db.Exec(`
+		UPDATE backup_jobs SET
+			name = ?, target_type = ?, target_id = ?, schedule = ?, destination = ?,
+			keep_last = ?, keep_daily = ?, keep_weekly = ?, keep_monthly = ?, enabled = ?,
+			updated_at = ?
+		WHERE id = ?
+	`,
+		j.Name, j.TargetType, j.TargetID, j.Schedule, j.Destination,
+		j.Retention.KeepLast, j.Retention.KeepDaily, j.Retention.KeepWeekly, j.Retention.KeepMonthly, j.Enabled,
+		j.UpdatedAt, j.ID,
+	)
+	return err
+}
+
+// SetLastRun records when a job last ran and how it went
+func (r *BackupJobRepo) SetLastRun(id string, at time.Time, status string) error {
+	_, err := r.db.Exec("UPDATE backup_jobs SET last_run_at = ?, last_status = ? WHERE id = ?", at, status, id)
+	return err
+}
+
+// Delete removes a backup job and its run history
+func (r *BackupJobRepo) Delete(id string) error {
+	_, err := r.db.Exec("DELETE FROM backup_jobs WHERE id = ?", id)
+	return err
+}
+
+// CreateRun records the start of a job run
+func (r *BackupJobRepo) CreateRun(run *models.BackupJobRun) error {
+	if run.ID == "" {
+		run.ID = uuid.New().String()
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO backup_job_runs (id, job_id, status, trigger, started_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, run.ID, run.JobID, run.Status, run.Trigger, run.StartedAt)
+	return err
+}
+
+// FinishRun stores the outcome of a job run
+func (r *BackupJobRepo) FinishRun(run *models.BackupJobRun) error {
+	backupIDs, _ := json.Marshal(run.BackupIDs)
+	pruned, _ := json.Marshal(run.Pruned)
+
+	_, err := r.db.Exec(`
+		UPDATE backup_job_runs SET
+			status = ?, finished_at = ?, duration_ms = ?, size_bytes = ?,
+			backup_ids = ?, pruned = ?, error = ?
+		WHERE id = ?
+	`,
+		run.Status, run.FinishedAt, run.DurationMs, run.SizeBytes,
+		string(backupIDs), string(pruned), run.Error, run.ID,
+	)
+	return err
+}
+
+// ListRuns returns the runs of a job, newest first
+func (r *BackupJobRepo) ListRuns(jobID string, limit int) ([]models.BackupJobRun, error) {
+	rows, err := r.db.Query(`
+		SELECT id, job_id, status, trigger, started_at, finished_at, duration_ms, size_bytes,
+			COALESCE(backup_ids, '[]'), COALESCE(pruned, '[]'), COALESCE(error, '')
+		FROM backup_job_runs
+		WHERE job_id = ?
+		ORDER BY started_at DESC
+		LIMIT ?
+	`, jobID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []models.BackupJobRun
+	for rows.Next() {
+		var run models.BackupJobRun
+		var finishedAt sql.NullTime
+		var backupIDs, pruned string
+		if err := rows.Scan(
+			&run.ID, &run.JobID, &run.Status, &run.Trigger, &run.StartedAt, &finishedAt, &run.DurationMs, &run.SizeBytes,
+			&backupIDs, &pruned, &run.Error,
+		); err != nil {
+			return nil, err
+		}
+		if finishedAt.Valid {
+			run.FinishedAt = &finishedAt.Time
+		}
+		json.Unmarshal([]byte(backupIDs), &run.BackupIDs)
+		json.Unmarshal([]byte(pruned), &run.Pruned)
+		if run.BackupIDs == nil {
+			run.BackupIDs = []string{}
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+// FailInterrupted marks runs still recorded as running as failed. Called at
+// startup, since a run can't survive a restart.
+func (r *BackupJobRepo) FailInterrupted() error {
+	_, err := r.db.Exec(`
+		UPDATE backup_job_runs SET status = ?, finished_at = ?, error = 'Interrupted by a restart'
+		WHERE status = ?
+	`, models.BackupRunFailed, time.Now(), models.BackupRunRunning)
+	return err
+}
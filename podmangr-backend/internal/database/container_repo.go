@@ -408,23 +408,67 @@ func NewContainerMetricsRepo() *ContainerMetricsRepo {
 
 // Save stores container metrics
 func (r *ContainerMetricsRepo) Save(m *models.ContainerMetrics) error {
-	m.Timestamp = time.Now()
+	// Stored in UTC so it compares correctly with SQLite's datetime('now')
+	m.Timestamp = time.Now().UTC()
 	_, err := r.db.Exec(`
 		INSERT INTO container_metrics (
-			container_id, timestamp, cpu_percent, memory_used, memory_limit,
+			container_id, timestamp, resolution, cpu_percent, memory_used, memory_limit,
 			network_rx, network_tx, block_read, block_write
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
-		m.ContainerID, m.Timestamp, m.CPUPercent, m.MemoryUsed, m.MemoryLimit,
+		m.ContainerID, m.Timestamp, m.Resolution, m.CPUPercent, m.MemoryUsed, m.MemoryLimit,
 		m.NetworkRx, m.NetworkTx, m.BlockRead, m.BlockWrite,
 	)
 	return err
 }
 
+// SaveBatch stores several samples in a single transaction, keeping their timestamps
+func (r *ContainerMetricsRepo) SaveBatch(metrics []models.ContainerMetrics) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertMetrics(tx, metrics); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertMetrics inserts samples using the given transaction
+func insertMetrics(tx *sql.Tx, metrics []models.ContainerMetrics) error {
+	stmt, err := tx.Prepare(`
+		INSERT INTO container_metrics (
+			container_id, timestamp, resolution, cpu_percent, memory_used, memory_limit,
+			network_rx, network_tx, block_read, block_write
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, m := range metrics {
+		if _, err := stmt.Exec(
+			m.ContainerID, m.Timestamp.UTC(), m.Resolution, m.CPUPercent, m.MemoryUsed, m.MemoryLimit,
+			m.NetworkRx, m.NetworkTx, m.BlockRead, m.BlockWrite,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetRecent retrieves recent metrics for a container
 func (r *ContainerMetricsRepo) GetRecent(containerID string, hours int) ([]models.ContainerMetrics, error) {
 	rows, err := r.db.Query(`
-		SELECT id, container_id, timestamp, cpu_percent, memory_used, memory_limit,
+		SELECT id, container_id, timestamp, resolution, cpu_percent, memory_used, memory_limit,
 			network_rx, network_tx, block_read, block_write
 		FROM container_metrics
 		WHERE container_id = ? AND timestamp > datetime('now', '-' || ? || ' hours')
@@ -435,11 +479,16 @@ func (r *ContainerMetricsRepo) GetRecent(containerID string, hours int) ([]model
 	}
 	defer rows.Close()
 
+	return scanMetrics(rows)
+}
+
+// scanMetrics reads container_metrics rows
+func scanMetrics(rows *sql.Rows) ([]models.ContainerMetrics, error) {
 	var metrics []models.ContainerMetrics
 	for rows.Next() {
 		var m models.ContainerMetrics
 		if err := rows.Scan(
-			&m.ID, &m.ContainerID, &m.Timestamp, &m.CPUPercent, &m.MemoryUsed, &m.MemoryLimit,
+			&m.ID, &m.ContainerID, &m.Timestamp, &m.Resolution, &m.CPUPercent, &m.MemoryUsed, &m.MemoryLimit,
 			&m.NetworkRx, &m.NetworkTx, &m.BlockRead, &m.BlockWrite,
 		); err != nil {
 			return nil, err
@@ -447,7 +496,115 @@ func (r *ContainerMetricsRepo) GetRecent(containerID string, hours int) ([]model
 		metrics = append(metrics, m)
 	}
 
-	return metrics, nil
+	return metrics, rows.Err()
+}
+
+// Downsample replaces samples finer than bucketSeconds that are older than
+// olderThan with one averaged row per container and bucket. The cutoff is
+// truncated to a bucket boundary so a bucket is only folded once it is
+// complete and never yields two rows across runs.
+// Returns the number of source rows that were folded.
+func (r *ContainerMetricsRepo) Downsample(olderThan time.Time, bucketSeconds int) (int, error) {
+	bucket := int64(bucketSeconds)
+	olderThan = time.Unix(olderThan.Unix()/bucket*bucket, 0)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT id, container_id, timestamp, resolution, cpu_percent, memory_used, memory_limit,
+			network_rx, network_tx, block_read, block_write
+		FROM container_metrics
+		WHERE resolution < ? AND timestamp < ?
+		ORDER BY container_id, timestamp ASC
+	`, bucketSeconds, olderThan.UTC())
+	if err != nil {
+		return 0, err
+	}
+	source, err := scanMetrics(rows)
+	rows.Close()
+	if err != nil {
+		return 0, err
+	}
+	if len(source) == 0 {
+		return 0, nil
+	}
+
+	if err := insertMetrics(tx, DownsampleMetrics(source, bucketSeconds)); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM container_metrics WHERE resolution < ? AND timestamp < ? AND id <= ?
+	`, bucketSeconds, olderThan.UTC(), maxMetricID(source)); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(source), nil
+}
+
+// DownsampleMetrics groups samples into buckets of bucketSeconds per container.
+// CPU and memory usage are averaged; the memory limit and the cumulative
+// network/block counters keep their highest value in the bucket.
+func DownsampleMetrics(samples []models.ContainerMetrics, bucketSeconds int) []models.ContainerMetrics {
+	type bucketKey struct {
+		containerID string
+		start       int64
+	}
+
+	bucket := int64(bucketSeconds)
+	buckets := make(map[bucketKey]*models.ContainerMetrics)
+	counts := make(map[bucketKey]int)
+	order := make([]bucketKey, 0)
+
+	for _, s := range samples {
+		key := bucketKey{s.ContainerID, s.Timestamp.Unix() / bucket * bucket}
+		agg, ok := buckets[key]
+		if !ok {
+			agg = &models.ContainerMetrics{
+				ContainerID: s.ContainerID,
+				Timestamp:   time.Unix(key.start, 0).UTC(),
+				Resolution:  bucketSeconds,
+			}
+			buckets[key] = agg
+			order = append(order, key)
+		}
+
+		// Rows of a finer tier are evenly spaced, so a plain mean is accurate enough
+		counts[key]++
+		agg.CPUPercent += s.CPUPercent
+		agg.MemoryUsed += s.MemoryUsed
+		agg.MemoryLimit = max(agg.MemoryLimit, s.MemoryLimit)
+		agg.NetworkRx = max(agg.NetworkRx, s.NetworkRx)
+		agg.NetworkTx = max(agg.NetworkTx, s.NetworkTx)
+		agg.BlockRead = max(agg.BlockRead, s.BlockRead)
+		agg.BlockWrite = max(agg.BlockWrite, s.BlockWrite)
+	}
+
+	result := make([]models.ContainerMetrics, 0, len(order))
+	for _, key := range order {
+		agg := buckets[key]
+		n := counts[key]
+		agg.CPUPercent /= float64(n)
+		agg.MemoryUsed /= int64(n)
+		result = append(result, *agg)
+	}
+	return result
+}
+
+// maxMetricID returns the highest row ID in samples
+func maxMetricID(samples []models.ContainerMetrics) int64 {
+	var maxID int64
+	for _, s := range samples {
+		maxID = max(maxID, s.ID)
+	}
+	return maxID
 }
 
 // Cleanup removes old metrics (older than specified hours)
@@ -531,6 +688,173 @@ func (r *ContainerEnvVarRepo) DeleteByContainerID(containerID string) error {
 	return err
 }
 
+// ContainerUpdateRepo handles the container update history
+type ContainerUpdateRepo struct {
+	db *sql.DB
+}
+
+// NewContainerUpdateRepo creates a new update history repository
+func NewContainerUpdateRepo() *ContainerUpdateRepo {
+	return &ContainerUpdateRepo{db: DB}
+}
+
+// Create stores the outcome of an update
+func (r *ContainerUpdateRepo) Create(u *models.ContainerUpdateRecord) error {
+	if u.ID == "" {
+		u.ID = uuid.New().String()
+	}
+
+	var createdBy sql.NullInt64
+	if u.CreatedBy != nil {
+		createdBy = sql.NullInt64{Int64: *u.CreatedBy, Valid: true}
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO container_updates (
+			id, container_name, old_container_id, new_container_id, old_image, new_image,
+			status, reason, backup_id, started_at, finished_at, created_by
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		u.ID, u.ContainerName, u.OldContainerID, u.NewContainerID, u.OldImage, u.NewImage,
+		u.Status, u.Reason, u.BackupID, u.StartedAt, u.FinishedAt, createdBy,
+	)
+	return err
+}
+
+// ListByContainerName returns the updates of a container, newest first
+func (r *ContainerUpdateRepo) ListByContainerName(name string, limit int) ([]models.ContainerUpdateRecord, error) {
+	rows, err := r.db.Query(`
+		SELECT id, container_name, old_container_id, COALESCE(new_container_id, ''), old_image, new_image,
+			status, COALESCE(reason, ''), COALESCE(backup_id, ''), started_at, finished_at, created_by
+		FROM container_updates
+		WHERE container_name = ?
+		ORDER BY started_at DESC
+		LIMIT ?
+	`, name, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var updates []models.ContainerUpdateRecord
+	for rows.Next() {
+		var u models.ContainerUpdateRecord
+		var finishedAt sql.NullTime
+		var createdBy sql.NullInt64
+		if err := rows.Scan(
+			&u.ID, &u.ContainerName, &u.OldContainerID, &u.NewContainerID, &u.OldImage, &u.NewImage,
+			&u.Status, &u.Reason, &u.BackupID, &u.StartedAt, &finishedAt, &createdBy,
+		); err != nil {
+			return nil, err
+		}
+		if finishedAt.Valid {
+			u.FinishedAt = &finishedAt.Time
+		}
+		if createdBy.Valid {
+			u.CreatedBy = &createdBy.Int64
+		}
+		updates = append(updates, u)
+	}
+
+	return updates, rows.Err()
+}
+
+// GetState returns the update state of a container on a socket
+func (r *ContainerUpdateRepo) GetState(socketID, name string) (*models.ContainerUpdateState, error) {
+	rows, err := r.db.Query(updateStateQuery+" WHERE socket_id = ? AND container_name = ?", socketID, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states, err := scanUpdateStates(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &states[0], nil
+}
+
+// ListStates returns the update state of every checked container
+func (r *ContainerUpdateRepo) ListStates() ([]models.ContainerUpdateState, error) {
+	rows, err := r.db.Query(updateStateQuery + " ORDER BY container_name, socket_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanUpdateStates(rows)
+}
+
+// SaveState stores the result of an update check, keeping the container's
+// policy. The policy of s is only stored for a container without a state yet.
+func (r *ContainerUpdateRepo) SaveState(s *models.ContainerUpdateState) error {
+	_, err := r.db.Exec(`
+		INSERT INTO container_update_state (
+			socket_id, container_name, container_id, image, policy, effective_policy, autoupdate_label,
+			update_available, current_digest, latest_digest, newer_tags, checked_at, last_error
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(socket_id, container_name) DO UPDATE SET
+			container_id = excluded.container_id,
+			image = excluded.image,
+			effective_policy = excluded.effective_policy,
+			autoupdate_label = excluded.autoupdate_label,
+			update_available = excluded.update_available,
+			current_digest = excluded.current_digest,
+			latest_digest = excluded.latest_digest,
+			newer_tags = excluded.newer_tags,
+			checked_at = excluded.checked_at,
+			last_error = excluded.last_error
+	`,
+		s.SocketID, s.ContainerName, s.ContainerID, s.Image, s.Policy, s.EffectivePolicy, s.AutoUpdateLabel,
+		s.UpdateAvailable, s.CurrentDigest, s.LatestDigest, sliceToJSON(s.NewerTags), s.CheckedAt, s.LastError,
+	)
+	return err
+}
+
+// SetPolicy sets the update policy of a container on a socket; empty
+// restores the default
+func (r *ContainerUpdateRepo) SetPolicy(socketID, name, policy string) error {
+	_, err := r.db.Exec(`
+		INSERT INTO container_update_state (socket_id, container_name, policy) VALUES (?, ?, ?)
+		ON CONFLICT(socket_id, container_name) DO UPDATE SET policy = excluded.policy
+	`, socketID, name, policy)
+	return err
+}
+
+const updateStateQuery = `
+	SELECT socket_id, container_name, COALESCE(container_id, ''), COALESCE(image, ''), COALESCE(policy, ''),
+		COALESCE(effective_policy, ''), COALESCE(autoupdate_label, ''), COALESCE(update_available, 0),
+		COALESCE(current_digest, ''), COALESCE(latest_digest, ''), COALESCE(newer_tags, '[]'), checked_at,
+		COALESCE(last_error, '')
+	FROM container_update_state`
+
+// scanUpdateStates reads container_update_state rows
+func scanUpdateStates(rows *sql.Rows) ([]models.ContainerUpdateState, error) {
+	var states []models.ContainerUpdateState
+	for rows.Next() {
+		var s models.ContainerUpdateState
+		var checkedAt sql.NullTime
+		var newerTags string
+		if err := rows.Scan(
+			&s.SocketID, &s.ContainerName, &s.ContainerID, &s.Image, &s.Policy,
+			&s.EffectivePolicy, &s.AutoUpdateLabel, &s.UpdateAvailable,
+			&s.CurrentDigest, &s.LatestDigest, &newerTags, &checkedAt, &s.LastError,
+		); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(newerTags), &s.NewerTags)
+		if checkedAt.Valid {
+			s.CheckedAt = &checkedAt.Time
+		}
+		states = append(states, s)
+	}
+
+	return states, rows.Err()
+}
+
 // Helper function to convert map to JSON string
 func mapToJSON(m map[string]string) string {
 	if m == nil {
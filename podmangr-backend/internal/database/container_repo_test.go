@@ -0,0 +1,206 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"podmangr-backend/internal/models"
+)
+
+// openTestDB opens a migrated database in a temporary directory
+func openTestDB(t *testing.T) {
+	t.Helper()
+
+	tmpDir, err := os.MkdirTemp("", "podmangr-db-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+
+	if err := Open(Config{Path: filepath.Join(tmpDir, "test.db")}); err != nil {
+		os.RemoveAll(tmpDir)
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		Close()
+		os.RemoveAll(tmpDir)
+	})
+}
+
+func TestDownsampleMetrics(t *testing.T) {
+	base := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	samples := []models.ContainerMetrics{
+		{ContainerID: "a", Timestamp: base, CPUPercent: 10, MemoryUsed: 100, MemoryLimit: 1000, NetworkRx: 5},
+		{ContainerID: "a", Timestamp: base.Add(time.Minute), CPUPercent: 20, MemoryUsed: 300, MemoryLimit: 1000, NetworkRx: 9},
+		{ContainerID: "a", Timestamp: base.Add(6 * time.Minute), CPUPercent: 50, MemoryUsed: 500, MemoryLimit: 2000, NetworkRx: 12},
+		{ContainerID: "b", Timestamp: base.Add(2 * time.Minute), CPUPercent: 1, MemoryUsed: 10},
+	}
+
+	result := DownsampleMetrics(samples, 300)
+
+	if len(result) != 3 {
+		t.Fatalf("Expected 3 buckets, got %d: %+v", len(result), result)
+	}
+
+	first := result[0]
+	if first.ContainerID != "a" || !first.Timestamp.Equal(base) {
+		t.Errorf("Unexpected first bucket: %+v", first)
+	}
+	if first.CPUPercent != 15 || first.MemoryUsed != 200 {
+		t.Errorf("Expected averaged CPU 15 and memory 200, got %f and %d", first.CPUPercent, first.MemoryUsed)
+	}
+	if first.NetworkRx != 9 {
+		t.Errorf("Expected cumulative counter to keep its latest value 9, got %d", first.NetworkRx)
+	}
+	if first.Resolution != 300 {
+		t.Errorf("Expected resolution 300, got %d", first.Resolution)
+	}
+
+	if !result[1].Timestamp.Equal(base.Add(5*time.Minute)) || result[1].MemoryLimit != 2000 {
+		t.Errorf("Unexpected second bucket: %+v", result[1])
+	}
+}
+
+func TestContainerMetricsRepoDownsample(t *testing.T) {
+	openTestDB(t)
+	repo := NewContainerMetricsRepo()
+
+	now := time.Now().UTC().Truncate(time.Hour)
+	old := now.Add(-48 * time.Hour)
+	samples := []models.ContainerMetrics{
+		{ContainerID: "abc", Timestamp: old, CPUPercent: 10},
+		{ContainerID: "abc", Timestamp: old.Add(time.Minute), CPUPercent: 30},
+		{ContainerID: "abc", Timestamp: now.Add(-time.Minute), CPUPercent: 99},
+	}
+	if err := repo.SaveBatch(samples); err != nil {
+		t.Fatalf("SaveBatch returned error: %v", err)
+	}
+
+	folded, err := repo.Downsample(now.Add(-24*time.Hour), 300)
+	if err != nil {
+		t.Fatalf("Downsample returned error: %v", err)
+	}
+	if folded != 2 {
+		t.Errorf("Expected 2 rows folded, got %d", folded)
+	}
+
+	metrics, err := repo.GetRecent("abc", 72)
+	if err != nil {
+		t.Fatalf("GetRecent returned error: %v", err)
+	}
+	if len(metrics) != 2 {
+		t.Fatalf("Expected 1 downsampled and 1 raw row, got %d: %+v", len(metrics), metrics)
+	}
+	if metrics[0].Resolution != 300 || metrics[0].CPUPercent != 20 {
+		t.Errorf("Unexpected downsampled row: %+v", metrics[0])
+	}
+	if metrics[1].Resolution != 0 || metrics[1].CPUPercent != 99 {
+		t.Errorf("Unexpected raw row: %+v", metrics[1])
+	}
+
+	// Retention removes the old bucket but keeps the recent sample
+	if err := repo.Cleanup(24); err != nil {
+		t.Fatalf("Cleanup returned error: %v", err)
+	}
+	metrics, err = repo.GetRecent("abc", 72)
+	if err != nil {
+		t.Fatalf("GetRecent returned error: %v", err)
+	}
+	if len(metrics) != 1 || metrics[0].CPUPercent != 99 {
+		t.Errorf("Expected only the recent sample after cleanup, got %+v", metrics)
+	}
+}
+
+func TestContainerMetricsRepoDownsampleAcrossBoundary(t *testing.T) {
+	openTestDB(t)
+	repo := NewContainerMetricsRepo()
+
+	bucketStart := time.Now().UTC().Truncate(time.Hour).Add(-48 * time.Hour)
+	samples := []models.ContainerMetrics{
+		{ContainerID: "abc", Timestamp: bucketStart, CPUPercent: 10},
+		{ContainerID: "abc", Timestamp: bucketStart.Add(time.Minute), CPUPercent: 20},
+		{ContainerID: "abc", Timestamp: bucketStart.Add(3 * time.Minute), CPUPercent: 60},
+	}
+	if err := repo.SaveBatch(samples); err != nil {
+		t.Fatalf("SaveBatch returned error: %v", err)
+	}
+
+	// The first cutoff falls inside the bucket, which must wait until it is complete
+	folded, err := repo.Downsample(bucketStart.Add(2*time.Minute), 300)
+	if err != nil {
+		t.Fatalf("Downsample returned error: %v", err)
+	}
+	if folded != 0 {
+		t.Errorf("Expected the straddling bucket to be left alone, got %d rows folded", folded)
+	}
+
+	if _, err := repo.Downsample(bucketStart.Add(7*time.Minute), 300); err != nil {
+		t.Fatalf("Downsample returned error: %v", err)
+	}
+
+	metrics, err := repo.GetRecent("abc", 72)
+	if err != nil {
+		t.Fatalf("GetRecent returned error: %v", err)
+	}
+	if len(metrics) != 1 {
+		t.Fatalf("Expected a single row for the bucket, got %d: %+v", len(metrics), metrics)
+	}
+	if !metrics[0].Timestamp.Equal(bucketStart) || metrics[0].Resolution != 300 || metrics[0].CPUPercent != 30 {
+		t.Errorf("Unexpected downsampled row: %+v", metrics[0])
+	}
+}
+
+func TestContainerUpdateState(t *testing.T) {
+	openTestDB(t)
+	repo := NewContainerUpdateRepo()
+
+	if err := repo.SetPolicy("root", "web", models.UpdatePolicyPatch); err != nil {
+		t.Fatalf("SetPolicy returned error: %v", err)
+	}
+
+	checkedAt := time.Now().UTC().Truncate(time.Second)
+	state := &models.ContainerUpdateState{
+		SocketID:        "root",
+		ContainerName:   "web",
+		ContainerID:     "abc",
+		Image:           "nginx:1.27",
+		EffectivePolicy: models.UpdatePolicyPatch,
+		UpdateAvailable: true,
+		LatestDigest:    "sha256:new",
+		CheckedAt:       &checkedAt,
+	}
+	if err := repo.SaveState(state); err != nil {
+		t.Fatalf("SaveState returned error: %v", err)
+	}
+
+	// Saving a check result keeps the policy set for the container
+	got, err := repo.GetState("root", "web")
+	if err != nil {
+		t.Fatalf("GetState returned error: %v", err)
+	}
+	if got.Policy != models.UpdatePolicyPatch || !got.UpdateAvailable || got.LatestDigest != "sha256:new" || !got.CheckedAt.Equal(checkedAt) {
+		t.Errorf("Unexpected state: %+v", got)
+	}
+
+	if _, err := repo.GetState("root", "missing"); err == nil {
+		t.Error("Expected an error for a container that was never checked")
+	}
+
+	// A container of the same name on another socket has its own state
+	if _, err := repo.GetState("user:alice", "web"); err == nil {
+		t.Error("Expected no state for web on another socket")
+	}
+	other := &models.ContainerUpdateState{SocketID: "user:alice", ContainerName: "web", LatestDigest: "sha256:other"}
+	if err := repo.SaveState(other); err != nil {
+		t.Fatalf("SaveState returned error: %v", err)
+	}
+	if got, err := repo.GetState("root", "web"); err != nil || got.LatestDigest != "sha256:new" || got.Policy != models.UpdatePolicyPatch {
+		t.Errorf("Unexpected state after saving another socket's: %+v (%v)", got, err)
+	}
+	states, err := repo.ListStates()
+	if err != nil || len(states) != 2 {
+		t.Errorf("Expected two states, got %v (%v)", states, err)
+	}
+}
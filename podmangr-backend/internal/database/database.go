@@ -683,4 +683,309 @@ var migrations = []migration{
 			CREATE INDEX idx_secrets_category ON secrets(category);
 		`,
 	},
+	{
+		name: "029_rebuild_container_metrics",
+		up: `
+			-- Metrics are keyed by Podman container ID so unmanaged containers can be
+			-- sampled too, and carry the bucket size they were downsampled to
+			CREATE TABLE container_metrics_new (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				container_id TEXT NOT NULL,           -- Podman container ID
+				timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+				resolution INTEGER NOT NULL DEFAULT 0, -- Bucket size in seconds (0 = raw sample)
+				cpu_percent REAL,
+				memory_used INTEGER,
+				memory_limit INTEGER,
+				network_rx INTEGER,
+				network_tx INTEGER,
+				block_read INTEGER,
+				block_write INTEGER
+			);
+			INSERT INTO container_metrics_new (
+				id, container_id, timestamp, cpu_percent, memory_used, memory_limit,
+				network_rx, network_tx, block_read, block_write
+			)
+			SELECT m.id,
+				COALESCE((SELECT c.container_id FROM containers c WHERE c.id = m.container_id), m.container_id),
+				m.timestamp, m.cpu_percent, m.memory_used, m.memory_limit,
+				m.network_rx, m.network_tx, m.block_read, m.block_write
+			FROM container_metrics m;
+			DROP TABLE container_metrics;
+			ALTER TABLE container_metrics_new RENAME TO container_metrics;
+			CREATE INDEX idx_container_metrics_container_time ON container_metrics(container_id, timestamp);
+			CREATE INDEX idx_container_metrics_resolution_time ON container_metrics(resolution, timestamp);
+
+			INSERT OR IGNORE INTO settings (key, value) VALUES
+				('metrics.enabled', 'true'),
+				('metrics.interval_seconds', '60'),
+				('metrics.retention_hours', '720');
+		`,
+	},
+	{
+		name: "030_add_stack_override_content",
+		up: `
+			-- Compose override file merged over the stack's compose file
+			ALTER TABLE stacks ADD COLUMN override_content TEXT DEFAULT '';
+		`,
+	},
+	{
+		name: "031_create_container_updates",
+		up: `
+			-- Outcome of each image update, kept by name since the container ID changes
+			CREATE TABLE container_updates (
+				id TEXT PRIMARY KEY,
+				container_name TEXT NOT NULL,
+				old_container_id TEXT NOT NULL,
+				new_container_id TEXT DEFAULT '',
+				old_image TEXT NOT NULL,
+				new_image TEXT NOT NULL,
+				status TEXT NOT NULL,
+				reason TEXT DEFAULT '',
+				backup_id TEXT DEFAULT '',
+				started_at DATETIME NOT NULL,
+				finished_at DATETIME,
+				created_by INTEGER,
+				FOREIGN KEY (created_by) REFERENCES users(id)
+			);
+			CREATE INDEX idx_container_updates_name ON container_updates(container_name, started_at);
+		`,
+	},
+	{
+		name: "032_create_container_update_state",
+		up: `
+			-- Per-container update policy and the result of the latest scheduled check,
+			-- kept per socket so same-named containers on different sockets don't share it
+			CREATE TABLE container_update_state (
+				socket_id TEXT NOT NULL,
+				container_name TEXT NOT NULL,
+				container_id TEXT DEFAULT '',
+				image TEXT DEFAULT '',
+				policy TEXT DEFAULT '',
+				effective_policy TEXT DEFAULT '',
+				autoupdate_label TEXT DEFAULT '',
+				update_available INTEGER DEFAULT 0,
+				current_digest TEXT DEFAULT '',
+				latest_digest TEXT DEFAULT '',
+				newer_tags TEXT NOT NULL DEFAULT '[]', -- Higher version tags found at the last check (JSON array)
+				checked_at DATETIME,
+				last_error TEXT DEFAULT '',
+				PRIMARY KEY (socket_id, container_name)
+			);
+
+			INSERT OR IGNORE INTO settings (key, value) VALUES
+				('updates.enabled', 'true'),
+				('updates.schedule', '0 4 * * *'),
+				('updates.default_policy', 'notify');
+		`,
+	},
+	{
+		name: "033_create_backup_jobs",
+		up: `
+			-- Scheduled backups of a container, stack or database server
+			CREATE TABLE backup_jobs (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL,
+				target_type TEXT NOT NULL,
+				target_id TEXT NOT NULL,
+				schedule TEXT NOT NULL,
+				destination TEXT NOT NULL DEFAULT 'default',
+				keep_last INTEGER DEFAULT 0,
+				keep_daily INTEGER DEFAULT 0,
+				keep_weekly INTEGER DEFAULT 0,
+				keep_monthly INTEGER DEFAULT 0,
+				enabled INTEGER DEFAULT 1,
+				last_run_at DATETIME,
+				last_status TEXT DEFAULT '',
+				created_at DATETIME NOT NULL,
+				updated_at DATETIME NOT NULL,
+				created_by INTEGER REFERENCES users(id) ON DELETE SET NULL
+			);
+
+			CREATE TABLE backup_job_runs (
+				id TEXT PRIMARY KEY,
+				job_id TEXT NOT NULL REFERENCES backup_jobs(id) ON DELETE CASCADE,
+				status TEXT NOT NULL,
+				trigger TEXT NOT NULL DEFAULT 'schedule',
+				started_at DATETIME NOT NULL,
+				finished_at DATETIME,
+				duration_ms INTEGER DEFAULT 0,
+				size_bytes INTEGER DEFAULT 0,
+				backup_ids TEXT DEFAULT '[]',
+				pruned TEXT DEFAULT '[]',
+				error TEXT DEFAULT ''
+			);
+
+			CREATE INDEX idx_backup_job_runs_job ON backup_job_runs(job_id, started_at);
+		`,
+	},
+	{
+		name: "034_create_backup_destinations",
+		up: `
+			-- Off-host backup targets; credentials live in the secrets table
+			CREATE TABLE backup_destinations (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL UNIQUE,
+				type TEXT NOT NULL,
+				endpoint TEXT DEFAULT '',
+				region TEXT DEFAULT '',
+				bucket TEXT DEFAULT '',
+				access_key_id TEXT DEFAULT '',
+				path_style INTEGER DEFAULT 0,
+				host TEXT DEFAULT '',
+				port INTEGER DEFAULT 0,
+				username TEXT DEFAULT '',
+				path TEXT DEFAULT '',
+				encrypt INTEGER DEFAULT 1,
+				secret_id TEXT REFERENCES secrets(id) ON DELETE SET NULL,
+				created_at DATETIME NOT NULL,
+				updated_at DATETIME NOT NULL,
+				created_by INTEGER REFERENCES users(id) ON DELETE SET NULL
+			);
+		`,
+	},
+	{
+		name: "035_widen_database_server_engines",
+		up: `
+			-- SQLite cannot alter a CHECK constraint, so the table is rebuilt. Foreign
+			-- keys are off meanwhile, or dropping it would cascade to databases.
+			PRAGMA foreign_keys = OFF;
+			CREATE TABLE database_servers_new (
+				id TEXT PRIMARY KEY,
+				container_id TEXT NOT NULL,
+				name TEXT NOT NULL UNIQUE,
+				engine TEXT NOT NULL CHECK(engine IN ('postgresql', 'mariadb', 'mysql', 'redis', 'valkey', 'mongodb', 'mssql')),
+				version TEXT NOT NULL,
+				image TEXT NOT NULL,
+				network TEXT NOT NULL DEFAULT 'podmangr-db',
+				status TEXT DEFAULT 'stopped' CHECK(status IN ('running', 'stopped', 'starting', 'stopping', 'error', 'unknown')),
+				root_password_encrypted TEXT NOT NULL,
+				volume_name TEXT,
+				internal_port INTEGER NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				created_by INTEGER REFERENCES users(id) ON DELETE SET NULL
+			);
+			INSERT INTO database_servers_new SELECT
+				id, container_id, name, engine, version, image, network,
+				status, root_password_encrypted, volume_name, internal_port,
+				created_at, updated_at, created_by
+			FROM database_servers;
+			DROP TABLE database_servers;
+			ALTER TABLE database_servers_new RENAME TO database_servers;
+			CREATE INDEX idx_database_servers_name ON database_servers(name);
+			CREATE INDEX idx_database_servers_engine ON database_servers(engine);
+			CREATE INDEX idx_database_servers_status ON database_servers(status);
+			CREATE INDEX idx_database_servers_container ON database_servers(container_id);
+			PRAGMA foreign_keys = ON;
+		`,
+	},
+	{
+		name: "036_add_database_server_settings",
+		up: `
+			-- Engine parameters (JSON object) and resource limits applied to the container
+			ALTER TABLE database_servers ADD COLUMN config TEXT NOT NULL DEFAULT '{}';
+			ALTER TABLE database_servers ADD COLUMN memory_limit INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE database_servers ADD COLUMN cpu_limit REAL NOT NULL DEFAULT 0;
+		`,
+	},
+	{
+		name: "037_create_database_users",
+		up: `
+			-- Users of a database besides the one it was created with
+			CREATE TABLE database_users (
+				id TEXT PRIMARY KEY,
+				database_id TEXT NOT NULL REFERENCES databases(id) ON DELETE CASCADE,
+				username TEXT NOT NULL,
+				role TEXT NOT NULL CHECK(role IN ('read-only', 'read-write', 'owner')),
+				password_encrypted TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+				UNIQUE(database_id, username)
+			);
+			CREATE INDEX idx_database_users_database_id ON database_users(database_id);
+		`,
+	},
+	{
+		name: "038_create_registry_credentials",
+		up: `
+			-- Logins for private container registries; tokens live in the secrets table.
+			-- Each login belongs to one Podman socket ("root" or "user:{name}") so a
+			-- rootless user's auth file never holds another socket's tokens.
+			CREATE TABLE registry_credentials (
+				id TEXT PRIMARY KEY,
+				socket_id TEXT NOT NULL,
+				registry TEXT NOT NULL,
+				username TEXT NOT NULL,
+				insecure INTEGER DEFAULT 0,
+				secret_id TEXT REFERENCES secrets(id) ON DELETE SET NULL,
+				created_at DATETIME NOT NULL,
+				updated_at DATETIME NOT NULL,
+				created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+				UNIQUE(socket_id, registry)
+			);
+		`,
+	},
+	{
+		name: "040_create_vulnerability_tables",
+		up: `
+			-- Imported OSV advisories and the package versions they affect
+			CREATE TABLE vulnerabilities (
+				id TEXT PRIMARY KEY,
+				aliases TEXT NOT NULL DEFAULT '[]',
+				summary TEXT,
+				severity TEXT NOT NULL DEFAULT 'unknown',
+				modified DATETIME,
+				imported_at DATETIME NOT NULL
+			);
+			CREATE TABLE vulnerability_ranges (
+				vulnerability_id TEXT NOT NULL REFERENCES vulnerabilities(id) ON DELETE CASCADE,
+				ecosystem TEXT NOT NULL,
+				package TEXT NOT NULL,
+				introduced TEXT,
+				fixed TEXT,
+				last_affected TEXT
+			);
+			CREATE INDEX idx_vulnerability_ranges_package ON vulnerability_ranges(package);
+			CREATE INDEX idx_vulnerability_ranges_vulnerability ON vulnerability_ranges(vulnerability_id);
+
+			-- Scan results by image manifest digest, with the package inventory
+			-- so reports can be refreshed after an import without rescanning
+			CREATE TABLE image_scans (
+				digest TEXT PRIMARY KEY,
+				image_id TEXT NOT NULL,
+				image TEXT NOT NULL,
+				os TEXT,
+				package_count INTEGER DEFAULT 0,
+				critical INTEGER DEFAULT 0,
+				high INTEGER DEFAULT 0,
+				medium INTEGER DEFAULT 0,
+				low INTEGER DEFAULT 0,
+				unknown INTEGER DEFAULT 0,
+				notes TEXT NOT NULL DEFAULT '[]',
+				error TEXT,
+				scanned_at DATETIME NOT NULL,
+				matched_at DATETIME NOT NULL
+			);
+			CREATE INDEX idx_image_scans_image_id ON image_scans(image_id);
+			CREATE TABLE image_packages (
+				digest TEXT NOT NULL REFERENCES image_scans(digest) ON DELETE CASCADE,
+				ecosystem TEXT NOT NULL,
+				name TEXT NOT NULL,
+				source TEXT,
+				version TEXT NOT NULL
+			);
+			CREATE INDEX idx_image_packages_digest ON image_packages(digest);
+			CREATE TABLE image_vulnerabilities (
+				digest TEXT NOT NULL REFERENCES image_scans(digest) ON DELETE CASCADE,
+				vulnerability_id TEXT NOT NULL,
+				package TEXT NOT NULL,
+				ecosystem TEXT NOT NULL,
+				installed_version TEXT NOT NULL,
+				fixed_version TEXT,
+				severity TEXT NOT NULL
+			);
+			CREATE INDEX idx_image_vulnerabilities_digest ON image_vulnerabilities(digest);
+		`,
+	},
 }
@@ -2,6 +2,7 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -26,16 +27,19 @@ func (r *DatabaseServerRepo) Create(s *models.DatabaseServer) error {
 	}
 	s.CreatedAt = time.Now()
 	s.UpdatedAt = time.Now()
+	config, _ := json.Marshal(s.Config)
 
 	_, err := r.db.Exec(`
 		INSERT INTO database_servers (
 			id, container_id, name, engine, version, image, network,
 			status, root_password_encrypted, volume_name, internal_port,
+			config, memory_limit, cpu_limit,
 			created_at, updated_at, created_by
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
 		s.ID, s.ContainerID, s.Name, s.Engine, s.Version, s.Image, s.Network,
 		s.Status, s.RootPasswordEncrypted, s.VolumeName, s.InternalPort,
+		string(config), s.MemoryLimit, s.CPULimit,
 		s.CreatedAt, s.UpdatedAt, s.CreatedBy,
 	)
 	return err
@@ -45,14 +49,17 @@ func (r *DatabaseServerRepo) Create(s *models.DatabaseServer) error {
 func (r *DatabaseServerRepo) GetByID(id string) (*models.DatabaseServer, error) {
 	s := &models.DatabaseServer{}
 	var volumeName sql.NullString
+	var config string
 	err := r.db.QueryRow(`
 		SELECT id, container_id, name, engine, version, image, network,
 			status, root_password_encrypted, volume_name, internal_port,
+			config, memory_limit, cpu_limit,
 			created_at, updated_at, created_by
 		FROM database_servers WHERE id = ?
 	`, id).Scan(
 		&s.ID, &s.ContainerID, &s.Name, &s.Engine, &s.Version, &s.Image, &s.Network,
 		&s.Status, &s.RootPasswordEncrypted, &volumeName, &s.InternalPort,
+		&config, &s.MemoryLimit, &s.CPULimit,
 		&s.CreatedAt, &s.UpdatedAt, &s.CreatedBy,
 	)
 	if err != nil {
@@ -61,6 +68,7 @@ func (r *DatabaseServerRepo) GetByID(id string) (*models.DatabaseServer, error)
 	if volumeName.Valid {
 		s.VolumeName = volumeName.String
 	}
+	json.Unmarshal([]byte(config), &s.Config)
 	return s, nil
 }
 
@@ -68,14 +76,17 @@ func (r *DatabaseServerRepo) GetByID(id string) (*models.DatabaseServer, error)
 func (r *DatabaseServerRepo) GetByName(name string) (*models.DatabaseServer, error) {
 	s := &models.DatabaseServer{}
 	var volumeName sql.NullString
+	var config string
 	err := r.db.QueryRow(`
 		SELECT id, container_id, name, engine, version, image, network,
 			status, root_password_encrypted, volume_name, internal_port,
+			config, memory_limit, cpu_limit,
 			created_at, updated_at, created_by
 		FROM database_servers WHERE name = ?
 	`, name).Scan(
 		&s.ID, &s.ContainerID, &s.Name, &s.Engine, &s.Version, &s.Image, &s.Network,
 		&s.Status, &s.RootPasswordEncrypted, &volumeName, &s.InternalPort,
+		&config, &s.MemoryLimit, &s.CPULimit,
 		&s.CreatedAt, &s.UpdatedAt, &s.CreatedBy,
 	)
 	if err != nil {
@@ -84,6 +95,7 @@ func (r *DatabaseServerRepo) GetByName(name string) (*models.DatabaseServer, err
 	if volumeName.Valid {
 		s.VolumeName = volumeName.String
 	}
+	json.Unmarshal([]byte(config), &s.Config)
 	return s, nil
 }
 
@@ -91,14 +103,17 @@ func (r *DatabaseServerRepo) GetByName(name string) (*models.DatabaseServer, err
 func (r *DatabaseServerRepo) GetByContainerID(containerID string) (*models.DatabaseServer, error) {
 	s := &models.DatabaseServer{}
 	var volumeName sql.NullString
+	var config string
 	err := r.db.QueryRow(`
 		SELECT id, container_id, name, engine, version, image, network,
 			status, root_password_encrypted, volume_name, internal_port,
+			config, memory_limit, cpu_limit,
 			created_at, updated_at, created_by
 		FROM database_servers WHERE container_id = ?
 	`, containerID).Scan(
 		&s.ID, &s.ContainerID, &s.Name, &s.Engine, &s.Version, &s.Image, &s.Network,
 		&s.Status, &s.RootPasswordEncrypted, &volumeName, &s.InternalPort,
+		&config, &s.MemoryLimit, &s.CPULimit,
 		&s.CreatedAt, &s.UpdatedAt, &s.CreatedBy,
 	)
 	if err != nil {
@@ -107,6 +122,7 @@ func (r *DatabaseServerRepo) GetByContainerID(containerID string) (*models.Datab
 	if volumeName.Valid {
 		s.VolumeName = volumeName.String
 	}
+	json.Unmarshal([]byte(config), &s.Config)
 	return s, nil
 }
 
@@ -144,16 +160,19 @@ func (r *DatabaseServerRepo) List() ([]models.DatabaseServerListItem, error) {
 // Update updates a database server
 func (r *DatabaseServerRepo) Update(s *models.DatabaseServer) error {
 	s.UpdatedAt = time.Now()
+	config, _ := json.Marshal(s.Config)
 	_, err := r.db.Exec(`
 		UPDATE database_servers SET
 			container_id = ?, name = ?, engine = ?, version = ?, image = ?,
 			network = ?, status = ?, root_password_encrypted = ?,
-			volume_name = ?, internal_port = ?, updated_at = ?
+			volume_name = ?, internal_port = ?,
+			config = ?, memory_limit = ?, cpu_limit = ?, updated_at = ?
 		WHERE id = ?
 	`,
 		s.ContainerID, s.Name, s.Engine, s.Version, s.Image,
 		s.Network, s.Status, s.RootPasswordEncrypted,
-		s.VolumeName, s.InternalPort, s.UpdatedAt, s.ID,
+		s.VolumeName, s.InternalPort,
+		string(config), s.MemoryLimit, s.CPULimit, s.UpdatedAt, s.ID,
 	)
 	return err
 }
@@ -330,6 +349,14 @@ func (r *DatabaseRepo) Delete(id string) error {
 	return err
 }
 
+// UpdatePassword stores a new encrypted password for the database's user
+func (r *DatabaseRepo) UpdatePassword(id, passwordEncrypted string) error {
+	_, err := r.db.Exec(`
+		UPDATE databases SET password_encrypted = ?, updated_at = ? WHERE id = ?
+	`, passwordEncrypted, time.Now(), id)
+	return err
+}
+
 // DeleteByServerID removes all databases in a server
 func (r *DatabaseRepo) DeleteByServerID(serverID string) error {
 	_, err := r.db.Exec("DELETE FROM databases WHERE server_id = ?", serverID)
@@ -347,3 +374,94 @@ func (r *DatabaseRepo) Exists(serverID, name string) (bool, error) {
 	}
 	return count > 0, nil
 }
+
+// ----------------------------------------
+// DatabaseUserRepo handles additional database users
+// ----------------------------------------
+
+// DatabaseUserRepo handles users of a database besides its primary user
+type DatabaseUserRepo struct {
+	db *sql.DB
+}
+
+// NewDatabaseUserRepo creates a new database user repository
+func NewDatabaseUserRepo() *DatabaseUserRepo {
+	return &DatabaseUserRepo{db: DB}
+}
+
+// Create adds a user to a database
+func (r *DatabaseUserRepo) Create(u *models.DatabaseUser) error {
+	if u.ID == "" {
+		u.ID = uuid.New().String()
+	}
+	u.CreatedAt = time.Now()
+	u.UpdatedAt = time.Now()
+
+	_, err := r.db.Exec(`
+		INSERT INTO database_users (
+			id, database_id, username, role, password_encrypted,
+			created_at, updated_at, created_by
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		u.ID, u.DatabaseID, u.Username, u.Role, u.PasswordEncrypted,
+		u.CreatedAt, u.UpdatedAt, u.CreatedBy,
+	)
+	return err
+}
+
+// GetByUsername retrieves a user of a database by name
+func (r *DatabaseUserRepo) GetByUsername(databaseID, username string) (*models.DatabaseUser, error) {
+	u := &models.DatabaseUser{}
+	err := r.db.QueryRow(`
+		SELECT id, database_id, username, role, password_encrypted,
+			created_at, updated_at, created_by
+		FROM database_users WHERE database_id = ? AND username = ?
+	`, databaseID, username).Scan(
+		&u.ID, &u.DatabaseID, &u.Username, &u.Role, &u.PasswordEncrypted,
+		&u.CreatedAt, &u.UpdatedAt, &u.CreatedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// ListByDatabaseID retrieves the additional users of a database
+func (r *DatabaseUserRepo) ListByDatabaseID(databaseID string) ([]models.DatabaseUser, error) {
+	rows, err := r.db.Query(`
+		SELECT id, database_id, username, role, created_at, updated_at, created_by
+		FROM database_users WHERE database_id = ?
+		ORDER BY username
+	`, databaseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.DatabaseUser
+	for rows.Next() {
+		var u models.DatabaseUser
+		if err := rows.Scan(
+			&u.ID, &u.DatabaseID, &u.Username, &u.Role, &u.CreatedAt, &u.UpdatedAt, &u.CreatedBy,
+		); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+// UpdatePassword stores a new encrypted password for a user
+func (r *DatabaseUserRepo) UpdatePassword(id, passwordEncrypted string) error {
+	_, err := r.db.Exec(`
+		UPDATE database_users SET password_encrypted = ?, updated_at = ? WHERE id = ?
+	`, passwordEncrypted, time.Now(), id)
+	return err
+}
+
+// Delete removes a user
+func (r *DatabaseUserRepo) Delete(id string) error {
+	_, err := r.db.Exec("DELETE FROM database_users WHERE id = ?", id)
+	return err
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+
+	"podmangr-backend/internal/models"
+)
+
+// RegistryCredentialRepo handles container registry logins
+type RegistryCredentialRepo struct {
+	db *sql.DB
+}
+
+// NewRegistryCredentialRepo creates a new registry credential repository
+func NewRegistryCredentialRepo() *RegistryCredentialRepo {
+	return &RegistryCredentialRepo{db: DB}
+}
+
+const registryCredentialColumns = `
+	id, socket_id, registry, username, insecure, COALESCE(secret_id, ''), created_at, updated_at, created_by
+`
+
+// Create adds a new registry credential
+func (r *RegistryCredentialRepo) Create(c *models.RegistryCredential) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+
+	_, err := r.db.Exec(`
+		INSERT INTO registry_credentials (id, socket_id, registry, username, insecure, secret_id, created_at, updated_at, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, c.ID, c.SocketID, c.Registry, c.Username, c.Insecure, nullString(c.SecretID), c.CreatedAt, c.UpdatedAt, c.CreatedBy)
+	return err
+}
+
+// GetByRegistry retrieves the credential of a registry on a Podman socket
+func (r *RegistryCredentialRepo) GetByRegistry(socketID, registry string) (*models.RegistryCredential, error) {
+	credentials, err := r.query("SELECT "+registryCredentialColumns+" FROM registry_credentials WHERE socket_id = ? AND registry = ?", socketID, registry)
+	if err != nil {
+		return nil, err
+	}
+	if len(credentials) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &credentials[0], nil
+}
+
+// List returns the registry credentials of a Podman socket ordered by registry
+func (r *RegistryCredentialRepo) List(socketID string) ([]models.RegistryCredential, error) {
+	return r.query("SELECT "+registryCredentialColumns+" FROM registry_credentials WHERE socket_id = ? ORDER BY registry", socketID)
+}
+
+func (r *RegistryCredentialRepo) query(query string, args ...interface{}) ([]models.RegistryCredential, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var credentials []models.RegistryCredential
+	for rows.Next() {
+		var c models.RegistryCredential
+		var createdBy sql.NullInt64
+		if err := rows.Scan(&c.ID, &c.SocketID, &c.Registry, &c.Username, &c.Insecure, &c.SecretID, &c.CreatedAt, &c.UpdatedAt, &createdBy); err != nil {
+			return nil, err
+		}
+		c.HasSecret = c.SecretID != ""
+		if createdBy.Valid {
+			c.CreatedBy = &createdBy.Int64
+		}
+		credentials = append(credentials, c)
+	}
+
+	return credentials, rows.Err()
+}
+
+// Update saves a registry credential
+func (r *RegistryCredentialRepo) Update(c *models.RegistryCredential) error {
+	c.UpdatedAt = time.Now()
+
+	_, err := r.db.Exec(`
+		UPDATE registry_credentials SET username = ?, insecure = ?, secret_id = ?, updated_at = ?
+		WHERE id = ?
+	`, c.Username, c.Insecure, nullString(c.SecretID), c.UpdatedAt, c.ID)
+	return err
+}
+
+// Delete removes a registry credential
+func (r *RegistryCredentialRepo) Delete(id string) error {
+	_, err := r.db.Exec("DELETE FROM registry_credentials WHERE id = ?", id)
+	return err
+}
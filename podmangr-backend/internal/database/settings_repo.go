@@ -73,4 +73,15 @@ const (
 	SettingAuthPAMEnabled      = "auth.pam_enabled"
 	SettingSessionTimeout      = "session.timeout_minutes"
 	SettingSessionMaxPerUser   = "session.max_per_user"
+	SettingMetricsEnabled      = "metrics.enabled"
+	SettingMetricsInterval     = "metrics.interval_seconds"
+	SettingMetricsRetention    = "metrics.retention_hours"
+	SettingUpdatesEnabled      = "updates.enabled"
+	SettingUpdatesSchedule     = "updates.schedule"
+	SettingUpdatesPolicy       = "updates.default_policy"
+	SettingScanEnabled         = "scan.enabled"
+	SettingScanSchedule        = "scan.schedule"
+	SettingPruneEnabled        = "prune.enabled"
+	SettingPruneSchedule       = "prune.schedule"
+	SettingPrunePolicy         = "prune.policy"
 )
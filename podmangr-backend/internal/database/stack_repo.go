@@ -26,6 +26,7 @@ func InitStackTable() error {
 			description TEXT DEFAULT '',
 			compose_content TEXT NOT NULL,
 			env_content TEXT DEFAULT '',
+			override_content TEXT DEFAULT '',
 			status TEXT DEFAULT 'stopped',
 			path TEXT DEFAULT '',
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
@@ -69,7 +70,7 @@ func (r *StackRepo) List() ([]models.StackListItem, error) {
 // GetByID returns a stack by ID
 func (r *StackRepo) GetByID(id string) (*models.Stack, error) {
 	query := `
-		SELECT id, name, description, compose_content, env_content, status, path, created_at, updated_at, created_by
+		SELECT id, name, description, compose_content, env_content, COALESCE(override_content, ''), status, path, created_at, updated_at, created_by
 		FROM stacks
 		WHERE id = ?
 	`
@@ -78,7 +79,7 @@ func (r *StackRepo) GetByID(id string) (*models.Stack, error) {
 	var status string
 	var createdBy sql.NullInt64
 	err := DB.QueryRow(query, id).Scan(
-		&s.ID, &s.Name, &s.Description, &s.ComposeContent, &s.EnvContent,
+		&s.ID, &s.Name, &s.Description, &s.ComposeContent, &s.EnvContent, &s.OverrideContent,
 		&status, &s.Path, &s.CreatedAt, &s.UpdatedAt, &createdBy,
 	)
 	if err != nil {
@@ -96,7 +97,7 @@ func (r *StackRepo) GetByID(id string) (*models.Stack, error) {
 // GetByName returns a stack by name
 func (r *StackRepo) GetByName(name string) (*models.Stack, error) {
 	query := `
-		SELECT id, name, description, compose_content, env_content, status, path, created_at, updated_at, created_by
+		SELECT id, name, description, compose_content, env_content, COALESCE(override_content, ''), status, path, created_at, updated_at, created_by
 		FROM stacks
 		WHERE name = ?
 	`
@@ -105,7 +106,7 @@ func (r *StackRepo) GetByName(name string) (*models.Stack, error) {
 	var status string
 	var createdBy sql.NullInt64
 	err := DB.QueryRow(query, name).Scan(
-		&s.ID, &s.Name, &s.Description, &s.ComposeContent, &s.EnvContent,
+		&s.ID, &s.Name, &s.Description, &s.ComposeContent, &s.EnvContent, &s.OverrideContent,
 		&status, &s.Path, &s.CreatedAt, &s.UpdatedAt, &createdBy,
 	)
 	if err != nil {
@@ -129,12 +130,12 @@ func (r *StackRepo) Create(s *models.Stack) error {
 	s.UpdatedAt = time.Now()
 
 	query := `
-		INSERT INTO stacks (id, name, description, compose_content, env_content, status, path, created_at, updated_at, created_by)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO stacks (id, name, description, compose_content, env_content, override_content, status, path, created_at, updated_at, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err := DB.Exec(query,
-		s.ID, s.Name, s.Description, s.ComposeContent, s.EnvContent,
+		s.ID, s.Name, s.Description, s.ComposeContent, s.EnvContent, s.OverrideContent,
 		string(s.Status), s.Path, s.CreatedAt, s.UpdatedAt, s.CreatedBy,
 	)
 	return err
@@ -146,12 +147,12 @@ func (r *StackRepo) Update(s *models.Stack) error {
 
 	query := `
 		UPDATE stacks
-		SET name = ?, description = ?, compose_content = ?, env_content = ?, status = ?, path = ?, updated_at = ?
+		SET name = ?, description = ?, compose_content = ?, env_content = ?, override_content = ?, status = ?, path = ?, updated_at = ?
 		WHERE id = ?
 	`
 
 	_, err := DB.Exec(query,
-		s.Name, s.Description, s.ComposeContent, s.EnvContent,
+		s.Name, s.Description, s.ComposeContent, s.EnvContent, s.OverrideContent,
 		string(s.Status), s.Path, s.UpdatedAt, s.ID,
 	)
 	return err
@@ -0,0 +1,339 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"podmangr-backend/internal/models"
+)
+
+// VulnerabilityRepo handles imported advisories and image scan reports
+type VulnerabilityRepo struct {
+	db *sql.DB
+}
+
+// NewVulnerabilityRepo creates a new vulnerability repository
+func NewVulnerabilityRepo() *VulnerabilityRepo {
+	return &VulnerabilityRepo{db: DB}
+}
+
+// ImportAdvisories stores advisories, replacing those with the same ID, and
+// removes withdrawn ones, in a single transaction
+func (r *VulnerabilityRepo) ImportAdvisories(advisories []models.VulnerabilityAdvisory, withdrawn []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	deleteRanges, err := tx.Prepare("DELETE FROM vulnerability_ranges WHERE vulnerability_id = ?")
+	if err != nil {
+		return err
+	}
+	defer deleteRanges.Close()
+	deleteAdvisory, err := tx.Prepare("DELETE FROM vulnerabilities WHERE id = ?")
+	if err != nil {
+		return err
+	}
+	defer deleteAdvisory.Close()
+	upsert, err := tx.Prepare(`
+		INSERT INTO vulnerabilities (id, aliases, summary, severity, modified, imported_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			aliases = excluded.aliases,
+			summary = excluded.summary,
+			severity = excluded.severity,
+			modified = excluded.modified,
+			imported_at = excluded.imported_at
+	`)
+	if err != nil {
+		return err
+	}
+	defer upsert.Close()
+	insertRange, err := tx.Prepare(`
+		INSERT INTO vulnerability_ranges (vulnerability_id, ecosystem, package, introduced, fixed, last_affected)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer insertRange.Close()
+
+	for _, id := range withdrawn {
+		if _, err := deleteRanges.Exec(id); err != nil {
+			return err
+		}
+		if _, err := deleteAdvisory.Exec(id); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for _, a := range advisories {
+		if _, err := upsert.Exec(a.ID, sliceToJSON(a.Aliases), nullString(a.Summary), a.Severity, a.Modified, now); err != nil {
+			return err
+		}
+		if _, err := deleteRanges.Exec(a.ID); err != nil {
+			return err
+		}
+		for _, rg := range a.Ranges {
+			if _, err := insertRange.Exec(
+				a.ID, rg.Ecosystem, rg.Package, nullString(rg.Introduced), nullString(rg.Fixed), nullString(rg.LastAffected),
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RangesForPackage returns the affected version ranges of a package name in every ecosystem
+func (r *VulnerabilityRepo) RangesForPackage(name string) ([]models.VulnerabilityRange, error) {
+	rows, err := r.db.Query(`
+		SELECT vr.vulnerability_id, vr.ecosystem, vr.package, COALESCE(vr.introduced, ''),
+			COALESCE(vr.fixed, ''), COALESCE(vr.last_affected, ''), v.severity, COALESCE(v.summary, '')
+		FROM vulnerability_ranges vr
+		JOIN vulnerabilities v ON v.id = vr.vulnerability_id
+		WHERE vr.package = ?
+	`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ranges []models.VulnerabilityRange
+	for rows.Next() {
+		var rg models.VulnerabilityRange
+		if err := rows.Scan(
+			&rg.AdvisoryID, &rg.Ecosystem, &rg.Package, &rg.Introduced,
+			&rg.Fixed, &rg.LastAffected, &rg.Severity, &rg.Summary,
+		); err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, rg)
+	}
+	return ranges, rows.Err()
+}
+
+// Status returns the size of the imported database and when it was last imported
+func (r *VulnerabilityRepo) Status() (*models.VulnerabilityDatabaseStatus, error) {
+	status := &models.VulnerabilityDatabaseStatus{Ecosystems: make(map[string]int)}
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM vulnerabilities").Scan(&status.Advisories); err != nil {
+		return nil, err
+	}
+	var importedAt sql.NullTime
+	err := r.db.QueryRow("SELECT imported_at FROM vulnerabilities ORDER BY imported_at DESC LIMIT 1").Scan(&importedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if importedAt.Valid {
+		status.ImportedAt = &importedAt.Time
+	}
+
+	rows, err := r.db.Query("SELECT ecosystem, COUNT(*) FROM vulnerability_ranges GROUP BY ecosystem")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ecosystem string
+		var count int
+		if err := rows.Scan(&ecosystem, &count); err != nil {
+			return nil, err
+		}
+		status.Ecosystems[ecosystem] = count
+	}
+	return status, rows.Err()
+}
+
+// SaveReport stores the result of a scan. The package inventory is replaced
+// when packages is not nil and kept otherwise.
+func (r *VulnerabilityRepo) SaveReport(report *models.ImageVulnerabilityReport, packages []models.ImagePackage) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	s := report.Summary
+	if _, err := tx.Exec(`
+		INSERT INTO image_scans (
+			digest, image_id, image, os, package_count, critical, high, medium, low, unknown,
+			notes, error, scanned_at, matched_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(digest) DO UPDATE SET
+			image_id = excluded.image_id,
+			image = excluded.image,
+			os = excluded.os,
+			package_count = excluded.package_count,
+			critical = excluded.critical,
+			high = excluded.high,
+			medium = excluded.medium,
+			low = excluded.low,
+			unknown = excluded.unknown,
+			notes = excluded.notes,
+			error = excluded.error,
+			scanned_at = excluded.scanned_at,
+			matched_at = excluded.matched_at
+	`,
+		report.Digest, report.ImageID, report.Image, nullString(report.OS), report.PackageCount,
+		s.Critical, s.High, s.Medium, s.Low, s.Unknown,
+		sliceToJSON(report.Notes), nullString(report.Error), report.ScannedAt, report.MatchedAt,
+	); err != nil {
+		return err
+	}
+
+	if packages != nil {
+		if _, err := tx.Exec("DELETE FROM image_packages WHERE digest = ?", report.Digest); err != nil {
+			return err
+		}
+		stmt, err := tx.Prepare("INSERT INTO image_packages (digest, ecosystem, name, source, version) VALUES (?, ?, ?, ?, ?)")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, p := range packages {
+			if _, err := stmt.Exec(report.Digest, p.Ecosystem, p.Name, nullString(p.Source), p.Version); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM image_vulnerabilities WHERE digest = ?", report.Digest); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`
+		INSERT INTO image_vulnerabilities (
+			digest, vulnerability_id, package, ecosystem, installed_version, fixed_version, severity
+		) VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, v := range report.Vulnerabilities {
+		if _, err := stmt.Exec(
+			report.Digest, v.ID, v.Package, v.Ecosystem, v.InstalledVersion, nullString(v.FixedVersion), v.Severity,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetPackages returns the package inventory of a scanned image
+func (r *VulnerabilityRepo) GetPackages(digest string) ([]models.ImagePackage, error) {
+	rows, err := r.db.Query(`
+		SELECT ecosystem, name, COALESCE(source, ''), version
+		FROM image_packages WHERE digest = ? ORDER BY name
+	`, digest)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	packages := make([]models.ImagePackage, 0)
+	for rows.Next() {
+		var p models.ImagePackage
+		if err := rows.Scan(&p.Ecosystem, &p.Name, &p.Source, &p.Version); err != nil {
+			return nil, err
+		}
+		packages = append(packages, p)
+	}
+	return packages, rows.Err()
+}
+
+const imageScanColumns = `
+	digest, image_id, image, COALESCE(os, ''), package_count, critical, high, medium, low, unknown,
+	notes, COALESCE(error, ''), scanned_at, matched_at
+`
+
+// GetReport returns the scan report of an image by digest or image ID,
+// with its vulnerabilities ordered by severity
+func (r *VulnerabilityRepo) GetReport(digestOrImageID string) (*models.ImageVulnerabilityReport, error) {
+	reports, err := r.queryScans(
+		"SELECT "+imageScanColumns+" FROM image_scans WHERE digest = ? OR image_id = ? ORDER BY scanned_at DESC LIMIT 1",
+		digestOrImageID, digestOrImageID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	report := &reports[0]
+
+	rows, err := r.db.Query(`
+		SELECT iv.vulnerability_id, COALESCE(v.aliases, '[]'), iv.package, iv.ecosystem, iv.installed_version,
+			COALESCE(iv.fixed_version, ''), iv.severity, COALESCE(v.summary, '')
+		FROM image_vulnerabilities iv
+		LEFT JOIN vulnerabilities v ON v.id = iv.vulnerability_id
+		WHERE iv.digest = ?
+		ORDER BY CASE iv.severity
+			WHEN 'critical' THEN 0 WHEN 'high' THEN 1 WHEN 'medium' THEN 2 WHEN 'low' THEN 3 ELSE 4
+		END, iv.package, iv.vulnerability_id
+	`, report.Digest)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report.Vulnerabilities = make([]models.ImageVulnerability, 0)
+	for rows.Next() {
+		var v models.ImageVulnerability
+		var aliases string
+		if err := rows.Scan(
+			&v.ID, &aliases, &v.Package, &v.Ecosystem, &v.InstalledVersion,
+			&v.FixedVersion, &v.Severity, &v.Summary,
+		); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(aliases), &v.Aliases)
+		report.Vulnerabilities = append(report.Vulnerabilities, v)
+	}
+	return report, rows.Err()
+}
+
+// ListReports returns every scan report without its vulnerabilities
+func (r *VulnerabilityRepo) ListReports() ([]models.ImageVulnerabilityReport, error) {
+	return r.queryScans("SELECT " + imageScanColumns + " FROM image_scans ORDER BY image")
+}
+
+// DeleteReport removes the report and inventory of an image digest
+func (r *VulnerabilityRepo) DeleteReport(digest string) error {
+	for _, table := range []string{"image_vulnerabilities", "image_packages", "image_scans"} {
+		if _, err := r.db.Exec("DELETE FROM "+table+" WHERE digest = ?", digest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *VulnerabilityRepo) queryScans(query string, args ...interface{}) ([]models.ImageVulnerabilityReport, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []models.ImageVulnerabilityReport
+	for rows.Next() {
+		var rep models.ImageVulnerabilityReport
+		var notes string
+		s := &rep.Summary
+		if err := rows.Scan(
+			&rep.Digest, &rep.ImageID, &rep.Image, &rep.OS, &rep.PackageCount,
+			&s.Critical, &s.High, &s.Medium, &s.Low, &s.Unknown,
+			&notes, &rep.Error, &rep.ScannedAt, &rep.MatchedAt,
+		); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(notes), &rep.Notes)
+		s.ScannedAt = rep.ScannedAt
+		reports = append(reports, rep)
+	}
+	return reports, rows.Err()
+}
@@ -46,4 +46,9 @@ const (
 	// Route actions
 	ActionRouteAdd    = "route.add"
 	ActionRouteDelete = "route.delete"
+
+	// Quadlet actions
+	ActionQuadletInstall = "quadlet.install"
+	ActionQuadletControl = "quadlet.control"
+	ActionQuadletRemove  = "quadlet.remove"
 )
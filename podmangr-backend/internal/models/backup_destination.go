@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Remote backup destination types
+const (
+	BackupDestinationS3    = "s3"    // S3-compatible object storage
+	BackupDestinationSFTP  = "sftp"  // SFTP server
+	BackupDestinationRsync = "rsync" // rsync over SSH
+)
+
+// BackupDestinationSecretCategory is the secrets store category of destination credentials
+const BackupDestinationSecretCategory = "backup_destination"
+
+// BackupDestination is an off-host location backups are copied to. Its
+// credential (the S3 secret access key or the SSH private key) is kept in the
+// secrets store.
+type BackupDestination struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"` // s3, sftp, rsync
+
+	// S3
+	Endpoint    string `json:"endpoint,omitempty"` // e.g. https://s3.eu-central-1.amazonaws.com or http://minio:9000
+	Region      string `json:"region,omitempty"`
+	Bucket      string `json:"bucket,omitempty"`
+	AccessKeyID string `json:"access_key_id,omitempty"`
+	PathStyle   bool   `json:"path_style,omitempty"` // Bucket in the URL path, as most self-hosted stores expect
+
+	// SFTP and rsync
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"` // Defaults to 22
+	Username string `json:"username,omitempty"`
+
+	Path      string    `json:"path"`    // Key prefix or remote directory
+	Encrypt   bool      `json:"encrypt"` // Encrypt backups before they leave the host
+	SecretID  string    `json:"-"`
+	HasSecret bool      `json:"has_secret"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	CreatedBy *int64    `json:"created_by,omitempty"`
+}
+
+// BackupDestinationRequest represents the request body for creating or replacing a backup destination
+type BackupDestinationRequest struct {
+	Name        string `json:"name" validate:"required"`
+	Type        string `json:"type" validate:"required"`
+	Endpoint    string `json:"endpoint,omitempty"`
+	Region      string `json:"region,omitempty"`
+	Bucket      string `json:"bucket,omitempty"`
+	AccessKeyID string `json:"access_key_id,omitempty"`
+	PathStyle   bool   `json:"path_style,omitempty"`
+	Host        string `json:"host,omitempty"`
+	Port        int    `json:"port,omitempty"`
+	Username    string `json:"username,omitempty"`
+	Path        string `json:"path"`
+	Encrypt     bool   `json:"encrypt"`
+	Secret      string `json:"secret,omitempty"` // Secret access key or SSH private key; unchanged if empty on update
+}
+
+// RemoteBackup is a backup stored at a backup destination
+type RemoteBackup struct {
+	ContainerBackup
+	DestinationID string `json:"destination_id"`
+	Encrypted     bool   `json:"encrypted"`
+	StoredBytes   int64  `json:"stored_bytes"` // Size of the uploaded archive
+}
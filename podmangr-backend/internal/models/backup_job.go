@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// Backup job targets
+const (
+	BackupTargetContainer      = "container"       // TargetID is the container name
+	BackupTargetStack          = "stack"           // TargetID is the stack ID
+	BackupTargetDatabaseServer = "database_server" // TargetID is the database server ID, dumped as a whole
+	BackupTargetDatabase       = "database"        // TargetID is the ID of a database within a server
+)
+
+// Backup job destinations. A job can also name the ID of a BackupDestination.
+const (
+	BackupDestinationDefault  = "default"  // BackupSettings.DefaultPath
+	BackupDestinationExternal = "external" // BackupSettings.ExternalDrivePath
+)
+
+// Backup job run statuses
+const (
+	BackupRunRunning   = "running"
+	BackupRunSucceeded = "succeeded"
+	BackupRunPartial   = "partial" // Some containers of the target failed
+	BackupRunFailed    = "failed"
+)
+
+// BackupRetention is a grandfather-father-son retention policy. A backup is
+// kept if any rule selects it; with all rules at zero nothing is pruned.
+type BackupRetention struct {
+	KeepLast    int `json:"keep_last"`    // Most recent backups
+	KeepDaily   int `json:"keep_daily"`   // Newest backup of each of the last N days with backups
+	KeepWeekly  int `json:"keep_weekly"`  // Newest backup of each of the last N ISO weeks
+	KeepMonthly int `json:"keep_monthly"` // Newest backup of each of the last N months
+}
+
+// BackupJob is a scheduled backup of a container, stack, database server or database
+type BackupJob struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	TargetType  string          `json:"target_type"` // container, stack, database_server, database
+	TargetID    string          `json:"target_id"`
+	Schedule    string          `json:"schedule"`    // Cron expression
+	Destination string          `json:"destination"` // default, external or a backup destination ID
+	Retention   BackupRetention `json:"retention"`
+	Enabled     bool            `json:"enabled"`
+	LastRunAt   *time.Time      `json:"last_run_at,omitempty"`
+	LastStatus  string          `json:"last_status,omitempty"`
+	NextRunAt   *time.Time      `json:"next_run_at,omitempty"` // Computed, not stored
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	CreatedBy   *int64          `json:"created_by,omitempty"`
+}
+
+// BackupJobRequest represents the request body for creating or replacing a backup job
+type BackupJobRequest struct {
+	Name        string          `json:"name" validate:"required"`
+	TargetType  string          `json:"target_type" validate:"required"`
+	TargetID    string          `json:"target_id" validate:"required"`
+	Schedule    string          `json:"schedule" validate:"required"`
+	Destination string          `json:"destination,omitempty"` // Defaults to "default"
+	Retention   BackupRetention `json:"retention"`
+	Enabled     bool            `json:"enabled"`
+}
+
+// BackupJobRun records one execution of a backup job
+type BackupJobRun struct {
+	ID         string     `json:"id"`
+	JobID      string     `json:"job_id"`
+	Status     string     `json:"status"`
+	Trigger    string     `json:"trigger"` // schedule, manual
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMs int64      `json:"duration_ms"`
+	SizeBytes  int64      `json:"size_bytes"`
+	BackupIDs  []string   `json:"backup_ids"`
+	Pruned     []string   `json:"pruned,omitempty"` // Backups removed by the retention policy
+	Error      string     `json:"error,omitempty"`
+}
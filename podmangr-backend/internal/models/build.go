@@ -0,0 +1,27 @@
+package models
+
+// BuildImageRequest represents a request to build an image. The context is
+// an uploaded tarball, a directory on the host or a git repository; without
+// one, the inline Containerfile is built with an empty context.
+type BuildImageRequest struct {
+	Tags              []string          `json:"tags"`
+	ContextUploadID   string            `json:"context_upload_id,omitempty"`  // Returned by POST /api/images/build/context
+	ContextDir        string            `json:"context_dir,omitempty"`        // Absolute directory on the host
+	GitURL            string            `json:"git_url,omitempty"`            // e.g. https://github.com/org/repo.git#main:subdir
+	Containerfile     string            `json:"containerfile,omitempty"`      // Inline Containerfile content
+	ContainerfilePath string            `json:"containerfile_path,omitempty"` // Relative to the context; Containerfile or Dockerfile by default
+	BuildArgs         map[string]string `json:"build_args,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	Target            string            `json:"target,omitempty"`     // Stage of a multi-stage build
+	Platform          string            `json:"platform,omitempty"`   // e.g. linux/arm64
+	NoCache           bool              `json:"no_cache,omitempty"`   // Don't use cached layers
+	Pull              bool              `json:"pull,omitempty"`       // Always pull base images
+	CacheFrom         []string          `json:"cache_from,omitempty"` // Repositories to reuse cached layers from
+	CacheTo           []string          `json:"cache_to,omitempty"`   // Repositories to push cached layers to
+}
+
+// BuildContext is an uploaded build context, kept until a build uses it
+type BuildContext struct {
+	ID   string `json:"id"`
+	Size int64  `json:"size"` // Bytes received
+}
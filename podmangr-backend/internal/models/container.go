@@ -90,6 +90,7 @@ type ContainerMetrics struct {
 	ID          int64     `json:"id"`
 	ContainerID string    `json:"container_id"`
 	Timestamp   time.Time `json:"timestamp"`
+	Resolution  int       `json:"resolution"` // Bucket size in seconds, 0 for raw samples
 	CPUPercent  float64   `json:"cpu_percent"`
 	MemoryUsed  int64     `json:"memory_used"`
 	MemoryLimit int64     `json:"memory_limit"`
@@ -161,12 +162,14 @@ type DeployComposeRequest struct {
 
 // Image represents a container image
 type Image struct {
-	ID          string    `json:"id"`
-	Repository  string    `json:"repository"`
-	Tag         string    `json:"tag"`
-	Size        int64     `json:"size"`
-	Created     time.Time `json:"created"`
-	Containers  int       `json:"containers"` // Number of containers using this image
+	ID              string                `json:"id"`
+	Repository      string                `json:"repository"`
+	Tag             string                `json:"tag"`
+	Size            int64                 `json:"size"`
+	Created         time.Time             `json:"created"`
+	Containers      int                   `json:"containers"` // Number of containers using this image
+	Digest          string                `json:"digest,omitempty"`
+	Vulnerabilities *VulnerabilitySummary `json:"vulnerabilities,omitempty"` // Of the latest scan, if any
 }
 
 // PullImageRequest represents a request to pull an image
@@ -290,18 +293,19 @@ const (
 
 // Stack represents a compose-based stack deployment
 type Stack struct {
-	ID             string      `json:"id"`
-	Name           string      `json:"name"`
-	Description    string      `json:"description"`
-	ComposeContent string      `json:"compose_content"`
-	Status         StackStatus `json:"status"`
-	ContainerCount int         `json:"container_count"`
-	RunningCount   int         `json:"running_count"`
-	CreatedAt      time.Time   `json:"created_at"`
-	UpdatedAt      time.Time   `json:"updated_at"`
-	CreatedBy      *int64      `json:"created_by,omitempty"`
-	EnvContent     string      `json:"env_content,omitempty"`
-	Path           string      `json:"path"`
+	ID              string      `json:"id"`
+	Name            string      `json:"name"`
+	Description     string      `json:"description"`
+	ComposeContent  string      `json:"compose_content"`
+	Status          StackStatus `json:"status"`
+	ContainerCount  int         `json:"container_count"`
+	RunningCount    int         `json:"running_count"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+	CreatedBy       *int64      `json:"created_by,omitempty"`
+	EnvContent      string      `json:"env_content,omitempty"`
+	OverrideContent string      `json:"override_content,omitempty"` // Merged over ComposeContent
+	Path            string      `json:"path"`
 }
 
 // StackListItem is a lightweight view for listing stacks
@@ -327,19 +331,21 @@ type StackContainer struct {
 
 // CreateStackRequest represents the request to create a stack
 type CreateStackRequest struct {
-	Name           string `json:"name" validate:"required,min=1,max=64"`
-	Description    string `json:"description,omitempty"`
-	ComposeContent string `json:"compose_content" validate:"required"`
-	EnvContent     string `json:"env_content,omitempty"`
-	Deploy         bool   `json:"deploy"`
+	Name            string `json:"name" validate:"required,min=1,max=64"`
+	Description     string `json:"description,omitempty"`
+	ComposeContent  string `json:"compose_content" validate:"required"`
+	EnvContent      string `json:"env_content,omitempty"`
+	OverrideContent string `json:"override_content,omitempty"`
+	Deploy          bool   `json:"deploy"`
 }
 
 // UpdateStackRequest represents the request to update a stack
 type UpdateStackRequest struct {
-	Name           *string `json:"name,omitempty"`
-	Description    *string `json:"description,omitempty"`
-	ComposeContent *string `json:"compose_content,omitempty"`
-	EnvContent     *string `json:"env_content,omitempty"`
+	Name            *string `json:"name,omitempty"`
+	Description     *string `json:"description,omitempty"`
+	ComposeContent  *string `json:"compose_content,omitempty"`
+	EnvContent      *string `json:"env_content,omitempty"`
+	OverrideContent *string `json:"override_content,omitempty"`
 }
 
 // ContainerBackup represents a backup of container volumes before an update
@@ -347,11 +353,13 @@ type ContainerBackup struct {
 	ID            string            `json:"id"`
 	ContainerID   string            `json:"container_id"`
 	ContainerName string            `json:"container_name"`
-	Image         string            `json:"image"`         // Image at time of backup
-	BackupPath    string            `json:"backup_path"`   // Path where backup is stored
-	BackupType    string            `json:"backup_type"`   // "bind" or "volume"
-	Mounts        []BackupMount     `json:"mounts"`        // What was backed up
-	SizeBytes     int64             `json:"size_bytes"`    // Total backup size
+	Image         string            `json:"image"`                 // Image at time of backup
+	BackupPath    string            `json:"backup_path"`           // Path where backup is stored
+	BackupType    string            `json:"backup_type"`           // "bind", "volume" or "mixed"
+	Format        string            `json:"format,omitempty"`      // "archive", "chunked", or empty for plain copies
+	Compression   string            `json:"compression,omitempty"` // Compression of archives and chunks
+	Mounts        []BackupMount     `json:"mounts"`                // What was backed up
+	SizeBytes     int64             `json:"size_bytes"`            // Total backup size
 	CreatedAt     time.Time         `json:"created_at"`
 	CreatedBy     *int64            `json:"created_by,omitempty"`
 	Metadata      map[string]string `json:"metadata,omitempty"` // Additional info
@@ -364,17 +372,120 @@ type BackupMount struct {
 	BackupPath  string `json:"backup_path"`  // Where this mount is backed up
 	Type        string `json:"type"`         // bind, volume
 	SizeBytes   int64  `json:"size_bytes"`
+	// Named volumes are recreated with their driver, labels and options if missing
+	Driver      string            `json:"driver,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Options     map[string]string `json:"options,omitempty"`
+}
+
+// Backup formats
+const (
+	BackupFormatArchive = "archive" // One compressed tar archive per mount
+	BackupFormatChunked = "chunked" // Files split into content-addressed chunks shared between backups
+)
+
+// BackupManifest lists every entry of a backup together with its checksum.
+// It is stored as manifest.json next to backup.json.
+type BackupManifest struct {
+	Version     int          `json:"version"`
+	Format      string       `json:"format"`
+	Compression string       `json:"compression"`
+	ChunkSize   int          `json:"chunk_size,omitempty"`
+	Files       []BackupFile `json:"files"`
+}
+
+// BackupFile is a single entry of a backed up mount
+type BackupFile struct {
+	Mount   int       `json:"mount"` // Index into ContainerBackup.Mounts
+	Path    string    `json:"path"`  // Relative to the mount root
+	Type    string    `json:"type"`  // file, dir, symlink, hardlink
+	Mode    int64     `json:"mode"`
+	UID     int       `json:"uid"`
+	GID     int       `json:"gid"`
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size"`
+	SHA256  string    `json:"sha256,omitempty"` // Regular files only
+	Link    string    `json:"link,omitempty"`   // Symlink or hardlink target
+	Chunks  []string  `json:"chunks,omitempty"` // SHA-256 of each chunk, chunked format only
+}
+
+// BackupVerifyResult is the outcome of checking a backup against its manifest
+type BackupVerifyResult struct {
+	BackupID     string   `json:"backup_id"`
+	Valid        bool     `json:"valid"`
+	FilesChecked int      `json:"files_checked"`
+	BytesChecked int64    `json:"bytes_checked"`
+	Errors       []string `json:"errors,omitempty"`
 }
 
 // UpdateContainerImageRequest represents a request to update a container's image
 type UpdateContainerImageRequest struct {
-	ContainerID     string `json:"container_id" validate:"required"`
-	NewImage        string `json:"new_image,omitempty"`        // New image (defaults to same image:latest)
-	CreateBackup    bool   `json:"create_backup"`              // Whether to backup volumes before update
-	BackupPath      string `json:"backup_path,omitempty"`      // Where to store backup (default: ~/.podmangr/backups)
-	OverwriteBackup bool   `json:"overwrite_backup"`           // Overwrite existing backup if present
-	StopTimeout     int    `json:"stop_timeout,omitempty"`     // Timeout for stopping container (default: 30)
-	RemoveOld       bool   `json:"remove_old"`                 // Remove old container after successful update
+	ContainerID     string       `json:"container_id" validate:"required"`
+	NewImage        string       `json:"new_image,omitempty"`      // New image (defaults to same image:latest)
+	CreateBackup    bool         `json:"create_backup"`            // Whether to backup volumes before update
+	BackupPath      string       `json:"backup_path,omitempty"`    // Where to store backup (default: ~/.podmangr/backups)
+	OverwriteBackup bool         `json:"overwrite_backup"`         // Overwrite existing backup if present
+	StopTimeout     int          `json:"stop_timeout,omitempty"`   // Timeout for stopping container (default: 30)
+	RemoveOld       bool         `json:"remove_old"`               // Remove old container after successful update
+	SkipPull        bool         `json:"skip_pull,omitempty"`      // Use the image already in local storage
+	HealthTimeout   int          `json:"health_timeout,omitempty"` // Seconds the new container has to become healthy (default: 60)
+	HealthProbe     *HealthProbe `json:"health_probe,omitempty"`   // Probe a published port instead of relying on the Podman healthcheck
+}
+
+// HealthProbe checks a container through one of its published ports
+type HealthProbe struct {
+	Type         string `json:"type"`                    // "http" or "tcp"
+	Port         int    `json:"port"`                    // Container port, resolved to its published host port
+	Path         string `json:"path,omitempty"`          // HTTP path (default: /)
+	ExpectStatus int    `json:"expect_status,omitempty"` // Expected HTTP status (default: any 2xx or 3xx)
+}
+
+// Update outcomes recorded in the update history
+const (
+	UpdateStatusSucceeded  = "succeeded"
+	UpdateStatusRolledBack = "rolled_back"
+	UpdateStatusFailed     = "failed"
+)
+
+// ContainerUpdateRecord is the outcome of one image update of a container
+type ContainerUpdateRecord struct {
+	ID             string     `json:"id"`
+	ContainerName  string     `json:"container_name"`
+	OldContainerID string     `json:"old_container_id"`
+	NewContainerID string     `json:"new_container_id,omitempty"`
+	OldImage       string     `json:"old_image"`
+	NewImage       string     `json:"new_image"`
+	Status         string     `json:"status"`           // succeeded, rolled_back or failed
+	Reason         string     `json:"reason,omitempty"` // Why the update was rolled back or failed
+	BackupID       string     `json:"backup_id,omitempty"`
+	StartedAt      time.Time  `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+	CreatedBy      *int64     `json:"created_by,omitempty"`
+}
+
+// Update policies for scheduled update checks
+const (
+	UpdatePolicyDisabled = "disabled" // Not checked
+	UpdatePolicyNotify   = "notify"   // Checked, updates are only reported
+	UpdatePolicyPatch    = "patch"    // Applied automatically for version tags like 1.27 or v2.3.1
+	UpdatePolicyAuto     = "auto"     // Always applied automatically
+)
+
+// ContainerUpdateState is the result of the latest scheduled update check of a container
+type ContainerUpdateState struct {
+	SocketID        string     `json:"socket_id"`
+	ContainerName   string     `json:"container_name"`
+	ContainerID     string     `json:"container_id"`
+	Image           string     `json:"image"`
+	Policy          string     `json:"policy"`                     // Set for this container; empty falls back to the label or default
+	EffectivePolicy string     `json:"effective_policy"`           // Policy applied at the last check
+	AutoUpdateLabel string     `json:"autoupdate_label,omitempty"` // Value of the io.containers.autoupdate label
+	UpdateAvailable bool       `json:"update_available"`
+	CurrentDigest   string     `json:"current_digest,omitempty"`
+	LatestDigest    string     `json:"latest_digest,omitempty"`
+	NewerTags       []string   `json:"newer_tags,omitempty"` // Higher versions of a version tag, newest first
+	CheckedAt       *time.Time `json:"checked_at,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
 }
 
 // ContainerUpdateProgress represents progress during container update
@@ -425,6 +536,8 @@ const (
 	ActionContainerBackup  = "container.backup"
 	ActionContainerRestore = "container.restore"
 	ActionImagePull        = "image.pull"
+	ActionImageBuild       = "image.build"
+	ActionImageScan        = "image.scan"
 	ActionImageRemove      = "image.remove"
 	ActionTemplateCreate   = "template.create"
 	ActionTemplateDelete   = "template.delete"
@@ -231,6 +231,8 @@ func TestAuditActionConstants(t *testing.T) {
 		ActionContainerBackup,
 		ActionContainerRestore,
 		ActionImagePull,
+		ActionImageBuild,
+		ActionImageScan,
 		ActionImageRemove,
 		ActionTemplateCreate,
 		ActionTemplateDelete,
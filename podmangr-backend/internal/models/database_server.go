@@ -9,6 +9,10 @@ const (
 	EnginePostgreSQL DatabaseEngine = "postgresql"
 	EngineMariaDB    DatabaseEngine = "mariadb"
 	EngineMySQL      DatabaseEngine = "mysql"
+	EngineRedis      DatabaseEngine = "redis"
+	EngineValkey     DatabaseEngine = "valkey"
+	EngineMongoDB    DatabaseEngine = "mongodb"
+	EngineMSSQL      DatabaseEngine = "mssql"
 )
 
 // DatabaseServerStatus represents the state of a database server container
@@ -24,13 +28,15 @@ const (
 )
 
 // DatabaseServer represents a database engine container (Tier 1)
-// A database server runs a single database engine (PostgreSQL, MariaDB, or MySQL)
-// and can host multiple logical databases within it.
+// A database server runs a single database engine (PostgreSQL, MariaDB, MySQL,
+// Redis, Valkey, MongoDB or SQL Server) and can host multiple logical databases
+// within it. On Redis and Valkey a database is an ACL user confined to its own
+// key prefix.
 type DatabaseServer struct {
 	ID                    string               `json:"id"`                      // Podmangr internal UUID
 	ContainerID           string               `json:"container_id"`            // Podman container ID
 	Name                  string               `json:"name"`                    // User-friendly server name
-	Engine                DatabaseEngine       `json:"engine"`                  // postgresql, mariadb, mysql
+	Engine                DatabaseEngine       `json:"engine"`                  // postgresql, mariadb, mysql, redis, valkey, mongodb, mssql
 	Version               string               `json:"version"`                 // Database version (e.g., "17", "11")
 	Image                 string               `json:"image"`                   // Full container image reference
 	Network               string               `json:"network"`                 // Podman network name
@@ -38,6 +44,9 @@ type DatabaseServer struct {
 	RootPasswordEncrypted string               `json:"-"`                       // Encrypted root password (never exposed via API)
 	VolumeName            string               `json:"volume_name,omitempty"`   // Podman volume for data persistence
 	InternalPort          int                  `json:"internal_port"`           // Database port inside container
+	Config                map[string]string    `json:"config,omitempty"`        // Engine parameters, e.g. shared_buffers
+	MemoryLimit           int64                `json:"memory_limit,omitempty"`  // Container memory limit in bytes (0 = unlimited)
+	CPULimit              float64              `json:"cpu_limit,omitempty"`     // Container CPU cores limit (0 = unlimited)
 	CreatedAt             time.Time            `json:"created_at"`
 	UpdatedAt             time.Time            `json:"updated_at"`
 	CreatedBy             *int64               `json:"created_by,omitempty"`    // User ID who created
@@ -95,6 +104,38 @@ type CreateDatabaseServerRequest struct {
 	Version      string         `json:"version,omitempty"`       // Uses default if not specified
 	Network      string         `json:"network,omitempty"`       // Uses podmangr-db if not specified
 	RootPassword string         `json:"root_password,omitempty"` // Auto-generated if not specified
+	Config       map[string]string `json:"config,omitempty"`     // Engine parameters
+	MemoryLimit  int64          `json:"memory_limit,omitempty"`  // Bytes
+	CPULimit     float64        `json:"cpu_limit,omitempty"`     // Cores
+}
+
+// UpdateDatabaseServerRequest changes the configuration of a database server.
+// Unset fields are left as they are; the container is recreated with the same
+// volume to apply the change.
+type UpdateDatabaseServerRequest struct {
+	Config      map[string]string `json:"config,omitempty"`       // Replaces all engine parameters; an empty object clears them
+	MemoryLimit *int64            `json:"memory_limit,omitempty"` // Bytes, 0 removes the limit
+	CPULimit    *float64          `json:"cpu_limit,omitempty"`    // Cores, 0 removes the limit
+}
+
+// Database server upgrade strategies
+const (
+	UpgradeStrategyInPlace     = "in-place"     // New image on the existing data volume
+	UpgradeStrategyDumpRestore = "dump-restore" // Dump, restore into a new volume, swap
+)
+
+// UpgradeDatabaseServerRequest represents the request body for upgrading a database server
+type UpgradeDatabaseServerRequest struct {
+	Version string `json:"version" validate:"required"`
+}
+
+// UpgradeDatabaseServerResponse describes a completed database server upgrade
+type UpgradeDatabaseServerResponse struct {
+	Server         *DatabaseServer `json:"server"`
+	FromVersion    string          `json:"from_version"`
+	Strategy       string          `json:"strategy"`
+	BackupID       string          `json:"backup_id"`                 // Backup taken before the upgrade
+	PreviousVolume string          `json:"previous_volume,omitempty"` // Volume of the old version, kept after a dump-restore upgrade
 }
 
 // CreateDatabaseRequest represents the request body for creating a database within a server
@@ -104,6 +145,66 @@ type CreateDatabaseRequest struct {
 	Password string `json:"password,omitempty"` // Auto-generated if not specified
 }
 
+// DatabaseUserRole is the access an additional database user is granted
+type DatabaseUserRole string
+
+const (
+	DatabaseRoleReadOnly  DatabaseUserRole = "read-only"
+	DatabaseRoleReadWrite DatabaseUserRole = "read-write"
+	DatabaseRoleOwner     DatabaseUserRole = "owner"
+)
+
+// DatabaseUser is a user of a database besides the one it was created with.
+// The primary user is listed alongside them with the owner role and no ID.
+type DatabaseUser struct {
+	ID                string           `json:"id,omitempty"`
+	DatabaseID        string           `json:"database_id"`
+	Username          string           `json:"username"`
+	Role              DatabaseUserRole `json:"role"`
+	Primary           bool             `json:"primary"`              // Created with the database
+	PasswordEncrypted string           `json:"-"`                    // Encrypted password (never exposed via API)
+	CreatedAt         time.Time        `json:"created_at"`
+	UpdatedAt         time.Time        `json:"updated_at"`
+	CreatedBy         *int64           `json:"created_by,omitempty"`
+}
+
+// CreateDatabaseUserRequest represents the request body for adding a user to a database
+type CreateDatabaseUserRequest struct {
+	Username string           `json:"username,omitempty"` // Auto-generated if not specified
+	Password string           `json:"password,omitempty"` // Auto-generated if not specified
+	Role     DatabaseUserRole `json:"role" validate:"required"`
+}
+
+// RotatePasswordRequest sets a new password for a database user
+type RotatePasswordRequest struct {
+	Password string `json:"password,omitempty"` // Auto-generated if not specified
+}
+
+// DatabaseSession is a client connection to a database
+type DatabaseSession struct {
+	ID         string `json:"id"`          // Engine session ID (backend PID, thread ID, ...)
+	Username   string `json:"username"`
+	ClientAddr string `json:"client_addr"`
+	State      string `json:"state"`       // Engine specific, e.g. active, idle, Sleep
+	Query      string `json:"query"`       // Current or last statement, if the engine reports it
+	Seconds    int64  `json:"seconds"`     // Time in the current state or statement
+}
+
+// DatabaseTableSize is the size of a table or collection
+type DatabaseTableSize struct {
+	Name       string `json:"name"`
+	Rows       int64  `json:"rows"`        // Estimated by most engines
+	SizeBytes  int64  `json:"size_bytes"`  // Data and indexes
+	IndexBytes int64  `json:"index_bytes"`
+}
+
+// DatabaseStats reports the storage used by a database
+type DatabaseStats struct {
+	Database  string              `json:"database"`
+	SizeBytes int64               `json:"size_bytes"`
+	Tables    []DatabaseTableSize `json:"tables"`
+}
+
 // ConnectionStringResponse contains connection details for a database
 type ConnectionStringResponse struct {
 	ConnectionString string `json:"connection_string"` // Full connection URI
@@ -121,6 +222,7 @@ type EngineConfig struct {
 	DefaultImage string         `json:"default_image"` // Docker Hub image
 	DefaultPort  int            `json:"default_port"`  // Default database port
 	Versions     []string       `json:"versions"`      // Available versions
+	Parameters   []string       `json:"parameters"`    // Commonly tuned configuration parameters
 }
 
 // DatabaseServerWithDatabases includes the server and its databases
@@ -128,3 +230,24 @@ type DatabaseServerWithDatabases struct {
 	DatabaseServer
 	Databases []DatabaseListItem `json:"databases"`
 }
+
+// DatabaseBackupType is the backup type of logical database dumps, which are
+// kept in the backup store alongside container backups
+const DatabaseBackupType = "database"
+
+// RestoreDatabaseRequest selects the dump to restore and where to restore it.
+// Either BackupID or LatestBefore must be set; LatestBefore picks the newest
+// dump taken at or before that time. Dumps are restored as taken, there is no
+// log replay up to the given time.
+type RestoreDatabaseRequest struct {
+	BackupID       string     `json:"backup_id,omitempty"`
+	LatestBefore   *time.Time `json:"latest_before,omitempty"`
+	TargetDatabase string     `json:"target_database,omitempty"` // Database to restore into, created if missing; defaults to the dumped database
+}
+
+// RestoreDatabaseResponse describes a completed database restore
+type RestoreDatabaseResponse struct {
+	BackupID string    `json:"backup_id"`
+	Database *Database `json:"database,omitempty"` // Database restored into, for single-database dumps
+	Password string    `json:"password,omitempty"` // Only set if the target database was created by the restore
+}
@@ -0,0 +1,84 @@
+package models
+
+// Image prune modes
+const (
+	PruneImagesDangling = "dangling" // Untagged images, such as those left by builds
+	PruneImagesUnused   = "unused"   // Every image no container uses
+)
+
+// Kinds of objects a prune removes
+const (
+	PruneTypeImage     = "image"
+	PruneTypeContainer = "container"
+	PruneTypeVolume    = "volume"
+	PruneTypeNetwork   = "network"
+)
+
+// DiskUsageCategory is the storage accounting of one kind of object
+type DiskUsageCategory struct {
+	Total       int   `json:"total"`
+	Active      int   `json:"active"` // In use by a container, or running
+	Size        int64 `json:"size"`
+	Reclaimable int64 `json:"reclaimable"` // Freed by removing the inactive ones
+}
+
+// DiskUsage is the storage used by Podman, as podman system df reports it
+type DiskUsage struct {
+	Images      DiskUsageCategory `json:"images"`
+	Containers  DiskUsageCategory `json:"containers"`
+	Volumes     DiskUsageCategory `json:"volumes"`
+	BuildCache  DiskUsageCategory `json:"build_cache"` // Dangling images left by builds, also counted in Images
+	Size        int64             `json:"size"`
+	Reclaimable int64             `json:"reclaimable"`
+}
+
+// PruneRequest selects what a prune removes
+type PruneRequest struct {
+	Images       string `json:"images,omitempty"`        // "dangling" or "unused"; empty keeps images
+	KeepVersions int    `json:"keep_versions,omitempty"` // If set, unused tagged images beyond the N newest of their repository are removed
+	Containers   bool   `json:"containers,omitempty"`    // Stopped containers
+	Volumes      bool   `json:"volumes,omitempty"`       // Volumes no container uses
+	Networks     bool   `json:"networks,omitempty"`      // Networks no container uses
+	DryRun       bool   `json:"dry_run,omitempty"`       // Only list what would be removed
+}
+
+// PruneItem is an object a prune removed, or would remove
+type PruneItem struct {
+	Type   string `json:"type"` // image, container, volume or network
+	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Size   int64  `json:"size"`
+	Reason string `json:"reason"`
+	Error  string `json:"error,omitempty"` // Set if removing it failed
+}
+
+// PruneResult reports a prune or its dry-run preview
+type PruneResult struct {
+	DryRun    bool        `json:"dry_run"`
+	Items     []PruneItem `json:"items"`
+	Reclaimed int64       `json:"reclaimed"` // Bytes freed, or that would be; image sizes include shared layers
+	Failed    int         `json:"failed"`
+}
+
+// PrunePolicy is the configuration of scheduled prunes, applied to every socket
+type PrunePolicy struct {
+	Enabled      bool   `json:"enabled"`
+	Schedule     string `json:"schedule"` // Cron expression, e.g. "0 5 * * 0"
+	Images       string `json:"images"`
+	KeepVersions int    `json:"keep_versions"`
+	Containers   bool   `json:"containers"`
+	Volumes      bool   `json:"volumes"`
+	Networks     bool   `json:"networks"`
+	NextRun      string `json:"next_run,omitempty"`
+}
+
+// Request returns the prune the policy runs
+func (p PrunePolicy) Request() PruneRequest {
+	return PruneRequest{
+		Images:       p.Images,
+		KeepVersions: p.KeepVersions,
+		Containers:   p.Containers,
+		Volumes:      p.Volumes,
+		Networks:     p.Networks,
+	}
+}
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// RegistryCredentialSecretCategory is the secrets store category of registry tokens
+const RegistryCredentialSecretCategory = "registry"
+
+// RegistryCredential is a login for a container registry, used for pulls and
+// update checks on the Podman socket it was made for. The password or token
+// is kept in the secrets store.
+type RegistryCredential struct {
+	ID        string    `json:"id"`
+	SocketID  string    `json:"socket_id"` // "root" or "user:{username}"
+	Registry  string    `json:"registry"`  // Host and optional port, e.g. ghcr.io or localhost:5000
+	Username  string    `json:"username"`
+	Insecure  bool      `json:"insecure"` // Plain HTTP or unverified TLS, e.g. a local registry:2
+	SecretID  string    `json:"-"`
+	HasSecret bool      `json:"has_secret"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	CreatedBy *int64    `json:"created_by,omitempty"`
+}
+
+// RegistryLoginRequest represents the request body for logging in to or testing a registry
+type RegistryLoginRequest struct {
+	Registry string `json:"registry" validate:"required"`
+	Username string `json:"username"`
+	Password string `json:"password"` // Password or access token; the stored one is tested if empty
+	Insecure bool   `json:"insecure"`
+}
+
+// RegistryLogoutRequest represents the request body for removing a registry login
+type RegistryLogoutRequest struct {
+	Registry string `json:"registry" validate:"required"`
+}
+
+// RegistryTags lists the tags of a repository in a registry
+type RegistryTags struct {
+	Registry   string   `json:"registry"`
+	Repository string   `json:"repository"` // e.g. library/nginx
+	Tags       []string `json:"tags"`
+	Current    string   `json:"current,omitempty"`    // Tag of the image asked about
+	NewerTags  []string `json:"newer_tags,omitempty"` // Higher versions of the same tag pattern as Current, newest first
+}
+
+// RegistryImage describes a tag or digest in a registry: a single image or a
+// manifest list with one image per platform
+type RegistryImage struct {
+	Registry   string             `json:"registry"`
+	Repository string             `json:"repository"`
+	Reference  string             `json:"reference"` // Tag or digest asked about
+	Digest     string             `json:"digest"`    // Digest of the manifest or manifest list
+	MediaType  string             `json:"media_type"`
+	Created    *time.Time         `json:"created,omitempty"` // Of the host's platform for manifest lists
+	Size       int64              `json:"size"`              // Compressed size of config and layers, of the host's platform for manifest lists
+	Platforms  []RegistryPlatform `json:"platforms"`
+}
+
+// RegistryPlatform is the image of one platform in a registry
+type RegistryPlatform struct {
+	OS           string     `json:"os"`
+	Architecture string     `json:"architecture"`
+	Variant      string     `json:"variant,omitempty"`
+	Digest       string     `json:"digest"`
+	Size         int64      `json:"size"`
+	Created      *time.Time `json:"created,omitempty"`
+}
+
+// RegistryRepository is a repository found by searching a registry's catalog
+type RegistryRepository struct {
+	Registry string `json:"registry"`
+	Name     string `json:"name"`
+}
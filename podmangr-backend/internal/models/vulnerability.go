@@ -0,0 +1,112 @@
+package models
+
+import "time"
+
+// Vulnerability severities, from the advisory or its CVSS v3 base score
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+	SeverityUnknown  = "unknown"
+)
+
+// VulnerabilitySummary counts the vulnerabilities of an image by severity
+type VulnerabilitySummary struct {
+	Critical  int       `json:"critical"`
+	High      int       `json:"high"`
+	Medium    int       `json:"medium"`
+	Low       int       `json:"low"`
+	Unknown   int       `json:"unknown"`
+	ScannedAt time.Time `json:"scanned_at"`
+}
+
+// Add counts one vulnerability of a severity
+func (s *VulnerabilitySummary) Add(severity string) {
+	switch severity {
+	case SeverityCritical:
+		s.Critical++
+	case SeverityHigh:
+		s.High++
+	case SeverityMedium:
+		s.Medium++
+	case SeverityLow:
+		s.Low++
+	default:
+		s.Unknown++
+	}
+}
+
+// ImagePackage is a package installed in an image
+type ImagePackage struct {
+	Ecosystem string `json:"ecosystem"`        // OSV ecosystem, e.g. Debian:12, Alpine:v3.20 or Go
+	Name      string `json:"name"`             // Binary package or Go module path
+	Source    string `json:"source,omitempty"` // Source package advisories name, if different
+	Version   string `json:"version"`
+}
+
+// ImageVulnerability is a vulnerability affecting a package of an image
+type ImageVulnerability struct {
+	ID               string   `json:"id"` // e.g. CVE-2024-3094 or GHSA-...
+	Aliases          []string `json:"aliases,omitempty"`
+	Package          string   `json:"package"`
+	Ecosystem        string   `json:"ecosystem"`
+	InstalledVersion string   `json:"installed_version"`
+	FixedVersion     string   `json:"fixed_version,omitempty"` // Empty if no fix is known
+	Severity         string   `json:"severity"`
+	Summary          string   `json:"summary,omitempty"`
+}
+
+// ImageVulnerabilityReport is the result of scanning an image, stored by
+// manifest digest so every tag and socket sharing the image shares it
+type ImageVulnerabilityReport struct {
+	Digest          string               `json:"digest"`
+	ImageID         string               `json:"image_id"`
+	Image           string               `json:"image"` // Reference the image was scanned as
+	OS              string               `json:"os,omitempty"`
+	PackageCount    int                  `json:"package_count"`
+	Summary         VulnerabilitySummary `json:"summary"`
+	Vulnerabilities []ImageVulnerability `json:"vulnerabilities"`
+	Notes           []string             `json:"notes,omitempty"` // Parts of the image that could not be inventoried
+	Error           string               `json:"error,omitempty"`
+	ScannedAt       time.Time            `json:"scanned_at"` // When the package inventory was taken
+	MatchedAt       time.Time            `json:"matched_at"` // When it was last matched against the database
+}
+
+// VulnerabilityAdvisory is an imported advisory with the package versions it affects
+type VulnerabilityAdvisory struct {
+	ID       string
+	Aliases  []string
+	Summary  string
+	Severity string
+	Modified *time.Time
+	Ranges   []VulnerabilityRange
+}
+
+// VulnerabilityRange is a range of affected versions of a package. An empty
+// Introduced means from the first version; without Fixed or LastAffected
+// every later version is affected.
+type VulnerabilityRange struct {
+	AdvisoryID   string
+	Ecosystem    string
+	Package      string
+	Introduced   string
+	Fixed        string
+	LastAffected string
+	Severity     string // Of the advisory
+	Summary      string
+}
+
+// VulnerabilityDatabaseStatus describes the imported vulnerability database
+type VulnerabilityDatabaseStatus struct {
+	Advisories int            `json:"advisories"`
+	Ecosystems map[string]int `json:"ecosystems"` // Affected package ranges by ecosystem
+	ImportedAt *time.Time     `json:"imported_at,omitempty"`
+}
+
+// VulnerabilityImportResult reports an import of OSV advisories
+type VulnerabilityImportResult struct {
+	Imported  int `json:"imported"`
+	Withdrawn int `json:"withdrawn"` // Advisories removed because they were withdrawn
+	Skipped   int `json:"skipped"`   // Entries that are not valid OSV advisories
+}
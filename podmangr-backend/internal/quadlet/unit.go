@@ -0,0 +1,168 @@
+package quadlet
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Quadlet unit file extensions
+const (
+	ExtContainer = ".container"
+	ExtPod       = ".pod"
+	ExtVolume    = ".volume"
+	ExtNetwork   = ".network"
+)
+
+// File is a generated Quadlet unit file
+type File struct {
+	Name    string `json:"name"`    // File name including extension, e.g. "web.container"
+	Kind    string `json:"kind"`    // container, pod, volume, network
+	Content string `json:"content"` // Unit file contents
+}
+
+// Unit builds a Quadlet unit file section by section, keeping the order in
+// which sections and keys were added
+type Unit struct {
+	name     string
+	sections []*section
+	err      error
+}
+
+type section struct {
+	name    string
+	entries [][2]string
+}
+
+// NewUnit creates an empty unit. name is the base name without extension and
+// is sanitized into a valid systemd unit name.
+func NewUnit(name, ext string) *Unit {
+	return &Unit{name: UnitName(name) + ext}
+}
+
+// Name returns the unit file name including its extension
+func (u *Unit) Name() string {
+	return u.name
+}
+
+// Add appends key=value to a section. Empty values are skipped. Values with
+// line breaks would inject extra keys, so they are dropped and reported by Err.
+func (u *Unit) Add(sectionName, key, value string) {
+	if value == "" {
+		return
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		if u.err == nil {
+			u.err = fmt.Errorf("%s %s value contains a line break", sectionName, key)
+		}
+		return
+	}
+	s := u.section(sectionName)
+	s.entries = append(s.entries, [2]string{key, value})
+}
+
+// AddAll appends one key=value line per value
+func (u *Unit) AddAll(sectionName, key string, values []string) {
+	for _, v := range values {
+		u.Add(sectionName, key, v)
+	}
+}
+
+// Err returns the first value Add rejected, if any
+func (u *Unit) Err() error {
+	return u.err
+}
+
+// section returns the named section, creating it at the end if needed
+func (u *Unit) section(name string) *section {
+	for _, s := range u.sections {
+		if s.name == name {
+			return s
+		}
+	}
+	s := &section{name: name}
+	u.sections = append(u.sections, s)
+	return s
+}
+
+// String renders the unit file
+func (u *Unit) String() string {
+	var b strings.Builder
+	for i, s := range u.sections {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("[" + s.name + "]\n")
+		for _, e := range s.entries {
+			b.WriteString(e[0] + "=" + e[1] + "\n")
+		}
+	}
+	return b.String()
+}
+
+// File returns the rendered unit as a File
+func (u *Unit) File() File {
+	return File{
+		Name:    u.name,
+		Kind:    strings.TrimPrefix(u.name[strings.LastIndex(u.name, "."):], "."),
+		Content: u.String(),
+	}
+}
+
+var invalidUnitChars = regexp.MustCompile(`[^A-Za-z0-9_.@-]+`)
+
+// UnitName turns an object name into something usable as a systemd unit name
+func UnitName(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	name = invalidUnitChars.ReplaceAllString(name, "-")
+	name = strings.Trim(name, "-.")
+	if name == "" {
+		return "unnamed"
+	}
+	return name
+}
+
+// Ref returns the reference another unit uses for this one, e.g. "data.volume"
+func Ref(name, ext string) string {
+	return UnitName(name) + ext
+}
+
+// ServiceName returns the systemd service Quadlet generates for a unit file.
+// Containers map to name.service; pods, volumes and networks get a suffix.
+func ServiceName(fileName string) string {
+	for _, ext := range []string{ExtPod, ExtVolume, ExtNetwork} {
+		if strings.HasSuffix(fileName, ext) {
+			return strings.TrimSuffix(fileName, ext) + "-" + strings.TrimPrefix(ext, ".") + ".service"
+		}
+	}
+	return strings.TrimSuffix(fileName, ExtContainer) + ".service"
+}
+
+// Escape escapes % and $ so systemd doesn't expand specifiers or variables
+// in a value Quadlet passes on verbatim
+func Escape(value string) string {
+	return strings.NewReplacer("%", "%%", "$", "$$").Replace(value)
+}
+
+// Quote quotes a value for systemd if it contains whitespace or quotes, and
+// escapes it like Escape
+func Quote(value string) string {
+	if value == "" {
+		return `""`
+	}
+	value = Escape(value)
+	if !strings.ContainsAny(value, " \t\"'\\") {
+		return value
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// QuoteArgs joins a command line, quoting arguments and escaping % where needed
+func QuoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = Quote(a)
+	}
+	return strings.Join(quoted, " ")
+}
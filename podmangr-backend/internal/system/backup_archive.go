@@ -0,0 +1,781 @@
+package system
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"podmangr-backend/internal/models"
+)
+
+const (
+	backupManifestVersion = 1
+	backupManifestFile    = "manifest.json"
+	backupCompression     = "gzip"
+	// backupChunkSize is the fixed chunk size of deduplicated backups. Unchanged
+	// files and data appended to files are shared with earlier backups.
+	backupChunkSize = 4 << 20
+	// backupChunkDir holds the chunks shared by all deduplicated backups of a backup path
+	backupChunkDir = ".chunks"
+)
+
+// backupWriter turns the tar streams of a container's mounts into a backup
+// archive, recording every entry and its checksum in a manifest
+type backupWriter struct {
+	dir      string
+	chunks   *chunkStore // nil unless deduplicating
+	manifest models.BackupManifest
+}
+
+func newBackupWriter(dir string, chunks *chunkStore) *backupWriter {
+	w := &backupWriter{
+		dir:    dir,
+		chunks: chunks,
+		manifest: models.BackupManifest{
+			Version:     backupManifestVersion,
+			Format:      models.BackupFormatArchive,
+			Compression: backupCompression,
+			Files:       make([]models.BackupFile, 0),
+		},
+	}
+	if chunks != nil {
+		w.manifest.Format = models.BackupFormatChunked
+		w.manifest.ChunkSize = backupChunkSize
+	}
+	return w
+}
+
+// addMount stores the tar stream of one mount. It returns the archive path
+// (empty for chunked backups) and the number of bytes written to disk.
+func (w *backupWriter) addMount(index int, r io.Reader) (string, int64, error) {
+	if w.chunks != nil {
+		size, err := w.addChunkedMount(index, r)
+		return "", size, err
+	}
+
+	archivePath := filepath.Join(w.dir, fmt.Sprintf("mount_%d.tar.gz", index))
+	file, err := os.Create(archivePath)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to read mount contents: %w", err)
+		}
+		entry, ok := backupFileFromHeader(index, hdr)
+		if !ok {
+			continue
+		}
+
+		hdr.Name = tarEntryName(entry)
+		if entry.Type == "hardlink" {
+			hdr.Linkname = entry.Link
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return "", 0, fmt.Errorf("failed to write archive: %w", err)
+		}
+		if entry.Type == "file" {
+			hash := sha256.New()
+			if _, err := io.Copy(io.MultiWriter(tw, hash), tr); err != nil {
+				return "", 0, fmt.Errorf("failed to archive %s: %w", entry.Path, err)
+			}
+			entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+		}
+		w.manifest.Files = append(w.manifest.Files, entry)
+	}
+
+	if err := tw.Close(); err != nil {
+		return "", 0, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return "", 0, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return "", 0, fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return archivePath, 0, nil
+	}
+	return archivePath, info.Size(), nil
+}
+
+// addChunkedMount splits the files of a tar stream into chunks, storing only
+// the chunks the store doesn't have yet
+func (w *backupWriter) addChunkedMount(index int, r io.Reader) (int64, error) {
+	var written int64
+	buf := make([]byte, backupChunkSize)
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return written, fmt.Errorf("failed to read mount contents: %w", err)
+		}
+		entry, ok := backupFileFromHeader(index, hdr)
+		if !ok {
+			continue
+		}
+
+		if entry.Type == "file" {
+			size, err := w.putChunks(&entry, tr, buf)
+			written += size
+			if err != nil {
+				return written, err
+			}
+		}
+		w.manifest.Files = append(w.manifest.Files, entry)
+	}
+
+	return written, nil
+}
+
+// putChunks splits the contents of a file into chunks, recording them and the
+// file's size and checksum in entry. It returns the number of bytes written to disk.
+func (w *backupWriter) putChunks(entry *models.BackupFile, r io.Reader, buf []byte) (int64, error) {
+	var written int64
+	hash := sha256.New()
+	entry.Size = 0
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			hash.Write(buf[:n])
+			sum, size, putErr := w.chunks.put(buf[:n])
+			if putErr != nil {
+				return written, putErr
+			}
+			entry.Chunks = append(entry.Chunks, sum)
+			entry.Size += int64(n)
+			written += size
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return written, fmt.Errorf("failed to read %s: %w", entry.Path, err)
+		}
+	}
+	entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return written, nil
+}
+
+// addFile stores a stream that isn't a tar archive, such as a database dump,
+// as the only file of a mount. It returns the archive path (empty for chunked
+// backups) and the number of bytes written to disk.
+func (w *backupWriter) addFile(index int, name string, r io.Reader) (string, int64, error) {
+	entry := models.BackupFile{
+		Mount:   index,
+		Path:    name,
+		Type:    "file",
+		Mode:    0600,
+		ModTime: time.Now().UTC().Truncate(time.Second),
+	}
+
+	if w.chunks != nil {
+		size, err := w.putChunks(&entry, r, make([]byte, backupChunkSize))
+		if err != nil {
+			return "", size, err
+		}
+		w.manifest.Files = append(w.manifest.Files, entry)
+		return "", size, nil
+	}
+
+	// Tar headers need the file size up front, so the stream is spooled first
+	spool, err := os.CreateTemp(w.dir, ".spool-*")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create spool file: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	entry.Size, err = io.Copy(spool, r)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tw := tar.NewWriter(pw)
+		err := tw.WriteHeader(tarHeader(entry))
+		if err == nil {
+			_, err = io.Copy(tw, io.NewSectionReader(spool, 0, entry.Size))
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	archivePath, size, err := w.addMount(index, pr)
+	pr.CloseWithError(io.ErrClosedPipe)
+	<-done
+	return archivePath, size, err
+}
+
+// close writes the manifest, returning its size
+func (w *backupWriter) close() (int64, error) {
+	data, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(filepath.Join(w.dir, backupManifestFile), data, 0644); err != nil {
+		return 0, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return int64(len(data)), nil
+}
+
+// backupFileFromHeader converts a tar header to a manifest entry. Entries
+// that can't be restored from a tar stream, such as devices, are skipped.
+func backupFileFromHeader(index int, hdr *tar.Header) (models.BackupFile, bool) {
+	entry := models.BackupFile{
+		Mount:   index,
+		Path:    cleanEntryPath(hdr.Name),
+		Mode:    hdr.Mode,
+		UID:     hdr.Uid,
+		GID:     hdr.Gid,
+		ModTime: hdr.ModTime.UTC(),
+	}
+	if entry.Path == "" {
+		return entry, false
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		entry.Type = "dir"
+	case tar.TypeReg:
+		entry.Type = "file"
+		entry.Size = hdr.Size
+	case tar.TypeSymlink:
+		entry.Type = "symlink"
+		entry.Link = hdr.Linkname
+	case tar.TypeLink:
+		entry.Type = "hardlink"
+		entry.Link = cleanEntryPath(hdr.Linkname)
+	default:
+		return entry, false
+	}
+	return entry, true
+}
+
+// cleanEntryPath normalizes a tar entry name to a relative slash path,
+// returning "" for the mount root and names escaping it
+func cleanEntryPath(name string) string {
+	name = path.Clean("/" + name)
+	return strings.TrimPrefix(name, "/")
+}
+
+// tarEntryName returns the tar name of a manifest entry
+func tarEntryName(entry models.BackupFile) string {
+	if entry.Type == "dir" {
+		return entry.Path + "/"
+	}
+	return entry.Path
+}
+
+// tarHeader rebuilds the tar header of a manifest entry
+func tarHeader(entry models.BackupFile) *tar.Header {
+	hdr := &tar.Header{
+		Name:    tarEntryName(entry),
+		Mode:    entry.Mode,
+		Uid:     entry.UID,
+		Gid:     entry.GID,
+		ModTime: entry.ModTime,
+		Format:  tar.FormatPAX,
+	}
+	switch entry.Type {
+	case "dir":
+		hdr.Typeflag = tar.TypeDir
+	case "file":
+		hdr.Typeflag = tar.TypeReg
+		hdr.Size = entry.Size
+	case "symlink":
+		hdr.Typeflag = tar.TypeSymlink
+		hdr.Linkname = entry.Link
+	case "hardlink":
+		hdr.Typeflag = tar.TypeLink
+		hdr.Linkname = entry.Link
+	}
+	return hdr
+}
+
+// readManifest loads the manifest of a backup directory
+func readManifest(dir string) (*models.BackupManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, backupManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var manifest models.BackupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// openBackupMount returns the tar stream of one mount of an archived backup
+func openBackupMount(backup *models.ContainerBackup, manifest *models.BackupManifest, index int) (io.ReadCloser, error) {
+	if manifest.Format == models.BackupFormatChunked {
+		store := newChunkStore(filepath.Dir(backup.BackupPath))
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(writeChunkedMount(pw, store, manifest, index))
+		}()
+		return pr, nil
+	}
+
+	file, err := os.Open(backup.Mounts[index].BackupPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	return &gzipFileReader{Reader: gz, file: file}, nil
+}
+
+// gzipFileReader closes the archive file together with its decompressor
+type gzipFileReader struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (r *gzipFileReader) Close() error {
+	r.Reader.Close()
+	return r.file.Close()
+}
+
+// writeChunkedMount reassembles the tar stream of a mount from its chunks
+func writeChunkedMount(w io.Writer, store *chunkStore, manifest *models.BackupManifest, index int) error {
+	tw := tar.NewWriter(w)
+	for _, entry := range manifest.Files {
+		if entry.Mount != index {
+			continue
+		}
+		if err := tw.WriteHeader(tarHeader(entry)); err != nil {
+			return err
+		}
+		for _, sum := range entry.Chunks {
+			data, err := store.get(sum)
+			if err != nil {
+				return fmt.Errorf("%s: %w", entry.Path, err)
+			}
+			if _, err := tw.Write(data); err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Close()
+}
+
+// extractOptions controls what extractTar restores and accepts
+type extractOptions struct {
+	keepOwners bool  // Restore owners from the archive; only done when running as root
+	maxSize    int64 // Bound on the total size of extracted files, 0 for none
+}
+
+// extractTar unpacks a tar stream into dir, which must exist. Entries are
+// confined to dir.
+func extractTar(r io.Reader, dir string, opts extractOptions) error {
+	type dirTime struct {
+		path  string
+		entry models.BackupFile
+	}
+	var dirs []dirTime
+	var size int64
+
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		entry, ok := backupFileFromHeader(0, hdr)
+		if !ok {
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(entry.Path))
+
+		// A symlink restored earlier must not redirect later entries outside
+		// dir, so containment is checked before anything is created
+		if err := checkConfined(root, filepath.Dir(target)); err != nil {
+			return fmt.Errorf("%s: %w", entry.Path, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if entry.Type != "dir" {
+			if err := os.RemoveAll(target); err != nil {
+				return err
+			}
+		} else if info, err := os.Lstat(target); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			// Directory modes and times must not be applied through a symlink
+			if err := os.Remove(target); err != nil {
+				return err
+			}
+		}
+
+		mode := fs.FileMode(entry.Mode).Perm()
+		switch entry.Type {
+		case "dir":
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirTime{target, entry})
+		case "file":
+			if size += hdr.Size; opts.maxSize > 0 && size > opts.maxSize {
+				return fmt.Errorf("archive unpacks to more than %d bytes", opts.maxSize)
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, tr)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return fmt.Errorf("failed to write %s: %w", entry.Path, err)
+			}
+		case "symlink":
+			if err := os.Symlink(entry.Link, target); err != nil {
+				return err
+			}
+		case "hardlink":
+			if entry.Link == "" {
+				return fmt.Errorf("hardlink %s has no target", entry.Path)
+			}
+			source := filepath.Join(dir, filepath.FromSlash(entry.Link))
+			if err := checkConfined(root, filepath.Dir(source)); err != nil {
+				return fmt.Errorf("%s: %w", entry.Path, err)
+			}
+			if err := os.Link(source, target); err != nil {
+				return err
+			}
+		}
+
+		// A hard link shares the owner of the file it links to, restored with that file
+		if opts.keepOwners && os.Getuid() == 0 && entry.Type != "hardlink" {
+			os.Lchown(target, entry.UID, entry.GID)
+		}
+		if entry.Type == "file" {
+			os.Chmod(target, mode)
+			os.Chtimes(target, entry.ModTime, entry.ModTime)
+		}
+	}
+
+	// Directory times are set last, since creating their contents changes them.
+	// A later entry may have replaced a directory or one of its parents with a
+	// symlink, which Chmod and Chtimes would follow.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if checkConfined(root, dirs[i].path) != nil {
+			continue
+		}
+		if info, err := os.Lstat(dirs[i].path); err != nil || !info.IsDir() {
+			continue
+		}
+		os.Chmod(dirs[i].path, fs.FileMode(dirs[i].entry.Mode).Perm())
+		os.Chtimes(dirs[i].path, dirs[i].entry.ModTime, dirs[i].entry.ModTime)
+	}
+	return nil
+}
+
+// checkConfined reports an error unless path, or the deepest ancestor of it
+// that exists, resolves to root or a directory below it. A path that doesn't
+// exist yet is created inside that ancestor, so it stays confined as well.
+func checkConfined(root, path string) error {
+	existing := path
+	for {
+		_, err := os.Lstat(existing)
+		if err == nil {
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return err
+		}
+		existing = parent
+	}
+
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return fmt.Errorf("points outside the restore directory: %w", err)
+	}
+	if resolved != root && !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+		return fmt.Errorf("points outside the restore directory")
+	}
+	return nil
+}
+
+// tarDirectory writes the contents of dir to w as a tar stream
+func tarDirectory(dir string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	if err := addDirectoryToTar(tw, dir, ""); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// addDirectoryToTar writes the contents of dir to tw, naming entries below prefix
+func addDirectoryToTar(tw *tar.Writer, dir, prefix string) error {
+	return filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, filePath)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(filePath); err != nil {
+				return err
+			}
+		} else if !info.Mode().IsRegular() && !info.IsDir() {
+			// Sockets, devices and pipes have no content to back up
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(prefix, filepath.ToSlash(rel))
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(tw, file)
+		return err
+	})
+}
+
+// verifyBackup checks every entry of an archived backup against its manifest
+func verifyBackup(backup *models.ContainerBackup) *models.BackupVerifyResult {
+	result := &models.BackupVerifyResult{BackupID: backup.ID}
+	fail := func(format string, args ...interface{}) {
+		result.Errors = append(result.Errors, fmt.Sprintf(format, args...))
+	}
+
+	manifest, err := readManifest(backup.BackupPath)
+	if err != nil {
+		fail("%v", err)
+		return result
+	}
+
+	for index := range backup.Mounts {
+		expected := make(map[string]models.BackupFile)
+		for _, entry := range manifest.Files {
+			if entry.Mount == index {
+				expected[entry.Path] = entry
+			}
+		}
+
+		stream, err := openBackupMount(backup, manifest, index)
+		if err != nil {
+			fail("mount %d: %v", index, err)
+			continue
+		}
+
+		tr := tar.NewReader(stream)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				fail("mount %d: %v", index, err)
+				break
+			}
+			entryPath := cleanEntryPath(hdr.Name)
+			want, ok := expected[entryPath]
+			if !ok {
+				continue
+			}
+			delete(expected, entryPath)
+			result.FilesChecked++
+
+			if want.Type != "file" {
+				continue
+			}
+			hash := sha256.New()
+			n, err := io.Copy(hash, tr)
+			result.BytesChecked += n
+			if err != nil {
+				fail("mount %d: %s: %v", index, entryPath, err)
+				break
+			}
+			if sum := hex.EncodeToString(hash.Sum(nil)); sum != want.SHA256 {
+				fail("mount %d: %s: checksum mismatch", index, entryPath)
+			} else if n != want.Size {
+				fail("mount %d: %s: size is %d bytes, expected %d", index, entryPath, n, want.Size)
+			}
+		}
+		// Reading to the end checks the gzip trailer, or the reassembly of chunks
+		if _, err := io.Copy(io.Discard, stream); err != nil {
+			fail("mount %d: %v", index, err)
+		}
+		stream.Close()
+
+		for entryPath := range expected {
+			fail("mount %d: %s is missing from the archive", index, entryPath)
+		}
+	}
+
+	result.Valid = len(result.Errors) == 0
+	return result
+}
+
+// chunkStore keeps gzip compressed chunks named by the SHA-256 of their content
+type chunkStore struct {
+	dir string
+}
+
+// newChunkStore returns the chunk store of a backup base path
+func newChunkStore(backupBasePath string) *chunkStore {
+	return &chunkStore{dir: filepath.Join(backupBasePath, backupChunkDir)}
+}
+
+func (s *chunkStore) path(sum string) string {
+	return filepath.Join(s.dir, sum[:2], sum)
+}
+
+// put stores a chunk unless it is already present. It returns the chunk's
+// checksum and the number of bytes written to disk.
+func (s *chunkStore) put(data []byte) (string, int64, error) {
+	digest := sha256.Sum256(data)
+	sum := hex.EncodeToString(digest[:])
+
+	chunkPath := s.path(sum)
+	if _, err := os.Stat(chunkPath); err == nil {
+		return sum, 0, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(chunkPath), 0755); err != nil {
+		return "", 0, fmt.Errorf("failed to create chunk directory: %w", err)
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(data)
+	if err := gz.Close(); err != nil {
+		return "", 0, err
+	}
+
+	// Write under a temporary name so an interrupted backup never leaves a
+	// truncated chunk behind under its final name
+	tmp, err := os.CreateTemp(filepath.Dir(chunkPath), sum+".tmp*")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to write chunk: %w", err)
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), chunkPath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", 0, fmt.Errorf("failed to write chunk: %w", err)
+	}
+	return sum, int64(buf.Len()), nil
+}
+
+// get reads a chunk, checking that its content still matches its name
+func (s *chunkStore) get(sum string) ([]byte, error) {
+	if len(sum) != sha256.Size*2 {
+		return nil, fmt.Errorf("invalid chunk %q", sum)
+	}
+	file, err := os.Open(s.path(sum))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("chunk %s is missing", sum)
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("chunk %s is corrupt: %w", sum, err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, fmt.Errorf("chunk %s is corrupt: %w", sum, err)
+	}
+	if digest := sha256.Sum256(data); hex.EncodeToString(digest[:]) != sum {
+		return nil, fmt.Errorf("chunk %s is corrupt: checksum mismatch", sum)
+	}
+	return data, nil
+}
+
+// collect removes every chunk not in referenced, returning the bytes freed
+func (s *chunkStore) collect(referenced map[string]bool) (int64, error) {
+	var freed int64
+	err := filepath.WalkDir(s.dir, func(chunkPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || referenced[d.Name()] {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			freed += info.Size()
+		}
+		return os.Remove(chunkPath)
+	})
+	return freed, err
+}
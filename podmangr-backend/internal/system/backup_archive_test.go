@@ -0,0 +1,231 @@
+package system
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"podmangr-backend/internal/models"
+)
+
+// writeTestBackup backs up the bind mount src the way BackupMounts does
+func writeTestBackup(t *testing.T, base, id string, created time.Time, src string, deduplicate bool) *models.ContainerBackup {
+	t.Helper()
+
+	var inspect podmanInspect
+	mounts := fmt.Sprintf(`{"Mounts":[{"Type":"bind","Source":%q,"Destination":"/data","RW":true}]}`, src)
+	if err := json.Unmarshal([]byte(mounts), &inspect); err != nil {
+		t.Fatal(err)
+	}
+
+	backup := &models.ContainerBackup{
+		ID:            id,
+		ContainerName: "app",
+		BackupPath:    filepath.Join(base, id),
+		CreatedAt:     created,
+	}
+	if err := os.MkdirAll(backup.BackupPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	p := &PodmanService{}
+	if err := p.writeBackup(context.Background(), backup, &inspect, deduplicate, nil); err != nil {
+		t.Fatalf("writeBackup returned error: %v", err)
+	}
+	return backup
+}
+
+func TestBackupArchiveRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	files := map[string]string{
+		"config.yml":        "listen: 8080\n",
+		"data/base/1":       strings.Repeat("row", 1000),
+		"data/empty":        "",
+		"data/.hidden/seed": "42",
+	}
+	for name, content := range files {
+		target := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("config.yml", filepath.Join(src, "current")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, deduplicate := range []bool{false, true} {
+		t.Run(fmt.Sprintf("deduplicate=%v", deduplicate), func(t *testing.T) {
+			base := t.TempDir()
+			backup := writeTestBackup(t, base, "app_1", time.Now(), src, deduplicate)
+
+			result, err := VerifyBackup(base, backup.ID)
+			if err != nil {
+				t.Fatalf("VerifyBackup returned error: %v", err)
+			}
+			if !result.Valid || result.FilesChecked != 8 {
+				t.Fatalf("Expected a valid backup of 8 entries, got %+v", result)
+			}
+
+			// Restoring replaces files created after the backup
+			dest := t.TempDir()
+			os.WriteFile(filepath.Join(dest, "stale"), []byte("x"), 0644)
+			p := &PodmanService{}
+			if err := p.RestoreMounts(context.Background(), &models.ContainerBackup{
+				ID:         backup.ID,
+				BackupPath: backup.BackupPath,
+				Format:     backup.Format,
+				Mounts:     []models.BackupMount{{Source: dest, Type: "bind", BackupPath: backup.Mounts[0].BackupPath}},
+			}, nil); err != nil {
+				t.Fatalf("RestoreMounts returned error: %v", err)
+			}
+			for name, content := range files {
+				data, err := os.ReadFile(filepath.Join(dest, name))
+				if err != nil || string(data) != content {
+					t.Errorf("Restored %s = %q (%v), want %q", name, data, err, content)
+				}
+			}
+			if link, err := os.Readlink(filepath.Join(dest, "current")); err != nil || link != "config.yml" {
+				t.Errorf("Expected the symlink to be restored, got %q (%v)", link, err)
+			}
+			if _, err := os.Stat(filepath.Join(dest, "stale")); !os.IsNotExist(err) {
+				t.Errorf("Expected stale file to be removed, got %v", err)
+			}
+
+			// Damage the stored data
+			if deduplicate {
+				chunks, _ := filepath.Glob(filepath.Join(base, backupChunkDir, "*", "*"))
+				if len(chunks) == 0 {
+					t.Fatal("Expected chunks to be stored")
+				}
+				os.Remove(chunks[0])
+			} else {
+				data, _ := os.ReadFile(backup.Mounts[0].BackupPath)
+				data[len(data)/2] ^= 0xff
+				os.WriteFile(backup.Mounts[0].BackupPath, data, 0644)
+			}
+			if result, _ := VerifyBackup(base, backup.ID); result.Valid {
+				t.Error("Expected verification of the damaged backup to fail")
+			}
+		})
+	}
+}
+
+func TestExtractTarSymlinkEscape(t *testing.T) {
+	outside := t.TempDir()
+
+	tests := map[string][]tar.Header{
+		"nested under symlink": {
+			{Name: "a", Linkname: outside, Typeflag: tar.TypeSymlink},
+			{Name: "a/x/y", Mode: 0644, Typeflag: tar.TypeReg},
+		},
+		"directory under symlink": {
+			{Name: "a", Linkname: outside, Typeflag: tar.TypeSymlink},
+			{Name: "a/x/", Mode: 0755, Typeflag: tar.TypeDir},
+		},
+		"hard link through symlink": {
+			{Name: "a", Linkname: outside, Typeflag: tar.TypeSymlink},
+			{Name: "b", Linkname: "a/x", Typeflag: tar.TypeLink},
+		},
+	}
+	for name, headers := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			for i := range headers {
+				tw.WriteHeader(&headers[i])
+			}
+			tw.Close()
+
+			if err := extractTar(&buf, t.TempDir(), extractOptions{keepOwners: true}); err == nil {
+				t.Error("Expected extracting through a symlink out of the directory to fail")
+			}
+			if _, err := os.Lstat(filepath.Join(outside, "x")); !os.IsNotExist(err) {
+				t.Errorf("Expected nothing to be created outside the directory, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExtractTarReplacedDirectory(t *testing.T) {
+	outside := t.TempDir()
+	victim := filepath.Join(outside, "victim")
+	os.WriteFile(victim, []byte("secret"), 0600)
+	os.Mkdir(filepath.Join(outside, "b"), 0700)
+
+	tests := map[string][]tar.Header{
+		"directory replaced by symlink": {
+			{Name: "a/", Mode: 0777, Typeflag: tar.TypeDir},
+			{Name: "a", Linkname: victim, Typeflag: tar.TypeSymlink},
+		},
+		"parent replaced by symlink": {
+			{Name: "a/", Mode: 0755, Typeflag: tar.TypeDir},
+			{Name: "a/b/", Mode: 0777, Typeflag: tar.TypeDir},
+			{Name: "a", Linkname: outside, Typeflag: tar.TypeSymlink},
+		},
+	}
+	for name, headers := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			for i := range headers {
+				tw.WriteHeader(&headers[i])
+			}
+			tw.Close()
+
+			extractTar(&buf, t.TempDir(), extractOptions{keepOwners: true})
+			for path, want := range map[string]os.FileMode{victim: 0600, filepath.Join(outside, "b"): 0700} {
+				info, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if info.Mode().Perm() != want {
+					t.Errorf("Expected %s to keep mode %v, got %v", path, want, info.Mode().Perm())
+				}
+			}
+		})
+	}
+}
+
+func TestPruneBackups(t *testing.T) {
+	src := t.TempDir()
+	os.WriteFile(filepath.Join(src, "db"), []byte("version 1"), 0644)
+
+	base := t.TempDir()
+	start := time.Now()
+	writeTestBackup(t, base, "app_1", start, src, true)
+	os.WriteFile(filepath.Join(src, "db"), []byte("version 2"), 0644)
+	writeTestBackup(t, base, "app_2", start.Add(time.Minute), src, true)
+	writeTestBackup(t, base, "app_3", start.Add(2*time.Minute), src, true)
+
+	// Unchanged content is stored once
+	chunks, _ := filepath.Glob(filepath.Join(base, backupChunkDir, "*", "*"))
+	if len(chunks) != 2 {
+		t.Fatalf("Expected 2 distinct chunks, got %d", len(chunks))
+	}
+
+	removed, err := PruneBackups(base, "app", 1)
+	if err != nil {
+		t.Fatalf("PruneBackups returned error: %v", err)
+	}
+	if strings.Join(removed, ",") != "app_2,app_1" {
+		t.Errorf("Expected the two oldest backups to be removed, got %v", removed)
+	}
+
+	// Only the chunk of the remaining backup is kept
+	chunks, _ = filepath.Glob(filepath.Join(base, backupChunkDir, "*", "*"))
+	if len(chunks) != 1 {
+		t.Errorf("Expected 1 chunk after pruning, got %d", len(chunks))
+	}
+	if result, err := VerifyBackup(base, "app_3"); err != nil || !result.Valid {
+		t.Errorf("Expected the remaining backup to verify, got %+v (%v)", result, err)
+	}
+}
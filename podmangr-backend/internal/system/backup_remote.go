@@ -0,0 +1,584 @@
+package system
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"podmangr-backend/internal/auth"
+	"podmangr-backend/internal/database"
+	"podmangr-backend/internal/models"
+)
+
+// Every backup at a destination is stored as two objects next to each other:
+// <id>.json with its metadata and <id>.tar holding the backup directory and
+// the chunks it uses. Encrypted backups have an extra ".enc" suffix.
+const (
+	remoteMetadataSuffix  = ".json"
+	remoteDataSuffix      = ".tar"
+	remoteEncryptedSuffix = ".enc"
+	// remoteDefaultPath is where backups go if a destination names no path
+	remoteDefaultPath = "podmangr-backups"
+)
+
+// remoteObject is a file at a backup destination
+type remoteObject struct {
+	name string
+	size int64
+}
+
+// remoteStore transfers files to and from a backup destination. Names are
+// relative to the destination's path.
+type remoteStore interface {
+	put(ctx context.Context, name, localPath string) error
+	get(ctx context.Context, name, localPath string) error
+	list(ctx context.Context) ([]remoteObject, error)
+	remove(ctx context.Context, name string) error
+}
+
+// BackupRemote copies backups to and from a backup destination
+type BackupRemote struct {
+	dest       *models.BackupDestination
+	store      remoteStore
+	encryption *auth.EncryptionService
+}
+
+// OpenBackupRemote prepares access to a destination, loading its credential
+// from the secrets store
+func OpenBackupRemote(dest *models.BackupDestination) (*BackupRemote, error) {
+	encryption, err := auth.GetEncryptionService()
+	if err != nil {
+		return nil, err
+	}
+
+	var secret string
+	if dest.SecretID != "" {
+		stored, err := database.NewSecretsRepo().GetByID(dest.SecretID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load credentials of %s: %w", dest.Name, err)
+		}
+		if secret, err = encryption.Decrypt(stored.ValueEncrypted); err != nil {
+			return nil, fmt.Errorf("failed to decrypt credentials of %s: %w", dest.Name, err)
+		}
+	}
+
+	store, err := newRemoteStore(dest, secret)
+	if err != nil {
+		return nil, err
+	}
+	return &BackupRemote{dest: dest, store: store, encryption: encryption}, nil
+}
+
+func newRemoteStore(dest *models.BackupDestination, secret string) (remoteStore, error) {
+	dir := dest.Path
+	if dir == "" {
+		dir = remoteDefaultPath
+	}
+	switch dest.Type {
+	case models.BackupDestinationS3:
+		if dest.Bucket == "" || dest.AccessKeyID == "" {
+			return nil, fmt.Errorf("bucket and access_key_id are required")
+		}
+		return newS3Store(dest.Endpoint, dest.Region, dest.Bucket, dir, dest.AccessKeyID, secret, dest.PathStyle)
+	case models.BackupDestinationSFTP, models.BackupDestinationRsync:
+		return newSSHStore(dest.Host, dest.Port, dest.Username, dir, secret, dest.Type == models.BackupDestinationRsync)
+	}
+	return nil, fmt.Errorf("type must be s3, sftp or rsync")
+}
+
+// ValidateBackupDestination checks a destination definition without contacting it
+func ValidateBackupDestination(dest *models.BackupDestination) error {
+	if strings.TrimSpace(dest.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if dest.Port < 0 || dest.Port > 65535 {
+		return fmt.Errorf("invalid port %d", dest.Port)
+	}
+	_, err := newRemoteStore(dest, "")
+	return err
+}
+
+// Check verifies that the destination can be reached with its credentials
+func (r *BackupRemote) Check(ctx context.Context) error {
+	_, err := r.store.list(ctx)
+	return err
+}
+
+// remoteNames returns the object names of a backup
+func remoteNames(backupID string, encrypted bool) (metadata, data string) {
+	suffix := ""
+	if encrypted {
+		suffix = remoteEncryptedSuffix
+	}
+	return backupID + remoteMetadataSuffix + suffix, backupID + remoteDataSuffix + suffix
+}
+
+// seal writes the output of fn to file, encrypting it if the destination asks for it
+func (r *BackupRemote) seal(file io.Writer, fn func(w io.Writer) error) error {
+	if !r.dest.Encrypt {
+		return fn(file)
+	}
+	w, err := r.encryption.NewEncryptWriter(file)
+	if err != nil {
+		return err
+	}
+	if err := fn(w); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// unseal returns the content of a downloaded object
+func (r *BackupRemote) unseal(file io.Reader, encrypted bool) (io.Reader, error) {
+	if !encrypted {
+		return file, nil
+	}
+	reader, err := r.encryption.NewDecryptReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt backup: %w", err)
+	}
+	return reader, nil
+}
+
+// sealToTemp writes the output of fn to a temporary file in dir
+func (r *BackupRemote) sealToTemp(dir string, fn func(w io.Writer) error) (string, error) {
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	err = r.seal(tmp, fn)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// remoteMetadata is the metadata object of an uploaded backup. The checksum
+// of the stored archive lets a download be checked before it is unpacked.
+type remoteMetadata struct {
+	models.ContainerBackup
+	ArchiveSHA256 string `json:"archive_sha256"`
+}
+
+// fileSHA256 returns the hex SHA-256 of a file
+func fileSHA256(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Upload copies a local backup to the destination. The data goes up first,
+// so a backup is only listed once it is complete.
+func (r *BackupRemote) Upload(ctx context.Context, backupBasePath, backupID string) (*models.RemoteBackup, error) {
+	backup, err := LoadBackup(backupBasePath, backupID)
+	if err != nil {
+		return nil, err
+	}
+	metadataName, dataName := remoteNames(backupID, r.dest.Encrypt)
+
+	dataPath, err := r.sealToTemp(backupBasePath, func(w io.Writer) error {
+		return packBackup(w, backupBasePath, backup)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack backup: %w", err)
+	}
+	defer os.Remove(dataPath)
+	info, err := os.Stat(dataPath)
+	if err != nil {
+		return nil, err
+	}
+	sum, err := fileSHA256(dataPath)
+	if err != nil {
+		return nil, err
+	}
+
+	metadataPath, err := r.sealToTemp(backupBasePath, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(remoteMetadata{ContainerBackup: *backup, ArchiveSHA256: sum})
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(metadataPath)
+
+	if err := r.store.put(ctx, dataName, dataPath); err != nil {
+		return nil, fmt.Errorf("failed to upload backup: %w", err)
+	}
+	if err := r.store.put(ctx, metadataName, metadataPath); err != nil {
+		return nil, fmt.Errorf("failed to upload backup metadata: %w", err)
+	}
+
+	return &models.RemoteBackup{
+		ContainerBackup: *backup,
+		DestinationID:   r.dest.ID,
+		Encrypted:       r.dest.Encrypt,
+		StoredBytes:     info.Size(),
+	}, nil
+}
+
+// packBackup writes a backup directory and the chunks it uses as a tar stream
+func packBackup(w io.Writer, backupBasePath string, backup *models.ContainerBackup) error {
+	// Chunks must not be collected while they are being packed
+	backupStoreMu.RLock()
+	defer backupStoreMu.RUnlock()
+
+	dir := filepath.Join(backupBasePath, backup.ID)
+	tw := tar.NewWriter(w)
+	if err := addDirectoryToTar(tw, dir, backup.ID); err != nil {
+		return err
+	}
+
+	if backup.Format == models.BackupFormatChunked {
+		manifest, err := readManifest(dir)
+		if err != nil {
+			return err
+		}
+		store := newChunkStore(backupBasePath)
+		packed := make(map[string]bool)
+		for _, file := range manifest.Files {
+			for _, sum := range file.Chunks {
+				if packed[sum] {
+					continue
+				}
+				packed[sum] = true
+				if err := addFileToTar(tw, store.path(sum), path.Join(backupChunkDir, sum[:2], sum)); err != nil {
+					return fmt.Errorf("chunk %s: %w", sum, err)
+				}
+			}
+		}
+	}
+	return tw.Close()
+}
+
+// addFileToTar writes a regular file to tw under name
+func addFileToTar(tw *tar.Writer, filePath, name string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, file)
+	return err
+}
+
+// List returns the backups at the destination, newest first
+func (r *BackupRemote) List(ctx context.Context) ([]models.RemoteBackup, error) {
+	objects, err := r.store.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make(map[string]int64)
+	for _, obj := range objects {
+		sizes[obj.name] = obj.size
+	}
+
+	tmpDir, err := os.MkdirTemp("", "podmangr-remote-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	backups := make([]models.RemoteBackup, 0)
+	seen := make(map[string]bool)
+	for _, obj := range objects {
+		backupID, encrypted, ok := parseRemoteName(obj.name, remoteMetadataSuffix)
+		if !ok || seen[backupID] {
+			continue
+		}
+		_, dataName := remoteNames(backupID, encrypted)
+		size, complete := sizes[dataName]
+		if !complete {
+			continue
+		}
+
+		metadata, err := r.readMetadata(ctx, obj.name, encrypted, filepath.Join(tmpDir, "metadata"))
+		if err != nil {
+			return nil, fmt.Errorf("backup %s: %w", backupID, err)
+		}
+		seen[backupID] = true
+		backups = append(backups, models.RemoteBackup{
+			ContainerBackup: metadata.ContainerBackup,
+			DestinationID:   r.dest.ID,
+			Encrypted:       encrypted,
+			StoredBytes:     size,
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// parseRemoteName returns the backup ID of an object with the given suffix
+func parseRemoteName(name, suffix string) (string, bool, bool) {
+	encrypted := strings.HasSuffix(name, remoteEncryptedSuffix)
+	name = strings.TrimSuffix(name, remoteEncryptedSuffix)
+	if !strings.HasSuffix(name, suffix) {
+		return "", false, false
+	}
+	backupID := strings.TrimSuffix(name, suffix)
+	if backupID == "" || backupID[0] == '.' || path.Base(backupID) != backupID {
+		return "", false, false
+	}
+	return backupID, encrypted, true
+}
+
+// readMetadata downloads and decodes the metadata object of a backup
+func (r *BackupRemote) readMetadata(ctx context.Context, name string, encrypted bool, localPath string) (*remoteMetadata, error) {
+	if err := r.store.get(ctx, name, localPath); err != nil {
+		return nil, err
+	}
+	defer os.Remove(localPath)
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader, err := r.unseal(file, encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata remoteMetadata
+	if err := json.NewDecoder(reader).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse backup metadata: %w", err)
+	}
+	return &metadata, nil
+}
+
+// find returns whether a backup exists at the destination and if it is encrypted
+func (r *BackupRemote) find(ctx context.Context, backupID string) (bool, error) {
+	objects, err := r.store.list(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, encrypted := range []bool{true, false} {
+		_, dataName := remoteNames(backupID, encrypted)
+		for _, obj := range objects {
+			if obj.name == dataName {
+				return encrypted, nil
+			}
+		}
+	}
+	return false, os.ErrNotExist
+}
+
+// Download copies a backup from the destination into the default backup
+// path, where it can be restored like any local backup. A backup that is
+// already present locally is returned as it is.
+func (r *BackupRemote) Download(ctx context.Context, backupID string) (*models.ContainerBackup, error) {
+	if backup, _, err := FindBackup(backupID); err == nil {
+		return backup, nil
+	}
+
+	encrypted, err := r.find(ctx, backupID)
+	if err != nil {
+		return nil, err
+	}
+
+	backupBasePath := LoadBackupSettings().DefaultPath
+	if err := os.MkdirAll(backupBasePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	// Staged next to its final location, so it is moved into place by renaming
+	staging, err := os.MkdirTemp(backupBasePath, ".download-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	metadataName, dataName := remoteNames(backupID, encrypted)
+	metadata, err := r.readMetadata(ctx, metadataName, encrypted, filepath.Join(staging, "metadata"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup metadata: %w", err)
+	}
+	if metadata.ArchiveSHA256 == "" {
+		return nil, fmt.Errorf("backup metadata has no archive checksum")
+	}
+	dataPath := filepath.Join(staging, "data")
+	if err := r.store.get(ctx, dataName, dataPath); err != nil {
+		return nil, fmt.Errorf("failed to download backup: %w", err)
+	}
+	// The archive is checked before anything in it is unpacked
+	sum, err := fileSHA256(dataPath)
+	if err != nil {
+		return nil, err
+	}
+	if sum != metadata.ArchiveSHA256 {
+		return nil, fmt.Errorf("downloaded archive does not match its checksum")
+	}
+
+	extractDir := filepath.Join(staging, "backup")
+	if err := os.Mkdir(extractDir, 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(dataPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader, err := r.unseal(file, encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if err := extractTar(reader, extractDir, extractOptions{keepOwners: true}); err != nil {
+		return nil, fmt.Errorf("failed to unpack backup: %w", err)
+	}
+
+	backup, err := LoadBackup(extractDir, backupID)
+	if err != nil {
+		return nil, fmt.Errorf("downloaded archive does not contain backup %s: %w", backupID, err)
+	}
+	if backup.ID != backupID {
+		return nil, fmt.Errorf("downloaded archive contains backup %s instead of %s", backup.ID, backupID)
+	}
+	return installBackup(extractDir, backupBasePath, backup)
+}
+
+// installBackup moves an unpacked backup and its chunks into a backup path,
+// pointing its metadata to the new location and checking it against its manifest
+func installBackup(extractDir, backupBasePath string, backup *models.ContainerBackup) (*models.ContainerBackup, error) {
+	backupStoreMu.Lock()
+	defer backupStoreMu.Unlock()
+
+	store := newChunkStore(backupBasePath)
+	err := filepath.WalkDir(filepath.Join(extractDir, backupChunkDir), func(chunkPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		// Anything but chunk files is ignored
+		if d.IsDir() || len(d.Name()) != 64 {
+			return nil
+		}
+		target := store.path(d.Name())
+		if _, err := os.Stat(target); err == nil {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		return os.Rename(chunkPath, target)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store chunks: %w", err)
+	}
+
+	dir := filepath.Join(backupBasePath, backup.ID)
+	if err := os.Rename(filepath.Join(extractDir, backup.ID), dir); err != nil {
+		return nil, fmt.Errorf("failed to store backup: %w", err)
+	}
+
+	oldDir := backup.BackupPath
+	backup.BackupPath = dir
+	for i := range backup.Mounts {
+		if rel, err := filepath.Rel(oldDir, backup.Mounts[i].BackupPath); err == nil && !strings.HasPrefix(rel, "..") {
+			backup.Mounts[i].BackupPath = filepath.Join(dir, rel)
+		}
+	}
+	metadataJSON, _ := json.MarshalIndent(backup, "", "  ")
+	if err := os.WriteFile(filepath.Join(dir, "backup.json"), metadataJSON, 0644); err != nil {
+		return nil, fmt.Errorf("failed to save backup metadata: %w", err)
+	}
+
+	if backup.Format != "" {
+		if result := verifyBackup(backup); !result.Valid {
+			os.RemoveAll(dir)
+			collectChunks(backupBasePath)
+			return nil, fmt.Errorf("downloaded backup is damaged: %s", strings.Join(result.Errors, "; "))
+		}
+	}
+	return backup, nil
+}
+
+// Delete removes a backup from the destination, its metadata first so it is
+// never listed half deleted
+func (r *BackupRemote) Delete(ctx context.Context, backupID string) error {
+	encrypted, err := r.find(ctx, backupID)
+	if err != nil {
+		return err
+	}
+	return r.remove(ctx, backupID, encrypted)
+}
+
+func (r *BackupRemote) remove(ctx context.Context, backupID string, encrypted bool) error {
+	metadataName, dataName := remoteNames(backupID, encrypted)
+	if err := r.store.remove(ctx, metadataName); err != nil {
+		return err
+	}
+	return r.store.remove(ctx, dataName)
+}
+
+// ApplyRetention prunes the backups a job stored at the destination according
+// to its retention policy. It returns the IDs of the removed backups.
+func (r *BackupRemote) ApplyRetention(ctx context.Context, jobID string, retention models.BackupRetention) ([]string, error) {
+	removed := make([]string, 0)
+	if retention == (models.BackupRetention{}) {
+		return removed, nil
+	}
+
+	backups, err := r.List(ctx)
+	if err != nil {
+		return removed, err
+	}
+
+	byContainer := make(map[string][]models.ContainerBackup)
+	encrypted := make(map[string]bool)
+	for _, backup := range backups {
+		if backup.Metadata[BackupJobKey] == jobID {
+			byContainer[backup.ContainerName] = append(byContainer[backup.ContainerName], backup.ContainerBackup)
+			encrypted[backup.ID] = backup.Encrypted
+		}
+	}
+
+	for _, containerBackups := range byContainer {
+		retained := retainedBackups(containerBackups, retention)
+		for _, backup := range containerBackups {
+			if retained[backup.ID] {
+				continue
+			}
+			if err := r.remove(ctx, backup.ID, encrypted[backup.ID]); err != nil {
+				return removed, fmt.Errorf("failed to remove backup %s: %w", backup.ID, err)
+			}
+			removed = append(removed, backup.ID)
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,407 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"podmangr-backend/internal/database"
+	"podmangr-backend/internal/models"
+)
+
+const (
+	backupSchedulerTick = time.Minute
+	backupJobTimeout    = 6 * time.Hour
+)
+
+// ErrBackupJobRunning is returned when a job is started while it is still running
+var ErrBackupJobRunning = errors.New("backup job is already running")
+
+// BackupScheduler runs backup jobs on their cron schedules, records every run
+// and applies each job's retention policy afterwards
+type BackupScheduler struct {
+	registry   *SocketRegistry
+	jobRepo    *database.BackupJobRepo
+	destRepo   *database.BackupDestinationRepo
+	stackRepo  *database.StackRepo
+	serverRepo *database.DatabaseServerRepo
+	dbRepo     *database.DatabaseRepo
+
+	// active holds the IDs of running jobs, so a job never overlaps itself
+	activeMu sync.Mutex
+	active   map[string]bool
+
+	mu      sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+	running bool
+}
+
+// NewBackupScheduler creates a new backup scheduler
+func NewBackupScheduler() *BackupScheduler {
+	return &BackupScheduler{
+		registry:   GetSocketRegistry(),
+		jobRepo:    database.NewBackupJobRepo(),
+		destRepo:   database.NewBackupDestinationRepo(),
+		stackRepo:  database.NewStackRepo(),
+		serverRepo: database.NewDatabaseServerRepo(),
+		dbRepo:     database.NewDatabaseRepo(),
+		active:     make(map[string]bool),
+	}
+}
+
+// Start launches the background scheduling loop
+func (s *BackupScheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return
+	}
+	s.running = true
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	if err := s.jobRepo.FailInterrupted(); err != nil {
+		log.Printf("Failed to mark interrupted backup runs: %v", err)
+	}
+
+	go s.run(s.stop, s.done)
+}
+
+// Stop stops the scheduling loop and waits for it to exit
+func (s *BackupScheduler) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	close(s.stop)
+	done := s.done
+	s.mu.Unlock()
+
+	<-done
+}
+
+func (s *BackupScheduler) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	for {
+		// Jobs are re-read every tick so changes apply without a restart
+		jobs, err := s.jobRepo.List()
+		if err != nil {
+			log.Printf("Failed to list backup jobs: %v", err)
+		}
+		now := time.Now()
+		for i := range jobs {
+			job := &jobs[i]
+			if !job.Enabled {
+				continue
+			}
+			if next := NextBackupRun(job); next.IsZero() || now.Before(next) {
+				continue
+			}
+			if _, err := s.RunJob(context.Background(), job, "schedule"); err != nil && !errors.Is(err, ErrBackupJobRunning) {
+				log.Printf("Backup job %s failed: %v", job.Name, err)
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(backupSchedulerTick):
+		}
+	}
+}
+
+// NextBackupRun returns when a job is due next, counting from its last run,
+// or from its last change if it never ran. It returns zero for invalid schedules.
+func NextBackupRun(job *models.BackupJob) time.Time {
+	schedule, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		return time.Time{}
+	}
+	from := job.UpdatedAt
+	if job.LastRunAt != nil && job.LastRunAt.After(from) {
+		from = *job.LastRunAt
+	}
+	return schedule.Next(from)
+}
+
+// ValidateBackupJob checks a job definition, including that its target exists
+func (s *BackupScheduler) ValidateBackupJob(job *models.BackupJob) error {
+	if strings.TrimSpace(job.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if _, err := ParseSchedule(job.Schedule); err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+
+	r := job.Retention
+	if r.KeepLast < 0 || r.KeepDaily < 0 || r.KeepWeekly < 0 || r.KeepMonthly < 0 {
+		return fmt.Errorf("retention counts must not be negative")
+	}
+
+	if _, _, err := s.resolveDestination(job.Destination); err != nil {
+		return err
+	}
+
+	switch job.TargetType {
+	case models.BackupTargetContainer:
+		if job.TargetID == "" {
+			return fmt.Errorf("target_id must name a container")
+		}
+	case models.BackupTargetStack:
+		if _, err := s.stackRepo.GetByID(job.TargetID); err != nil {
+			return fmt.Errorf("stack %s not found", job.TargetID)
+		}
+	case models.BackupTargetDatabaseServer:
+		if _, err := s.serverRepo.GetByID(job.TargetID); err != nil {
+			return fmt.Errorf("database server %s not found", job.TargetID)
+		}
+	case models.BackupTargetDatabase:
+		if _, err := s.dbRepo.GetByID(job.TargetID); err != nil {
+			return fmt.Errorf("database %s not found", job.TargetID)
+		}
+	default:
+		return fmt.Errorf("target_type must be container, stack, database_server or database")
+	}
+	return nil
+}
+
+// resolveDestination resolves a job destination to the directory backups are
+// written to and, for off-host destinations, the destination they are then
+// uploaded to
+func (s *BackupScheduler) resolveDestination(destination string) (string, *models.BackupDestination, error) {
+	settings := LoadBackupSettings()
+	switch destination {
+	case models.BackupDestinationDefault, "":
+		return settings.DefaultPath, nil, nil
+	case models.BackupDestinationExternal:
+		if settings.ExternalDrivePath == "" {
+			return "", nil, fmt.Errorf("no external drive path is configured in the backup settings")
+		}
+		return settings.ExternalDrivePath, nil, nil
+	}
+
+	remote, err := s.destRepo.GetByID(destination)
+	if err != nil {
+		return "", nil, fmt.Errorf("destination must be default, external or the ID of a backup destination")
+	}
+	return settings.DefaultPath, remote, nil
+}
+
+// jobContainer is a container a job backs up, with the socket it runs on
+type jobContainer struct {
+	svc *PodmanService
+	id  string
+}
+
+// resolveTargets finds the containers of a job's target across all sockets
+func (s *BackupScheduler) resolveTargets(ctx context.Context, job *models.BackupJob) ([]jobContainer, error) {
+	var targets []jobContainer
+
+	switch job.TargetType {
+	case models.BackupTargetContainer:
+		for _, svc := range s.registry.Services() {
+			if inspect, err := svc.InspectContainer(ctx, job.TargetID); err == nil {
+				return []jobContainer{{svc: svc, id: inspect.ID}}, nil
+			}
+		}
+		return nil, fmt.Errorf("container %s not found", job.TargetID)
+
+	case models.BackupTargetStack:
+		stack, err := s.stackRepo.GetByID(job.TargetID)
+		if err != nil {
+			return nil, fmt.Errorf("stack %s not found", job.TargetID)
+		}
+		for _, svc := range s.registry.Services() {
+			containers, err := svc.projectContainers(ctx, stack.Name)
+			if err != nil {
+				continue
+			}
+			for _, c := range byCreated(containers, false) {
+				targets = append(targets, jobContainer{svc: svc, id: c.ID})
+			}
+		}
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("stack %s has no containers", stack.Name)
+		}
+		return targets, nil
+	}
+
+	return nil, fmt.Errorf("unknown target type %q", job.TargetType)
+}
+
+// dumpDatabase writes a logical dump of the database or database server a job targets
+func (s *BackupScheduler) dumpDatabase(ctx context.Context, job *models.BackupJob, backupPath string, metadata map[string]string) (*models.ContainerBackup, error) {
+	dbService, err := NewDatabaseService()
+	if err != nil {
+		return nil, err
+	}
+	if job.TargetType == models.BackupTargetDatabase {
+		return dbService.DumpDatabase(ctx, job.TargetID, backupPath, metadata)
+	}
+	return dbService.DumpServer(ctx, job.TargetID, backupPath, metadata)
+}
+
+// IsRunning reports whether a job is running right now
+func (s *BackupScheduler) IsRunning(jobID string) bool {
+	s.activeMu.Lock()
+	defer s.activeMu.Unlock()
+	return s.active[jobID]
+}
+
+// RunJob backs up every container of a job's target, or dumps its database,
+// applies the job's retention policy and records the run. The run is returned
+// even when it failed; the error is set when nothing could be backed up.
+func (s *BackupScheduler) RunJob(ctx context.Context, job *models.BackupJob, trigger string) (*models.BackupJobRun, error) {
+	s.activeMu.Lock()
+	if s.active[job.ID] {
+		s.activeMu.Unlock()
+		return nil, ErrBackupJobRunning
+	}
+	s.active[job.ID] = true
+	s.activeMu.Unlock()
+	defer func() {
+		s.activeMu.Lock()
+		delete(s.active, job.ID)
+		s.activeMu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, backupJobTimeout)
+	defer cancel()
+
+	run := &models.BackupJobRun{
+		JobID:     job.ID,
+		Status:    models.BackupRunRunning,
+		Trigger:   trigger,
+		StartedAt: time.Now(),
+		BackupIDs: make([]string, 0),
+	}
+	if err := s.jobRepo.CreateRun(run); err != nil {
+		return nil, fmt.Errorf("failed to record backup run: %w", err)
+	}
+
+	var errs []error
+	var remote *BackupRemote
+	backupPath, dest, err := s.resolveDestination(job.Destination)
+	if err == nil {
+		err = os.MkdirAll(backupPath, 0755)
+	}
+	if err == nil && dest != nil {
+		remote, err = OpenBackupRemote(dest)
+	}
+
+	isDump := job.TargetType == models.BackupTargetDatabaseServer || job.TargetType == models.BackupTargetDatabase
+	var targets []jobContainer
+	if err == nil && !isDump {
+		targets, err = s.resolveTargets(ctx, job)
+	}
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	// keep records a finished backup in the run. Off-host backups are staged
+	// locally and only kept at the destination.
+	keep := func(backup *models.ContainerBackup, label string) {
+		if remote != nil {
+			uploaded, err := remote.Upload(ctx, backupPath, backup.ID)
+			if removeErr := DeleteBackup(backupPath, backup.ID); removeErr != nil {
+				log.Printf("Failed to remove staged backup %s: %v", backup.ID, removeErr)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", label, err))
+				return
+			}
+			backup.SizeBytes = uploaded.StoredBytes
+		}
+
+		run.BackupIDs = append(run.BackupIDs, backup.ID)
+		run.SizeBytes += backup.SizeBytes
+	}
+
+	metadata := func() map[string]string {
+		return map[string]string{
+			BackupJobKey: job.ID,
+			"job_name":   job.Name,
+		}
+	}
+
+	// Databases are dumped rather than copied, since their files are only
+	// consistent while the server is stopped
+	if err == nil && isDump {
+		if backup, err := s.dumpDatabase(ctx, job, backupPath, metadata()); err != nil {
+			errs = append(errs, err)
+		} else {
+			keep(backup, "database "+backup.Mounts[0].Source)
+		}
+	}
+
+	for _, target := range targets {
+		backup, err := target.svc.CreateBackup(ctx, target.id, backupPath, metadata(), nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("container %s: %w", shortID(target.id), err))
+			continue
+		}
+		keep(backup, "container "+shortID(target.id))
+	}
+
+	// Retention only runs after a successful backup, so a failing job never
+	// prunes its way down to nothing
+	if len(run.BackupIDs) > 0 {
+		var pruned []string
+		var err error
+		if remote != nil {
+			pruned, err = remote.ApplyRetention(ctx, job.ID, job.Retention)
+		} else {
+			pruned, err = ApplyRetention(backupPath, job.ID, job.Retention)
+		}
+		run.Pruned = pruned
+		if err != nil {
+			errs = append(errs, fmt.Errorf("retention: %w", err))
+		}
+	}
+
+	switch {
+	case len(errs) == 0:
+		run.Status = models.BackupRunSucceeded
+	case len(run.BackupIDs) > 0:
+		run.Status = models.BackupRunPartial
+	default:
+		run.Status = models.BackupRunFailed
+	}
+	runErr := errors.Join(errs...)
+	if runErr != nil {
+		run.Error = runErr.Error()
+	}
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.DurationMs = finishedAt.Sub(run.StartedAt).Milliseconds()
+	if err := s.jobRepo.FinishRun(run); err != nil {
+		log.Printf("Failed to record backup run of %s: %v", job.Name, err)
+	}
+	if err := s.jobRepo.SetLastRun(job.ID, run.StartedAt, run.Status); err != nil {
+		log.Printf("Failed to update backup job %s: %v", job.Name, err)
+	}
+
+	if run.Status == models.BackupRunFailed {
+		return run, runErr
+	}
+	return run, nil
+}
+
+// shortID shortens container IDs for messages, leaving names as they are
+func shortID(id string) string {
+	if len(id) == 64 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,100 @@
+package system
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"podmangr-backend/internal/models"
+)
+
+func TestRetainedBackups(t *testing.T) {
+	// Two backups a day from Wed 2024-01-03 to Fri 2024-03-01, newest first
+	var backups []models.ContainerBackup
+	for day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local); !day.Before(time.Date(2024, 1, 3, 0, 0, 0, 0, time.Local)); day = day.AddDate(0, 0, -1) {
+		for _, hour := range []int{18, 6} {
+			at := day.Add(time.Duration(hour) * time.Hour)
+			backups = append(backups, models.ContainerBackup{ID: at.Format("2006-01-02T15"), CreatedAt: at})
+		}
+	}
+
+	ids := func(retained map[string]bool) []string {
+		result := make([]string, 0, len(retained))
+		for id := range retained {
+			result = append(result, id)
+		}
+		sort.Strings(result)
+		return result
+	}
+
+	tests := []struct {
+		name      string
+		retention models.BackupRetention
+		want      []string
+	}{
+		{"last", models.BackupRetention{KeepLast: 3}, []string{"2024-02-29T18", "2024-03-01T06", "2024-03-01T18"}},
+		{"daily", models.BackupRetention{KeepDaily: 2}, []string{"2024-02-29T18", "2024-03-01T18"}},
+		{"weekly", models.BackupRetention{KeepWeekly: 2}, []string{"2024-02-25T18", "2024-03-01T18"}},
+		{"monthly", models.BackupRetention{KeepMonthly: 5}, []string{"2024-01-31T18", "2024-02-29T18", "2024-03-01T18"}},
+		{"combined", models.BackupRetention{KeepLast: 1, KeepDaily: 1, KeepWeekly: 2, KeepMonthly: 2},
+			[]string{"2024-02-25T18", "2024-02-29T18", "2024-03-01T18"}},
+	}
+	for _, tt := range tests {
+		got := ids(retainedBackups(backups, tt.retention))
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: retained %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	base := t.TempDir()
+	start := time.Date(2024, 5, 1, 3, 0, 0, 0, time.Local)
+
+	write := func(id, container, jobID string, at time.Time) {
+		backup := models.ContainerBackup{ID: id, ContainerName: container, CreatedAt: at}
+		if jobID != "" {
+			backup.Metadata = map[string]string{BackupJobKey: jobID}
+		}
+		data, _ := json.Marshal(backup)
+		if err := os.MkdirAll(filepath.Join(base, id), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(base, id, "backup.json"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		at := start.AddDate(0, 0, i)
+		write(fmt.Sprintf("web_%d", i), "web", "job1", at)
+		write(fmt.Sprintf("db_%d", i), "db", "job1", at)
+		write(fmt.Sprintf("other_%d", i), "web", "job2", at)
+	}
+	write("manual", "web", "", start)
+
+	removed, err := ApplyRetention(base, "job1", models.BackupRetention{KeepLast: 2})
+	if err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	sort.Strings(removed)
+	if want := []string{"db_0", "db_1", "web_0", "web_1"}; fmt.Sprint(removed) != fmt.Sprint(want) {
+		t.Errorf("Removed %v, want %v", removed, want)
+	}
+
+	// Other jobs' and pre-update backups are untouched
+	for _, id := range []string{"web_2", "web_3", "db_2", "db_3", "other_0", "manual"} {
+		if _, err := LoadBackup(base, id); err != nil {
+			t.Errorf("Backup %s should have been kept: %v", id, err)
+		}
+	}
+
+	// Pre-update pruning leaves job backups alone
+	removed, err = PruneBackups(base, "web", 1)
+	if err != nil || len(removed) != 0 {
+		t.Errorf("PruneBackups removed %v (%v), want nothing", removed, err)
+	}
+}
@@ -0,0 +1,228 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sshStore keeps backups in a directory on an SSH server, transferring them
+// with sftp or rsync. Both use the system's OpenSSH client with the
+// destination's private key; host keys are pinned on first use.
+type sshStore struct {
+	host       string
+	port       int
+	user       string
+	dir        string
+	privateKey string
+	rsync      bool
+}
+
+func newSSHStore(host string, port int, user, dir, privateKey string, rsync bool) (*sshStore, error) {
+	if host == "" || user == "" {
+		return nil, fmt.Errorf("host and username are required")
+	}
+	if strings.HasPrefix(host, "-") || strings.HasPrefix(user, "-") {
+		return nil, fmt.Errorf("invalid host or username")
+	}
+	if port == 0 {
+		port = 22
+	}
+	if dir == "" {
+		dir = "."
+	}
+	return &sshStore{host: host, port: port, user: user, dir: dir, privateKey: privateKey, rsync: rsync}, nil
+}
+
+// sshKnownHostsPath returns the known hosts file of backup destinations
+func sshKnownHostsPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".podmangr", "known_hosts")
+}
+
+// withKey writes the private key to a file only the current user can read
+// for the duration of fn, returning the ssh options that use it
+func (s *sshStore) withKey(fn func(opts []string) error) error {
+	knownHosts := sshKnownHostsPath()
+	if err := os.MkdirAll(filepath.Dir(knownHosts), 0700); err != nil {
+		return err
+	}
+	opts := []string{
+		"-o", "BatchMode=yes",
+		"-o", "StrictHostKeyChecking=accept-new",
+		"-o", "UserKnownHostsFile=" + knownHosts,
+		"-o", "ConnectTimeout=30",
+	}
+
+	if s.privateKey != "" {
+		keyFile, err := os.CreateTemp("", "podmangr-key-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(keyFile.Name())
+		key := strings.TrimSpace(s.privateKey) + "\n"
+		_, err = keyFile.WriteString(key)
+		if closeErr := keyFile.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		opts = append(opts, "-o", "IdentitiesOnly=yes", "-i", keyFile.Name())
+	}
+	return fn(opts)
+}
+
+func (s *sshStore) target() string {
+	return s.user + "@" + s.host
+}
+
+// runCommand executes a command, returning its output, or its stderr in the error
+func runCommand(ctx context.Context, name string, args []string, stdin string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s failed: %s", name, msg)
+		}
+		return "", fmt.Errorf("%s failed: %w", name, err)
+	}
+	return stdout.String(), nil
+}
+
+// sftp runs a batch of sftp commands. Commands prefixed with "-" may fail.
+func (s *sshStore) sftp(ctx context.Context, commands ...string) (string, error) {
+	var out string
+	err := s.withKey(func(opts []string) error {
+		args := append([]string{"-b", "-", "-P", strconv.Itoa(s.port)}, opts...)
+		var err error
+		out, err = runCommand(ctx, "sftp", append(args, s.target()), strings.Join(commands, "\n")+"\n")
+		return err
+	})
+	return out, err
+}
+
+// ssh runs a shell command on the server
+func (s *sshStore) ssh(ctx context.Context, command string) (string, error) {
+	var out string
+	err := s.withKey(func(opts []string) error {
+		args := append([]string{"-p", strconv.Itoa(s.port)}, opts...)
+		var err error
+		out, err = runCommand(ctx, "ssh", append(args, s.target(), command), "")
+		return err
+	})
+	return out, err
+}
+
+// rsyncCopy copies a file from src to dst, one of which is remote. Remote
+// paths are sent unquoted; -s keeps the remote shell from interpreting them.
+func (s *sshStore) rsyncCopy(ctx context.Context, src, dst string) error {
+	return s.withKey(func(opts []string) error {
+		shell := append([]string{"ssh", "-p", strconv.Itoa(s.port)}, opts...)
+		_, err := runCommand(ctx, "rsync", []string{"-s", "--partial", "-e", strings.Join(shell, " "), src, dst}, "")
+		return err
+	})
+}
+
+// sftpQuote quotes an argument of an sftp batch command
+func sftpQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// shellQuote quotes an argument of a remote shell command
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func (s *sshStore) remotePath(name string) string {
+	return path.Join(s.dir, name)
+}
+
+func (s *sshStore) put(ctx context.Context, name, localPath string) error {
+	remote := s.remotePath(name)
+	partial := remote + ".part"
+
+	if s.rsync {
+		if _, err := s.ssh(ctx, "mkdir -p -- "+shellQuote(s.dir)); err != nil {
+			return err
+		}
+		if err := s.rsyncCopy(ctx, localPath, s.target()+":"+partial); err != nil {
+			return err
+		}
+		_, err := s.ssh(ctx, "mv -f -- "+shellQuote(partial)+" "+shellQuote(remote))
+		return err
+	}
+
+	// Uploaded under a temporary name, since sftp can't replace files atomically
+	_, err := s.sftp(ctx,
+		"-mkdir "+sftpQuote(s.dir),
+		"put "+sftpQuote(localPath)+" "+sftpQuote(partial),
+		"-rm "+sftpQuote(remote),
+		"rename "+sftpQuote(partial)+" "+sftpQuote(remote),
+	)
+	return err
+}
+
+func (s *sshStore) get(ctx context.Context, name, localPath string) error {
+	if s.rsync {
+		return s.rsyncCopy(ctx, s.target()+":"+s.remotePath(name), localPath)
+	}
+	_, err := s.sftp(ctx, "get "+sftpQuote(s.remotePath(name))+" "+sftpQuote(localPath))
+	return err
+}
+
+func (s *sshStore) list(ctx context.Context) ([]remoteObject, error) {
+	var out string
+	var err error
+	if s.rsync {
+		out, err = s.ssh(ctx, "ls -ln -- "+shellQuote(s.dir)+" 2>/dev/null || true")
+	} else {
+		// A missing directory just means nothing was uploaded yet
+		out, err = s.sftp(ctx, "-ls -ln "+sftpQuote(s.dir))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseLongListing(out), nil
+}
+
+// parseLongListing reads the regular files of an "ls -ln" listing
+func parseLongListing(out string) []remoteObject {
+	var objects []remoteObject
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 9 || !strings.HasPrefix(fields[0], "-") {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			continue
+		}
+		name := path.Base(strings.Join(fields[8:], " "))
+		if strings.HasSuffix(name, ".part") {
+			continue
+		}
+		objects = append(objects, remoteObject{name: name, size: size})
+	}
+	return objects
+}
+
+func (s *sshStore) remove(ctx context.Context, name string) error {
+	if s.rsync {
+		_, err := s.ssh(ctx, "rm -f -- "+shellQuote(s.remotePath(name)))
+		return err
+	}
+	_, err := s.sftp(ctx, "-rm "+sftpQuote(s.remotePath(name)))
+	return err
+}
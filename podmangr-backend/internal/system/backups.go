@@ -0,0 +1,378 @@
+package system
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"podmangr-backend/internal/models"
+)
+
+// BackupSettings represents global backup configuration
+type BackupSettings struct {
+	DefaultPath            string `json:"default_path"`              // Default backup directory
+	ExternalDrivePath      string `json:"external_drive_path"`       // Path to external drive for large backups
+	AutoBackupEnabled      bool   `json:"auto_backup_enabled"`       // Auto-backup before updates
+	MaxBackupsPerContainer int    `json:"max_backups_per_container"` // Max backups to keep (0 = unlimited)
+	Deduplicate            bool   `json:"deduplicate"`               // Share unchanged chunks between backups
+}
+
+// BackupJobKey is the backup metadata key holding the ID of the job that made it
+const BackupJobKey = "job_id"
+
+// backupStoreMu keeps chunk garbage collection from removing chunks that a
+// running backup has written but not yet recorded in its manifest
+var backupStoreMu sync.RWMutex
+
+// backupSettingsPath returns the location of the backup settings file
+func backupSettingsPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".podmangr", "backup_settings.json")
+}
+
+// LoadBackupSettings reads the backup settings, applying defaults for missing values
+func LoadBackupSettings() BackupSettings {
+	settings := BackupSettings{
+		DefaultPath:            DefaultBackupPath(),
+		AutoBackupEnabled:      true,
+		MaxBackupsPerContainer: 3,
+	}
+
+	if data, err := os.ReadFile(backupSettingsPath()); err == nil {
+		json.Unmarshal(data, &settings)
+	}
+	return settings
+}
+
+// SaveBackupSettings writes the backup settings
+func SaveBackupSettings(settings BackupSettings) error {
+	configPath := backupSettingsPath()
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize settings: %w", err)
+	}
+	return os.WriteFile(configPath, data, 0644)
+}
+
+// BackupPaths returns every location backups are kept in: the path of
+// pre-update backups and the destinations of backup jobs
+func BackupPaths() []string {
+	settings := LoadBackupSettings()
+
+	paths := make([]string, 0, 3)
+	seen := make(map[string]bool)
+	for _, path := range []string{DefaultBackupPath(), settings.DefaultPath, settings.ExternalDrivePath} {
+		if path == "" {
+			continue
+		}
+		path = filepath.Clean(path)
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// FindBackup looks up a backup in every backup location, returning it
+// together with the location it was found in
+func FindBackup(backupID string) (*models.ContainerBackup, string, error) {
+	for _, backupBasePath := range BackupPaths() {
+		backup, err := LoadBackup(backupBasePath, backupID)
+		if err == nil {
+			return backup, backupBasePath, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, "", err
+		}
+	}
+	return nil, "", os.ErrNotExist
+}
+
+// newBackupID returns an unused ID for a backup of name made now. Backups of
+// the same name within a second get a suffix.
+func newBackupID(backupBasePath, name string) string {
+	timestamp := time.Now().Format("20060102-150405")
+	id := fmt.Sprintf("%s_%s", name, timestamp)
+	for n := 2; ; n++ {
+		if _, err := os.Stat(filepath.Join(backupBasePath, id)); os.IsNotExist(err) {
+			return id
+		}
+		id = fmt.Sprintf("%s_%s-%d", name, timestamp, n)
+	}
+}
+
+// LoadBackup reads the metadata of a backup
+func LoadBackup(backupBasePath, backupID string) (*models.ContainerBackup, error) {
+	if backupID == "" || filepath.Base(backupID) != backupID || backupID[0] == '.' {
+		return nil, fmt.Errorf("invalid backup ID %q", backupID)
+	}
+
+	data, err := os.ReadFile(filepath.Join(backupBasePath, backupID, "backup.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var backup models.ContainerBackup
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, fmt.Errorf("failed to parse backup metadata: %w", err)
+	}
+	return &backup, nil
+}
+
+// VerifyBackup checks the archives or chunks of a backup against the
+// checksums in its manifest. Plain directory copies have nothing to check.
+func VerifyBackup(backupBasePath, backupID string) (*models.BackupVerifyResult, error) {
+	backup, err := LoadBackup(backupBasePath, backupID)
+	if err != nil {
+		return nil, err
+	}
+	if backup.Format == "" {
+		return nil, fmt.Errorf("backup %s was created without a manifest and can't be verified", backupID)
+	}
+
+	backupStoreMu.RLock()
+	defer backupStoreMu.RUnlock()
+	return verifyBackup(backup), nil
+}
+
+// DeleteBackup removes a backup and the chunks no other backup uses
+func DeleteBackup(backupBasePath, backupID string) error {
+	if _, err := LoadBackup(backupBasePath, backupID); err != nil {
+		return err
+	}
+
+	backupStoreMu.Lock()
+	defer backupStoreMu.Unlock()
+
+	if err := os.RemoveAll(filepath.Join(backupBasePath, backupID)); err != nil {
+		return err
+	}
+	_, err := collectChunks(backupBasePath)
+	return err
+}
+
+// PruneBackups removes the oldest pre-update backups of a container beyond
+// keep and the chunks only they used. Backups made by backup jobs follow the
+// job's retention instead, and database dumps are kept until deleted. It
+// returns the IDs of the removed backups.
+func PruneBackups(backupBasePath, containerName string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+
+	backups, err := listBackups(backupBasePath, containerName)
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []models.ContainerBackup
+	kept := 0
+	for _, backup := range backups {
+		if backup.Metadata[BackupJobKey] != "" || backup.BackupType == models.DatabaseBackupType {
+			continue
+		}
+		if kept < keep {
+			kept++
+			continue
+		}
+		expired = append(expired, backup)
+	}
+	return removeBackups(backupBasePath, expired)
+}
+
+// ApplyRetention prunes the backups a job made according to its retention
+// policy, separately for each container. It returns the IDs of the removed backups.
+func ApplyRetention(backupBasePath, jobID string, retention models.BackupRetention) ([]string, error) {
+	if retention == (models.BackupRetention{}) {
+		return nil, nil
+	}
+
+	backups, err := listBackups(backupBasePath, "")
+	if err != nil {
+		return nil, err
+	}
+
+	byContainer := make(map[string][]models.ContainerBackup)
+	for _, backup := range backups {
+		if backup.Metadata[BackupJobKey] == jobID {
+			byContainer[backup.ContainerName] = append(byContainer[backup.ContainerName], backup)
+		}
+	}
+
+	var expired []models.ContainerBackup
+	for _, containerBackups := range byContainer {
+		retained := retainedBackups(containerBackups, retention)
+		for _, backup := range containerBackups {
+			if !retained[backup.ID] {
+				expired = append(expired, backup)
+			}
+		}
+	}
+	return removeBackups(backupBasePath, expired)
+}
+
+// retainedBackups returns the IDs of the backups, sorted newest first, that a
+// grandfather-father-son policy keeps. Each daily, weekly and monthly rule
+// keeps the newest backup of as many distinct periods as it allows.
+func retainedBackups(backups []models.ContainerBackup, retention models.BackupRetention) map[string]bool {
+	retained := make(map[string]bool)
+	for i := 0; i < retention.KeepLast && i < len(backups); i++ {
+		retained[backups[i].ID] = true
+	}
+
+	rules := []struct {
+		keep   int
+		period func(t time.Time) string
+	}{
+		{retention.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{retention.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{retention.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	for _, rule := range rules {
+		seen := make(map[string]bool)
+		for _, backup := range backups {
+			if len(seen) >= rule.keep {
+				break
+			}
+			period := rule.period(backup.CreatedAt.Local())
+			if !seen[period] {
+				seen[period] = true
+				retained[backup.ID] = true
+			}
+		}
+	}
+	return retained
+}
+
+// removeBackups deletes backups and the chunks only they used
+func removeBackups(backupBasePath string, backups []models.ContainerBackup) ([]string, error) {
+	removed := make([]string, 0)
+	if len(backups) == 0 {
+		return removed, nil
+	}
+
+	backupStoreMu.Lock()
+	defer backupStoreMu.Unlock()
+
+	for _, backup := range backups {
+		if err := os.RemoveAll(filepath.Join(backupBasePath, backup.ID)); err != nil {
+			return removed, fmt.Errorf("failed to remove backup %s: %w", backup.ID, err)
+		}
+		removed = append(removed, backup.ID)
+	}
+
+	if _, err := collectChunks(backupBasePath); err != nil {
+		return removed, err
+	}
+	return removed, nil
+}
+
+// PruneAllBackups applies the retention limit to the pre-update backups of every container
+func PruneAllBackups(backupBasePath string, keep int) ([]string, error) {
+	backups, err := listBackups(backupBasePath, "")
+	if err != nil {
+		return nil, err
+	}
+
+	removed := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, backup := range backups {
+		if seen[backup.ContainerName] {
+			continue
+		}
+		seen[backup.ContainerName] = true
+
+		ids, err := PruneBackups(backupBasePath, backup.ContainerName, keep)
+		removed = append(removed, ids...)
+		if err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// listBackups returns the backups of a container (or all if containerName is
+// empty), newest first
+func listBackups(backupBasePath, containerName string) ([]models.ContainerBackup, error) {
+	entries, err := os.ReadDir(backupBasePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	backups := make([]models.ContainerBackup, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == backupChunkDir {
+			continue
+		}
+		backup, err := LoadBackup(backupBasePath, entry.Name())
+		if err != nil {
+			continue
+		}
+		// Matched by metadata, since name prefixes overlap ("web" and "web_db")
+		if containerName != "" && backup.ContainerName != containerName {
+			continue
+		}
+		backups = append(backups, *backup)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// collectChunks removes chunks no manifest under backupBasePath refers to.
+// Callers hold backupStoreMu for writing.
+func collectChunks(backupBasePath string) (int64, error) {
+	store := newChunkStore(backupBasePath)
+	if _, err := os.Stat(store.dir); os.IsNotExist(err) {
+		return 0, nil
+	}
+
+	entries, err := os.ReadDir(backupBasePath)
+	if err != nil {
+		return 0, err
+	}
+
+	referenced := make(map[string]bool)
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == backupChunkDir {
+			continue
+		}
+		manifest, err := readManifest(filepath.Join(backupBasePath, entry.Name()))
+		if err != nil {
+			if _, statErr := os.Stat(filepath.Join(backupBasePath, entry.Name(), backupManifestFile)); statErr == nil {
+				// An unreadable manifest might still reference chunks, so keep them all
+				return 0, fmt.Errorf("backup %s: %w", entry.Name(), err)
+			}
+			continue
+		}
+		for _, file := range manifest.Files {
+			for _, sum := range file.Chunks {
+				referenced[sum] = true
+			}
+		}
+	}
+
+	freed, err := store.collect(referenced)
+	if freed > 0 {
+		log.Printf("Removed %.2f MB of unused backup chunks", float64(freed)/(1024*1024))
+	}
+	return freed, err
+}
@@ -0,0 +1,367 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"podmangr-backend/internal/database"
+	"podmangr-backend/internal/models"
+)
+
+// UpdateProgressFunc receives the steps of a container update.
+// Details carry extra fields for the client, such as the new container ID.
+type UpdateProgressFunc func(step, message string, isError bool, progress int, details map[string]interface{})
+
+// DefaultBackupPath returns where container backups are stored unless configured otherwise
+func DefaultBackupPath() string {
+	if path := os.Getenv("PODMANGR_BACKUP_PATH"); path != "" {
+		return path
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".podmangr", "backups")
+}
+
+// UpdateContainer replaces a container with one created from a freshly pulled
+// image: it backs up bind mounts and named volumes, renames the original aside,
+// starts the new container and waits for it to become healthy. If any step
+// after the rename fails, the original container and its mount data are restored.
+// The outcome is stored in the update history and returned; the error is
+// set whenever the update did not succeed.
+func (p *PodmanService) UpdateContainer(ctx context.Context, req *models.UpdateContainerImageRequest, userID *int64, progress UpdateProgressFunc) (*models.ContainerUpdateRecord, error) {
+	if progress == nil {
+		progress = func(string, string, bool, int, map[string]interface{}) {}
+	}
+	containerRepo := database.NewContainerRepo()
+	containerID := req.ContainerID
+
+	// Step 1: Get current container configuration
+	progress("config", "Reading container configuration...", false, 5, nil)
+
+	config, err := p.GetContainerConfig(ctx, containerID)
+	if err != nil {
+		progress("config", "Failed to read container config: "+err.Error(), true, 0, nil)
+		return nil, fmt.Errorf("failed to read container config: %w", err)
+	}
+
+	// Enrich with database metadata
+	var dbContainer *models.Container
+	if dc, err := containerRepo.GetByContainerID(containerID); err == nil {
+		dbContainer = dc
+		config.HasWebUI = dc.HasWebUI
+		config.WebUIPort = dc.WebUIPort
+		config.WebUIPath = dc.WebUIPath
+		config.Icon = dc.Icon
+		config.IconLight = dc.IconLight
+		config.IconDark = dc.IconDark
+		config.AutoStart = dc.AutoStart
+	}
+
+	// Determine new image
+	newImage := req.NewImage
+	if newImage == "" {
+		newImage = config.Image // Use same image (will pull latest)
+	}
+
+	// Validate the probe before touching the running container
+	if probe := req.HealthProbe; probe != nil {
+		published := false
+		for _, port := range config.Ports {
+			if port.ContainerPort == probe.Port && port.Protocol != "udp" {
+				published = true
+			}
+		}
+		if !published || (probe.Type != "http" && probe.Type != "tcp") {
+			err := fmt.Errorf("invalid health probe: needs type http or tcp and a published TCP port, got %s on %d", probe.Type, probe.Port)
+			progress("config", err.Error(), true, 0, nil)
+			return nil, err
+		}
+	}
+	healthTimeout := DefaultUpdateHealthTimeout
+	if req.HealthTimeout > 0 {
+		healthTimeout = time.Duration(req.HealthTimeout) * time.Second
+	}
+
+	wasRunning := false
+	if inspect, err := p.InspectContainer(ctx, containerID); err == nil {
+		wasRunning = inspect.State.Running
+	}
+
+	// Persist the outcome in the update history
+	startedAt := time.Now()
+	var backup *models.ContainerBackup
+	finish := func(status, reason, newContainerID string) (*models.ContainerUpdateRecord, error) {
+		finishedAt := time.Now()
+		record := &models.ContainerUpdateRecord{
+			ContainerName:  config.Name,
+			OldContainerID: containerID,
+			NewContainerID: newContainerID,
+			OldImage:       config.Image,
+			NewImage:       newImage,
+			Status:         status,
+			Reason:         reason,
+			StartedAt:      startedAt,
+			FinishedAt:     &finishedAt,
+			CreatedBy:      userID,
+		}
+		if backup != nil {
+			record.BackupID = backup.ID
+		}
+		if err := database.NewContainerUpdateRepo().Create(record); err != nil {
+			log.Printf("Failed to record update of %s: %v", config.Name, err)
+		}
+		if status != models.UpdateStatusSucceeded {
+			return record, errors.New(reason)
+		}
+		return record, nil
+	}
+
+	progress("config", "Configuration read successfully", false, 10, map[string]interface{}{
+		"container_name": config.Name,
+		"current_image":  config.Image,
+		"new_image":      newImage,
+		"has_volumes":    len(config.Volumes) > 0,
+	})
+
+	// Step 2: Backup volumes if requested and volumes exist
+	hasMounts := false
+	for _, vol := range config.Volumes {
+		if vol.Type == "bind" || vol.Type == "volume" {
+			hasMounts = true
+			break
+		}
+	}
+
+	if req.CreateBackup && hasMounts {
+		progress("backup", "Creating backup of bind mounts and volumes...", false, 15, nil)
+
+		// Determine backup path
+		backupPath := req.BackupPath
+		if backupPath == "" {
+			backupPath = DefaultBackupPath()
+		}
+
+		// Create backup directory
+		if err := os.MkdirAll(backupPath, 0755); err != nil {
+			progress("backup", "Failed to create backup directory: "+err.Error(), true, 0, nil)
+			return finish(models.UpdateStatusFailed, "Failed to create backup directory: "+err.Error(), "")
+		}
+
+		// Create progress channel for backup
+		progressChan := make(chan string, 10)
+		backupDone := make(chan error, 1)
+
+		go func() {
+			var backupErr error
+			backup, backupErr = p.BackupMounts(ctx, containerID, backupPath, req.OverwriteBackup, progressChan)
+			close(progressChan)
+			backupDone <- backupErr
+		}()
+
+		// Stream backup progress
+		for msg := range progressChan {
+			progress("backup", msg, false, 20, nil)
+		}
+
+		if err := <-backupDone; err != nil {
+			progress("backup", "Backup failed: "+err.Error(), true, 0, nil)
+			return finish(models.UpdateStatusFailed, "Backup failed: "+err.Error(), "")
+		}
+
+		progress("backup", fmt.Sprintf("Backup created: %s (%.2f MB)", backup.ID, float64(backup.SizeBytes)/(1024*1024)), false, 25, map[string]interface{}{
+			"backup_id":   backup.ID,
+			"backup_path": backup.BackupPath,
+			"backup_size": backup.SizeBytes,
+		})
+	} else if req.CreateBackup && !hasMounts {
+		progress("backup", "No bind mounts or volumes to backup, skipping...", false, 25, nil)
+	}
+
+	// Step 3: Pull new image, unless it is built or loaded locally
+	if req.SkipPull {
+		progress("pull", "Using local image: "+newImage, false, 45, nil)
+	} else {
+		progress("pull", "Pulling new image: "+newImage, false, 30, nil)
+
+		pullChan := make(chan string, 100)
+		pullDone := make(chan error, 1)
+
+		go func() {
+			pullDone <- p.PullImageWithProgress(ctx, newImage, pullChan)
+		}()
+
+		for line := range pullChan {
+			progress("pull", line, false, 35, map[string]interface{}{"output": true})
+		}
+
+		if err := <-pullDone; err != nil {
+			progress("pull", "Failed to pull image: "+err.Error(), true, 0, nil)
+			return finish(models.UpdateStatusFailed, "Failed to pull image: "+err.Error(), "")
+		}
+
+		progress("pull", "Image pulled successfully", false, 45, nil)
+	}
+
+	// Step 4: Stop current container
+	stopTimeout := req.StopTimeout
+	if stopTimeout == 0 {
+		stopTimeout = 30
+	}
+
+	progress("stop", "Stopping current container...", false, 50, nil)
+
+	if err := p.StopContainer(ctx, containerID, stopTimeout); err != nil {
+		// Container might already be stopped, that's okay
+		progress("stop", "Container stopped (or was already stopped)", false, 55, nil)
+	} else {
+		progress("stop", "Container stopped", false, 55, nil)
+	}
+
+	// Step 5: Rename old container
+	backupContainerName := fmt.Sprintf("%s_backup_%s", config.Name, time.Now().Format("20060102_150405"))
+	progress("rename", "Renaming old container to: "+backupContainerName, false, 60, nil)
+
+	if err := p.RenameContainer(ctx, containerID, backupContainerName); err != nil {
+		progress("rename", "Failed to rename container: "+err.Error(), true, 0, nil)
+		return finish(models.UpdateStatusFailed, "Failed to rename container: "+err.Error(), "")
+	}
+
+	progress("rename", "Old container renamed", false, 65, nil)
+
+	// rollback removes the new container and brings back the original,
+	// restoring the mount backup if the new container got to run
+	rollback := func(step, reason, newContainerID string) (*models.ContainerUpdateRecord, error) {
+		progress(step, reason+", rolling back...", true, 0, nil)
+		if newContainerID != "" {
+			p.RemoveContainer(ctx, newContainerID, true)
+			if backup != nil {
+				if err := p.RestoreMounts(ctx, backup, nil); err != nil {
+					progress("rollback", "Warning: Failed to restore mounts: "+err.Error(), true, 0, nil)
+				} else {
+					progress("rollback", "Mounts restored from backup "+backup.ID, false, 0, nil)
+				}
+			}
+		}
+		if err := p.RenameContainer(ctx, backupContainerName, config.Name); err != nil {
+			progress("rollback", "Warning: Failed to rename original container back: "+err.Error(), true, 0, nil)
+		}
+		if wasRunning {
+			if err := p.StartContainer(ctx, containerID); err != nil {
+				progress("rollback", "Warning: Failed to start original container: "+err.Error(), true, 0, nil)
+			}
+		}
+		progress(step, "Rollback complete. Original container restored.", true, 0, map[string]interface{}{
+			"rolled_back": true,
+		})
+		return finish(models.UpdateStatusRolledBack, reason, newContainerID)
+	}
+
+	// Step 6: Create new container with updated image
+	progress("create", "Creating new container with updated image...", false, 70, nil)
+
+	createReq := &models.CreateContainerRequest{
+		Name:          config.Name,
+		Image:         newImage,
+		Ports:         config.Ports,
+		Volumes:       config.Volumes,
+		Environment:   config.Environment,
+		Labels:        config.Labels,
+		RestartPolicy: config.RestartPolicy,
+		NetworkMode:   config.NetworkMode,
+		Hostname:      config.Hostname,
+		User:          config.User,
+		WorkDir:       config.WorkDir,
+		Entrypoint:    config.Entrypoint,
+		Command:       config.Command,
+		CPULimit:      config.CPULimit,
+		MemoryLimit:   config.MemoryLimit,
+		HasWebUI:      config.HasWebUI,
+		WebUIPort:     config.WebUIPort,
+		WebUIPath:     config.WebUIPath,
+		Icon:          config.Icon,
+		IconLight:     config.IconLight,
+		IconDark:      config.IconDark,
+		AutoStart:     config.AutoStart,
+	}
+
+	newContainerID, err := p.CreateContainer(ctx, createReq)
+	if err != nil {
+		return rollback("create", "Failed to create new container: "+err.Error(), "")
+	}
+
+	progress("create", "New container created", false, 80, map[string]interface{}{
+		"new_container_id": newContainerID,
+	})
+
+	// Step 7: Start new container
+	progress("start", "Starting new container...", false, 85, nil)
+
+	if err := p.StartContainer(ctx, newContainerID); err != nil {
+		return rollback("start", "Failed to start new container: "+err.Error(), newContainerID)
+	}
+
+	progress("start", "New container started", false, 85, nil)
+
+	// Step 8: Wait for the new container to become healthy
+	progress("health", fmt.Sprintf("Waiting up to %s for the new container to become healthy...", healthTimeout), false, 86, nil)
+
+	healthChan := make(chan string, 10)
+	healthDone := make(chan error, 1)
+
+	go func() {
+		healthDone <- p.WaitHealthy(ctx, newContainerID, req.HealthProbe, healthTimeout, healthChan)
+		close(healthChan)
+	}()
+
+	for msg := range healthChan {
+		progress("health", msg, false, 88, nil)
+	}
+
+	if err := <-healthDone; err != nil {
+		return rollback("health", "New container is not healthy: "+err.Error(), newContainerID)
+	}
+
+	progress("health", "New container is healthy", false, 90, nil)
+
+	// Step 9: Update database record
+	if dbContainer != nil {
+		dbContainer.ContainerID = newContainerID
+		dbContainer.Image = newImage
+		dbContainer.Status = models.ContainerStatusRunning
+		containerRepo.Update(dbContainer)
+	}
+
+	// Step 10: Optionally remove old container
+	if req.RemoveOld {
+		progress("cleanup", "Removing old container backup...", false, 95, nil)
+		if err := p.RemoveContainer(ctx, backupContainerName, true); err != nil {
+			progress("cleanup", "Warning: Failed to remove old container: "+err.Error(), false, 95, nil)
+		} else {
+			progress("cleanup", "Old container removed", false, 97, nil)
+		}
+	} else {
+		progress("cleanup", fmt.Sprintf("Old container kept as: %s", backupContainerName), false, 97, nil)
+	}
+
+	// Final success
+	details := map[string]interface{}{
+		"new_container_id":   newContainerID,
+		"new_image":          newImage,
+		"backup_container":   backupContainerName,
+		"backup_removed":     req.RemoveOld,
+		"volume_backup_id":   "",
+		"volume_backup_path": "",
+		"complete":           true,
+	}
+	if backup != nil {
+		details["volume_backup_id"] = backup.ID
+		details["volume_backup_path"] = backup.BackupPath
+	}
+	progress("complete", "Container updated successfully!", false, 100, details)
+
+	return finish(models.UpdateStatusSucceeded, "", newContainerID)
+}
@@ -0,0 +1,481 @@
+package system
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"podmangr-backend/internal/models"
+)
+
+// Metadata keys of database dumps
+const (
+	DatabaseBackupServerKey   = "server_id"
+	DatabaseBackupDatabaseKey = "database_id" // Unset for dumps of a whole server
+	databaseBackupNameKey     = "database"
+	databaseBackupEngineKey   = "engine"
+)
+
+// ExecStream runs a command inside a container, feeding it stdin if not nil
+// and writing its output to stdout. Unlike Exec the streams are never held
+// in memory, so dumps of any size can pass through.
+func (p *PodmanService) ExecStream(ctx context.Context, containerID string, command []string, stdin io.Reader, stdout io.Writer) error {
+	args := []string{"exec"}
+	if stdin != nil {
+		args = append(args, "-i")
+	}
+	args = append(args, containerID)
+	args = append(args, command...)
+
+	cmd := p.podmanStreamCmd(ctx, args...)
+	var stderr bytes.Buffer
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w - %s", command[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// dumpCommand returns the command that writes a dump of dbName, or of every
+// database of the server if dbName is empty, to stdout, together with the
+// name the dump is stored under. PostgreSQL databases are dumped in the
+// custom format without compression, leaving that to the backup store so
+// unchanged data deduplicates.
+func dumpCommand(engine models.DatabaseEngine, dbName, rootPassword string) ([]string, string, error) {
+	switch engine {
+	case models.EnginePostgreSQL:
+		if dbName == "" {
+			return []string{"pg_dumpall", "-U", "postgres", "--clean", "--if-exists"}, "server.sql", nil
+		}
+		return []string{"pg_dump", "-U", "postgres", "--format=custom", "--compress=0", "--dbname=" + dbName}, "database.dump", nil
+	case models.EngineMariaDB, models.EngineMySQL:
+		dump := "mysqldump"
+		if engine == models.EngineMariaDB {
+			dump = "mariadb-dump"
+		}
+		cmd := []string{dump, "-u", "root", fmt.Sprintf("-p%s", rootPassword),
+			"--single-transaction", "--routines", "--triggers", "--events", "--hex-blob"}
+		if dbName == "" {
+			return append(cmd, "--all-databases"), "server.sql", nil
+		}
+		return append(cmd, dbName), "database.sql", nil
+	}
+	return nil, "", fmt.Errorf("unsupported engine: %s", engine)
+}
+
+// restoreCommand returns the command that restores a dump read from stdin
+// into dbName, or a dump of a whole server if dbName is empty. Restored
+// PostgreSQL objects are owned by owner; a database restore runs in a single
+// transaction, so a failure leaves the database as it was. Every restore
+// stops at the first failing statement.
+func restoreCommand(engine models.DatabaseEngine, dbName, owner, rootPassword string) ([]string, error) {
+	switch engine {
+	case models.EnginePostgreSQL:
+		if dbName == "" {
+			return []string{"psql", "-U", "postgres", "-d", "postgres", "-X", "-q", "-v", "ON_ERROR_STOP=1"}, nil
+		}
+		cmd := []string{"pg_restore", "-U", "postgres", "--dbname=" + dbName,
+			"--clean", "--if-exists", "--no-owner", "--single-transaction"}
+		if owner != "" {
+			cmd = append(cmd, "--role="+owner)
+		}
+		return cmd, nil
+	case models.EngineMariaDB, models.EngineMySQL:
+		client := "mysql"
+		if engine == models.EngineMariaDB {
+			client = "mariadb"
+		}
+		cmd := []string{client, "-u", "root", fmt.Sprintf("-p%s", rootPassword)}
+		if dbName != "" {
+			cmd = append(cmd, dbName)
+		}
+		return cmd, nil
+	}
+	return nil, fmt.Errorf("unsupported engine: %s", engine)
+}
+
+// dumpFamily groups engines whose dumps can be restored into each other
+func dumpFamily(engine models.DatabaseEngine) string {
+	if engine == models.EngineMariaDB {
+		return string(models.EngineMySQL)
+	}
+	return string(engine)
+}
+
+// backupNamePart makes a database name usable in a backup ID
+func backupNamePart(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '-'
+	}, name)
+}
+
+// DumpDatabase writes a logical dump of a database to the backup store at
+// backupBasePath while the server keeps running. Metadata is stored with
+// the backup as is.
+func (s *DatabaseService) DumpDatabase(ctx context.Context, id, backupBasePath string, metadata map[string]string) (*models.ContainerBackup, error) {
+	db, err := s.dbRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	server, err := s.serverRepo.GetByID(db.ServerID)
+	if err != nil {
+		return nil, fmt.Errorf("server not found: %w", err)
+	}
+
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	metadata[DatabaseBackupDatabaseKey] = db.ID
+	metadata[databaseBackupNameKey] = db.Name
+	return s.dump(ctx, server, db.Name, backupBasePath, metadata)
+}
+
+// DumpServer writes a logical dump of every database of a server, including
+// roles and users, to the backup store at backupBasePath
+func (s *DatabaseService) DumpServer(ctx context.Context, serverID, backupBasePath string, metadata map[string]string) (*models.ContainerBackup, error) {
+	server, err := s.serverRepo.GetByID(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	return s.dump(ctx, server, "", backupBasePath, metadata)
+}
+
+// dump runs the dump command of a server and stores its output as the only
+// file of a backup, with the manifest and backup metadata
+func (s *DatabaseService) dump(ctx context.Context, server *models.DatabaseServer, dbName, backupBasePath string, metadata map[string]string) (*models.ContainerBackup, error) {
+	s.syncServerStatus(ctx, server)
+	if server.Status != models.DatabaseServerStatusRunning {
+		return nil, fmt.Errorf("server must be running to dump databases (current status: %s)", server.Status)
+	}
+
+	rootPassword, err := s.encryption.Decrypt(server.RootPasswordEncrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt server credentials: %w", err)
+	}
+	command, fileName, err := dumpCommand(server.Engine, dbName, rootPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	containerName := fmt.Sprintf("podmangr-db-%s", server.Name)
+	name := containerName
+	source := server.Name
+	if dbName != "" {
+		name += "-" + backupNamePart(dbName)
+		source = dbName
+	}
+
+	if err := os.MkdirAll(backupBasePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	id := newBackupID(backupBasePath, name)
+	backupDir := filepath.Join(backupBasePath, id)
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	metadata[DatabaseBackupServerKey] = server.ID
+	metadata[databaseBackupEngineKey] = string(server.Engine)
+	backup := &models.ContainerBackup{
+		ID:            id,
+		ContainerID:   server.ContainerID,
+		ContainerName: containerName,
+		Image:         server.Image,
+		BackupPath:    backupDir,
+		BackupType:    models.DatabaseBackupType,
+		Mounts: []models.BackupMount{{
+			Source: source,
+			Target: fileName,
+			Type:   "dump",
+		}},
+		CreatedAt: time.Now(),
+		Metadata:  metadata,
+	}
+
+	if err := s.writeDump(ctx, backup, command, LoadBackupSettings().Deduplicate); err != nil {
+		os.RemoveAll(backupDir)
+		return nil, err
+	}
+	return backup, nil
+}
+
+// writeDump streams the output of the dump command into the backup directory
+func (s *DatabaseService) writeDump(ctx context.Context, backup *models.ContainerBackup, command []string, deduplicate bool) error {
+	backupStoreMu.RLock()
+	defer backupStoreMu.RUnlock()
+
+	var chunks *chunkStore
+	if deduplicate {
+		chunks = newChunkStore(filepath.Dir(backup.BackupPath))
+	}
+	writer := newBackupWriter(backup.BackupPath, chunks)
+
+	dumpCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pr, pw := io.Pipe()
+	dumpDone := make(chan error, 1)
+	go func() {
+		err := s.podman.ExecStream(dumpCtx, backup.ContainerID, command, nil, pw)
+		pw.CloseWithError(err)
+		dumpDone <- err
+	}()
+	archivePath, size, err := writer.addFile(0, backup.Mounts[0].Target, pr)
+	if err != nil {
+		cancel()
+	}
+	pr.CloseWithError(err)
+	if dumpErr := <-dumpDone; err == nil {
+		err = dumpErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to dump %s: %w", backup.Mounts[0].Source, err)
+	}
+
+	manifestSize, err := writer.close()
+	if err != nil {
+		return err
+	}
+
+	backup.Mounts[0].BackupPath = archivePath
+	backup.Mounts[0].SizeBytes = size
+	backup.Format = writer.manifest.Format
+	backup.Compression = writer.manifest.Compression
+	backup.SizeBytes = size + manifestSize
+
+	metadataJSON, _ := json.MarshalIndent(backup, "", "  ")
+	if err := os.WriteFile(filepath.Join(backup.BackupPath, "backup.json"), metadataJSON, 0644); err != nil {
+		return fmt.Errorf("failed to save backup metadata: %w", err)
+	}
+	return nil
+}
+
+// ListDatabaseBackups returns the dumps of a database, or with an empty
+// databaseID those of the whole server, from every backup location, newest first
+func ListDatabaseBackups(serverID, databaseID string) ([]models.ContainerBackup, error) {
+	dumps := make([]models.ContainerBackup, 0)
+	for _, backupBasePath := range BackupPaths() {
+		backups, err := listBackups(backupBasePath, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, backup := range backups {
+			if backup.BackupType == models.DatabaseBackupType &&
+				backup.Metadata[DatabaseBackupServerKey] == serverID &&
+				backup.Metadata[DatabaseBackupDatabaseKey] == databaseID {
+				dumps = append(dumps, backup)
+			}
+		}
+	}
+
+	sort.Slice(dumps, func(i, j int) bool {
+		return dumps[i].CreatedAt.After(dumps[j].CreatedAt)
+	})
+	return dumps, nil
+}
+
+// selectDatabaseBackup picks the dump a restore request asks for from dumps
+// sorted newest first: the one named by backupID, or the newest one taken
+// at or before latestBefore
+func selectDatabaseBackup(dumps []models.ContainerBackup, backupID string, latestBefore *time.Time) (*models.ContainerBackup, error) {
+	switch {
+	case backupID != "":
+		for i := range dumps {
+			if dumps[i].ID == backupID {
+				return &dumps[i], nil
+			}
+		}
+		return nil, fmt.Errorf("backup %s is not a dump of this database: %w", backupID, os.ErrNotExist)
+	case latestBefore != nil:
+		for i := range dumps {
+			if !dumps[i].CreatedAt.After(*latestBefore) {
+				return &dumps[i], nil
+			}
+		}
+		return nil, fmt.Errorf("no dump was taken at or before %s: %w", latestBefore.Format(time.RFC3339), os.ErrNotExist)
+	}
+	return nil, fmt.Errorf("backup_id or latest_before is required")
+}
+
+// RestoreDatabase restores a dump of a database into the same database or,
+// with req.TargetDatabase set, into another database of the same server,
+// which is created if it doesn't exist yet. Objects in the dump replace
+// those of the target database; other objects are kept. The password of a
+// created database is returned once, as on creation.
+func (s *DatabaseService) RestoreDatabase(ctx context.Context, id string, req *models.RestoreDatabaseRequest, userID *int64) (*models.RestoreDatabaseResponse, error) {
+	source, err := s.dbRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	server, err := s.serverRepo.GetByID(source.ServerID)
+	if err != nil {
+		return nil, fmt.Errorf("server not found: %w", err)
+	}
+
+	dumps, err := ListDatabaseBackups(server.ID, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	backup, err := selectDatabaseBackup(dumps, req.BackupID, req.LatestBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.RestoreDatabaseResponse{BackupID: backup.ID, Database: source}
+	if req.TargetDatabase != "" && req.TargetDatabase != source.Name {
+		target, password, err := s.restoreTarget(ctx, server, req.TargetDatabase, userID)
+		if err != nil {
+			return nil, err
+		}
+		response.Database = target
+		response.Password = password
+	}
+
+	if err := s.restoreDump(ctx, server, backup, response.Database.Name, response.Database.Username); err != nil {
+		// A database created for the restore is removed again rather than left half restored
+		if response.Password != "" {
+			_ = s.DeleteDatabase(ctx, response.Database.ID)
+		}
+		return nil, err
+	}
+	return response, nil
+}
+
+// restoreTarget looks up a database of a server by name, creating it if it
+// doesn't exist. The password is only returned for created databases.
+func (s *DatabaseService) restoreTarget(ctx context.Context, server *models.DatabaseServer, name string, userID *int64) (*models.Database, string, error) {
+	databases, err := s.dbRepo.ListByServerID(server.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, db := range databases {
+		if db.Name == name {
+			target, err := s.dbRepo.GetByID(db.ID)
+			return target, "", err
+		}
+	}
+
+	target, password, err := s.CreateDatabase(ctx, server.ID, &models.CreateDatabaseRequest{Name: name}, userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create database %s: %w", name, err)
+	}
+	return target, password, nil
+}
+
+// RestoreServer restores a dump of a whole server, recreating its databases and roles
+func (s *DatabaseService) RestoreServer(ctx context.Context, serverID string, req *models.RestoreDatabaseRequest) (*models.RestoreDatabaseResponse, error) {
+	server, err := s.serverRepo.GetByID(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	dumps, err := ListDatabaseBackups(server.ID, "")
+	if err != nil {
+		return nil, err
+	}
+	backup, err := selectDatabaseBackup(dumps, req.BackupID, req.LatestBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.restoreDump(ctx, server, backup, "", ""); err != nil {
+		return nil, err
+	}
+	return &models.RestoreDatabaseResponse{BackupID: backup.ID}, nil
+}
+
+// restoreDump verifies a dump against its manifest and streams it into the
+// restore command of a server
+func (s *DatabaseService) restoreDump(ctx context.Context, server *models.DatabaseServer, backup *models.ContainerBackup, dbName, owner string) error {
+	s.syncServerStatus(ctx, server)
+	if server.Status != models.DatabaseServerStatusRunning {
+		return fmt.Errorf("server must be running to restore databases (current status: %s)", server.Status)
+	}
+	if engine := models.DatabaseEngine(backup.Metadata[databaseBackupEngineKey]); dumpFamily(engine) != dumpFamily(server.Engine) {
+		return fmt.Errorf("a %s dump can't be restored into a %s server", engine, server.Engine)
+	}
+
+	rootPassword, err := s.encryption.Decrypt(server.RootPasswordEncrypted)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt server credentials: %w", err)
+	}
+	command, err := restoreCommand(server.Engine, dbName, owner, rootPassword)
+	if err != nil {
+		return err
+	}
+
+	backupStoreMu.RLock()
+	defer backupStoreMu.RUnlock()
+
+	if result := verifyBackup(backup); !result.Valid {
+		return fmt.Errorf("backup %s is damaged: %s", backup.ID, strings.Join(result.Errors, "; "))
+	}
+	manifest, err := readManifest(backup.BackupPath)
+	if err != nil {
+		return err
+	}
+	stream, err := openBackupMount(backup, manifest, 0)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	tr := tar.NewReader(stream)
+	if _, err := tr.Next(); err != nil {
+		return fmt.Errorf("failed to read dump: %w", err)
+	}
+	var input io.Reader = tr
+	if server.Engine == models.EnginePostgreSQL && dbName == "" {
+		// pg_dumpall --clean drops every role, including the postgres
+		// superuser psql restores as, which would stop the restore
+		filtered := withoutLine(tr, "DROP ROLE IF EXISTS postgres;")
+		defer filtered.Close()
+		input = filtered
+	}
+	if err := s.podman.ExecStream(ctx, server.ContainerID, command, input, io.Discard); err != nil {
+		return fmt.Errorf("failed to restore backup %s: %w", backup.ID, err)
+	}
+	return nil
+}
+
+// withoutLine copies r, leaving out every line equal to line. Closing the
+// returned reader stops the copy.
+func withoutLine(r io.Reader, line string) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		br := bufio.NewReader(r)
+		for {
+			text, err := br.ReadString('\n')
+			if strings.TrimSuffix(text, "\n") != line {
+				if _, werr := io.WriteString(pw, text); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				pw.CloseWithError(err)
+				return
+			}
+		}
+	}()
+	return pr
+}
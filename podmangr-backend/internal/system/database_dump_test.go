@@ -0,0 +1,126 @@
+package system
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"podmangr-backend/internal/models"
+)
+
+func TestBackupWriterAddFile(t *testing.T) {
+	// Larger than a chunk, so chunked dumps span several chunks
+	dump := strings.Repeat("INSERT INTO t VALUES (1);\n", backupChunkSize/16)
+
+	for _, deduplicate := range []bool{false, true} {
+		name := fmt.Sprintf("deduplicate=%v", deduplicate)
+		base := t.TempDir()
+		dir := filepath.Join(base, "db_20240101-000000")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		var chunks *chunkStore
+		if deduplicate {
+			chunks = newChunkStore(base)
+		}
+		writer := newBackupWriter(dir, chunks)
+		archivePath, _, err := writer.addFile(0, "database.sql", strings.NewReader(dump))
+		if err != nil {
+			t.Fatalf("%s: addFile returned error: %v", name, err)
+		}
+		if _, err := writer.close(); err != nil {
+			t.Fatal(err)
+		}
+
+		backup := &models.ContainerBackup{
+			ID:         filepath.Base(dir),
+			BackupPath: dir,
+			BackupType: models.DatabaseBackupType,
+			Format:     writer.manifest.Format,
+			Mounts:     []models.BackupMount{{Target: "database.sql", Type: "dump", BackupPath: archivePath}},
+		}
+		if result := verifyBackup(backup); !result.Valid || result.BytesChecked != int64(len(dump)) {
+			t.Errorf("%s: dump does not verify: %+v", name, result)
+		}
+
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), ".spool-") {
+				t.Errorf("%s: spool file %s left behind", name, entry.Name())
+			}
+		}
+
+		manifest, err := readManifest(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream, err := openBackupMount(backup, manifest, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr := tar.NewReader(stream)
+		if _, err := tr.Next(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		data, err := io.ReadAll(tr)
+		stream.Close()
+		if err != nil || string(data) != dump {
+			t.Errorf("%s: read back %d bytes (%v), want %d", name, len(data), err, len(dump))
+		}
+	}
+}
+
+func TestSelectDatabaseBackup(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 2, 0, 0, 0, time.UTC) }
+	dumps := []models.ContainerBackup{
+		{ID: "db_3", CreatedAt: day(3)},
+		{ID: "db_2", CreatedAt: day(2)},
+		{ID: "db_1", CreatedAt: day(1)},
+	}
+	before := func(t time.Time) *time.Time { return &t }
+
+	tests := []struct {
+		backupID string
+		before   *time.Time
+		want     string
+	}{
+		{"db_2", nil, "db_2"},
+		{"", before(day(2)), "db_2"},
+		{"", before(day(2).Add(time.Hour)), "db_2"},
+		{"", before(day(5)), "db_3"},
+		{"", before(day(1).Add(-time.Second)), ""},
+		{"other", nil, ""},
+		{"", nil, ""},
+	}
+	for _, tt := range tests {
+		got, err := selectDatabaseBackup(dumps, tt.backupID, tt.before)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("selectDatabaseBackup(%q, %v) = %s, want error", tt.backupID, tt.before, got.ID)
+			}
+			continue
+		}
+		if err != nil || got.ID != tt.want {
+			t.Errorf("selectDatabaseBackup(%q, %v) = %v, %v, want %s", tt.backupID, tt.before, got, err, tt.want)
+		}
+	}
+}
+
+func TestWithoutLine(t *testing.T) {
+	dump := "DROP DATABASE IF EXISTS app;\nDROP ROLE IF EXISTS app;\nDROP ROLE IF EXISTS postgres;\nCREATE ROLE app;\n"
+	r := withoutLine(strings.NewReader(dump), "DROP ROLE IF EXISTS postgres;")
+	defer r.Close()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "DROP DATABASE IF EXISTS app;\nDROP ROLE IF EXISTS app;\nCREATE ROLE app;\n"; string(got) != want {
+		t.Errorf("withoutLine = %q, want %q", got, want)
+	}
+}
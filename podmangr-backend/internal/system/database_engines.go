@@ -0,0 +1,195 @@
+package system
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"podmangr-backend/internal/models"
+)
+
+// redisACLFile keeps the ACL users of Redis and Valkey servers in their data
+// volume, so databases survive restarts
+const redisACLFile = "/data/users.acl"
+
+// redisServerCommand returns the container command of a Redis or Valkey
+// server, passing args on to the server. The ACL file is seeded with the
+// password of the default user on first start, before the image's entrypoint
+// takes over.
+func redisServerCommand(engine models.DatabaseEngine, args ...string) []string {
+	server := "redis-server"
+	if engine == models.EngineValkey {
+		server = "valkey-server"
+	}
+	script := fmt.Sprintf(`[ -f %[1]s ] || printf 'user default on >%%s ~* &* +@all\n' "$REDIS_PASSWORD" > %[1]s
+exec docker-entrypoint.sh %[2]s --aclfile %[1]s --appendonly yes "$@"`, redisACLFile, server)
+	return append([]string{"sh", "-c", script, "sh"}, args...)
+}
+
+// connectionString builds the URI applications use to connect to a database
+func connectionString(engine models.DatabaseEngine, username, password, host string, port int, dbName string) string {
+	switch engine {
+	case models.EnginePostgreSQL:
+		return fmt.Sprintf("postgresql://%s:%s@%s:%d/%s", username, password, host, port, dbName)
+	case models.EngineMariaDB, models.EngineMySQL:
+		return fmt.Sprintf("mysql://%s:%s@%s:%d/%s", username, password, host, port, dbName)
+	case models.EngineRedis, models.EngineValkey:
+		// Keys are confined to the "<database>:" prefix rather than a numbered database
+		return fmt.Sprintf("redis://%s:%s@%s:%d/0", username, password, host, port)
+	case models.EngineMongoDB:
+		return fmt.Sprintf("mongodb://%s:%s@%s:%d/%s?authSource=%s", username, password, host, port, dbName, dbName)
+	case models.EngineMSSQL:
+		return fmt.Sprintf("sqlserver://%s:%s@%s:%d?database=%s", username, password, host, port, dbName)
+	}
+	return ""
+}
+
+// readinessCheck returns a command that succeeds once a server accepts
+// connections, and the output it must print, if any
+func readinessCheck(engine models.DatabaseEngine, rootPassword string) ([]string, string) {
+	switch engine {
+	case models.EnginePostgreSQL:
+		// Over TCP, as the server initializing a new volume only listens on its socket
+		return []string{"pg_isready", "-U", "postgres", "-h", "127.0.0.1"}, ""
+	case models.EngineMariaDB, models.EngineMySQL:
+		return []string{"mysqladmin", "-u", "root", fmt.Sprintf("-p%s", rootPassword), "ping"}, ""
+	case models.EngineRedis, models.EngineValkey:
+		// redis-cli exits successfully even when the server replies with an error
+		return redisCLI(engine, rootPassword, "PING"), "PONG"
+	case models.EngineMongoDB:
+		return []string{"mongosh", "--quiet", "--norc", "--eval", "db.adminCommand({ping: 1}).ok"}, "1"
+	case models.EngineMSSQL:
+		return sqlcmd(rootPassword, "SELECT 1"), ""
+	}
+	return nil, ""
+}
+
+// redisCLI returns a redis-cli (or valkey-cli) command authenticated as the default user
+func redisCLI(engine models.DatabaseEngine, rootPassword string, args ...string) []string {
+	cli := "redis-cli"
+	if engine == models.EngineValkey {
+		cli = "valkey-cli"
+	}
+	return append([]string{cli, "--no-auth-warning", "-a", rootPassword}, args...)
+}
+
+// redisExec runs a redis-cli command, turning error replies into errors
+func (s *DatabaseService) redisExec(ctx context.Context, server *models.DatabaseServer, rootPassword string, args ...string) (string, error) {
+	output, err := s.podman.Exec(ctx, server.ContainerID, redisCLI(server.Engine, rootPassword, args...), false)
+	if err != nil {
+		return "", err
+	}
+	reply := strings.TrimSpace(string(output))
+	for _, prefix := range []string{"ERR", "NOAUTH", "WRONGPASS", "NOPERM", "WRONGTYPE"} {
+		if strings.HasPrefix(reply, prefix) {
+			return "", fmt.Errorf("%s", reply)
+		}
+	}
+	return reply, nil
+}
+
+// redisGlobEscape escapes the glob characters of a key prefix
+func redisGlobEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`).Replace(s)
+}
+
+// createRedisUser creates an ACL user that may only use keys and channels
+// prefixed with "<dbName>:"
+func (s *DatabaseService) createRedisUser(ctx context.Context, server *models.DatabaseServer, dbName, username, password, rootPassword string) error {
+	pattern := redisGlobEscape(dbName) + ":*"
+	if _, err := s.redisExec(ctx, server, rootPassword,
+		"ACL", "SETUSER", username, "reset", "on", ">"+password,
+		"~"+pattern, "&"+pattern, "+@all", "-@admin", "-@dangerous"); err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	if _, err := s.redisExec(ctx, server, rootPassword, "ACL", "SAVE"); err != nil {
+		_, _ = s.redisExec(ctx, server, rootPassword, "ACL", "DELUSER", username)
+		return fmt.Errorf("failed to save users: %w", err)
+	}
+	return nil
+}
+
+// dropRedisUser removes the ACL user of a database and the keys under its prefix
+func (s *DatabaseService) dropRedisUser(ctx context.Context, server *models.DatabaseServer, dbName, username, rootPassword string) {
+	_, _ = s.redisExec(ctx, server, rootPassword, "ACL", "DELUSER", username)
+	_, _ = s.redisExec(ctx, server, rootPassword, "ACL", "SAVE")
+	_, _ = s.redisExec(ctx, server, rootPassword, "EVAL",
+		"for _, key in ipairs(redis.call('KEYS', ARGV[1])) do redis.call('DEL', key) end return 0",
+		"0", redisGlobEscape(dbName)+":*")
+}
+
+// jsString quotes a string as a JavaScript literal
+func jsString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// mongosh returns a mongosh command evaluating script as the root user
+func mongosh(rootPassword, script string) []string {
+	return []string{"mongosh", "--quiet", "--norc",
+		"-u", "root", "-p", rootPassword, "--authenticationDatabase", "admin",
+		"--eval", script}
+}
+
+// createMongoDatabase creates a user owning a MongoDB database. The database
+// itself appears with the first collection written to it.
+func (s *DatabaseService) createMongoDatabase(ctx context.Context, containerID, dbName, username, password, rootPassword string) error {
+	script := fmt.Sprintf(`db.getSiblingDB(%s).createUser({user: %s, pwd: %s, roles: [{role: "dbOwner", db: %s}]})`,
+		jsString(dbName), jsString(username), jsString(password), jsString(dbName))
+	if _, err := s.podman.Exec(ctx, containerID, mongosh(rootPassword, script), false); err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+// dropMongoDatabase drops a MongoDB database and its user
+func (s *DatabaseService) dropMongoDatabase(ctx context.Context, containerID, dbName, username, rootPassword string) {
+	script := fmt.Sprintf(`const d = db.getSiblingDB(%s); if (d.getUser(%s)) { d.dropUser(%s) }; d.dropDatabase()`,
+		jsString(dbName), jsString(username), jsString(username))
+	_, _ = s.podman.Exec(ctx, containerID, mongosh(rootPassword, script), false)
+}
+
+// sqlcmd returns a sqlcmd command running query as sa, with extra flags if
+// given. The tools moved from /opt/mssql-tools to /opt/mssql-tools18 in newer
+// images; the newest is used.
+func sqlcmd(rootPassword, query string, flags ...string) []string {
+	cmd := []string{"sh", "-c", `exec "$(ls -d /opt/mssql-tools*/bin/sqlcmd | tail -n 1)" "$@"`, "sqlcmd",
+		"-C", "-S", "localhost", "-U", "sa", "-P", rootPassword, "-b"}
+	cmd = append(cmd, flags...)
+	return append(cmd, "-Q", query)
+}
+
+// mssqlIdent quotes a SQL Server identifier
+func mssqlIdent(s string) string {
+	return "[" + strings.ReplaceAll(s, "]", "]]") + "]"
+}
+
+// mssqlString quotes a SQL Server Unicode string literal
+func mssqlString(s string) string {
+	return "N'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// createMSSQLDatabase creates a SQL Server database with a login owning it
+func (s *DatabaseService) createMSSQLDatabase(ctx context.Context, containerID, dbName, username, password, rootPassword string) error {
+	if _, err := s.podman.Exec(ctx, containerID, sqlcmd(rootPassword,
+		fmt.Sprintf("CREATE DATABASE %s;", mssqlIdent(dbName))), false); err != nil {
+		return fmt.Errorf("failed to create database: %w", err)
+	}
+
+	query := fmt.Sprintf("CREATE LOGIN %[1]s WITH PASSWORD = %[2]s; USE %[3]s; CREATE USER %[1]s FOR LOGIN %[1]s; ALTER ROLE db_owner ADD MEMBER %[1]s;",
+		mssqlIdent(username), mssqlString(password), mssqlIdent(dbName))
+	if _, err := s.podman.Exec(ctx, containerID, sqlcmd(rootPassword, query), false); err != nil {
+		// Clean up on failure
+		s.dropMSSQLDatabase(ctx, containerID, dbName, username, rootPassword)
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+// dropMSSQLDatabase drops a SQL Server database, disconnecting its sessions, and its login
+func (s *DatabaseService) dropMSSQLDatabase(ctx context.Context, containerID, dbName, username, rootPassword string) {
+	query := fmt.Sprintf("IF DB_ID(%[1]s) IS NOT NULL BEGIN ALTER DATABASE %[2]s SET SINGLE_USER WITH ROLLBACK IMMEDIATE; DROP DATABASE %[2]s; END; IF SUSER_ID(%[3]s) IS NOT NULL DROP LOGIN %[4]s;",
+		mssqlString(dbName), mssqlIdent(dbName), mssqlString(username), mssqlIdent(username))
+	_, _ = s.podman.Exec(ctx, containerID, sqlcmd(rootPassword, query), false)
+}
@@ -0,0 +1,248 @@
+package system
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"podmangr-backend/internal/models"
+)
+
+// parseRows splits tab separated query output into rows of at least n fields,
+// skipping blank and short lines
+func parseRows(output string, n int) [][]string {
+	var rows [][]string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < n {
+			continue
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		rows = append(rows, fields)
+	}
+	return rows
+}
+
+// parseInt parses a number from query output, treating anything else as 0
+func parseInt(s string) int64 {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		f, _ := strconv.ParseFloat(s, 64)
+		return int64(f)
+	}
+	return n
+}
+
+// parseRedisClients returns the sessions of the given users from the output
+// of CLIENT LIST
+func parseRedisClients(output string, users map[string]bool) []models.DatabaseSession {
+	sessions := make([]models.DatabaseSession, 0)
+	for _, line := range strings.Split(output, "\n") {
+		fields := make(map[string]string)
+		for _, pair := range strings.Fields(line) {
+			if key, value, ok := strings.Cut(pair, "="); ok {
+				fields[key] = value
+			}
+		}
+		if fields["id"] == "" || !users[fields["user"]] {
+			continue
+		}
+		state := "idle"
+		if fields["idle"] == "0" {
+			state = "active"
+		}
+		sessions = append(sessions, models.DatabaseSession{
+			ID:         fields["id"],
+			Username:   fields["user"],
+			ClientAddr: fields["addr"],
+			State:      state,
+			Query:      fields["cmd"],
+			Seconds:    parseInt(fields["idle"]),
+		})
+	}
+	return sessions
+}
+
+// mssqlRowFlags make sqlcmd print rows without headers, tab separated
+var mssqlRowFlags = []string{"-h", "-1", "-W", "-s", "\t"}
+
+// ListDatabaseSessions returns the client connections to a database
+func (s *DatabaseService) ListDatabaseSessions(ctx context.Context, id string) ([]models.DatabaseSession, error) {
+	db, server, rootPassword, err := s.runningDatabase(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var cmd []string
+	switch server.Engine {
+	case models.EnginePostgreSQL:
+		cmd = psqlCommand(db.Name, `SELECT pid, usename, coalesce(client_addr::text, 'local'), coalesce(state, ''),
+	coalesce(extract(epoch FROM now() - coalesce(query_start, backend_start))::bigint, 0),
+	regexp_replace(coalesce(query, ''), '\s+', ' ', 'g')
+FROM pg_stat_activity
+WHERE datname = current_database() AND pid <> pg_backend_pid()
+ORDER BY backend_start`, "-A", "-t", "-F", "\t")
+	case models.EngineMariaDB, models.EngineMySQL:
+		cmd = mysqlCommand(server.Engine, rootPassword, fmt.Sprintf(`SELECT ID, USER, HOST, COMMAND, TIME, REPLACE(REPLACE(COALESCE(INFO, ''), '\t', ' '), '\n', ' ')
+FROM information_schema.PROCESSLIST WHERE DB = %s AND ID <> CONNECTION_ID() ORDER BY ID`, mysqlString(db.Name)), "-N", "-B", "-r")
+	case models.EngineRedis, models.EngineValkey:
+		users, err := s.ListDatabaseUsers(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		names := make(map[string]bool)
+		for _, user := range users {
+			names[user.Username] = true
+		}
+		output, err := s.redisExec(ctx, server, rootPassword, "CLIENT", "LIST")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+		return parseRedisClients(output, names), nil
+	case models.EngineMongoDB:
+		script := fmt.Sprintf(`print(EJSON.stringify(db.getSiblingDB("admin").aggregate([
+	{$currentOp: {allUsers: true, idleConnections: true}},
+	{$match: {"effectiveUsers.db": %s}}
+]).toArray().map(o => ({
+	id: String(o.opid ?? o.connectionId ?? ""),
+	username: (o.effectiveUsers || []).map(u => u.user).join(","),
+	client_addr: o.client || "",
+	state: o.active ? "active" : "idle",
+	query: o.command ? EJSON.stringify(o.command).slice(0, 2000) : "",
+	seconds: Number(o.secs_running || 0)
+})), {relaxed: true}))`, jsString(db.Name))
+		output, err := s.podman.Exec(ctx, server.ContainerID, mongosh(rootPassword, script), false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+		sessions := make([]models.DatabaseSession, 0)
+		if err := json.Unmarshal(output, &sessions); err != nil {
+			return nil, fmt.Errorf("failed to parse sessions: %w", err)
+		}
+		return sessions, nil
+	case models.EngineMSSQL:
+		cmd = sqlcmd(rootPassword, fmt.Sprintf(`SET NOCOUNT ON;
+SELECT s.session_id, s.login_name, ISNULL(c.client_net_address, ''), s.status,
+	DATEDIFF(SECOND, ISNULL(r.start_time, s.last_request_start_time), GETDATE()),
+	REPLACE(REPLACE(REPLACE(ISNULL(t.text, ''), CHAR(9), ' '), CHAR(10), ' '), CHAR(13), ' ')
+FROM sys.dm_exec_sessions s
+LEFT JOIN sys.dm_exec_connections c ON c.session_id = s.session_id
+LEFT JOIN sys.dm_exec_requests r ON r.session_id = s.session_id
+OUTER APPLY sys.dm_exec_sql_text(r.sql_handle) t
+WHERE s.is_user_process = 1 AND s.database_id = DB_ID(%s) AND s.session_id <> @@SPID
+ORDER BY s.session_id`, mssqlString(db.Name)), mssqlRowFlags...)
+	default:
+		return nil, fmt.Errorf("unsupported engine: %s", server.Engine)
+	}
+
+	output, err := s.podman.Exec(ctx, server.ContainerID, cmd, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	sessions := make([]models.DatabaseSession, 0)
+	for _, row := range parseRows(string(output), 6) {
+		sessions = append(sessions, models.DatabaseSession{
+			ID:         row[0],
+			Username:   row[1],
+			ClientAddr: row[2],
+			State:      row[3],
+			Seconds:    parseInt(row[4]),
+			Query:      strings.Join(row[5:], " "),
+		})
+	}
+	return sessions, nil
+}
+
+// GetDatabaseStats returns the size of a database and of its tables,
+// largest first
+func (s *DatabaseService) GetDatabaseStats(ctx context.Context, id string) (*models.DatabaseStats, error) {
+	db, server, rootPassword, err := s.runningDatabase(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every query returns the database size first, as a table named after the database
+	var cmd []string
+	switch server.Engine {
+	case models.EnginePostgreSQL:
+		cmd = psqlCommand(db.Name, `SELECT current_database(), 0, pg_database_size(current_database()), 0
+UNION ALL (
+	SELECT schemaname || '.' || relname, n_live_tup, pg_total_relation_size(relid), pg_indexes_size(relid)
+	FROM pg_stat_user_tables ORDER BY 3 DESC
+)`, "-A", "-t", "-F", "\t")
+	case models.EngineMariaDB, models.EngineMySQL:
+		cmd = mysqlCommand(server.Engine, rootPassword, fmt.Sprintf(`SELECT %[1]s, 0, COALESCE(SUM(DATA_LENGTH + INDEX_LENGTH), 0), COALESCE(SUM(INDEX_LENGTH), 0)
+FROM information_schema.TABLES WHERE TABLE_SCHEMA = %[1]s
+UNION ALL (
+	SELECT TABLE_NAME, COALESCE(TABLE_ROWS, 0), COALESCE(DATA_LENGTH + INDEX_LENGTH, 0), COALESCE(INDEX_LENGTH, 0)
+	FROM information_schema.TABLES WHERE TABLE_SCHEMA = %[1]s ORDER BY 3 DESC
+)`, mysqlString(db.Name)), "-N", "-B", "-r")
+	case models.EngineMongoDB:
+		script := fmt.Sprintf(`const d = db.getSiblingDB(%s);
+const s = d.stats();
+print(JSON.stringify({
+	database: d.getName(),
+	size_bytes: Number(s.storageSize || 0) + Number(s.indexSize || 0),
+	tables: d.getCollectionNames().map(c => {
+		const cs = d.getCollection(c).stats();
+		return {name: c, rows: Number(cs.count || 0), size_bytes: Number(cs.storageSize || 0) + Number(cs.totalIndexSize || 0), index_bytes: Number(cs.totalIndexSize || 0)};
+	}).sort((a, b) => b.size_bytes - a.size_bytes)
+}))`, jsString(db.Name))
+		output, err := s.podman.Exec(ctx, server.ContainerID, mongosh(rootPassword, script), false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read database size: %w", err)
+		}
+		var stats models.DatabaseStats
+		if err := json.Unmarshal(output, &stats); err != nil {
+			return nil, fmt.Errorf("failed to parse database size: %w", err)
+		}
+		return &stats, nil
+	case models.EngineMSSQL:
+		cmd = sqlcmd(rootPassword, fmt.Sprintf(`SET NOCOUNT ON;
+USE %[1]s;
+SELECT DB_NAME(), 0, (SELECT CAST(SUM(CAST(size AS bigint)) * 8192 AS bigint) FROM sys.database_files), 0;
+SELECT SCHEMA_NAME(t.schema_id) + '.' + t.name,
+	SUM(CASE WHEN p.index_id IN (0, 1) THEN p.row_count ELSE 0 END),
+	SUM(p.reserved_page_count) * 8192,
+	SUM(CASE WHEN p.index_id > 1 THEN p.reserved_page_count ELSE 0 END) * 8192
+FROM sys.tables t JOIN sys.dm_db_partition_stats p ON p.object_id = t.object_id
+GROUP BY t.schema_id, t.name
+ORDER BY 3 DESC`, mssqlIdent(db.Name)), mssqlRowFlags...)
+	default:
+		// Redis and Valkey databases are key prefixes, which have no size of their own
+		return nil, fmt.Errorf("database sizes are not available for %s", EngineConfigs[server.Engine].Name)
+	}
+
+	output, err := s.podman.Exec(ctx, server.ContainerID, cmd, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database size: %w", err)
+	}
+	return parseDatabaseStats(db.Name, string(output)), nil
+}
+
+// parseDatabaseStats reads query output whose first row is the database size
+// and the following rows are table sizes
+func parseDatabaseStats(dbName, output string) *models.DatabaseStats {
+	stats := &models.DatabaseStats{Database: dbName, Tables: make([]models.DatabaseTableSize, 0)}
+	for i, row := range parseRows(output, 4) {
+		if i == 0 {
+			stats.SizeBytes = parseInt(row[2])
+			continue
+		}
+		stats.Tables = append(stats.Tables, models.DatabaseTableSize{
+			Name:       row[0],
+			Rows:       parseInt(row[1]),
+			SizeBytes:  parseInt(row[2]),
+			IndexBytes: parseInt(row[3]),
+		})
+	}
+	return stats
+}
@@ -0,0 +1,429 @@
+package system
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"podmangr-backend/internal/models"
+)
+
+// databaseReadyTimeout bounds how long a recreated database server may take
+// to accept connections before the change is rolled back. SQL Server
+// upgrades its databases on first start, which can take a while.
+const databaseReadyTimeout = 5 * time.Minute
+
+var (
+	// Parameter names are passed on the command line, never through a shell
+	configKeyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]*$`)
+	versionPattern   = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// validateServerConfig checks engine parameters before they reach a container
+func validateServerConfig(engine models.DatabaseEngine, config map[string]string) error {
+	for key, value := range config {
+		if !configKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid parameter name: %q", key)
+		}
+		if value == "" || strings.ContainsAny(value, "\x00\r\n") {
+			return fmt.Errorf("invalid value for %s", key)
+		}
+		// SQL Server is configured through environment variables only
+		if engine == models.EngineMSSQL && !strings.HasPrefix(key, "MSSQL_") {
+			return fmt.Errorf("SQL Server parameters are MSSQL_* environment variables, got %s", key)
+		}
+	}
+	return nil
+}
+
+// serverContainerRequest builds the container of a database server from its
+// record: image, volume, engine parameters and resource limits
+func serverContainerRequest(server *models.DatabaseServer, rootPassword string) *models.CreateContainerRequest {
+	keys := make([]string, 0, len(server.Config))
+	for key := range server.Config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Environment variables, command line and data directory differ by engine
+	env := make(map[string]string)
+	var dataPath string
+	var command []string
+	switch server.Engine {
+	case models.EnginePostgreSQL:
+		env["POSTGRES_PASSWORD"] = rootPassword
+		dataPath = "/var/lib/postgresql/data"
+		if len(keys) > 0 {
+			command = []string{"postgres"}
+		}
+		for _, key := range keys {
+			command = append(command, "-c", key+"="+server.Config[key])
+		}
+	case models.EngineMariaDB, models.EngineMySQL:
+		env["MYSQL_ROOT_PASSWORD"] = rootPassword
+		if server.Engine == models.EngineMariaDB {
+			// Upgrade the system tables when started on a newer version
+			env["MARIADB_AUTO_UPGRADE"] = "1"
+		}
+		dataPath = "/var/lib/mysql"
+		// The entrypoint passes options to mysqld
+		for _, key := range keys {
+			command = append(command, "--"+key+"="+server.Config[key])
+		}
+	case models.EngineRedis, models.EngineValkey:
+		env["REDIS_PASSWORD"] = rootPassword
+		dataPath = "/data"
+		var args []string
+		for _, key := range keys {
+			args = append(args, "--"+key, server.Config[key])
+		}
+		command = redisServerCommand(server.Engine, args...)
+	case models.EngineMongoDB:
+		env["MONGO_INITDB_ROOT_USERNAME"] = "root"
+		env["MONGO_INITDB_ROOT_PASSWORD"] = rootPassword
+		dataPath = "/data/db"
+		// The entrypoint passes options to mongod
+		for _, key := range keys {
+			command = append(command, "--"+key, server.Config[key])
+		}
+	case models.EngineMSSQL:
+		env["ACCEPT_EULA"] = "Y"
+		env["MSSQL_SA_PASSWORD"] = rootPassword
+		dataPath = "/var/opt/mssql"
+		for _, key := range keys {
+			env[key] = server.Config[key]
+		}
+	}
+
+	return &models.CreateContainerRequest{
+		Name:        fmt.Sprintf("podmangr-db-%s", server.Name),
+		Image:       server.Image,
+		Command:     command,
+		NetworkMode: server.Network,
+		Environment: env,
+		Volumes: []models.VolumeMount{
+			{
+				Source: server.VolumeName,
+				Target: dataPath,
+				Type:   "volume",
+			},
+		},
+		Labels: map[string]string{
+			"podmangr.managed":   "true",
+			"podmangr.db-server": "true",
+			"podmangr.db-engine": string(server.Engine),
+			"podmangr.db-name":   server.Name,
+		},
+		RestartPolicy: "unless-stopped",
+		CPULimit:      server.CPULimit,
+		MemoryLimit:   server.MemoryLimit,
+	}
+}
+
+// versionParts returns the leading numeric components of a version tag,
+// e.g. [10 11] for "10.11-jammy" and [2022] for "2022-latest"
+func versionParts(version string) []int {
+	var parts []int
+	for _, field := range strings.Split(version, ".") {
+		end := 0
+		for end < len(field) && field[end] >= '0' && field[end] <= '9' {
+			end++
+		}
+		n, err := strconv.Atoi(field[:end])
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+		if end < len(field) {
+			break
+		}
+	}
+	return parts
+}
+
+// compareVersions orders two version tags by their numeric components
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(pa) - len(pb)
+}
+
+// upgradeStrategy picks how a server moves between versions. PostgreSQL
+// data directories are tied to a major version, so those upgrades go through
+// a dump; every other engine upgrades its data files when started on the new
+// version.
+func upgradeStrategy(engine models.DatabaseEngine, from, to string) string {
+	if engine == models.EnginePostgreSQL {
+		pf, pt := versionParts(from), versionParts(to)
+		if len(pf) == 0 || len(pt) == 0 || pf[0] != pt[0] {
+			return models.UpgradeStrategyDumpRestore
+		}
+	}
+	return models.UpgradeStrategyInPlace
+}
+
+// imageWithTag replaces the tag of an image reference
+func imageWithTag(image, tag string) string {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image + ":" + tag
+}
+
+// replaceContainer swaps the container of a server for one built from next,
+// the server's record with the intended changes. The old container is renamed
+// aside until the new one accepts connections and afterStart, if set,
+// succeeded; otherwise the new container is removed, the volumes are restored
+// from backup if given, and the old container is brought back. The server is
+// left running if wasRunning is set. On success next is saved with its new
+// container ID.
+func (s *DatabaseService) replaceContainer(ctx context.Context, server, next *models.DatabaseServer, wasRunning bool, backup *models.ContainerBackup, afterStart func() error) error {
+	rootPassword, err := s.encryption.Decrypt(server.RootPasswordEncrypted)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt server credentials: %w", err)
+	}
+
+	// Pull before stopping anything to keep the downtime short
+	if !s.podman.ImageExists(ctx, next.Image) {
+		if err := s.podman.PullImage(ctx, next.Image); err != nil {
+			return fmt.Errorf("failed to pull %s: %w", next.Image, err)
+		}
+	}
+
+	_ = s.serverRepo.UpdateStatus(server.ID, models.DatabaseServerStatusStopping)
+	_ = s.podman.StopContainer(ctx, server.ContainerID, 30)
+
+	containerName := fmt.Sprintf("podmangr-db-%s", server.Name)
+	previousName := fmt.Sprintf("%s_previous_%s", containerName, time.Now().Format("20060102_150405"))
+	if err := s.podman.RenameContainer(ctx, server.ContainerID, previousName); err != nil {
+		if wasRunning {
+			_ = s.podman.StartContainer(ctx, server.ContainerID)
+		}
+		s.syncServerStatus(ctx, server)
+		return fmt.Errorf("failed to rename container: %w", err)
+	}
+
+	rollback := func(reason error, newContainerID string) error {
+		if newContainerID != "" {
+			_ = s.podman.RemoveContainer(ctx, newContainerID, true)
+			if backup != nil {
+				if err := s.podman.RestoreMounts(ctx, backup, nil); err != nil {
+					log.Printf("Failed to restore volumes of database server %s from backup %s: %v", server.Name, backup.ID, err)
+				}
+			}
+		}
+		if err := s.podman.RenameContainer(ctx, previousName, containerName); err != nil {
+			log.Printf("Failed to rename database server container %s back: %v", previousName, err)
+		}
+		if wasRunning {
+			_ = s.podman.StartContainer(ctx, server.ContainerID)
+		}
+		_ = s.serverRepo.UpdateStatus(server.ID, models.DatabaseServerStatusStopped)
+		s.syncServerStatus(ctx, server)
+		return fmt.Errorf("%w (previous container restored)", reason)
+	}
+
+	newContainerID, err := s.podman.CreateContainer(ctx, serverContainerRequest(next, rootPassword))
+	if err != nil {
+		return rollback(fmt.Errorf("failed to create container: %w", err), "")
+	}
+	next.ContainerID = newContainerID
+
+	if err := s.podman.StartContainer(ctx, newContainerID); err != nil {
+		return rollback(fmt.Errorf("failed to start container: %w", err), newContainerID)
+	}
+	if err := s.waitReady(ctx, next, databaseReadyTimeout); err != nil {
+		return rollback(fmt.Errorf("server did not become ready: %w", err), newContainerID)
+	}
+	next.Status = models.DatabaseServerStatusRunning
+	if afterStart != nil {
+		if err := afterStart(); err != nil {
+			return rollback(err, newContainerID)
+		}
+	}
+
+	if !wasRunning {
+		if err := s.podman.StopContainer(ctx, newContainerID, 30); err == nil {
+			next.Status = models.DatabaseServerStatusStopped
+		}
+	}
+	if err := s.serverRepo.Update(next); err != nil {
+		return rollback(fmt.Errorf("failed to save server record: %w", err), newContainerID)
+	}
+	if err := s.podman.RemoveContainer(ctx, previousName, true); err != nil {
+		log.Printf("Failed to remove previous container %s of database server %s: %v", previousName, server.Name, err)
+	}
+	return nil
+}
+
+// UpdateServer changes the engine parameters and resource limits of a
+// server by recreating its container on the same volume. A change the
+// server does not start with is rolled back.
+func (s *DatabaseService) UpdateServer(ctx context.Context, id string, req *models.UpdateDatabaseServerRequest) (*models.DatabaseServer, error) {
+	server, err := s.serverRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	next := *server
+	if req.Config != nil {
+		if err := validateServerConfig(server.Engine, req.Config); err != nil {
+			return nil, err
+		}
+		next.Config = req.Config
+	}
+	if req.MemoryLimit != nil {
+		if *req.MemoryLimit < 0 {
+			return nil, fmt.Errorf("memory_limit can't be negative")
+		}
+		next.MemoryLimit = *req.MemoryLimit
+	}
+	if req.CPULimit != nil {
+		if *req.CPULimit < 0 {
+			return nil, fmt.Errorf("cpu_limit can't be negative")
+		}
+		next.CPULimit = *req.CPULimit
+	}
+
+	s.syncServerStatus(ctx, server)
+	wasRunning := server.Status == models.DatabaseServerStatusRunning
+	if err := s.replaceContainer(ctx, server, &next, wasRunning, nil, nil); err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+// UpgradeServer moves a running server to another version of its engine.
+// A backup is taken first: a dump for dump-restore upgrades, or a copy of
+// the stopped data volume for in-place upgrades, which is restored if the
+// new version fails to start. After a dump-restore upgrade the old volume is
+// kept until the administrator removes it.
+func (s *DatabaseService) UpgradeServer(ctx context.Context, id string, req *models.UpgradeDatabaseServerRequest, backupBasePath string) (*models.UpgradeDatabaseServerResponse, error) {
+	server, err := s.serverRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !versionPattern.MatchString(req.Version) {
+		return nil, fmt.Errorf("invalid version: %q", req.Version)
+	}
+	if req.Version == server.Version {
+		return nil, fmt.Errorf("server already runs version %s", server.Version)
+	}
+	s.syncServerStatus(ctx, server)
+	if server.Status != models.DatabaseServerStatusRunning {
+		return nil, fmt.Errorf("server must be running to upgrade (current status: %s)", server.Status)
+	}
+
+	strategy := upgradeStrategy(server.Engine, server.Version, req.Version)
+	if strategy == models.UpgradeStrategyInPlace && compareVersions(req.Version, server.Version) < 0 {
+		return nil, fmt.Errorf("%s can't be downgraded from %s to %s", EngineConfigs[server.Engine].Name, server.Version, req.Version)
+	}
+
+	next := *server
+	next.Version = req.Version
+	next.Image = imageWithTag(server.Image, req.Version)
+	if err := s.podman.PullImage(ctx, next.Image); err != nil {
+		return nil, fmt.Errorf("failed to pull %s: %w", next.Image, err)
+	}
+
+	result := &models.UpgradeDatabaseServerResponse{
+		FromVersion: server.Version,
+		Strategy:    strategy,
+	}
+	metadata := map[string]string{"upgrade_to": req.Version}
+
+	if strategy == models.UpgradeStrategyDumpRestore {
+		backup, err := s.dump(ctx, server, "", backupBasePath, metadata)
+		if err != nil {
+			return nil, fmt.Errorf("pre-upgrade backup failed: %w", err)
+		}
+		result.BackupID = backup.ID
+
+		next.VolumeName = fmt.Sprintf("podmangr-db-%s-data-%s", server.Name, backupNamePart(req.Version))
+		if next.VolumeName == server.VolumeName {
+			next.VolumeName += time.Now().Format("-20060102150405")
+		}
+		if err := s.podman.CreateVolume(ctx, &models.CreateVolumeRequest{
+			Name: next.VolumeName,
+			Labels: map[string]string{
+				"podmangr.managed":   "true",
+				"podmangr.db-server": server.Name,
+			},
+		}); err != nil {
+			return nil, fmt.Errorf("failed to create volume: %w", err)
+		}
+
+		restore := func() error {
+			if err := s.restoreDump(ctx, &next, backup, "", ""); err != nil {
+				return fmt.Errorf("failed to restore pre-upgrade backup %s: %w", backup.ID, err)
+			}
+			return nil
+		}
+		if err := s.replaceContainer(ctx, server, &next, true, nil, restore); err != nil {
+			_ = s.podman.RemoveVolume(ctx, next.VolumeName, true)
+			return nil, err
+		}
+		result.PreviousVolume = server.VolumeName
+	} else {
+		// Copy the volume while the server is stopped, so the copy is consistent
+		if err := s.podman.StopContainer(ctx, server.ContainerID, 30); err != nil {
+			return nil, fmt.Errorf("failed to stop server: %w", err)
+		}
+		backup, err := s.podman.CreateBackup(ctx, server.ContainerID, backupBasePath, metadata, nil)
+		if err != nil {
+			_ = s.podman.StartContainer(ctx, server.ContainerID)
+			return nil, fmt.Errorf("pre-upgrade backup failed: %w", err)
+		}
+		result.BackupID = backup.ID
+
+		var afterStart func() error
+		if server.Engine == models.EngineMongoDB {
+			afterStart = func() error { return s.setMongoFeatureCompatibility(ctx, &next) }
+		}
+		if err := s.replaceContainer(ctx, server, &next, true, backup, afterStart); err != nil {
+			return nil, fmt.Errorf("%w; pre-upgrade backup: %s", err, backup.ID)
+		}
+	}
+
+	result.Server = &next
+	return result, nil
+}
+
+// setMongoFeatureCompatibility enables the features of the MongoDB version a
+// server was upgraded to, as MongoDB keeps those of the previous version
+func (s *DatabaseService) setMongoFeatureCompatibility(ctx context.Context, server *models.DatabaseServer) error {
+	parts := versionParts(server.Version)
+	if len(parts) == 0 {
+		return nil
+	}
+	minor := 0
+	if len(parts) > 1 {
+		minor = parts[1]
+	}
+	rootPassword, err := s.encryption.Decrypt(server.RootPasswordEncrypted)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt server credentials: %w", err)
+	}
+
+	command := fmt.Sprintf(`{setFeatureCompatibilityVersion: "%d.%d"}`, parts[0], minor)
+	if parts[0] >= 7 {
+		command = fmt.Sprintf(`{setFeatureCompatibilityVersion: "%d.%d", confirm: true}`, parts[0], minor)
+	}
+	script := fmt.Sprintf(`const r = db.adminCommand(%s); if (!r.ok) { throw new Error(r.errmsg) }`, command)
+	if _, err := s.podman.Exec(ctx, server.ContainerID, mongosh(rootPassword, script), false); err != nil {
+		return fmt.Errorf("failed to set feature compatibility version: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package system
+
+import (
+	"reflect"
+	"testing"
+
+	"podmangr-backend/internal/models"
+)
+
+func TestUpgradeStrategy(t *testing.T) {
+	tests := []struct {
+		engine   models.DatabaseEngine
+		from, to string
+		want     string
+		cmp      int
+	}{
+		{models.EnginePostgreSQL, "16", "17", models.UpgradeStrategyDumpRestore, 1},
+		{models.EnginePostgreSQL, "16.2", "16.4-alpine", models.UpgradeStrategyInPlace, 1},
+		{models.EngineMariaDB, "10.11", "11", models.UpgradeStrategyInPlace, 1},
+		{models.EngineMariaDB, "10.6", "10.11", models.UpgradeStrategyInPlace, 1},
+		{models.EngineMySQL, "8.4", "8.0", models.UpgradeStrategyInPlace, -1},
+		{models.EngineMSSQL, "2019-latest", "2022-latest", models.UpgradeStrategyInPlace, 1},
+	}
+	for _, tt := range tests {
+		if got := upgradeStrategy(tt.engine, tt.from, tt.to); got != tt.want {
+			t.Errorf("upgradeStrategy(%s, %s, %s) = %s, want %s", tt.engine, tt.from, tt.to, got, tt.want)
+		}
+		if got := compareVersions(tt.to, tt.from); (got > 0) != (tt.cmp > 0) || (got < 0) != (tt.cmp < 0) {
+			t.Errorf("compareVersions(%s, %s) = %d, want sign of %d", tt.to, tt.from, got, tt.cmp)
+		}
+	}
+
+	if got := imageWithTag("localhost:5000/postgres:16", "17"); got != "localhost:5000/postgres:17" {
+		t.Errorf("imageWithTag = %s", got)
+	}
+	if got := imageWithTag("localhost:5000/postgres", "17"); got != "localhost:5000/postgres:17" {
+		t.Errorf("imageWithTag without tag = %s", got)
+	}
+}
+
+func TestServerContainerRequest(t *testing.T) {
+	server := &models.DatabaseServer{
+		Name:        "main",
+		Engine:      models.EnginePostgreSQL,
+		Image:       "docker.io/postgres:17",
+		Network:     DefaultDatabaseNetwork,
+		VolumeName:  "podmangr-db-main-data",
+		Config:      map[string]string{"shared_buffers": "1GB", "max_connections": "200"},
+		MemoryLimit: 2 << 30,
+		CPULimit:    1.5,
+	}
+	req := serverContainerRequest(server, "secret")
+
+	want := []string{"postgres", "-c", "max_connections=200", "-c", "shared_buffers=1GB"}
+	if !reflect.DeepEqual(req.Command, want) {
+		t.Errorf("command = %q, want %q", req.Command, want)
+	}
+	if req.Name != "podmangr-db-main" || req.MemoryLimit != 2<<30 || req.CPULimit != 1.5 {
+		t.Errorf("unexpected container request: %+v", req)
+	}
+	if len(req.Volumes) != 1 || req.Volumes[0].Source != server.VolumeName || req.Volumes[0].Target != "/var/lib/postgresql/data" {
+		t.Errorf("volumes = %+v", req.Volumes)
+	}
+
+	server.Engine = models.EngineMySQL
+	server.Config = nil
+	if req := serverContainerRequest(server, "secret"); len(req.Command) != 0 {
+		t.Errorf("command without parameters = %q, want image default", req.Command)
+	}
+}
+
+func TestValidateServerConfig(t *testing.T) {
+	valid := map[string]string{"shared_buffers": "256MB", "maxmemory-policy": "allkeys-lru"}
+	if err := validateServerConfig(models.EnginePostgreSQL, valid); err != nil {
+		t.Errorf("valid config rejected: %v", err)
+	}
+	for _, config := range []map[string]string{
+		{"--datadir": "/tmp"},
+		{"a b": "1"},
+		{"work_mem": ""},
+		{"work_mem": "1MB\nlisten_addresses=*"},
+	} {
+		if err := validateServerConfig(models.EnginePostgreSQL, config); err == nil {
+			t.Errorf("config %q accepted", config)
+		}
+	}
+	if err := validateServerConfig(models.EngineMSSQL, map[string]string{"PATH": "/tmp"}); err == nil {
+		t.Error("non-MSSQL environment variable accepted for SQL Server")
+	}
+}
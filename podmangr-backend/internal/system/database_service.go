@@ -23,6 +23,7 @@ var EngineConfigs = map[models.DatabaseEngine]models.EngineConfig{
 		DefaultImage: "docker.io/postgres",
 		DefaultPort:  5432,
 		Versions:     []string{"17", "16", "15", "14", "13"},
+		Parameters:   []string{"shared_buffers", "max_connections", "work_mem", "effective_cache_size", "maintenance_work_mem"},
 	},
 	models.EngineMariaDB: {
 		Engine:       models.EngineMariaDB,
@@ -30,6 +31,7 @@ var EngineConfigs = map[models.DatabaseEngine]models.EngineConfig{
 		DefaultImage: "docker.io/mariadb",
 		DefaultPort:  3306,
 		Versions:     []string{"11", "10.11", "10.6", "10.5"},
+		Parameters:   []string{"innodb_buffer_pool_size", "max_connections", "innodb_log_file_size", "max_allowed_packet"},
 	},
 	models.EngineMySQL: {
 		Engine:       models.EngineMySQL,
@@ -37,6 +39,39 @@ var EngineConfigs = map[models.DatabaseEngine]models.EngineConfig{
 		DefaultImage: "docker.io/mysql",
 		DefaultPort:  3306,
 		Versions:     []string{"9.0", "8.4", "8.0"},
+		Parameters:   []string{"innodb_buffer_pool_size", "max_connections", "innodb_redo_log_capacity", "max_allowed_packet"},
+	},
+	models.EngineRedis: {
+		Engine:       models.EngineRedis,
+		Name:         "Redis",
+		DefaultImage: "docker.io/redis",
+		DefaultPort:  6379,
+		Versions:     []string{"7.4", "7.2", "6.2"},
+		Parameters:   []string{"maxmemory", "maxmemory-policy", "save"},
+	},
+	models.EngineValkey: {
+		Engine:       models.EngineValkey,
+		Name:         "Valkey",
+		DefaultImage: "docker.io/valkey/valkey",
+		DefaultPort:  6379,
+		Versions:     []string{"8.0", "7.2"},
+		Parameters:   []string{"maxmemory", "maxmemory-policy", "save"},
+	},
+	models.EngineMongoDB: {
+		Engine:       models.EngineMongoDB,
+		Name:         "MongoDB",
+		DefaultImage: "docker.io/mongo",
+		DefaultPort:  27017,
+		Versions:     []string{"8.0", "7.0", "6.0"},
+		Parameters:   []string{"wiredTigerCacheSizeGB", "maxConns"},
+	},
+	models.EngineMSSQL: {
+		Engine:       models.EngineMSSQL,
+		Name:         "SQL Server",
+		DefaultImage: "mcr.microsoft.com/mssql/server",
+		DefaultPort:  1433,
+		Versions:     []string{"2022-latest", "2019-latest"},
+		Parameters:   []string{"MSSQL_MEMORY_LIMIT_MB", "MSSQL_COLLATION", "MSSQL_PID"},
 	},
 }
 
@@ -45,6 +80,7 @@ type DatabaseService struct {
 	podman      *PodmanService
 	serverRepo  *database.DatabaseServerRepo
 	dbRepo      *database.DatabaseRepo
+	userRepo    *database.DatabaseUserRepo
 	secretsRepo *database.SecretsRepo
 	encryption  *auth.EncryptionService
 }
@@ -60,6 +96,7 @@ func NewDatabaseService() (*DatabaseService, error) {
 		podman:      NewPodmanService(),
 		serverRepo:  database.NewDatabaseServerRepo(),
 		dbRepo:      database.NewDatabaseRepo(),
+		userRepo:    database.NewDatabaseUserRepo(),
 		secretsRepo: database.NewSecretsRepo(),
 		encryption:  encryption,
 	}, nil
@@ -112,6 +149,13 @@ func (s *DatabaseService) CreateServer(ctx context.Context, req *models.CreateDa
 		return nil, "", fmt.Errorf("unsupported database engine: %s", req.Engine)
 	}
 
+	if err := validateServerConfig(req.Engine, req.Config); err != nil {
+		return nil, "", err
+	}
+	if req.MemoryLimit < 0 || req.CPULimit < 0 {
+		return nil, "", fmt.Errorf("resource limits can't be negative")
+	}
+
 	// Use defaults if not specified
 	version := req.Version
 	if version == "" && len(engineCfg.Versions) > 0 {
@@ -158,63 +202,32 @@ func (s *DatabaseService) CreateServer(ctx context.Context, req *models.CreateDa
 	}
 
 	// Build container configuration
-	image := fmt.Sprintf("%s:%s", engineCfg.DefaultImage, version)
-	containerName := fmt.Sprintf("podmangr-db-%s", req.Name)
-
-	// Environment variables differ by engine
-	env := make(map[string]string)
-	var dataPath string
-	switch req.Engine {
-	case models.EnginePostgreSQL:
-		env["POSTGRES_PASSWORD"] = rootPassword
-		dataPath = "/var/lib/postgresql/data"
-	case models.EngineMariaDB, models.EngineMySQL:
-		env["MYSQL_ROOT_PASSWORD"] = rootPassword
-		dataPath = "/var/lib/mysql"
-	}
-
-	// Create the container
-	containerID, err := s.podman.CreateContainer(ctx, &models.CreateContainerRequest{
-		Name:        containerName,
-		Image:       image,
-		NetworkMode: network,
-		Environment: env,
-		Volumes: []models.VolumeMount{
-			{
-				Source: volumeName,
-				Target: dataPath,
-				Type:   "volume",
-			},
-		},
-		Labels: map[string]string{
-			"podmangr.managed":   "true",
-			"podmangr.db-server": "true",
-			"podmangr.db-engine": string(req.Engine),
-			"podmangr.db-name":   req.Name,
-		},
-		RestartPolicy: "unless-stopped",
-	})
-	if err != nil {
-		// Clean up volume on failure
-		_ = s.podman.RemoveVolume(ctx, volumeName, true)
-		return nil, "", fmt.Errorf("failed to create container: %w", err)
-	}
-
-	// Create database record
 	server := &models.DatabaseServer{
-		ContainerID:           containerID,
 		Name:                  req.Name,
 		Engine:                req.Engine,
 		Version:               version,
-		Image:                 image,
+		Image:                 fmt.Sprintf("%s:%s", engineCfg.DefaultImage, version),
 		Network:               network,
 		Status:                models.DatabaseServerStatusStopped,
 		RootPasswordEncrypted: encryptedPassword,
 		VolumeName:            volumeName,
 		InternalPort:          engineCfg.DefaultPort,
+		Config:                req.Config,
+		MemoryLimit:           req.MemoryLimit,
+		CPULimit:              req.CPULimit,
 		CreatedBy:             userID,
 	}
 
+	// Create the container
+	containerID, err := s.podman.CreateContainer(ctx, serverContainerRequest(server, rootPassword))
+	if err != nil {
+		// Clean up volume on failure
+		_ = s.podman.RemoveVolume(ctx, volumeName, true)
+		return nil, "", fmt.Errorf("failed to create container: %w", err)
+	}
+	server.ContainerID = containerID
+
+	// Create database record
 	if err := s.serverRepo.Create(server); err != nil {
 		// Clean up container and volume on failure
 		_ = s.podman.RemoveContainer(ctx, containerID, true)
@@ -496,13 +509,14 @@ func (s *DatabaseService) CreateDatabase(ctx context.Context, serverID string, r
 	}
 
 	// Save credentials to secrets store for easy retrieval
-	s.saveDatabaseSecret(server, db, password, userID)
+	s.saveDatabaseSecret(server, db, db.Username, password, userID)
 
 	return db, password, nil
 }
 
-// saveDatabaseSecret saves the database credentials to the secrets store
-func (s *DatabaseService) saveDatabaseSecret(server *models.DatabaseServer, db *models.Database, password string, userID *int64) {
+// saveDatabaseSecret saves the credentials of a database user to the secrets
+// store, replacing the value of the user's earlier secret if there is one
+func (s *DatabaseService) saveDatabaseSecret(server *models.DatabaseServer, db *models.Database, username, password string, userID *int64) {
 	// Encrypt the password for secrets store
 	encrypted, err := s.encryption.Encrypt(password)
 	if err != nil {
@@ -512,25 +526,29 @@ func (s *DatabaseService) saveDatabaseSecret(server *models.DatabaseServer, db *
 	containerName := fmt.Sprintf("podmangr-db-%s", server.Name)
 	category := "database"
 	description := fmt.Sprintf("Credentials for database '%s' on %s", db.Name, server.Name)
+	name := fmt.Sprintf("%s/%s - Credentials", server.Name, db.Name)
+	if username != db.Username {
+		description = fmt.Sprintf("Credentials of user '%s' for database '%s' on %s", username, db.Name, server.Name)
+		name = fmt.Sprintf("%s/%s (%s) - Credentials", server.Name, db.Name, username)
+	}
 
 	// Build connection string based on engine
-	var connStr string
-	switch server.Engine {
-	case models.EnginePostgreSQL:
-		connStr = fmt.Sprintf("postgresql://%s:%s@%s:%d/%s",
-			db.Username, password, containerName, server.InternalPort, db.Name)
-	case models.EngineMariaDB, models.EngineMySQL:
-		connStr = fmt.Sprintf("mysql://%s:%s@%s:%d/%s",
-			db.Username, password, containerName, server.InternalPort, db.Name)
-	}
+	connStr := connectionString(server.Engine, username, password, containerName, server.InternalPort, db.Name)
 
 	metadata := fmt.Sprintf(`{"engine":"%s","server":"%s","database":"%s","username":"%s","host":"%s","port":%d,"connection_string":"%s"}`,
-		server.Engine, server.Name, db.Name, db.Username, containerName, server.InternalPort, connStr)
+		server.Engine, server.Name, db.Name, username, containerName, server.InternalPort, connStr)
+
+	if existing := s.findSecret(containerName, name); existing != nil {
+		existing.ValueEncrypted = encrypted
+		existing.Metadata = &metadata
+		_ = s.secretsRepo.Update(existing)
+		return
+	}
 
 	secret := &models.Secret{
 		ContainerID:    &server.ContainerID,
 		ContainerName:  &containerName,
-		Name:           fmt.Sprintf("%s/%s - Credentials", server.Name, db.Name),
+		Name:           name,
 		ValueEncrypted: encrypted,
 		SecretType:     models.SecretTypeConnectionString,
 		Category:       &category,
@@ -542,6 +560,22 @@ func (s *DatabaseService) saveDatabaseSecret(server *models.DatabaseServer, db *
 	_ = s.secretsRepo.Create(secret)
 }
 
+// findSecret returns the secret of a container with the given name, if any
+func (s *DatabaseService) findSecret(containerName, name string) *models.Secret {
+	secrets, err := s.secretsRepo.ListByContainerName(containerName)
+	if err != nil {
+		return nil
+	}
+	for _, item := range secrets {
+		if item.Name == name {
+			if secret, err := s.secretsRepo.GetByID(item.ID); err == nil {
+				return secret
+			}
+		}
+	}
+	return nil
+}
+
 // createDatabaseSQL executes SQL to create a database and user
 func (s *DatabaseService) createDatabaseSQL(ctx context.Context, server *models.DatabaseServer, dbName, username, password, rootPassword string) error {
 	switch server.Engine {
@@ -549,6 +583,12 @@ func (s *DatabaseService) createDatabaseSQL(ctx context.Context, server *models.
 		return s.createPostgreSQLDatabase(ctx, server.ContainerID, dbName, username, password)
 	case models.EngineMariaDB, models.EngineMySQL:
 		return s.createMySQLDatabase(ctx, server.ContainerID, dbName, username, password, rootPassword)
+	case models.EngineRedis, models.EngineValkey:
+		return s.createRedisUser(ctx, server, dbName, username, password, rootPassword)
+	case models.EngineMongoDB:
+		return s.createMongoDatabase(ctx, server.ContainerID, dbName, username, password, rootPassword)
+	case models.EngineMSSQL:
+		return s.createMSSQLDatabase(ctx, server.ContainerID, dbName, username, password, rootPassword)
 	default:
 		return fmt.Errorf("unsupported engine: %s", server.Engine)
 	}
@@ -663,6 +703,12 @@ func (s *DatabaseService) dropDatabaseSQL(ctx context.Context, server *models.Da
 			"mysql", "-u", "root", fmt.Sprintf("-p%s", rootPassword), "-e",
 			fmt.Sprintf("DROP DATABASE IF EXISTS `%s`;", dbName),
 		}, false)
+	case models.EngineRedis, models.EngineValkey:
+		s.dropRedisUser(ctx, server, dbName, username, rootPassword)
+	case models.EngineMongoDB:
+		s.dropMongoDatabase(ctx, server.ContainerID, dbName, username, rootPassword)
+	case models.EngineMSSQL:
+		s.dropMSSQLDatabase(ctx, server.ContainerID, dbName, username, rootPassword)
 	}
 	return nil
 }
@@ -698,6 +744,7 @@ func (s *DatabaseService) DeleteDatabase(ctx context.Context, id string) error {
 	if server.Status == models.DatabaseServerStatusRunning {
 		rootPassword, err := s.encryption.Decrypt(server.RootPasswordEncrypted)
 		if err == nil {
+			s.dropAdditionalUsers(ctx, server, db, rootPassword)
 			_ = s.dropDatabaseSQL(ctx, server, db.Name, db.Username, rootPassword)
 		}
 	}
@@ -731,15 +778,7 @@ func (s *DatabaseService) GetConnectionString(ctx context.Context, id string) (*
 	containerName := fmt.Sprintf("podmangr-db-%s", server.Name)
 	port := server.InternalPort
 
-	var connStr string
-	switch server.Engine {
-	case models.EnginePostgreSQL:
-		connStr = fmt.Sprintf("postgresql://%s:%s@%s:%d/%s",
-			db.Username, password, containerName, port, db.Name)
-	case models.EngineMariaDB, models.EngineMySQL:
-		connStr = fmt.Sprintf("mysql://%s:%s@%s:%d/%s",
-			db.Username, password, containerName, port, db.Name)
-	}
+	connStr := connectionString(server.Engine, db.Username, password, containerName, port, db.Name)
 
 	return &models.ConnectionStringResponse{
 		ConnectionString: connStr,
@@ -757,21 +796,22 @@ func (s *DatabaseService) WaitForServerReady(ctx context.Context, serverID strin
 	if err != nil {
 		return err
 	}
+	return s.waitReady(ctx, server, timeout)
+}
+
+// waitReady polls the readiness check of a server's engine in its container
+func (s *DatabaseService) waitReady(ctx context.Context, server *models.DatabaseServer, timeout time.Duration) error {
+	rootPassword, _ := s.encryption.Decrypt(server.RootPasswordEncrypted)
+	checkCmd, expected := readinessCheck(server.Engine, rootPassword)
+	if checkCmd == nil {
+		return fmt.Errorf("unsupported engine: %s", server.Engine)
+	}
 
 	deadline := time.Now().Add(timeout)
 	for time.Now().Before(deadline) {
 		// Try to execute a simple command to check if the database is ready
-		var checkCmd []string
-		switch server.Engine {
-		case models.EnginePostgreSQL:
-			checkCmd = []string{"pg_isready", "-U", "postgres"}
-		case models.EngineMariaDB, models.EngineMySQL:
-			rootPassword, _ := s.encryption.Decrypt(server.RootPasswordEncrypted)
-			checkCmd = []string{"mysqladmin", "-u", "root", fmt.Sprintf("-p%s", rootPassword), "ping"}
-		}
-
-		_, err := s.podman.Exec(ctx, server.ContainerID, checkCmd, false)
-		if err == nil {
+		output, err := s.podman.Exec(ctx, server.ContainerID, checkCmd, false)
+		if err == nil && strings.Contains(string(output), expected) {
 			return nil
 		}
 
@@ -0,0 +1,429 @@
+package system
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"podmangr-backend/internal/auth"
+	"podmangr-backend/internal/models"
+)
+
+// databaseUsernamePattern keeps usernames valid on every engine, including
+// Redis ACL names, which can't contain spaces
+var databaseUsernamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]{0,62}$`)
+
+// pgIdent quotes a PostgreSQL identifier
+func pgIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// sqlString quotes a standard SQL string literal
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// mysqlIdent quotes a MySQL identifier
+func mysqlIdent(s string) string {
+	return "`" + strings.ReplaceAll(s, "`", "``") + "`"
+}
+
+// mysqlString quotes a MySQL string literal, where backslashes escape too
+func mysqlString(s string) string {
+	return sqlString(strings.ReplaceAll(s, `\`, `\\`))
+}
+
+// psqlCommand returns a psql command running query in dbName as postgres
+func psqlCommand(dbName, query string, flags ...string) []string {
+	cmd := append([]string{"psql", "-U", "postgres", "-d", dbName, "-X", "-v", "ON_ERROR_STOP=1"}, flags...)
+	return append(cmd, "-c", query)
+}
+
+// mysqlCommand returns a mysql (or mariadb) client command running query as root
+func mysqlCommand(engine models.DatabaseEngine, rootPassword, query string, flags ...string) []string {
+	client := "mysql"
+	if engine == models.EngineMariaDB {
+		client = "mariadb"
+	}
+	cmd := append([]string{client, "-u", "root", fmt.Sprintf("-p%s", rootPassword)}, flags...)
+	return append(cmd, "-e", query)
+}
+
+// postgresGrantSQL grants a role on a database to a PostgreSQL user. Owners
+// become members of the database owner's role; the others get privileges on
+// the public schema, including on tables the owner creates later.
+func postgresGrantSQL(owner, username string, role models.DatabaseUserRole) string {
+	u, o := pgIdent(username), pgIdent(owner)
+	if role == models.DatabaseRoleOwner {
+		return fmt.Sprintf("GRANT %s TO %s;", o, u)
+	}
+	tables, sequences := "SELECT", "SELECT"
+	if role == models.DatabaseRoleReadWrite {
+		tables, sequences = "SELECT, INSERT, UPDATE, DELETE", "USAGE, SELECT"
+	}
+	return fmt.Sprintf(`GRANT USAGE ON SCHEMA public TO %[1]s;
+GRANT %[3]s ON ALL TABLES IN SCHEMA public TO %[1]s;
+GRANT %[4]s ON ALL SEQUENCES IN SCHEMA public TO %[1]s;
+ALTER DEFAULT PRIVILEGES FOR ROLE %[2]s IN SCHEMA public GRANT %[3]s ON TABLES TO %[1]s;
+ALTER DEFAULT PRIVILEGES FOR ROLE %[2]s IN SCHEMA public GRANT %[4]s ON SEQUENCES TO %[1]s;`, u, o, tables, sequences)
+}
+
+// mysqlGrantSQL grants a role on a database to a MySQL user
+func mysqlGrantSQL(dbName, username string, role models.DatabaseUserRole) string {
+	privileges := "ALL PRIVILEGES"
+	switch role {
+	case models.DatabaseRoleReadOnly:
+		privileges = "SELECT, SHOW VIEW"
+	case models.DatabaseRoleReadWrite:
+		privileges = "SELECT, INSERT, UPDATE, DELETE, SHOW VIEW, EXECUTE, CREATE TEMPORARY TABLES, LOCK TABLES"
+	}
+	return fmt.Sprintf("GRANT %s ON %s.* TO %s@'%%';", privileges, mysqlIdent(dbName), mysqlString(username))
+}
+
+// redisACLRules returns the ACL rules of a role, confined to the database's key prefix
+func redisACLRules(dbName string, role models.DatabaseUserRole) []string {
+	pattern := redisGlobEscape(dbName) + ":*"
+	rules := []string{"~" + pattern, "&" + pattern}
+	switch role {
+	case models.DatabaseRoleReadOnly:
+		return append(rules, "+@read", "+@connection", "-@dangerous")
+	case models.DatabaseRoleReadWrite:
+		return append(rules, "+@read", "+@write", "+@connection", "+@transaction", "-@dangerous")
+	}
+	return append(rules, "+@all", "-@admin", "-@dangerous")
+}
+
+// mongoRole returns the built-in MongoDB role of a database role
+func mongoRole(role models.DatabaseUserRole) string {
+	switch role {
+	case models.DatabaseRoleReadOnly:
+		return "read"
+	case models.DatabaseRoleReadWrite:
+		return "readWrite"
+	}
+	return "dbOwner"
+}
+
+// mssqlRoles returns the fixed SQL Server database roles of a database role
+func mssqlRoles(role models.DatabaseUserRole) []string {
+	switch role {
+	case models.DatabaseRoleReadOnly:
+		return []string{"db_datareader"}
+	case models.DatabaseRoleReadWrite:
+		return []string{"db_datareader", "db_datawriter"}
+	}
+	return []string{"db_owner"}
+}
+
+// runningDatabase loads a database with its server, which must be running,
+// and the server's root password
+func (s *DatabaseService) runningDatabase(ctx context.Context, id string) (*models.Database, *models.DatabaseServer, string, error) {
+	db, err := s.dbRepo.GetByID(id)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	server, err := s.serverRepo.GetByID(db.ServerID)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("server not found: %w", err)
+	}
+	s.syncServerStatus(ctx, server)
+	if server.Status != models.DatabaseServerStatusRunning {
+		return nil, nil, "", fmt.Errorf("server must be running (current status: %s)", server.Status)
+	}
+	rootPassword, err := s.encryption.Decrypt(server.RootPasswordEncrypted)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to decrypt server credentials: %w", err)
+	}
+	return db, server, rootPassword, nil
+}
+
+// ListDatabaseUsers returns the primary user of a database followed by its
+// additional users
+func (s *DatabaseService) ListDatabaseUsers(ctx context.Context, id string) ([]models.DatabaseUser, error) {
+	db, err := s.dbRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	users, err := s.userRepo.ListByDatabaseID(id)
+	if err != nil {
+		return nil, err
+	}
+	primary := models.DatabaseUser{
+		DatabaseID: db.ID,
+		Username:   db.Username,
+		Role:       models.DatabaseRoleOwner,
+		Primary:    true,
+		CreatedAt:  db.CreatedAt,
+		UpdatedAt:  db.UpdatedAt,
+		CreatedBy:  db.CreatedBy,
+	}
+	return append([]models.DatabaseUser{primary}, users...), nil
+}
+
+// CreateDatabaseUser adds a user with the given role to a database and saves
+// its credentials to the secrets store. Returns the user and its password.
+func (s *DatabaseService) CreateDatabaseUser(ctx context.Context, id string, req *models.CreateDatabaseUserRequest, userID *int64) (*models.DatabaseUser, string, error) {
+	switch req.Role {
+	case models.DatabaseRoleReadOnly, models.DatabaseRoleReadWrite, models.DatabaseRoleOwner:
+	default:
+		return nil, "", fmt.Errorf("invalid role %q: use read-only, read-write or owner", req.Role)
+	}
+
+	db, server, rootPassword, err := s.runningDatabase(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	username := req.Username
+	if username == "" {
+		username, err = auth.GenerateUsername(db.Name, 8)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to generate username: %w", err)
+		}
+	}
+	if !databaseUsernamePattern.MatchString(username) {
+		return nil, "", fmt.Errorf("invalid username: %q", username)
+	}
+	if username == db.Username {
+		return nil, "", fmt.Errorf("user '%s' already exists on this database", username)
+	}
+	if _, err := s.userRepo.GetByUsername(db.ID, username); err == nil {
+		return nil, "", fmt.Errorf("user '%s' already exists on this database", username)
+	} else if err != sql.ErrNoRows {
+		return nil, "", err
+	}
+
+	password := req.Password
+	if password == "" {
+		password, err = auth.GeneratePassword(24)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to generate password: %w", err)
+		}
+	}
+	encryptedPassword, err := s.encryption.Encrypt(password)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encrypt password: %w", err)
+	}
+
+	if err := s.createDatabaseUser(ctx, server, db, username, password, req.Role, rootPassword); err != nil {
+		return nil, "", err
+	}
+
+	user := &models.DatabaseUser{
+		DatabaseID:        db.ID,
+		Username:          username,
+		Role:              req.Role,
+		PasswordEncrypted: encryptedPassword,
+		CreatedBy:         userID,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		s.dropDatabaseUser(ctx, server, db, username, rootPassword)
+		return nil, "", fmt.Errorf("failed to save user record: %w", err)
+	}
+
+	s.saveDatabaseSecret(server, db, username, password, userID)
+	return user, password, nil
+}
+
+// DeleteDatabaseUser removes an additional user from a database and its secret
+func (s *DatabaseService) DeleteDatabaseUser(ctx context.Context, id, username string) error {
+	db, err := s.dbRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if username == db.Username {
+		return fmt.Errorf("the primary user of a database is removed with the database")
+	}
+	user, err := s.userRepo.GetByUsername(id, username)
+	if err != nil {
+		return err
+	}
+
+	_, server, rootPassword, err := s.runningDatabase(ctx, id)
+	if err != nil {
+		return err
+	}
+	s.dropDatabaseUser(ctx, server, db, username, rootPassword)
+
+	containerName := fmt.Sprintf("podmangr-db-%s", server.Name)
+	if secret := s.findSecret(containerName, fmt.Sprintf("%s/%s (%s) - Credentials", server.Name, db.Name, username)); secret != nil {
+		_ = s.secretsRepo.Delete(secret.ID)
+	}
+	return s.userRepo.Delete(user.ID)
+}
+
+// RotateDatabasePassword sets a new password for the primary or an
+// additional user of a database and updates the user's secret. Returns the
+// new password.
+func (s *DatabaseService) RotateDatabasePassword(ctx context.Context, id, username, password string, userID *int64) (string, error) {
+	db, err := s.dbRepo.GetByID(id)
+	if err != nil {
+		return "", err
+	}
+	var user *models.DatabaseUser
+	if username != db.Username {
+		if user, err = s.userRepo.GetByUsername(id, username); err != nil {
+			return "", err
+		}
+	}
+
+	_, server, rootPassword, err := s.runningDatabase(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if password == "" {
+		password, err = auth.GeneratePassword(24)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate password: %w", err)
+		}
+	}
+	encryptedPassword, err := s.encryption.Encrypt(password)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt password: %w", err)
+	}
+
+	if err := s.setDatabaseUserPassword(ctx, server, db, username, password, rootPassword); err != nil {
+		return "", err
+	}
+	if user != nil {
+		err = s.userRepo.UpdatePassword(user.ID, encryptedPassword)
+	} else {
+		err = s.dbRepo.UpdatePassword(db.ID, encryptedPassword)
+	}
+	if err != nil {
+		return "", fmt.Errorf("password changed but not saved: %w", err)
+	}
+
+	s.saveDatabaseSecret(server, db, username, password, userID)
+	return password, nil
+}
+
+// dropAdditionalUsers removes the additional users of a database from its
+// server, before the database itself is dropped
+func (s *DatabaseService) dropAdditionalUsers(ctx context.Context, server *models.DatabaseServer, db *models.Database, rootPassword string) {
+	users, err := s.userRepo.ListByDatabaseID(db.ID)
+	if err != nil {
+		return
+	}
+	for _, user := range users {
+		s.dropDatabaseUser(ctx, server, db, user.Username, rootPassword)
+	}
+}
+
+// createDatabaseUser creates a user on the server and grants it a role on the database
+func (s *DatabaseService) createDatabaseUser(ctx context.Context, server *models.DatabaseServer, db *models.Database, username, password string, role models.DatabaseUserRole, rootPassword string) error {
+	var commands [][]string
+	switch server.Engine {
+	case models.EnginePostgreSQL:
+		commands = [][]string{
+			psqlCommand("postgres", fmt.Sprintf("CREATE USER %s WITH PASSWORD %s; GRANT CONNECT ON DATABASE %s TO %s;",
+				pgIdent(username), sqlString(password), pgIdent(db.Name), pgIdent(username))),
+			psqlCommand(db.Name, postgresGrantSQL(db.Username, username, role)),
+		}
+	case models.EngineMariaDB, models.EngineMySQL:
+		commands = [][]string{
+			mysqlCommand(server.Engine, rootPassword, fmt.Sprintf("CREATE USER %s@'%%' IDENTIFIED BY %s; %s",
+				mysqlString(username), mysqlString(password), mysqlGrantSQL(db.Name, username, role))),
+		}
+	case models.EngineRedis, models.EngineValkey:
+		args := append([]string{"ACL", "SETUSER", username, "reset", "on", ">" + password}, redisACLRules(db.Name, role)...)
+		if _, err := s.redisExec(ctx, server, rootPassword, args...); err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		if _, err := s.redisExec(ctx, server, rootPassword, "ACL", "SAVE"); err != nil {
+			s.dropDatabaseUser(ctx, server, db, username, rootPassword)
+			return fmt.Errorf("failed to save users: %w", err)
+		}
+		return nil
+	case models.EngineMongoDB:
+		commands = [][]string{
+			mongosh(rootPassword, fmt.Sprintf(`db.getSiblingDB(%s).createUser({user: %s, pwd: %s, roles: [{role: %s, db: %s}]})`,
+				jsString(db.Name), jsString(username), jsString(password), jsString(mongoRole(role)), jsString(db.Name))),
+		}
+	case models.EngineMSSQL:
+		query := fmt.Sprintf("CREATE LOGIN %[1]s WITH PASSWORD = %[2]s; USE %[3]s; CREATE USER %[1]s FOR LOGIN %[1]s;",
+			mssqlIdent(username), mssqlString(password), mssqlIdent(db.Name))
+		for _, r := range mssqlRoles(role) {
+			query += fmt.Sprintf(" ALTER ROLE %s ADD MEMBER %s;", r, mssqlIdent(username))
+		}
+		commands = [][]string{sqlcmd(rootPassword, query)}
+	default:
+		return fmt.Errorf("unsupported engine: %s", server.Engine)
+	}
+
+	for _, cmd := range commands {
+		if _, err := s.podman.Exec(ctx, server.ContainerID, cmd, false); err != nil {
+			// Clean up on failure
+			s.dropDatabaseUser(ctx, server, db, username, rootPassword)
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+	}
+	return nil
+}
+
+// dropDatabaseUser removes a user from the server. Objects a PostgreSQL user
+// owns are handed to the database owner first.
+func (s *DatabaseService) dropDatabaseUser(ctx context.Context, server *models.DatabaseServer, db *models.Database, username, rootPassword string) {
+	var commands [][]string
+	switch server.Engine {
+	case models.EnginePostgreSQL:
+		commands = [][]string{
+			psqlCommand(db.Name, fmt.Sprintf("REASSIGN OWNED BY %[1]s TO %[2]s; DROP OWNED BY %[1]s;", pgIdent(username), pgIdent(db.Username))),
+			psqlCommand("postgres", fmt.Sprintf("DROP USER IF EXISTS %s;", pgIdent(username))),
+		}
+	case models.EngineMariaDB, models.EngineMySQL:
+		commands = [][]string{
+			mysqlCommand(server.Engine, rootPassword, fmt.Sprintf("DROP USER IF EXISTS %s@'%%';", mysqlString(username))),
+		}
+	case models.EngineRedis, models.EngineValkey:
+		_, _ = s.redisExec(ctx, server, rootPassword, "ACL", "DELUSER", username)
+		_, _ = s.redisExec(ctx, server, rootPassword, "ACL", "SAVE")
+	case models.EngineMongoDB:
+		commands = [][]string{
+			mongosh(rootPassword, fmt.Sprintf(`const d = db.getSiblingDB(%s); if (d.getUser(%s)) { d.dropUser(%s) }`,
+				jsString(db.Name), jsString(username), jsString(username))),
+		}
+	case models.EngineMSSQL:
+		commands = [][]string{
+			sqlcmd(rootPassword, fmt.Sprintf("USE %s; DROP USER IF EXISTS %s; IF SUSER_ID(%s) IS NOT NULL DROP LOGIN %s;",
+				mssqlIdent(db.Name), mssqlIdent(username), mssqlString(username), mssqlIdent(username))),
+		}
+	}
+
+	for _, cmd := range commands {
+		_, _ = s.podman.Exec(ctx, server.ContainerID, cmd, false)
+	}
+}
+
+// setDatabaseUserPassword changes the password of a user on the server
+func (s *DatabaseService) setDatabaseUserPassword(ctx context.Context, server *models.DatabaseServer, db *models.Database, username, password, rootPassword string) error {
+	var cmd []string
+	switch server.Engine {
+	case models.EnginePostgreSQL:
+		cmd = psqlCommand("postgres", fmt.Sprintf("ALTER USER %s WITH PASSWORD %s;", pgIdent(username), sqlString(password)))
+	case models.EngineMariaDB, models.EngineMySQL:
+		cmd = mysqlCommand(server.Engine, rootPassword, fmt.Sprintf("ALTER USER %s@'%%' IDENTIFIED BY %s;", mysqlString(username), mysqlString(password)))
+	case models.EngineRedis, models.EngineValkey:
+		if _, err := s.redisExec(ctx, server, rootPassword, "ACL", "SETUSER", username, "resetpass", ">"+password); err != nil {
+			return fmt.Errorf("failed to change password: %w", err)
+		}
+		if _, err := s.redisExec(ctx, server, rootPassword, "ACL", "SAVE"); err != nil {
+			return fmt.Errorf("failed to save users: %w", err)
+		}
+		return nil
+	case models.EngineMongoDB:
+		cmd = mongosh(rootPassword, fmt.Sprintf(`db.getSiblingDB(%s).changeUserPassword(%s, %s)`,
+			jsString(db.Name), jsString(username), jsString(password)))
+	case models.EngineMSSQL:
+		cmd = sqlcmd(rootPassword, fmt.Sprintf("ALTER LOGIN %s WITH PASSWORD = %s;", mssqlIdent(username), mssqlString(password)))
+	default:
+		return fmt.Errorf("unsupported engine: %s", server.Engine)
+	}
+
+	if _, err := s.podman.Exec(ctx, server.ContainerID, cmd, false); err != nil {
+		return fmt.Errorf("failed to change password: %w", err)
+	}
+	return nil
+}
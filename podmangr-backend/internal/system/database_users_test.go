@@ -0,0 +1,66 @@
+package system
+
+import (
+	"strings"
+	"testing"
+
+	"podmangr-backend/internal/models"
+)
+
+func TestDatabaseUserGrants(t *testing.T) {
+	if got := postgresGrantSQL("app_owner", "reporting", models.DatabaseRoleOwner); got != `GRANT "app_owner" TO "reporting";` {
+		t.Errorf("postgres owner grant = %s", got)
+	}
+	readOnly := postgresGrantSQL("app_owner", "reporting", models.DatabaseRoleReadOnly)
+	if !strings.Contains(readOnly, `GRANT SELECT ON ALL TABLES IN SCHEMA public TO "reporting"`) ||
+		!strings.Contains(readOnly, `ALTER DEFAULT PRIVILEGES FOR ROLE "app_owner"`) || strings.Contains(readOnly, "INSERT") {
+		t.Errorf("postgres read-only grant = %s", readOnly)
+	}
+
+	if got := mysqlGrantSQL("shop", "o'brien", models.DatabaseRoleReadOnly); got != "GRANT SELECT, SHOW VIEW ON `shop`.* TO 'o''brien'@'%';" {
+		t.Errorf("mysql read-only grant = %s", got)
+	}
+
+	rules := strings.Join(redisACLRules("cache", models.DatabaseRoleReadWrite), " ")
+	if !strings.HasPrefix(rules, "~cache:* &cache:* ") || !strings.Contains(rules, "+@write") {
+		t.Errorf("redis read-write rules = %s", rules)
+	}
+	if strings.Contains(strings.Join(redisACLRules("cache", models.DatabaseRoleReadOnly), " "), "+@write") {
+		t.Error("redis read-only rules allow writes")
+	}
+
+	if mongoRole(models.DatabaseRoleReadWrite) != "readWrite" || len(mssqlRoles(models.DatabaseRoleReadWrite)) != 2 {
+		t.Error("unexpected MongoDB or SQL Server roles for read-write")
+	}
+
+	for _, name := range []string{"app_ro", "svc.reporting", "a-b"} {
+		if !databaseUsernamePattern.MatchString(name) {
+			t.Errorf("username %q rejected", name)
+		}
+	}
+	for _, name := range []string{"", "1abc", "a b", "x;DROP", `a"b`} {
+		if databaseUsernamePattern.MatchString(name) {
+			t.Errorf("username %q accepted", name)
+		}
+	}
+}
+
+func TestParseDatabaseInsight(t *testing.T) {
+	stats := parseDatabaseStats("shop", "shop\t0\t8675309\t0\npublic.orders\t1200\t65536\t16384\n\npublic.short\t1\n")
+	if stats.SizeBytes != 8675309 || len(stats.Tables) != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if table := stats.Tables[0]; table.Name != "public.orders" || table.Rows != 1200 || table.IndexBytes != 16384 {
+		t.Errorf("table = %+v", table)
+	}
+
+	clients := "id=3 addr=10.88.0.5:40112 laddr=10.88.0.2:6379 fd=8 name= age=12 idle=0 flags=N db=0 cmd=get user=cache_app\n" +
+		"id=4 addr=127.0.0.1:50000 fd=9 age=1 idle=7 cmd=client|list user=default\n"
+	sessions := parseRedisClients(clients, map[string]bool{"cache_app": true})
+	if len(sessions) != 1 {
+		t.Fatalf("sessions = %+v", sessions)
+	}
+	if s := sessions[0]; s.ID != "3" || s.ClientAddr != "10.88.0.5:40112" || s.State != "active" || s.Query != "get" {
+		t.Errorf("session = %+v", s)
+	}
+}
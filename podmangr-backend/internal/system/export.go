@@ -0,0 +1,503 @@
+package system
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"podmangr-backend/internal/translator"
+)
+
+// Labels podman-compose puts on the containers of a project
+const (
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+)
+
+// sensitiveEnvPattern matches environment variable names whose values are
+// redacted from exports unless secrets are explicitly included
+var sensitiveEnvPattern = regexp.MustCompile(`(?i)(pass(word|wd)?|secret|token|api_?key|private_?key|credential|auth)`)
+
+// ExportOptions controls how running containers are turned into a compose file
+type ExportOptions struct {
+	// Project is the compose project name. Volumes and networks prefixed
+	// with it are written without the prefix, as compose adds it back.
+	Project string
+	// IncludeSecrets keeps the values of sensitive environment variables.
+	// Otherwise they are replaced by ${NAME} references.
+	IncludeSecrets bool
+}
+
+// ExportResult is a compose file that recreates running containers
+type ExportResult struct {
+	Compose  *translator.ComposeFile
+	Redacted []string // Environment variables replaced by references
+	Warnings []string
+}
+
+// composeExporter collects services, volumes and networks while exporting
+type composeExporter struct {
+	p        *PodmanService
+	opts     ExportOptions
+	compose  *translator.ComposeFile
+	redacted map[string]bool
+	warnings []string
+}
+
+// ExportContainers builds a compose file with one service per container
+func (p *PodmanService) ExportContainers(ctx context.Context, containerIDs []string, opts ExportOptions) (*ExportResult, error) {
+	if len(containerIDs) == 0 {
+		return nil, fmt.Errorf("no containers to export")
+	}
+
+	e := p.newComposeExporter(opts)
+	for _, id := range containerIDs {
+		inspect, err := p.InspectContainer(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect container %s: %w", id, err)
+		}
+		if inspect.Pod != "" {
+			e.warn("%s: member of a pod; export the pod to keep its shared network", strings.TrimPrefix(inspect.Name, "/"))
+		}
+		if err := e.addContainer(ctx, inspect, ""); err != nil {
+			return nil, err
+		}
+	}
+	return e.result(), nil
+}
+
+// ExportStack builds a compose file from the containers of a compose project
+func (p *PodmanService) ExportStack(ctx context.Context, projectName string, opts ExportOptions) (*ExportResult, error) {
+	containers, err := p.GetStackContainers(ctx, projectName)
+	if err != nil {
+		return nil, err
+	}
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("stack %s has no containers", projectName)
+	}
+
+	ids := make([]string, 0, len(containers))
+	for _, c := range containers {
+		ids = append(ids, c.Name)
+	}
+	opts.Project = projectName
+	return p.ExportContainers(ctx, ids, opts)
+}
+
+// ExportPod builds a compose file from the members of a pod. The first
+// member owns the pod's ports and networks; the others join its network
+// namespace with network_mode: service:<first>.
+func (p *PodmanService) ExportPod(ctx context.Context, podID string, opts ExportOptions) (*ExportResult, error) {
+	pod, err := p.inspectPodConfig(ctx, podID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect pod: %w", err)
+	}
+	if opts.Project == "" {
+		opts.Project = pod.Name
+	}
+
+	members := make([]string, 0, len(pod.Containers))
+	for _, m := range pod.Containers {
+		if m.ID != pod.InfraContainerID {
+			members = append(members, m.ID)
+		}
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("pod %s has no containers", pod.Name)
+	}
+
+	e := p.newComposeExporter(opts)
+	inspects := make([]*podmanInspect, 0, len(members))
+	for _, id := range members {
+		inspect, err := p.InspectContainer(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect container %s: %w", id, err)
+		}
+		inspects = append(inspects, inspect)
+	}
+	sort.Slice(inspects, func(i, j int) bool { return inspects[i].Name < inspects[j].Name })
+
+	var owner string
+	for _, inspect := range inspects {
+		if err := e.addContainer(ctx, inspect, owner); err != nil {
+			return nil, err
+		}
+		if owner == "" {
+			owner = e.serviceName(inspect)
+			service := e.compose.Services[owner]
+			// Members run in the infra container's namespaces, which hold the pod's network
+			service.Ports = publishPorts(pod.InfraConfig.PortBindings)
+			service.Networks = nil
+			if pod.InfraConfig.HostNetwork {
+				service.NetworkMode = "host"
+			} else if networks := e.networks(ctx, pod.InfraConfig.Networks, nil); networks != nil {
+				service.NetworkMode = ""
+				service.Networks = networks
+			} else {
+				service.NetworkMode = "bridge"
+			}
+			e.compose.Services[owner] = service
+		}
+	}
+	return e.result(), nil
+}
+
+func (p *PodmanService) newComposeExporter(opts ExportOptions) *composeExporter {
+	return &composeExporter{
+		p:    p,
+		opts: opts,
+		compose: &translator.ComposeFile{
+			Name:     opts.Project,
+			Services: make(map[string]translator.Service),
+		},
+		redacted: make(map[string]bool),
+	}
+}
+
+func (e *composeExporter) warn(format string, args ...interface{}) {
+	e.warnings = append(e.warnings, fmt.Sprintf(format, args...))
+}
+
+func (e *composeExporter) result() *ExportResult {
+	redacted := make([]string, 0, len(e.redacted))
+	for name := range e.redacted {
+		redacted = append(redacted, name)
+	}
+	sort.Strings(redacted)
+	if len(redacted) > 0 {
+		e.warn("values of %s were redacted; set them in the environment or an .env file", strings.Join(redacted, ", "))
+	}
+	return &ExportResult{Compose: e.compose, Redacted: redacted, Warnings: e.warnings}
+}
+
+// serviceName returns the compose service a container is exported as: its
+// compose service label within the project, else its container name
+func (e *composeExporter) serviceName(inspect *podmanInspect) string {
+	labels := inspect.Config.Labels
+	if e.opts.Project != "" && labels[composeProjectLabel] == e.opts.Project && labels[composeServiceLabel] != "" {
+		return labels[composeServiceLabel]
+	}
+	return composeName(strings.TrimPrefix(inspect.Name, "/"))
+}
+
+// stripProject removes the project prefix compose adds to volume and network
+// names. ok is false if the name does not carry the prefix.
+func (e *composeExporter) stripProject(name string) (string, bool) {
+	if e.opts.Project == "" {
+		return name, false
+	}
+	for _, sep := range []string{"_", "-"} {
+		if key, found := strings.CutPrefix(name, e.opts.Project+sep); found && key != "" {
+			return key, true
+		}
+	}
+	return name, false
+}
+
+// addContainer exports one container. When joinService is set the container
+// shares that service's network namespace, as pod members do.
+func (e *composeExporter) addContainer(ctx context.Context, inspect *podmanInspect, joinService string) error {
+	name := strings.TrimPrefix(inspect.Name, "/")
+	key := e.serviceName(inspect)
+	if _, exists := e.compose.Services[key]; exists {
+		key = composeName(name)
+	}
+
+	// Image defaults are left out so the service only carries what was customised
+	image, _ := e.p.InspectImage(ctx, inspect.Config.Image, false)
+	imageEnv := make(map[string]string)
+	imageLabels := make(map[string]string)
+	if image != nil {
+		for _, env := range image.Environment {
+			imageEnv[env.Key] = env.Value
+		}
+		imageLabels = image.Labels
+	}
+
+	service := translator.Service{
+		Image:       inspect.Config.Image,
+		CapAdd:      inspect.HostConfig.CapAdd,
+		CapDrop:     inspect.HostConfig.CapDrop,
+		Privileged:  inspect.HostConfig.Privileged,
+		ReadOnly:    inspect.HostConfig.ReadonlyRootfs,
+		SecurityOpt: inspect.HostConfig.SecurityOpt,
+		ExtraHosts:  inspect.HostConfig.ExtraHosts,
+	}
+
+	// podman-compose names containers <project>_<service>_<n>; compose recreates that itself
+	if !strings.HasPrefix(name, e.opts.Project+"_"+key+"_") || e.opts.Project == "" {
+		service.ContainerName = name
+	}
+
+	if joinService != "" {
+		service.NetworkMode = "service:" + joinService
+	} else {
+		switch mode := inspect.HostConfig.NetworkMode; {
+		case mode == "host" || mode == "none" || strings.HasPrefix(mode, "container:"):
+			service.NetworkMode = mode
+		default:
+			if networks := e.networks(ctx, sortedKeys(inspect.NetworkSettings.Networks), inspect); networks != nil {
+				service.Networks = networks
+			} else {
+				service.NetworkMode = "bridge"
+			}
+		}
+		service.Ports = publishPorts(inspect.HostConfig.PortBindings)
+		if hostname := inspect.Config.Hostname; hostname != "" && !strings.HasPrefix(inspect.ID, hostname) {
+			service.Hostname = hostname
+		}
+		if len(inspect.HostConfig.DNS) > 0 {
+			service.DNS = toInterfaces(inspect.HostConfig.DNS)
+		}
+	}
+
+	for _, mount := range inspect.Mounts {
+		suffix := ""
+		if !mount.RW {
+			suffix = ":ro"
+		}
+		switch {
+		case mount.Type == "volume" && mount.Name != "":
+			service.Volumes = append(service.Volumes, e.volume(ctx, mount.Name)+":"+mount.Destination+suffix)
+		case mount.Type == "bind":
+			service.Volumes = append(service.Volumes, mount.Source+":"+mount.Destination+suffix)
+		}
+	}
+	if paths := sortedKeys(inspect.HostConfig.Tmpfs); len(paths) > 0 {
+		tmpfs := make([]interface{}, 0, len(paths))
+		for _, path := range paths {
+			if opts := inspect.HostConfig.Tmpfs[path]; opts != "" {
+				path += ":" + opts
+			}
+			tmpfs = append(tmpfs, path)
+		}
+		service.Tmpfs = tmpfs
+	}
+	for _, dev := range inspect.HostConfig.Devices {
+		spec := dev.PathOnHost
+		if dev.PathInContainer != "" && dev.PathInContainer != dev.PathOnHost {
+			spec += ":" + dev.PathInContainer
+		}
+		service.Devices = append(service.Devices, spec)
+	}
+
+	env := make(map[string]interface{})
+	for _, entry := range inspect.Config.Env {
+		k, v, _ := strings.Cut(entry, "=")
+		if k == "container" || k == "HOSTNAME" {
+			continue // set by Podman itself
+		}
+		if imageValue, ok := imageEnv[k]; ok && imageValue == v {
+			continue
+		}
+		if !e.opts.IncludeSecrets && sensitiveEnvPattern.MatchString(k) {
+			e.redacted[k] = true
+			v = "${" + k + "}"
+		}
+		env[k] = v
+	}
+	if len(env) > 0 {
+		service.Environment = env
+	}
+
+	labels := make(map[string]interface{})
+	for k, v := range inspect.Config.Labels {
+		if strings.HasPrefix(k, "io.podman.") || strings.HasPrefix(k, "org.opencontainers.") ||
+			strings.HasPrefix(k, "com.docker.compose.") || k == "PODMAN_SYSTEMD_UNIT" {
+			continue
+		}
+		if imageValue, ok := imageLabels[k]; ok && imageValue == v {
+			continue
+		}
+		labels[k] = v
+	}
+	if len(labels) > 0 {
+		service.Labels = labels
+	}
+
+	if image == nil || !slices.Equal(image.Entrypoint, inspect.Config.Entrypoint) {
+		if len(inspect.Config.Entrypoint) > 0 {
+			service.Entrypoint = toInterfaces(inspect.Config.Entrypoint)
+		}
+	}
+	if image == nil || !slices.Equal(image.Cmd, inspect.Config.Cmd) {
+		if len(inspect.Config.Cmd) > 0 {
+			service.Command = toInterfaces(inspect.Config.Cmd)
+		}
+	}
+	if image == nil || image.User != inspect.Config.User {
+		service.User = inspect.Config.User
+	}
+	if (image == nil || image.WorkingDir != inspect.Config.WorkingDir) && inspect.Config.WorkingDir != "/" {
+		service.WorkingDir = inspect.Config.WorkingDir
+	}
+
+	if hc := inspect.Config.Healthcheck; hc != nil && len(hc.Test) > 0 && hc.Test[0] != "NONE" {
+		healthcheck := map[string]interface{}{"test": toInterfaces(hc.Test)}
+		for field, ns := range map[string]int64{"interval": hc.Interval, "timeout": hc.Timeout, "start_period": hc.StartPeriod} {
+			if ns > 0 {
+				healthcheck[field] = time.Duration(ns).String()
+			}
+		}
+		if hc.Retries > 0 {
+			healthcheck["retries"] = hc.Retries
+		}
+		service.HealthCheck = healthcheck
+	}
+
+	limits := make(map[string]interface{})
+	if inspect.HostConfig.Memory > 0 {
+		limits["memory"] = strconv.FormatInt(inspect.HostConfig.Memory, 10)
+	}
+	if inspect.HostConfig.NanoCpus > 0 {
+		limits["cpus"] = strconv.FormatFloat(float64(inspect.HostConfig.NanoCpus)/1e9, 'f', -1, 64)
+	}
+	if len(limits) > 0 {
+		service.Deploy = map[string]interface{}{
+			"resources": map[string]interface{}{"limits": limits},
+		}
+	}
+
+	switch policy := inspect.HostConfig.RestartPolicy; policy.Name {
+	case "", "no":
+	case "on-failure":
+		service.Restart = "on-failure"
+		if policy.MaximumRetryCount > 0 {
+			service.Restart = fmt.Sprintf("on-failure:%d", policy.MaximumRetryCount)
+		}
+	default:
+		service.Restart = policy.Name
+	}
+
+	e.compose.Services[key] = service
+	return nil
+}
+
+// volume declares a named volume at the top level and returns the key services use
+func (e *composeExporter) volume(ctx context.Context, name string) string {
+	key, prefixed := e.stripProject(name)
+	key = composeName(key)
+	if e.compose.Volumes == nil {
+		e.compose.Volumes = make(map[string]translator.Volume)
+	}
+	if _, ok := e.compose.Volumes[key]; ok {
+		return key
+	}
+
+	volume := translator.Volume{}
+	if !prefixed {
+		// Keep the exact name so the existing volume is reused
+		volume.Name = name
+	}
+	if v, err := e.p.InspectVolume(ctx, name); err == nil {
+		if v.Driver != "" && v.Driver != "local" {
+			volume.Driver = v.Driver
+		}
+		if len(v.Options) > 0 {
+			volume.DriverOpts = v.Options
+		}
+		if len(v.Labels) > 0 {
+			labels := make(map[string]interface{})
+			for k, val := range v.Labels {
+				if !strings.HasPrefix(k, "com.docker.compose.") && !strings.HasPrefix(k, "io.podman.") {
+					labels[k] = val
+				}
+			}
+			if len(labels) > 0 {
+				volume.Labels = labels
+			}
+		}
+	}
+	e.compose.Volumes[key] = volume
+	return key
+}
+
+// networks declares the networks a container joins and returns the service
+// networks field, or nil if it only uses Podman's default network
+func (e *composeExporter) networks(ctx context.Context, names []string, inspect *podmanInspect) interface{} {
+	result := make(map[string]interface{})
+	for _, name := range names {
+		if name == defaultPodmanNetwork {
+			continue
+		}
+		key, prefixed := e.stripProject(name)
+		key = composeName(key)
+
+		if e.compose.Networks == nil {
+			e.compose.Networks = make(map[string]translator.Network)
+		}
+		if _, ok := e.compose.Networks[key]; !ok {
+			network := translator.Network{}
+			if !prefixed {
+				network.Name = name
+			}
+			if n, err := e.p.InspectNetwork(ctx, name); err == nil {
+				if n.Driver != "" && n.Driver != "bridge" {
+					network.Driver = n.Driver
+				}
+				network.Internal = n.Internal
+				network.EnableIPv6 = n.IPv6Enabled
+				if len(n.Subnets) > 0 {
+					configs := make([]interface{}, 0, len(n.Subnets))
+					for _, s := range n.Subnets {
+						cfg := map[string]interface{}{"subnet": s.Subnet}
+						if s.Gateway != "" {
+							cfg["gateway"] = s.Gateway
+						}
+						configs = append(configs, cfg)
+					}
+					network.Ipam = map[string]interface{}{"config": configs}
+				}
+			}
+			e.compose.Networks[key] = network
+		}
+
+		var opts interface{}
+		if inspect != nil {
+			// Podman adds the container name and short ID as aliases itself
+			shortID := inspect.ID
+			if len(shortID) > 12 {
+				shortID = shortID[:12]
+			}
+			var aliases []interface{}
+			for _, alias := range inspect.NetworkSettings.Networks[name].Aliases {
+				if alias != strings.TrimPrefix(inspect.Name, "/") && alias != shortID && alias != inspect.Config.Labels[composeServiceLabel] {
+					aliases = append(aliases, alias)
+				}
+			}
+			if len(aliases) > 0 {
+				opts = map[string]interface{}{"aliases": aliases}
+			}
+		}
+		result[key] = opts
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+var invalidComposeNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// composeName turns a Podman object name into a valid compose key
+func composeName(name string) string {
+	name = strings.Trim(invalidComposeNameChars.ReplaceAllString(name, "-"), "-.")
+	if name == "" {
+		return "unnamed"
+	}
+	return name
+}
+
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
@@ -0,0 +1,101 @@
+package system
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"podmangr-backend/internal/translator"
+)
+
+func TestExportContainers(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/blog_web_1/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"Id": "0123456789abcdef",
+			"Name": "blog_web_1",
+			"Config": {
+				"Hostname": "0123456789ab",
+				"Image": "docker.io/library/wordpress:6",
+				"Env": ["container=podman", "WORDPRESS_DB_HOST=db", "WORDPRESS_DB_PASSWORD=hunter2"],
+				"Cmd": ["apache2-foreground"],
+				"Labels": {"com.docker.compose.project": "blog", "com.docker.compose.service": "web", "traefik.enable": "true"},
+				"Healthcheck": {"Test": ["CMD-SHELL", "curl -f localhost"], "Interval": 30000000000, "Retries": 3}
+			},
+			"HostConfig": {
+				"RestartPolicy": {"Name": "unless-stopped"},
+				"PortBindings": {"80/tcp": [{"HostIp": "", "HostPort": "8080"}]},
+				"NetworkMode": "bridge",
+				"Memory": 536870912
+			},
+			"Mounts": [
+				{"Type": "volume", "Name": "blog_content", "Destination": "/var/www/html", "RW": true},
+				{"Type": "bind", "Source": "/srv/blog/php.ini", "Destination": "/usr/local/etc/php/php.ini", "RW": false}
+			],
+			"NetworkSettings": {"Networks": {"blog_default": {"Aliases": ["web", "0123456789ab", "wordpress"]}}}
+		}`))
+	})
+	mux.HandleFunc("/volumes/blog_content/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Name": "blog_content", "Driver": "local", "Labels": {"com.docker.compose.project": "blog"}, "Options": {}}`))
+	})
+	mux.HandleFunc("/networks/blog_default/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "blog_default", "driver": "bridge", "subnets": [{"subnet": "10.89.3.0/24", "gateway": "10.89.3.1"}]}`))
+	})
+	svc := newFakePodmanSocket(t, mux)
+
+	export, err := svc.ExportContainers(context.Background(), []string{"blog_web_1"}, ExportOptions{Project: "blog"})
+	if err != nil {
+		t.Fatalf("ExportContainers returned error: %v", err)
+	}
+	if len(export.Redacted) != 1 || export.Redacted[0] != "WORDPRESS_DB_PASSWORD" {
+		t.Errorf("Expected WORDPRESS_DB_PASSWORD to be redacted, got %v", export.Redacted)
+	}
+
+	result, err := translator.NewTranslator().Render(export.Compose, translator.FormatPodmanCompose, translator.Options{})
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	compose := result.Output
+	for _, want := range []string{
+		"name: blog\n",
+		"    web:\n",
+		"image: docker.io/library/wordpress:6\n",
+		"- 8080:80\n",
+		"- content:/var/www/html\n",
+		"- /srv/blog/php.ini:/usr/local/etc/php/php.ini:ro\n",
+		"WORDPRESS_DB_HOST: db\n",
+		"WORDPRESS_DB_PASSWORD: ${WORDPRESS_DB_PASSWORD}\n",
+		"traefik.enable: \"true\"\n",
+		"restart: unless-stopped\n",
+		"aliases:\n                    - wordpress\n",
+		"interval: 30s\n",
+		"memory: \"536870912\"\n",
+		"subnet: 10.89.3.0/24\n",
+	} {
+		if !strings.Contains(compose, want) {
+			t.Errorf("Compose output missing %q:\n%s", want, compose)
+		}
+	}
+	for _, unwanted := range []string{"hunter2", "container_name", "com.docker.compose", "container=podman", "hostname"} {
+		if strings.Contains(compose, unwanted) {
+			t.Errorf("Compose output should not contain %q:\n%s", unwanted, compose)
+		}
+	}
+
+	// The export must be valid input for the other formats too
+	result, err = translator.NewTranslator().Render(export.Compose, translator.FormatQuadlet, translator.Options{})
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	var names []string
+	for _, f := range result.Files {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != "web.container,content.volume,default.network" {
+		t.Errorf("Unexpected Quadlet files: %v", names)
+	}
+	if !strings.Contains(result.Output, "VolumeName=blog_content\n") || !strings.Contains(result.Output, "NetworkName=blog_default\n") {
+		t.Errorf("Quadlet units should reuse the existing volume and network:\n%s", result.Output)
+	}
+}
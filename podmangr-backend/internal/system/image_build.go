@@ -0,0 +1,342 @@
+package system
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"podmangr-backend/internal/models"
+)
+
+// buildContextsDir holds uploaded build contexts and the files of running builds
+var buildContextsDir = "/var/lib/podmangr/builds"
+
+const (
+	// buildContextMaxAge is how long an uploaded context no build used is kept
+	buildContextMaxAge = 24 * time.Hour
+	// buildContextMaxUpload bounds the size of an uploaded context as sent
+	buildContextMaxUpload = 2 << 30
+	// buildContextMaxSize bounds the size of an uploaded context once unpacked
+	buildContextMaxSize = 8 << 30
+)
+
+var (
+	buildIDPattern  = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	platformPattern = regexp.MustCompile(`^[a-z0-9_]+/[a-z0-9_]+(/[a-z0-9_.]+)?$`)
+)
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// SaveBuildContext unpacks an uploaded tar or tar.gz build context for a
+// later build. Contexts left unused for a day are removed on the next upload.
+// Owners in the archive are not restored, as they come from the client.
+func SaveBuildContext(r io.Reader) (*models.BuildContext, error) {
+	removeStaleBuildContexts()
+
+	id := uuid.New().String()
+	dir := filepath.Join(buildContextsDir, id, "context")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create build context directory: %w", err)
+	}
+
+	counter := &countingReader{r: io.LimitReader(r, buildContextMaxUpload+1)}
+	stream := bufio.NewReader(counter)
+	var archive io.Reader = stream
+	if magic, err := stream.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(stream)
+		if err != nil {
+			RemoveBuildContext(id)
+			return nil, fmt.Errorf("invalid gzip stream: %w", err)
+		}
+		defer gz.Close()
+		archive = gz
+	}
+	err := extractTar(archive, dir, extractOptions{maxSize: buildContextMaxSize})
+	if counter.n > buildContextMaxUpload {
+		err = fmt.Errorf("upload is larger than %d bytes", int64(buildContextMaxUpload))
+	}
+	if err != nil {
+		RemoveBuildContext(id)
+		return nil, fmt.Errorf("failed to unpack build context: %w", err)
+	}
+	return &models.BuildContext{ID: id, Size: counter.n}, nil
+}
+
+// RemoveBuildContext deletes an uploaded build context
+func RemoveBuildContext(id string) error {
+	if !buildIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid build context ID: %q", id)
+	}
+	return os.RemoveAll(filepath.Join(buildContextsDir, id))
+}
+
+// removeStaleBuildContexts deletes contexts and build directories older than buildContextMaxAge
+func removeStaleBuildContexts() {
+	entries, err := os.ReadDir(buildContextsDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err == nil && buildIDPattern.MatchString(entry.Name()) && time.Since(info.ModTime()) > buildContextMaxAge {
+			os.RemoveAll(filepath.Join(buildContextsDir, entry.Name()))
+		}
+	}
+}
+
+// isGitURL reports whether a build context URL is a git repository podman can clone
+func isGitURL(url string) bool {
+	for _, prefix := range []string{"https://", "http://", "git://", "git@", "github.com/"} {
+		if strings.HasPrefix(url, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateBuildRequest checks that a request names one context and only
+// holds values podman build accepts
+func validateBuildRequest(req *models.BuildImageRequest) error {
+	contexts := 0
+	for _, set := range []bool{req.ContextUploadID != "", req.ContextDir != "", req.GitURL != ""} {
+		if set {
+			contexts++
+		}
+	}
+	switch {
+	case contexts > 1:
+		return fmt.Errorf("give only one of context_upload_id, context_dir and git_url")
+	case contexts == 0 && req.Containerfile == "":
+		return fmt.Errorf("a build context or an inline Containerfile is required")
+	case req.Containerfile != "" && req.ContainerfilePath != "":
+		return fmt.Errorf("containerfile and containerfile_path can't be combined")
+	case req.ContextUploadID != "" && !buildIDPattern.MatchString(req.ContextUploadID):
+		return fmt.Errorf("invalid build context ID: %q", req.ContextUploadID)
+	case req.ContextDir != "" && !filepath.IsAbs(req.ContextDir):
+		return fmt.Errorf("context_dir must be an absolute path")
+	case req.GitURL != "" && !isGitURL(req.GitURL):
+		return fmt.Errorf("git_url must be an http(s), git:// or git@ URL")
+	case req.ContainerfilePath != "" && !filepath.IsAbs(req.ContainerfilePath) && !filepath.IsLocal(req.ContainerfilePath):
+		return fmt.Errorf("containerfile_path must stay within the build context")
+	case req.Platform != "" && !platformPattern.MatchString(req.Platform):
+		return fmt.Errorf("invalid platform %q: use os/arch, e.g. linux/arm64", req.Platform)
+	}
+	if req.ContextDir != "" {
+		if info, err := os.Stat(req.ContextDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("context_dir %s is not a directory", req.ContextDir)
+		}
+	}
+	for _, tag := range req.Tags {
+		if tag == "" || strings.HasPrefix(tag, "-") || strings.ContainsAny(tag, " \t\n") {
+			return fmt.Errorf("invalid tag %q", tag)
+		}
+	}
+	for name := range req.BuildArgs {
+		if name == "" || strings.ContainsAny(name, "= \t\n") {
+			return fmt.Errorf("invalid build argument name %q", name)
+		}
+	}
+	return nil
+}
+
+// buildCommand returns the podman build arguments for a request, building
+// contextPath with the Containerfile at file (podman's default if empty)
+// and writing the image ID to iidFile
+func buildCommand(req *models.BuildImageRequest, contextPath, file, iidFile string) []string {
+	args := []string{"build", "--iidfile", iidFile}
+	if file != "" {
+		args = append(args, "--file", file)
+	}
+	for _, tag := range req.Tags {
+		args = append(args, "--tag", tag)
+	}
+	for _, name := range sortedKeys(req.BuildArgs) {
+		args = append(args, "--build-arg", name+"="+req.BuildArgs[name])
+	}
+	for _, name := range sortedKeys(req.Labels) {
+		args = append(args, "--label", name+"="+req.Labels[name])
+	}
+	if req.Target != "" {
+		args = append(args, "--target", req.Target)
+	}
+	if req.Platform != "" {
+		args = append(args, "--platform", req.Platform)
+	}
+	if req.NoCache {
+		args = append(args, "--no-cache")
+	}
+	if req.Pull {
+		args = append(args, "--pull=always")
+	}
+	for _, repo := range req.CacheFrom {
+		args = append(args, "--cache-from", repo)
+	}
+	for _, repo := range req.CacheTo {
+		args = append(args, "--cache-to", repo)
+	}
+	return append(args, contextPath)
+}
+
+// chownTree hands a directory tree to a user, so podman running as that
+// user through sudo can read the context and write the image ID file
+func chownTree(dir string, uid, gid int) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, uid, gid)
+	})
+}
+
+// BuildImage builds an image with podman build, sending each line of build
+// output to output, which is closed when the build ends. Base images are
+// pulled with the stored registry logins. An uploaded context is removed
+// once used. Returns the ID of the built image.
+func (p *PodmanService) BuildImage(ctx context.Context, req *models.BuildImageRequest, output chan<- string) (string, error) {
+	if err := validateBuildRequest(req); err != nil {
+		close(output)
+		return "", err
+	}
+
+	// The working directory holds an inline Containerfile and the image ID file
+	work := filepath.Join(buildContextsDir, uuid.New().String())
+	if err := os.MkdirAll(work, 0755); err != nil {
+		close(output)
+		return "", fmt.Errorf("failed to create build directory: %w", err)
+	}
+	defer os.RemoveAll(work)
+
+	contextPath := req.GitURL
+	owned := []string{work}
+	switch {
+	case req.ContextUploadID != "":
+		contextPath = filepath.Join(buildContextsDir, req.ContextUploadID, "context")
+		if _, err := os.Stat(contextPath); err != nil {
+			close(output)
+			return "", fmt.Errorf("build context %s not found; upload it again", req.ContextUploadID)
+		}
+		defer RemoveBuildContext(req.ContextUploadID)
+		owned = append(owned, filepath.Dir(contextPath))
+	case req.ContextDir != "":
+		contextPath = req.ContextDir
+	case req.GitURL == "":
+		contextPath = filepath.Join(work, "context")
+		if err := os.Mkdir(contextPath, 0755); err != nil {
+			close(output)
+			return "", err
+		}
+	}
+
+	file := req.ContainerfilePath
+	if req.Containerfile != "" {
+		file = filepath.Join(work, "Containerfile")
+		if err := os.WriteFile(file, []byte(req.Containerfile), 0644); err != nil {
+			close(output)
+			return "", fmt.Errorf("failed to write Containerfile: %w", err)
+		}
+	} else if file != "" && !filepath.IsAbs(file) && req.GitURL == "" {
+		file = filepath.Join(contextPath, file)
+	}
+
+	if os.Getuid() == 0 && p.targetUser != "" {
+		uid, gid, err := lookupIDs(p.targetUser)
+		for _, dir := range owned {
+			if err == nil {
+				err = chownTree(dir, uid, gid)
+			}
+		}
+		if err != nil {
+			close(output)
+			return "", fmt.Errorf("failed to hand the build context to %s: %w", p.targetUser, err)
+		}
+	}
+
+	iidFile := filepath.Join(work, "iid")
+	args := buildCommand(req, contextPath, file, iidFile)
+	args = append(append([]string{args[0]}, p.registryArgs("")...), args[1:]...)
+	if err := p.podmanStream(ctx, args, output); err != nil {
+		return "", fmt.Errorf("build failed: %w", err)
+	}
+
+	id, err := os.ReadFile(iidFile)
+	if err != nil {
+		return "", fmt.Errorf("build finished but its image ID is unknown: %w", err)
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(id)), "sha256:"), nil
+}
+
+// podmanCommand prepares a podman command as podmanCmd runs it, for callers
+// that need its output as it is printed
+func (p *PodmanService) podmanCommand(ctx context.Context, args ...string) *exec.Cmd {
+	if os.Getuid() == 0 && p.targetUser != "" {
+		return exec.CommandContext(ctx, "sudo", append([]string{"-u", p.targetUser, "podman"}, args...)...)
+	}
+	return exec.CommandContext(ctx, "podman", args...)
+}
+
+// podmanStream runs a podman command, sending each line it prints to
+// output, stdout and stderr interleaved, and closes output when it exits.
+// A failure's error includes the last line printed.
+func (p *PodmanService) podmanStream(ctx context.Context, args []string, output chan<- string) error {
+	cmd := p.podmanCommand(ctx, args...)
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	if err := cmd.Start(); err != nil {
+		close(output)
+		return err
+	}
+
+	lastLine := make(chan string, 1)
+	go func() {
+		defer close(output)
+		last := ""
+		scanner := bufio.NewScanner(pr)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "" {
+				continue
+			}
+			last = line
+			// Output is still drained after cancellation, or podman would block
+			if ctx.Err() == nil {
+				select {
+				case output <- line:
+				case <-ctx.Done():
+				}
+			}
+		}
+		io.Copy(io.Discard, pr)
+		lastLine <- last
+	}()
+
+	err := cmd.Wait()
+	pw.Close()
+	last := <-lastLine
+	if err != nil && last != "" {
+		return fmt.Errorf("%w: %s", err, last)
+	}
+	return err
+}
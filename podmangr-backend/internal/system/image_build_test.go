@@ -0,0 +1,120 @@
+package system
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"podmangr-backend/internal/models"
+)
+
+func TestBuildCommand(t *testing.T) {
+	req := &models.BuildImageRequest{
+		Tags:      []string{"localhost/app:dev", "registry.example.com/app:1.0"},
+		BuildArgs: map[string]string{"VERSION": "1.2", "GO": "1.24"},
+		Labels:    map[string]string{"team": "ops"},
+		Target:    "runtime",
+		Platform:  "linux/arm64",
+		NoCache:   true,
+		Pull:      true,
+		CacheFrom: []string{"registry.example.com/cache"},
+	}
+	got := strings.Join(buildCommand(req, "/ctx", "/ctx/Dockerfile", "/work/iid"), " ")
+	want := "build --iidfile /work/iid --file /ctx/Dockerfile --tag localhost/app:dev --tag registry.example.com/app:1.0" +
+		" --build-arg GO=1.24 --build-arg VERSION=1.2 --label team=ops --target runtime --platform linux/arm64" +
+		" --no-cache --pull=always --cache-from registry.example.com/cache /ctx"
+	if got != want {
+		t.Errorf("buildCommand =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestValidateBuildRequest(t *testing.T) {
+	dir := t.TempDir()
+	valid := []models.BuildImageRequest{
+		{Containerfile: "FROM alpine"},
+		{ContextDir: dir, ContainerfilePath: "build/Containerfile"},
+		{GitURL: "https://github.com/org/app.git#main:docker", Platform: "linux/arm64/v8"},
+	}
+	for i := range valid {
+		if err := validateBuildRequest(&valid[i]); err != nil {
+			t.Errorf("valid request %d rejected: %v", i, err)
+		}
+	}
+
+	invalid := map[string]models.BuildImageRequest{
+		"no context":         {},
+		"two contexts":       {ContextDir: dir, GitURL: "https://github.com/org/app.git"},
+		"relative dir":       {ContextDir: "app"},
+		"missing dir":        {ContextDir: filepath.Join(dir, "missing")},
+		"escaping file":      {ContextDir: dir, ContainerfilePath: "../Containerfile"},
+		"inline and file":    {ContextDir: dir, Containerfile: "FROM alpine", ContainerfilePath: "Containerfile"},
+		"bad upload id":      {ContextUploadID: "../etc"},
+		"bad git url":        {GitURL: "file:///etc"},
+		"flag as tag":        {Containerfile: "FROM alpine", Tags: []string{"--rm"}},
+		"bad platform":       {Containerfile: "FROM alpine", Platform: "arm64"},
+		"bad build arg name": {Containerfile: "FROM alpine", BuildArgs: map[string]string{"A=B": "c"}},
+	}
+	for name, req := range invalid {
+		if err := validateBuildRequest(&req); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestSaveBuildContext(t *testing.T) {
+	defer func(dir string) { buildContextsDir = dir }(buildContextsDir)
+	buildContextsDir = t.TempDir()
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	content := []byte("FROM alpine\nCOPY app /app\n")
+	tw.WriteHeader(&tar.Header{Name: "Containerfile", Mode: 0644, Size: int64(len(content))})
+	tw.Write(content)
+	tw.Close()
+	gz.Close()
+	size := archive.Len()
+
+	buildContext, err := SaveBuildContext(&archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buildContext.Size != int64(size) {
+		t.Errorf("Size = %d, want %d", buildContext.Size, size)
+	}
+	data, err := os.ReadFile(filepath.Join(buildContextsDir, buildContext.ID, "context", "Containerfile"))
+	if err != nil || !bytes.Equal(data, content) {
+		t.Errorf("Containerfile = %q, %v", data, err)
+	}
+
+	if err := RemoveBuildContext(buildContext.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(buildContextsDir, buildContext.ID)); !os.IsNotExist(err) {
+		t.Errorf("context not removed: %v", err)
+	}
+	if RemoveBuildContext("../..") == nil {
+		t.Error("invalid ID accepted")
+	}
+}
+
+func TestExtractTarMaxSize(t *testing.T) {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	for _, name := range []string{"a", "b"} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 8})
+		tw.Write([]byte("12345678"))
+	}
+	tw.Close()
+
+	if err := extractTar(bytes.NewReader(archive.Bytes()), t.TempDir(), extractOptions{maxSize: 16}); err != nil {
+		t.Errorf("archive within the limit rejected: %v", err)
+	}
+	if err := extractTar(bytes.NewReader(archive.Bytes()), t.TempDir(), extractOptions{maxSize: 10}); err == nil {
+		t.Error("archive over the limit accepted")
+	}
+}
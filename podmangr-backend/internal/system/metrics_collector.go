@@ -0,0 +1,235 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"podmangr-backend/internal/database"
+	"podmangr-backend/internal/models"
+)
+
+const (
+	defaultMetricsInterval       = 60 * time.Second
+	minMetricsInterval           = 10 * time.Second
+	defaultMetricsRetentionHours = 720
+	metricsMaintenanceInterval   = time.Hour
+)
+
+// metricsTiers controls downsampling: samples older than age are folded
+// into buckets of the given size (in seconds)
+var metricsTiers = []struct {
+	age    time.Duration
+	bucket int
+}{
+	{age: 24 * time.Hour, bucket: 300},      // raw -> 5 minutes after a day
+	{age: 7 * 24 * time.Hour, bucket: 3600}, // 5 minutes -> 1 hour after a week
+}
+
+// MetricsCollector periodically samples stats for all running containers on
+// every known Podman socket and stores them in container_metrics
+type MetricsCollector struct {
+	registry     *SocketRegistry
+	metricsRepo  *database.ContainerMetricsRepo
+	settingsRepo *database.SettingsRepo
+
+	mu      sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+	running bool
+}
+
+// NewMetricsCollector creates a new metrics collector
+func NewMetricsCollector() *MetricsCollector {
+	return &MetricsCollector{
+		registry:     GetSocketRegistry(),
+		metricsRepo:  database.NewContainerMetricsRepo(),
+		settingsRepo: database.NewSettingsRepo(),
+	}
+}
+
+// Start launches the background sampling loop
+func (m *MetricsCollector) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running {
+		return
+	}
+	m.running = true
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+
+	go m.run(m.stop, m.done)
+}
+
+// Stop stops the sampling loop and waits for it to exit
+func (m *MetricsCollector) Stop() {
+	m.mu.Lock()
+	if !m.running {
+		m.mu.Unlock()
+		return
+	}
+	m.running = false
+	close(m.stop)
+	done := m.done
+	m.mu.Unlock()
+
+	<-done
+}
+
+func (m *MetricsCollector) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	var lastMaintenance time.Time
+	for {
+		// Settings are re-read every cycle so changes apply without a restart
+		interval := m.interval()
+
+		if m.enabled() {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			if _, err := m.Collect(ctx); err != nil {
+				log.Printf("Metrics collection error: %v", err)
+			}
+			cancel()
+		}
+
+		if time.Since(lastMaintenance) >= metricsMaintenanceInterval {
+			if err := m.Maintain(time.Now()); err != nil {
+				log.Printf("Metrics maintenance error: %v", err)
+			}
+			lastMaintenance = time.Now()
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// enabled reports whether sampling is turned on (defaults to true)
+func (m *MetricsCollector) enabled() bool {
+	if _, err := m.settingsRepo.Get(database.SettingMetricsEnabled); err != nil {
+		return true
+	}
+	enabled, _ := m.settingsRepo.GetBool(database.SettingMetricsEnabled)
+	return enabled
+}
+
+// interval returns the configured sampling interval
+func (m *MetricsCollector) interval() time.Duration {
+	seconds, err := m.settingsRepo.GetInt(database.SettingMetricsInterval)
+	if err != nil || seconds <= 0 {
+		return defaultMetricsInterval
+	}
+	return max(time.Duration(seconds)*time.Second, minMetricsInterval)
+}
+
+// retentionHours returns how long metrics are kept
+func (m *MetricsCollector) retentionHours() int {
+	hours, err := m.settingsRepo.GetInt(database.SettingMetricsRetention)
+	if err != nil || hours <= 0 {
+		return defaultMetricsRetentionHours
+	}
+	return hours
+}
+
+// Collect takes one sample of every running container on every socket and
+// stores them. Sockets that fail are reported but don't block the others.
+func (m *MetricsCollector) Collect(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	seen := make(map[string]bool)
+	samples := make([]models.ContainerMetrics, 0)
+	var errs []error
+
+	for socketID, svc := range m.registry.Services() {
+		batch, err := collectContainerMetrics(ctx, svc, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("socket %s: %w", socketID, err))
+			continue
+		}
+		for _, sample := range batch {
+			if !seen[sample.ContainerID] {
+				seen[sample.ContainerID] = true
+				samples = append(samples, sample)
+			}
+		}
+	}
+
+	if err := m.metricsRepo.SaveBatch(samples); err != nil {
+		return 0, fmt.Errorf("failed to save metrics: %w", err)
+	}
+
+	return len(samples), errors.Join(errs...)
+}
+
+// Maintain downsamples old samples into coarser buckets and removes
+// everything past the retention period
+func (m *MetricsCollector) Maintain(now time.Time) error {
+	for _, tier := range metricsTiers {
+		if _, err := m.metricsRepo.Downsample(now.Add(-tier.age), tier.bucket); err != nil {
+			return fmt.Errorf("failed to downsample metrics: %w", err)
+		}
+	}
+
+	if err := m.metricsRepo.Cleanup(m.retentionHours()); err != nil {
+		return fmt.Errorf("failed to clean up metrics: %w", err)
+	}
+	return nil
+}
+
+// collectContainerMetrics samples all running containers of one Podman
+// instance with a single batched stats call
+func collectContainerMetrics(ctx context.Context, svc *PodmanService, now time.Time) ([]models.ContainerMetrics, error) {
+	stats, err := svc.GetAllContainerStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(stats) == 0 {
+		return nil, nil
+	}
+
+	// The CLI reports truncated IDs; map them to full IDs so samples line up
+	// with what the API (and the metrics endpoint) use
+	var running []podmanContainer
+	for _, s := range stats {
+		if len(s.ContainerID) < 64 {
+			running, err = svc.listContainers(ctx, map[string][]string{"status": {"running"}})
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	result := make([]models.ContainerMetrics, 0, len(stats))
+	for _, s := range stats {
+		containerID := s.ContainerID
+		for _, c := range running {
+			if strings.HasPrefix(c.ID, containerID) {
+				containerID = c.ID
+				break
+			}
+		}
+
+		result = append(result, models.ContainerMetrics{
+			ContainerID: containerID,
+			Timestamp:   now,
+			CPUPercent:  s.CPUPercent,
+			MemoryUsed:  s.MemoryUsed,
+			MemoryLimit: s.MemoryLimit,
+			NetworkRx:   s.NetworkRx,
+			NetworkTx:   s.NetworkTx,
+			BlockRead:   s.BlockRead,
+			BlockWrite:  s.BlockWrite,
+		})
+	}
+
+	return result, nil
+}
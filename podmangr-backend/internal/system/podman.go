@@ -4,11 +4,13 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
+	"os/user"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -20,11 +22,18 @@ import (
 // podmanDebug controls whether Podman command execution is logged
 var podmanDebug = os.Getenv("PODMANGR_PODMAN_DEBUG") == "true"
 
+// podmanForceCLI disables the REST API client and always shells out to podman
+var podmanForceCLI = os.Getenv("PODMANGR_PODMAN_TRANSPORT") == "cli"
+
 // PodmanService provides operations for Podman container management
 type PodmanService struct {
 	// targetUser is the user whose Podman we should query (for rootless mode)
 	// If empty and running as root, will use root's Podman
 	targetUser string
+
+	// client talks to the Podman REST API socket. When nil (or when the
+	// socket cannot be reached) operations fall back to the podman CLI.
+	client *PodmanClient
 }
 
 // NewPodmanService creates a new PodmanService
@@ -46,9 +55,12 @@ func NewPodmanService() *PodmanService {
 		}
 	}
 
-	return &PodmanService{
-		targetUser: targetUser,
+	socketPath := os.Getenv("PODMANGR_PODMAN_SOCKET")
+	if socketPath == "" {
+		socketPath = podmanSocketPath(targetUser)
 	}
+
+	return NewPodmanServiceWithSocket(targetUser, socketPath)
 }
 
 // detectPodmanUser attempts to find a user with active Podman containers
@@ -94,9 +106,66 @@ func detectPodmanUser() string {
 
 // NewPodmanServiceWithUser creates a PodmanService targeting a specific user
 func NewPodmanServiceWithUser(user string) *PodmanService {
-	return &PodmanService{
-		targetUser: user,
+	return NewPodmanServiceWithSocket(user, podmanSocketPath(user))
+}
+
+// NewPodmanServiceWithSocket creates a PodmanService that talks to the REST API
+// at socketPath, falling back to the CLI (as targetUser) if the socket is missing
+func NewPodmanServiceWithSocket(targetUser, socketPath string) *PodmanService {
+	svc := &PodmanService{
+		targetUser: targetUser,
+	}
+
+	if !podmanForceCLI && socketPath != "" {
+		if info, err := os.Stat(socketPath); err == nil && info.Mode()&os.ModeSocket != 0 {
+			svc.client = NewPodmanClient(socketPath)
+		}
+	}
+
+	return svc
+}
+
+// podmanSocketPath returns the conventional API socket location for a user's Podman.
+// An empty user means the Podman instance of the current process.
+func podmanSocketPath(targetUser string) string {
+	if targetUser != "" {
+		u, err := user.Lookup(targetUser)
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprintf("/run/user/%s/podman/podman.sock", u.Uid)
+	}
+
+	if os.Getuid() == 0 {
+		return "/run/podman/podman.sock"
+	}
+
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "podman", "podman.sock")
+	}
+	return fmt.Sprintf("/run/user/%d/podman/podman.sock", os.Getuid())
+}
+
+// GetSocketPath returns the REST API socket in use, or "" when using the CLI
+func (p *PodmanService) GetSocketPath() string {
+	if p.client == nil {
+		return ""
+	}
+	return p.client.SocketPath()
+}
+
+// useCLI reports whether a failed API call should be retried through the CLI.
+// Structured API errors (not found, conflict, ...) are returned as-is; only
+// transport failures such as a stopped socket service trigger the fallback.
+func (p *PodmanService) useCLI(ctx context.Context, err error) bool {
+	var apiErr *PodmanAPIError
+	if errors.As(err, &apiErr) || ctx.Err() != nil {
+		return false
+	}
+	if podmanDebug {
+		log.Printf("[PODMAN] API unavailable on %s, falling back to CLI: %v", p.client.SocketPath(), err)
 	}
+	return true
 }
 
 // GetTargetUser returns the user whose Podman is being used
@@ -166,6 +235,16 @@ func (p *PodmanService) podmanCmd(ctx context.Context, args ...string) ([]byte,
 
 // CheckPodman verifies Podman is installed and returns version info
 func (p *PodmanService) CheckPodman(ctx context.Context) (string, error) {
+	if p.client != nil {
+		version, err := p.client.Version(ctx)
+		if err == nil {
+			return version, nil
+		}
+		if !p.useCLI(ctx, err) {
+			return "", fmt.Errorf("podman not available: %w", err)
+		}
+	}
+
 	output, err := p.podmanCmd(ctx, "version", "--format", "json")
 	if err != nil {
 		return "", fmt.Errorf("podman not available: %w", err)
@@ -188,6 +267,7 @@ type podmanContainer struct {
 	ID      string   `json:"Id"`
 	Names   []string `json:"Names"`
 	Image   string   `json:"Image"`
+	ImageID string   `json:"ImageID"`
 	State   string   `json:"State"`
 	Status  string   `json:"Status"`
 	Created int64    `json:"Created"` // Unix timestamp
@@ -203,16 +283,11 @@ type podmanContainer struct {
 
 // ListContainers returns all containers (running and stopped)
 func (p *PodmanService) ListContainers(ctx context.Context) ([]models.ContainerListItem, error) {
-	output, err := p.podmanCmd(ctx, "ps", "-a", "--format", "json")
+	containers, err := p.listContainers(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	var containers []podmanContainer
-	if err := json.Unmarshal(output, &containers); err != nil {
-		return nil, fmt.Errorf("failed to parse container list: %w", err)
-	}
-
 	result := make([]models.ContainerListItem, 0, len(containers))
 	for _, c := range containers {
 		name := ""
@@ -257,6 +332,39 @@ func (p *PodmanService) ListContainers(ctx context.Context) ([]models.ContainerL
 	return result, nil
 }
 
+// listContainers returns raw container entries (running and stopped) matching
+// the given podman ps filters, using the REST API when available
+func (p *PodmanService) listContainers(ctx context.Context, filters map[string][]string) ([]podmanContainer, error) {
+	if p.client != nil {
+		containers, err := p.client.ListContainers(ctx, true, filters)
+		if err == nil {
+			return containers, nil
+		}
+		if !p.useCLI(ctx, err) {
+			return nil, err
+		}
+	}
+
+	args := []string{"ps", "-a", "--format", "json"}
+	for key, values := range filters {
+		for _, value := range values {
+			args = append(args, "--filter", fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+
+	output, err := p.podmanCmd(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var containers []podmanContainer
+	if err := json.Unmarshal(output, &containers); err != nil {
+		return nil, fmt.Errorf("failed to parse container list: %w", err)
+	}
+
+	return containers, nil
+}
+
 // normalizeImageName ensures image names have a registry prefix
 // If no registry is specified, defaults to docker.io
 func normalizeImageName(image string) string {
@@ -267,6 +375,7 @@ func normalizeImageName(image string) string {
 	// - "docker.io/nginx" -> "docker.io/nginx" (unchanged)
 	// - "ghcr.io/user/image" -> "ghcr.io/user/image" (unchanged)
 	// - "localhost:5000/myimage" -> "localhost:5000/myimage" (unchanged)
+	// - "localhost/myimage" -> "localhost/myimage" (unchanged)
 
 	parts := strings.Split(image, "/")
 
@@ -277,9 +386,10 @@ func normalizeImageName(image string) string {
 		return "docker.io/" + image
 	}
 
-	// Check if first part looks like a registry (has . or :)
+	// Check if first part looks like a registry (has . or :, or is localhost,
+	// where Podman keeps images it built)
 	firstPart := parts[0]
-	if !strings.Contains(firstPart, ".") && !strings.Contains(firstPart, ":") {
+	if !strings.Contains(firstPart, ".") && !strings.Contains(firstPart, ":") && firstPart != "localhost" {
 		// First part is likely a namespace, not a registry
 		// Example: "library/nginx" -> "docker.io/library/nginx"
 		return "docker.io/" + image
@@ -316,6 +426,7 @@ type podmanInspect struct {
 	ID      string `json:"Id"`
 	Created string `json:"Created"`
 	Name    string `json:"Name"`
+	Pod     string `json:"Pod"` // ID of the pod the container belongs to, if any
 	State   struct {
 		Status     string `json:"Status"`
 		Running    bool   `json:"Running"`
@@ -328,16 +439,27 @@ type podmanInspect struct {
 		Error      string `json:"Error"`
 		StartedAt  string `json:"StartedAt"`
 		FinishedAt string `json:"FinishedAt"`
+		Health     *struct {
+			Status        string `json:"Status"` // starting, healthy, unhealthy
+			FailingStreak int    `json:"FailingStreak"`
+		} `json:"Health"`
 	} `json:"State"`
 	Config struct {
-		Hostname   string            `json:"Hostname"`
-		User       string            `json:"User"`
-		Env        []string          `json:"Env"`
-		Cmd        []string          `json:"Cmd"`
-		Image      string            `json:"Image"`
-		WorkingDir string            `json:"WorkingDir"`
-		Entrypoint []string          `json:"Entrypoint"`
-		Labels     map[string]string `json:"Labels"`
+		Hostname    string            `json:"Hostname"`
+		User        string            `json:"User"`
+		Env         []string          `json:"Env"`
+		Cmd         []string          `json:"Cmd"`
+		Image       string            `json:"Image"`
+		WorkingDir  string            `json:"WorkingDir"`
+		Entrypoint  []string          `json:"Entrypoint"`
+		Labels      map[string]string `json:"Labels"`
+		Healthcheck *struct {
+			Test        []string `json:"Test"`
+			Interval    int64    `json:"Interval"` // Nanoseconds
+			Timeout     int64    `json:"Timeout"`
+			StartPeriod int64    `json:"StartPeriod"`
+			Retries     int      `json:"Retries"`
+		} `json:"Healthcheck"`
 	} `json:"Config"`
 	HostConfig struct {
 		RestartPolicy struct {
@@ -348,28 +470,53 @@ type podmanInspect struct {
 			HostIP   string `json:"HostIp"`
 			HostPort string `json:"HostPort"`
 		} `json:"PortBindings"`
-		Binds       []string `json:"Binds"`
-		NetworkMode string   `json:"NetworkMode"`
-		Memory      int64    `json:"Memory"`
-		NanoCpus    int64    `json:"NanoCpus"`
+		Binds          []string          `json:"Binds"`
+		NetworkMode    string            `json:"NetworkMode"`
+		Memory         int64             `json:"Memory"`
+		NanoCpus       int64             `json:"NanoCpus"`
+		CapAdd         []string          `json:"CapAdd"`
+		CapDrop        []string          `json:"CapDrop"`
+		Privileged     bool              `json:"Privileged"`
+		ReadonlyRootfs bool              `json:"ReadonlyRootfs"`
+		SecurityOpt    []string          `json:"SecurityOpt"`
+		ExtraHosts     []string          `json:"ExtraHosts"`
+		DNS            []string          `json:"Dns"`
+		Tmpfs          map[string]string `json:"Tmpfs"`
+		Devices        []struct {
+			PathOnHost      string `json:"PathOnHost"`
+			PathInContainer string `json:"PathInContainer"`
+		} `json:"Devices"`
 	} `json:"HostConfig"`
 	Mounts []struct {
 		Type        string `json:"Type"`
+		Name        string `json:"Name"` // Volume name for named volumes
 		Source      string `json:"Source"`
 		Destination string `json:"Destination"`
 		RW          bool   `json:"RW"`
 	} `json:"Mounts"`
 	NetworkSettings struct {
 		Networks map[string]struct {
-			IPAddress string `json:"IPAddress"`
-			Gateway   string `json:"Gateway"`
-			MacAddr   string `json:"MacAddress"`
+			IPAddress string   `json:"IPAddress"`
+			Gateway   string   `json:"Gateway"`
+			MacAddr   string   `json:"MacAddress"`
+			Aliases   []string `json:"Aliases"`
 		} `json:"Networks"`
 	} `json:"NetworkSettings"`
+	RestartCount int `json:"RestartCount"`
 }
 
 // InspectContainer returns detailed information about a container
 func (p *PodmanService) InspectContainer(ctx context.Context, containerID string) (*podmanInspect, error) {
+	if p.client != nil {
+		inspect, err := p.client.InspectContainer(ctx, containerID)
+		if err == nil {
+			return inspect, nil
+		}
+		if !p.useCLI(ctx, err) {
+			return nil, err
+		}
+	}
+
 	output, err := p.podmanCmd(ctx, "inspect", containerID, "--format", "json")
 	if err != nil {
 		return nil, err
@@ -389,6 +536,13 @@ func (p *PodmanService) InspectContainer(ctx context.Context, containerID string
 
 // GetContainerSize returns the container's disk usage (writable layer size and total size)
 func (p *PodmanService) GetContainerSize(ctx context.Context, containerID string) (sizeRw int64, sizeRootFs int64) {
+	if p.client != nil {
+		sizeRw, sizeRootFs, err := p.client.ContainerSize(ctx, containerID)
+		if err == nil || !p.useCLI(ctx, err) {
+			return sizeRw, sizeRootFs
+		}
+	}
+
 	// Use podman inspect with size flag to get container size
 	output, err := p.podmanCmd(ctx, "inspect", containerID, "--size", "--format", "json")
 	if err != nil {
@@ -518,12 +672,26 @@ func (p *PodmanService) CreateContainer(ctx context.Context, req *models.CreateC
 
 // StartContainer starts a stopped container
 func (p *PodmanService) StartContainer(ctx context.Context, containerID string) error {
+	if p.client != nil {
+		err := p.client.ContainerAction(ctx, containerID, "start", 0)
+		if err == nil || !p.useCLI(ctx, err) {
+			return err
+		}
+	}
+
 	_, err := p.podmanCmd(ctx, "start", containerID)
 	return err
 }
 
 // StopContainer stops a running container
 func (p *PodmanService) StopContainer(ctx context.Context, containerID string, timeout int) error {
+	if p.client != nil {
+		err := p.client.ContainerAction(ctx, containerID, "stop", timeout)
+		if err == nil || !p.useCLI(ctx, err) {
+			return err
+		}
+	}
+
 	args := []string{"stop"}
 	if timeout > 0 {
 		args = append(args, "-t", strconv.Itoa(timeout))
@@ -535,6 +703,13 @@ func (p *PodmanService) StopContainer(ctx context.Context, containerID string, t
 
 // RestartContainer restarts a container
 func (p *PodmanService) RestartContainer(ctx context.Context, containerID string, timeout int) error {
+	if p.client != nil {
+		err := p.client.ContainerAction(ctx, containerID, "restart", timeout)
+		if err == nil || !p.useCLI(ctx, err) {
+			return err
+		}
+	}
+
 	args := []string{"restart"}
 	if timeout > 0 {
 		args = append(args, "-t", strconv.Itoa(timeout))
@@ -546,6 +721,13 @@ func (p *PodmanService) RestartContainer(ctx context.Context, containerID string
 
 // RemoveContainer removes a container
 func (p *PodmanService) RemoveContainer(ctx context.Context, containerID string, force bool) error {
+	if p.client != nil {
+		err := p.client.RemoveContainer(ctx, containerID, force)
+		if err == nil || !p.useCLI(ctx, err) {
+			return err
+		}
+	}
+
 	args := []string{"rm"}
 	if force {
 		args = append(args, "-f")
@@ -608,11 +790,68 @@ func (l *logReader) Close() error {
 
 // GetContainerStats gets real-time stats for a container
 func (p *PodmanService) GetContainerStats(ctx context.Context, containerID string) (*models.ContainerStats, error) {
+	if p.client != nil {
+		stats, err := p.client.ContainerStats(ctx, []string{containerID})
+		if err == nil {
+			if len(stats) == 0 {
+				return nil, fmt.Errorf("no stats available for container: %s", containerID)
+			}
+			result := stats[0].toModel()
+			result.ContainerID = containerID
+			return result, nil
+		}
+		if !p.useCLI(ctx, err) {
+			return nil, err
+		}
+	}
+
 	output, err := p.podmanCmd(ctx, "stats", containerID, "--no-stream", "--format", "json")
 	if err != nil {
 		return nil, err
 	}
 
+	stats, err := parseStatsOutput(output)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stats) == 0 {
+		return nil, fmt.Errorf("no stats available for container: %s", containerID)
+	}
+
+	result := &stats[0]
+	result.ContainerID = containerID
+	return result, nil
+}
+
+// GetAllContainerStats returns one stats sample for every running container
+// using a single API call (or a single podman stats invocation).
+// ContainerID is the full ID when using the API; the CLI reports short IDs.
+func (p *PodmanService) GetAllContainerStats(ctx context.Context) ([]models.ContainerStats, error) {
+	if p.client != nil {
+		stats, err := p.client.ContainerStats(ctx, nil)
+		if err == nil {
+			result := make([]models.ContainerStats, 0, len(stats))
+			for _, s := range stats {
+				result = append(result, *s.toModel())
+			}
+			return result, nil
+		}
+		if !p.useCLI(ctx, err) {
+			return nil, err
+		}
+	}
+
+	output, err := p.podmanCmd(ctx, "stats", "--no-stream", "--format", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseStatsOutput(output)
+}
+
+// parseStatsOutput parses the JSON output of podman stats --format json
+func parseStatsOutput(output []byte) ([]models.ContainerStats, error) {
 	// Podman stats JSON uses snake_case field names
 	var stats []struct {
 		ID         string `json:"id"`
@@ -628,34 +867,50 @@ func (p *PodmanService) GetContainerStats(ctx context.Context, containerID strin
 		return nil, fmt.Errorf("failed to parse stats: %w", err)
 	}
 
-	if len(stats) == 0 {
-		return nil, fmt.Errorf("no stats available for container: %s", containerID)
-	}
+	result := make([]models.ContainerStats, 0, len(stats))
+	for _, s := range stats {
+		entry := models.ContainerStats{
+			ContainerID: s.ID,
+		}
 
-	s := stats[0]
-	result := &models.ContainerStats{
-		ContainerID: containerID,
-	}
+		// Parse CPU percentage (e.g., "0.83%")
+		entry.CPUPercent = parsePercentage(s.CPUPercent)
 
-	// Parse CPU percentage (e.g., "0.83%")
-	result.CPUPercent = parsePercentage(s.CPUPercent)
+		// Parse memory (e.g., "88.65MB / 7.971GB")
+		entry.MemoryUsed, entry.MemoryLimit = parseMemoryUsage(s.MemUsage)
+		entry.MemoryPct = parsePercentage(s.MemPercent)
 
-	// Parse memory (e.g., "88.65MB / 7.971GB")
-	result.MemoryUsed, result.MemoryLimit = parseMemoryUsage(s.MemUsage)
-	result.MemoryPct = parsePercentage(s.MemPercent)
+		// Parse network I/O (e.g., "6.345kB / 6.338kB")
+		entry.NetworkRx, entry.NetworkTx = parseIOStats(s.NetIO)
 
-	// Parse network I/O (e.g., "6.345kB / 6.338kB")
-	result.NetworkRx, result.NetworkTx = parseIOStats(s.NetIO)
+		// Parse block I/O (e.g., "0B / 0B")
+		entry.BlockRead, entry.BlockWrite = parseIOStats(s.BlockIO)
 
-	// Parse block I/O (e.g., "0B / 0B")
-	result.BlockRead, result.BlockWrite = parseIOStats(s.BlockIO)
+		// Parse PIDs
+		entry.PIDs, _ = strconv.Atoi(s.PIDs)
 
-	// Parse PIDs
-	result.PIDs, _ = strconv.Atoi(s.PIDs)
+		result = append(result, entry)
+	}
 
 	return result, nil
 }
 
+// toModel converts a libpod stats entry into our ContainerStats type
+func (s libpodStats) toModel() *models.ContainerStats {
+	return &models.ContainerStats{
+		ContainerID: s.ContainerID,
+		CPUPercent:  s.CPU,
+		MemoryUsed:  int64(s.MemUsage),
+		MemoryLimit: int64(s.MemLimit),
+		MemoryPct:   s.MemPerc,
+		NetworkRx:   int64(s.NetInput),
+		NetworkTx:   int64(s.NetOutput),
+		BlockRead:   int64(s.BlockInput),
+		BlockWrite:  int64(s.BlockOutput),
+		PIDs:        int(s.PIDs),
+	}
+}
+
 // parsePercentage parses a percentage string like "2.5%" into a float64
 func parsePercentage(s string) float64 {
 	s = strings.TrimSuffix(s, "%")
@@ -727,20 +982,16 @@ type podmanImage struct {
 	Size       int64    `json:"Size"`
 	Created    int64    `json:"Created"`
 	Containers int      `json:"Containers"`
+	Digest     string   `json:"Digest"`
 }
 
 // ListImages returns all container images
 func (p *PodmanService) ListImages(ctx context.Context) ([]models.Image, error) {
-	output, err := p.podmanCmd(ctx, "images", "--format", "json")
+	images, err := p.listImages(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var images []podmanImage
-	if err := json.Unmarshal(output, &images); err != nil {
-		return nil, fmt.Errorf("failed to parse image list: %w", err)
-	}
-
 	result := make([]models.Image, 0, len(images))
 	for _, img := range images {
 		repo := img.Repository
@@ -760,17 +1011,44 @@ func (p *PodmanService) ListImages(ctx context.Context) ([]models.Image, error)
 			Size:       img.Size,
 			Created:    time.Unix(img.Created, 0),
 			Containers: img.Containers,
+			Digest:     img.Digest,
 		})
 	}
 
 	return result, nil
 }
 
+// listImages returns the images as Podman lists them
+func (p *PodmanService) listImages(ctx context.Context) ([]podmanImage, error) {
+	var images []podmanImage
+	var err error
+	if p.client != nil {
+		images, err = p.client.ListImages(ctx)
+		if err != nil && !p.useCLI(ctx, err) {
+			return nil, err
+		}
+	}
+
+	if p.client == nil || err != nil {
+		output, err := p.podmanCmd(ctx, "images", "--format", "json")
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(output, &images); err != nil {
+			return nil, fmt.Errorf("failed to parse image list: %w", err)
+		}
+	}
+
+	return images, nil
+}
+
 // PullImage pulls an image from a registry
 func (p *PodmanService) PullImage(ctx context.Context, image string) error {
 	// Normalize image name to include registry prefix
 	normalizedImage := normalizeImageName(image)
-	_, err := p.podmanCmd(ctx, "pull", normalizedImage)
+	args := append([]string{"pull"}, p.registryArgs(normalizedImage)...)
+	_, err := p.podmanCmd(ctx, append(args, normalizedImage)...)
 	return err
 }
 
@@ -778,12 +1056,15 @@ func (p *PodmanService) PullImage(ctx context.Context, image string) error {
 func (p *PodmanService) PullImageWithProgress(ctx context.Context, image string, output chan<- string) error {
 	normalizedImage := normalizeImageName(image)
 
+	args := append([]string{"pull"}, p.registryArgs(normalizedImage)...)
+	args = append(args, normalizedImage)
+
 	// Build command with rootless support
 	var cmd *exec.Cmd
 	if os.Getuid() == 0 && p.targetUser != "" {
-		cmd = exec.CommandContext(ctx, "sudo", "-u", p.targetUser, "podman", "pull", normalizedImage)
+		cmd = exec.CommandContext(ctx, "sudo", append([]string{"-u", p.targetUser, "podman"}, args...)...)
 	} else {
-		cmd = exec.CommandContext(ctx, "podman", "pull", normalizedImage)
+		cmd = exec.CommandContext(ctx, "podman", args...)
 	}
 
 	// Get stdout and stderr pipes
@@ -839,6 +1120,13 @@ func (p *PodmanService) PullImageWithProgress(ctx context.Context, image string,
 
 // ContainerExists checks if a container with the given name exists
 func (p *PodmanService) ContainerExists(ctx context.Context, name string) (bool, error) {
+	if p.client != nil {
+		exists, err := p.client.ContainerExists(ctx, name)
+		if err == nil || !p.useCLI(ctx, err) {
+			return exists, err
+		}
+	}
+
 	output, err := p.podmanCmd(ctx, "ps", "-a", "--filter", fmt.Sprintf("name=^%s$", name), "--format", "{{.Names}}")
 	if err != nil {
 		return false, err
@@ -849,6 +1137,13 @@ func (p *PodmanService) ContainerExists(ctx context.Context, name string) (bool,
 // ImageExists checks if an image exists locally
 func (p *PodmanService) ImageExists(ctx context.Context, image string) bool {
 	normalizedImage := normalizeImageName(image)
+	if p.client != nil {
+		exists, err := p.client.ImageExists(ctx, normalizedImage)
+		if err == nil || !p.useCLI(ctx, err) {
+			return exists
+		}
+	}
+
 	_, err := p.podmanCmd(ctx, "image", "exists", normalizedImage)
 	return err == nil
 }
@@ -866,24 +1161,37 @@ func (p *PodmanService) RemoveImage(ctx context.Context, imageID string, force b
 
 // Volume operations
 
+// podmanVolume represents a volume as listed by podman volume ls
+type podmanVolume struct {
+	Name       string            `json:"Name"`
+	Driver     string            `json:"Driver"`
+	MountPoint string            `json:"Mountpoint"`
+	CreatedAt  string            `json:"CreatedAt"`
+	Labels     map[string]string `json:"Labels"`
+	Scope      string            `json:"Scope"`
+	Options    map[string]string `json:"Options"`
+}
+
 // ListVolumes returns all Podman volumes
 func (p *PodmanService) ListVolumes(ctx context.Context) ([]models.Volume, error) {
-	output, err := p.podmanCmd(ctx, "volume", "ls", "--format", "json")
-	if err != nil {
-		return nil, err
+	var volumes []podmanVolume
+	var err error
+	if p.client != nil {
+		volumes, err = p.client.ListVolumes(ctx)
+		if err != nil && !p.useCLI(ctx, err) {
+			return nil, err
+		}
 	}
 
-	var volumes []struct {
-		Name       string            `json:"Name"`
-		Driver     string            `json:"Driver"`
-		MountPoint string            `json:"Mountpoint"`
-		CreatedAt  string            `json:"CreatedAt"`
-		Labels     map[string]string `json:"Labels"`
-		Scope      string            `json:"Scope"`
-		Options    map[string]string `json:"Options"`
-	}
-	if err := json.Unmarshal(output, &volumes); err != nil {
-		return nil, fmt.Errorf("failed to parse volume list: %w", err)
+	if p.client == nil || err != nil {
+		output, err := p.podmanCmd(ctx, "volume", "ls", "--format", "json")
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(output, &volumes); err != nil {
+			return nil, fmt.Errorf("failed to parse volume list: %w", err)
+		}
 	}
 
 	result := make([]models.Volume, 0, len(volumes))
@@ -935,6 +1243,35 @@ func (p *PodmanService) RemoveVolume(ctx context.Context, name string, force boo
 	return err
 }
 
+// InspectVolume returns the details of a single volume
+func (p *PodmanService) InspectVolume(ctx context.Context, name string) (*podmanVolume, error) {
+	if p.client != nil {
+		volume, err := p.client.InspectVolume(ctx, name)
+		if err == nil {
+			return volume, nil
+		}
+		if !p.useCLI(ctx, err) {
+			return nil, err
+		}
+	}
+
+	output, err := p.podmanCmd(ctx, "volume", "inspect", name, "--format", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var volumes []podmanVolume
+	if err := json.Unmarshal(output, &volumes); err != nil {
+		return nil, fmt.Errorf("failed to parse volume inspect: %w", err)
+	}
+
+	if len(volumes) == 0 {
+		return nil, fmt.Errorf("volume not found: %s", name)
+	}
+
+	return &volumes[0], nil
+}
+
 // Network operations
 
 // ListNetworks returns all Podman networks
@@ -1030,6 +1367,52 @@ func (p *PodmanService) RemoveNetwork(ctx context.Context, name string, force bo
 	return err
 }
 
+// podmanNetworkInspect represents a network as reported by podman network inspect
+type podmanNetworkInspect struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Driver      string            `json:"driver"`
+	Internal    bool              `json:"internal"`
+	IPv6Enabled bool              `json:"ipv6_enabled"`
+	DNSEnabled  bool              `json:"dns_enabled"`
+	Labels      map[string]string `json:"labels"`
+	Options     map[string]string `json:"options"`
+	Subnets     []struct {
+		Subnet  string `json:"subnet"`
+		Gateway string `json:"gateway"`
+	} `json:"subnets"`
+	IPAMOptions map[string]string `json:"ipam_options"`
+}
+
+// InspectNetwork returns the details of a single network
+func (p *PodmanService) InspectNetwork(ctx context.Context, name string) (*podmanNetworkInspect, error) {
+	if p.client != nil {
+		network, err := p.client.InspectNetwork(ctx, name)
+		if err == nil {
+			return network, nil
+		}
+		if !p.useCLI(ctx, err) {
+			return nil, err
+		}
+	}
+
+	output, err := p.podmanCmd(ctx, "network", "inspect", name, "--format", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var networks []podmanNetworkInspect
+	if err := json.Unmarshal(output, &networks); err != nil {
+		return nil, fmt.Errorf("failed to parse network inspect: %w", err)
+	}
+
+	if len(networks) == 0 {
+		return nil, fmt.Errorf("network not found: %s", name)
+	}
+
+	return &networks[0], nil
+}
+
 // Exec runs a command inside a container
 func (p *PodmanService) Exec(ctx context.Context, containerID string, cmd []string, interactive bool) ([]byte, error) {
 	args := []string{"exec"}
@@ -1044,6 +1427,22 @@ func (p *PodmanService) Exec(ctx context.Context, containerID string, cmd []stri
 
 // GetLogs returns container logs as a slice of strings (for REST API)
 func (p *PodmanService) GetLogs(ctx context.Context, containerID string, tail string, timestamps bool) ([]string, error) {
+	if p.client != nil {
+		body, err := p.client.ContainerLogs(ctx, containerID, tail, false, timestamps)
+		if err == nil {
+			defer body.Close()
+			lines := make([]string, 0)
+			err = readLogFrames(body, func(_, line string) bool {
+				lines = append(lines, line)
+				return true
+			})
+			return lines, err
+		}
+		if !p.useCLI(ctx, err) {
+			return nil, err
+		}
+	}
+
 	args := []string{"logs"}
 	if tail != "" && tail != "all" {
 		args = append(args, "--tail", tail)
@@ -1115,6 +1514,34 @@ func (p *PodmanService) ExecInteractive(ctx context.Context, containerID string)
 
 // StreamLogs streams logs to a channel (for WebSocket)
 func (p *PodmanService) StreamLogs(ctx context.Context, containerID string, tail int, logChan chan<- models.ContainerLog) error {
+	if p.client != nil {
+		tailArg := ""
+		if tail > 0 {
+			tailArg = strconv.Itoa(tail)
+		}
+		body, err := p.client.ContainerLogs(ctx, containerID, tailArg, true, true)
+		if err == nil {
+			defer body.Close()
+			readLogFrames(body, func(stream, line string) bool {
+				ts, msg := parseLogLine(line)
+				select {
+				case logChan <- models.ContainerLog{
+					Timestamp: ts,
+					Stream:    stream,
+					Message:   msg,
+				}:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			})
+			return ctx.Err()
+		}
+		if !p.useCLI(ctx, err) {
+			return err
+		}
+	}
+
 	args := []string{"logs", "-f", "--timestamps"}
 	if tail > 0 {
 		args = append(args, "--tail", strconv.Itoa(tail))
@@ -1272,199 +1699,28 @@ func (p *PodmanService) InstallPackage(ctx context.Context, packageName string,
 
 // PauseContainer pauses a running container
 func (p *PodmanService) PauseContainer(ctx context.Context, containerID string) error {
+	if p.client != nil {
+		err := p.client.ContainerAction(ctx, containerID, "pause", 0)
+		if err == nil || !p.useCLI(ctx, err) {
+			return err
+		}
+	}
+
 	_, err := p.podmanCmd(ctx, "pause", containerID)
 	return err
 }
 
 // UnpauseContainer unpauses a paused container
 func (p *PodmanService) UnpauseContainer(ctx context.Context, containerID string) error {
-	_, err := p.podmanCmd(ctx, "unpause", containerID)
-	return err
-}
-
-// Compose operations
-
-// ComposeUp deploys a compose stack
-func (p *PodmanService) ComposeUp(ctx context.Context, projectDir string, projectName string, outputChan chan<- string) error {
-	args := []string{"-f", projectDir + "/docker-compose.yml"}
-	if projectName != "" {
-		args = append(args, "-p", projectName)
-	}
-	args = append(args, "up", "-d")
-
-	cmd := exec.CommandContext(ctx, "podman-compose", args...)
-	cmd.Dir = projectDir
-
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stdout pipe: %w", err)
-	}
-
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stderr pipe: %w", err)
-	}
-
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start compose up: %w", err)
-	}
-
-	// Read stdout
-	go func() {
-		scanner := bufio.NewScanner(stdout)
-		for scanner.Scan() {
-			if outputChan != nil {
-				outputChan <- scanner.Text()
-			}
-		}
-	}()
-
-	// Read stderr
-	go func() {
-		scanner := bufio.NewScanner(stderr)
-		for scanner.Scan() {
-			if outputChan != nil {
-				outputChan <- scanner.Text()
-			}
-		}
-	}()
-
-	return cmd.Wait()
-}
-
-// ComposeDown stops and removes a compose stack
-func (p *PodmanService) ComposeDown(ctx context.Context, projectDir string, projectName string, removeVolumes bool, outputChan chan<- string) error {
-	args := []string{"-f", projectDir + "/docker-compose.yml"}
-	if projectName != "" {
-		args = append(args, "-p", projectName)
-	}
-	args = append(args, "down")
-	if removeVolumes {
-		args = append(args, "-v")
-	}
-
-	cmd := exec.CommandContext(ctx, "podman-compose", args...)
-	cmd.Dir = projectDir
-
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stdout pipe: %w", err)
-	}
-
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stderr pipe: %w", err)
-	}
-
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start compose down: %w", err)
-	}
-
-	// Read output
-	go func() {
-		scanner := bufio.NewScanner(stdout)
-		for scanner.Scan() {
-			if outputChan != nil {
-				outputChan <- scanner.Text()
-			}
-		}
-	}()
-
-	go func() {
-		scanner := bufio.NewScanner(stderr)
-		for scanner.Scan() {
-			if outputChan != nil {
-				outputChan <- scanner.Text()
-			}
+	if p.client != nil {
+		err := p.client.ContainerAction(ctx, containerID, "unpause", 0)
+		if err == nil || !p.useCLI(ctx, err) {
+			return err
 		}
-	}()
-
-	return cmd.Wait()
-}
-
-// ComposeStop stops a compose stack (without removing)
-func (p *PodmanService) ComposeStop(ctx context.Context, projectDir string, projectName string) error {
-	args := []string{"-f", projectDir + "/docker-compose.yml"}
-	if projectName != "" {
-		args = append(args, "-p", projectName)
-	}
-	args = append(args, "stop")
-
-	cmd := exec.CommandContext(ctx, "podman-compose", args...)
-	cmd.Dir = projectDir
-	return cmd.Run()
-}
-
-// ComposeStart starts a stopped compose stack
-func (p *PodmanService) ComposeStart(ctx context.Context, projectDir string, projectName string) error {
-	args := []string{"-f", projectDir + "/docker-compose.yml"}
-	if projectName != "" {
-		args = append(args, "-p", projectName)
-	}
-	args = append(args, "start")
-
-	cmd := exec.CommandContext(ctx, "podman-compose", args...)
-	cmd.Dir = projectDir
-	return cmd.Run()
-}
-
-// ComposeRestart restarts a compose stack
-func (p *PodmanService) ComposeRestart(ctx context.Context, projectDir string, projectName string) error {
-	args := []string{"-f", projectDir + "/docker-compose.yml"}
-	if projectName != "" {
-		args = append(args, "-p", projectName)
 	}
-	args = append(args, "restart")
 
-	cmd := exec.CommandContext(ctx, "podman-compose", args...)
-	cmd.Dir = projectDir
-	return cmd.Run()
-}
-
-// ComposePull pulls images for a compose stack
-func (p *PodmanService) ComposePull(ctx context.Context, projectDir string, projectName string, outputChan chan<- string) error {
-	args := []string{"-f", projectDir + "/docker-compose.yml"}
-	if projectName != "" {
-		args = append(args, "-p", projectName)
-	}
-	args = append(args, "pull")
-
-	cmd := exec.CommandContext(ctx, "podman-compose", args...)
-	cmd.Dir = projectDir
-
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stdout pipe: %w", err)
-	}
-
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stderr pipe: %w", err)
-	}
-
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start compose pull: %w", err)
-	}
-
-	go func() {
-		scanner := bufio.NewScanner(stdout)
-		for scanner.Scan() {
-			if outputChan != nil {
-				outputChan <- scanner.Text()
-			}
-		}
-	}()
-
-	go func() {
-		scanner := bufio.NewScanner(stderr)
-		for scanner.Scan() {
-			if outputChan != nil {
-				outputChan <- scanner.Text()
-			}
-		}
-	}()
-
-	return cmd.Wait()
+	_, err := p.podmanCmd(ctx, "unpause", containerID)
+	return err
 }
 
 // ImageConfig represents the configuration hints extracted from an image
@@ -1677,29 +1933,22 @@ func (p *PodmanService) InspectImageWithProgress(ctx context.Context, image stri
 // GetStackContainers returns containers belonging to a compose project
 func (p *PodmanService) GetStackContainers(ctx context.Context, projectName string) ([]models.StackContainer, error) {
 	// Try to find containers by compose project label first
-	output, err := p.podmanCmd(ctx, "ps", "-a", "--format", "json",
-		"--filter", fmt.Sprintf("label=com.docker.compose.project=%s", projectName))
+	containers, err := p.listContainers(ctx, map[string][]string{
+		"label": {fmt.Sprintf("com.docker.compose.project=%s", projectName)},
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	var containers []podmanContainer
-	if err := json.Unmarshal(output, &containers); err != nil {
-		return nil, fmt.Errorf("failed to parse container list: %w", err)
-	}
-
 	// If no containers found by label, try matching by container name prefix
 	// podman-compose often names containers as: <project>_<service>_<number>
 	if len(containers) == 0 {
-		allOutput, err := p.podmanCmd(ctx, "ps", "-a", "--format", "json")
+		allContainers, err := p.listContainers(ctx, nil)
 		if err == nil {
-			var allContainers []podmanContainer
-			if json.Unmarshal(allOutput, &allContainers) == nil {
-				prefix := projectName + "_"
-				for _, c := range allContainers {
-					if len(c.Names) > 0 && strings.HasPrefix(c.Names[0], prefix) {
-						containers = append(containers, c)
-					}
+			prefix := projectName + "_"
+			for _, c := range allContainers {
+				if len(c.Names) > 0 && strings.HasPrefix(c.Names[0], prefix) {
+					containers = append(containers, c)
 				}
 			}
 		}
@@ -1959,10 +2208,15 @@ func (p *PodmanService) GetContainerConfig(ctx context.Context, containerID stri
 		}
 	}
 
-	// Parse volume mounts
+	// Parse volume mounts; named volumes are referenced by name so a
+	// recreated container keeps using the volume rather than its mountpoint
 	for _, mount := range inspect.Mounts {
+		source := mount.Source
+		if mount.Type == "volume" && mount.Name != "" {
+			source = mount.Name
+		}
 		config.Volumes = append(config.Volumes, models.VolumeMount{
-			Source:   mount.Source,
+			Source:   source,
 			Target:   mount.Destination,
 			ReadOnly: !mount.RW,
 			Type:     mount.Type,
@@ -1978,139 +2232,252 @@ func (p *PodmanService) RenameContainer(ctx context.Context, containerID, newNam
 	return err
 }
 
-// BackupBindMounts creates a backup of bind mount directories
+// BackupMounts creates a backup of bind mount directories and named volumes
+// before an update. Older update backups of the container are removed if
+// overwrite is set and pruned to the configured retention otherwise.
 // Returns the backup info and any error
-func (p *PodmanService) BackupBindMounts(ctx context.Context, containerID, backupBasePath string, overwrite bool, progressChan chan<- string) (*models.ContainerBackup, error) {
+func (p *PodmanService) BackupMounts(ctx context.Context, containerID, backupBasePath string, overwrite bool, progressChan chan<- string) (*models.ContainerBackup, error) {
+	backup, err := p.CreateBackup(ctx, containerID, backupBasePath, nil, progressChan)
+	if err != nil {
+		return nil, err
+	}
+
+	// Older backups are only removed once the new one is complete
+	keep := LoadBackupSettings().MaxBackupsPerContainer
+	if overwrite {
+		keep = 1
+	}
+	removed, err := PruneBackups(backupBasePath, backup.ContainerName, keep)
+	for _, id := range removed {
+		if progressChan != nil {
+			progressChan <- fmt.Sprintf("Removed old backup: %s", id)
+		}
+	}
+	if err != nil {
+		log.Printf("Warning: failed to prune backups of %s: %v", backup.ContainerName, err)
+	}
+
+	return backup, nil
+}
+
+// CreateBackup writes a backup of a container's bind mounts and named volumes
+// to backupBasePath. Every mount is stored as a compressed tar archive, or as
+// deduplicated chunks if enabled in the backup settings, with a manifest of
+// per-file checksums. Metadata is stored with the backup as is.
+func (p *PodmanService) CreateBackup(ctx context.Context, containerID, backupBasePath string, metadata map[string]string, progressChan chan<- string) (*models.ContainerBackup, error) {
 	inspect, err := p.InspectContainer(ctx, containerID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to inspect container: %w", err)
 	}
 
 	containerName := strings.TrimPrefix(inspect.Name, "/")
-	timestamp := time.Now().Format("20060102-150405")
-	backupDir := fmt.Sprintf("%s/%s_%s", backupBasePath, containerName, timestamp)
-
-	// Check for existing backups if not overwriting
-	if !overwrite {
-		// Find existing backups for this container
-		entries, err := os.ReadDir(backupBasePath)
-		if err == nil {
-			for _, entry := range entries {
-				if entry.IsDir() && strings.HasPrefix(entry.Name(), containerName+"_") {
-					return nil, fmt.Errorf("existing backup found at %s/%s. Set overwrite_backup=true to replace it", backupBasePath, entry.Name())
-				}
-			}
-		}
-	} else {
-		// Remove existing backups for this container
-		entries, err := os.ReadDir(backupBasePath)
-		if err == nil {
-			for _, entry := range entries {
-				if entry.IsDir() && strings.HasPrefix(entry.Name(), containerName+"_") {
-					oldPath := fmt.Sprintf("%s/%s", backupBasePath, entry.Name())
-					if progressChan != nil {
-						progressChan <- fmt.Sprintf("Removing existing backup: %s", entry.Name())
-					}
-					os.RemoveAll(oldPath)
-				}
-			}
-		}
-	}
+	id := newBackupID(backupBasePath, containerName)
+	backupDir := fmt.Sprintf("%s/%s", backupBasePath, id)
 
 	// Create backup directory
 	if err := os.MkdirAll(backupDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create backup directory: %w", err)
 	}
 
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
 	backup := &models.ContainerBackup{
-		ID:            fmt.Sprintf("%s_%s", containerName, timestamp),
+		ID:            id,
 		ContainerID:   containerID,
 		ContainerName: containerName,
 		Image:         inspect.Config.Image,
 		BackupPath:    backupDir,
-		BackupType:    "bind",
 		Mounts:        make([]models.BackupMount, 0),
 		CreatedAt:     time.Now(),
-		Metadata:      make(map[string]string),
+		Metadata:      metadata,
 	}
 
-	var totalSize int64
-
-	// Backup each bind mount
-	for i, mount := range inspect.Mounts {
-		if mount.Type != "bind" {
-			continue
-		}
+	if err := p.writeBackup(ctx, backup, inspect, LoadBackupSettings().Deduplicate, progressChan); err != nil {
+		os.RemoveAll(backupDir)
+		return nil, err
+	}
 
-		if progressChan != nil {
-			progressChan <- fmt.Sprintf("Backing up mount %d: %s -> %s", i+1, mount.Source, mount.Destination)
-		}
+	return backup, nil
+}
 
-		// Create a subdirectory for this mount
-		mountBackupDir := fmt.Sprintf("%s/mount_%d", backupDir, i)
-		if err := os.MkdirAll(mountBackupDir, 0755); err != nil {
-			return nil, fmt.Errorf("failed to create mount backup dir: %w", err)
-		}
+// writeBackup stores every bind mount and named volume of a container in the
+// backup directory, followed by the manifest and backup metadata
+func (p *PodmanService) writeBackup(ctx context.Context, backup *models.ContainerBackup, inspect *podmanInspect, deduplicate bool, progressChan chan<- string) error {
+	backupStoreMu.RLock()
+	defer backupStoreMu.RUnlock()
 
-		// Use rsync or cp to copy the data
-		var copyCmd *exec.Cmd
-		sourcePath := mount.Source
-		if !strings.HasSuffix(sourcePath, "/") {
-			sourcePath += "/"
-		}
+	var chunks *chunkStore
+	if deduplicate {
+		chunks = newChunkStore(filepath.Dir(backup.BackupPath))
+	}
+	writer := newBackupWriter(backup.BackupPath, chunks)
 
-		// Check if rsync is available
-		if _, err := exec.LookPath("rsync"); err == nil {
-			copyCmd = exec.CommandContext(ctx, "rsync", "-av", "--progress", sourcePath, mountBackupDir+"/")
-		} else {
-			copyCmd = exec.CommandContext(ctx, "cp", "-a", sourcePath+".", mountBackupDir+"/")
-		}
+	var totalSize int64
 
-		output, err := copyCmd.CombinedOutput()
-		if err != nil {
-			return nil, fmt.Errorf("failed to backup mount %s: %w - %s", mount.Source, err, string(output))
-		}
+	// Backup each bind mount and named volume
+	for i, mount := range inspect.Mounts {
+		var backupMount *models.BackupMount
+		var source func(ctx context.Context, w io.Writer) error
 
-		// Get the size of the backup
-		var mountSize int64
-		err = filepath.Walk(mountBackupDir, func(_ string, info os.FileInfo, err error) error {
+		switch {
+		case mount.Type == "volume" && mount.Name != "":
+			if progressChan != nil {
+				progressChan <- fmt.Sprintf("Backing up volume %d: %s -> %s", i+1, mount.Name, mount.Destination)
+			}
+			volumeMount, err := p.volumeBackupMount(ctx, mount.Name, mount.Destination)
 			if err != nil {
 				return err
 			}
-			if !info.IsDir() {
-				mountSize += info.Size()
+			backupMount = volumeMount
+			source = func(ctx context.Context, w io.Writer) error {
+				return p.ExportVolume(ctx, mount.Name, w)
 			}
-			return nil
-		})
+		case mount.Type == "bind":
+			if info, err := os.Stat(mount.Source); err == nil && !info.IsDir() {
+				// Single files are configuration the update doesn't change
+				if progressChan != nil {
+					progressChan <- fmt.Sprintf("Skipping mount %d: %s is a file", i+1, mount.Source)
+				}
+				continue
+			}
+			if progressChan != nil {
+				progressChan <- fmt.Sprintf("Backing up mount %d: %s -> %s", i+1, mount.Source, mount.Destination)
+			}
+			backupMount = &models.BackupMount{
+				Source: mount.Source,
+				Target: mount.Destination,
+				Type:   mount.Type,
+			}
+			source = func(_ context.Context, w io.Writer) error {
+				return tarDirectory(mount.Source, w)
+			}
+		default:
+			continue
+		}
+
+		// Stream the mount contents straight into the archive
+		sourceCtx, cancel := context.WithCancel(ctx)
+		pr, pw := io.Pipe()
+		sourceDone := make(chan error, 1)
+		go func() {
+			err := source(sourceCtx, pw)
+			pw.CloseWithError(err)
+			sourceDone <- err
+		}()
+		archivePath, size, err := writer.addMount(len(backup.Mounts), pr)
+		if err == nil {
+			// Drain trailing padding so the source can exit and report its status
+			_, err = io.Copy(io.Discard, pr)
+		} else {
+			cancel()
+		}
+		pr.CloseWithError(err)
+		if sourceErr := <-sourceDone; err == nil {
+			err = sourceErr
+		}
+		cancel()
 		if err != nil {
-			mountSize = 0 // Non-fatal, just can't calculate size
+			return fmt.Errorf("failed to backup mount %s: %w", backupMount.Source, err)
 		}
 
-		backup.Mounts = append(backup.Mounts, models.BackupMount{
-			Source:     mount.Source,
-			Target:     mount.Destination,
-			BackupPath: mountBackupDir,
-			Type:       mount.Type,
-			SizeBytes:  mountSize,
-		})
-		totalSize += mountSize
+		backupMount.BackupPath = archivePath
+		backupMount.SizeBytes = size
+		backup.Mounts = append(backup.Mounts, *backupMount)
+		totalSize += size
 	}
 
-	backup.SizeBytes = totalSize
+	manifestSize, err := writer.close()
+	if err != nil {
+		return err
+	}
+
+	backup.BackupType = backupType(backup.Mounts)
+	backup.Format = writer.manifest.Format
+	backup.Compression = writer.manifest.Compression
+	backup.SizeBytes = totalSize + manifestSize
 
 	// Save backup metadata
-	metadataPath := fmt.Sprintf("%s/backup.json", backupDir)
+	metadataPath := fmt.Sprintf("%s/backup.json", backup.BackupPath)
 	metadataJSON, _ := json.MarshalIndent(backup, "", "  ")
 	if err := os.WriteFile(metadataPath, metadataJSON, 0644); err != nil {
-		log.Printf("Warning: failed to save backup metadata: %v", err)
+		return fmt.Errorf("failed to save backup metadata: %w", err)
 	}
 
-	return backup, nil
+	return nil
 }
 
-// RestoreBindMounts restores bind mounts from a backup
-func (p *PodmanService) RestoreBindMounts(ctx context.Context, backup *models.ContainerBackup, progressChan chan<- string) error {
+// RestoreMounts restores bind mounts and named volumes from a backup. Archived
+// backups are verified against their manifest before anything is replaced.
+func (p *PodmanService) RestoreMounts(ctx context.Context, backup *models.ContainerBackup, progressChan chan<- string) error {
+	if backup.BackupType == models.DatabaseBackupType {
+		return fmt.Errorf("backup %s is a database dump and is restored through its database server", backup.ID)
+	}
+	if backup.Format == "" {
+		return p.restoreCopiedMounts(ctx, backup, progressChan)
+	}
+
+	backupStoreMu.RLock()
+	defer backupStoreMu.RUnlock()
+
+	if progressChan != nil {
+		progressChan <- fmt.Sprintf("Verifying backup %s", backup.ID)
+	}
+	if result := verifyBackup(backup); !result.Valid {
+		return fmt.Errorf("backup %s is damaged: %s", backup.ID, strings.Join(result.Errors, "; "))
+	}
+
+	manifest, err := readManifest(backup.BackupPath)
+	if err != nil {
+		return err
+	}
+
 	for i, mount := range backup.Mounts {
+		stream, err := openBackupMount(backup, manifest, i)
+		if err != nil {
+			return fmt.Errorf("failed to restore mount %s: %w", mount.Target, err)
+		}
+
+		if mount.Type == "volume" {
+			if progressChan != nil {
+				progressChan <- fmt.Sprintf("Restoring volume %d: %s", i+1, mount.Source)
+			}
+			err = p.restoreVolume(ctx, &mount, stream)
+		} else {
+			if progressChan != nil {
+				progressChan <- fmt.Sprintf("Restoring mount %d: %s", i+1, mount.Target)
+			}
+			err = restoreDirectory(mount.Source, stream)
+		}
+		stream.Close()
+		if err != nil {
+			return fmt.Errorf("failed to restore mount %s: %w", mount.Target, err)
+		}
+	}
+
+	return nil
+}
+
+// restoreCopiedMounts restores backups made before archives were introduced,
+// which hold a plain copy of each bind mount
+func (p *PodmanService) restoreCopiedMounts(ctx context.Context, backup *models.ContainerBackup, progressChan chan<- string) error {
+	for i, mount := range backup.Mounts {
+		if mount.Type == "volume" {
+			if progressChan != nil {
+				progressChan <- fmt.Sprintf("Restoring volume %d: %s", i+1, mount.Source)
+			}
+			file, err := os.Open(mount.BackupPath)
+			if err != nil {
+				return fmt.Errorf("failed to open backup of volume %s: %w", mount.Source, err)
+			}
+			err = p.restoreVolume(ctx, &mount, file)
+			file.Close()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
 		if progressChan != nil {
 			progressChan <- fmt.Sprintf("Restoring mount %d: %s", i+1, mount.Target)
 		}
@@ -2204,7 +2571,8 @@ func (p *PodmanService) CheckImageUpdate(ctx context.Context, image string) (boo
 
 	// Pull to check for updates (this will update if newer is available)
 	// We use --quiet to suppress output
-	_, pullErr := p.podmanCmd(ctx, "pull", "--quiet", normalizedImage)
+	args := append([]string{"pull", "--quiet"}, p.registryArgs(normalizedImage)...)
+	_, pullErr := p.podmanCmd(ctx, append(args, normalizedImage)...)
 	if pullErr != nil {
 		return false, localDigest, "", fmt.Errorf("failed to check for updates: %w", pullErr)
 	}
@@ -0,0 +1,391 @@
+package system
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// libpodAPIVersion is the API version prefix sent with every libpod request.
+// Podman 4.x and 5.x both accept v4.0.0 paths.
+const libpodAPIVersion = "v4.0.0"
+
+// PodmanAPIError is a structured error returned by the Podman REST API
+type PodmanAPIError struct {
+	StatusCode int    `json:"response"`
+	Message    string `json:"message"`
+	Cause      string `json:"cause"`
+}
+
+func (e *PodmanAPIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("podman error: %s", e.Message)
+	}
+	return fmt.Sprintf("podman error: HTTP %d", e.StatusCode)
+}
+
+// IsNotFound returns true if the API reported that the object does not exist
+func (e *PodmanAPIError) IsNotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// IsPodmanNotFound returns true if err is a Podman API "no such object" error
+func IsPodmanNotFound(err error) bool {
+	var apiErr *PodmanAPIError
+	return errors.As(err, &apiErr) && apiErr.IsNotFound()
+}
+
+// PodmanClient talks to the libpod REST API over a unix socket
+type PodmanClient struct {
+	socketPath string
+	httpClient *http.Client
+}
+
+// NewPodmanClient creates a client for the Podman socket at socketPath
+func NewPodmanClient(socketPath string) *PodmanClient {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+		MaxIdleConns:    10,
+		IdleConnTimeout: 90 * time.Second,
+	}
+
+	return &PodmanClient{
+		socketPath: socketPath,
+		// No client timeout: callers bound requests with their context,
+		// and followed log streams must be able to stay open.
+		httpClient: &http.Client{Transport: transport},
+	}
+}
+
+// SocketPath returns the unix socket this client connects to
+func (c *PodmanClient) SocketPath() string {
+	return c.socketPath
+}
+
+// do sends a request to a libpod endpoint and returns the response.
+// Responses with a status of 400 or above are converted into a *PodmanAPIError.
+func (c *PodmanClient) do(ctx context.Context, method, endpoint string, query url.Values, body io.Reader) (*http.Response, error) {
+	u := fmt.Sprintf("http://d/%s/libpod%s", libpodAPIVersion, endpoint)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if podmanDebug {
+		log.Printf("[PODMAN] API request: %s %s", method, u)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		apiErr := &PodmanAPIError{StatusCode: resp.StatusCode}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		apiErr.StatusCode = resp.StatusCode
+		return nil, apiErr
+	}
+
+	return resp, nil
+}
+
+// getJSON performs a GET request and decodes the JSON response into out
+func (c *PodmanClient) getJSON(ctx context.Context, endpoint string, query url.Values, out interface{}) error {
+	resp, err := c.do(ctx, http.MethodGet, endpoint, query, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", endpoint, err)
+	}
+	return nil
+}
+
+// send performs a request whose response body is not needed
+func (c *PodmanClient) send(ctx context.Context, method, endpoint string, query url.Values) error {
+	resp, err := c.do(ctx, method, endpoint, query, nil)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return nil
+}
+
+// exists calls a libpod */exists endpoint, which answers 204 or 404
+func (c *PodmanClient) exists(ctx context.Context, endpoint string) (bool, error) {
+	err := c.send(ctx, http.MethodGet, endpoint, nil)
+	if err == nil {
+		return true, nil
+	}
+	if IsPodmanNotFound(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// Ping checks that the Podman service is answering on the socket
+func (c *PodmanClient) Ping(ctx context.Context) error {
+	return c.send(ctx, http.MethodGet, "/_ping", nil)
+}
+
+// Version returns the Podman server version
+func (c *PodmanClient) Version(ctx context.Context) (string, error) {
+	var version struct {
+		Version string `json:"Version"`
+	}
+	if err := c.getJSON(ctx, "/version", nil, &version); err != nil {
+		return "", err
+	}
+	return version.Version, nil
+}
+
+// ListContainers returns containers, optionally including stopped ones.
+// filters uses the same keys as `podman ps --filter`.
+func (c *PodmanClient) ListContainers(ctx context.Context, all bool, filters map[string][]string) ([]podmanContainer, error) {
+	query := url.Values{}
+	if all {
+		query.Set("all", "true")
+	}
+	if len(filters) > 0 {
+		encoded, err := json.Marshal(filters)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("filters", string(encoded))
+	}
+
+	var containers []podmanContainer
+	if err := c.getJSON(ctx, "/containers/json", query, &containers); err != nil {
+		return nil, err
+	}
+	return containers, nil
+}
+
+// InspectContainer returns the inspect data for a single container
+func (c *PodmanClient) InspectContainer(ctx context.Context, nameOrID string) (*podmanInspect, error) {
+	var inspect podmanInspect
+	if err := c.getJSON(ctx, "/containers/"+url.PathEscape(nameOrID)+"/json", nil, &inspect); err != nil {
+		return nil, err
+	}
+	return &inspect, nil
+}
+
+// ContainerSize returns the writable layer and root filesystem size of a container
+func (c *PodmanClient) ContainerSize(ctx context.Context, nameOrID string) (sizeRw int64, sizeRootFs int64, err error) {
+	var size struct {
+		SizeRw     int64 `json:"SizeRw"`
+		SizeRootFs int64 `json:"SizeRootFs"`
+	}
+	query := url.Values{"size": {"true"}}
+	if err := c.getJSON(ctx, "/containers/"+url.PathEscape(nameOrID)+"/json", query, &size); err != nil {
+		return 0, 0, err
+	}
+	return size.SizeRw, size.SizeRootFs, nil
+}
+
+// ContainerExists checks whether a container with the given name or ID exists
+func (c *PodmanClient) ContainerExists(ctx context.Context, nameOrID string) (bool, error) {
+	return c.exists(ctx, "/containers/"+url.PathEscape(nameOrID)+"/exists")
+}
+
+// ContainerAction runs a simple lifecycle action (start, stop, restart, pause, unpause)
+func (c *PodmanClient) ContainerAction(ctx context.Context, nameOrID, action string, timeout int) error {
+	query := url.Values{}
+	if timeout > 0 && (action == "stop" || action == "restart") {
+		query.Set("timeout", fmt.Sprintf("%d", timeout))
+	}
+
+	// A 304 answer (already in the requested state) is treated as success
+	return c.send(ctx, http.MethodPost, "/containers/"+url.PathEscape(nameOrID)+"/"+action, query)
+}
+
+// RemoveContainer deletes a container
+func (c *PodmanClient) RemoveContainer(ctx context.Context, nameOrID string, force bool) error {
+	query := url.Values{}
+	if force {
+		query.Set("force", "true")
+	}
+	return c.send(ctx, http.MethodDelete, "/containers/"+url.PathEscape(nameOrID), query)
+}
+
+// libpodStats is a single entry of the libpod container stats report
+type libpodStats struct {
+	ContainerID string  `json:"ContainerID"`
+	Name        string  `json:"Name"`
+	CPU         float64 `json:"CPU"`
+	MemUsage    uint64  `json:"MemUsage"`
+	MemLimit    uint64  `json:"MemLimit"`
+	MemPerc     float64 `json:"MemPerc"`
+	NetInput    uint64  `json:"NetInput"`
+	NetOutput   uint64  `json:"NetOutput"`
+	BlockInput  uint64  `json:"BlockInput"`
+	BlockOutput uint64  `json:"BlockOutput"`
+	PIDs        uint64  `json:"PIDs"`
+}
+
+// ContainerStats returns a single stats sample for the given containers.
+// An empty list returns stats for all running containers.
+func (c *PodmanClient) ContainerStats(ctx context.Context, containerIDs []string) ([]libpodStats, error) {
+	query := url.Values{"stream": {"false"}}
+	for _, id := range containerIDs {
+		query.Add("containers", id)
+	}
+
+	var report struct {
+		Error interface{}   `json:"Error"`
+		Stats []libpodStats `json:"Stats"`
+	}
+	if err := c.getJSON(ctx, "/containers/stats", query, &report); err != nil {
+		return nil, err
+	}
+	if report.Error != nil {
+		return nil, fmt.Errorf("podman error: %v", report.Error)
+	}
+	return report.Stats, nil
+}
+
+// ContainerLogs opens the log stream of a container.
+// tail may be "all" or a line count; the caller must close the returned body.
+func (c *PodmanClient) ContainerLogs(ctx context.Context, nameOrID string, tail string, follow, timestamps bool) (io.ReadCloser, error) {
+	query := url.Values{
+		"stdout": {"true"},
+		"stderr": {"true"},
+	}
+	if tail != "" {
+		query.Set("tail", tail)
+	}
+	if follow {
+		query.Set("follow", "true")
+	}
+	if timestamps {
+		query.Set("timestamps", "true")
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(nameOrID)+"/logs", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// ListImages returns all local images
+func (c *PodmanClient) ListImages(ctx context.Context) ([]podmanImage, error) {
+	var images []podmanImage
+	if err := c.getJSON(ctx, "/images/json", nil, &images); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// ImageExists checks whether an image exists in local storage
+func (c *PodmanClient) ImageExists(ctx context.Context, image string) (bool, error) {
+	return c.exists(ctx, "/images/"+url.PathEscape(image)+"/exists")
+}
+
+// ListVolumes returns all volumes
+func (c *PodmanClient) ListVolumes(ctx context.Context) ([]podmanVolume, error) {
+	var volumes []podmanVolume
+	if err := c.getJSON(ctx, "/volumes/json", nil, &volumes); err != nil {
+		return nil, err
+	}
+	return volumes, nil
+}
+
+// InspectVolume returns the details of a single volume
+func (c *PodmanClient) InspectVolume(ctx context.Context, name string) (*podmanVolume, error) {
+	var volume podmanVolume
+	if err := c.getJSON(ctx, "/volumes/"+url.PathEscape(name)+"/json", nil, &volume); err != nil {
+		return nil, err
+	}
+	return &volume, nil
+}
+
+// InspectNetwork returns the details of a single network
+func (c *PodmanClient) InspectNetwork(ctx context.Context, name string) (*podmanNetworkInspect, error) {
+	var network podmanNetworkInspect
+	if err := c.getJSON(ctx, "/networks/"+url.PathEscape(name)+"/json", nil, &network); err != nil {
+		return nil, err
+	}
+	return &network, nil
+}
+
+// readLogFrames decodes a libpod log stream and calls fn for every line.
+// Containers without a TTY use the multiplexed format (an 8-byte header
+// carrying the stream type and frame length); TTY output is sent raw.
+// Returning false from fn stops reading.
+func readLogFrames(r io.Reader, fn func(stream, line string) bool) error {
+	br := bufio.NewReader(r)
+
+	header, err := br.Peek(8)
+	if len(header) < 8 || header[0] > 2 || header[1] != 0 || header[2] != 0 || header[3] != 0 {
+		if err != nil && err != io.EOF && len(header) == 0 {
+			return err
+		}
+		scanner := bufio.NewScanner(br)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if !fn("stdout", scanner.Text()) {
+				return nil
+			}
+		}
+		return scanner.Err()
+	}
+
+	frameHeader := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(br, frameHeader); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+
+		stream := "stdout"
+		if frameHeader[0] == 2 {
+			stream = "stderr"
+		}
+
+		payload := make([]byte, binary.BigEndian.Uint32(frameHeader[4:]))
+		if _, err := io.ReadFull(br, payload); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+
+		for _, line := range bytes.Split(bytes.TrimSuffix(payload, []byte("\n")), []byte("\n")) {
+			if !fn(stream, strings.TrimSuffix(string(line), "\r")) {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package system
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newFakePodmanSocket serves handler on a temporary unix socket that mimics
+// the libpod REST API and returns a PodmanService connected to it
+func newFakePodmanSocket(t *testing.T, handler http.Handler) *PodmanService {
+	t.Helper()
+
+	// Unix socket paths are limited to ~108 bytes, so avoid t.TempDir()
+	dir, err := os.MkdirTemp("", "podmangr-sock-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	socketPath := filepath.Join(dir, "podman.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("Failed to listen on fake socket: %v", err)
+	}
+
+	server := &http.Server{Handler: http.StripPrefix("/"+libpodAPIVersion+"/libpod", handler)}
+	go server.Serve(listener)
+
+	t.Cleanup(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	svc := NewPodmanServiceWithSocket("", socketPath)
+	if svc.GetSocketPath() != socketPath {
+		t.Fatalf("Expected service to use socket %s, got %q", socketPath, svc.GetSocketPath())
+	}
+	return svc
+}
+
+// writeLogFrame writes a multiplexed log frame as libpod does for non-TTY containers
+func writeLogFrame(w http.ResponseWriter, stream byte, line string) {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(line)))
+	w.Write(header)
+	w.Write([]byte(line))
+}
+
+func TestPodmanAPIListContainers(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("all") != "true" {
+			t.Errorf("Expected all=true, got %q", r.URL.Query().Get("all"))
+		}
+		w.Write([]byte(`[{
+			"Id": "abc123",
+			"Names": ["web"],
+			"Image": "docker.io/library/nginx:latest",
+			"State": "running",
+			"Status": "Up 5 minutes",
+			"Created": 1700000000,
+			"Ports": [{"host_ip": "", "host_port": 8080, "container_port": 80, "protocol": "tcp"}],
+			"Labels": {"podmangr.webui": "true", "podmangr.icon": "nginx"},
+			"Mounts": ["/data"]
+		}]`))
+	})
+	svc := newFakePodmanSocket(t, mux)
+
+	containers, err := svc.ListContainers(context.Background())
+	if err != nil {
+		t.Fatalf("ListContainers returned error: %v", err)
+	}
+
+	if len(containers) != 1 {
+		t.Fatalf("Expected 1 container, got %d", len(containers))
+	}
+
+	c := containers[0]
+	if c.ContainerID != "abc123" || c.Name != "web" {
+		t.Errorf("Unexpected container identity: %s/%s", c.ContainerID, c.Name)
+	}
+	if c.Status != "running" {
+		t.Errorf("Expected status running, got %s", c.Status)
+	}
+	if !c.HasWebUI || c.Icon != "nginx" {
+		t.Errorf("Expected podmangr labels to be applied, got webui=%v icon=%q", c.HasWebUI, c.Icon)
+	}
+	if len(c.Ports) != 1 || c.Ports[0].HostPort != 8080 || c.Ports[0].ContainerPort != 80 {
+		t.Errorf("Unexpected ports: %+v", c.Ports)
+	}
+}
+
+func TestPodmanAPIInspectNotFound(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"cause":    "no such container",
+			"message":  "no container with name or ID \"missing\" found: no such container",
+			"response": 404,
+		})
+	})
+	svc := newFakePodmanSocket(t, mux)
+
+	_, err := svc.InspectContainer(context.Background(), "missing")
+	if err == nil {
+		t.Fatal("InspectContainer should return error for missing container")
+	}
+	if !IsPodmanNotFound(err) {
+		t.Errorf("Expected a not-found API error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "no such container") {
+		t.Errorf("Expected error message from API, got %q", err.Error())
+	}
+}
+
+func TestPodmanAPIContainerStats(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("stream") != "false" {
+			t.Errorf("Expected stream=false, got %q", r.URL.Query().Get("stream"))
+		}
+		w.Write([]byte(`{"Error": null, "Stats": [{
+			"ContainerID": "abc123",
+			"Name": "web",
+			"CPU": 12.5,
+			"MemUsage": 104857600,
+			"MemLimit": 1073741824,
+			"MemPerc": 9.77,
+			"NetInput": 2048,
+			"NetOutput": 1024,
+			"BlockInput": 4096,
+			"BlockOutput": 512,
+			"PIDs": 7
+		}]}`))
+	})
+	svc := newFakePodmanSocket(t, mux)
+
+	stats, err := svc.GetContainerStats(context.Background(), "abc123")
+	if err != nil {
+		t.Fatalf("GetContainerStats returned error: %v", err)
+	}
+
+	if stats.CPUPercent != 12.5 {
+		t.Errorf("Expected CPU 12.5, got %f", stats.CPUPercent)
+	}
+	if stats.MemoryUsed != 104857600 || stats.MemoryLimit != 1073741824 {
+		t.Errorf("Unexpected memory: %d / %d", stats.MemoryUsed, stats.MemoryLimit)
+	}
+	if stats.NetworkRx != 2048 || stats.NetworkTx != 1024 {
+		t.Errorf("Unexpected network I/O: %d / %d", stats.NetworkRx, stats.NetworkTx)
+	}
+	if stats.PIDs != 7 {
+		t.Errorf("Expected 7 PIDs, got %d", stats.PIDs)
+	}
+}
+
+func TestPodmanAPIGetLogs(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/abc123/logs", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("tail") != "50" {
+			t.Errorf("Expected tail=50, got %q", r.URL.Query().Get("tail"))
+		}
+		writeLogFrame(w, 1, "first line\n")
+		writeLogFrame(w, 2, "an error\n")
+		writeLogFrame(w, 1, "last line\n")
+	})
+	svc := newFakePodmanSocket(t, mux)
+
+	lines, err := svc.GetLogs(context.Background(), "abc123", "50", false)
+	if err != nil {
+		t.Fatalf("GetLogs returned error: %v", err)
+	}
+
+	expected := []string{"first line", "an error", "last line"}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %d: %v", len(expected), len(lines), lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Line %d: expected %q, got %q", i, expected[i], lines[i])
+		}
+	}
+}
+
+func TestReadLogFramesRaw(t *testing.T) {
+	// TTY containers stream plain text without frame headers
+	var got []string
+	err := readLogFrames(strings.NewReader("hello\nworld\n"), func(stream, line string) bool {
+		if stream != "stdout" {
+			t.Errorf("Expected stdout stream, got %s", stream)
+		}
+		got = append(got, line)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("readLogFrames returned error: %v", err)
+	}
+	if len(got) != 2 || got[0] != "hello" || got[1] != "world" {
+		t.Errorf("Unexpected lines: %v", got)
+	}
+}
+
+func TestCollectContainerMetricsBatched(t *testing.T) {
+	statsCalls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/stats", func(w http.ResponseWriter, r *http.Request) {
+		statsCalls++
+		if len(r.URL.Query()["containers"]) != 0 {
+			t.Errorf("Expected a single call for all containers, got %v", r.URL.Query()["containers"])
+		}
+		w.Write([]byte(`{"Error": null, "Stats": [
+			{"ContainerID": "` + strings.Repeat("a", 64) + `", "CPU": 1.5, "MemUsage": 100},
+			{"ContainerID": "` + strings.Repeat("b", 64) + `", "CPU": 2.5, "MemUsage": 200}
+		]}`))
+	})
+	svc := newFakePodmanSocket(t, mux)
+
+	samples, err := collectContainerMetrics(context.Background(), svc, time.Now())
+	if err != nil {
+		t.Fatalf("collectContainerMetrics returned error: %v", err)
+	}
+
+	if statsCalls != 1 {
+		t.Errorf("Expected 1 stats call, got %d", statsCalls)
+	}
+	if len(samples) != 2 {
+		t.Fatalf("Expected 2 samples, got %d", len(samples))
+	}
+	if samples[1].ContainerID != strings.Repeat("b", 64) || samples[1].CPUPercent != 2.5 || samples[1].MemoryUsed != 200 {
+		t.Errorf("Unexpected sample: %+v", samples[1])
+	}
+}
@@ -0,0 +1,322 @@
+package system
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"podmangr-backend/internal/models"
+)
+
+// defaultNetwork is Podman's own network, which is never pruned
+const defaultNetwork = "podman"
+
+// podmanDFSummary is one row of podman system df
+type podmanDFSummary struct {
+	Type           string `json:"Type"`
+	Total          int    `json:"Total"`
+	Active         int    `json:"Active"`
+	RawSize        int64  `json:"RawSize"`
+	RawReclaimable int64  `json:"RawReclaimable"`
+}
+
+// DiskUsage returns the storage used by images, containers and volumes and
+// how much of it a prune would reclaim. Podman has no separate build cache;
+// builds leave dangling images, which are reported as the build cache.
+func (p *PodmanService) DiskUsage(ctx context.Context) (*models.DiskUsage, error) {
+	output, err := p.podmanCmd(ctx, "system", "df", "--format", "json")
+	if err != nil {
+		return nil, err
+	}
+	var summaries []podmanDFSummary
+	if err := json.Unmarshal(output, &summaries); err != nil {
+		return nil, fmt.Errorf("failed to parse disk usage: %w", err)
+	}
+
+	usage := &models.DiskUsage{}
+	for _, s := range summaries {
+		category := models.DiskUsageCategory{
+			Total:       s.Total,
+			Active:      s.Active,
+			Size:        s.RawSize,
+			Reclaimable: s.RawReclaimable,
+		}
+		switch s.Type {
+		case "Images":
+			usage.Images = category
+		case "Containers":
+			usage.Containers = category
+		case "Local Volumes":
+			usage.Volumes = category
+		}
+	}
+
+	images, err := p.listImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, img := range images {
+		if len(imageTags(img)) == 0 {
+			usage.BuildCache.Total++
+			usage.BuildCache.Size += img.Size
+			if img.Containers > 0 {
+				usage.BuildCache.Active++
+			} else {
+				usage.BuildCache.Reclaimable += img.Size
+			}
+		}
+	}
+
+	for _, c := range []models.DiskUsageCategory{usage.Images, usage.Containers, usage.Volumes} {
+		usage.Size += c.Size
+		usage.Reclaimable += c.Reclaimable
+	}
+	return usage, nil
+}
+
+// imageTags returns the tags of an image, without the <none> placeholders
+// of dangling images
+func imageTags(img podmanImage) []string {
+	var tags []string
+	for _, t := range img.RepoTags {
+		if t != "" && !strings.HasPrefix(t, "<none>") {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// tagRepository strips the tag from an image reference
+func tagRepository(ref string) string {
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i]
+	}
+	return ref
+}
+
+// imagePruneCandidates selects the images a prune removes from those not
+// used by any container. With keep set, a tagged image is removed when it
+// is older than the keep newest images of every repository it is tagged in;
+// images in use still count towards the newest.
+func imagePruneCandidates(images []podmanImage, used map[string]bool, mode string, keep int) []models.PruneItem {
+	// Rank images by age within each repository
+	byRepo := make(map[string][]podmanImage)
+	for _, img := range images {
+		seen := make(map[string]bool)
+		for _, tag := range imageTags(img) {
+			repo := tagRepository(tag)
+			if !seen[repo] {
+				seen[repo] = true
+				byRepo[repo] = append(byRepo[repo], img)
+			}
+		}
+	}
+	rank := make(map[string]map[string]int)
+	for repo, list := range byRepo {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Created > list[j].Created })
+		for i, img := range list {
+			if rank[img.ID] == nil {
+				rank[img.ID] = make(map[string]int)
+			}
+			rank[img.ID][repo] = i
+		}
+	}
+
+	items := make([]models.PruneItem, 0)
+	for _, img := range images {
+		if used[img.ID] {
+			continue
+		}
+		tags := imageTags(img)
+		item := models.PruneItem{Type: models.PruneTypeImage, ID: img.ID, Size: img.Size}
+		if len(tags) > 0 {
+			item.Name = strings.Join(tags, ", ")
+		}
+
+		switch {
+		case len(tags) == 0 && (mode == models.PruneImagesDangling || mode == models.PruneImagesUnused):
+			item.Reason = "dangling"
+		case len(tags) == 0:
+			continue
+		case mode == models.PruneImagesUnused:
+			item.Reason = "unused"
+		case keep > 0:
+			older := true
+			for _, r := range rank[img.ID] {
+				if r < keep {
+					older = false
+					break
+				}
+			}
+			if !older {
+				continue
+			}
+			item.Reason = fmt.Sprintf("older than the %d newest versions", keep)
+		default:
+			continue
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// stoppedContainer reports whether a container state is one a container prune removes
+func stoppedContainer(state string) bool {
+	switch strings.ToLower(state) {
+	case "exited", "stopped", "created":
+		return true
+	}
+	return false
+}
+
+// Prune removes, or with DryRun lists, stopped containers, unused images,
+// volumes and networks. Containers go first so images they used are pruned
+// in the same run. Volumes and networks only count as unused once their
+// containers are removed, so a dry run doesn't list those freed by it.
+func (p *PodmanService) Prune(ctx context.Context, req models.PruneRequest) (*models.PruneResult, error) {
+	result := &models.PruneResult{DryRun: req.DryRun, Items: make([]models.PruneItem, 0)}
+	// add records an item, removing it unless this is a dry run, and reports
+	// whether it is (or would be) gone
+	add := func(item models.PruneItem, remove func() error) bool {
+		if !req.DryRun {
+			if err := remove(); err != nil {
+				item.Error = err.Error()
+			}
+		}
+		if item.Error != "" {
+			result.Failed++
+		} else {
+			result.Reclaimed += item.Size
+		}
+		result.Items = append(result.Items, item)
+		return item.Error == ""
+	}
+
+	containers, err := p.listContainers(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := make(map[string]bool)
+	if req.Containers {
+		sizes := p.containerSizes(ctx)
+		for _, c := range containers {
+			if !stoppedContainer(c.State) {
+				continue
+			}
+			item := models.PruneItem{Type: models.PruneTypeContainer, ID: c.ID, Size: sizes[c.ID], Reason: c.State}
+			if len(c.Names) > 0 {
+				item.Name = strings.TrimPrefix(c.Names[0], "/")
+			}
+			if add(item, func() error { return p.RemoveContainer(ctx, c.ID, false) }) {
+				removed[c.ID] = true
+			}
+		}
+	}
+
+	if req.Images != "" || req.KeepVersions > 0 {
+		images, err := p.listImages(ctx)
+		if err != nil {
+			return nil, err
+		}
+		used := make(map[string]bool)
+		for _, c := range containers {
+			if !removed[c.ID] {
+				used[c.ImageID] = true
+			}
+		}
+		for _, item := range imagePruneCandidates(images, used, req.Images, req.KeepVersions) {
+			id := item.ID
+			add(item, func() error { return p.RemoveImage(ctx, id, false) })
+		}
+	}
+
+	if req.Volumes {
+		output, err := p.podmanCmd(ctx, "volume", "ls", "--filter", "dangling=true", "--format", "json")
+		if err != nil {
+			return nil, err
+		}
+		var volumes []podmanVolume
+		if err := json.Unmarshal(output, &volumes); err != nil {
+			return nil, fmt.Errorf("failed to parse volume list: %w", err)
+		}
+		for _, v := range volumes {
+			name := v.Name
+			item := models.PruneItem{Type: models.PruneTypeVolume, ID: name, Name: name, Size: dirSize(v.MountPoint), Reason: "unused"}
+			add(item, func() error { return p.RemoveVolume(ctx, name, false) })
+		}
+	}
+
+	if req.Networks {
+		output, err := p.podmanCmd(ctx, "network", "ls", "--filter", "dangling=true", "--format", "json")
+		if err != nil {
+			return nil, err
+		}
+		var networks []struct {
+			ID   string `json:"Id"`
+			Name string `json:"Name"`
+		}
+		if err := json.Unmarshal(output, &networks); err != nil {
+			return nil, fmt.Errorf("failed to parse network list: %w", err)
+		}
+		for _, n := range networks {
+			if n.Name == defaultNetwork {
+				continue
+			}
+			name := n.Name
+			item := models.PruneItem{Type: models.PruneTypeNetwork, ID: n.ID, Name: name, Reason: "unused"}
+			add(item, func() error { return p.RemoveNetwork(ctx, name, false) })
+		}
+	}
+
+	return result, nil
+}
+
+// containerSizes returns the size of the writable layer of each container,
+// which removing it frees. Sizes are left out if podman can't compute them.
+func (p *PodmanService) containerSizes(ctx context.Context) map[string]int64 {
+	sizes := make(map[string]int64)
+	output, err := p.podmanCmd(ctx, "ps", "-a", "--size", "--format", "json")
+	if err != nil {
+		return sizes
+	}
+	var containers []struct {
+		ID   string `json:"Id"`
+		Size *struct {
+			RwSize int64 `json:"rwSize"`
+		} `json:"Size"`
+	}
+	if json.Unmarshal(output, &containers) != nil {
+		return sizes
+	}
+	for _, c := range containers {
+		if c.Size != nil {
+			sizes[c.ID] = c.Size.RwSize
+		}
+	}
+	return sizes
+}
+
+// dirSize sums the sizes of the files under a directory, skipping what can't be read
+func dirSize(dir string) int64 {
+	var size int64
+	if dir == "" {
+		return 0
+	}
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
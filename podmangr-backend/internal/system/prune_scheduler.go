@@ -0,0 +1,180 @@
+package system
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"podmangr-backend/internal/database"
+	"podmangr-backend/internal/models"
+)
+
+const (
+	DefaultPruneSchedule = "0 5 * * 0"
+
+	pruneTimeout = time.Hour // per socket
+)
+
+// LoadPrunePolicy reads the scheduled prune policy. Scheduled prunes are
+// off until enabled and remove dangling images by default.
+func LoadPrunePolicy() models.PrunePolicy {
+	settingsRepo := database.NewSettingsRepo()
+
+	policy := models.PrunePolicy{
+		Schedule: DefaultPruneSchedule,
+		Images:   models.PruneImagesDangling,
+	}
+	if raw, err := settingsRepo.Get(database.SettingPrunePolicy); err == nil {
+		json.Unmarshal([]byte(raw), &policy)
+	}
+	policy.Enabled, _ = settingsRepo.GetBool(database.SettingPruneEnabled)
+	if v, err := settingsRepo.Get(database.SettingPruneSchedule); err == nil && strings.TrimSpace(v) != "" {
+		policy.Schedule = v
+	}
+	policy.NextRun = ""
+	if schedule, err := ParseSchedule(policy.Schedule); err == nil && policy.Enabled {
+		policy.NextRun = schedule.Next(time.Now()).Format(time.RFC3339)
+	}
+	return policy
+}
+
+// ValidatePrunePolicy checks the schedule and what a policy removes
+func ValidatePrunePolicy(policy models.PrunePolicy) error {
+	if _, err := ParseSchedule(policy.Schedule); err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+	return ValidatePruneRequest(policy.Request())
+}
+
+// ValidatePruneRequest checks the image mode and version count of a prune
+func ValidatePruneRequest(req models.PruneRequest) error {
+	switch req.Images {
+	case "", models.PruneImagesDangling, models.PruneImagesUnused:
+	default:
+		return fmt.Errorf("images must be empty, dangling or unused")
+	}
+	if req.KeepVersions < 0 {
+		return fmt.Errorf("keep_versions must not be negative")
+	}
+	return nil
+}
+
+// SavePrunePolicy stores the scheduled prune policy
+func SavePrunePolicy(policy models.PrunePolicy) error {
+	settingsRepo := database.NewSettingsRepo()
+
+	selection, err := json.Marshal(policy.Request())
+	if err != nil {
+		return err
+	}
+	values := map[string]string{
+		database.SettingPruneEnabled:  strconv.FormatBool(policy.Enabled),
+		database.SettingPruneSchedule: policy.Schedule,
+		database.SettingPrunePolicy:   string(selection),
+	}
+	for key, value := range values {
+		if err := settingsRepo.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneScheduler applies the prune policy to every socket on its cron schedule
+type PruneScheduler struct {
+	registry *SocketRegistry
+
+	mu      sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+	running bool
+}
+
+// NewPruneScheduler creates a new prune scheduler
+func NewPruneScheduler() *PruneScheduler {
+	return &PruneScheduler{registry: GetSocketRegistry()}
+}
+
+// Start launches the background scheduling loop
+func (s *PruneScheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return
+	}
+	s.running = true
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go s.run(s.stop, s.done)
+}
+
+// Stop stops the scheduling loop and waits for it to exit
+func (s *PruneScheduler) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	close(s.stop)
+	done := s.done
+	s.mu.Unlock()
+
+	<-done
+}
+
+func (s *PruneScheduler) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	var spec string
+	var next time.Time
+	for {
+		// The policy is re-read every tick so changes apply without a restart
+		if policy := LoadPrunePolicy(); policy.Enabled {
+			if policy.Schedule != spec {
+				spec = policy.Schedule
+				next = nextScheduledRun(spec, time.Now())
+			}
+			if !next.IsZero() && !time.Now().Before(next) {
+				if err := s.PruneAll(context.Background(), policy.Request()); err != nil {
+					log.Printf("Scheduled prune error: %v", err)
+				}
+				next = nextScheduledRun(spec, time.Now())
+			}
+		} else {
+			spec = ""
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(updateSchedulerTick):
+		}
+	}
+}
+
+// PruneAll runs a prune on every socket. Sockets that fail are reported but
+// don't block the others.
+func (s *PruneScheduler) PruneAll(ctx context.Context, req models.PruneRequest) error {
+	var errs []error
+	for socketID, svc := range s.registry.Services() {
+		socketCtx, cancel := context.WithTimeout(ctx, pruneTimeout)
+		result, err := svc.Prune(socketCtx, req)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("socket %s: %w", socketID, err))
+			continue
+		}
+		log.Printf("Pruned %d objects on socket %s, reclaiming %d bytes (%d failed)",
+			len(result.Items)-result.Failed, socketID, result.Reclaimed, result.Failed)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,68 @@
+package system
+
+import (
+	"reflect"
+	"testing"
+
+	"podmangr-backend/internal/models"
+)
+
+func TestImagePruneCandidates(t *testing.T) {
+	images := []podmanImage{
+		{ID: "app-v1", RepoTags: []string{"localhost:5000/app:1"}, Created: 100, Size: 10},
+		{ID: "app-v2", RepoTags: []string{"localhost:5000/app:2"}, Created: 200, Size: 10},
+		{ID: "app-v3", RepoTags: []string{"localhost:5000/app:3", "localhost:5000/app:latest"}, Created: 300, Size: 10},
+		{ID: "app-v0", RepoTags: []string{"localhost:5000/app:0"}, Created: 50, Size: 10},
+		{ID: "nginx", RepoTags: []string{"docker.io/library/nginx:1.27"}, Created: 10, Size: 20},
+		{ID: "shared", RepoTags: []string{"docker.io/library/redis:6", "ghcr.io/acme/redis:latest"}, Created: 5, Size: 30},
+		{ID: "redis-7", RepoTags: []string{"docker.io/library/redis:7"}, Created: 400, Size: 30},
+		{ID: "dangling", RepoTags: []string{"<none>:<none>"}, Created: 1, Size: 5},
+	}
+	// app-v0 is old but still used by a container
+	used := map[string]bool{"app-v0": true}
+
+	ids := func(items []models.PruneItem) []string {
+		var out []string
+		for _, item := range items {
+			out = append(out, item.ID)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name string
+		mode string
+		keep int
+		want []string
+	}{
+		{"dangling", models.PruneImagesDangling, 0, []string{"dangling"}},
+		{"unused", models.PruneImagesUnused, 0, []string{"app-v1", "app-v2", "app-v3", "nginx", "shared", "redis-7", "dangling"}},
+		// The used app-v0 counts towards the newest but isn't removed; shared
+		// is kept as the newest of ghcr.io/acme/redis
+		{"keep two versions", "", 2, []string{"app-v1"}},
+		{"keep one version and dangling", models.PruneImagesDangling, 1, []string{"app-v1", "app-v2", "dangling"}},
+		{"nothing selected", "", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ids(imagePruneCandidates(images, used, tt.mode, tt.keep))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagRepository(t *testing.T) {
+	tests := map[string]string{
+		"docker.io/library/nginx:1.27": "docker.io/library/nginx",
+		"localhost:5000/app:2":         "localhost:5000/app",
+		"localhost:5000/app":           "localhost:5000/app",
+		"nginx":                        "nginx",
+	}
+	for ref, want := range tests {
+		if got := tagRepository(ref); got != want {
+			t.Errorf("tagRepository(%q) = %q, want %q", ref, got, want)
+		}
+	}
+}
@@ -0,0 +1,562 @@
+package system
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"podmangr-backend/internal/quadlet"
+)
+
+// defaultPodmanNetwork is the network containers join when none is given.
+// It always exists, so units never reference or generate it.
+const defaultPodmanNetwork = "podman"
+
+// podmanPodInspect is the subset of podman pod inspect used for Quadlet generation
+type podmanPodInspect struct {
+	ID               string            `json:"Id"`
+	Name             string            `json:"Name"`
+	Labels           map[string]string `json:"Labels"`
+	InfraContainerID string            `json:"InfraContainerID"`
+	InfraConfig      struct {
+		PortBindings map[string][]struct {
+			HostIP   string `json:"HostIp"`
+			HostPort string `json:"HostPort"`
+		} `json:"PortBindings"`
+		HostNetwork bool     `json:"HostNetwork"`
+		Networks    []string `json:"Networks"`
+	} `json:"InfraConfig"`
+	Containers []struct {
+		ID    string `json:"Id"`
+		Name  string `json:"Name"`
+		State string `json:"State"`
+	} `json:"Containers"`
+}
+
+// quadletBundle collects generated units, keeping each file once and in
+// the order it was first added
+type quadletBundle struct {
+	files []quadlet.File
+	seen  map[string]bool
+}
+
+func newQuadletBundle() *quadletBundle {
+	return &quadletBundle{seen: make(map[string]bool)}
+}
+
+func (b *quadletBundle) add(u *quadlet.Unit) {
+	if b.seen[u.Name()] {
+		return
+	}
+	b.seen[u.Name()] = true
+	b.files = append(b.files, u.File())
+}
+
+func (b *quadletBundle) has(name string) bool {
+	return b.seen[name]
+}
+
+// GenerateContainerQuadlet returns the Quadlet units that recreate a container:
+// its .container unit plus the .pod, .volume and .network units it references
+func (p *PodmanService) GenerateContainerQuadlet(ctx context.Context, containerID string) ([]quadlet.File, error) {
+	inspect, err := p.InspectContainer(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	b := newQuadletBundle()
+	podRef := ""
+	var podUnit *quadlet.Unit
+	if inspect.Pod != "" {
+		pod, err := p.inspectPodConfig(ctx, inspect.Pod)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect pod: %w", err)
+		}
+		podRef = quadlet.Ref(pod.Name, quadlet.ExtPod)
+		if podUnit, err = p.podUnit(ctx, b, pod); err != nil {
+			return nil, err
+		}
+	}
+
+	// The container comes first so it is the obvious entry point of the bundle
+	unit, err := p.containerUnit(ctx, b, inspect, podRef)
+	if err != nil {
+		return nil, err
+	}
+	files := []quadlet.File{unit.File()}
+	if podUnit != nil {
+		files = append(files, podUnit.File())
+	}
+	return append(files, b.files...), nil
+}
+
+// GeneratePodQuadlet returns the .pod unit for a pod, a .container unit for
+// every member (except the infra container) and the units they reference
+func (p *PodmanService) GeneratePodQuadlet(ctx context.Context, podID string) ([]quadlet.File, error) {
+	pod, err := p.inspectPodConfig(ctx, podID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect pod: %w", err)
+	}
+
+	b := newQuadletBundle()
+	podUnit, err := p.podUnit(ctx, b, pod)
+	if err != nil {
+		return nil, err
+	}
+
+	members := pod.Containers
+	sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+
+	files := []quadlet.File{podUnit.File()}
+	podRef := quadlet.Ref(pod.Name, quadlet.ExtPod)
+	for _, member := range members {
+		if member.ID == pod.InfraContainerID {
+			continue
+		}
+		inspect, err := p.InspectContainer(ctx, member.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect container %s: %w", member.Name, err)
+		}
+		unit, err := p.containerUnit(ctx, b, inspect, podRef)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, unit.File())
+	}
+
+	return append(files, b.files...), nil
+}
+
+// GenerateVolumeQuadlet returns the .volume unit for a named volume
+func (p *PodmanService) GenerateVolumeQuadlet(ctx context.Context, name string) ([]quadlet.File, error) {
+	b := newQuadletBundle()
+	if _, err := p.volumeUnit(ctx, b, name); err != nil {
+		return nil, err
+	}
+	return b.files, nil
+}
+
+// GenerateNetworkQuadlet returns the .network unit for a Podman network
+func (p *PodmanService) GenerateNetworkQuadlet(ctx context.Context, name string) ([]quadlet.File, error) {
+	if name == defaultPodmanNetwork {
+		return nil, fmt.Errorf("the default %q network is managed by Podman and has no unit", defaultPodmanNetwork)
+	}
+	b := newQuadletBundle()
+	if _, err := p.networkUnit(ctx, b, name); err != nil {
+		return nil, err
+	}
+	return b.files, nil
+}
+
+// inspectPodConfig returns typed pod inspect data
+func (p *PodmanService) inspectPodConfig(ctx context.Context, podID string) (*podmanPodInspect, error) {
+	raw, err := p.InspectPod(ctx, podID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var pod podmanPodInspect
+	if err := json.Unmarshal(data, &pod); err != nil {
+		return nil, fmt.Errorf("failed to parse pod inspect: %w", err)
+	}
+	return &pod, nil
+}
+
+// containerUnit builds the .container unit for an inspected container and adds
+// the volumes and networks it uses to the bundle. Members of a pod get Pod=
+// instead of their own ports and networks, which belong to the pod.
+func (p *PodmanService) containerUnit(ctx context.Context, b *quadletBundle, inspect *podmanInspect, podRef string) (*quadlet.Unit, error) {
+	name := strings.TrimPrefix(inspect.Name, "/")
+	u := quadlet.NewUnit(name, quadlet.ExtContainer)
+
+	// Image defaults are left out so the unit only carries what was customised.
+	// Without image data (e.g. the image was removed) everything is kept.
+	image, _ := p.InspectImage(ctx, inspect.Config.Image, false)
+	imageEnv := make(map[string]string)
+	imageLabels := make(map[string]string)
+	if image != nil {
+		for _, env := range image.Environment {
+			imageEnv[env.Key] = env.Value
+		}
+		imageLabels = image.Labels
+	}
+
+	u.Add("Unit", "Description", fmt.Sprintf("Podman container %s", name))
+
+	u.Add("Container", "Image", inspect.Config.Image)
+	u.Add("Container", "ContainerName", name)
+
+	if podRef != "" {
+		u.Add("Container", "Pod", podRef)
+	} else {
+		networks, err := p.networkRefs(ctx, b, inspect.HostConfig.NetworkMode, containerNetworks(inspect))
+		if err != nil {
+			return nil, err
+		}
+		u.AddAll("Container", "Network", networks)
+		u.AddAll("Container", "PublishPort", publishPorts(inspect.HostConfig.PortBindings))
+	}
+
+	for _, mount := range inspect.Mounts {
+		suffix := ""
+		if !mount.RW {
+			suffix = ":ro"
+		}
+		switch {
+		case mount.Type == "volume" && mount.Name != "":
+			ref, err := p.volumeUnit(ctx, b, mount.Name)
+			if err != nil {
+				return nil, err
+			}
+			u.Add("Container", "Volume", fmt.Sprintf("%s:%s%s", ref, mount.Destination, suffix))
+		case mount.Type == "bind":
+			u.Add("Container", "Volume", quadlet.Quote(fmt.Sprintf("%s:%s%s", mount.Source, mount.Destination, suffix)))
+		}
+	}
+
+	tmpfs := sortedKeys(inspect.HostConfig.Tmpfs)
+	for _, path := range tmpfs {
+		if opts := inspect.HostConfig.Tmpfs[path]; opts != "" {
+			path += ":" + opts
+		}
+		u.Add("Container", "Tmpfs", path)
+	}
+
+	env := make(map[string]string)
+	for _, e := range inspect.Config.Env {
+		key, value, _ := strings.Cut(e, "=")
+		if key == "container" || key == "HOSTNAME" {
+			continue // set by Podman itself
+		}
+		if imageValue, ok := imageEnv[key]; ok && imageValue == value {
+			continue
+		}
+		env[key] = value
+	}
+	for _, key := range sortedKeys(env) {
+		u.Add("Container", "Environment", quadlet.Quote(key+"="+env[key]))
+	}
+
+	labels := make(map[string]string)
+	for key, value := range inspect.Config.Labels {
+		if strings.HasPrefix(key, "io.podman.") || strings.HasPrefix(key, "org.opencontainers.") || key == "PODMAN_SYSTEMD_UNIT" {
+			continue
+		}
+		if imageValue, ok := imageLabels[key]; ok && imageValue == value {
+			continue
+		}
+		labels[key] = value
+	}
+	for _, key := range sortedKeys(labels) {
+		u.Add("Container", "Label", quadlet.Quote(key+"="+labels[key]))
+	}
+
+	if image == nil || !slices.Equal(image.Entrypoint, inspect.Config.Entrypoint) {
+		switch len(inspect.Config.Entrypoint) {
+		case 0:
+		case 1:
+			u.Add("Container", "Entrypoint", quadlet.Escape(inspect.Config.Entrypoint[0]))
+		default:
+			// Multi-word entrypoints are passed to --entrypoint as a JSON array
+			encoded, _ := json.Marshal(inspect.Config.Entrypoint)
+			u.Add("Container", "Entrypoint", quadlet.Escape(string(encoded)))
+		}
+	}
+	if image == nil || !slices.Equal(image.Cmd, inspect.Config.Cmd) {
+		if len(inspect.Config.Cmd) > 0 {
+			u.Add("Container", "Exec", quadlet.QuoteArgs(inspect.Config.Cmd))
+		}
+	}
+
+	// Podman defaults the hostname to the short container ID
+	if hostname := inspect.Config.Hostname; hostname != "" && !strings.HasPrefix(inspect.ID, hostname) && podRef == "" {
+		u.Add("Container", "HostName", hostname)
+	}
+	if image == nil || image.User != inspect.Config.User {
+		u.Add("Container", "User", inspect.Config.User)
+	}
+	if image == nil || image.WorkingDir != inspect.Config.WorkingDir {
+		if inspect.Config.WorkingDir != "/" {
+			u.Add("Container", "WorkingDir", inspect.Config.WorkingDir)
+		}
+	}
+
+	u.AddAll("Container", "AddCapability", inspect.HostConfig.CapAdd)
+	u.AddAll("Container", "DropCapability", inspect.HostConfig.CapDrop)
+	for _, dev := range inspect.HostConfig.Devices {
+		spec := dev.PathOnHost
+		if dev.PathInContainer != "" && dev.PathInContainer != dev.PathOnHost {
+			spec += ":" + dev.PathInContainer
+		}
+		u.Add("Container", "AddDevice", spec)
+	}
+	if podRef == "" {
+		u.AddAll("Container", "DNS", inspect.HostConfig.DNS)
+		u.AddAll("Container", "AddHost", inspect.HostConfig.ExtraHosts)
+	}
+	if inspect.HostConfig.ReadonlyRootfs {
+		u.Add("Container", "ReadOnly", "true")
+	}
+
+	if hc := inspect.Config.Healthcheck; hc != nil && len(hc.Test) > 1 {
+		switch hc.Test[0] {
+		case "CMD-SHELL":
+			u.Add("Container", "HealthCmd", hc.Test[1])
+		case "CMD":
+			u.Add("Container", "HealthCmd", quadlet.QuoteArgs(hc.Test[1:]))
+		}
+	}
+
+	// Options without a dedicated Quadlet key are passed through PodmanArgs
+	var podmanArgs []string
+	for _, opt := range inspect.HostConfig.SecurityOpt {
+		switch {
+		case opt == "label=disable" || opt == "label:disable":
+			u.Add("Container", "SecurityLabelDisable", "true")
+		case opt == "no-new-privileges" || opt == "no-new-privileges:true":
+			u.Add("Container", "NoNewPrivileges", "true")
+		case strings.HasPrefix(opt, "seccomp="):
+			u.Add("Container", "SeccompProfile", strings.TrimPrefix(opt, "seccomp="))
+		default:
+			podmanArgs = append(podmanArgs, "--security-opt="+opt)
+		}
+	}
+	if inspect.HostConfig.Privileged {
+		podmanArgs = append(podmanArgs, "--privileged")
+	}
+	if inspect.HostConfig.Memory > 0 {
+		podmanArgs = append(podmanArgs, fmt.Sprintf("--memory=%d", inspect.HostConfig.Memory))
+	}
+	if inspect.HostConfig.NanoCpus > 0 {
+		podmanArgs = append(podmanArgs, "--cpus="+strconv.FormatFloat(float64(inspect.HostConfig.NanoCpus)/1e9, 'f', -1, 64))
+	}
+	if len(podmanArgs) > 0 {
+		u.Add("Container", "PodmanArgs", quadlet.QuoteArgs(podmanArgs))
+	}
+
+	switch inspect.HostConfig.RestartPolicy.Name {
+	case "always", "unless-stopped":
+		u.Add("Service", "Restart", "always")
+	case "on-failure":
+		u.Add("Service", "Restart", "on-failure")
+	}
+
+	// Pod members are started by their pod's service
+	if podRef == "" {
+		u.Add("Install", "WantedBy", "default.target")
+	}
+
+	if err := u.Err(); err != nil {
+		return nil, fmt.Errorf("container %s: %w", name, err)
+	}
+	return u, nil
+}
+
+// podUnit builds the .pod unit for a pod and adds the networks it joins to the bundle
+func (p *PodmanService) podUnit(ctx context.Context, b *quadletBundle, pod *podmanPodInspect) (*quadlet.Unit, error) {
+	u := quadlet.NewUnit(pod.Name, quadlet.ExtPod)
+	u.Add("Unit", "Description", fmt.Sprintf("Podman pod %s", pod.Name))
+
+	u.Add("Pod", "PodName", pod.Name)
+
+	mode := "bridge"
+	if pod.InfraConfig.HostNetwork {
+		mode = "host"
+	}
+	networks, err := p.networkRefs(ctx, b, mode, pod.InfraConfig.Networks)
+	if err != nil {
+		return nil, err
+	}
+	u.AddAll("Pod", "Network", networks)
+	u.AddAll("Pod", "PublishPort", publishPorts(pod.InfraConfig.PortBindings))
+
+	for _, key := range sortedKeys(pod.Labels) {
+		u.Add("Pod", "Label", quadlet.Quote(key+"="+pod.Labels[key]))
+	}
+
+	u.Add("Install", "WantedBy", "default.target")
+	if err := u.Err(); err != nil {
+		return nil, fmt.Errorf("pod %s: %w", pod.Name, err)
+	}
+	return u, nil
+}
+
+// volumeUnit adds the .volume unit for a named volume to the bundle and
+// returns the reference containers use for it
+func (p *PodmanService) volumeUnit(ctx context.Context, b *quadletBundle, name string) (string, error) {
+	ref := quadlet.Ref(name, quadlet.ExtVolume)
+	if b.has(ref) {
+		return ref, nil
+	}
+
+	volume, err := p.InspectVolume(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect volume %s: %w", name, err)
+	}
+
+	u := quadlet.NewUnit(volume.Name, quadlet.ExtVolume)
+	u.Add("Volume", "VolumeName", volume.Name)
+	if volume.Driver != "" && volume.Driver != "local" {
+		u.Add("Volume", "Driver", volume.Driver)
+	}
+
+	var podmanArgs []string
+	for _, key := range sortedKeys(volume.Options) {
+		value := volume.Options[key]
+		switch key {
+		case "type":
+			u.Add("Volume", "Type", value)
+		case "device":
+			u.Add("Volume", "Device", value)
+		case "o":
+			u.Add("Volume", "Options", value)
+		default:
+			podmanArgs = append(podmanArgs, fmt.Sprintf("--opt=%s=%s", key, value))
+		}
+	}
+	if len(podmanArgs) > 0 {
+		u.Add("Volume", "PodmanArgs", quadlet.QuoteArgs(podmanArgs))
+	}
+
+	for _, key := range sortedKeys(volume.Labels) {
+		u.Add("Volume", "Label", quadlet.Quote(key+"="+volume.Labels[key]))
+	}
+
+	if err := u.Err(); err != nil {
+		return "", fmt.Errorf("volume %s: %w", name, err)
+	}
+	b.add(u)
+	return ref, nil
+}
+
+// networkUnit adds the .network unit for a Podman network to the bundle and
+// returns the reference containers and pods use for it
+func (p *PodmanService) networkUnit(ctx context.Context, b *quadletBundle, name string) (string, error) {
+	ref := quadlet.Ref(name, quadlet.ExtNetwork)
+	if b.has(ref) {
+		return ref, nil
+	}
+
+	network, err := p.InspectNetwork(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect network %s: %w", name, err)
+	}
+
+	u := quadlet.NewUnit(network.Name, quadlet.ExtNetwork)
+	u.Add("Network", "NetworkName", network.Name)
+	if network.Driver != "" && network.Driver != "bridge" {
+		u.Add("Network", "Driver", network.Driver)
+	}
+	for _, subnet := range network.Subnets {
+		u.Add("Network", "Subnet", subnet.Subnet)
+		u.Add("Network", "Gateway", subnet.Gateway)
+	}
+	if driver := network.IPAMOptions["driver"]; driver != "" && driver != "host-local" {
+		u.Add("Network", "IPAMDriver", driver)
+	}
+	if network.Internal {
+		u.Add("Network", "Internal", "true")
+	}
+	if network.IPv6Enabled {
+		u.Add("Network", "IPv6", "true")
+	}
+	if !network.DNSEnabled && (network.Driver == "" || network.Driver == "bridge") {
+		u.Add("Network", "DisableDNS", "true")
+	}
+	for _, key := range sortedKeys(network.Options) {
+		u.Add("Network", "Options", fmt.Sprintf("%s=%s", key, network.Options[key]))
+	}
+	for _, key := range sortedKeys(network.Labels) {
+		u.Add("Network", "Label", quadlet.Quote(key+"="+network.Labels[key]))
+	}
+
+	if err := u.Err(); err != nil {
+		return "", fmt.Errorf("network %s: %w", name, err)
+	}
+	b.add(u)
+	return ref, nil
+}
+
+// networkRefs returns the Network= values for a network mode and the networks
+// joined, generating .network units for user-defined networks
+func (p *PodmanService) networkRefs(ctx context.Context, b *quadletBundle, mode string, networks []string) ([]string, error) {
+	switch {
+	case mode == "host" || mode == "none":
+		return []string{mode}, nil
+	case strings.HasPrefix(mode, "container:") || strings.HasPrefix(mode, "ns:"):
+		return []string{mode}, nil
+	case mode == "slirp4netns" || mode == "pasta" || strings.HasPrefix(mode, "slirp4netns:") || strings.HasPrefix(mode, "pasta:"):
+		// The rootless default needs no Network= unless it carries options
+		if strings.Contains(mode, ":") {
+			return []string{mode}, nil
+		}
+		return nil, nil
+	}
+
+	refs := make([]string, 0, len(networks))
+	for _, name := range networks {
+		if name == defaultPodmanNetwork {
+			continue
+		}
+		ref, err := p.networkUnit(ctx, b, name)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// containerNetworks returns the names of the networks a container is attached to
+func containerNetworks(inspect *podmanInspect) []string {
+	networks := make([]string, 0, len(inspect.NetworkSettings.Networks))
+	for name := range inspect.NetworkSettings.Networks {
+		networks = append(networks, name)
+	}
+	sort.Strings(networks)
+	return networks
+}
+
+// publishPorts converts inspect port bindings into PublishPort= values
+func publishPorts(bindings map[string][]struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}) []string {
+	ports := make([]string, 0, len(bindings))
+	for _, spec := range sortedKeys(bindings) {
+		containerPort, protocol, _ := strings.Cut(spec, "/")
+		for _, binding := range bindings[spec] {
+			port := containerPort
+			if binding.HostPort != "" {
+				port = binding.HostPort + ":" + port
+				if binding.HostIP != "" && binding.HostIP != "0.0.0.0" {
+					port = binding.HostIP + ":" + port
+				}
+			}
+			if protocol != "" && protocol != "tcp" {
+				port += "/" + protocol
+			}
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
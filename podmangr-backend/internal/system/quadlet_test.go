@@ -0,0 +1,136 @@
+package system
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"podmangr-backend/internal/quadlet"
+)
+
+func TestGenerateContainerQuadlet(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/web/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"Id": "0123456789ab",
+			"Name": "web",
+			"Config": {
+				"Hostname": "0123456789ab",
+				"Image": "docker.io/library/nginx:latest",
+				"Env": ["container=podman", "APP_MODE=prod", "GREETING=hello world", "PROGRESS=100%"],
+				"Cmd": ["nginx", "-g", "daemon off;"],
+				"Labels": {"podmangr.webui": "true", "io.podman.annotations.foo": "bar", "note": "50% of %h"}
+			},
+			"HostConfig": {
+				"RestartPolicy": {"Name": "unless-stopped"},
+				"PortBindings": {"80/tcp": [{"HostIp": "", "HostPort": "8080"}], "53/udp": [{"HostIp": "127.0.0.1", "HostPort": "5353"}]},
+				"NetworkMode": "bridge",
+				"CapAdd": ["NET_ADMIN"],
+				"ExtraHosts": ["db:10.0.0.5"]
+			},
+			"Mounts": [
+				{"Type": "volume", "Name": "web-data", "Source": "/var/lib/containers/storage/volumes/web-data/_data", "Destination": "/data", "RW": true},
+				{"Type": "bind", "Source": "/srv/conf", "Destination": "/etc/nginx/conf.d", "RW": false}
+			],
+			"NetworkSettings": {"Networks": {"frontend": {}, "podman": {}}}
+		}`))
+	})
+	mux.HandleFunc("/volumes/web-data/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Name": "web-data", "Driver": "local", "Labels": {"app": "web"}, "Options": {}}`))
+	})
+	mux.HandleFunc("/networks/frontend/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "frontend", "driver": "bridge", "dns_enabled": true,
+			"subnets": [{"subnet": "10.89.1.0/24", "gateway": "10.89.1.1"}]}`))
+	})
+	svc := newFakePodmanSocket(t, mux)
+
+	files, err := svc.GenerateContainerQuadlet(context.Background(), "web")
+	if err != nil {
+		t.Fatalf("GenerateContainerQuadlet returned error: %v", err)
+	}
+
+	if len(files) != 3 {
+		t.Fatalf("Expected container, volume and network units, got %d: %+v", len(files), files)
+	}
+	names := []string{files[0].Name, files[1].Name, files[2].Name}
+	if names[0] != "web.container" || names[1] != "frontend.network" || names[2] != "web-data.volume" {
+		t.Errorf("Unexpected file names: %v", names)
+	}
+
+	container := files[0].Content
+	for _, want := range []string{
+		"Image=docker.io/library/nginx:latest\n",
+		"ContainerName=web\n",
+		"Network=frontend.network\n",
+		"PublishPort=127.0.0.1:5353:53/udp\n",
+		"PublishPort=8080:80\n",
+		"Volume=web-data.volume:/data\n",
+		"Volume=/srv/conf:/etc/nginx/conf.d:ro\n",
+		"Environment=APP_MODE=prod\n",
+		`Environment="GREETING=hello world"` + "\n",
+		"Label=podmangr.webui=true\n",
+		// % would otherwise be expanded as a systemd specifier
+		"Environment=PROGRESS=100%%\n",
+		`Label="note=50%% of %%h"` + "\n",
+		`Exec=nginx -g "daemon off;"` + "\n",
+		"AddCapability=NET_ADMIN\n",
+		"AddHost=db:10.0.0.5\n",
+		"[Service]\nRestart=always\n",
+		"[Install]\nWantedBy=default.target\n",
+	} {
+		if !strings.Contains(container, want) {
+			t.Errorf("Container unit missing %q:\n%s", want, container)
+		}
+	}
+	for _, unwanted := range []string{"Network=podman", "container=podman", "io.podman.annotations", "HostName="} {
+		if strings.Contains(container, unwanted) {
+			t.Errorf("Container unit should not contain %q:\n%s", unwanted, container)
+		}
+	}
+
+	if !strings.Contains(files[1].Content, "NetworkName=frontend\n") || !strings.Contains(files[1].Content, "Subnet=10.89.1.0/24\n") {
+		t.Errorf("Unexpected network unit:\n%s", files[1].Content)
+	}
+	if !strings.Contains(files[2].Content, "VolumeName=web-data\n") || !strings.Contains(files[2].Content, "Label=app=web\n") {
+		t.Errorf("Unexpected volume unit:\n%s", files[2].Content)
+	}
+}
+
+func TestWriteQuadletFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".config", "containers", "systemd")
+	files := []quadlet.File{
+		{Name: "web.container", Content: "[Container]\nImage=nginx\nPod=app.pod\n"},
+		{Name: "app.pod", Content: "[Pod]\nPodName=app\n"},
+		{Name: "data.volume", Content: "[Volume]\n"},
+	}
+
+	for _, f := range files {
+		if err := validateQuadletFileName(f.Name); err != nil {
+			t.Errorf("Expected %s to be valid: %v", f.Name, err)
+		}
+	}
+	for _, name := range []string{"../evil.container", "web.service", ".hidden.container", "a b.container"} {
+		if err := validateQuadletFileName(name); err == nil {
+			t.Errorf("Expected %q to be rejected", name)
+		}
+	}
+
+	if err := writeQuadletFiles(dir, files, ""); err != nil {
+		t.Fatalf("writeQuadletFiles returned error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "app.pod"))
+	if err != nil || string(data) != files[1].Content {
+		t.Errorf("Unexpected app.pod contents %q (err %v)", data, err)
+	}
+
+	// Pod members are started through their pod, volumes through their users
+	if isTopLevelQuadlet(files[0]) || !isTopLevelQuadlet(files[1]) || isTopLevelQuadlet(files[2]) {
+		t.Error("Unexpected top-level unit detection")
+	}
+	if got := quadlet.ServiceName("app.pod"); got != "app-pod.service" {
+		t.Errorf("Expected app-pod.service, got %s", got)
+	}
+}
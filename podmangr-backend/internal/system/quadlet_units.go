@@ -0,0 +1,338 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"podmangr-backend/internal/quadlet"
+)
+
+// rootfulQuadletDir is where Quadlet reads units for the system manager
+const rootfulQuadletDir = "/etc/containers/systemd"
+
+// quadletJournalLines is how many journal lines unit status includes
+const quadletJournalLines = 20
+
+// QuadletUnit is a Quadlet file installed on disk
+type QuadletUnit struct {
+	File    string         `json:"file"`    // e.g. "web.container"
+	Kind    string         `json:"kind"`    // container, pod, volume, network
+	Path    string         `json:"path"`    // Absolute path of the unit file
+	Service string         `json:"service"` // Generated systemd service, e.g. "web.service"
+	Status  *ServiceDetail `json:"status,omitempty"`
+}
+
+// QuadletStatus is the systemd state of a Quadlet-backed service
+type QuadletStatus struct {
+	Unit    string         `json:"unit"`
+	Scope   SystemdScope   `json:"scope"`
+	Status  *ServiceDetail `json:"status"`
+	Journal []string       `json:"journal"`
+}
+
+// QuadletInstallResult describes what InstallQuadlet wrote and started
+type QuadletInstallResult struct {
+	Directory string        `json:"directory"`
+	Scope     SystemdScope  `json:"scope"`
+	Units     []QuadletUnit `json:"units"`
+	Started   []string      `json:"started"`
+}
+
+// SystemdScope returns the systemd instance that owns this Podman's Quadlet
+// units: the system manager for rootful Podman, the user's manager otherwise
+func (p *PodmanService) SystemdScope() SystemdScope {
+	if p.targetUser != "" {
+		return SystemdScope{User: p.targetUser}
+	}
+	if os.Getuid() != 0 {
+		if current, err := user.Current(); err == nil {
+			return SystemdScope{User: current.Username}
+		}
+	}
+	return SystemdScope{}
+}
+
+// QuadletDir returns the directory Quadlet units are installed into:
+// /etc/containers/systemd for rootful Podman, ~/.config/containers/systemd
+// of the socket's user for rootless Podman
+func (p *PodmanService) QuadletDir() (string, error) {
+	scope := p.SystemdScope()
+	if !scope.IsUser() {
+		return rootfulQuadletDir, nil
+	}
+
+	u, err := user.Lookup(scope.User)
+	if err != nil {
+		return "", fmt.Errorf("unknown user %s: %w", scope.User, err)
+	}
+	return filepath.Join(u.HomeDir, ".config", "containers", "systemd"), nil
+}
+
+// InstallQuadlet writes Quadlet units, reloads systemd so Quadlet generates
+// their services and, if start is set, starts the top-level services. Units
+// carry their own [Install] section, so they are enabled by being installed.
+func (p *PodmanService) InstallQuadlet(files []quadlet.File, start bool) (*QuadletInstallResult, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no units to install")
+	}
+	for _, f := range files {
+		if err := validateQuadletFileName(f.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	dir, err := p.QuadletDir()
+	if err != nil {
+		return nil, err
+	}
+	scope := p.SystemdScope()
+
+	if err := writeQuadletFiles(dir, files, quadletFileOwner(scope)); err != nil {
+		return nil, err
+	}
+
+	if err := DaemonReload(scope); err != nil {
+		return nil, err
+	}
+
+	result := &QuadletInstallResult{
+		Directory: dir,
+		Scope:     scope,
+		Units:     make([]QuadletUnit, 0, len(files)),
+		Started:   make([]string, 0),
+	}
+	for _, f := range files {
+		result.Units = append(result.Units, quadletUnitFor(dir, f.Name))
+	}
+
+	if start {
+		// Volumes, networks and pod members are pulled in by the units that use them
+		for _, f := range files {
+			if !isTopLevelQuadlet(f) {
+				continue
+			}
+			service := quadlet.ServiceName(f.Name)
+			if err := ServiceControlInScope(scope, service, "start"); err != nil {
+				return result, err
+			}
+			result.Started = append(result.Started, service)
+		}
+	}
+
+	return result, nil
+}
+
+// ListQuadlets returns the Quadlet units installed for this Podman instance
+func (p *PodmanService) ListQuadlets() ([]QuadletUnit, error) {
+	dir, err := p.QuadletDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []QuadletUnit{}, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	scope := p.SystemdScope()
+	units := make([]QuadletUnit, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || validateQuadletFileName(entry.Name()) != nil {
+			continue
+		}
+		unit := quadletUnitFor(dir, entry.Name())
+		unit.Status, _ = GetServiceInScope(scope, unit.Service)
+		units = append(units, unit)
+	}
+
+	sort.Slice(units, func(i, j int) bool { return units[i].File < units[j].File })
+	return units, nil
+}
+
+// GetQuadletStatus returns the service state and journal tail of an installed unit
+func (p *PodmanService) GetQuadletStatus(file string) (*QuadletStatus, error) {
+	if err := validateQuadletFileName(file); err != nil {
+		return nil, err
+	}
+	return unitStatus(p.SystemdScope(), quadlet.ServiceName(file))
+}
+
+// QuadletAction starts, stops or restarts the service of an installed unit
+func (p *PodmanService) QuadletAction(file, action string) error {
+	if err := validateQuadletFileName(file); err != nil {
+		return err
+	}
+	switch action {
+	case "start", "stop", "restart":
+	default:
+		// Generated services can't be enabled with systemctl; that is what
+		// the unit's [Install] section is for
+		return fmt.Errorf("invalid action for Quadlet unit: %s", action)
+	}
+	return ServiceControlInScope(p.SystemdScope(), quadlet.ServiceName(file), action)
+}
+
+// RemoveQuadlet stops an installed unit's service, deletes the unit file and
+// reloads systemd. The container, volume or network itself is left alone.
+func (p *PodmanService) RemoveQuadlet(file string) error {
+	if err := validateQuadletFileName(file); err != nil {
+		return err
+	}
+
+	dir, err := p.QuadletDir()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, file)
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("unit not installed: %s", file)
+	}
+
+	scope := p.SystemdScope()
+	// Stopping may fail if the service never started; removal continues regardless
+	ServiceControlInScope(scope, quadlet.ServiceName(file), "stop")
+
+	if err := removeQuadletFile(path, quadletFileOwner(scope)); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return DaemonReload(scope)
+}
+
+// ContainerUnitStatus returns the systemd state of the unit managing a
+// container, or nil if the container was not started by systemd
+func (p *PodmanService) ContainerUnitStatus(inspect *podmanInspect) (*QuadletStatus, error) {
+	// Podman labels containers run from a unit (Quadlet or generate systemd)
+	unit := inspect.Config.Labels["PODMAN_SYSTEMD_UNIT"]
+	if unit == "" {
+		return nil, nil
+	}
+	return unitStatus(p.SystemdScope(), unit)
+}
+
+// unitStatus collects the service detail and journal tail of a unit
+func unitStatus(scope SystemdScope, service string) (*QuadletStatus, error) {
+	detail, err := GetServiceInScope(scope, service)
+	if err != nil {
+		return nil, err
+	}
+
+	journal, err := ServiceJournal(scope, service, quadletJournalLines)
+	if err != nil {
+		journal = []string{}
+	}
+
+	return &QuadletStatus{
+		Unit:    service,
+		Scope:   scope,
+		Status:  detail,
+		Journal: journal,
+	}, nil
+}
+
+// writeQuadletFiles writes units into dir, creating it if needed. If owner is
+// set, the files are written as that user, since dir is in the user's home.
+func writeQuadletFiles(dir string, files []quadlet.File, owner string) error {
+	if owner == "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		for _, f := range files {
+			path := filepath.Join(dir, f.Name)
+			if err := os.WriteFile(path, []byte(f.Content), 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", path, err)
+			}
+		}
+		return nil
+	}
+
+	for _, f := range files {
+		if err := writeFileAsUser(owner, filepath.Join(dir, f.Name), []byte(f.Content), "0644"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeQuadletFile deletes a unit file, as owner through sudo if it is set
+func removeQuadletFile(path, owner string) error {
+	if owner == "" {
+		return os.Remove(path)
+	}
+	return removeFileAsUser(owner, path)
+}
+
+// quadletFileOwner returns the user unit files must be written as: the
+// scope's user when root manages a rootless user's units, empty otherwise
+func quadletFileOwner(scope SystemdScope) string {
+	if scope.IsUser() && os.Getuid() == 0 {
+		return scope.User
+	}
+	return ""
+}
+
+// validateQuadletFileName rejects names that are not plain Quadlet unit files
+func validateQuadletFileName(name string) error {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid unit file name: %q", name)
+	}
+	if quadlet.UnitName(strings.TrimSuffix(name, filepath.Ext(name))) != strings.TrimSuffix(name, filepath.Ext(name)) {
+		return fmt.Errorf("invalid unit file name: %q", name)
+	}
+	switch filepath.Ext(name) {
+	case quadlet.ExtContainer, quadlet.ExtPod, quadlet.ExtVolume, quadlet.ExtNetwork:
+		return nil
+	}
+	return fmt.Errorf("unsupported unit type: %q", name)
+}
+
+// isTopLevelQuadlet reports whether a unit's service should be started
+// directly: pods and containers that are not part of a pod
+func isTopLevelQuadlet(f quadlet.File) bool {
+	switch filepath.Ext(f.Name) {
+	case quadlet.ExtPod:
+		return true
+	case quadlet.ExtContainer:
+		for _, line := range strings.Split(f.Content, "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "Pod=") {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// quadletUnitFor describes the unit file name installed in dir
+func quadletUnitFor(dir, name string) QuadletUnit {
+	return QuadletUnit{
+		File:    name,
+		Kind:    strings.TrimPrefix(filepath.Ext(name), "."),
+		Path:    filepath.Join(dir, name),
+		Service: quadlet.ServiceName(name),
+	}
+}
+
+// lookupIDs returns the numeric uid and gid of a user
+func lookupIDs(username string) (int, int, error) {
+	u, err := user.Lookup(username)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unknown user %s: %w", username, err)
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return 0, 0, err
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return 0, 0, err
+	}
+	return uid, gid, nil
+}
@@ -0,0 +1,430 @@
+package system
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"podmangr-backend/internal/auth"
+	"podmangr-backend/internal/database"
+)
+
+// ErrRegistryUnauthorized is returned when a registry rejects a login
+var ErrRegistryUnauthorized = errors.New("invalid username or password")
+
+// registryPattern matches a registry host with an optional port
+var registryPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?(:[0-9]{1,5})?$`)
+
+// registryAuth is a decrypted registry login
+type registryAuth struct {
+	Username string
+	Password string
+	Insecure bool
+}
+
+// NormalizeRegistry reduces a registry given as a URL or host to the host
+// (and port) Podman uses as the key of its auth file. The Docker Hub aliases
+// all become docker.io.
+func NormalizeRegistry(registry string) string {
+	r := strings.ToLower(strings.TrimSpace(registry))
+	r = strings.TrimPrefix(strings.TrimPrefix(r, "https://"), "http://")
+	r, _, _ = strings.Cut(r, "/")
+	switch r {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com", "hub.docker.com":
+		return "docker.io"
+	}
+	return r
+}
+
+// ValidateRegistry checks that a normalized registry is a host with an optional port
+func ValidateRegistry(registry string) error {
+	if !registryPattern.MatchString(registry) {
+		return fmt.Errorf("invalid registry %q: use a host such as ghcr.io or localhost:5000", registry)
+	}
+	return nil
+}
+
+// RegistryOfImage returns the registry an image reference is pulled from
+func RegistryOfImage(image string) string {
+	registry, _, _ := strings.Cut(normalizeImageName(image), "/")
+	return NormalizeRegistry(registry)
+}
+
+// loadRegistryAuths returns the registry logins stored for a Podman socket,
+// keyed by registry
+func loadRegistryAuths(socketID string) (map[string]registryAuth, error) {
+	auths := make(map[string]registryAuth)
+	if database.DB == nil {
+		return auths, nil
+	}
+	credentials, err := database.NewRegistryCredentialRepo().List(socketID)
+	if err != nil || len(credentials) == 0 {
+		return auths, err
+	}
+
+	encryption, err := auth.GetEncryptionService()
+	if err != nil {
+		return nil, err
+	}
+	secrets := database.NewSecretsRepo()
+	for _, c := range credentials {
+		if c.SecretID == "" {
+			continue
+		}
+		secret, err := secrets.GetByID(c.SecretID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load credentials of %s: %w", c.Registry, err)
+		}
+		password, err := encryption.Decrypt(secret.ValueEncrypted)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt credentials of %s: %w", c.Registry, err)
+		}
+		auths[c.Registry] = registryAuth{Username: c.Username, Password: password, Insecure: c.Insecure}
+	}
+	return auths, nil
+}
+
+// registryAuthFileContent renders logins in the containers-auth.json format
+func registryAuthFileContent(auths map[string]registryAuth) ([]byte, error) {
+	type entry struct {
+		Auth string `json:"auth"`
+	}
+	file := struct {
+		Auths map[string]entry `json:"auths"`
+	}{Auths: make(map[string]entry, len(auths))}
+	for registry, a := range auths {
+		file.Auths[registry] = entry{Auth: base64.StdEncoding.EncodeToString([]byte(a.Username + ":" + a.Password))}
+	}
+	return json.MarshalIndent(file, "", "  ")
+}
+
+// RegistrySocketID returns the socket ID this service's registry logins are
+// stored under: "root" for rootful Podman, "user:{username}" for rootless
+func (p *PodmanService) RegistrySocketID() string {
+	if p.targetUser != "" {
+		return "user:" + p.targetUser
+	}
+	if os.Getuid() != 0 {
+		if current, err := user.Current(); err == nil {
+			return "user:" + current.Username
+		}
+	}
+	return "root"
+}
+
+// registryAuthPath returns where the auth file of this service's Podman user
+// is kept, and the user it must be written as when running as root for a
+// rootless socket. Runtime directories are tmpfs readable only by their user,
+// so the tokens never reach the disk.
+func (p *PodmanService) registryAuthPath() (string, string, error) {
+	if p.targetUser != "" {
+		u, err := user.Lookup(p.targetUser)
+		if err != nil {
+			return "", "", err
+		}
+		owner := ""
+		if os.Getuid() == 0 {
+			owner = p.targetUser
+		}
+		return filepath.Join("/run/user", u.Uid, "podmangr", "auth.json"), owner, nil
+	}
+	if os.Getuid() == 0 {
+		return "/run/podmangr/auth.json", "", nil
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = fmt.Sprintf("/run/user/%d", os.Getuid())
+	}
+	return filepath.Join(runtimeDir, "podmangr", "auth.json"), "", nil
+}
+
+// writeRegistryAuthFile writes the logins to this service's auth file and
+// returns its path, or removes the file and returns "" if there are none
+func (p *PodmanService) writeRegistryAuthFile(auths map[string]registryAuth) (string, error) {
+	path, owner, err := p.registryAuthPath()
+	if err != nil {
+		return "", err
+	}
+	if len(auths) == 0 {
+		if owner != "" {
+			return "", removeFileAsUser(owner, path)
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		return "", nil
+	}
+
+	data, err := registryAuthFileContent(auths)
+	if err != nil {
+		return "", err
+	}
+	// Podman runs as the socket's user through sudo, so that user must own the
+	// file; root writing into the user's runtime directory would follow symlinks
+	if owner != "" {
+		if err := writeFileAsUser(owner, path, data, "0600"); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(dir, ".auth-*.json")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// registryArgs returns the podman flags that apply the stored registry logins
+// to a pull of image: the auth file, and --tls-verify=false when the image's
+// registry is marked insecure. Without stored logins, pulls stay anonymous.
+// With an empty image, as for builds pulling several base images, only the
+// auth file is given.
+func (p *PodmanService) registryArgs(image string) []string {
+	auths, err := loadRegistryAuths(p.RegistrySocketID())
+	if err != nil {
+		log.Printf("Warning: failed to load registry credentials: %v", err)
+		return nil
+	}
+	path, err := p.writeRegistryAuthFile(auths)
+	if err != nil {
+		log.Printf("Warning: failed to write registry auth file: %v", err)
+		return nil
+	}
+
+	var args []string
+	if path != "" {
+		args = append(args, "--authfile", path)
+	}
+	if image == "" {
+		return args
+	}
+	if a, ok := auths[RegistryOfImage(image)]; ok && a.Insecure {
+		args = append(args, "--tls-verify=false")
+	}
+	return args
+}
+
+// RefreshRegistryAuthFiles rewrites the auth file of every Podman socket
+// after logins changed, so removed tokens don't linger until the next pull
+func RefreshRegistryAuthFiles() error {
+	var errs []error
+	for id, svc := range GetSocketRegistry().Services() {
+		auths, err := loadRegistryAuths(svc.RegistrySocketID())
+		if err == nil {
+			_, err = svc.writeRegistryAuthFile(auths)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// registryHTTPClient returns a client for the distribution API of a
+// registry, skipping TLS verification for insecure registries
+func registryHTTPClient(insecure bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &http.Client{Transport: transport, Timeout: 30 * time.Second}
+}
+
+// registryBaseURLs returns the URLs to reach a registry at, in order of
+// preference. Insecure registries fall back to plain HTTP, as Podman does.
+func registryBaseURLs(registry string, insecure bool) []string {
+	host := registry
+	if host == "docker.io" {
+		host = "registry-1.docker.io"
+	}
+	urls := []string{"https://" + host}
+	if insecure {
+		urls = append(urls, "http://"+host)
+	}
+	return urls
+}
+
+// parseAuthChallenge splits a WWW-Authenticate header into its lowercased
+// scheme and its parameters
+func parseAuthChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+	for rest = strings.TrimSpace(rest); rest != ""; {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			// Quoted values may contain commas, e.g. scope="repository:a:pull,push"
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				params[key] = value[1:]
+				break
+			}
+			params[key] = value[1 : end+1]
+			rest = value[end+2:]
+		} else {
+			params[key], rest, _ = strings.Cut(value, ",")
+			params[key] = strings.TrimSpace(params[key])
+		}
+		rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), ","))
+	}
+	return strings.ToLower(scheme), params
+}
+
+// fetchRegistryToken requests a bearer token from the realm of a token
+// challenge, authenticating with the login if one is given
+func fetchRegistryToken(ctx context.Context, client *http.Client, challenge map[string]string, scope, username, password string) (string, error) {
+	realm, err := url.Parse(challenge["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("registry sent an invalid token realm %q", challenge["realm"])
+	}
+	query := realm.Query()
+	if service := challenge["service"]; service != "" {
+		query.Set("service", service)
+	}
+	if scope != "" {
+		query.Set("scope", scope)
+	}
+	if username != "" {
+		query.Set("account", username)
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return "", ErrRegistryUnauthorized
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("token request failed with status %s", resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if body.Token == "" {
+		body.Token = body.AccessToken
+	}
+	if body.Token == "" {
+		return "", errors.New("token response contains no token")
+	}
+	return body.Token, nil
+}
+
+// pingRegistry requests the API root of a registry with the login, answering
+// basic and token challenges
+func pingRegistry(ctx context.Context, client *http.Client, baseURL, username, password string) error {
+	get := func(authorize func(*http.Request)) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/v2/", nil)
+		if err != nil {
+			return nil, err
+		}
+		if authorize != nil {
+			authorize(req)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+		return resp, nil
+	}
+
+	resp, err := get(nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusOK {
+		// Anonymous access; the registry has nothing to check the login against
+		return nil
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return fmt.Errorf("registry answered %s", resp.Status)
+	}
+
+	scheme, challenge := parseAuthChallenge(resp.Header.Get("WWW-Authenticate"))
+	var authorize func(*http.Request)
+	switch scheme {
+	case "basic":
+		authorize = func(req *http.Request) { req.SetBasicAuth(username, password) }
+	case "bearer":
+		token, err := fetchRegistryToken(ctx, client, challenge, "", username, password)
+		if err != nil {
+			return err
+		}
+		authorize = func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }
+	default:
+		return fmt.Errorf("unsupported authentication scheme %q", scheme)
+	}
+
+	if resp, err = get(authorize); err != nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrRegistryUnauthorized
+	}
+	return fmt.Errorf("registry answered %s", resp.Status)
+}
+
+// CheckRegistryLogin checks a login against a registry's distribution API.
+// ErrRegistryUnauthorized is returned if the registry rejects it.
+func CheckRegistryLogin(ctx context.Context, registry, username, password string, insecure bool) error {
+	client := registryHTTPClient(insecure)
+	var lastErr error
+	for _, baseURL := range registryBaseURLs(registry, insecure) {
+		err := pingRegistry(ctx, client, baseURL, username, password)
+		if err == nil || errors.Is(err, ErrRegistryUnauthorized) || ctx.Err() != nil {
+			return err
+		}
+		lastErr = err
+	}
+	return fmt.Errorf("registry %s is not reachable: %w", registry, lastErr)
+}
@@ -0,0 +1,124 @@
+package system
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeRegistry stands in for registry:2 with htpasswd auth, or with token
+// auth when a token server is given
+func fakeRegistry(t *testing.T, tokenRealm string) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/" {
+			http.NotFound(w, r)
+			return
+		}
+		if tokenRealm != "" {
+			if r.Header.Get("Authorization") == "Bearer good-token" {
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+tokenRealm+`",service="registry.test",scope="repository:a:pull,push"`)
+		} else {
+			if user, pass, ok := r.BasicAuth(); ok && user == "ci" && pass == "s3cret" {
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Basic realm="Registry Realm"`)
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestCheckRegistryLogin(t *testing.T) {
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if r.URL.Query().Get("service") != "registry.test" || user != "ci" || pass != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": "good-token"})
+	}))
+	defer tokens.Close()
+
+	for name, ts := range map[string]*httptest.Server{
+		"basic": fakeRegistry(t, ""),
+		"token": fakeRegistry(t, tokens.URL+"/token"),
+	} {
+		registry := NormalizeRegistry(ts.URL)
+		ctx := context.Background()
+		if err := CheckRegistryLogin(ctx, registry, "ci", "s3cret", true); err != nil {
+			t.Errorf("%s: valid login rejected: %v", name, err)
+		}
+		if err := CheckRegistryLogin(ctx, registry, "ci", "wrong", true); !errors.Is(err, ErrRegistryUnauthorized) {
+			t.Errorf("%s: wrong password: got %v, want ErrRegistryUnauthorized", name, err)
+		}
+		// Without insecure, plain HTTP is never tried
+		if err := CheckRegistryLogin(ctx, registry, "ci", "s3cret", false); err == nil || errors.Is(err, ErrRegistryUnauthorized) {
+			t.Errorf("%s: secure login to an HTTP registry: got %v", name, err)
+		}
+	}
+}
+
+func TestRegistryNames(t *testing.T) {
+	for in, want := range map[string]string{
+		"https://GHCR.io/":              "ghcr.io",
+		"http://localhost:5000/v2/":     "localhost:5000",
+		"index.docker.io":               "docker.io",
+		"registry.example.com:8443/foo": "registry.example.com:8443",
+	} {
+		if got := NormalizeRegistry(in); got != want {
+			t.Errorf("NormalizeRegistry(%q) = %q, want %q", in, got, want)
+		}
+		if err := ValidateRegistry(NormalizeRegistry(in)); err != nil {
+			t.Errorf("ValidateRegistry(%q): %v", in, err)
+		}
+	}
+	if ValidateRegistry("bad host") == nil || ValidateRegistry("") == nil {
+		t.Error("invalid registries accepted")
+	}
+
+	for image, want := range map[string]string{
+		"nginx":                  "docker.io",
+		"library/nginx:1.27":     "docker.io",
+		"ghcr.io/org/app:v1":     "ghcr.io",
+		"localhost:5000/app:dev": "localhost:5000",
+	} {
+		if got := RegistryOfImage(image); got != want {
+			t.Errorf("RegistryOfImage(%q) = %q, want %q", image, got, want)
+		}
+	}
+
+	scheme, params := parseAuthChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:a/b:pull,push"`)
+	if scheme != "bearer" || params["realm"] != "https://auth.example.com/token" || params["scope"] != "repository:a/b:pull,push" || params["service"] != "registry.example.com" {
+		t.Errorf("parseAuthChallenge = %s %v", scheme, params)
+	}
+}
+
+func TestRegistryAuthFileContent(t *testing.T) {
+	data, err := registryAuthFileContent(map[string]registryAuth{
+		"ghcr.io": {Username: "bot", Password: "ghp_token"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	decoded, _ := base64.StdEncoding.DecodeString(file.Auths["ghcr.io"].Auth)
+	if string(decoded) != "bot:ghp_token" || strings.Contains(string(data), "ghp_token") {
+		t.Errorf("auth file = %s", data)
+	}
+}
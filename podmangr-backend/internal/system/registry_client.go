@@ -0,0 +1,514 @@
+package system
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"podmangr-backend/internal/models"
+)
+
+// ErrRegistryNotFound is returned when a repository, tag or digest is not in a registry
+var ErrRegistryNotFound = errors.New("not found in registry")
+
+const (
+	// registryMaxTags bounds the tags listed for one repository
+	registryMaxTags = 10000
+	// registryMaxBody bounds manifests and configs read from a registry
+	registryMaxBody = 8 << 20
+)
+
+// Manifest media types of the OCI image spec and Docker's schema 2
+const (
+	mediaTypeOCIIndex         = "application/vnd.oci.image.index.v1+json"
+	mediaTypeOCIManifest      = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerList       = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerManifest   = "application/vnd.docker.distribution.manifest.v2+json"
+	manifestAcceptHeader      = mediaTypeOCIIndex + ", " + mediaTypeDockerList + ", " + mediaTypeOCIManifest + ", " + mediaTypeDockerManifest
+	imageManifestAcceptHeader = mediaTypeOCIManifest + ", " + mediaTypeDockerManifest
+)
+
+// Token scopes for reading the catalog and pulling from a repository
+const (
+	registryCatalogScope       = "registry:catalog:*"
+	registryRepositoryScopeFmt = "repository:%s:pull"
+)
+
+// tagVersion splits a version tag such as v1.27.3-alpine into its prefix,
+// numeric components and suffix
+var tagVersion = regexp.MustCompile(`^(v?)(\d+(?:\.\d+)*)(.*)$`)
+
+// linkNext extracts the next page URL from a Link header
+var linkNext = regexp.MustCompile(`<([^>]+)>;\s*rel="?next"?`)
+
+// RegistryClient reads from a registry's distribution API with the stored
+// login of the registry, answering basic and token challenges
+type RegistryClient struct {
+	registry string
+	username string
+	password string
+	insecure bool
+	client   *http.Client
+	baseURL  string            // Set once a base URL answered
+	basic    bool              // The registry asked for basic auth
+	tokens   map[string]string // Bearer tokens by scope
+}
+
+// NewRegistryClient returns a client for a registry, using the login stored
+// for it on a Podman socket if there is one
+func NewRegistryClient(registry, socketID string) (*RegistryClient, error) {
+	registry = NormalizeRegistry(registry)
+	if err := ValidateRegistry(registry); err != nil {
+		return nil, err
+	}
+	auths, err := loadRegistryAuths(socketID)
+	if err != nil {
+		return nil, err
+	}
+	a := auths[registry]
+	return &RegistryClient{
+		registry: registry,
+		username: a.Username,
+		password: a.Password,
+		insecure: a.Insecure,
+		client:   registryHTTPClient(a.Insecure),
+		tokens:   make(map[string]string),
+	}, nil
+}
+
+// Registry returns the registry the client reads from
+func (r *RegistryClient) Registry() string {
+	return r.registry
+}
+
+// ParseImageReference splits an image reference into its registry, its
+// repository as the distribution API names it (library/ for official Docker
+// Hub images) and its tag or digest, "latest" if it has neither
+func ParseImageReference(image string) (string, string, string) {
+	registry, rest, _ := strings.Cut(normalizeImageName(strings.TrimSpace(image)), "/")
+	registry = NormalizeRegistry(registry)
+
+	reference := ""
+	if name, digest, ok := strings.Cut(rest, "@"); ok {
+		rest, reference = name, digest
+	}
+	if i := strings.LastIndex(rest, ":"); i > strings.LastIndex(rest, "/") {
+		if reference == "" {
+			reference = rest[i+1:]
+		}
+		rest = rest[:i]
+	}
+	if reference == "" {
+		reference = "latest"
+	}
+	if registry == "docker.io" && !strings.Contains(rest, "/") {
+		rest = "library/" + rest
+	}
+	return registry, rest, reference
+}
+
+// get requests a path of the registry API, trying each base URL until one
+// answers and authenticating when challenged
+func (r *RegistryClient) get(ctx context.Context, path, scope, accept string) (*http.Response, error) {
+	return r.request(ctx, http.MethodGet, path, scope, accept)
+}
+
+// request sends a request with the given method the way get does
+func (r *RegistryClient) request(ctx context.Context, method, path, scope, accept string) (*http.Response, error) {
+	bases := registryBaseURLs(r.registry, r.insecure)
+	if r.baseURL != "" {
+		bases = []string{r.baseURL}
+	}
+	var lastErr error
+	for _, base := range bases {
+		resp, err := r.requestFrom(ctx, method, base, path, scope, accept)
+		if err == nil {
+			r.baseURL = base
+			return resp, nil
+		}
+		if errors.Is(err, ErrRegistryUnauthorized) || ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("registry %s is not reachable: %w", r.registry, lastErr)
+}
+
+func (r *RegistryClient) requestFrom(ctx context.Context, method, base, path, scope, accept string) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, base+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if token := r.tokens[scope]; token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		} else if r.basic && r.username != "" {
+			req.SetBasicAuth(r.username, r.password)
+		}
+		return r.client.Do(req)
+	}
+
+	resp, err := send()
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	scheme, challenge := parseAuthChallenge(resp.Header.Get("WWW-Authenticate"))
+	switch scheme {
+	case "basic":
+		if r.basic || r.username == "" {
+			return nil, ErrRegistryUnauthorized
+		}
+		r.basic = true
+	case "bearer":
+		if r.tokens[scope] != "" {
+			return nil, ErrRegistryUnauthorized
+		}
+		token, err := fetchRegistryToken(ctx, r.client, challenge, scope, r.username, r.password)
+		if err != nil {
+			return nil, err
+		}
+		r.tokens[scope] = token
+	default:
+		return nil, fmt.Errorf("unsupported authentication scheme %q", scheme)
+	}
+
+	if resp, err = send(); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		return nil, ErrRegistryUnauthorized
+	}
+	return resp, nil
+}
+
+// getBody requests a path and returns the body of a successful response
+func (r *RegistryClient) getBody(ctx context.Context, path, scope, accept string) (*http.Response, []byte, error) {
+	resp, err := r.get(ctx, path, scope, accept)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, nil, ErrRegistryNotFound
+	case resp.StatusCode == http.StatusForbidden:
+		return nil, nil, ErrRegistryUnauthorized
+	case resp.StatusCode != http.StatusOK:
+		return nil, nil, fmt.Errorf("registry answered %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, registryMaxBody))
+	return resp, body, err
+}
+
+// nextPage returns the path of the next page named by a Link header, or ""
+func nextPage(resp *http.Response) string {
+	m := linkNext.FindStringSubmatch(resp.Header.Get("Link"))
+	if m == nil {
+		return ""
+	}
+	next, err := url.Parse(m[1])
+	if err != nil {
+		return ""
+	}
+	return next.RequestURI()
+}
+
+// ListTags returns the tags of a repository, e.g. library/nginx
+func (r *RegistryClient) ListTags(ctx context.Context, repository string) ([]string, error) {
+	scope := fmt.Sprintf(registryRepositoryScopeFmt, repository)
+	path := "/v2/" + repository + "/tags/list?n=1000"
+	var tags []string
+	for path != "" && len(tags) < registryMaxTags {
+		resp, body, err := r.getBody(ctx, path, scope, "")
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse tag list: %w", err)
+		}
+		tags = append(tags, page.Tags...)
+		path = nextPage(resp)
+	}
+	return tags, nil
+}
+
+// SearchCatalog returns the repositories of the registry's catalog whose
+// name contains query. Registries may not offer the catalog, Docker Hub
+// among them.
+func (r *RegistryClient) SearchCatalog(ctx context.Context, query string, limit int) ([]models.RegistryRepository, error) {
+	query = strings.ToLower(query)
+	path := "/v2/_catalog?n=1000"
+	results := make([]models.RegistryRepository, 0)
+	for pages := 0; path != "" && pages < 20 && len(results) < limit; pages++ {
+		resp, body, err := r.getBody(ctx, path, registryCatalogScope, "")
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Repositories []string `json:"repositories"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse catalog: %w", err)
+		}
+		for _, name := range page.Repositories {
+			if strings.Contains(strings.ToLower(name), query) && len(results) < limit {
+				results = append(results, models.RegistryRepository{Registry: r.registry, Name: name})
+			}
+		}
+		path = nextPage(resp)
+	}
+	return results, nil
+}
+
+// registryManifest holds the fields of image manifests and manifest lists
+type registryManifest struct {
+	MediaType string `json:"mediaType"`
+	Config    struct {
+		Digest string `json:"digest"`
+		Size   int64  `json:"size"`
+	} `json:"config"`
+	Layers []struct {
+		Size int64 `json:"size"`
+	} `json:"layers"`
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform *struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+			Variant      string `json:"variant"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+// manifest fetches a manifest and returns it with its digest and media type
+func (r *RegistryClient) manifest(ctx context.Context, repository, reference, accept string) (*registryManifest, string, string, error) {
+	scope := fmt.Sprintf(registryRepositoryScopeFmt, repository)
+	resp, body, err := r.getBody(ctx, "/v2/"+repository+"/manifests/"+reference, scope, accept)
+	if err != nil {
+		return nil, "", "", err
+	}
+	var m registryManifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, "", "", fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		sum := sha256.Sum256(body)
+		digest = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	mediaType := m.MediaType
+	if mediaType == "" {
+		mediaType, _, _ = strings.Cut(resp.Header.Get("Content-Type"), ";")
+	}
+	return &m, digest, mediaType, nil
+}
+
+// ManifestDigest returns the digest a tag or digest of a repository points
+// to, as podman pull would resolve it, without downloading the manifest
+func (r *RegistryClient) ManifestDigest(ctx context.Context, repository, reference string) (string, error) {
+	scope := fmt.Sprintf(registryRepositoryScopeFmt, repository)
+	resp, err := r.request(ctx, http.MethodHead, "/v2/"+repository+"/manifests/"+reference, scope, manifestAcceptHeader)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", ErrRegistryNotFound
+	case resp.StatusCode == http.StatusForbidden:
+		return "", ErrRegistryUnauthorized
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("registry answered %s", resp.Status)
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	// Some registries only send the digest with the manifest itself
+	_, digest, _, err := r.manifest(ctx, repository, reference, manifestAcceptHeader)
+	return digest, err
+}
+
+// platformImage reads an image manifest and its config for one platform
+func (r *RegistryClient) platformImage(ctx context.Context, repository, reference string) (*models.RegistryPlatform, error) {
+	m, digest, _, err := r.manifest(ctx, repository, reference, imageManifestAcceptHeader)
+	if err != nil {
+		return nil, err
+	}
+	platform := &models.RegistryPlatform{Digest: digest, Size: m.Config.Size}
+	for _, layer := range m.Layers {
+		platform.Size += layer.Size
+	}
+	if m.Config.Digest == "" {
+		return platform, nil
+	}
+
+	scope := fmt.Sprintf(registryRepositoryScopeFmt, repository)
+	_, body, err := r.getBody(ctx, "/v2/"+repository+"/blobs/"+m.Config.Digest, scope, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch image config: %w", err)
+	}
+	var config struct {
+		Created      *time.Time `json:"created"`
+		OS           string     `json:"os"`
+		Architecture string     `json:"architecture"`
+		Variant      string     `json:"variant"`
+	}
+	if err := json.Unmarshal(body, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse image config: %w", err)
+	}
+	platform.Created = config.Created
+	platform.OS = config.OS
+	platform.Architecture = config.Architecture
+	platform.Variant = config.Variant
+	return platform, nil
+}
+
+// InspectImage describes a tag or digest of a repository: its digest, and
+// the creation date and size of each platform's image
+func (r *RegistryClient) InspectImage(ctx context.Context, repository, reference string) (*models.RegistryImage, error) {
+	m, digest, mediaType, err := r.manifest(ctx, repository, reference, manifestAcceptHeader)
+	if err != nil {
+		return nil, err
+	}
+	image := &models.RegistryImage{
+		Registry:   r.registry,
+		Repository: repository,
+		Reference:  reference,
+		Digest:     digest,
+		MediaType:  mediaType,
+		Platforms:  make([]models.RegistryPlatform, 0),
+	}
+
+	if len(m.Manifests) == 0 {
+		platform, err := r.platformImage(ctx, repository, reference)
+		if err != nil {
+			return nil, err
+		}
+		image.Platforms = append(image.Platforms, *platform)
+		image.Created, image.Size = platform.Created, platform.Size
+		return image, nil
+	}
+
+	for _, entry := range m.Manifests {
+		// Attestations are listed with an unknown platform
+		if entry.Platform == nil || entry.Platform.OS == "unknown" {
+			continue
+		}
+		platform, err := r.platformImage(ctx, repository, entry.Digest)
+		if err != nil {
+			return nil, err
+		}
+		platform.OS = entry.Platform.OS
+		platform.Architecture = entry.Platform.Architecture
+		platform.Variant = entry.Platform.Variant
+		image.Platforms = append(image.Platforms, *platform)
+	}
+	for i, platform := range image.Platforms {
+		if i == 0 || (platform.OS == runtime.GOOS && platform.Architecture == runtime.GOARCH) {
+			image.Created, image.Size = platform.Created, platform.Size
+		}
+	}
+	return image, nil
+}
+
+// parseTagVersion splits a version tag into its numeric components and the
+// pattern of the rest, e.g. 1.27.3-alpine into [1 27 3] and "3|-alpine"
+func parseTagVersion(tag string) ([]int, string, bool) {
+	m := tagVersion.FindStringSubmatch(tag)
+	if m == nil {
+		return nil, "", false
+	}
+	parts := strings.Split(m[2], ".")
+	version := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, "", false
+		}
+		version[i] = n
+	}
+	return version, m[1] + strconv.Itoa(len(parts)) + "|" + m[3], true
+}
+
+// compareTagVersions compares tag versions with the same number of components
+func compareTagVersions(a, b []int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return 0
+}
+
+// NewerTags returns the tags that are higher versions of current with the
+// same pattern, newest first: 1.27-alpine is followed by 1.28-alpine but not
+// by 1.28 or 1.28.1-alpine. Tags that are not versions have no newer tags.
+func NewerTags(current string, tags []string) []string {
+	version, pattern, ok := parseTagVersion(current)
+	if !ok {
+		return nil
+	}
+	type candidate struct {
+		tag     string
+		version []int
+	}
+	var newer []candidate
+	for _, tag := range tags {
+		v, p, ok := parseTagVersion(tag)
+		if ok && p == pattern && compareTagVersions(v, version) > 0 {
+			newer = append(newer, candidate{tag, v})
+		}
+	}
+	sort.Slice(newer, func(i, j int) bool {
+		return compareTagVersions(newer[i].version, newer[j].version) > 0
+	})
+	result := make([]string, len(newer))
+	for i, c := range newer {
+		result[i] = c.tag
+	}
+	return result
+}
+
+// NewerImageTags looks up the tags of an image's repository that are higher
+// versions of its tag, with the logins of a Podman socket. Images pinned by
+// digest or tagged with a name such as latest have none.
+func NewerImageTags(ctx context.Context, image, socketID string) ([]string, error) {
+	registry, repository, reference := ParseImageReference(image)
+	if _, _, ok := parseTagVersion(reference); !ok || registry == "localhost" {
+		return nil, nil
+	}
+	client, err := NewRegistryClient(registry, socketID)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := client.ListTags(ctx, repository)
+	if err != nil {
+		return nil, err
+	}
+	return NewerTags(reference, tags), nil
+}
@@ -0,0 +1,139 @@
+package system
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeOCIRegistry serves library/app with token auth: tags in two pages,
+// a manifest list for 1.2 and single images for each platform
+func fakeOCIRegistry(t *testing.T) *httptest.Server {
+	t.Helper()
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("scope") != "repository:library/app:pull" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"token": "pull-token"})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer pull-token" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+ts.URL+`/token",service="fake"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v2/library/app/tags/list":
+			if r.URL.Query().Get("last") == "" {
+				w.Header().Set("Link", `</v2/library/app/tags/list?n=1000&last=1.2>; rel="next"`)
+				w.Write([]byte(`{"name":"library/app","tags":["1.1","1.2"]}`))
+				return
+			}
+			w.Write([]byte(`{"name":"library/app","tags":["1.10","latest"]}`))
+		case "/v2/library/app/manifests/1.2":
+			w.Header().Set("Content-Type", mediaTypeOCIIndex)
+			w.Header().Set("Docker-Content-Digest", "sha256:index")
+			w.Write([]byte(`{"schemaVersion":2,"manifests":[
+				{"digest":"sha256:amd","platform":{"os":"linux","architecture":"amd64"}},
+				{"digest":"sha256:arm","platform":{"os":"linux","architecture":"arm64","variant":"v8"}},
+				{"digest":"sha256:att","platform":{"os":"unknown","architecture":"unknown"}}]}`))
+		case "/v2/library/app/manifests/sha256:amd", "/v2/library/app/manifests/sha256:arm":
+			arch := strings.TrimPrefix(r.URL.Path, "/v2/library/app/manifests/sha256:")
+			w.Header().Set("Content-Type", mediaTypeOCIManifest)
+			w.Write([]byte(`{"schemaVersion":2,"config":{"digest":"sha256:cfg` + arch + `","size":100},
+				"layers":[{"size":1000},{"size":2000}]}`))
+		case "/v2/library/app/blobs/sha256:cfgamd", "/v2/library/app/blobs/sha256:cfgarm":
+			w.Write([]byte(`{"created":"2026-03-01T10:00:00Z","os":"linux","architecture":"x"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestRegistryClient(t *testing.T) {
+	ts := fakeOCIRegistry(t)
+	client := &RegistryClient{
+		registry: NormalizeRegistry(ts.URL),
+		insecure: true,
+		client:   registryHTTPClient(true),
+		tokens:   make(map[string]string),
+	}
+	ctx := context.Background()
+
+	tags, err := client.ListTags(ctx, "library/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"1.1", "1.2", "1.10", "latest"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("ListTags = %v, want %v", tags, want)
+	}
+
+	image, err := client.InspectImage(ctx, "library/app", "1.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image.Digest != "sha256:index" || image.MediaType != mediaTypeOCIIndex || len(image.Platforms) != 2 {
+		t.Fatalf("InspectImage = %+v", image)
+	}
+	arm := image.Platforms[1]
+	if arm.Architecture != "arm64" || arm.Variant != "v8" || arm.Size != 3100 || arm.Created == nil || arm.Created.Year() != 2026 {
+		t.Errorf("arm64 platform = %+v", arm)
+	}
+	if image.Size != 3100 || image.Created == nil {
+		t.Errorf("image size %d, created %v", image.Size, image.Created)
+	}
+
+	if digest, err := client.ManifestDigest(ctx, "library/app", "1.2"); err != nil || digest != "sha256:index" {
+		t.Errorf("ManifestDigest = %q, %v, want sha256:index", digest, err)
+	}
+	if _, err := client.ManifestDigest(ctx, "library/app", "9.9"); !errors.Is(err, ErrRegistryNotFound) {
+		t.Errorf("missing tag digest: got %v, want ErrRegistryNotFound", err)
+	}
+	if _, err := client.InspectImage(ctx, "library/app", "9.9"); !errors.Is(err, ErrRegistryNotFound) {
+		t.Errorf("missing tag: got %v, want ErrRegistryNotFound", err)
+	}
+	if _, err := client.ListTags(ctx, "private/app"); !errors.Is(err, ErrRegistryUnauthorized) {
+		t.Errorf("denied scope: got %v, want ErrRegistryUnauthorized", err)
+	}
+}
+
+func TestNewerTags(t *testing.T) {
+	tags := []string{"1.26", "1.27", "1.28", "1.30", "1.28-alpine", "1.29-alpine", "1.28.1", "1.31-rc1", "latest", "v1.40"}
+	for current, want := range map[string][]string{
+		"1.27":        {"1.30", "1.28"},
+		"1.27-alpine": {"1.29-alpine", "1.28-alpine"},
+		"1.30":        {},
+		"latest":      nil,
+	} {
+		got := NewerTags(current, tags)
+		if len(got) != len(want) || (len(want) > 0 && !reflect.DeepEqual(got, want)) {
+			t.Errorf("NewerTags(%q) = %v, want %v", current, got, want)
+		}
+	}
+}
+
+func TestParseImageReference(t *testing.T) {
+	for image, want := range map[string][3]string{
+		"nginx":                         {"docker.io", "library/nginx", "latest"},
+		"grafana/grafana:11.2":          {"docker.io", "grafana/grafana", "11.2"},
+		"ghcr.io/org/app@sha256:abc":    {"ghcr.io", "org/app", "sha256:abc"},
+		"localhost:5000/app:dev":        {"localhost:5000", "app", "dev"},
+		"quay.io/org/app:1.0@sha256:ff": {"quay.io", "org/app", "sha256:ff"},
+	} {
+		registry, repository, reference := ParseImageReference(image)
+		if got := [3]string{registry, repository, reference}; got != want {
+			t.Errorf("ParseImageReference(%q) = %v, want %v", image, got, want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package system
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// Wednesday
+	from := time.Date(2024, 5, 15, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"0 4 * * *", "2024-05-16 04:00"},
+		{"@hourly", "2024-05-15 11:00"},
+		{"*/15 * * * *", "2024-05-15 10:30"},
+		{"5/20 10 * * *", "2024-05-15 10:25"},
+		{"30 2 * * sun", "2024-05-19 02:30"},
+		{"0 9 * * mon-fri", "2024-05-16 09:00"},
+		{"0 0 1 jan,jul *", "2024-07-01 00:00"},
+		{"0 3 * * 7", "2024-05-19 03:00"},
+		// Day of month and day of week restricted together: either matches
+		{"0 12 1 * 5", "2024-05-17 12:00"},
+		{"0 0 29 2 *", "2028-02-29 00:00"},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q) returned error: %v", tt.spec, err)
+			continue
+		}
+		if got := schedule.Next(from).Format("2006-01-02 15:04"); got != tt.want {
+			t.Errorf("Next(%q) = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+
+	impossible, _ := ParseSchedule("0 0 30 2 *")
+	if next := impossible.Next(from); !next.IsZero() {
+		t.Errorf("Expected no run for February 30th, got %s", next)
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "@sometimes"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("Expected ParseSchedule(%q) to fail", spec)
+		}
+	}
+}
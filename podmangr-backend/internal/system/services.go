@@ -3,7 +3,9 @@ package system
 import (
 	"bufio"
 	"fmt"
+	"os"
 	"os/exec"
+	"os/user"
 	"strings"
 )
 
@@ -22,13 +24,57 @@ type Service struct {
 // ServiceDetail contains extended service information
 type ServiceDetail struct {
 	Service
-	MainPID     int      `json:"main_pid"`
-	Memory      string   `json:"memory"`
-	CPU         string   `json:"cpu"`
-	Tasks       int      `json:"tasks"`
-	StartedAt   string   `json:"started_at"`
-	ExecStart   string   `json:"exec_start"`
-	Environment []string `json:"environment"`
+	MainPID      int      `json:"main_pid"`
+	Memory       string   `json:"memory"`
+	CPU          string   `json:"cpu"`
+	Tasks        int      `json:"tasks"`
+	StartedAt    string   `json:"started_at"`
+	ExecStart    string   `json:"exec_start"`
+	Environment  []string `json:"environment"`
+	Result       string   `json:"result"`         // success, exit-code, signal, ...
+	LastExitCode int      `json:"last_exit_code"` // Exit status of the last main process
+	LastExitAt   string   `json:"last_exit_at"`
+	SourcePath   string   `json:"source_path,omitempty"` // Quadlet file a generated unit came from
+}
+
+// SystemdScope selects which systemd instance a unit belongs to: the system
+// manager, or the user manager of User (used for rootless Podman)
+type SystemdScope struct {
+	User string `json:"user,omitempty"`
+}
+
+// IsUser returns true for units of a user manager
+func (s SystemdScope) IsUser() bool {
+	return s.User != ""
+}
+
+// command builds a systemd tool invocation for the scope. User units are
+// reached through `--user`; when running as root the command runs as the
+// target user with that user's runtime directory so it finds the user bus.
+func (s SystemdScope) command(tool string, args ...string) (*exec.Cmd, error) {
+	if !s.IsUser() {
+		return exec.Command(tool, args...), nil
+	}
+
+	args = append([]string{"--user"}, args...)
+	if current, err := user.Current(); err == nil && current.Username == s.User {
+		return exec.Command(tool, args...), nil
+	}
+	if os.Getuid() != 0 {
+		return nil, fmt.Errorf("cannot manage user units of %s without root", s.User)
+	}
+
+	u, err := user.Lookup(s.User)
+	if err != nil {
+		return nil, fmt.Errorf("unknown user %s: %w", s.User, err)
+	}
+	runtimeDir := fmt.Sprintf("/run/user/%s", u.Uid)
+	sudoArgs := []string{"-u", s.User, "env",
+		"XDG_RUNTIME_DIR=" + runtimeDir,
+		"DBUS_SESSION_BUS_ADDRESS=unix:path=" + runtimeDir + "/bus",
+		tool,
+	}
+	return exec.Command("sudo", append(sudoArgs, args...)...), nil
 }
 
 // ListServices returns all systemd services
@@ -108,6 +154,11 @@ func getEnabledServices() (map[string]bool, error) {
 
 // GetService returns detailed information about a service
 func GetService(name string) (*ServiceDetail, error) {
+	return GetServiceInScope(SystemdScope{}, name)
+}
+
+// GetServiceInScope returns detailed information about a system or user service
+func GetServiceInScope(scope SystemdScope, name string) (*ServiceDetail, error) {
 	// Add .service suffix if not present
 	unitName := name
 	if !strings.HasSuffix(name, ".service") {
@@ -115,8 +166,11 @@ func GetService(name string) (*ServiceDetail, error) {
 	}
 
 	// Get service status
-	cmd := exec.Command("systemctl", "show", unitName,
-		"--property=Id,Description,LoadState,ActiveState,SubState,MainPID,MemoryCurrent,CPUUsageNSec,TasksCurrent,ActiveEnterTimestamp,ExecStart,UnitFileState")
+	cmd, err := scope.command("systemctl", "show", unitName,
+		"--property=Id,Description,LoadState,ActiveState,SubState,MainPID,MemoryCurrent,CPUUsageNSec,TasksCurrent,ActiveEnterTimestamp,ExecStart,UnitFileState,Result,ExecMainStatus,ExecMainExitTimestamp,SourcePath")
+	if err != nil {
+		return nil, err
+	}
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get service info: %w", err)
@@ -171,6 +225,14 @@ func GetService(name string) (*ServiceDetail, error) {
 			detail.ExecStart = value
 		case "UnitFileState":
 			detail.Enabled = value == "enabled" || value == "static"
+		case "Result":
+			detail.Result = value
+		case "ExecMainStatus":
+			fmt.Sscanf(value, "%d", &detail.LastExitCode)
+		case "ExecMainExitTimestamp":
+			detail.LastExitAt = value
+		case "SourcePath":
+			detail.SourcePath = value
 		}
 	}
 
@@ -179,6 +241,11 @@ func GetService(name string) (*ServiceDetail, error) {
 
 // ServiceControl performs an action on a service
 func ServiceControl(name string, action string) error {
+	return ServiceControlInScope(SystemdScope{}, name, action)
+}
+
+// ServiceControlInScope performs an action on a system or user service
+func ServiceControlInScope(scope SystemdScope, name string, action string) error {
 	// Validate action
 	validActions := map[string]bool{
 		"start":   true,
@@ -200,7 +267,10 @@ func ServiceControl(name string, action string) error {
 	}
 
 	// Execute systemctl command
-	cmd := exec.Command("systemctl", action, unitName)
+	cmd, err := scope.command("systemctl", action, unitName)
+	if err != nil {
+		return err
+	}
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to %s service: %s - %s", action, err, string(output))
@@ -209,6 +279,57 @@ func ServiceControl(name string, action string) error {
 	return nil
 }
 
+// DaemonReload makes systemd re-read unit files (and re-run generators such as Quadlet)
+func DaemonReload(scope SystemdScope) error {
+	cmd, err := scope.command("systemctl", "daemon-reload")
+	if err != nil {
+		return err
+	}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to reload systemd: %s - %s", err, string(output))
+	}
+	return nil
+}
+
+// ServiceJournal returns the last lines of a service's journal
+func ServiceJournal(scope SystemdScope, name string, lines int) ([]string, error) {
+	unitName := name
+	if !strings.HasSuffix(name, ".service") {
+		unitName = name + ".service"
+	}
+
+	args := []string{"-n", fmt.Sprintf("%d", lines), "--no-pager", "-o", "short-iso"}
+	var cmd *exec.Cmd
+	if scope.IsUser() && os.Getuid() == 0 {
+		// Root reads user units from the system journal, limited to that user
+		u, err := user.Lookup(scope.User)
+		if err != nil {
+			return nil, fmt.Errorf("unknown user %s: %w", scope.User, err)
+		}
+		cmd = exec.Command("journalctl", append([]string{"--user-unit", unitName, "_UID=" + u.Uid}, args...)...)
+	} else {
+		var err error
+		cmd, err = scope.command("journalctl", append([]string{"-u", unitName}, args...)...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	result := make([]string, 0, lines)
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		if line != "" && line != "-- No entries --" {
+			result = append(result, line)
+		}
+	}
+	return result, nil
+}
+
 // Helper functions
 func parseBytes(s string) (uint64, error) {
 	var value uint64
@@ -51,13 +51,7 @@ func (r *SocketRegistry) initializeSockets() {
 
 	for _, socket := range sockets {
 		if socket.Accessible {
-			var svc *PodmanService
-			if socket.Mode == "rootful" {
-				svc = &PodmanService{targetUser: ""}
-			} else {
-				svc = NewPodmanServiceWithUser(socket.User)
-			}
-			r.services[socket.ID] = svc
+			r.services[socket.ID] = newServiceForSocket(socket)
 
 			// Set first accessible socket as current if none set
 			if r.currentSocket == "" {
@@ -73,7 +67,9 @@ func (r *SocketRegistry) initializeSockets() {
 	}
 }
 
-// GetCurrentService returns the currently active PodmanService
+// GetCurrentService returns the default PodmanService.
+// Requests normally resolve their own socket (see api.PodmanContext); this is
+// only used when neither the request nor the user's preferences pick one.
 func (r *SocketRegistry) GetCurrentService() *PodmanService {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -86,14 +82,14 @@ func (r *SocketRegistry) GetCurrentService() *PodmanService {
 	return NewPodmanService()
 }
 
-// GetCurrentSocketID returns the ID of the currently active socket
+// GetCurrentSocketID returns the ID of the default socket
 func (r *SocketRegistry) GetCurrentSocketID() string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	return r.currentSocket
 }
 
-// SwitchSocket changes the active Podman socket
+// SwitchSocket changes the default Podman socket
 func (r *SocketRegistry) SwitchSocket(socketID string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -117,6 +113,18 @@ func (r *SocketRegistry) GetService(socketID string) (*PodmanService, error) {
 	return nil, fmt.Errorf("socket not found: %s", socketID)
 }
 
+// Services returns a snapshot of all registered services keyed by socket ID
+func (r *SocketRegistry) Services() map[string]*PodmanService {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	services := make(map[string]*PodmanService, len(r.services))
+	for id, svc := range r.services {
+		services[id] = svc
+	}
+	return services
+}
+
 // ListSockets returns all discovered sockets with their status
 func (r *SocketRegistry) ListSockets() []PodmanSocket {
 	r.mu.RLock()
@@ -147,13 +155,7 @@ func (r *SocketRegistry) RefreshSockets() {
 
 	for _, socket := range sockets {
 		if socket.Accessible {
-			var svc *PodmanService
-			if socket.Mode == "rootful" {
-				svc = &PodmanService{targetUser: ""}
-			} else {
-				svc = NewPodmanServiceWithUser(socket.User)
-			}
-			r.services[socket.ID] = svc
+			r.services[socket.ID] = newServiceForSocket(socket)
 		}
 	}
 
@@ -168,6 +170,14 @@ func (r *SocketRegistry) RefreshSockets() {
 	}
 }
 
+// newServiceForSocket creates a PodmanService bound to a discovered socket
+func newServiceForSocket(socket PodmanSocket) *PodmanService {
+	if socket.Mode == "rootful" {
+		return NewPodmanServiceWithSocket("", socket.Path)
+	}
+	return NewPodmanServiceWithSocket(socket.User, socket.Path)
+}
+
 // DiscoverSockets finds all available Podman sockets on the system
 func DiscoverSockets() []PodmanSocket {
 	var sockets []PodmanSocket
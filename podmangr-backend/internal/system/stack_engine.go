@@ -0,0 +1,532 @@
+package system
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"podmangr-backend/internal/models"
+	"podmangr-backend/internal/translator"
+)
+
+// StackEvent is one step of a stack operation, streamed to the client
+type StackEvent struct {
+	Step     string `json:"step"`     // network, volume, pod, pull, build, create, start, wait, stop, remove
+	Resource string `json:"resource"` // Name of the network, volume, image or container
+	Status   string `json:"status"`   // running, done, skipped, failed
+	Message  string `json:"message,omitempty"`
+}
+
+// Stack event statuses
+const (
+	StackEventRunning = "running"
+	StackEventDone    = "done"
+	StackEventSkipped = "skipped"
+	StackEventFailed  = "failed"
+)
+
+// StackUpOptions controls how a stack is reconciled
+type StackUpOptions struct {
+	// Pull pulls every image, not only missing ones
+	Pull bool
+	// Build rebuilds the images of services with a build section, not only missing ones
+	Build bool
+	// RemoveOrphans removes project containers of services no longer in the plan
+	RemoveOrphans bool
+	// HealthTimeout bounds waiting for service_healthy and
+	// service_completed_successfully dependencies
+	HealthTimeout time.Duration
+}
+
+// defaultHealthTimeout is used when StackUpOptions.HealthTimeout is zero
+const defaultHealthTimeout = 5 * time.Minute
+
+// healthPollInterval is how often dependency conditions are checked
+var healthPollInterval = 2 * time.Second
+
+// stackEngine runs stack operations and reports their progress
+type stackEngine struct {
+	p      *PodmanService
+	events chan<- StackEvent
+}
+
+func (e *stackEngine) emit(step, resource, status, message string) {
+	if e.events != nil {
+		e.events <- StackEvent{Step: step, Resource: resource, Status: status, Message: message}
+	}
+}
+
+// fail reports a failed step and returns its error
+func (e *stackEngine) fail(step, resource string, err error) error {
+	e.emit(step, resource, StackEventFailed, err.Error())
+	return fmt.Errorf("%s %s: %w", step, resource, err)
+}
+
+// StackUp reconciles a planned stack: it creates missing networks, volumes
+// and the pod, then creates and starts each service once its dependencies
+// meet their depends_on condition. Containers whose configuration and image
+// are unchanged are kept. Progress is sent to events, which may be nil.
+func (p *PodmanService) StackUp(ctx context.Context, plan *translator.Plan, opts StackUpOptions, events chan<- StackEvent) error {
+	e := &stackEngine{p: p, events: events}
+	if opts.HealthTimeout == 0 {
+		opts.HealthTimeout = defaultHealthTimeout
+	}
+
+	for _, n := range plan.Networks {
+		if err := e.ensure(ctx, "network", n); err != nil {
+			return err
+		}
+	}
+	for _, v := range plan.Volumes {
+		if err := e.ensure(ctx, "volume", v); err != nil {
+			return err
+		}
+	}
+	if plan.Pod != nil {
+		if p.objectExists(ctx, "pod", plan.Pod.Name) {
+			e.emit("pod", plan.Pod.Name, StackEventSkipped, "already exists")
+		} else {
+			e.emit("pod", plan.Pod.Name, StackEventRunning, "")
+			if _, err := p.podmanCmd(ctx, plan.Pod.Args...); err != nil {
+				return e.fail("pod", plan.Pod.Name, err)
+			}
+			e.emit("pod", plan.Pod.Name, StackEventDone, "")
+		}
+	}
+
+	existing, err := p.projectContainers(ctx, plan.Project)
+	if err != nil {
+		return fmt.Errorf("failed to list stack containers: %w", err)
+	}
+
+	services := make(map[string]translator.PlannedService, len(plan.Services))
+	for _, s := range plan.Services {
+		services[s.Name] = s
+	}
+
+	for _, service := range plan.Services {
+		for _, dep := range service.DependsOn {
+			target, ok := services[dep.Service]
+			if !ok {
+				continue
+			}
+			if err := e.waitFor(ctx, service.Name, target, dep, opts.HealthTimeout); err != nil {
+				if dep.Required {
+					return err
+				}
+				e.emit("wait", dep.Service, StackEventSkipped, "optional dependency: "+err.Error())
+			}
+		}
+
+		if service.Build != nil {
+			err = e.build(ctx, service, opts.Build)
+		} else {
+			err = e.pull(ctx, service.Image, opts.Pull)
+		}
+		if err != nil {
+			return err
+		}
+		imageID := p.imageID(ctx, service.Image)
+
+		for _, c := range service.Containers {
+			if err := e.upContainer(ctx, c, existing[c.Name], imageID); err != nil {
+				return err
+			}
+			delete(existing, c.Name)
+		}
+	}
+
+	// Whatever is left belongs to removed services or replicas
+	for _, name := range sortedContainerNames(existing) {
+		c := existing[name]
+		_, inPlan := services[c.Labels[translator.LabelService]]
+		if inPlan || opts.RemoveOrphans {
+			e.emit("remove", name, StackEventRunning, "")
+			if err := p.RemoveContainer(ctx, c.ID, true); err != nil {
+				return e.fail("remove", name, err)
+			}
+			e.emit("remove", name, StackEventDone, "no longer in the compose file")
+		} else {
+			e.emit("remove", name, StackEventSkipped, "orphaned container kept")
+		}
+	}
+
+	return nil
+}
+
+// ensure creates a network or volume unless it exists. External ones must
+// exist already.
+func (e *stackEngine) ensure(ctx context.Context, kind string, res translator.PlannedResource) error {
+	if e.p.objectExists(ctx, kind, res.Name) {
+		e.emit(kind, res.Name, StackEventSkipped, "already exists")
+		return nil
+	}
+	if res.External {
+		return e.fail(kind, res.Name, fmt.Errorf("external %s does not exist", kind))
+	}
+
+	e.emit(kind, res.Name, StackEventRunning, "")
+	if _, err := e.p.podmanCmd(ctx, res.Args...); err != nil {
+		return e.fail(kind, res.Name, err)
+	}
+	e.emit(kind, res.Name, StackEventDone, "")
+	return nil
+}
+
+// pull fetches an image when it is missing or always is set
+func (e *stackEngine) pull(ctx context.Context, image string, always bool) error {
+	if !always && e.p.ImageExists(ctx, image) {
+		e.emit("pull", image, StackEventSkipped, "image present")
+		return nil
+	}
+	e.emit("pull", image, StackEventRunning, "")
+	if err := e.p.PullImage(ctx, image); err != nil {
+		return e.fail("pull", image, err)
+	}
+	e.emit("pull", image, StackEventDone, "")
+	return nil
+}
+
+// build builds the image of a service when it is missing or always is set,
+// sending each line of build output as a running event
+func (e *stackEngine) build(ctx context.Context, service translator.PlannedService, always bool) error {
+	if !always && e.p.ImageExists(ctx, service.Image) {
+		e.emit("build", service.Image, StackEventSkipped, "image present")
+		return nil
+	}
+	e.emit("build", service.Image, StackEventRunning, "")
+
+	output := make(chan string, 16)
+	done := make(chan error, 1)
+	go func() {
+		_, err := e.p.BuildImage(ctx, buildRequestForService(service), output)
+		done <- err
+	}()
+	for line := range output {
+		e.emit("build", service.Image, StackEventRunning, line)
+	}
+	if err := <-done; err != nil {
+		return e.fail("build", service.Image, err)
+	}
+	e.emit("build", service.Image, StackEventDone, "")
+	return nil
+}
+
+// buildRequestForService converts the build section of a planned stack
+// service to a build request tagging the service image
+func buildRequestForService(service translator.PlannedService) *models.BuildImageRequest {
+	b := service.Build
+	return &models.BuildImageRequest{
+		Tags:              append([]string{service.Image}, b.Tags...),
+		ContextDir:        b.ContextDir,
+		GitURL:            b.GitURL,
+		Containerfile:     b.Inline,
+		ContainerfilePath: b.Containerfile,
+		BuildArgs:         b.Args,
+		Labels:            b.Labels,
+		Target:            b.Target,
+		Platform:          b.Platform,
+		NoCache:           b.NoCache,
+		Pull:              b.Pull,
+		CacheFrom:         b.CacheFrom,
+		CacheTo:           b.CacheTo,
+	}
+}
+
+// upContainer creates and starts a container, keeping an existing one with
+// the same configuration hash and image
+func (e *stackEngine) upContainer(ctx context.Context, c translator.PlannedContainer, current *podmanContainer, imageID string) error {
+	if current != nil {
+		unchanged := current.Labels[translator.LabelConfigHash] == c.Hash &&
+			(imageID == "" || current.ImageID == imageID)
+		if unchanged {
+			if current.State == "running" {
+				e.emit("create", c.Name, StackEventSkipped, "up to date")
+				return nil
+			}
+			return e.start(ctx, c.Name)
+		}
+
+		e.emit("remove", c.Name, StackEventRunning, "configuration changed")
+		if err := e.p.RemoveContainer(ctx, current.ID, true); err != nil {
+			return e.fail("remove", c.Name, err)
+		}
+		e.emit("remove", c.Name, StackEventDone, "")
+	}
+
+	e.emit("create", c.Name, StackEventRunning, "")
+	if _, err := e.p.podmanCmd(ctx, c.Args...); err != nil {
+		return e.fail("create", c.Name, err)
+	}
+	e.emit("create", c.Name, StackEventDone, "")
+	return e.start(ctx, c.Name)
+}
+
+func (e *stackEngine) start(ctx context.Context, name string) error {
+	e.emit("start", name, StackEventRunning, "")
+	if err := e.p.StartContainer(ctx, name); err != nil {
+		return e.fail("start", name, err)
+	}
+	e.emit("start", name, StackEventDone, "")
+	return nil
+}
+
+// waitFor blocks until every container of target meets the dependency condition
+func (e *stackEngine) waitFor(ctx context.Context, service string, target translator.PlannedService, dep translator.Dependency, timeout time.Duration) error {
+	if dep.Condition == "service_started" || dep.Condition == "" {
+		// The target was started earlier in dependency order
+		return nil
+	}
+	if dep.Condition == "service_healthy" && !target.HasHealthcheck {
+		return e.fail("wait", target.Name, fmt.Errorf("%s waits for it to be healthy but it has no healthcheck", service))
+	}
+
+	e.emit("wait", target.Name, StackEventRunning, fmt.Sprintf("%s waits for %s", service, dep.Condition))
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for _, c := range target.Containers {
+		for {
+			met, err := e.p.conditionMet(ctx, c.Name, dep.Condition)
+			if err != nil {
+				return e.fail("wait", target.Name, err)
+			}
+			if met {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return e.fail("wait", target.Name, fmt.Errorf("timed out waiting for %s", dep.Condition))
+			case <-time.After(healthPollInterval):
+			}
+		}
+	}
+
+	e.emit("wait", target.Name, StackEventDone, dep.Condition)
+	return nil
+}
+
+// conditionMet checks a depends_on condition against a container's state.
+// An error means the condition can no longer be met.
+func (p *PodmanService) conditionMet(ctx context.Context, name, condition string) (bool, error) {
+	inspect, err := p.InspectContainer(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	state := inspect.State
+
+	switch condition {
+	case "service_healthy":
+		if state.Health != nil {
+			switch state.Health.Status {
+			case "healthy":
+				return true, nil
+			case "unhealthy":
+				return false, fmt.Errorf("%s is unhealthy", name)
+			}
+		}
+		if !state.Running && state.Status != "created" {
+			return false, fmt.Errorf("%s exited with code %d", name, state.ExitCode)
+		}
+		return false, nil
+	case "service_completed_successfully":
+		if state.Running || state.Status == "created" {
+			return false, nil
+		}
+		if state.ExitCode != 0 {
+			return false, fmt.Errorf("%s exited with code %d", name, state.ExitCode)
+		}
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown condition %s", condition)
+}
+
+// StackDown stops and removes the containers, pod and networks of a stack,
+// and its volumes when removeVolumes is set
+func (p *PodmanService) StackDown(ctx context.Context, project string, removeVolumes bool, events chan<- StackEvent) error {
+	e := &stackEngine{p: p, events: events}
+
+	containers, err := p.projectContainers(ctx, project)
+	if err != nil {
+		return fmt.Errorf("failed to list stack containers: %w", err)
+	}
+	for _, c := range byCreated(containers, true) {
+		name := c.Names[0]
+		e.emit("remove", name, StackEventRunning, "")
+		if err := p.RemoveContainer(ctx, c.ID, true); err != nil {
+			return e.fail("remove", name, err)
+		}
+		e.emit("remove", name, StackEventDone, "")
+	}
+
+	pod := "pod_" + project
+	if p.objectExists(ctx, "pod", pod) {
+		e.emit("remove", pod, StackEventRunning, "")
+		if _, err := p.podmanCmd(ctx, "pod", "rm", "-f", pod); err != nil {
+			return e.fail("remove", pod, err)
+		}
+		e.emit("remove", pod, StackEventDone, "")
+	}
+
+	networks, err := p.ListNetworks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list networks: %w", err)
+	}
+	for _, n := range networks {
+		if n.Labels[translator.LabelProject] != project {
+			continue
+		}
+		e.emit("remove", n.Name, StackEventRunning, "")
+		if err := p.RemoveNetwork(ctx, n.Name, false); err != nil {
+			return e.fail("remove", n.Name, err)
+		}
+		e.emit("remove", n.Name, StackEventDone, "")
+	}
+
+	if !removeVolumes {
+		return nil
+	}
+	volumes, err := p.ListVolumes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list volumes: %w", err)
+	}
+	for _, v := range volumes {
+		if v.Labels[translator.LabelProject] != project {
+			continue
+		}
+		e.emit("remove", v.Name, StackEventRunning, "")
+		if err := p.RemoveVolume(ctx, v.Name, false); err != nil {
+			return e.fail("remove", v.Name, err)
+		}
+		e.emit("remove", v.Name, StackEventDone, "")
+	}
+	return nil
+}
+
+// StackPull pulls the images of a planned stack
+func (p *PodmanService) StackPull(ctx context.Context, plan *translator.Plan, events chan<- StackEvent) error {
+	e := &stackEngine{p: p, events: events}
+	pulled := make(map[string]bool)
+	for _, s := range plan.Services {
+		if pulled[s.Image] {
+			continue
+		}
+		pulled[s.Image] = true
+		if s.Build != nil {
+			e.emit("pull", s.Image, StackEventSkipped, "built from source")
+			continue
+		}
+		if err := e.pull(ctx, s.Image, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StackStart starts the stopped containers of a stack, oldest first so
+// dependencies come up before the services that use them
+func (p *PodmanService) StackStart(ctx context.Context, project string) error {
+	containers, err := p.projectContainers(ctx, project)
+	if err != nil {
+		return err
+	}
+	for _, c := range byCreated(containers, false) {
+		if c.State == "running" {
+			continue
+		}
+		if err := p.StartContainer(ctx, c.ID); err != nil {
+			return fmt.Errorf("failed to start %s: %w", c.Names[0], err)
+		}
+	}
+	return nil
+}
+
+// StackStop stops the containers of a stack, newest first
+func (p *PodmanService) StackStop(ctx context.Context, project string) error {
+	containers, err := p.projectContainers(ctx, project)
+	if err != nil {
+		return err
+	}
+	for _, c := range byCreated(containers, true) {
+		if c.State != "running" {
+			continue
+		}
+		if err := p.StopContainer(ctx, c.ID, 0); err != nil {
+			return fmt.Errorf("failed to stop %s: %w", c.Names[0], err)
+		}
+	}
+	return nil
+}
+
+// StackRestart stops and starts the containers of a stack
+func (p *PodmanService) StackRestart(ctx context.Context, project string) error {
+	if err := p.StackStop(ctx, project); err != nil {
+		return err
+	}
+	return p.StackStart(ctx, project)
+}
+
+// projectContainers returns the containers labelled with a project, by name
+func (p *PodmanService) projectContainers(ctx context.Context, project string) (map[string]*podmanContainer, error) {
+	containers, err := p.listContainers(ctx, map[string][]string{
+		"label": {translator.LabelProject + "=" + project},
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*podmanContainer, len(containers))
+	for i := range containers {
+		if len(containers[i].Names) > 0 {
+			result[containers[i].Names[0]] = &containers[i]
+		}
+	}
+	return result, nil
+}
+
+// objectExists reports whether a network, volume or pod exists
+func (p *PodmanService) objectExists(ctx context.Context, kind, name string) bool {
+	_, err := p.podmanCmd(ctx, kind, "exists", name)
+	return err == nil
+}
+
+// imageID returns the ID of a local image, or "" when it can't be inspected
+func (p *PodmanService) imageID(ctx context.Context, image string) string {
+	output, err := p.podmanCmd(ctx, "image", "inspect", "--format", "{{.Id}}", normalizeImageName(image))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// byCreated orders containers by creation time, then container number
+func byCreated(containers map[string]*podmanContainer, newestFirst bool) []*podmanContainer {
+	list := make([]*podmanContainer, 0, len(containers))
+	for _, c := range containers {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Created == b.Created {
+			na, _ := strconv.Atoi(a.Labels[translator.LabelContainerNumber])
+			nb, _ := strconv.Atoi(b.Labels[translator.LabelContainerNumber])
+			if na == nb {
+				return (a.Names[0] < b.Names[0]) != newestFirst
+			}
+			return (na < nb) != newestFirst
+		}
+		return (a.Created < b.Created) != newestFirst
+	})
+	return list
+}
+
+func sortedContainerNames(containers map[string]*podmanContainer) []string {
+	names := make([]string, 0, len(containers))
+	for name := range containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,159 @@
+package system
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"podmangr-backend/internal/models"
+)
+
+// DefaultUpdateHealthTimeout is how long an updated container has to become healthy
+const DefaultUpdateHealthTimeout = 60 * time.Second
+
+// probeTimeout bounds a single HTTP or TCP probe
+const probeTimeout = 5 * time.Second
+
+// WaitHealthy waits up to timeout for a freshly started container to prove it works.
+// With a probe, it succeeds once the probe passes; otherwise once the Podman
+// healthcheck reports healthy. A container without either must stay running
+// for the whole timeout. Exiting, restarting or turning unhealthy fails at once.
+func (p *PodmanService) WaitHealthy(ctx context.Context, containerID string, probe *models.HealthProbe, timeout time.Duration, progressChan chan<- string) error {
+	inspect, err := p.InspectContainer(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container: %w", err)
+	}
+	restarts := inspect.RestartCount
+
+	var address string
+	if probe != nil {
+		address, err = probeAddress(inspect, probe.Port)
+		if err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	var lastErr error
+	for {
+		inspect, err := p.InspectContainer(ctx, containerID)
+		if err != nil {
+			return fmt.Errorf("failed to inspect container: %w", err)
+		}
+		if err := crashed(inspect, restarts); err != nil {
+			return err
+		}
+
+		switch {
+		case probe != nil:
+			if lastErr = runProbe(ctx, probe, address); lastErr == nil {
+				return nil
+			}
+		case inspect.State.Health != nil && inspect.Config.Healthcheck != nil:
+			switch inspect.State.Health.Status {
+			case "healthy":
+				return nil
+			case "unhealthy":
+				return fmt.Errorf("healthcheck failed %d times in a row", inspect.State.Health.FailingStreak)
+			}
+			lastErr = fmt.Errorf("healthcheck is still %s", inspect.State.Health.Status)
+		default:
+			if !time.Now().Before(deadline) {
+				// Stayed up for the whole grace period
+				return nil
+			}
+		}
+
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("not healthy after %s: %v", timeout, lastErr)
+		}
+		if progressChan != nil && lastErr != nil {
+			progressChan <- "Waiting for container: " + lastErr.Error()
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(healthPollInterval, time.Until(deadline))):
+		}
+	}
+}
+
+// crashed reports a container that stopped or was restarted by its restart policy
+func crashed(inspect *podmanInspect, restarts int) error {
+	state := inspect.State
+	if inspect.RestartCount > restarts || state.Restarting {
+		return fmt.Errorf("container is crash-looping (restarted %d times)", inspect.RestartCount-restarts)
+	}
+	if !state.Running {
+		if state.OOMKilled {
+			return fmt.Errorf("container was killed for running out of memory")
+		}
+		return fmt.Errorf("container exited with code %d", state.ExitCode)
+	}
+	return nil
+}
+
+// probeAddress finds the host address a container port is published on
+func probeAddress(inspect *podmanInspect, port int) (string, error) {
+	bindings := inspect.HostConfig.PortBindings[fmt.Sprintf("%d/tcp", port)]
+	for _, b := range bindings {
+		if b.HostPort == "" {
+			continue
+		}
+		host := b.HostIP
+		switch host {
+		case "", "0.0.0.0":
+			host = "127.0.0.1"
+		case "::":
+			host = "::1"
+		}
+		return net.JoinHostPort(host, b.HostPort), nil
+	}
+	return "", fmt.Errorf("container port %d is not published", port)
+}
+
+// runProbe checks an address once with an HTTP request or a TCP connection
+func runProbe(ctx context.Context, probe *models.HealthProbe, address string) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	switch probe.Type {
+	case "tcp":
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case "http", "":
+		path := probe.Path
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+address+path, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if probe.ExpectStatus != 0 {
+			if resp.StatusCode != probe.ExpectStatus {
+				return fmt.Errorf("%s returned %d, expected %d", path, resp.StatusCode, probe.ExpectStatus)
+			}
+			return nil
+		}
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("%s returned %d", path, resp.StatusCode)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown probe type %s", probe.Type)
+}
@@ -0,0 +1,78 @@
+package system
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"podmangr-backend/internal/models"
+)
+
+func TestWaitHealthy(t *testing.T) {
+	healthPollInterval = 10 * time.Millisecond
+	defer func() { healthPollInterval = 2 * time.Second }()
+
+	// The probed application answers 503 twice before it is ready
+	var hits atomic.Int32
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ready" || hits.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer app.Close()
+	_, appPort, _ := net.SplitHostPort(strings.TrimPrefix(app.URL, "http://"))
+
+	// Each container's state is served as a libpod inspect document
+	states := map[string]string{
+		"probed":    `"State": {"Running": true}, "HostConfig": {"PortBindings": {"80/tcp": [{"HostIp": "", "HostPort": "` + appPort + `"}]}}`,
+		"healthy":   `"State": {"Running": true, "Health": {"Status": "healthy"}}, "Config": {"Healthcheck": {"Test": ["CMD", "true"]}}`,
+		"unhealthy": `"State": {"Running": true, "Health": {"Status": "unhealthy", "FailingStreak": 3}}, "Config": {"Healthcheck": {"Test": ["CMD", "false"]}}`,
+		"exited":    `"State": {"Running": false, "ExitCode": 137}`,
+		"stable":    `"State": {"Running": true}`,
+	}
+	var restarts atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/containers/"), "/json")
+		if name == "looping" {
+			fmt.Fprintf(w, `{"State": {"Running": true}, "RestartCount": %d}`, restarts.Add(1))
+			return
+		}
+		fmt.Fprintf(w, `{%s}`, states[name])
+	})
+	svc := newFakePodmanSocket(t, mux)
+	ctx := context.Background()
+
+	probe := &models.HealthProbe{Type: "http", Port: 80, Path: "ready"}
+	if err := svc.WaitHealthy(ctx, "probed", probe, 5*time.Second, nil); err != nil {
+		t.Errorf("Expected the probe to pass once the app is ready, got %v", err)
+	}
+	if hits.Load() != 3 {
+		t.Errorf("Expected 3 probe requests, got %d", hits.Load())
+	}
+	if err := svc.WaitHealthy(ctx, "probed", &models.HealthProbe{Type: "tcp", Port: 8080}, time.Second, nil); err == nil || !strings.Contains(err.Error(), "not published") {
+		t.Errorf("Expected an unpublished port error, got %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"healthy":   "",
+		"stable":    "",
+		"unhealthy": "failed 3 times",
+		"exited":    "exited with code 137",
+		"looping":   "crash-looping",
+	} {
+		err := svc.WaitHealthy(ctx, name, nil, 50*time.Millisecond, nil)
+		if want == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+		if want != "" && (err == nil || !strings.Contains(err.Error(), want)) {
+			t.Errorf("%s: expected an error containing %q, got %v", name, want, err)
+		}
+	}
+}
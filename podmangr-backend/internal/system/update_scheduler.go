@@ -0,0 +1,437 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"podmangr-backend/internal/database"
+	"podmangr-backend/internal/models"
+)
+
+const (
+	DefaultUpdateSchedule = "0 4 * * *"
+	// AutoUpdateLabel is Podman's own auto-update label ("registry" or "local")
+	AutoUpdateLabel = "io.containers.autoupdate"
+
+	updateSchedulerTick  = time.Minute
+	updateCheckTimeout   = 10 * time.Minute // per container, including an automatic update
+	defaultUpdatesPolicy = models.UpdatePolicyNotify
+)
+
+// versionTag matches tags that pin a version line, such as 1.27, v2.3.1 or 16-alpine
+var versionTag = regexp.MustCompile(`^v?\d+(\.\d+)*([-_.+][0-9A-Za-z.-]+)?$`)
+
+// UpdateScheduler checks the images of managed containers, and of containers
+// carrying the io.containers.autoupdate label, on a cron schedule. Depending on
+// the container's policy it records the available update or applies it.
+type UpdateScheduler struct {
+	registry      *SocketRegistry
+	updateRepo    *database.ContainerUpdateRepo
+	containerRepo *database.ContainerRepo
+	settingsRepo  *database.SettingsRepo
+
+	// checking serializes scheduled and manually triggered runs
+	checking sync.Mutex
+
+	mu      sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+	running bool
+}
+
+// NewUpdateScheduler creates a new update scheduler
+func NewUpdateScheduler() *UpdateScheduler {
+	return &UpdateScheduler{
+		registry:      GetSocketRegistry(),
+		updateRepo:    database.NewContainerUpdateRepo(),
+		containerRepo: database.NewContainerRepo(),
+		settingsRepo:  database.NewSettingsRepo(),
+	}
+}
+
+// Start launches the background scheduling loop
+func (s *UpdateScheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return
+	}
+	s.running = true
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go s.run(s.stop, s.done)
+}
+
+// Stop stops the scheduling loop and waits for it to exit
+func (s *UpdateScheduler) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	close(s.stop)
+	done := s.done
+	s.mu.Unlock()
+
+	<-done
+}
+
+func (s *UpdateScheduler) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	var spec string
+	var next time.Time
+	for {
+		// Settings are re-read every tick so changes apply without a restart
+		if s.enabled() {
+			if current := s.schedule(); current != spec {
+				spec = current
+				next = nextScheduledRun(spec, time.Now())
+			}
+			if !next.IsZero() && !time.Now().Before(next) {
+				if _, err := s.CheckAll(context.Background()); err != nil {
+					log.Printf("Scheduled update check error: %v", err)
+				}
+				next = nextScheduledRun(spec, time.Now())
+			}
+		} else {
+			spec = ""
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(updateSchedulerTick):
+		}
+	}
+}
+
+// nextScheduledRun returns the next run of a schedule, or zero if it is invalid
+func nextScheduledRun(spec string, now time.Time) time.Time {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		log.Printf("Invalid update schedule %q: %v", spec, err)
+		return time.Time{}
+	}
+	return schedule.Next(now)
+}
+
+// enabled reports whether scheduled checks are turned on (defaults to true)
+func (s *UpdateScheduler) enabled() bool {
+	if _, err := s.settingsRepo.Get(database.SettingUpdatesEnabled); err != nil {
+		return true
+	}
+	enabled, _ := s.settingsRepo.GetBool(database.SettingUpdatesEnabled)
+	return enabled
+}
+
+// schedule returns the configured cron schedule
+func (s *UpdateScheduler) schedule() string {
+	spec, err := s.settingsRepo.Get(database.SettingUpdatesSchedule)
+	if err != nil || strings.TrimSpace(spec) == "" {
+		return DefaultUpdateSchedule
+	}
+	return spec
+}
+
+// defaultPolicy returns the policy of managed containers without their own
+func (s *UpdateScheduler) defaultPolicy() string {
+	policy, err := s.settingsRepo.Get(database.SettingUpdatesPolicy)
+	if err != nil || !ValidUpdatePolicy(policy) {
+		return defaultUpdatesPolicy
+	}
+	return policy
+}
+
+// ValidUpdatePolicy reports whether policy is one of the known update policies
+func ValidUpdatePolicy(policy string) bool {
+	switch policy {
+	case models.UpdatePolicyDisabled, models.UpdatePolicyNotify, models.UpdatePolicyPatch, models.UpdatePolicyAuto:
+		return true
+	}
+	return false
+}
+
+// CheckAll checks every participating container on every socket, applies
+// updates the policies allow and returns the recorded states. Sockets that
+// fail are reported but don't block the others.
+func (s *UpdateScheduler) CheckAll(ctx context.Context) ([]models.ContainerUpdateState, error) {
+	s.checking.Lock()
+	defer s.checking.Unlock()
+
+	managed := make(map[string]bool)
+	if containers, err := s.containerRepo.List(); err == nil {
+		for _, c := range containers {
+			managed[c.ContainerID] = true
+			managed[c.Name] = true
+		}
+	}
+	defaultPolicy := s.defaultPolicy()
+
+	seen := make(map[string]bool)
+	states := make([]models.ContainerUpdateState, 0)
+	var errs []error
+	for socketID, svc := range s.registry.Services() {
+		containers, err := svc.listContainers(ctx, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("socket %s: %w", socketID, err))
+			continue
+		}
+
+		for _, c := range containers {
+			if seen[c.ID] || len(c.Names) == 0 {
+				continue
+			}
+			seen[c.ID] = true
+			name := strings.TrimPrefix(c.Names[0], "/")
+
+			var policy string
+			if stored, err := s.updateRepo.GetState(socketID, name); err == nil {
+				policy = stored.Policy
+			}
+			label := c.Labels[AutoUpdateLabel]
+			hasUnit := c.Labels["PODMAN_SYSTEMD_UNIT"] != ""
+			effective := effectiveUpdatePolicy(policy, label, hasUnit, managed[c.ID] || managed[name], defaultPolicy)
+			if effective == "" || effective == models.UpdatePolicyDisabled {
+				continue
+			}
+
+			state := s.check(ctx, svc, c, label, effective)
+			state.SocketID = socketID
+			state.Policy = policy
+			if err := s.updateRepo.SaveState(&state); err != nil {
+				errs = append(errs, fmt.Errorf("failed to save update state of %s: %w", name, err))
+			}
+			states = append(states, state)
+		}
+	}
+
+	return states, errors.Join(errs...)
+}
+
+// check looks for a newer image of one container and applies it if the policy allows
+func (s *UpdateScheduler) check(ctx context.Context, svc *PodmanService, c podmanContainer, label, policy string) models.ContainerUpdateState {
+	ctx, cancel := context.WithTimeout(ctx, updateCheckTimeout)
+	defer cancel()
+
+	now := time.Now()
+	state := models.ContainerUpdateState{
+		ContainerName:   strings.TrimPrefix(c.Names[0], "/"),
+		ContainerID:     c.ID,
+		Image:           c.Image,
+		EffectivePolicy: policy,
+		AutoUpdateLabel: label,
+		CheckedAt:       &now,
+	}
+
+	// "local" only compares against local storage, as podman auto-update does
+	local := label == "local"
+	available, current, latest, err := svc.imageUpdateAvailable(ctx, c, local)
+	if err != nil {
+		state.LastError = err.Error()
+		return state
+	}
+	state.UpdateAvailable = available
+	state.CurrentDigest = current
+	state.LatestDigest = latest
+
+	// Version tags are also compared against the tags the registry offers
+	if !local {
+		if newer, err := NewerImageTags(ctx, c.Image, svc.RegistrySocketID()); err != nil {
+			log.Printf("Failed to list newer tags of %s: %v", c.Image, err)
+		} else {
+			state.NewerTags = newer
+		}
+	}
+
+	if !available || !autoApplies(policy, c.Image) {
+		return state
+	}
+
+	if inspect, err := svc.InspectContainer(ctx, c.ID); err == nil && inspect.Pod != "" {
+		state.LastError = "containers in a pod are not updated automatically"
+		return state
+	}
+
+	log.Printf("Updating %s to the latest %s", state.ContainerName, c.Image)
+
+	// Containers run by a systemd unit are recreated from the image when the
+	// unit restarts, which is how podman auto-update applies updates too
+	if unit := c.Labels["PODMAN_SYSTEMD_UNIT"]; unit != "" {
+		startedAt := time.Now()
+		record := &models.ContainerUpdateRecord{
+			ContainerName:  state.ContainerName,
+			OldContainerID: c.ID,
+			OldImage:       c.Image,
+			NewImage:       c.Image,
+			Status:         models.UpdateStatusSucceeded,
+			StartedAt:      startedAt,
+		}
+		// The check only resolved the digest, so the new image is pulled first
+		if err := svc.pullUpdate(ctx, c.Image, local); err != nil {
+			record.Status = models.UpdateStatusFailed
+			record.Reason = "Failed to pull " + c.Image + ": " + err.Error()
+			state.LastError = "Automatic update failed: " + record.Reason
+		} else if err := ServiceControlInScope(svc.SystemdScope(), unit, "restart"); err != nil {
+			record.Status = models.UpdateStatusFailed
+			record.Reason = "Failed to restart " + unit + ": " + err.Error()
+			state.LastError = "Automatic update failed: " + record.Reason
+		} else {
+			state.UpdateAvailable = false
+			state.CurrentDigest = latest
+		}
+		finishedAt := time.Now()
+		record.FinishedAt = &finishedAt
+		if err := s.updateRepo.Create(record); err != nil {
+			log.Printf("Failed to record update of %s: %v", state.ContainerName, err)
+		}
+		return state
+	}
+
+	// Only managed containers without the label get here (see
+	// effectiveUpdatePolicy), so they are recreated from their settings
+	record, err := svc.UpdateContainer(ctx, &models.UpdateContainerImageRequest{
+		ContainerID:     c.ID,
+		CreateBackup:    true,
+		OverwriteBackup: true,
+		RemoveOld:       true,
+		SkipPull:        local,
+	}, nil, nil)
+	if err != nil {
+		state.LastError = "Automatic update failed: " + err.Error()
+		return state
+	}
+
+	state.ContainerID = record.NewContainerID
+	state.UpdateAvailable = false
+	state.CurrentDigest = latest
+	return state
+}
+
+// effectiveUpdatePolicy picks the policy of a container: its own, then the
+// io.containers.autoupdate label, then the default for managed containers.
+// Unmanaged containers without the label don't take part. Labelled containers
+// are updated by restarting their systemd unit; without one, recreating them
+// could lose settings, so their updates are only reported.
+func effectiveUpdatePolicy(policy, label string, hasUnit, managed bool, defaultPolicy string) string {
+	effective := policy
+	if effective == "" {
+		switch label {
+		case "registry", "image", "local":
+			effective = models.UpdatePolicyAuto
+		default:
+			if managed {
+				return defaultPolicy
+			}
+			return ""
+		}
+	}
+	if label != "" && !hasUnit && (effective == models.UpdatePolicyAuto || effective == models.UpdatePolicyPatch) {
+		return models.UpdatePolicyNotify
+	}
+	return effective
+}
+
+// autoApplies reports whether an available update of image is applied without asking.
+// The patch policy only follows tags that pin a version line, since a new
+// digest there is a rebuild or patch release rather than a new major version.
+func autoApplies(policy, image string) bool {
+	switch policy {
+	case models.UpdatePolicyAuto:
+		return true
+	case models.UpdatePolicyPatch:
+		return versionTag.MatchString(imageTag(image))
+	}
+	return false
+}
+
+// imageTag returns the tag of an image reference, or "" for digests and untagged images
+func imageTag(image string) string {
+	if strings.Contains(image, "@") {
+		return ""
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	if _, tag, ok := strings.Cut(name, ":"); ok {
+		return tag
+	}
+	return ""
+}
+
+// imageUpdateAvailable reports whether the image reference of a container now
+// points to a different image than the one it runs. Unless local is set, the
+// reference is resolved in its registry without pulling; the update is only
+// pulled when it is applied.
+func (p *PodmanService) imageUpdateAvailable(ctx context.Context, c podmanContainer, local bool) (bool, string, string, error) {
+	current := p.imageDigest(ctx, c.ImageID)
+
+	if local {
+		latestID := p.imageID(ctx, c.Image)
+		if latestID == "" {
+			return false, "", "", fmt.Errorf("image %s is not in local storage", c.Image)
+		}
+		latest := p.imageDigest(ctx, latestID)
+		available := !strings.HasPrefix(latestID, c.ImageID) && !strings.HasPrefix(c.ImageID, latestID)
+		return available, current, latest, nil
+	}
+
+	registry, repository, reference := ParseImageReference(c.Image)
+	client, err := NewRegistryClient(registry, p.RegistrySocketID())
+	if err != nil {
+		return false, "", "", err
+	}
+	latest, err := client.ManifestDigest(ctx, repository, reference)
+	if err != nil {
+		return false, "", "", fmt.Errorf("failed to check %s: %w", c.Image, err)
+	}
+
+	// A manifest list and the image of this platform in it have different
+	// digests; podman records both for a pulled image
+	for _, digest := range p.imageRepoDigests(ctx, c.ImageID) {
+		if digest == latest {
+			return false, current, latest, nil
+		}
+	}
+	return true, current, latest, nil
+}
+
+// imageRepoDigests returns the manifest digests a local image was pulled by
+func (p *PodmanService) imageRepoDigests(ctx context.Context, id string) []string {
+	output, err := p.podmanCmd(ctx, "image", "inspect", "--format", "{{.Digest}} {{range .RepoDigests}}{{.}} {{end}}", id)
+	if err != nil {
+		return nil
+	}
+	var digests []string
+	for _, field := range strings.Fields(string(output)) {
+		if _, digest, ok := strings.Cut(field, "@"); ok {
+			field = digest
+		}
+		digests = append(digests, field)
+	}
+	return digests
+}
+
+// pullUpdate pulls the image an update applies, unless it is a local one
+func (p *PodmanService) pullUpdate(ctx context.Context, image string, local bool) error {
+	if local {
+		return nil
+	}
+	return p.PullImage(ctx, image)
+}
+
+// imageDigest returns the manifest digest of a local image ID, or "" if unknown
+func (p *PodmanService) imageDigest(ctx context.Context, id string) string {
+	output, err := p.podmanCmd(ctx, "image", "inspect", "--format", "{{.Digest}}", id)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
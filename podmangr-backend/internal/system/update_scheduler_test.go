@@ -0,0 +1,51 @@
+package system
+
+import (
+	"testing"
+
+	"podmangr-backend/internal/models"
+)
+
+func TestUpdatePolicies(t *testing.T) {
+	policies := []struct {
+		policy, label    string
+		hasUnit, managed bool
+		want             string
+	}{
+		{"", "", false, true, models.UpdatePolicyNotify},
+		{"", "", false, false, ""},
+		{"", "registry", true, false, models.UpdatePolicyAuto},
+		{"", "local", true, true, models.UpdatePolicyAuto},
+		{models.UpdatePolicyDisabled, "registry", true, false, models.UpdatePolicyDisabled},
+		{models.UpdatePolicyPatch, "", false, true, models.UpdatePolicyPatch},
+		// Labelled containers without a unit to restart are only reported
+		{"", "registry", false, false, models.UpdatePolicyNotify},
+		{models.UpdatePolicyAuto, "image", false, true, models.UpdatePolicyNotify},
+		{models.UpdatePolicyDisabled, "registry", false, false, models.UpdatePolicyDisabled},
+	}
+	for _, tt := range policies {
+		if got := effectiveUpdatePolicy(tt.policy, tt.label, tt.hasUnit, tt.managed, models.UpdatePolicyNotify); got != tt.want {
+			t.Errorf("effectiveUpdatePolicy(%q, %q, %v, %v) = %q, want %q", tt.policy, tt.label, tt.hasUnit, tt.managed, got, tt.want)
+		}
+	}
+
+	applies := []struct {
+		policy, image string
+		want          bool
+	}{
+		{models.UpdatePolicyAuto, "nginx", true},
+		{models.UpdatePolicyNotify, "nginx:1.27", false},
+		{models.UpdatePolicyPatch, "nginx:1.27", true},
+		{models.UpdatePolicyPatch, "docker.io/library/postgres:16-alpine", true},
+		{models.UpdatePolicyPatch, "ghcr.io/org/app:v2.3.1", true},
+		{models.UpdatePolicyPatch, "localhost:5000/app", false},
+		{models.UpdatePolicyPatch, "nginx:latest", false},
+		{models.UpdatePolicyPatch, "nginx:stable-alpine", false},
+		{models.UpdatePolicyPatch, "nginx@sha256:0123", false},
+	}
+	for _, tt := range applies {
+		if got := autoApplies(tt.policy, tt.image); got != tt.want {
+			t.Errorf("autoApplies(%q, %q) = %v, want %v", tt.policy, tt.image, got, tt.want)
+		}
+	}
+}
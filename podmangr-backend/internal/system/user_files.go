@@ -0,0 +1,29 @@
+package system
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// writeFileAsUser writes a file as username through sudo, creating missing
+// parent directories. Root uses it for paths a user controls, such as their
+// home or runtime directory, where writing itself would follow any symlink
+// the user placed there.
+func writeFileAsUser(username, path string, data []byte, mode string) error {
+	cmd := exec.Command("sudo", "-u", username, "install", "-D", "-m", mode, "/dev/stdin", path)
+	cmd.Stdin = bytes.NewReader(data)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write %s as %s: %s", path, username, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// removeFileAsUser removes a file as username through sudo, if it exists
+func removeFileAsUser(username, path string) error {
+	if output, err := exec.Command("sudo", "-u", username, "rm", "-f", "--", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to remove %s as %s: %s", path, username, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
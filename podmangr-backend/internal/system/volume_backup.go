@@ -0,0 +1,150 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"podmangr-backend/internal/models"
+)
+
+// podmanStreamCmd builds a podman command whose stdin and stdout the caller
+// connects, with the same rootless handling as podmanCmd
+func (p *PodmanService) podmanStreamCmd(ctx context.Context, args ...string) *exec.Cmd {
+	if os.Getuid() == 0 && p.targetUser != "" {
+		sudoArgs := []string{"-u", p.targetUser, "podman"}
+		sudoArgs = append(sudoArgs, args...)
+		return exec.CommandContext(ctx, "sudo", sudoArgs...)
+	}
+	return exec.CommandContext(ctx, "podman", args...)
+}
+
+// ExportVolume writes the contents of a named volume to w as a tar archive
+func (p *PodmanService) ExportVolume(ctx context.Context, name string, w io.Writer) error {
+	// The archive is streamed through stdout rather than --output, since in
+	// rootless mode podman runs as another user that can't write our files
+	cmd := p.podmanStreamCmd(ctx, "volume", "export", name)
+	var stderr bytes.Buffer
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to export volume %s: %w - %s", name, err, stderr.String())
+	}
+	return nil
+}
+
+// ImportVolume extracts a tar archive read from r into a named volume.
+// Existing files are overwritten but files missing from the archive are kept.
+func (p *PodmanService) ImportVolume(ctx context.Context, name string, r io.Reader) error {
+	cmd := p.podmanStreamCmd(ctx, "volume", "import", name, "-")
+	var stderr bytes.Buffer
+	cmd.Stdin = r
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to import volume %s: %w - %s", name, err, stderr.String())
+	}
+	return nil
+}
+
+// volumeBackupMount describes a named volume for a backup, keeping what is
+// needed to recreate the volume if it is gone by the time it is restored
+func (p *PodmanService) volumeBackupMount(ctx context.Context, name, target string) (*models.BackupMount, error) {
+	volume, err := p.InspectVolume(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect volume %s: %w", name, err)
+	}
+
+	return &models.BackupMount{
+		Source:  name,
+		Target:  target,
+		Type:    "volume",
+		Driver:  volume.Driver,
+		Labels:  volume.Labels,
+		Options: volume.Options,
+	}, nil
+}
+
+// restoreVolume replaces the contents of a named volume with a tar stream,
+// recreating the volume if it was removed in the meantime
+func (p *PodmanService) restoreVolume(ctx context.Context, mount *models.BackupMount, r io.Reader) error {
+	volume, err := p.InspectVolume(ctx, mount.Source)
+	if err != nil {
+		err = p.CreateVolume(ctx, &models.CreateVolumeRequest{
+			Name:    mount.Source,
+			Driver:  mount.Driver,
+			Labels:  mount.Labels,
+			Options: mount.Options,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to recreate volume %s: %w", mount.Source, err)
+		}
+	} else if err := p.clearVolume(ctx, volume); err != nil {
+		return fmt.Errorf("failed to clear volume %s: %w", mount.Source, err)
+	}
+
+	return p.ImportVolume(ctx, mount.Source, r)
+}
+
+// clearVolume removes everything inside a volume, so that importing a backup
+// doesn't leave files behind that were created after it was taken
+func (p *PodmanService) clearVolume(ctx context.Context, volume *podmanVolume) error {
+	if volume.MountPoint == "" {
+		return fmt.Errorf("volume has no mountpoint")
+	}
+
+	// Rootless volumes hold files owned by subordinate IDs, which can only be
+	// removed from inside the user namespace
+	if os.Getuid() != 0 || p.targetUser != "" {
+		_, err := p.podmanCmd(ctx, "unshare", "find", volume.MountPoint, "-mindepth", "1", "-delete")
+		return err
+	}
+	return emptyDir(volume.MountPoint)
+}
+
+// emptyDir removes the contents of a directory but keeps the directory itself
+func emptyDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreDirectory replaces the contents of a bind mount directory with a tar stream
+func restoreDirectory(dir string, r io.Reader) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := emptyDir(dir); err != nil {
+		return err
+	}
+	return extractTar(r, dir, extractOptions{keepOwners: true})
+}
+
+// backupType describes what a backup holds: "bind", "volume" or "mixed"
+func backupType(mounts []models.BackupMount) string {
+	hasBind, hasVolume := false, false
+	for _, mount := range mounts {
+		if mount.Type == "volume" {
+			hasVolume = true
+		} else {
+			hasBind = true
+		}
+	}
+	switch {
+	case hasBind && hasVolume:
+		return "mixed"
+	case hasVolume:
+		return "volume"
+	}
+	return "bind"
+}
@@ -0,0 +1,52 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"podmangr-backend/internal/models"
+)
+
+func TestEmptyDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "data", "base"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"PG_VERSION", "data/base/1", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := emptyDir(dir); err != nil {
+		t.Fatalf("emptyDir returned error: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Expected the directory itself to be kept: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected an empty directory, got %d entries", len(entries))
+	}
+}
+
+func TestBackupType(t *testing.T) {
+	bind := models.BackupMount{Type: "bind"}
+	volume := models.BackupMount{Type: "volume"}
+
+	tests := []struct {
+		mounts []models.BackupMount
+		want   string
+	}{
+		{nil, "bind"},
+		{[]models.BackupMount{bind}, "bind"},
+		{[]models.BackupMount{volume, volume}, "volume"},
+		{[]models.BackupMount{bind, volume}, "mixed"},
+	}
+	for _, tt := range tests {
+		if got := backupType(tt.mounts); got != tt.want {
+			t.Errorf("backupType(%v) = %q, want %q", tt.mounts, got, tt.want)
+		}
+	}
+}
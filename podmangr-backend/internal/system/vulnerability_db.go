@@ -0,0 +1,356 @@
+package system
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"podmangr-backend/internal/database"
+	"podmangr-backend/internal/models"
+)
+
+// vulnerabilityImportBatch is how many advisories are stored per transaction
+const vulnerabilityImportBatch = 500
+
+// osvEntry holds the fields of an OSV advisory the scanner uses
+type osvEntry struct {
+	ID        string     `json:"id"`
+	Aliases   []string   `json:"aliases"`
+	Summary   string     `json:"summary"`
+	Details   string     `json:"details"`
+	Modified  *time.Time `json:"modified"`
+	Withdrawn *time.Time `json:"withdrawn"`
+	Severity  []struct {
+		Type  string `json:"type"`
+		Score string `json:"score"`
+	} `json:"severity"`
+	Affected []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string `json:"type"`
+			Events []struct {
+				Introduced   string `json:"introduced"`
+				Fixed        string `json:"fixed"`
+				LastAffected string `json:"last_affected"`
+			} `json:"events"`
+		} `json:"ranges"`
+		Versions          []string               `json:"versions"`
+		EcosystemSpecific map[string]interface{} `json:"ecosystem_specific"`
+		DatabaseSpecific  map[string]interface{} `json:"database_specific"`
+	} `json:"affected"`
+	DatabaseSpecific map[string]interface{} `json:"database_specific"`
+}
+
+// severityOf maps a severity label of an advisory source to ours
+func severityOf(label string) string {
+	switch strings.ToLower(strings.TrimSpace(label)) {
+	case "critical":
+		return models.SeverityCritical
+	case "high", "important":
+		return models.SeverityHigh
+	case "medium", "moderate":
+		return models.SeverityMedium
+	case "low", "negligible", "unimportant":
+		return models.SeverityLow
+	}
+	return ""
+}
+
+// severityOfScore maps a CVSS base score to a severity
+func severityOfScore(score float64) string {
+	switch {
+	case score >= 9:
+		return models.SeverityCritical
+	case score >= 7:
+		return models.SeverityHigh
+	case score >= 4:
+		return models.SeverityMedium
+	case score > 0:
+		return models.SeverityLow
+	}
+	return models.SeverityUnknown
+}
+
+// cvss3Weights are the metric weights of the CVSS v3 base score
+var cvss3Weights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"AC": {"L": 0.77, "H": 0.44},
+	"UI": {"N": 0.85, "R": 0.62},
+	"C":  {"H": 0.56, "L": 0.22, "N": 0},
+	"I":  {"H": 0.56, "L": 0.22, "N": 0},
+	"A":  {"H": 0.56, "L": 0.22, "N": 0},
+}
+
+// cvss3BaseScore computes the base score of a CVSS v3.x vector such as
+// CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H
+func cvss3BaseScore(vector string) (float64, error) {
+	if !strings.HasPrefix(vector, "CVSS:3") {
+		return 0, fmt.Errorf("not a CVSS v3 vector: %q", vector)
+	}
+	metrics := make(map[string]string)
+	for _, part := range strings.Split(vector, "/")[1:] {
+		if k, v, ok := strings.Cut(part, ":"); ok {
+			metrics[k] = v
+		}
+	}
+
+	changed := metrics["S"] == "C"
+	values := make(map[string]float64)
+	for metric, weights := range cvss3Weights {
+		w, ok := weights[metrics[metric]]
+		if !ok {
+			return 0, fmt.Errorf("CVSS vector %q lacks a valid %s", vector, metric)
+		}
+		values[metric] = w
+	}
+	pr := map[string]float64{"N": 0.85, "L": 0.62, "H": 0.27}
+	if changed {
+		pr = map[string]float64{"N": 0.85, "L": 0.68, "H": 0.5}
+	}
+	privileges, ok := pr[metrics["PR"]]
+	if !ok {
+		return 0, fmt.Errorf("CVSS vector %q lacks a valid PR", vector)
+	}
+
+	iss := 1 - (1-values["C"])*(1-values["I"])*(1-values["A"])
+	impact := 6.42 * iss
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	if impact <= 0 {
+		return 0, nil
+	}
+	exploitability := 8.22 * values["AV"] * values["AC"] * privileges * values["UI"]
+	score := impact + exploitability
+	if changed {
+		score *= 1.08
+	}
+	return cvssRoundUp(math.Min(score, 10)), nil
+}
+
+// cvssRoundUp rounds up to one decimal as the CVSS v3.1 specification defines
+func cvssRoundUp(x float64) float64 {
+	i := int(math.Round(x * 100000))
+	if i%10000 == 0 {
+		return float64(i) / 100000
+	}
+	return float64(i/10000+1) / 10
+}
+
+// advisorySeverity picks the severity of an advisory: a label given by its
+// source, else the CVSS v3 base score
+func advisorySeverity(e *osvEntry) string {
+	if label, ok := e.DatabaseSpecific["severity"].(string); ok && severityOf(label) != "" {
+		return severityOf(label)
+	}
+	for _, a := range e.Affected {
+		for _, specific := range []map[string]interface{}{a.EcosystemSpecific, a.DatabaseSpecific} {
+			if label, ok := specific["severity"].(string); ok && severityOf(label) != "" {
+				return severityOf(label)
+			}
+		}
+	}
+	for _, s := range e.Severity {
+		if s.Type == "CVSS_V3" {
+			if score, err := cvss3BaseScore(s.Score); err == nil {
+				return severityOfScore(score)
+			}
+		}
+	}
+	return models.SeverityUnknown
+}
+
+// osvAdvisory converts an OSV entry to an advisory with its affected ranges.
+// Git commit ranges can't be matched against package versions and are left out.
+func osvAdvisory(e *osvEntry) models.VulnerabilityAdvisory {
+	summary := e.Summary
+	if summary == "" {
+		summary, _, _ = strings.Cut(strings.TrimSpace(e.Details), "\n")
+	}
+	a := models.VulnerabilityAdvisory{
+		ID:       e.ID,
+		Aliases:  e.Aliases,
+		Summary:  summary,
+		Severity: advisorySeverity(e),
+		Modified: e.Modified,
+	}
+
+	for _, affected := range e.Affected {
+		pkg := models.VulnerabilityRange{Ecosystem: affected.Package.Ecosystem, Package: affected.Package.Name}
+		if pkg.Ecosystem == "" || pkg.Package == "" {
+			continue
+		}
+		ranged := false
+		for _, r := range affected.Ranges {
+			if r.Type != "ECOSYSTEM" && r.Type != "SEMVER" {
+				continue
+			}
+			var open *models.VulnerabilityRange
+			for _, ev := range r.Events {
+				switch {
+				case ev.Introduced != "":
+					rg := pkg
+					rg.Introduced = ev.Introduced
+					open = &rg
+				case open != nil && (ev.Fixed != "" || ev.LastAffected != ""):
+					open.Fixed, open.LastAffected = ev.Fixed, ev.LastAffected
+					a.Ranges = append(a.Ranges, *open)
+					open = nil
+				}
+			}
+			if open != nil {
+				a.Ranges = append(a.Ranges, *open)
+			}
+			ranged = true
+		}
+		// Without usable ranges, the listed versions are the affected ones
+		if !ranged {
+			for _, v := range affected.Versions {
+				rg := pkg
+				rg.Introduced, rg.LastAffected = v, v
+				a.Ranges = append(a.Ranges, rg)
+			}
+		}
+	}
+	return a
+}
+
+// readOSVEntries decodes OSV advisories from a JSON object, a JSON array or
+// JSON lines, calling fn with each
+func readOSVEntries(r io.Reader, fn func(*osvEntry) error) error {
+	br := bufio.NewReader(r)
+	first, err := firstNonSpace(br)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(br)
+	if first == '[' {
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	}
+	for dec.More() {
+		var e osvEntry
+		if err := dec.Decode(&e); err != nil {
+			return fmt.Errorf("invalid OSV JSON: %w", err)
+		}
+		if err := fn(&e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// firstNonSpace peeks at the first byte of r that isn't white space
+func firstNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\n' && b != '\r' && b != '\t' {
+			return b, r.UnreadByte()
+		}
+	}
+}
+
+// ImportVulnerabilityDatabase imports OSV advisories from a file: a zip of
+// OSV JSON files as published per ecosystem at
+// https://osv-vulnerabilities.storage.googleapis.com/<ecosystem>/all.zip,
+// a JSON array, JSON lines or a single advisory. Advisories replace those
+// with the same ID; withdrawn ones are removed.
+func ImportVulnerabilityDatabase(path string) (*models.VulnerabilityImportResult, error) {
+	repo := database.NewVulnerabilityRepo()
+	result := &models.VulnerabilityImportResult{}
+	var batch []models.VulnerabilityAdvisory
+	var withdrawn []string
+	var storeErr error
+	flush := func() error {
+		if len(batch) == 0 && len(withdrawn) == 0 {
+			return nil
+		}
+		if err := repo.ImportAdvisories(batch, withdrawn); err != nil {
+			storeErr = fmt.Errorf("failed to store advisories: %w", err)
+			return storeErr
+		}
+		batch, withdrawn = batch[:0], withdrawn[:0]
+		return nil
+	}
+	add := func(e *osvEntry) error {
+		switch {
+		case e.ID == "":
+			result.Skipped++
+			return nil
+		case e.Withdrawn != nil:
+			withdrawn = append(withdrawn, e.ID)
+			result.Withdrawn++
+		default:
+			batch = append(batch, osvAdvisory(e))
+			result.Imported++
+		}
+		if len(batch)+len(withdrawn) >= vulnerabilityImportBatch {
+			return flush()
+		}
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return nil, fmt.Errorf("vulnerability database is empty or truncated")
+	}
+
+	if bytes.Equal(magic, []byte("PK\x03\x04")) {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		archive, err := zip.NewReader(f, info.Size())
+		if err != nil {
+			return nil, fmt.Errorf("invalid zip archive: %w", err)
+		}
+		for _, file := range archive.File {
+			if !strings.HasSuffix(file.Name, ".json") {
+				continue
+			}
+			rc, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+			err = readOSVEntries(rc, add)
+			rc.Close()
+			if storeErr != nil {
+				return nil, storeErr
+			}
+			if err != nil {
+				result.Skipped++
+			}
+		}
+	} else {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if err := readOSVEntries(f, add); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
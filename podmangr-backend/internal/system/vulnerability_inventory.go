@@ -0,0 +1,403 @@
+package system
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"database/sql"
+	"debug/buildinfo"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"podmangr-backend/internal/models"
+)
+
+// goBinaryMaxSize bounds the executables read to find Go build information
+const goBinaryMaxSize = 256 << 20
+
+// Paths of the package databases in an image filesystem
+const (
+	dpkgStatusPath   = "var/lib/dpkg/status"
+	dpkgStatusDir    = "var/lib/dpkg/status.d/" // Distroless images keep one file per package
+	apkInstalledPath = "lib/apk/db/installed"
+	rpmSQLitePath    = "var/lib/rpm/rpmdb.sqlite"
+	rpmSQLiteNewPath = "usr/lib/sysimage/rpm/rpmdb.sqlite"
+	rpmBerkeleyPath  = "var/lib/rpm/Packages"
+)
+
+// imageInventory is what a scan found installed in an image
+type imageInventory struct {
+	OS       string
+	Packages []models.ImagePackage
+	Notes    []string // Parts that could not be read
+}
+
+// distroEcosystem returns the OSV ecosystem of a distribution's packages
+// from the ID and VERSION_ID of its os-release, or "" if there is none
+func distroEcosystem(id, versionID string) string {
+	major, _, _ := strings.Cut(versionID, ".")
+	switch id {
+	case "debian":
+		if major == "" {
+			return "Debian"
+		}
+		return "Debian:" + major
+	case "ubuntu":
+		return "Ubuntu:" + versionID
+	case "alpine":
+		parts := strings.SplitN(versionID, ".", 3)
+		if len(parts) < 2 {
+			return "Alpine"
+		}
+		return "Alpine:v" + parts[0] + "." + parts[1]
+	case "rocky":
+		return "Rocky Linux:" + major
+	case "almalinux":
+		return "AlmaLinux:" + major
+	case "rhel", "centos":
+		return "Red Hat"
+	case "wolfi":
+		return "Wolfi"
+	case "chainguard":
+		return "Chainguard"
+	case "opensuse-leap", "opensuse-tumbleweed":
+		return "openSUSE"
+	case "sles":
+		return "SUSE"
+	}
+	return ""
+}
+
+// parseOSRelease reads the KEY=value pairs of an os-release file
+func parseOSRelease(data []byte) map[string]string {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if ok && !strings.HasPrefix(key, "#") {
+			values[key] = strings.Trim(value, `"'`)
+		}
+	}
+	return values
+}
+
+// parseStanzas splits a dpkg status or apk installed database into its
+// records of fields, separated by blank lines
+func parseStanzas(data []byte, sep string) []map[string]string {
+	var stanzas []map[string]string
+	current := make(map[string]string)
+	last := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+			if len(current) > 0 {
+				stanzas = append(stanzas, current)
+				current = make(map[string]string)
+			}
+		case line[0] == ' ' || line[0] == '\t':
+			// Continuation of a multi-line dpkg field
+			current[last] += "\n" + strings.TrimSpace(line)
+		default:
+			if key, value, ok := strings.Cut(line, sep); ok {
+				last = key
+				current[key] = strings.TrimSpace(value)
+			}
+		}
+	}
+	if len(current) > 0 {
+		stanzas = append(stanzas, current)
+	}
+	return stanzas
+}
+
+// parseDpkgStatus returns the installed packages of a dpkg status database
+func parseDpkgStatus(data []byte, ecosystem string) []models.ImagePackage {
+	var packages []models.ImagePackage
+	for _, s := range parseStanzas(data, ":") {
+		// Distroless status.d files have no Status field
+		if status, ok := s["Status"]; ok && !strings.HasSuffix(status, " installed") {
+			continue
+		}
+		if s["Package"] == "" || s["Version"] == "" {
+			continue
+		}
+		source, _, _ := strings.Cut(s["Source"], " ")
+		if source == s["Package"] {
+			source = ""
+		}
+		packages = append(packages, models.ImagePackage{
+			Ecosystem: ecosystem,
+			Name:      s["Package"],
+			Source:    source,
+			Version:   s["Version"],
+		})
+	}
+	return packages
+}
+
+// parseAPKInstalled returns the packages of an apk installed database
+func parseAPKInstalled(data []byte, ecosystem string) []models.ImagePackage {
+	var packages []models.ImagePackage
+	for _, s := range parseStanzas(data, ":") {
+		if s["P"] == "" || s["V"] == "" {
+			continue
+		}
+		origin := s["o"]
+		if origin == s["P"] {
+			origin = ""
+		}
+		packages = append(packages, models.ImagePackage{
+			Ecosystem: ecosystem,
+			Name:      s["P"],
+			Source:    origin,
+			Version:   s["V"],
+		})
+	}
+	return packages
+}
+
+// RPM header tags read from the package database
+const (
+	rpmTagName      = 1000
+	rpmTagVersion   = 1001
+	rpmTagRelease   = 1002
+	rpmTagEpoch     = 1003
+	rpmTagSourceRPM = 1044
+)
+
+// parseRPMHeader reads the name, [epoch:]version-release and source package
+// name from an rpm header blob as rpmdb.sqlite stores it
+func parseRPMHeader(blob []byte) (string, string, string, error) {
+	if len(blob) < 8 {
+		return "", "", "", fmt.Errorf("rpm header too short")
+	}
+	count := int(binary.BigEndian.Uint32(blob[0:4]))
+	dataLen := int(binary.BigEndian.Uint32(blob[4:8]))
+	start := 8 + count*16
+	if count <= 0 || start+dataLen > len(blob) {
+		return "", "", "", fmt.Errorf("invalid rpm header")
+	}
+	data := blob[start : start+dataLen]
+
+	strs := make(map[int]string)
+	epoch := -1
+	for i := 0; i < count; i++ {
+		entry := blob[8+i*16 : 8+(i+1)*16]
+		tag := int(binary.BigEndian.Uint32(entry[0:4]))
+		typ := binary.BigEndian.Uint32(entry[4:8])
+		offset := int(binary.BigEndian.Uint32(entry[8:12]))
+		if offset < 0 || offset >= len(data) {
+			continue
+		}
+		switch {
+		case tag == rpmTagEpoch && typ == 4 && offset+4 <= len(data):
+			epoch = int(binary.BigEndian.Uint32(data[offset : offset+4]))
+		case (tag == rpmTagName || tag == rpmTagVersion || tag == rpmTagRelease || tag == rpmTagSourceRPM) && typ == 6:
+			end := bytes.IndexByte(data[offset:], 0)
+			if end < 0 {
+				continue
+			}
+			strs[tag] = string(data[offset : offset+end])
+		}
+	}
+
+	name := strs[rpmTagName]
+	if name == "" || strs[rpmTagVersion] == "" {
+		return "", "", "", fmt.Errorf("rpm header without name or version")
+	}
+	version := strs[rpmTagVersion]
+	if strs[rpmTagRelease] != "" {
+		version += "-" + strs[rpmTagRelease]
+	}
+	if epoch > 0 {
+		version = strconv.Itoa(epoch) + ":" + version
+	}
+
+	// openssl-3.0.7-27.el9.src.rpm names the source package openssl
+	source := strings.TrimSuffix(strs[rpmTagSourceRPM], ".src.rpm")
+	for i := 0; i < 2 && source != ""; i++ {
+		if j := strings.LastIndex(source, "-"); j > 0 {
+			source = source[:j]
+		} else {
+			source = ""
+		}
+	}
+	if source == name {
+		source = ""
+	}
+	return name, version, source, nil
+}
+
+// parseRPMDatabase returns the packages of an rpmdb.sqlite database file
+func parseRPMDatabase(file, ecosystem string) ([]models.ImagePackage, error) {
+	db, err := sql.Open("sqlite", "file:"+file+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT blob FROM Packages")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rpm database: %w", err)
+	}
+	defer rows.Close()
+
+	var packages []models.ImagePackage
+	for rows.Next() {
+		var blob []byte
+		if err := rows.Scan(&blob); err != nil {
+			return nil, err
+		}
+		name, version, source, err := parseRPMHeader(blob)
+		// gpg-pubkey entries are imported signing keys, not packages
+		if err != nil || name == "gpg-pubkey" {
+			continue
+		}
+		packages = append(packages, models.ImagePackage{Ecosystem: ecosystem, Name: name, Source: source, Version: version})
+	}
+	return packages, rows.Err()
+}
+
+// goPackages returns the Go modules and standard library a binary was built
+// with, from the build information the Go linker embeds
+func goPackages(binary []byte) []models.ImagePackage {
+	info, err := buildinfo.Read(bytes.NewReader(binary))
+	if err != nil {
+		return nil
+	}
+	var packages []models.ImagePackage
+	if goVersion, _, _ := strings.Cut(info.GoVersion, " "); strings.HasPrefix(goVersion, "go1") {
+		packages = append(packages, models.ImagePackage{Ecosystem: "Go", Name: "stdlib", Version: strings.TrimPrefix(goVersion, "go")})
+	}
+	if info.Main.Path != "" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		packages = append(packages, models.ImagePackage{Ecosystem: "Go", Name: info.Main.Path, Version: info.Main.Version})
+	}
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		if dep.Version != "" && dep.Version != "(devel)" {
+			packages = append(packages, models.ImagePackage{Ecosystem: "Go", Name: dep.Path, Version: dep.Version})
+		}
+	}
+	return packages
+}
+
+// inventoryFromTar reads the package databases and Go binaries of an image
+// filesystem exported as a tar stream
+func inventoryFromTar(r io.Reader) (*imageInventory, error) {
+	inv := &imageInventory{}
+	var osRelease, libOSRelease, dpkgStatus, apkInstalled []byte
+	var dpkgStatusD [][]byte
+	var rpmDB string
+	var goPkgs []models.ImagePackage
+
+	readAll := func(tr io.Reader) []byte {
+		data, _ := io.ReadAll(tr)
+		return data
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image filesystem: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")
+
+		switch {
+		case name == "etc/os-release":
+			osRelease = readAll(tr)
+		case name == "usr/lib/os-release":
+			libOSRelease = readAll(tr)
+		case name == dpkgStatusPath:
+			dpkgStatus = readAll(tr)
+		case strings.HasPrefix(name, dpkgStatusDir) && !strings.HasSuffix(name, ".md5sums"):
+			dpkgStatusD = append(dpkgStatusD, readAll(tr))
+		case name == apkInstalledPath:
+			apkInstalled = readAll(tr)
+		case name == rpmSQLitePath || name == rpmSQLiteNewPath:
+			if rpmDB != "" {
+				continue
+			}
+			f, err := os.CreateTemp("", "podmangr-rpmdb-*.sqlite")
+			if err != nil {
+				return nil, err
+			}
+			defer os.Remove(f.Name())
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to copy rpm database: %w", err)
+			}
+			rpmDB = f.Name()
+		case name == rpmBerkeleyPath:
+			inv.Notes = append(inv.Notes, "The BerkeleyDB rpm database of RHEL 7 and 8 based images is not supported")
+		case hdr.Mode&0111 != 0 && hdr.Size > 4 && hdr.Size <= goBinaryMaxSize:
+			magic := make([]byte, 4)
+			if _, err := io.ReadFull(tr, magic); err != nil || string(magic) != "\x7fELF" {
+				continue
+			}
+			rest := readAll(tr)
+			goPkgs = append(goPkgs, goPackages(append(magic, rest...))...)
+		}
+	}
+
+	if osRelease == nil {
+		osRelease = libOSRelease
+	}
+	release := parseOSRelease(osRelease)
+	inv.OS = release["PRETTY_NAME"]
+	if inv.OS == "" {
+		inv.OS = strings.TrimSpace(release["ID"] + " " + release["VERSION_ID"])
+	}
+	ecosystem := distroEcosystem(release["ID"], release["VERSION_ID"])
+
+	hasDistroPackages := dpkgStatus != nil || dpkgStatusD != nil || apkInstalled != nil || rpmDB != ""
+	if hasDistroPackages && ecosystem == "" {
+		inv.Notes = append(inv.Notes, fmt.Sprintf("Packages of the distribution %q are not matched; no advisories are known for it", release["ID"]))
+	}
+	if ecosystem != "" {
+		inv.Packages = append(inv.Packages, parseDpkgStatus(dpkgStatus, ecosystem)...)
+		for _, data := range dpkgStatusD {
+			inv.Packages = append(inv.Packages, parseDpkgStatus(data, ecosystem)...)
+		}
+		inv.Packages = append(inv.Packages, parseAPKInstalled(apkInstalled, ecosystem)...)
+		if rpmDB != "" {
+			rpms, err := parseRPMDatabase(rpmDB, ecosystem)
+			if err != nil {
+				inv.Notes = append(inv.Notes, err.Error())
+			}
+			inv.Packages = append(inv.Packages, rpms...)
+		}
+	}
+
+	// Binaries often share modules; each module version is listed once
+	seen := make(map[string]bool)
+	for _, p := range goPkgs {
+		if key := p.Name + "@" + p.Version; !seen[key] {
+			seen[key] = true
+			inv.Packages = append(inv.Packages, p)
+		}
+	}
+
+	sort.SliceStable(inv.Packages, func(i, j int) bool {
+		return inv.Packages[i].Name < inv.Packages[j].Name
+	})
+	return inv, nil
+}
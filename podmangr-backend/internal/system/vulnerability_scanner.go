@@ -0,0 +1,396 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"podmangr-backend/internal/database"
+	"podmangr-backend/internal/models"
+)
+
+const (
+	DefaultScanSchedule = "0 3 * * *"
+
+	scanQueueSize = 32
+	scanTimeout   = 15 * time.Minute // per image, including the filesystem export
+	scanLabel     = "io.podmangr.scan"
+)
+
+// scanRequest is an image waiting to be scanned after a pull or build
+type scanRequest struct {
+	svc   *PodmanService
+	image string
+}
+
+// VulnerabilityScanner inventories the packages of images and matches them
+// against the imported advisories. Images are scanned after they are pulled
+// and on a cron schedule, which scans new images and re-matches the stored
+// inventories of the others.
+type VulnerabilityScanner struct {
+	registry     *SocketRegistry
+	repo         *database.VulnerabilityRepo
+	settingsRepo *database.SettingsRepo
+	queue        chan scanRequest
+
+	// scanning serializes scans so filesystem exports don't pile up
+	scanning sync.Mutex
+
+	mu      sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+	running bool
+}
+
+// NewVulnerabilityScanner creates a new vulnerability scanner
+func NewVulnerabilityScanner() *VulnerabilityScanner {
+	return &VulnerabilityScanner{
+		registry:     GetSocketRegistry(),
+		repo:         database.NewVulnerabilityRepo(),
+		settingsRepo: database.NewSettingsRepo(),
+		queue:        make(chan scanRequest, scanQueueSize),
+	}
+}
+
+// Start launches the background scanning loop
+func (s *VulnerabilityScanner) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return
+	}
+	s.running = true
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go s.run(s.stop, s.done)
+}
+
+// Stop stops the scanning loop and waits for it to exit
+func (s *VulnerabilityScanner) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	close(s.stop)
+	done := s.done
+	s.mu.Unlock()
+
+	<-done
+}
+
+// Enqueue schedules a scan of an image without waiting for it. Requests are
+// dropped when scanning is disabled or the queue is full; the next scheduled
+// run picks those images up.
+func (s *VulnerabilityScanner) Enqueue(svc *PodmanService, image string) {
+	if svc == nil || image == "" || !s.enabled() {
+		return
+	}
+	select {
+	case s.queue <- scanRequest{svc: svc, image: image}:
+	default:
+		log.Printf("Vulnerability scan queue full, skipping %s", image)
+	}
+}
+
+func (s *VulnerabilityScanner) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	var spec string
+	var next time.Time
+	for {
+		// Settings are re-read every tick so changes apply without a restart
+		if s.enabled() {
+			if current := s.schedule(); current != spec {
+				spec = current
+				next = nextScheduledRun(spec, time.Now())
+			}
+			if !next.IsZero() && !time.Now().Before(next) {
+				if _, err := s.ScanAll(context.Background()); err != nil {
+					log.Printf("Scheduled vulnerability scan error: %v", err)
+				}
+				next = nextScheduledRun(spec, time.Now())
+			}
+		} else {
+			spec = ""
+		}
+
+		select {
+		case <-stop:
+			return
+		case req := <-s.queue:
+			if _, err := s.ScanImage(context.Background(), req.svc, req.image, false); err != nil {
+				log.Printf("Vulnerability scan of %s failed: %v", req.image, err)
+			}
+		case <-time.After(updateSchedulerTick):
+		}
+	}
+}
+
+// enabled reports whether scanning is turned on (defaults to true)
+func (s *VulnerabilityScanner) enabled() bool {
+	if _, err := s.settingsRepo.Get(database.SettingScanEnabled); err != nil {
+		return true
+	}
+	enabled, _ := s.settingsRepo.GetBool(database.SettingScanEnabled)
+	return enabled
+}
+
+// schedule returns the configured cron schedule
+func (s *VulnerabilityScanner) schedule() string {
+	spec, err := s.settingsRepo.Get(database.SettingScanSchedule)
+	if err != nil || strings.TrimSpace(spec) == "" {
+		return DefaultScanSchedule
+	}
+	return spec
+}
+
+// ScanImage scans an image and stores its report. The stored package
+// inventory of the image's digest is re-matched unless rescan is set or
+// there is none; otherwise the image filesystem is inventoried.
+func (s *VulnerabilityScanner) ScanImage(ctx context.Context, svc *PodmanService, image string, rescan bool) (*models.ImageVulnerabilityReport, error) {
+	s.scanning.Lock()
+	defer s.scanning.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, scanTimeout)
+	defer cancel()
+
+	imageID, digest, err := svc.ImageIdentity(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+
+	if !rescan {
+		if existing, err := s.repo.GetReport(digest); err == nil && existing.Error == "" {
+			packages, err := s.repo.GetPackages(digest)
+			if err != nil {
+				return nil, err
+			}
+			existing.ImageID = imageID
+			existing.Image = image
+			return s.match(existing, packages, false)
+		}
+	}
+
+	report := &models.ImageVulnerabilityReport{
+		Digest:    digest,
+		ImageID:   imageID,
+		Image:     image,
+		ScannedAt: time.Now(),
+	}
+	inv, err := svc.imageInventory(ctx, imageID)
+	if err != nil {
+		// The failure is recorded so the image shows as scanned with an error
+		report.Error = err.Error()
+		report.MatchedAt = report.ScannedAt
+		if saveErr := s.repo.SaveReport(report, []models.ImagePackage{}); saveErr != nil {
+			return nil, saveErr
+		}
+		return report, err
+	}
+	report.OS = inv.OS
+	report.Notes = inv.Notes
+	return s.match(report, inv.Packages, true)
+}
+
+// match matches a package inventory against the advisories and stores the
+// report, with the inventory if it is new
+func (s *VulnerabilityScanner) match(report *models.ImageVulnerabilityReport, packages []models.ImagePackage, store bool) (*models.ImageVulnerabilityReport, error) {
+	vulns, err := matchVulnerabilities(packages, s.repo.RangesForPackage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match vulnerabilities: %w", err)
+	}
+	report.Vulnerabilities = vulns
+	report.PackageCount = len(packages)
+	report.MatchedAt = time.Now()
+	report.Summary = models.VulnerabilitySummary{ScannedAt: report.ScannedAt}
+	for _, v := range vulns {
+		report.Summary.Add(v.Severity)
+	}
+
+	var stored []models.ImagePackage
+	if store {
+		stored = packages
+		if stored == nil {
+			stored = []models.ImagePackage{}
+		}
+	}
+	if err := s.repo.SaveReport(report, stored); err != nil {
+		return nil, fmt.Errorf("failed to save scan report: %w", err)
+	}
+	return report, nil
+}
+
+// matchVulnerabilities returns the vulnerabilities affecting packages, looking
+// up the ranges of both a package's name and its source package. A
+// vulnerability is listed once per affected package name and version.
+func matchVulnerabilities(packages []models.ImagePackage, lookup func(string) ([]models.VulnerabilityRange, error)) ([]models.ImageVulnerability, error) {
+	cache := make(map[string][]models.VulnerabilityRange)
+	seen := make(map[string]bool)
+	vulns := make([]models.ImageVulnerability, 0)
+
+	for _, pkg := range packages {
+		names := []string{pkg.Name}
+		if pkg.Source != "" && pkg.Source != pkg.Name {
+			names = append(names, pkg.Source)
+		}
+		for _, name := range names {
+			ranges, ok := cache[name]
+			if !ok {
+				var err error
+				if ranges, err = lookup(name); err != nil {
+					return nil, err
+				}
+				cache[name] = ranges
+			}
+
+			for _, rg := range ranges {
+				if !ecosystemMatches(rg.Ecosystem, pkg.Ecosystem) || !rangeAffects(rg, pkg.Version) {
+					continue
+				}
+				key := rg.AdvisoryID + "|" + name + "|" + pkg.Version
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				vulns = append(vulns, models.ImageVulnerability{
+					ID:               rg.AdvisoryID,
+					Package:          name,
+					Ecosystem:        pkg.Ecosystem,
+					InstalledVersion: pkg.Version,
+					FixedVersion:     rg.Fixed,
+					Severity:         rg.Severity,
+					Summary:          rg.Summary,
+				})
+			}
+		}
+	}
+	return vulns, nil
+}
+
+// ScanAll scans the images of every socket that have no report yet and
+// re-matches the stored inventories of the others. Sockets that fail are
+// reported but don't block the others.
+func (s *VulnerabilityScanner) ScanAll(ctx context.Context) ([]models.ImageVulnerabilityReport, error) {
+	seen := make(map[string]bool)
+	reports := make([]models.ImageVulnerabilityReport, 0)
+	var errs []error
+	for socketID, svc := range s.registry.Services() {
+		images, err := svc.ListImages(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("socket %s: %w", socketID, err))
+			continue
+		}
+		for _, img := range images {
+			key := img.Digest
+			if key == "" {
+				key = img.ID
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			ref := img.ID
+			if img.Repository != "" && img.Repository != "<none>" {
+				ref = img.Repository + ":" + img.Tag
+			}
+			report, err := s.ScanImage(ctx, svc, ref, false)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("image %s: %w", ref, err))
+			}
+			if report != nil {
+				reports = append(reports, *report)
+			}
+		}
+	}
+	return reports, errors.Join(errs...)
+}
+
+// RematchAll matches every stored inventory against the advisories again,
+// as needed after a vulnerability database import
+func (s *VulnerabilityScanner) RematchAll() error {
+	s.scanning.Lock()
+	defer s.scanning.Unlock()
+
+	reports, err := s.repo.ListReports()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := range reports {
+		report := &reports[i]
+		if report.Error != "" {
+			continue
+		}
+		packages, err := s.repo.GetPackages(report.Digest)
+		if err == nil {
+			_, err = s.match(report, packages, false)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("image %s: %w", report.Image, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ImageIdentity returns the ID of an image and the digest its scan report is
+// stored under
+func (p *PodmanService) ImageIdentity(ctx context.Context, image string) (string, string, error) {
+	output, err := p.podmanCmd(ctx, "image", "inspect", "--format", "{{.Id}} {{.Digest}}", normalizeImageName(image))
+	if err != nil {
+		return "", "", fmt.Errorf("image %s not found: %w", image, err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 {
+		return "", "", fmt.Errorf("image %s not found", image)
+	}
+	// Images built locally may have no digest; their ID identifies their content as well
+	if len(fields) == 1 {
+		return fields[0], "sha256:" + fields[0], nil
+	}
+	return fields[0], fields[1], nil
+}
+
+// imageInventory reads the installed packages of an image by exporting the
+// filesystem of a container created from it, which is never started
+func (p *PodmanService) imageInventory(ctx context.Context, imageID string) (*imageInventory, error) {
+	output, err := p.podmanCmd(ctx, "create", "--pull=never", "--network=none",
+		"--label", scanLabel+"=true", "--entrypoint", "", imageID, "podmangr-scan")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scan container: %w", err)
+	}
+	containerID := strings.TrimSpace(string(output))
+	defer p.podmanCmd(context.Background(), "rm", "-f", containerID)
+
+	cmd := p.podmanCommand(ctx, "export", containerID)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to export image filesystem: %w", err)
+	}
+
+	inv, readErr := inventoryFromTar(stdout)
+	// The rest of the stream is drained so podman can exit
+	io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("failed to export image filesystem: %s", strings.TrimSpace(stderr.String()))
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	return inv, nil
+}
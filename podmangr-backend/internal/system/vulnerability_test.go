@@ -0,0 +1,222 @@
+package system
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"podmangr-backend/internal/models"
+)
+
+func TestCompareVersionsIn(t *testing.T) {
+	tests := []struct {
+		ecosystem string
+		a, b      string
+		want      int
+	}{
+		{"Debian:12", "1.2.3-1", "1.2.3-2", -1},
+		{"Debian:12", "1:1.0-1", "2.0-1", 1},
+		{"Debian:12", "1.0~rc1-1", "1.0-1", -1},
+		{"Debian:12", "3.0.11-1~deb12u2", "3.0.11-1~deb12u1", 1},
+		{"Ubuntu:22.04", "2.35-0ubuntu3.6", "2.35-0ubuntu3.10", -1},
+		{"Rocky Linux:9", "3.0.7-27.el9", "3.0.7-24.el9", 1},
+		{"Red Hat", "1:1.1.1k-9.el8", "1.1.1k-12.el8", 1},
+		{"AlmaLinux:9", "1.0~beta", "1.0", -1},
+		{"Alpine:v3.20", "3.3.2-r0", "3.3.2-r1", -1},
+		{"Alpine:v3.20", "1.36.1-r29", "1.36.1-r5", 1},
+		{"Alpine:v3.20", "2.0_rc1-r0", "2.0-r0", -1},
+		{"Alpine:v3.20", "1.2.3a-r0", "1.2.3-r0", 1},
+		{"Go", "v0.17.0", "0.23.0", -1},
+		{"Go", "1.22.3", "1.22.3", 0},
+		{"Go", "1.0.0-rc.1", "1.0.0", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersionsIn(tt.ecosystem, tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersionsIn(%q, %q, %q) = %d, want %d", tt.ecosystem, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCVSS3BaseScore(t *testing.T) {
+	tests := map[string]float64{
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H": 9.8,
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H": 10.0,
+		"CVSS:3.0/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N": 6.1,
+		"CVSS:3.1/AV:L/AC:L/PR:L/UI:N/S:U/C:N/I:N/A:N": 0,
+	}
+	for vector, want := range tests {
+		got, err := cvss3BaseScore(vector)
+		if err != nil {
+			t.Errorf("cvss3BaseScore(%q) error: %v", vector, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("cvss3BaseScore(%q) = %v, want %v", vector, got, want)
+		}
+	}
+	if _, err := cvss3BaseScore("AV:N/AC:L"); err == nil {
+		t.Error("expected an error for a vector without the CVSS prefix")
+	}
+}
+
+func TestOSVAdvisoryRanges(t *testing.T) {
+	const entry = `{
+		"id": "DSA-0000-1",
+		"aliases": ["CVE-2024-0001"],
+		"details": "Buffer overflow in parser.\nMore text.",
+		"severity": [{"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}],
+		"affected": [{
+			"package": {"ecosystem": "Debian:12", "name": "libfoo"},
+			"ranges": [
+				{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.2-3"}]},
+				{"type": "GIT", "events": [{"introduced": "abc"}, {"fixed": "def"}]}
+			]
+		}, {
+			"package": {"ecosystem": "Go", "name": "example.com/mod"},
+			"versions": ["1.0.0"]
+		}]
+	}`
+	var e osvEntry
+	if err := json.Unmarshal([]byte(entry), &e); err != nil {
+		t.Fatal(err)
+	}
+	a := osvAdvisory(&e)
+
+	if a.Summary != "Buffer overflow in parser." {
+		t.Errorf("summary = %q", a.Summary)
+	}
+	if a.Severity != models.SeverityCritical {
+		t.Errorf("severity = %q, want critical", a.Severity)
+	}
+	if len(a.Ranges) != 2 {
+		t.Fatalf("expected 2 ranges, got %+v", a.Ranges)
+	}
+
+	debian := a.Ranges[0]
+	for version, want := range map[string]bool{"1.2-2": true, "1.2-3": false, "1.2-3+deb12u1": false, "1.1-9": true} {
+		if got := rangeAffects(debian, version); got != want {
+			t.Errorf("rangeAffects(%+v, %q) = %v, want %v", debian, version, got, want)
+		}
+	}
+	exact := a.Ranges[1]
+	if !rangeAffects(exact, "v1.0.0") || rangeAffects(exact, "1.0.1") {
+		t.Errorf("listed version range %+v matched wrongly", exact)
+	}
+
+	if !ecosystemMatches("Ubuntu:22.04:LTS", "Ubuntu:22.04") || ecosystemMatches("Debian:11", "Debian:12") {
+		t.Error("ecosystemMatches gave a wrong result")
+	}
+}
+
+func TestMatchVulnerabilities(t *testing.T) {
+	ranges := map[string][]models.VulnerabilityRange{
+		"openssl": {
+			{AdvisoryID: "CVE-1", Ecosystem: "Debian:12", Package: "openssl", Fixed: "3.0.13-1~deb12u1", Severity: models.SeverityHigh},
+			{AdvisoryID: "CVE-2", Ecosystem: "Debian:11", Package: "openssl", Fixed: "9.9", Severity: models.SeverityLow},
+		},
+	}
+	lookup := func(name string) ([]models.VulnerabilityRange, error) { return ranges[name], nil }
+
+	packages := []models.ImagePackage{
+		{Ecosystem: "Debian:12", Name: "libssl3", Source: "openssl", Version: "3.0.11-1~deb12u2"},
+		{Ecosystem: "Debian:12", Name: "openssl", Version: "3.0.11-1~deb12u2"},
+		{Ecosystem: "Debian:12", Name: "zlib1g", Source: "zlib", Version: "1:1.2.13"},
+	}
+	vulns, err := matchVulnerabilities(packages, lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both binary packages of openssl share one finding
+	if len(vulns) != 1 || vulns[0].ID != "CVE-1" || vulns[0].Package != "openssl" || vulns[0].FixedVersion != "3.0.13-1~deb12u1" {
+		t.Errorf("unexpected matches: %+v", vulns)
+	}
+}
+
+func TestInventoryFromTar(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	files := map[string]string{
+		"etc/os-release": "PRETTY_NAME=\"Debian GNU/Linux 12 (bookworm)\"\nID=debian\nVERSION_ID=\"12\"\n",
+		"var/lib/dpkg/status": "Package: libssl3\nStatus: install ok installed\nSource: openssl (3.0.11-1~deb12u2)\nVersion: 3.0.11-1~deb12u2\nDescription: SSL\n shared libraries\n\n" +
+			"Package: removed\nStatus: deinstall ok config-files\nVersion: 1.0\n\n" +
+			"Package: bash\nStatus: install ok installed\nVersion: 5.2.15-2+b2\n",
+		"var/lib/dpkg/status.d/base-files": "Package: base-files\nVersion: 12.4+deb12u5\n",
+		"usr/bin/script":                   "#!/bin/sh\necho hello\n",
+	}
+	for _, name := range []string{"etc/os-release", "var/lib/dpkg/status", "var/lib/dpkg/status.d/base-files", "usr/bin/script"} {
+		mode := int64(0644)
+		if name == "usr/bin/script" {
+			mode = 0755
+		}
+		tw.WriteHeader(&tar.Header{Name: name, Mode: mode, Size: int64(len(files[name])), Typeflag: tar.TypeReg})
+		tw.Write([]byte(files[name]))
+	}
+	tw.Close()
+
+	inv, err := inventoryFromTar(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inv.OS != "Debian GNU/Linux 12 (bookworm)" {
+		t.Errorf("OS = %q", inv.OS)
+	}
+	want := []models.ImagePackage{
+		{Ecosystem: "Debian:12", Name: "base-files", Version: "12.4+deb12u5"},
+		{Ecosystem: "Debian:12", Name: "bash", Version: "5.2.15-2+b2"},
+		{Ecosystem: "Debian:12", Name: "libssl3", Source: "openssl", Version: "3.0.11-1~deb12u2"},
+	}
+	if len(inv.Packages) != len(want) {
+		t.Fatalf("packages = %+v, want %+v", inv.Packages, want)
+	}
+	for i := range want {
+		if inv.Packages[i] != want[i] {
+			t.Errorf("package %d = %+v, want %+v", i, inv.Packages[i], want[i])
+		}
+	}
+}
+
+func TestParseAPKInstalled(t *testing.T) {
+	data := "C:Q1abc=\nP:libcrypto3\nV:3.3.2-r0\no:openssl\n\nP:busybox\nV:1.36.1-r29\no:busybox\n"
+	got := parseAPKInstalled([]byte(data), distroEcosystem("alpine", "3.20.3"))
+	want := []models.ImagePackage{
+		{Ecosystem: "Alpine:v3.20", Name: "libcrypto3", Source: "openssl", Version: "3.3.2-r0"},
+		{Ecosystem: "Alpine:v3.20", Name: "busybox", Version: "1.36.1-r29"},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("parseAPKInstalled = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseRPMHeader(t *testing.T) {
+	// An rpm header: entry count, data length, 16-byte index entries, data
+	type entry struct {
+		tag, typ uint32
+		value    []byte
+	}
+	entries := []entry{
+		{rpmTagName, 6, []byte("openssl-libs\x00")},
+		{rpmTagVersion, 6, []byte("3.0.7\x00")},
+		{rpmTagRelease, 6, []byte("27.el9\x00")},
+		{rpmTagEpoch, 4, []byte{0, 0, 0, 1}},
+		{rpmTagSourceRPM, 6, []byte("openssl-3.0.7-27.el9.src.rpm\x00")},
+	}
+	var index, data bytes.Buffer
+	for _, e := range entries {
+		binary.Write(&index, binary.BigEndian, []uint32{e.tag, e.typ, uint32(data.Len()), 1})
+		data.Write(e.value)
+	}
+	var blob bytes.Buffer
+	binary.Write(&blob, binary.BigEndian, []uint32{uint32(len(entries)), uint32(data.Len())})
+	blob.Write(index.Bytes())
+	blob.Write(data.Bytes())
+
+	name, version, source, err := parseRPMHeader(blob.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "openssl-libs" || version != "1:3.0.7-27.el9" || source != "openssl" {
+		t.Errorf("parseRPMHeader = %q %q %q", name, version, source)
+	}
+}
@@ -0,0 +1,366 @@
+package system
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"podmangr-backend/internal/models"
+)
+
+// compareVersionsIn compares two package versions with the rules of an OSV
+// ecosystem: dpkg for Debian and Ubuntu, rpm for Red Hat derivatives, apk
+// for Alpine and Wolfi, semver for Go
+func compareVersionsIn(ecosystem, a, b string) int {
+	name, _, _ := strings.Cut(ecosystem, ":")
+	switch name {
+	case "Debian", "Ubuntu":
+		return compareDpkgVersions(a, b)
+	case "Red Hat", "Rocky Linux", "AlmaLinux", "openSUSE", "SUSE", "Mageia":
+		return compareRPMVersions(a, b)
+	case "Go":
+		return compareSemver(a, b)
+	}
+	return compareAPKVersions(a, b)
+}
+
+// sign reduces a comparison to -1, 0 or 1
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// splitEpoch splits "epoch:rest" into the numeric epoch and the rest
+func splitEpoch(v string) (int, string) {
+	if e, rest, ok := strings.Cut(v, ":"); ok {
+		if n, err := strconv.Atoi(e); err == nil {
+			return n, rest
+		}
+	}
+	return 0, v
+}
+
+// compareDpkgVersions compares Debian versions: [epoch:]upstream[-revision]
+func compareDpkgVersions(a, b string) int {
+	ea, a := splitEpoch(a)
+	eb, b := splitEpoch(b)
+	if ea != eb {
+		return sign(ea - eb)
+	}
+	ua, ra := a, "0"
+	if i := strings.LastIndex(a, "-"); i >= 0 {
+		ua, ra = a[:i], a[i+1:]
+	}
+	ub, rb := b, "0"
+	if i := strings.LastIndex(b, "-"); i >= 0 {
+		ub, rb = b[:i], b[i+1:]
+	}
+	if c := compareDpkgPart(ua, ub); c != 0 {
+		return c
+	}
+	return compareDpkgPart(ra, rb)
+}
+
+// dpkgOrder orders a character of the non-digit part of a Debian version:
+// ~ sorts before anything, even the end of the part, and letters before
+// other characters
+func dpkgOrder(c byte) int {
+	switch {
+	case c == '~':
+		return -1
+	case c >= '0' && c <= '9':
+		return 0
+	case unicode.IsLetter(rune(c)):
+		return int(c)
+	}
+	return int(c) + 256
+}
+
+// compareDpkgPart compares an upstream version or revision with dpkg's algorithm
+func compareDpkgPart(a, b string) int {
+	for a != "" || b != "" {
+		// Non-digit prefixes compare character by character
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			ca, cb := 0, 0
+			if a != "" {
+				ca = dpkgOrder(a[0])
+			}
+			if b != "" {
+				cb = dpkgOrder(b[0])
+			}
+			if ca != cb {
+				return sign(ca - cb)
+			}
+			a, b = a[1:], b[1:]
+		}
+		// Digit runs compare numerically
+		var na, nb string
+		na, a = takeDigits(a)
+		nb, b = takeDigits(b)
+		if c := compareNumeric(na, nb); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// takeDigits splits a leading run of digits off s
+func takeDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// compareNumeric compares digit strings of any length by value
+func compareNumeric(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return sign(len(a) - len(b))
+	}
+	return strings.Compare(a, b)
+}
+
+// compareRPMVersions compares [epoch:]version[-release] with rpmvercmp
+func compareRPMVersions(a, b string) int {
+	ea, a := splitEpoch(a)
+	eb, b := splitEpoch(b)
+	if ea != eb {
+		return sign(ea - eb)
+	}
+	va, ra, _ := strings.Cut(a, "-")
+	vb, rb, _ := strings.Cut(b, "-")
+	if c := rpmvercmp(va, vb); c != 0 || ra == "" || rb == "" {
+		return c
+	}
+	return rpmvercmp(ra, rb)
+}
+
+// rpmSeparator reports whether c only separates runs in an rpm version
+func rpmSeparator(c rune) bool {
+	return !unicode.IsDigit(c) && !unicode.IsLetter(c) && c != '~' && c != '^'
+}
+
+// rpmvercmp compares alternating runs of digits and letters; other
+// characters only separate runs, ~ sorts before everything and ^ after
+// everything but the end of the version
+func rpmvercmp(a, b string) int {
+	for a != "" || b != "" {
+		a = strings.TrimLeftFunc(a, rpmSeparator)
+		b = strings.TrimLeftFunc(b, rpmSeparator)
+
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			switch {
+			case a == "":
+				return -1
+			case b == "":
+				return 1
+			case !strings.HasPrefix(a, "^"):
+				return 1
+			case !strings.HasPrefix(b, "^"):
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if a == "" || b == "" {
+			break
+		}
+
+		var ra, rb string
+		numeric := isDigit(a[0])
+		if numeric {
+			ra, a = takeDigits(a)
+			rb, b = takeDigits(b)
+		} else {
+			ra, a = takeLetters(a)
+			rb, b = takeLetters(b)
+		}
+		if rb == "" {
+			// Numeric runs are newer than alphabetic ones
+			if numeric {
+				return 1
+			}
+			return -1
+		}
+		c := strings.Compare(ra, rb)
+		if numeric {
+			c = compareNumeric(ra, rb)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	}
+	return 1
+}
+
+// takeLetters splits a leading run of letters off s
+func takeLetters(s string) (string, string) {
+	i := 0
+	for i < len(s) && unicode.IsLetter(rune(s[i])) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// apkSuffixes orders the pre- and post-release suffixes of apk versions
+var apkSuffixes = map[string]int{
+	"alpha": -4, "beta": -3, "pre": -2, "rc": -1,
+	"cvs": 1, "svn": 2, "git": 3, "hg": 4, "p": 5,
+}
+
+// compareAPKVersions compares Alpine versions: digits separated by dots,
+// an optional letter, _suffix[N] parts and -rN for the package release
+func compareAPKVersions(a, b string) int {
+	va, ra, _ := strings.Cut(a, "-r")
+	vb, rb, _ := strings.Cut(b, "-r")
+	if c := compareAPKVersion(va, vb); c != 0 {
+		return c
+	}
+	return compareNumeric(ra, rb)
+}
+
+func compareAPKVersion(a, b string) int {
+	mainA, sufA, _ := strings.Cut(a, "_")
+	mainB, sufB, _ := strings.Cut(b, "_")
+
+	pa, pb := strings.Split(mainA, "."), strings.Split(mainB, ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		if i >= len(pa) {
+			return -1
+		}
+		if i >= len(pb) {
+			return 1
+		}
+		na, la := takeDigits(pa[i])
+		nb, lb := takeDigits(pb[i])
+		if c := compareNumeric(na, nb); c != 0 {
+			return c
+		}
+		if c := strings.Compare(la, lb); c != 0 {
+			return c
+		}
+	}
+
+	suffixes := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, "_")
+	}
+	sa, sb := suffixes(sufA), suffixes(sufB)
+	for i := 0; i < len(sa) || i < len(sb); i++ {
+		var xa, xb string
+		if i < len(sa) {
+			xa = sa[i]
+		}
+		if i < len(sb) {
+			xb = sb[i]
+		}
+		nameA, numA := takeLetters(xa)
+		nameB, numB := takeLetters(xb)
+		// A missing suffix is newer than a pre-release and older than a patch
+		if c := sign(apkSuffixes[nameA] - apkSuffixes[nameB]); c != 0 {
+			return c
+		}
+		if c := compareNumeric(numA, numB); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareSemver compares semantic versions with or without the v prefix.
+// Pre-releases sort before their release; build metadata is ignored.
+func compareSemver(a, b string) int {
+	a, _, _ = strings.Cut(strings.TrimPrefix(a, "v"), "+")
+	b, _, _ = strings.Cut(strings.TrimPrefix(b, "v"), "+")
+	coreA, preA, _ := strings.Cut(a, "-")
+	coreB, preB, _ := strings.Cut(b, "-")
+
+	pa, pb := strings.Split(coreA, "."), strings.Split(coreB, ".")
+	for i := 0; i < 3; i++ {
+		var xa, xb string
+		if i < len(pa) {
+			xa = pa[i]
+		}
+		if i < len(pb) {
+			xb = pb[i]
+		}
+		if c := compareNumeric(xa, xb); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	ia, ib := strings.Split(preA, "."), strings.Split(preB, ".")
+	for i := 0; i < len(ia) && i < len(ib); i++ {
+		_, restA := takeDigits(ia[i])
+		_, restB := takeDigits(ib[i])
+		var c int
+		if restA == "" && restB == "" {
+			c = compareNumeric(ia[i], ib[i])
+		} else {
+			c = strings.Compare(ia[i], ib[i])
+		}
+		if c != 0 {
+			return sign(c)
+		}
+	}
+	return sign(len(ia) - len(ib))
+}
+
+// rangeAffects reports whether a version of a package falls in an affected range
+func rangeAffects(rg models.VulnerabilityRange, version string) bool {
+	cmp := func(other string) int { return compareVersionsIn(rg.Ecosystem, version, other) }
+	if rg.Introduced != "" && rg.Introduced != "0" && cmp(rg.Introduced) < 0 {
+		return false
+	}
+	switch {
+	case rg.Fixed != "":
+		return cmp(rg.Fixed) < 0
+	case rg.LastAffected != "":
+		return cmp(rg.LastAffected) <= 0
+	}
+	return true
+}
+
+// ecosystemMatches reports whether an advisory's ecosystem covers a package's:
+// Ubuntu:22.04 covers Ubuntu:22.04:LTS advisories, Red Hat covers all of Red Hat's
+func ecosystemMatches(advisory, pkg string) bool {
+	return advisory == pkg || strings.HasPrefix(advisory, pkg+":")
+}
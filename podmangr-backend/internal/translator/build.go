@@ -0,0 +1,160 @@
+package translator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PlannedBuild is how the image of a service with a build section is built
+type PlannedBuild struct {
+	ContextDir    string            // Absolute context directory; empty for git contexts
+	GitURL        string            // Git repository context, e.g. https://host/repo.git#main:dir
+	Containerfile string            // dockerfile, relative to the context unless absolute
+	Inline        string            // dockerfile_inline
+	Args          map[string]string // Build arguments with a value
+	Labels        map[string]string
+	Target        string
+	Platform      string
+	CacheFrom     []string
+	CacheTo       []string
+	NoCache       bool
+	Pull          bool
+	Tags          []string // Tags besides the service image
+}
+
+// buildKeys are the keys of a build section the stack engine applies
+var buildKeys = map[string]bool{
+	"context": true, "dockerfile": true, "dockerfile_inline": true, "args": true, "labels": true,
+	"target": true, "platforms": true, "cache_from": true, "cache_to": true, "no_cache": true,
+	"pull": true, "tags": true,
+}
+
+// isGitContext reports whether a build context is a git repository rather than a directory
+func isGitContext(context string) bool {
+	for _, prefix := range []string{"git://", "git@", "github.com/"} {
+		if strings.HasPrefix(context, prefix) {
+			return true
+		}
+	}
+	if strings.HasPrefix(context, "http://") || strings.HasPrefix(context, "https://") {
+		path, _, _ := strings.Cut(context, "#")
+		return strings.HasSuffix(path, ".git") || strings.Contains(context, "#")
+	}
+	return false
+}
+
+// builtImageName returns the image a service is built as. Unqualified names
+// get the localhost/ prefix Podman stores local builds under.
+func builtImageName(project, service, image string) string {
+	if image == "" {
+		image = strings.ToLower(project + "_" + service)
+	}
+	first, _, hasSlash := strings.Cut(image, "/")
+	if hasSlash && (first == "localhost" || strings.ContainsAny(first, ".:")) {
+		return image
+	}
+	return "localhost/" + image
+}
+
+// build plans the build section of a service, given as a context string or a map
+func (p *planner) build(loc string, raw interface{}) (*PlannedBuild, error) {
+	spec := map[string]interface{}{}
+	switch b := raw.(type) {
+	case string:
+		spec["context"] = b
+	case map[string]interface{}:
+		spec = b
+	default:
+		return nil, fmt.Errorf("%s: must be a context path or a mapping", loc)
+	}
+
+	str := func(key string) (string, error) {
+		switch v := spec[key].(type) {
+		case nil:
+			return "", nil
+		case string:
+			return v, nil
+		}
+		return "", fmt.Errorf("%s.%s: must be a string", loc, key)
+	}
+	list := func(key string) []string {
+		var values []string
+		if items, ok := spec[key].([]interface{}); ok {
+			for _, item := range items {
+				if s, ok := item.(string); ok {
+					values = append(values, s)
+				}
+			}
+		}
+		return values
+	}
+
+	b := &PlannedBuild{Labels: parseLabels(spec["labels"]), Args: map[string]string{}}
+	context, err := str("context")
+	if err != nil {
+		return nil, err
+	}
+	if context == "" {
+		context = "."
+	}
+	if isGitContext(context) {
+		b.GitURL = context
+	} else {
+		b.ContextDir = p.path(context)
+	}
+	if b.Containerfile, err = str("dockerfile"); err != nil {
+		return nil, err
+	}
+	if b.Inline, err = str("dockerfile_inline"); err != nil {
+		return nil, err
+	}
+	if b.Containerfile != "" && b.Inline != "" {
+		return nil, fmt.Errorf("%s: dockerfile and dockerfile_inline can't be combined", loc)
+	}
+	if b.Target, err = str("target"); err != nil {
+		return nil, err
+	}
+
+	// Arguments without a value keep the default of their ARG instruction
+	switch args := spec["args"].(type) {
+	case map[string]interface{}:
+		for k, v := range args {
+			if v != nil {
+				b.Args[k] = fmt.Sprintf("%v", v)
+			}
+		}
+	case []interface{}:
+		for _, item := range args {
+			if s, ok := item.(string); ok {
+				if k, v, ok := strings.Cut(s, "="); ok {
+					b.Args[k] = v
+				}
+			}
+		}
+	}
+
+	if platforms := list("platforms"); len(platforms) > 0 {
+		b.Platform = platforms[0]
+		if len(platforms) > 1 {
+			p.warn(loc+".platforms", "only %s is built; multi-platform builds are not supported", b.Platform)
+		}
+	}
+	b.CacheFrom = list("cache_from")
+	b.CacheTo = list("cache_to")
+	b.Tags = list("tags")
+	b.NoCache, _ = spec["no_cache"].(bool)
+	b.Pull, _ = spec["pull"].(bool)
+
+	var ignored []string
+	for key := range spec {
+		if !buildKeys[key] {
+			ignored = append(ignored, key)
+		}
+	}
+	sort.Strings(ignored)
+	for _, key := range ignored {
+		p.warn(loc+"."+key, "not supported by the stack engine, ignored")
+	}
+	return b, nil
+}
@@ -7,16 +7,26 @@ import (
 	"strings"
 
 	"gopkg.in/yaml.v3"
+
+	"podmangr-backend/internal/quadlet"
 )
 
 // ComposeFile represents a Docker Compose file structure
 type ComposeFile struct {
 	Version  string                 `yaml:"version,omitempty"`
+	Name     string                 `yaml:"name,omitempty"` // Project name
 	Services map[string]Service     `yaml:"services"`
 	Networks map[string]Network     `yaml:"networks,omitempty"`
 	Volumes  map[string]Volume      `yaml:"volumes,omitempty"`
 	Secrets  map[string]interface{} `yaml:"secrets,omitempty"`
 	Configs  map[string]interface{} `yaml:"configs,omitempty"`
+	Podman   *PodmanExtension       `yaml:"x-podman,omitempty"`
+}
+
+// PodmanExtension holds the podman-compose x-podman settings of a project
+type PodmanExtension struct {
+	// InPod runs all services in one pod, as podman-compose does by default
+	InPod bool `yaml:"in_pod,omitempty"`
 }
 
 // Service represents a Docker Compose service
@@ -41,6 +51,7 @@ type Service struct {
 	CapAdd        []string          `yaml:"cap_add,omitempty"`
 	CapDrop       []string          `yaml:"cap_drop,omitempty"`
 	Privileged    bool              `yaml:"privileged,omitempty"`
+	ReadOnly      bool              `yaml:"read_only,omitempty"`
 	SecurityOpt   []string          `yaml:"security_opt,omitempty"`
 	Sysctls       interface{}       `yaml:"sysctls,omitempty"`
 	Ulimits       interface{}       `yaml:"ulimits,omitempty"`
@@ -61,6 +72,8 @@ type Service struct {
 	StopSignal    string            `yaml:"stop_signal,omitempty"`
 	StopTimeout   interface{}       `yaml:"stop_grace_period,omitempty"`
 	Runtime       string            `yaml:"runtime,omitempty"`
+	Secrets       interface{}       `yaml:"secrets,omitempty"`
+	Configs       interface{}       `yaml:"configs,omitempty"`
 }
 
 // Network represents a Docker Compose network
@@ -88,6 +101,7 @@ type Volume struct {
 type TranslationResult struct {
 	Output       string            `json:"output"`
 	OutputFormat string            `json:"output_format"`
+	Files        []quadlet.File    `json:"files,omitempty"` // Individual unit files (quadlet format)
 	Warnings     []string          `json:"warnings"`
 	Errors       []string          `json:"errors"`
 	Changes      []TransformChange `json:"changes"`
@@ -161,17 +175,67 @@ func (t *Translator) initRules() {
 	}
 }
 
-// Parse parses a Docker Compose YAML string
+// Parse parses a Docker Compose YAML string. Variables are left blank;
+// use TranslateWithOptions or Load to interpolate them.
 func (t *Translator) Parse(input string) (*ComposeFile, error) {
-	var compose ComposeFile
-	if err := yaml.Unmarshal([]byte(input), &compose); err != nil {
+	compose, _, err := Load([]ConfigFile{{Path: defaultComposeFile, Content: input}}, LoadOptions{})
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse compose file: %w", err)
 	}
-	return &compose, nil
+	return compose, nil
+}
+
+// defaultComposeFile is the path the main input is loaded under
+const defaultComposeFile = "docker-compose.yml"
+
+// Options carries context the compose file itself does not contain
+type Options struct {
+	// Project names the generated pod, volumes and networks when the
+	// compose file has no top-level name
+	Project string
+	// Files holds the contents of files the compose file references
+	// (env_file, secrets, include, extends), keyed by the path as written
+	// in the compose file
+	Files map[string]string
+	// Env holds the variables used for interpolation (the .env file)
+	Env map[string]string
+	// Profiles lists the active profiles
+	Profiles []string
+	// Overrides are compose files merged over the input, in order
+	Overrides []string
+}
+
+// Load resolves the input and overrides into a single compose file
+func (o Options) Load(input string) (*ComposeFile, []string, error) {
+	files := []ConfigFile{{Path: defaultComposeFile, Content: input}}
+	for i, override := range o.Overrides {
+		files = append(files, ConfigFile{Path: fmt.Sprintf("override-%d.yml", i+1), Content: override})
+	}
+	return Load(files, LoadOptions{
+		Project:  o.Project,
+		Env:      o.Env,
+		Profiles: o.Profiles,
+		ReadFile: o.readFile,
+	})
+}
+
+// readFile looks a referenced file up in Files
+func (o Options) readFile(path string) ([]byte, error) {
+	for _, key := range []string{path, "./" + path} {
+		if content, ok := o.Files[key]; ok {
+			return []byte(content), nil
+		}
+	}
+	return nil, fmt.Errorf("file %s was not provided", path)
 }
 
 // Translate converts a Docker Compose file to the specified output format
 func (t *Translator) Translate(input string, format OutputFormat) (*TranslationResult, error) {
+	return t.TranslateWithOptions(input, format, Options{})
+}
+
+// TranslateWithOptions converts a Docker Compose file using extra context
+func (t *Translator) TranslateWithOptions(input string, format OutputFormat, opts Options) (*TranslationResult, error) {
 	result := &TranslationResult{
 		OutputFormat: string(format),
 		Warnings:     []string{},
@@ -179,8 +243,9 @@ func (t *Translator) Translate(input string, format OutputFormat) (*TranslationR
 		Changes:      []TransformChange{},
 	}
 
-	// Parse the input
-	compose, err := t.Parse(input)
+	// Load the input with its overrides and variables
+	compose, warnings, err := opts.Load(input)
+	result.Warnings = append(result.Warnings, warnings...)
 	if err != nil {
 		result.Errors = append(result.Errors, err.Error())
 		return result, err
@@ -193,7 +258,26 @@ func (t *Translator) Translate(input string, format OutputFormat) (*TranslationR
 		}
 	}
 
-	// Generate output based on format
+	return t.render(compose, format, opts, result)
+}
+
+// Render generates output for an already built compose file without
+// applying the Docker-to-Podman transformation rules. It is used to export
+// what is already running under Podman.
+func (t *Translator) Render(compose *ComposeFile, format OutputFormat, opts Options) (*TranslationResult, error) {
+	if compose.Name == "" {
+		compose.Name = opts.Project
+	}
+	return t.render(compose, format, opts, &TranslationResult{
+		OutputFormat: string(format),
+		Warnings:     []string{},
+		Errors:       []string{},
+		Changes:      []TransformChange{},
+	})
+}
+
+// render generates the output format into result
+func (t *Translator) render(compose *ComposeFile, format OutputFormat, opts Options, result *TranslationResult) (*TranslationResult, error) {
 	switch format {
 	case FormatPodmanCompose:
 		output, err := t.toPodmanCompose(compose)
@@ -204,15 +288,14 @@ func (t *Translator) Translate(input string, format OutputFormat) (*TranslationR
 		result.Output = output
 
 	case FormatQuadlet:
-		output, err := t.toQuadlet(compose)
-		if err != nil {
-			result.Errors = append(result.Errors, err.Error())
-			return result, err
-		}
-		result.Output = output
+		files, warnings := t.toQuadlet(compose)
+		result.Files = files
+		result.Output = joinQuadletFiles(files)
+		result.Warnings = append(result.Warnings, warnings...)
 
 	case FormatKube:
-		output, err := t.toKube(compose)
+		output, warnings, err := t.toKube(compose, opts.Files)
+		result.Warnings = append(result.Warnings, warnings...)
 		if err != nil {
 			result.Errors = append(result.Errors, err.Error())
 			return result, err
@@ -235,228 +318,6 @@ func (t *Translator) toPodmanCompose(compose *ComposeFile) (string, error) {
 	return string(output), nil
 }
 
-// toQuadlet generates Quadlet systemd unit files
-func (t *Translator) toQuadlet(compose *ComposeFile) (string, error) {
-	var result strings.Builder
-
-	// Sort service names for consistent output
-	serviceNames := make([]string, 0, len(compose.Services))
-	for name := range compose.Services {
-		serviceNames = append(serviceNames, name)
-	}
-	sort.Strings(serviceNames)
-
-	for i, name := range serviceNames {
-		service := compose.Services[name]
-		if i > 0 {
-			result.WriteString("\n---\n\n")
-		}
-
-		result.WriteString(fmt.Sprintf("# %s.container\n", name))
-		result.WriteString("[Container]\n")
-
-		if service.Image != "" {
-			result.WriteString(fmt.Sprintf("Image=%s\n", service.Image))
-		}
-
-		if service.ContainerName != "" {
-			result.WriteString(fmt.Sprintf("ContainerName=%s\n", service.ContainerName))
-		} else {
-			result.WriteString(fmt.Sprintf("ContainerName=%s\n", name))
-		}
-
-		// Environment variables
-		envs := parseEnvironment(service.Environment)
-		for k, v := range envs {
-			result.WriteString(fmt.Sprintf("Environment=%s=%s\n", k, v))
-		}
-
-		// Ports
-		for _, port := range service.Ports {
-			result.WriteString(fmt.Sprintf("PublishPort=%s\n", port))
-		}
-
-		// Volumes
-		for _, vol := range service.Volumes {
-			result.WriteString(fmt.Sprintf("Volume=%s\n", vol))
-		}
-
-		// Networks
-		networks := parseNetworks(service.Networks)
-		for _, net := range networks {
-			result.WriteString(fmt.Sprintf("Network=%s\n", net))
-		}
-
-		// Labels
-		labels := parseLabels(service.Labels)
-		for k, v := range labels {
-			result.WriteString(fmt.Sprintf("Label=%s=%s\n", k, v))
-		}
-
-		// Capabilities
-		for _, cap := range service.CapAdd {
-			result.WriteString(fmt.Sprintf("AddCapability=%s\n", cap))
-		}
-		for _, cap := range service.CapDrop {
-			result.WriteString(fmt.Sprintf("DropCapability=%s\n", cap))
-		}
-
-		// Privileged
-		if service.Privileged {
-			result.WriteString("SecurityLabelDisable=true\n")
-		}
-
-		// User
-		if service.User != "" {
-			result.WriteString(fmt.Sprintf("User=%s\n", service.User))
-		}
-
-		// Working directory
-		if service.WorkingDir != "" {
-			result.WriteString(fmt.Sprintf("WorkingDir=%s\n", service.WorkingDir))
-		}
-
-		// Devices
-		for _, dev := range service.Devices {
-			result.WriteString(fmt.Sprintf("AddDevice=%s\n", dev))
-		}
-
-		// Extra hosts
-		for _, host := range service.ExtraHosts {
-			result.WriteString(fmt.Sprintf("HostName=%s\n", host))
-		}
-
-		result.WriteString("\n[Service]\n")
-		// Restart policy
-		if service.Restart != "" {
-			switch service.Restart {
-			case "always", "unless-stopped":
-				result.WriteString("Restart=always\n")
-			case "on-failure":
-				result.WriteString("Restart=on-failure\n")
-			case "no":
-				result.WriteString("Restart=no\n")
-			}
-		}
-
-		result.WriteString("\n[Install]\n")
-		result.WriteString("WantedBy=default.target\n")
-	}
-
-	return result.String(), nil
-}
-
-// toKube generates Kubernetes YAML
-func (t *Translator) toKube(compose *ComposeFile) (string, error) {
-	var result strings.Builder
-
-	// Sort service names for consistent output
-	serviceNames := make([]string, 0, len(compose.Services))
-	for name := range compose.Services {
-		serviceNames = append(serviceNames, name)
-	}
-	sort.Strings(serviceNames)
-
-	result.WriteString("apiVersion: v1\n")
-	result.WriteString("kind: Pod\n")
-	result.WriteString("metadata:\n")
-	result.WriteString("  name: compose-pod\n")
-	result.WriteString("spec:\n")
-	result.WriteString("  containers:\n")
-
-	for _, name := range serviceNames {
-		service := compose.Services[name]
-
-		result.WriteString(fmt.Sprintf("    - name: %s\n", name))
-		if service.Image != "" {
-			result.WriteString(fmt.Sprintf("      image: %s\n", service.Image))
-		}
-
-		// Command
-		if service.Command != nil {
-			switch cmd := service.Command.(type) {
-			case string:
-				result.WriteString(fmt.Sprintf("      command: [\"/bin/sh\", \"-c\", %q]\n", cmd))
-			case []interface{}:
-				result.WriteString("      command:\n")
-				for _, c := range cmd {
-					result.WriteString(fmt.Sprintf("        - %q\n", c))
-				}
-			}
-		}
-
-		// Environment
-		envs := parseEnvironment(service.Environment)
-		if len(envs) > 0 {
-			result.WriteString("      env:\n")
-			for k, v := range envs {
-				result.WriteString(fmt.Sprintf("        - name: %s\n", k))
-				result.WriteString(fmt.Sprintf("          value: %q\n", v))
-			}
-		}
-
-		// Ports
-		if len(service.Ports) > 0 {
-			result.WriteString("      ports:\n")
-			for _, port := range service.Ports {
-				// Parse port mapping
-				parts := strings.Split(port, ":")
-				if len(parts) >= 2 {
-					containerPort := parts[len(parts)-1]
-					// Remove /tcp or /udp suffix
-					containerPort = strings.Split(containerPort, "/")[0]
-					result.WriteString(fmt.Sprintf("        - containerPort: %s\n", containerPort))
-				}
-			}
-		}
-
-		// Volume mounts
-		if len(service.Volumes) > 0 {
-			result.WriteString("      volumeMounts:\n")
-			for i, vol := range service.Volumes {
-				parts := strings.Split(vol, ":")
-				if len(parts) >= 2 {
-					result.WriteString(fmt.Sprintf("        - name: volume-%d\n", i))
-					result.WriteString(fmt.Sprintf("          mountPath: %s\n", parts[1]))
-				}
-			}
-		}
-
-		// Security context
-		if service.Privileged {
-			result.WriteString("      securityContext:\n")
-			result.WriteString("        privileged: true\n")
-		}
-	}
-
-	// Add volume definitions
-	hasVolumes := false
-	for _, name := range serviceNames {
-		if len(compose.Services[name].Volumes) > 0 {
-			hasVolumes = true
-			break
-		}
-	}
-
-	if hasVolumes {
-		result.WriteString("  volumes:\n")
-		volIndex := 0
-		for _, name := range serviceNames {
-			for _, vol := range compose.Services[name].Volumes {
-				parts := strings.Split(vol, ":")
-				if len(parts) >= 2 {
-					result.WriteString(fmt.Sprintf("    - name: volume-%d\n", volIndex))
-					result.WriteString("      hostPath:\n")
-					result.WriteString(fmt.Sprintf("        path: %s\n", parts[0]))
-					volIndex++
-				}
-			}
-		}
-	}
-
-	return result.String(), nil
-}
-
 // Transformation Rules
 
 func ruleDockerSocket(compose *ComposeFile, changes *[]TransformChange) error {
@@ -0,0 +1,1016 @@
+package translator
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The types below are the subset of the Kubernetes API that podman kube play
+// understands. They are marshalled as-is, so field names follow the API.
+
+type kubeMeta struct {
+	Name        string            `yaml:"name,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+type kubePod struct {
+	APIVersion string      `yaml:"apiVersion"`
+	Kind       string      `yaml:"kind"`
+	Metadata   kubeMeta    `yaml:"metadata"`
+	Spec       kubePodSpec `yaml:"spec"`
+}
+
+type kubeDeployment struct {
+	APIVersion string             `yaml:"apiVersion"`
+	Kind       string             `yaml:"kind"`
+	Metadata   kubeMeta           `yaml:"metadata"`
+	Spec       kubeDeploymentSpec `yaml:"spec"`
+}
+
+type kubeDeploymentSpec struct {
+	Replicas int                 `yaml:"replicas"`
+	Selector kubeLabelSelector   `yaml:"selector"`
+	Template kubePodTemplateSpec `yaml:"template"`
+}
+
+type kubeLabelSelector struct {
+	MatchLabels map[string]string `yaml:"matchLabels"`
+}
+
+type kubePodTemplateSpec struct {
+	Metadata kubeMeta    `yaml:"metadata"`
+	Spec     kubePodSpec `yaml:"spec"`
+}
+
+type kubePodSpec struct {
+	Hostname                      string          `yaml:"hostname,omitempty"`
+	HostNetwork                   bool            `yaml:"hostNetwork,omitempty"`
+	HostAliases                   []kubeHostAlias `yaml:"hostAliases,omitempty"`
+	RestartPolicy                 string          `yaml:"restartPolicy,omitempty"`
+	TerminationGracePeriodSeconds *int            `yaml:"terminationGracePeriodSeconds,omitempty"`
+	Containers                    []kubeContainer `yaml:"containers"`
+	Volumes                       []kubeVolume    `yaml:"volumes,omitempty"`
+}
+
+type kubeHostAlias struct {
+	IP        string   `yaml:"ip"`
+	Hostnames []string `yaml:"hostnames"`
+}
+
+type kubeContainer struct {
+	Name            string               `yaml:"name"`
+	Image           string               `yaml:"image"`
+	Command         []string             `yaml:"command,omitempty"`
+	Args            []string             `yaml:"args,omitempty"`
+	WorkingDir      string               `yaml:"workingDir,omitempty"`
+	Env             []kubeEnvVar         `yaml:"env,omitempty"`
+	EnvFrom         []kubeEnvFromSource  `yaml:"envFrom,omitempty"`
+	Ports           []kubeContainerPort  `yaml:"ports,omitempty"`
+	VolumeMounts    []kubeVolumeMount    `yaml:"volumeMounts,omitempty"`
+	LivenessProbe   *kubeProbe           `yaml:"livenessProbe,omitempty"`
+	Resources       *kubeResources       `yaml:"resources,omitempty"`
+	SecurityContext *kubeSecurityContext `yaml:"securityContext,omitempty"`
+	Stdin           bool                 `yaml:"stdin,omitempty"`
+	TTY             bool                 `yaml:"tty,omitempty"`
+}
+
+type kubeEnvVar struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+type kubeEnvFromSource struct {
+	ConfigMapRef *kubeLocalObjectRef `yaml:"configMapRef,omitempty"`
+}
+
+type kubeLocalObjectRef struct {
+	Name string `yaml:"name"`
+}
+
+type kubeContainerPort struct {
+	ContainerPort int    `yaml:"containerPort"`
+	HostPort      int    `yaml:"hostPort,omitempty"`
+	HostIP        string `yaml:"hostIP,omitempty"`
+	Protocol      string `yaml:"protocol,omitempty"`
+}
+
+type kubeVolumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+	SubPath   string `yaml:"subPath,omitempty"`
+	ReadOnly  bool   `yaml:"readOnly,omitempty"`
+}
+
+type kubeProbe struct {
+	Exec                kubeExecAction `yaml:"exec"`
+	InitialDelaySeconds int            `yaml:"initialDelaySeconds,omitempty"`
+	PeriodSeconds       int            `yaml:"periodSeconds,omitempty"`
+	TimeoutSeconds      int            `yaml:"timeoutSeconds,omitempty"`
+	FailureThreshold    int            `yaml:"failureThreshold,omitempty"`
+}
+
+type kubeExecAction struct {
+	Command []string `yaml:"command"`
+}
+
+type kubeResources struct {
+	Limits   map[string]string `yaml:"limits,omitempty"`
+	Requests map[string]string `yaml:"requests,omitempty"`
+}
+
+type kubeSecurityContext struct {
+	Privileged               *bool               `yaml:"privileged,omitempty"`
+	RunAsUser                *int64              `yaml:"runAsUser,omitempty"`
+	RunAsGroup               *int64              `yaml:"runAsGroup,omitempty"`
+	AllowPrivilegeEscalation *bool               `yaml:"allowPrivilegeEscalation,omitempty"`
+	ReadOnlyRootFilesystem   *bool               `yaml:"readOnlyRootFilesystem,omitempty"`
+	Capabilities             *kubeCapabilities   `yaml:"capabilities,omitempty"`
+	SELinuxOptions           *kubeSELinuxOptions `yaml:"seLinuxOptions,omitempty"`
+}
+
+type kubeCapabilities struct {
+	Add  []string `yaml:"add,omitempty"`
+	Drop []string `yaml:"drop,omitempty"`
+}
+
+type kubeSELinuxOptions struct {
+	Type string `yaml:"type,omitempty"`
+}
+
+type kubeVolume struct {
+	Name                  string                  `yaml:"name"`
+	PersistentVolumeClaim *kubePVCSource          `yaml:"persistentVolumeClaim,omitempty"`
+	HostPath              *kubeHostPathSource     `yaml:"hostPath,omitempty"`
+	EmptyDir              *kubeEmptyDirSource     `yaml:"emptyDir,omitempty"`
+	Secret                *kubeSecretVolumeSource `yaml:"secret,omitempty"`
+}
+
+type kubePVCSource struct {
+	ClaimName string `yaml:"claimName"`
+	ReadOnly  bool   `yaml:"readOnly,omitempty"`
+}
+
+type kubeHostPathSource struct {
+	Path string `yaml:"path"`
+	Type string `yaml:"type,omitempty"`
+}
+
+type kubeEmptyDirSource struct {
+	Medium string `yaml:"medium,omitempty"`
+}
+
+type kubeSecretVolumeSource struct {
+	SecretName string `yaml:"secretName"`
+}
+
+type kubePVC struct {
+	APIVersion string      `yaml:"apiVersion"`
+	Kind       string      `yaml:"kind"`
+	Metadata   kubeMeta    `yaml:"metadata"`
+	Spec       kubePVCSpec `yaml:"spec"`
+}
+
+type kubePVCSpec struct {
+	AccessModes []string      `yaml:"accessModes"`
+	Resources   kubeResources `yaml:"resources"`
+}
+
+type kubeConfigMap struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   kubeMeta          `yaml:"metadata"`
+	Data       map[string]string `yaml:"data"`
+}
+
+type kubeSecret struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   kubeMeta          `yaml:"metadata"`
+	Type       string            `yaml:"type"`
+	StringData map[string]string `yaml:"stringData"`
+}
+
+// defaultKubeProject names the pod when the compose file has no project name
+const defaultKubeProject = "compose"
+
+// defaultClaimSize is the storage request on generated claims. Podman
+// ignores it, but the field is required by the API.
+const defaultClaimSize = "1Gi"
+
+// kubeGenerator turns a parsed compose file into Kubernetes objects
+type kubeGenerator struct {
+	compose  *ComposeFile
+	files    map[string]string
+	warnings []string
+
+	name       string
+	volumes    []kubeVolume
+	volumeIdx  map[string]string // pod volume key -> volume name
+	claims     []string          // PVC names to generate, in order
+	claimSet   map[string]bool
+	configMaps []kubeConfigMap
+	secrets    []kubeSecret
+	secretSet  map[string]bool
+}
+
+// toKube generates a multi-document Kubernetes YAML for podman kube play:
+// PersistentVolumeClaims, ConfigMaps and Secrets followed by a Pod (or a
+// Deployment when a service sets deploy.replicas) holding every service
+func (t *Translator) toKube(compose *ComposeFile, files map[string]string) (string, []string, error) {
+	g := &kubeGenerator{
+		compose:   compose,
+		files:     files,
+		name:      kubeName(compose.Name),
+		volumeIdx: make(map[string]string),
+		claimSet:  make(map[string]bool),
+		secretSet: make(map[string]bool),
+	}
+	if compose.Name == "" {
+		g.name = defaultKubeProject
+	}
+
+	spec := kubePodSpec{Containers: []kubeContainer{}}
+	replicas := 0
+	for _, name := range g.startOrder() {
+		service := compose.Services[name]
+		spec.Containers = append(spec.Containers, g.container(name, service, &spec))
+		if r, ok := deployReplicas(service.Deploy); ok && r > replicas {
+			replicas = r
+		}
+	}
+	spec.Volumes = g.volumes
+	spec.RestartPolicy = g.restartPolicy()
+
+	var docs []interface{}
+	for _, claim := range g.claims {
+		docs = append(docs, kubePVC{
+			APIVersion: "v1",
+			Kind:       "PersistentVolumeClaim",
+			Metadata:   kubeMeta{Name: claim},
+			Spec: kubePVCSpec{
+				AccessModes: []string{"ReadWriteOnce"},
+				Resources:   kubeResources{Requests: map[string]string{"storage": defaultClaimSize}},
+			},
+		})
+	}
+	for _, cm := range g.configMaps {
+		docs = append(docs, cm)
+	}
+	for _, s := range g.secrets {
+		docs = append(docs, s)
+	}
+
+	labels := map[string]string{"app": g.name}
+	if replicas > 0 {
+		if replicas > 1 {
+			g.warn("deploy.replicas", "podman kube play runs a single replica of a Deployment")
+		}
+		if spec.RestartPolicy != "Always" {
+			g.warn("restart", "Deployments always restart their containers")
+			spec.RestartPolicy = "Always"
+		}
+		docs = append(docs, kubeDeployment{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Metadata:   kubeMeta{Name: g.name, Labels: labels},
+			Spec: kubeDeploymentSpec{
+				Replicas: replicas,
+				Selector: kubeLabelSelector{MatchLabels: labels},
+				Template: kubePodTemplateSpec{Metadata: kubeMeta{Labels: labels}, Spec: spec},
+			},
+		})
+	} else {
+		docs = append(docs, kubePod{
+			APIVersion: "v1",
+			Kind:       "Pod",
+			Metadata:   kubeMeta{Name: g.name, Labels: labels},
+			Spec:       spec,
+		})
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return "", g.warnings, fmt.Errorf("failed to encode Kubernetes YAML: %w", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return "", g.warnings, fmt.Errorf("failed to encode Kubernetes YAML: %w", err)
+	}
+
+	return out.String(), g.warnings, nil
+}
+
+func (g *kubeGenerator) warn(location, format string, args ...interface{}) {
+	g.warnings = append(g.warnings, fmt.Sprintf("%s: %s", location, fmt.Sprintf(format, args...)))
+}
+
+// startOrder returns service names with dependencies first. Podman starts
+// the containers of a pod in the order they are listed.
+func (g *kubeGenerator) startOrder() []string {
+	var order []string
+	state := make(map[string]int) // 1 = visiting, 2 = done
+
+	var visit func(name string)
+	visit = func(name string) {
+		if state[name] != 0 {
+			if state[name] == 1 {
+				g.warn("services."+name+".depends_on", "dependency cycle")
+			}
+			return
+		}
+		state[name] = 1
+		for _, dep := range parseDependsOn(g.compose.Services[name].DependsOn) {
+			if _, ok := g.compose.Services[dep.Service]; ok {
+				visit(dep.Service)
+			}
+		}
+		state[name] = 2
+		order = append(order, name)
+	}
+
+	for _, name := range sortedKeys(g.compose.Services) {
+		visit(name)
+	}
+	return order
+}
+
+// restartPolicy picks the pod restart policy. Kubernetes has one per pod,
+// so the most persistent policy of any service wins.
+func (g *kubeGenerator) restartPolicy() string {
+	policy := "Never"
+	seen := make(map[string]bool)
+	for _, name := range sortedKeys(g.compose.Services) {
+		service := g.compose.Services[name]
+		restart := service.Restart
+		if restart == "" {
+			if deploy, ok := service.Deploy.(map[string]interface{}); ok {
+				if rp, ok := deploy["restart_policy"].(map[string]interface{}); ok {
+					restart, _ = rp["condition"].(string)
+				}
+			}
+		}
+
+		p := "Never"
+		switch {
+		case restart == "always" || restart == "unless-stopped" || restart == "any":
+			p = "Always"
+		case strings.HasPrefix(restart, "on-failure"):
+			p = "OnFailure"
+		}
+		seen[p] = true
+		if p == "Always" || (p == "OnFailure" && policy == "Never") {
+			policy = p
+		}
+	}
+	if len(seen) > 1 {
+		g.warn("restart", "services use different restart policies; the pod uses %s", policy)
+	}
+	return policy
+}
+
+// container maps a compose service onto a pod container. Pod-wide settings
+// (hostname, host network, host aliases, grace period) are merged into spec.
+func (g *kubeGenerator) container(name string, service Service, spec *kubePodSpec) kubeContainer {
+	loc := "services." + name
+	c := kubeContainer{
+		Name:       kubeName(name),
+		Image:      service.Image,
+		WorkingDir: service.WorkingDir,
+		Stdin:      service.StdinOpen,
+		TTY:        service.Tty,
+	}
+	if c.Image == "" {
+		g.warn(loc+".build", "podman kube play needs a prebuilt image; build it and set image")
+	}
+
+	switch ep := service.Entrypoint.(type) {
+	case string:
+		c.Command = splitCommand(ep)
+	case []interface{}:
+		c.Command = toStrings(ep)
+	}
+	switch cmd := service.Command.(type) {
+	case string:
+		c.Args = splitCommand(cmd)
+	case []interface{}:
+		c.Args = toStrings(cmd)
+	}
+
+	envs := parseEnvironment(service.Environment)
+	for _, k := range sortedKeys(envs) {
+		c.Env = append(c.Env, kubeEnvVar{Name: k, Value: envs[k]})
+	}
+	for i, path := range parseEnvFiles(service.EnvFile) {
+		cm := kubeName(fmt.Sprintf("%s-%s-env-%d", g.name, name, i))
+		if content, ok := g.files[path]; ok {
+			g.configMaps = append(g.configMaps, kubeConfigMap{
+				APIVersion: "v1",
+				Kind:       "ConfigMap",
+				Metadata:   kubeMeta{Name: cm},
+				Data:       ParseEnv(content),
+			})
+		} else {
+			g.warn(fmt.Sprintf("%s.env_file[%d]", loc, i), "contents of %s unknown; pass ConfigMap %s with podman kube play --configmap", path, cm)
+		}
+		c.EnvFrom = append(c.EnvFrom, kubeEnvFromSource{ConfigMapRef: &kubeLocalObjectRef{Name: cm}})
+	}
+
+	// Networking
+	switch mode := service.NetworkMode; {
+	case mode == "host":
+		spec.HostNetwork = true
+	case mode == "", mode == "bridge":
+	default:
+		g.warn(loc+".network_mode", "%s is not supported; containers in a pod share its network", mode)
+	}
+	if networks := parseNetworks(service.Networks); len(networks) > 0 {
+		if len(networks) > 1 || networks[0] != defaultNetworkName {
+			g.warn(loc+".networks", "all services share the pod network; use podman kube play --network %s", strings.Join(networks, ","))
+		}
+	}
+	for i, port := range service.Ports {
+		ports, err := parsePort(port)
+		if err != nil {
+			g.warn(fmt.Sprintf("%s.ports[%d]", loc, i), "%v", err)
+			continue
+		}
+		c.Ports = append(c.Ports, ports...)
+	}
+	for i, port := range service.Expose {
+		p, proto, _ := strings.Cut(port, "/")
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			g.warn(fmt.Sprintf("%s.expose[%d]", loc, i), "invalid port %q", port)
+			continue
+		}
+		c.Ports = append(c.Ports, kubeContainerPort{ContainerPort: n, Protocol: kubeProtocol(proto)})
+	}
+
+	if service.Hostname != "" {
+		if spec.Hostname != "" && spec.Hostname != service.Hostname {
+			g.warn(loc+".hostname", "the pod already uses hostname %s", spec.Hostname)
+		} else {
+			spec.Hostname = service.Hostname
+		}
+	}
+	for _, host := range service.ExtraHosts {
+		hostname, ip, ok := strings.Cut(host, ":")
+		if !ok {
+			hostname, ip, ok = strings.Cut(host, "=")
+		}
+		if !ok {
+			g.warn(loc+".extra_hosts", "invalid entry %q", host)
+			continue
+		}
+		spec.HostAliases = addHostAlias(spec.HostAliases, ip, hostname)
+	}
+	if service.StopTimeout != nil {
+		if seconds, ok := durationSeconds(service.StopTimeout); ok {
+			if spec.TerminationGracePeriodSeconds == nil || seconds > *spec.TerminationGracePeriodSeconds {
+				spec.TerminationGracePeriodSeconds = &seconds
+			}
+		}
+	}
+
+	// Storage
+	for i, vol := range service.Volumes {
+		if m, ok := g.volumeMount(fmt.Sprintf("%s.volumes[%d]", loc, i), vol); ok {
+			c.VolumeMounts = append(c.VolumeMounts, m)
+		}
+	}
+	for _, path := range stringOrList(service.Tmpfs) {
+		path, _, _ = strings.Cut(path, ":")
+		c.VolumeMounts = append(c.VolumeMounts, kubeVolumeMount{
+			Name:      g.podVolume("tmpfs:"+name+path, "tmp", kubeVolume{EmptyDir: &kubeEmptyDirSource{Medium: "Memory"}}),
+			MountPath: path,
+		})
+	}
+	c.VolumeMounts = append(c.VolumeMounts, g.secretMounts(loc+".secrets", service.Secrets)...)
+
+	c.LivenessProbe = g.probe(loc+".healthcheck", service.HealthCheck)
+	c.Resources = g.resources(loc+".deploy.resources", service.Deploy)
+	c.SecurityContext = g.securityContext(loc, service)
+
+	if len(parseLabels(service.Labels)) > 0 {
+		g.warn(loc+".labels", "container labels are not supported by Kubernetes YAML")
+	}
+	if service.Configs != nil {
+		g.warn(loc+".configs", "configs are not supported; mount the files with a bind volume")
+	}
+	for _, field := range []struct {
+		set  bool
+		name string
+	}{
+		{len(service.Devices) > 0, "devices"},
+		{service.DNS != nil, "dns"},
+		{service.DNSSearch != nil, "dns_search"},
+		{service.Logging != nil, "logging"},
+		{service.Ulimits != nil, "ulimits"},
+		{service.Sysctls != nil, "sysctls"},
+		{service.PidMode != "", "pid"},
+		{service.IpcMode != "", "ipc"},
+	} {
+		if field.set {
+			g.warn(loc+"."+field.name, "not supported by podman kube play, ignored")
+		}
+	}
+
+	return c
+}
+
+// volumeMount maps a compose volume entry onto a pod volume and mount
+func (g *kubeGenerator) volumeMount(location, spec string) (kubeVolumeMount, bool) {
+	parts := strings.Split(spec, ":")
+	mount := kubeVolumeMount{}
+
+	if len(parts) == 1 {
+		// Anonymous volume
+		mount.MountPath = parts[0]
+		mount.Name = g.podVolume("anon:"+location, "anon", kubeVolume{EmptyDir: &kubeEmptyDirSource{}})
+		return mount, true
+	}
+
+	source := parts[0]
+	mount.MountPath = parts[1]
+	for _, opt := range parts[2:] {
+		for _, o := range strings.Split(opt, ",") {
+			if o == "ro" {
+				mount.ReadOnly = true
+			}
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(source, "/"):
+		mount.Name = g.podVolume("host:"+source, source, kubeVolume{HostPath: &kubeHostPathSource{Path: source}})
+	case strings.HasPrefix(source, ".") || strings.HasPrefix(source, "~"):
+		g.warn(location, "hostPath %s must be absolute for podman kube play", source)
+		mount.Name = g.podVolume("host:"+source, source, kubeVolume{HostPath: &kubeHostPathSource{Path: source}})
+	default:
+		volume, declared := g.compose.Volumes[source]
+		if !declared {
+			g.warn(location, "volume %q is not declared at the top level", source)
+		}
+		claim := kubeName(g.volumeName(source, volume))
+		// podman kube play creates (or reuses) a volume named after the claim
+		if !isExternal(volume.External) && !g.claimSet[claim] {
+			g.claimSet[claim] = true
+			g.claims = append(g.claims, claim)
+		}
+		mount.Name = g.podVolume("pvc:"+claim, source, kubeVolume{PersistentVolumeClaim: &kubePVCSource{ClaimName: claim}})
+	}
+	return mount, true
+}
+
+// volumeName returns the Podman name of a compose volume
+func (g *kubeGenerator) volumeName(key string, volume Volume) string {
+	if isExternal(volume.External) {
+		return externalName(key, volume.External, volume.Name)
+	}
+	if volume.Name != "" {
+		return volume.Name
+	}
+	if g.compose.Name != "" {
+		return g.compose.Name + "_" + key
+	}
+	return key
+}
+
+// podVolume registers a pod volume once per key and returns its name
+func (g *kubeGenerator) podVolume(key, hint string, volume kubeVolume) string {
+	if name, ok := g.volumeIdx[key]; ok {
+		return name
+	}
+
+	base := kubeName(hint)
+	name := base
+	for i := 2; g.volumeNameTaken(name); i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	volume.Name = name
+	g.volumes = append(g.volumes, volume)
+	g.volumeIdx[key] = name
+	return name
+}
+
+func (g *kubeGenerator) volumeNameTaken(name string) bool {
+	for _, v := range g.volumes {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// secretMounts mounts service secrets under /run/secrets like compose does.
+// Secrets whose contents are known are emitted as Secret objects; otherwise
+// podman kube play falls back to a Podman secret of the same name.
+func (g *kubeGenerator) secretMounts(location string, raw interface{}) []kubeVolumeMount {
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var mounts []kubeVolumeMount
+	for _, item := range list {
+		var source, target string
+		switch s := item.(type) {
+		case string:
+			source = s
+		case map[string]interface{}:
+			source, _ = s["source"].(string)
+			target, _ = s["target"].(string)
+		}
+		if source == "" {
+			continue
+		}
+		if target == "" {
+			target = source
+		}
+		if !strings.HasPrefix(target, "/") {
+			target = "/run/secrets/" + target
+		}
+
+		secretName := kubeName(source)
+		if def, ok := g.compose.Secrets[source].(map[string]interface{}); ok {
+			if name, ok := def["name"].(string); ok && name != "" {
+				secretName = kubeName(name)
+			}
+			if !g.secretSet[secretName] {
+				g.secretSet[secretName] = true
+				file, _ := def["file"].(string)
+				if content, ok := g.files[file]; ok && file != "" {
+					g.secrets = append(g.secrets, kubeSecret{
+						APIVersion: "v1",
+						Kind:       "Secret",
+						Metadata:   kubeMeta{Name: secretName},
+						Type:       "Opaque",
+						StringData: map[string]string{source: content},
+					})
+				} else {
+					g.warn(location, "create secret %s with podman secret create before playing the YAML", secretName)
+				}
+			}
+		}
+
+		mounts = append(mounts, kubeVolumeMount{
+			Name:      g.podVolume("secret:"+secretName, "secret-"+secretName, kubeVolume{Secret: &kubeSecretVolumeSource{SecretName: secretName}}),
+			MountPath: target,
+			SubPath:   source,
+			ReadOnly:  true,
+		})
+	}
+	return mounts
+}
+
+// probe maps a compose healthcheck onto an exec liveness probe
+func (g *kubeGenerator) probe(location string, raw interface{}) *kubeProbe {
+	hc, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if disable, _ := hc["disable"].(bool); disable {
+		return nil
+	}
+
+	var command []string
+	switch test := hc["test"].(type) {
+	case string:
+		command = []string{"/bin/sh", "-c", test}
+	case []interface{}:
+		args := toStrings(test)
+		if len(args) == 0 {
+			return nil
+		}
+		switch args[0] {
+		case "NONE":
+			return nil
+		case "CMD-SHELL":
+			command = []string{"/bin/sh", "-c", strings.Join(args[1:], " ")}
+		case "CMD":
+			command = args[1:]
+		default:
+			command = args
+		}
+	}
+	if len(command) == 0 {
+		g.warn(location, "no test command")
+		return nil
+	}
+
+	probe := &kubeProbe{Exec: kubeExecAction{Command: command}}
+	for _, field := range []struct {
+		key string
+		dst *int
+	}{
+		{"interval", &probe.PeriodSeconds},
+		{"timeout", &probe.TimeoutSeconds},
+		{"start_period", &probe.InitialDelaySeconds},
+	} {
+		v, ok := hc[field.key]
+		if !ok {
+			continue
+		}
+		seconds, ok := durationSeconds(v)
+		if !ok {
+			g.warn(location+"."+field.key, "invalid duration %v", v)
+			continue
+		}
+		if seconds < 1 && field.key != "start_period" {
+			seconds = 1
+		}
+		*field.dst = seconds
+	}
+	if retries, ok := hc["retries"].(int); ok {
+		probe.FailureThreshold = retries
+	}
+	return probe
+}
+
+// resources maps deploy.resources limits and reservations
+func (g *kubeGenerator) resources(location string, raw interface{}) *kubeResources {
+	deploy, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	resources, ok := deploy["resources"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	convert := func(section string) map[string]string {
+		values, ok := resources[section].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		out := make(map[string]string)
+		if cpus, ok := values["cpus"]; ok {
+			if q, err := cpuQuantity(cpus); err == nil {
+				out["cpu"] = q
+			} else {
+				g.warn(location+"."+section+".cpus", "%v", err)
+			}
+		}
+		if memory, ok := values["memory"]; ok {
+			if q, err := memoryQuantity(memory); err == nil {
+				out["memory"] = q
+			} else {
+				g.warn(location+"."+section+".memory", "%v", err)
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	}
+
+	r := &kubeResources{Limits: convert("limits"), Requests: convert("reservations")}
+	if r.Limits == nil && r.Requests == nil {
+		return nil
+	}
+	return r
+}
+
+// securityContext maps user, privileges and capabilities
+func (g *kubeGenerator) securityContext(location string, service Service) *kubeSecurityContext {
+	sc := &kubeSecurityContext{}
+	set := false
+
+	if service.Privileged {
+		privileged := true
+		sc.Privileged = &privileged
+		set = true
+	}
+	if service.ReadOnly {
+		readOnly := true
+		sc.ReadOnlyRootFilesystem = &readOnly
+		set = true
+	}
+	if service.User != "" {
+		u, grp, _ := strings.Cut(service.User, ":")
+		if uid, err := strconv.ParseInt(u, 10, 64); err == nil {
+			sc.RunAsUser = &uid
+			set = true
+		} else {
+			g.warn(location+".user", "Kubernetes needs a numeric user, got %q", u)
+		}
+		if grp != "" {
+			if gid, err := strconv.ParseInt(grp, 10, 64); err == nil {
+				sc.RunAsGroup = &gid
+			} else {
+				g.warn(location+".user", "Kubernetes needs a numeric group, got %q", grp)
+			}
+		}
+	}
+	if len(service.CapAdd) > 0 || len(service.CapDrop) > 0 {
+		sc.Capabilities = &kubeCapabilities{Add: service.CapAdd, Drop: service.CapDrop}
+		set = true
+	}
+	for _, opt := range service.SecurityOpt {
+		switch {
+		case strings.HasPrefix(opt, "no-new-privileges"):
+			allow := false
+			sc.AllowPrivilegeEscalation = &allow
+			set = true
+		case strings.HasPrefix(opt, "label=type:") || strings.HasPrefix(opt, "label:type:"):
+			sc.SELinuxOptions = &kubeSELinuxOptions{Type: opt[len("label=type:"):]}
+			set = true
+		case opt == "label=disable" || opt == "label:disable":
+			sc.SELinuxOptions = &kubeSELinuxOptions{Type: "spc_t"}
+			set = true
+		default:
+			g.warn(location+".security_opt", "%s is not supported, ignored", opt)
+		}
+	}
+
+	if !set {
+		return nil
+	}
+	return sc
+}
+
+// deployReplicas returns deploy.replicas if set
+func deployReplicas(raw interface{}) (int, bool) {
+	deploy, ok := raw.(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	replicas, ok := deploy["replicas"].(int)
+	return replicas, ok
+}
+
+var portPattern = regexp.MustCompile(`^(?:(?:\[?([0-9a-fA-F.:]+?)\]?:)?(\d+(?:-\d+)?)?:)?(\d+(?:-\d+)?)(?:/(tcp|udp|sctp))?$`)
+
+// parsePort parses a compose short-syntax port ("[ip:][host:]container[/proto]")
+// into container ports, expanding ranges
+func parsePort(spec string) ([]kubeContainerPort, error) {
+	m := portPattern.FindStringSubmatch(spec)
+	if m == nil {
+		return nil, fmt.Errorf("invalid port %q", spec)
+	}
+	hostIP, hostRange, containerRange, proto := m[1], m[2], m[3], m[4]
+
+	cStart, cEnd, err := parsePortRange(containerRange)
+	if err != nil {
+		return nil, err
+	}
+	hStart, hEnd := 0, 0
+	if hostRange != "" {
+		if hStart, hEnd, err = parsePortRange(hostRange); err != nil {
+			return nil, err
+		}
+		if hEnd-hStart != cEnd-cStart {
+			return nil, fmt.Errorf("host and container port ranges differ in size: %q", spec)
+		}
+	}
+
+	var ports []kubeContainerPort
+	for i := 0; i <= cEnd-cStart; i++ {
+		p := kubeContainerPort{ContainerPort: cStart + i, HostIP: hostIP, Protocol: kubeProtocol(proto)}
+		if hStart != 0 {
+			p.HostPort = hStart + i
+		}
+		ports = append(ports, p)
+	}
+	return ports, nil
+}
+
+func parsePortRange(r string) (int, int, error) {
+	startStr, endStr, isRange := strings.Cut(r, "-")
+	start, err := strconv.Atoi(startStr)
+	if err != nil || start < 1 || start > 65535 {
+		return 0, 0, fmt.Errorf("invalid port %q", r)
+	}
+	if !isRange {
+		return start, start, nil
+	}
+	end, err := strconv.Atoi(endStr)
+	if err != nil || end < start || end > 65535 {
+		return 0, 0, fmt.Errorf("invalid port range %q", r)
+	}
+	return start, end, nil
+}
+
+func kubeProtocol(proto string) string {
+	if proto == "" {
+		return "TCP"
+	}
+	return strings.ToUpper(proto)
+}
+
+// addHostAlias adds hostname to the alias entry of ip, creating it if needed
+func addHostAlias(aliases []kubeHostAlias, ip, hostname string) []kubeHostAlias {
+	for i := range aliases {
+		if aliases[i].IP == ip {
+			for _, h := range aliases[i].Hostnames {
+				if h == hostname {
+					return aliases
+				}
+			}
+			aliases[i].Hostnames = append(aliases[i].Hostnames, hostname)
+			return aliases
+		}
+	}
+	return append(aliases, kubeHostAlias{IP: ip, Hostnames: []string{hostname}})
+}
+
+var memoryPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([bkmgt]?)b?$`)
+
+// memoryQuantity converts a compose byte value ("512m", "1.5g", 1048576)
+// into a Kubernetes quantity
+func memoryQuantity(raw interface{}) (string, error) {
+	m := memoryPattern.FindStringSubmatch(strings.ToLower(fmt.Sprintf("%v", raw)))
+	if m == nil {
+		return "", fmt.Errorf("invalid memory value %v", raw)
+	}
+	value, _ := strconv.ParseFloat(m[1], 64)
+	shift := map[string]uint{"": 0, "b": 0, "k": 10, "m": 20, "g": 30, "t": 40}[m[2]]
+	bytes := int64(value * float64(uint64(1)<<shift))
+
+	for _, unit := range []struct {
+		suffix string
+		shift  uint
+	}{{"Ti", 40}, {"Gi", 30}, {"Mi", 20}, {"Ki", 10}} {
+		size := int64(1) << unit.shift
+		if bytes >= size && bytes%size == 0 {
+			return fmt.Sprintf("%d%s", bytes/size, unit.suffix), nil
+		}
+	}
+	return strconv.FormatInt(bytes, 10), nil
+}
+
+// cpuQuantity converts compose cpus ("0.5", 2) into a Kubernetes quantity
+func cpuQuantity(raw interface{}) (string, error) {
+	cpus, err := strconv.ParseFloat(fmt.Sprintf("%v", raw), 64)
+	if err != nil || cpus <= 0 {
+		return "", fmt.Errorf("invalid cpus value %v", raw)
+	}
+	milli := int64(math.Round(cpus * 1000))
+	if milli%1000 == 0 {
+		return strconv.FormatInt(milli/1000, 10), nil
+	}
+	return fmt.Sprintf("%dm", milli), nil
+}
+
+// splitCommand splits a command string into words the way compose does,
+// honouring single and double quotes
+func splitCommand(s string) []string {
+	var words []string
+	var cur strings.Builder
+	var quote rune
+	inWord := false
+
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words
+}
+
+var invalidKubeNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// kubeName turns a compose name into a valid Kubernetes object name
+// (lowercase RFC 1123 label)
+func kubeName(name string) string {
+	name = invalidKubeNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	name = strings.Trim(name, "-")
+	if len(name) > 63 {
+		name = strings.TrimRight(name[:63], "-")
+	}
+	if name == "" {
+		return "unnamed"
+	}
+	return name
+}
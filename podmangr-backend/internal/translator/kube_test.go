@@ -0,0 +1,309 @@
+package translator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	kubeNamePattern     = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	kubeQuantityPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(m|Ki|Mi|Gi|Ti)?$`)
+)
+
+// validateKubePlay applies the checks podman kube play makes before it
+// creates anything. externalClaims are volumes expected to exist already.
+func validateKubePlay(output string, externalClaims ...string) error {
+	docs := make(map[string]map[string]bool) // kind -> names
+	var workloads []map[string]interface{}
+
+	dec := yaml.NewDecoder(strings.NewReader(output))
+	for {
+		var doc map[string]interface{}
+		if err := dec.Decode(&doc); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("invalid YAML: %w", err)
+		}
+
+		kind, _ := doc["kind"].(string)
+		apiVersion, _ := doc["apiVersion"].(string)
+		switch kind + "/" + apiVersion {
+		case "Pod/v1", "PersistentVolumeClaim/v1", "ConfigMap/v1", "Secret/v1", "Deployment/apps/v1":
+		default:
+			return fmt.Errorf("unsupported kind %s/%s", apiVersion, kind)
+		}
+		name, _ := lookup(doc, "metadata", "name").(string)
+		if !kubeNamePattern.MatchString(name) {
+			return fmt.Errorf("%s has invalid name %q", kind, name)
+		}
+		if docs[kind] == nil {
+			docs[kind] = make(map[string]bool)
+		}
+		if docs[kind][name] {
+			return fmt.Errorf("duplicate %s %s", kind, name)
+		}
+		docs[kind][name] = true
+
+		if kind == "Pod" || kind == "Deployment" {
+			workloads = append(workloads, doc)
+		}
+	}
+	if len(workloads) != 1 {
+		return fmt.Errorf("expected one Pod or Deployment, got %d", len(workloads))
+	}
+	for _, claim := range externalClaims {
+		if docs["PersistentVolumeClaim"] == nil {
+			docs["PersistentVolumeClaim"] = make(map[string]bool)
+		}
+		docs["PersistentVolumeClaim"][claim] = true
+	}
+
+	workload := workloads[0]
+	spec, _ := lookup(workload, "spec").(map[string]interface{})
+	if workload["kind"] == "Deployment" {
+		selector := fmt.Sprint(lookup(spec, "selector", "matchLabels"))
+		if selector != fmt.Sprint(lookup(spec, "template", "metadata", "labels")) {
+			return fmt.Errorf("deployment selector %s does not match template labels", selector)
+		}
+		spec, _ = lookup(spec, "template", "spec").(map[string]interface{})
+	}
+
+	switch spec["restartPolicy"] {
+	case nil, "Always", "OnFailure", "Never":
+	default:
+		return fmt.Errorf("invalid restartPolicy %v", spec["restartPolicy"])
+	}
+
+	volumes := make(map[string]bool)
+	for _, v := range asList(spec["volumes"]) {
+		vol := v.(map[string]interface{})
+		name, _ := vol["name"].(string)
+		if !kubeNamePattern.MatchString(name) || volumes[name] {
+			return fmt.Errorf("invalid or duplicate volume name %q", name)
+		}
+		volumes[name] = true
+		if len(vol) != 2 {
+			return fmt.Errorf("volume %s must have exactly one source", name)
+		}
+		if claim, ok := lookup(vol, "persistentVolumeClaim", "claimName").(string); ok && !docs["PersistentVolumeClaim"][claim] {
+			return fmt.Errorf("volume %s references unknown claim %s", name, claim)
+		}
+		if path, ok := lookup(vol, "hostPath", "path").(string); ok && !filepath.IsAbs(path) {
+			return fmt.Errorf("volume %s has relative hostPath %s", name, path)
+		}
+	}
+
+	containers := asList(spec["containers"])
+	if len(containers) == 0 {
+		return fmt.Errorf("pod has no containers")
+	}
+	names := make(map[string]bool)
+	hostPorts := make(map[string]bool)
+	for _, item := range containers {
+		c := item.(map[string]interface{})
+		name, _ := c["name"].(string)
+		if !kubeNamePattern.MatchString(name) || names[name] {
+			return fmt.Errorf("invalid or duplicate container name %q", name)
+		}
+		names[name] = true
+		if image, _ := c["image"].(string); image == "" {
+			return fmt.Errorf("container %s has no image", name)
+		}
+
+		for _, p := range asList(c["ports"]) {
+			port := p.(map[string]interface{})
+			containerPort, _ := port["containerPort"].(int)
+			if containerPort < 1 || containerPort > 65535 {
+				return fmt.Errorf("container %s has invalid port %v", name, port["containerPort"])
+			}
+			if hostPort, ok := port["hostPort"].(int); ok {
+				key := fmt.Sprintf("%v/%d", port["protocol"], hostPort)
+				if hostPorts[key] {
+					return fmt.Errorf("host port %s is bound twice", key)
+				}
+				hostPorts[key] = true
+			}
+		}
+		for _, m := range asList(c["volumeMounts"]) {
+			mount := m.(map[string]interface{})
+			if !volumes[mount["name"].(string)] {
+				return fmt.Errorf("container %s mounts unknown volume %v", name, mount["name"])
+			}
+			if path, _ := mount["mountPath"].(string); !filepath.IsAbs(path) {
+				return fmt.Errorf("container %s has relative mountPath %v", name, mount["mountPath"])
+			}
+		}
+		for _, e := range asList(c["envFrom"]) {
+			ref, _ := lookup(e.(map[string]interface{}), "configMapRef", "name").(string)
+			if !docs["ConfigMap"][ref] {
+				return fmt.Errorf("container %s references unknown ConfigMap %s", name, ref)
+			}
+		}
+		if probe, ok := c["livenessProbe"]; ok && len(asList(lookup(probe.(map[string]interface{}), "exec", "command"))) == 0 {
+			return fmt.Errorf("container %s has a probe without a command", name)
+		}
+		for _, section := range []string{"limits", "requests"} {
+			values, _ := lookup(c, "resources", section).(map[string]interface{})
+			for resource, q := range values {
+				if !kubeQuantityPattern.MatchString(fmt.Sprint(q)) {
+					return fmt.Errorf("container %s has invalid %s.%s %v", name, section, resource, q)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func lookup(doc interface{}, path ...string) interface{} {
+	for _, key := range path {
+		m, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		doc = m[key]
+	}
+	return doc
+}
+
+func asList(v interface{}) []interface{} {
+	list, _ := v.([]interface{})
+	return list
+}
+
+func TestTranslateKubeFixtures(t *testing.T) {
+	fixtures := []struct {
+		file     string
+		files    map[string]string
+		external []string
+		want     []string
+	}{
+		{
+			file: "wordpress.yml",
+			want: []string{
+				"kind: PersistentVolumeClaim\nmetadata:\n  name: blog-wp-content\n",
+				"kind: PersistentVolumeClaim\nmetadata:\n  name: blog-db\n",
+				"kind: Pod\nmetadata:\n  name: blog\n",
+				"restartPolicy: Always\n",
+				"terminationGracePeriodSeconds: 45\n",
+				"- ip: 10.0.0.9\n      hostnames:\n        - backup.internal\n",
+				// Dependencies start first
+				"containers:\n    - name: db\n",
+				"args:\n        - --max-connections=200\n        - --character-set-server=utf8mb4\n",
+				"livenessProbe:\n        exec:\n          command:\n            - healthcheck.sh\n            - --connect\n        initialDelaySeconds: 60\n        periodSeconds: 30\n        timeoutSeconds: 5\n        failureThreshold: 3\n",
+				"- containerPort: 80\n          hostPort: 8080\n          protocol: TCP\n",
+				"- containerPort: 443\n          hostPort: 8443\n          hostIP: 127.0.0.1\n",
+				"persistentVolumeClaim:\n        claimName: blog-db\n",
+				"hostPath:\n        path: /srv/blog/uploads.ini\n",
+				"readOnly: true\n",
+			},
+		},
+		{
+			file: "replicas.yml",
+			want: []string{
+				"kind: Deployment\nmetadata:\n  name: compose\n",
+				"replicas: 2\n",
+				"command:\n            - /app/api\n          args:\n            - serve\n",
+				"- containerPort: 9001\n              hostPort: 9001\n              protocol: UDP\n",
+				"limits:\n              cpu: 500m\n              memory: 512Mi\n            requests:\n              memory: 1536Mi\n",
+				"command:\n                - /bin/sh\n                - -c\n                - curl -fsS http://localhost:9000/health\n",
+				"runAsUser: 1000\n            runAsGroup: 1000\n            allowPrivilegeEscalation: false\n",
+			},
+		},
+		{
+			file: "secrets.yml",
+			files: map[string]string{
+				"./smtp.env":           "# relay\nRELAYHOST=smtp.example.com\nexport ALLOWED_SENDER_DOMAINS=\"example.com\"\n",
+				"./relay_password.txt": "hunter2\n",
+			},
+			external: []string{"shared"},
+			want: []string{
+				"kind: ConfigMap\nmetadata:\n  name: mail-stack-smtp-env-0\ndata:\n  ALLOWED_SENDER_DOMAINS: example.com\n  RELAYHOST: smtp.example.com\n",
+				"kind: Secret\nmetadata:\n  name: relay-password\ntype: Opaque\nstringData:\n  relay_password: |\n    hunter2\n",
+				"name: mail-stack-queue\n",
+				"hostname: mail.example.com\n",
+				"hostNetwork: true\n",
+				"restartPolicy: OnFailure\n",
+				"mountPath: /etc/opendkim/keys/mail.private\n",
+				"secret:\n        secretName: dkim-mail\n",
+				"emptyDir:\n        medium: Memory\n",
+				"args:\n        - sh\n        - -c\n        - while true; do sleep 3600; done\n",
+			},
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.file, func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join("testdata", "kube", f.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := NewTranslator().TranslateWithOptions(string(input), FormatKube, Options{Files: f.files})
+			if err != nil {
+				t.Fatalf("Translate returned error: %v", err)
+			}
+			if err := validateKubePlay(result.Output, f.external...); err != nil {
+				t.Fatalf("Output rejected: %v\n%s", err, result.Output)
+			}
+			for _, want := range f.want {
+				if !strings.Contains(result.Output, want) {
+					t.Errorf("Output missing %q:\n%s", want, result.Output)
+				}
+			}
+		})
+	}
+}
+
+func TestTranslateKubeWarnings(t *testing.T) {
+	input := `
+services:
+  app:
+    build: .
+    volumes: ["./data:/data"]
+    env_file: app.env
+    networks: [backend]
+networks:
+  backend: {}
+`
+	result, err := NewTranslator().TranslateWithOptions(input, FormatKube, Options{Project: "demo"})
+	if err != nil {
+		t.Fatalf("Translate returned error: %v", err)
+	}
+	if !strings.Contains(result.Output, "name: demo\n") {
+		t.Errorf("Expected the project name to name the pod:\n%s", result.Output)
+	}
+	for _, prefix := range []string{"services.app.build", "services.app.volumes[0]", "services.app.env_file[0]", "services.app.networks"} {
+		found := false
+		for _, w := range result.Warnings {
+			found = found || strings.HasPrefix(w, prefix)
+		}
+		if !found {
+			t.Errorf("Expected a warning for %s, got %v", prefix, result.Warnings)
+		}
+	}
+	// The relative bind mount and unknown ConfigMap are exactly what kube play rejects
+	if err := validateKubePlay(result.Output); err == nil {
+		t.Error("Expected validation to fail")
+	}
+}
+
+func TestKubeQuantities(t *testing.T) {
+	for in, want := range map[interface{}]string{"512m": "512Mi", "1g": "1Gi", "1.5G": "1536Mi", 1048576: "1Mi", "100b": "100", "64k": "64Ki"} {
+		if got, err := memoryQuantity(in); err != nil || got != want {
+			t.Errorf("memoryQuantity(%v) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for in, want := range map[interface{}]string{"0.5": "500m", 2: "2", "1.25": "1250m"} {
+		if got, err := cpuQuantity(in); err != nil || got != want {
+			t.Errorf("cpuQuantity(%v) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,1005 @@
+package translator
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFile is one compose document. When several are loaded, later files
+// override earlier ones (docker-compose.yml, then docker-compose.override.yml).
+type ConfigFile struct {
+	Path    string
+	Content string
+}
+
+// LoadOptions carries the context a compose file is resolved in
+type LoadOptions struct {
+	// Project is used when the files don't set a top-level name
+	Project string
+	// Env holds the variables available for ${VAR} interpolation,
+	// usually parsed from the project's .env file
+	Env map[string]string
+	// Profiles lists the active profiles. When empty, COMPOSE_PROFILES
+	// from Env is used.
+	Profiles []string
+	// ReadFile loads files referenced by include and extends.file. Paths
+	// are relative to the project directory. Nil disables both.
+	ReadFile func(path string) ([]byte, error)
+}
+
+// maxIncludeDepth bounds include and extends chains
+const maxIncludeDepth = 10
+
+// Service keys whose list entries are combined when files are merged;
+// other lists are replaced by the overriding file
+var appendedServiceKeys = map[string]bool{
+	"cap_add": true, "cap_drop": true, "devices": true, "dns": true, "dns_search": true,
+	"env_file": true, "expose": true, "external_links": true, "extra_hosts": true,
+	"group_add": true, "links": true, "ports": true, "profiles": true,
+	"security_opt": true, "tmpfs": true, "volumes_from": true,
+}
+
+// loader resolves compose files into a single project
+type loader struct {
+	opts     LoadOptions
+	warnings []string
+	unset    map[string]bool
+}
+
+// Load parses, interpolates and merges compose files into one project:
+// ${VAR} interpolation from opts.Env, x- extension fields and YAML anchors,
+// include, extends, profiles, long-form ports and volumes, and override files.
+// The result uses the short forms the generators expect.
+func Load(files []ConfigFile, opts LoadOptions) (*ComposeFile, []string, error) {
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("no compose files given")
+	}
+	l := &loader{opts: opts, unset: make(map[string]bool)}
+
+	var project map[string]interface{}
+	for _, f := range files {
+		doc, err := l.loadFile(f.Path, []byte(f.Content), nil)
+		if err != nil {
+			return nil, l.warnings, err
+		}
+		if project == nil {
+			project = doc
+		} else {
+			project = mergeProject(project, doc)
+		}
+	}
+
+	if err := l.applyProfiles(project); err != nil {
+		return nil, l.warnings, err
+	}
+
+	if _, ok := project["services"]; !ok {
+		return nil, l.warnings, fmt.Errorf("compose file has no services")
+	}
+
+	// Round-trip through YAML to get the typed structure
+	data, err := yaml.Marshal(project)
+	if err != nil {
+		return nil, l.warnings, fmt.Errorf("failed to encode compose file: %w", err)
+	}
+	var compose ComposeFile
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		return nil, l.warnings, fmt.Errorf("invalid compose file: %w", err)
+	}
+	if compose.Name == "" {
+		compose.Name = opts.Project
+	}
+
+	return &compose, l.warnings, nil
+}
+
+// ParseEnv parses .env content: KEY=value lines, skipping blanks and
+// comments, with optional export prefixes and quotes
+func ParseEnv(content string) map[string]string {
+	result := make(map[string]string)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		k, v, _ := strings.Cut(line, "=")
+		v = strings.TrimSpace(v)
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		} else if i := strings.Index(v, " #"); i >= 0 {
+			v = strings.TrimSpace(v[:i])
+		}
+		result[strings.TrimSpace(k)] = v
+	}
+	return result
+}
+
+func (l *loader) warn(format string, args ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf(format, args...))
+}
+
+// loadFile parses one file and resolves its extends and include entries.
+// chain holds the files being loaded to detect include cycles.
+func (l *loader) loadFile(file string, data []byte, chain []string) (map[string]interface{}, error) {
+	if len(chain) > maxIncludeDepth {
+		return nil, fmt.Errorf("%s: includes nested too deeply", file)
+	}
+	for _, f := range chain {
+		if f == file {
+			return nil, fmt.Errorf("%s: include cycle", file)
+		}
+	}
+	chain = append(chain, file)
+
+	doc, err := l.parseFile(file, data)
+	if err != nil {
+		return nil, err
+	}
+
+	services, _ := doc["services"].(map[string]interface{})
+	resolved := make(map[string]interface{}, len(services))
+	for _, name := range sortedKeys(services) {
+		svc, err := l.resolveExtends(file, services, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		resolved[name] = svc
+	}
+	if services != nil {
+		doc["services"] = resolved
+	}
+
+	includes, err := l.includes(file, doc["include"])
+	if err != nil {
+		return nil, err
+	}
+	delete(doc, "include")
+	for _, inc := range includes {
+		data, err := l.read(inc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: include %s: %w", file, inc, err)
+		}
+		included, err := l.loadFile(inc, data, chain)
+		if err != nil {
+			return nil, err
+		}
+		if err := mergeInclude(doc, included, inc); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+
+	return doc, nil
+}
+
+// parseFile decodes, interpolates and normalizes a single file
+func (l *loader) parseFile(file string, data []byte) (map[string]interface{}, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	if raw == nil {
+		return map[string]interface{}{}, nil
+	}
+	doc, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: top level must be a mapping", file)
+	}
+
+	// Extension fields only exist to be referenced through anchors, which
+	// the decoder has already resolved. x-podman carries settings of its own.
+	for key := range doc {
+		if isExtension(key) {
+			delete(doc, key)
+		}
+	}
+
+	interpolated, err := l.interpolateValue(doc, file, nil)
+	if err != nil {
+		return nil, err
+	}
+	doc = interpolated.(map[string]interface{})
+
+	if services, ok := doc["services"].(map[string]interface{}); ok {
+		for name, svc := range services {
+			m, ok := svc.(map[string]interface{})
+			if !ok {
+				if svc != nil {
+					return nil, fmt.Errorf("%s: services.%s must be a mapping", file, name)
+				}
+				m = map[string]interface{}{}
+			}
+			normalized, err := l.normalizeService(m, fmt.Sprintf("%s: services.%s", file, name))
+			if err != nil {
+				return nil, err
+			}
+			services[name] = normalized
+		}
+	} else if doc["services"] != nil {
+		return nil, fmt.Errorf("%s: services must be a mapping", file)
+	}
+
+	return doc, nil
+}
+
+// read loads a referenced file through opts.ReadFile
+func (l *loader) read(file string) ([]byte, error) {
+	if l.opts.ReadFile == nil {
+		return nil, fmt.Errorf("referenced files are not available here")
+	}
+	return l.opts.ReadFile(file)
+}
+
+// includes returns the files listed by a top-level include, relative to the project
+func (l *loader) includes(file string, raw interface{}) ([]string, error) {
+	list, ok := raw.([]interface{})
+	if raw != nil && !ok {
+		return nil, fmt.Errorf("%s: include must be a list", file)
+	}
+
+	var paths []string
+	for _, item := range list {
+		switch inc := item.(type) {
+		case string:
+			paths = append(paths, inc)
+		case map[string]interface{}:
+			switch p := inc["path"].(type) {
+			case string:
+				paths = append(paths, p)
+			case []interface{}:
+				// Several files merged into one included project
+				for _, s := range p {
+					paths = append(paths, fmt.Sprintf("%v", s))
+				}
+			}
+			if _, ok := inc["env_file"]; ok {
+				l.warn("%s: include env_file is not supported; the project's .env is used", file)
+			}
+		}
+	}
+
+	for i, p := range paths {
+		paths[i] = relativeTo(file, p)
+	}
+	return paths, nil
+}
+
+// resolveExtends merges a service over the service it extends, following
+// chains across files. chain holds file:service pairs to detect cycles.
+func (l *loader) resolveExtends(file string, services map[string]interface{}, name string, chain []string) (map[string]interface{}, error) {
+	svc, ok := services[name].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: extended service %s not found", file, name)
+	}
+	ext, ok := svc["extends"]
+	if !ok {
+		return svc, nil
+	}
+
+	key := file + ":" + name
+	for _, c := range chain {
+		if c == key {
+			return nil, fmt.Errorf("%s: services.%s: extends cycle", file, name)
+		}
+	}
+	if len(chain) > maxIncludeDepth {
+		return nil, fmt.Errorf("%s: services.%s: extends nested too deeply", file, name)
+	}
+	chain = append(chain, key)
+
+	var baseName, baseFile string
+	switch e := ext.(type) {
+	case string:
+		baseName = e
+	case map[string]interface{}:
+		baseName, _ = e["service"].(string)
+		baseFile, _ = e["file"].(string)
+	}
+	if baseName == "" {
+		return nil, fmt.Errorf("%s: services.%s: extends needs a service", file, name)
+	}
+
+	var base map[string]interface{}
+	var err error
+	if baseFile == "" {
+		base, err = l.resolveExtends(file, services, baseName, chain)
+	} else {
+		other := relativeTo(file, baseFile)
+		data, readErr := l.read(other)
+		if readErr != nil {
+			return nil, fmt.Errorf("%s: services.%s: extends %s: %w", file, name, other, readErr)
+		}
+		doc, parseErr := l.parseFile(other, data)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		otherServices, _ := doc["services"].(map[string]interface{})
+		base, err = l.resolveExtends(other, otherServices, baseName, chain)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	own := make(map[string]interface{}, len(svc))
+	for k, v := range svc {
+		if k != "extends" {
+			own[k] = v
+		}
+	}
+	return mergeService(base, own), nil
+}
+
+// applyProfiles removes services whose profiles are all inactive
+func (l *loader) applyProfiles(project map[string]interface{}) error {
+	active := l.opts.Profiles
+	if len(active) == 0 && l.opts.Env["COMPOSE_PROFILES"] != "" {
+		active = strings.Split(l.opts.Env["COMPOSE_PROFILES"], ",")
+	}
+	enabled := func(profiles []interface{}) bool {
+		if len(profiles) == 0 {
+			return true
+		}
+		for _, p := range profiles {
+			for _, a := range active {
+				if a == "*" || strings.TrimSpace(a) == fmt.Sprintf("%v", p) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	services, _ := project["services"].(map[string]interface{})
+	for name, svc := range services {
+		profiles, _ := svc.(map[string]interface{})["profiles"].([]interface{})
+		if !enabled(profiles) {
+			delete(services, name)
+		}
+	}
+
+	for _, name := range sortedKeys(services) {
+		deps, _ := services[name].(map[string]interface{})["depends_on"].(map[string]interface{})
+		for _, dep := range sortedKeys(deps) {
+			if _, ok := services[dep]; ok {
+				continue
+			}
+			if opts, ok := deps[dep].(map[string]interface{}); ok && opts["required"] == false {
+				delete(deps, dep)
+				continue
+			}
+			return fmt.Errorf("service %s depends on %s, which is undefined or not in an active profile", name, dep)
+		}
+	}
+	return nil
+}
+
+// typedFields are the numeric and boolean fields whose substituted values
+// are typed again, so "${REPLICAS:-2}" is a number. Everything else stays a
+// string, keeping values like "${PIN:-0123}" intact. * matches any key or
+// list item.
+var typedFields = map[string]string{
+	"services.*.deploy.replicas":         "int",
+	"services.*.healthcheck.retries":     "int",
+	"services.*.healthcheck.disable":     "bool",
+	"services.*.privileged":              "bool",
+	"services.*.read_only":               "bool",
+	"services.*.stdin_open":              "bool",
+	"services.*.tty":                     "bool",
+	"services.*.init":                    "bool",
+	"services.*.build.no_cache":          "bool",
+	"services.*.build.pull":              "bool",
+	"services.*.depends_on.*.required":   "bool",
+	"services.*.depends_on.*.restart":    "bool",
+	"services.*.volumes.*.read_only":     "bool",
+	"services.*.volumes.*.volume.nocopy": "bool",
+	"networks.*.external":                "bool",
+	"networks.*.internal":                "bool",
+	"networks.*.enable_ipv6":             "bool",
+	"networks.*.attachable":              "bool",
+	"volumes.*.external":                 "bool",
+}
+
+// fieldType returns the type substituted values at path are converted to,
+// or "" for strings
+func fieldType(path []string) string {
+	for pattern, typ := range typedFields {
+		parts := strings.Split(pattern, ".")
+		if len(parts) != len(path) {
+			continue
+		}
+		matched := true
+		for i, part := range parts {
+			if part != "*" && part != path[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return typ
+		}
+	}
+	return ""
+}
+
+// interpolateValue substitutes variables in every string value (not keys).
+// path locates v in the document.
+func (l *loader) interpolateValue(v interface{}, file string, path []string) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		if !strings.Contains(val, "$") {
+			return val, nil
+		}
+		out, err := l.interpolate(val)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if out != val {
+			switch fieldType(path) {
+			case "int":
+				if n, err := strconv.Atoi(out); err == nil {
+					return n, nil
+				}
+			case "bool":
+				if out == "true" || out == "false" {
+					return out == "true", nil
+				}
+			}
+		}
+		return out, nil
+	case map[string]interface{}:
+		for k, item := range val {
+			if isExtension(k) {
+				delete(val, k)
+				continue
+			}
+			out, err := l.interpolateValue(item, file, append(path[:len(path):len(path)], k))
+			if err != nil {
+				return nil, err
+			}
+			val[k] = out
+		}
+		return val, nil
+	case []interface{}:
+		for i, item := range val {
+			out, err := l.interpolateValue(item, file, append(path[:len(path):len(path)], "*"))
+			if err != nil {
+				return nil, err
+			}
+			val[i] = out
+		}
+		return val, nil
+	}
+	return v, nil
+}
+
+// interpolate expands $VAR, ${VAR} and the ${VAR:-default}, ${VAR-default},
+// ${VAR:?error}, ${VAR?error}, ${VAR:+alt} and ${VAR+alt} forms. $$ is a literal $.
+func (l *loader) interpolate(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '$' {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '$' {
+			b.WriteByte('$')
+			i += 2
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '{' {
+			end := closingBrace(s, i+1)
+			if end < 0 {
+				return "", fmt.Errorf("invalid interpolation format in %q", s)
+			}
+			value, err := l.expand(s[i+2 : end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end + 1
+			continue
+		}
+
+		j := i + 1
+		for j < len(s) && isVarChar(s[j], j == i+1) {
+			j++
+		}
+		if j == i+1 {
+			b.WriteByte('$')
+			i++
+			continue
+		}
+		b.WriteString(l.lookup(s[i+1 : j]))
+		i = j
+	}
+	return b.String(), nil
+}
+
+// expand evaluates the inside of ${...}
+func (l *loader) expand(expr string) (string, error) {
+	n := 0
+	for n < len(expr) && isVarChar(expr[n], n == 0) {
+		n++
+	}
+	name, op := expr[:n], expr[n:]
+	if name == "" {
+		return "", fmt.Errorf("invalid interpolation format ${%s}", expr)
+	}
+	value, set := l.opts.Env[name]
+
+	switch {
+	case op == "":
+		return l.lookup(name), nil
+	case strings.HasPrefix(op, ":-"):
+		if !set || value == "" {
+			return l.interpolate(op[2:])
+		}
+	case strings.HasPrefix(op, "-"):
+		if !set {
+			return l.interpolate(op[1:])
+		}
+	case strings.HasPrefix(op, ":?"), strings.HasPrefix(op, "?"):
+		colon := op[0] == ':'
+		if !set || (colon && value == "") {
+			msg, err := l.interpolate(strings.TrimLeft(op, ":?"))
+			if err != nil {
+				return "", err
+			}
+			if msg == "" {
+				msg = "is missing a value"
+			}
+			return "", fmt.Errorf("required variable %s %s", name, msg)
+		}
+	case strings.HasPrefix(op, ":+"):
+		if set && value != "" {
+			return l.interpolate(op[2:])
+		}
+		return "", nil
+	case strings.HasPrefix(op, "+"):
+		if set {
+			return l.interpolate(op[1:])
+		}
+		return "", nil
+	default:
+		return "", fmt.Errorf("invalid interpolation format ${%s}", expr)
+	}
+	return value, nil
+}
+
+// lookup returns a variable, warning once about unset ones
+func (l *loader) lookup(name string) string {
+	value, ok := l.opts.Env[name]
+	if !ok && !l.unset[name] {
+		l.unset[name] = true
+		l.warn("variable %s is not set, defaulting to a blank string", name)
+	}
+	return value
+}
+
+// closingBrace returns the index of the } matching the { at open
+func closingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// isExtension reports whether a key is an x- extension field to drop
+func isExtension(key string) bool {
+	return strings.HasPrefix(key, "x-") && key != "x-podman"
+}
+
+func isVarChar(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+// normalizeService converts the alternative syntaxes of a service into the
+// forms the merge and the generators use: maps for environment, labels,
+// depends_on and networks; short strings for ports, expose and volumes
+func (l *loader) normalizeService(svc map[string]interface{}, location string) (map[string]interface{}, error) {
+	for key := range svc {
+		if strings.HasPrefix(key, "x-") {
+			delete(svc, key)
+		}
+	}
+
+	if env, ok := svc["environment"].([]interface{}); ok {
+		m := make(map[string]interface{}, len(env))
+		for _, item := range env {
+			k, v, hasValue := strings.Cut(fmt.Sprintf("%v", item), "=")
+			if hasValue {
+				m[k] = v
+			} else if value, ok := l.opts.Env[k]; ok {
+				// A bare name passes the variable through from the environment
+				m[k] = value
+			}
+		}
+		svc["environment"] = m
+	} else if env, ok := svc["environment"].(map[string]interface{}); ok {
+		for k, v := range env {
+			if v == nil {
+				if value, ok := l.opts.Env[k]; ok {
+					env[k] = value
+				} else {
+					delete(env, k)
+				}
+			}
+		}
+	}
+
+	for _, key := range []string{"labels", "sysctls"} {
+		if list, ok := svc[key].([]interface{}); ok {
+			m := make(map[string]interface{}, len(list))
+			for _, item := range list {
+				k, v, _ := strings.Cut(fmt.Sprintf("%v", item), "=")
+				m[k] = v
+			}
+			svc[key] = m
+		}
+	}
+
+	if deps, ok := svc["depends_on"].([]interface{}); ok {
+		m := make(map[string]interface{}, len(deps))
+		for _, d := range deps {
+			m[fmt.Sprintf("%v", d)] = map[string]interface{}{"condition": "service_started"}
+		}
+		svc["depends_on"] = m
+	}
+
+	if networks, ok := svc["networks"].([]interface{}); ok {
+		m := make(map[string]interface{}, len(networks))
+		for _, n := range networks {
+			m[fmt.Sprintf("%v", n)] = nil
+		}
+		svc["networks"] = m
+	}
+
+	if hosts, ok := svc["extra_hosts"].(map[string]interface{}); ok {
+		list := make([]interface{}, 0, len(hosts))
+		for _, host := range sortedKeys(hosts) {
+			list = append(list, fmt.Sprintf("%s:%v", host, hosts[host]))
+		}
+		svc["extra_hosts"] = list
+	}
+
+	for _, key := range []string{"dns", "dns_search", "tmpfs", "env_file"} {
+		if s, ok := svc[key].(string); ok {
+			svc[key] = []interface{}{s}
+		}
+	}
+
+	if expose, ok := svc["expose"].([]interface{}); ok {
+		for i, e := range expose {
+			expose[i] = fmt.Sprintf("%v", e)
+		}
+	}
+
+	if ports, ok := svc["ports"].([]interface{}); ok {
+		for i, p := range ports {
+			switch port := p.(type) {
+			case map[string]interface{}:
+				short, err := shortPort(port)
+				if err != nil {
+					return nil, fmt.Errorf("%s.ports[%d]: %w", location, i, err)
+				}
+				ports[i] = short
+			default:
+				ports[i] = fmt.Sprintf("%v", port)
+			}
+		}
+	}
+
+	if volumes, ok := svc["volumes"].([]interface{}); ok {
+		kept := make([]interface{}, 0, len(volumes))
+		for i, v := range volumes {
+			vol, ok := v.(map[string]interface{})
+			if !ok {
+				kept = append(kept, fmt.Sprintf("%v", v))
+				continue
+			}
+			if vol["type"] == "tmpfs" {
+				tmpfs, _ := svc["tmpfs"].([]interface{})
+				svc["tmpfs"] = append(tmpfs, shortTmpfs(vol))
+				continue
+			}
+			short, err := shortVolume(vol)
+			if err != nil {
+				return nil, fmt.Errorf("%s.volumes[%d]: %w", location, i, err)
+			}
+			kept = append(kept, short)
+		}
+		svc["volumes"] = kept
+	}
+
+	return svc, nil
+}
+
+// shortPort converts a long-form port mapping to [host_ip:][published:]target[/protocol]
+func shortPort(port map[string]interface{}) (string, error) {
+	target, ok := port["target"]
+	if !ok {
+		return "", fmt.Errorf("target is required")
+	}
+	spec := fmt.Sprintf("%v", target)
+	published := ""
+	if p, ok := port["published"]; ok && p != nil {
+		published = fmt.Sprintf("%v", p)
+	}
+	hostIP, _ := port["host_ip"].(string)
+
+	switch {
+	case hostIP != "":
+		spec = fmt.Sprintf("%s:%s:%s", hostIP, published, spec)
+	case published != "":
+		spec = published + ":" + spec
+	}
+	if proto, ok := port["protocol"].(string); ok && proto != "" && proto != "tcp" {
+		spec += "/" + proto
+	}
+	return spec, nil
+}
+
+// shortVolume converts a long-form volume to source:target[:options]
+func shortVolume(vol map[string]interface{}) (string, error) {
+	target, _ := vol["target"].(string)
+	if target == "" {
+		return "", fmt.Errorf("target is required")
+	}
+	source, _ := vol["source"].(string)
+	volType, _ := vol["type"].(string)
+	switch volType {
+	case "", "volume", "bind":
+	default:
+		return "", fmt.Errorf("volume type %s is not supported", volType)
+	}
+	if source == "" {
+		if volType == "bind" {
+			return "", fmt.Errorf("bind mounts need a source")
+		}
+		return target, nil
+	}
+
+	var opts []string
+	if readOnly, _ := vol["read_only"].(bool); readOnly {
+		opts = append(opts, "ro")
+	}
+	if bind, ok := vol["bind"].(map[string]interface{}); ok {
+		if selinux, ok := bind["selinux"].(string); ok && selinux != "" {
+			opts = append(opts, selinux)
+		}
+		if propagation, ok := bind["propagation"].(string); ok && propagation != "" {
+			opts = append(opts, propagation)
+		}
+	}
+	if v, ok := vol["volume"].(map[string]interface{}); ok {
+		if nocopy, _ := v["nocopy"].(bool); nocopy {
+			opts = append(opts, "nocopy")
+		}
+	}
+
+	spec := source + ":" + target
+	if len(opts) > 0 {
+		spec += ":" + strings.Join(opts, ",")
+	}
+	return spec, nil
+}
+
+// shortTmpfs converts a long-form tmpfs volume to path[:size=N]
+func shortTmpfs(vol map[string]interface{}) string {
+	spec, _ := vol["target"].(string)
+	if t, ok := vol["tmpfs"].(map[string]interface{}); ok {
+		if size, ok := t["size"]; ok {
+			spec += fmt.Sprintf(":size=%v", size)
+		}
+	}
+	return spec
+}
+
+// mergeProject merges an override file over a base project
+func mergeProject(base, override map[string]interface{}) map[string]interface{} {
+	result := deepCopyMap(base)
+	for key, value := range override {
+		if key != "services" {
+			result[key] = mergeValue(result[key], value)
+			continue
+		}
+		services, _ := result["services"].(map[string]interface{})
+		if services == nil {
+			services = make(map[string]interface{})
+		}
+		overrides, _ := value.(map[string]interface{})
+		for name, svc := range overrides {
+			o, _ := svc.(map[string]interface{})
+			if b, ok := services[name].(map[string]interface{}); ok {
+				services[name] = mergeService(b, o)
+			} else {
+				services[name] = deepCopy(o)
+			}
+		}
+		result["services"] = services
+	}
+	return result
+}
+
+// mergeInclude adds an included project to doc. Included resources must not
+// redefine ones the including file already has.
+func mergeInclude(doc, included map[string]interface{}, file string) error {
+	for _, section := range []string{"services", "volumes", "networks", "secrets", "configs"} {
+		inc, _ := included[section].(map[string]interface{})
+		if len(inc) == 0 {
+			continue
+		}
+		own, _ := doc[section].(map[string]interface{})
+		if own == nil {
+			own = make(map[string]interface{})
+			doc[section] = own
+		}
+		for _, name := range sortedKeys(inc) {
+			if existing, ok := own[name]; ok {
+				if section == "services" || !yamlEqual(existing, inc[name]) {
+					return fmt.Errorf("%s.%s from %s conflicts with an existing definition", section, name, file)
+				}
+				continue
+			}
+			own[name] = inc[name]
+		}
+	}
+	return nil
+}
+
+// mergeService merges two service definitions following the compose rules
+func mergeService(base, override map[string]interface{}) map[string]interface{} {
+	result := deepCopyMap(base)
+	for key, value := range override {
+		switch {
+		case appendedServiceKeys[key]:
+			result[key] = appendUnique(result[key], value)
+		case key == "volumes":
+			result[key] = mergeByKey(result[key], value, volumeTarget)
+		case key == "secrets" || key == "configs":
+			result[key] = mergeByKey(result[key], value, secretSource)
+		default:
+			result[key] = mergeValue(result[key], value)
+		}
+	}
+	return result
+}
+
+// mergeValue merges mappings recursively; anything else is replaced
+func mergeValue(base, override interface{}) interface{} {
+	b, okBase := base.(map[string]interface{})
+	o, okOverride := override.(map[string]interface{})
+	if !okBase || !okOverride {
+		return deepCopy(override)
+	}
+	result := deepCopyMap(b)
+	for k, v := range o {
+		result[k] = mergeValue(result[k], v)
+	}
+	return result
+}
+
+// appendUnique appends the override list to the base list, skipping duplicates
+func appendUnique(base, override interface{}) interface{} {
+	b, _ := base.([]interface{})
+	o, ok := override.([]interface{})
+	if !ok {
+		return deepCopy(override)
+	}
+	result := append([]interface{}{}, b...)
+	for _, item := range o {
+		dup := false
+		for _, existing := range result {
+			if yamlEqual(existing, item) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// mergeByKey merges two lists, replacing base entries that share a key
+func mergeByKey(base, override interface{}, key func(interface{}) string) interface{} {
+	b, _ := base.([]interface{})
+	o, ok := override.([]interface{})
+	if !ok {
+		return deepCopy(override)
+	}
+	result := append([]interface{}{}, b...)
+	for _, item := range o {
+		replaced := false
+		for i, existing := range result {
+			if key(existing) == key(item) {
+				result[i] = item
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// volumeTarget returns the mount path of a short-form volume
+func volumeTarget(v interface{}) string {
+	parts := strings.Split(fmt.Sprintf("%v", v), ":")
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return parts[1]
+}
+
+// secretSource returns the source of a short or long-form secret or config
+func secretSource(v interface{}) string {
+	if m, ok := v.(map[string]interface{}); ok {
+		return fmt.Sprintf("%v", m["source"])
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// relativeTo resolves a referenced path against the directory of the referencing file
+func relativeTo(file, ref string) string {
+	if path.IsAbs(ref) {
+		return path.Clean(ref)
+	}
+	return path.Clean(path.Join(path.Dir(file), ref))
+}
+
+func yamlEqual(a, b interface{}) bool {
+	ya, errA := yaml.Marshal(a)
+	yb, errB := yaml.Marshal(b)
+	return errA == nil && errB == nil && string(ya) == string(yb)
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return deepCopyMap(val)
+	case []interface{}:
+		result := make([]interface{}, len(val))
+		for i, item := range val {
+			result[i] = deepCopy(item)
+		}
+		return result
+	}
+	return v
+}
+
+func deepCopyMap(m map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		result[k] = deepCopy(v)
+	}
+	return result
+}
+
+// ServiceNames returns the services of a loaded project in sorted order
+func (c *ComposeFile) ServiceNames() []string {
+	names := make([]string, 0, len(c.Services))
+	for name := range c.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,203 @@
+package translator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadInterpolation(t *testing.T) {
+	input := `
+x-common: &common
+  restart: unless-stopped
+  labels:
+    app: shop
+    pin: ${PIN}
+    enabled: ${ENABLED}
+services:
+  web:
+    <<: *common
+    image: "nginx:${NGINX_TAG:-1.27}"
+    command: echo $$HOME ${GREETING}
+    environment:
+      - DB_HOST=${DB_HOST:-db}
+      - PASSED
+      - ALT=${DEBUG:+verbose}
+    read_only: ${ENABLED}
+    deploy:
+      replicas: ${REPLICAS}
+    x-notes: ignored
+`
+	compose, warnings, err := Load([]ConfigFile{{Path: "docker-compose.yml", Content: input}}, LoadOptions{
+		Project: "shop",
+		Env:     map[string]string{"PASSED": "yes", "REPLICAS": "3", "DEBUG": "1", "PIN": "0123", "ENABLED": "true"},
+	})
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	web := compose.Services["web"]
+	if web.Image != "nginx:1.27" || web.Restart != "unless-stopped" || compose.Name != "shop" {
+		t.Errorf("Unexpected service: image=%q restart=%q name=%q", web.Image, web.Restart, compose.Name)
+	}
+	if web.Command != "echo $HOME " {
+		t.Errorf("Unexpected command %q", web.Command)
+	}
+	env := parseEnvironment(web.Environment)
+	want := map[string]string{"DB_HOST": "db", "PASSED": "yes", "ALT": "verbose"}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("Environment = %v, want %v", env, want)
+	}
+	if replicas, ok := deployReplicas(web.Deploy); !ok || replicas != 3 {
+		t.Errorf("Expected 3 replicas, got %v", web.Deploy)
+	}
+	if !web.ReadOnly {
+		t.Error("Expected read_only to be typed as a boolean")
+	}
+	// Only typed fields are converted, other values keep their spelling
+	labels, _ := web.Labels.(map[string]interface{})
+	if labels["pin"] != "0123" || labels["enabled"] != "true" {
+		t.Errorf("Expected labels to stay strings, got %#v", web.Labels)
+	}
+	if parseLabels(web.Labels)["app"] != "shop" {
+		t.Errorf("Expected labels from the anchor, got %v", web.Labels)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "GREETING") {
+		t.Errorf("Expected one warning about GREETING, got %v", warnings)
+	}
+
+	_, _, err = Load([]ConfigFile{{Path: "docker-compose.yml", Content: `
+services:
+  db:
+    image: postgres
+    environment:
+      POSTGRES_PASSWORD: ${DB_PASSWORD:?set it in .env}
+`}}, LoadOptions{})
+	if err == nil || !strings.Contains(err.Error(), "required variable DB_PASSWORD set it in .env") {
+		t.Errorf("Expected a required variable error, got %v", err)
+	}
+}
+
+func TestLoadMergeExtendsInclude(t *testing.T) {
+	files := map[string]string{
+		"common.yml": `
+services:
+  base:
+    image: app:1
+    environment:
+      LOG_LEVEL: info
+    volumes:
+      - data:/data
+`,
+		"monitoring/compose.yml": `
+services:
+  exporter:
+    image: exporter:1
+    profiles: [metrics]
+`,
+	}
+	main := `
+include:
+  - monitoring/compose.yml
+services:
+  api:
+    extends:
+      file: common.yml
+      service: base
+    ports:
+      - target: 8080
+        published: "80"
+        host_ip: 127.0.0.1
+      - "9000:9000/udp"
+    volumes:
+      - type: bind
+        source: ./config
+        target: /etc/app
+        read_only: true
+        bind:
+          selinux: z
+      - type: tmpfs
+        target: /tmp
+        tmpfs:
+          size: 1000000
+  worker:
+    extends: api
+    command: ["work"]
+    depends_on: [api]
+  debug:
+    image: busybox
+    profiles: [debug]
+volumes:
+  data: {}
+`
+	override := `
+services:
+  api:
+    environment:
+      LOG_LEVEL: debug
+    ports: ["9000:9000/udp", "9443:443"]
+    volumes:
+      - other:/data
+`
+	compose, _, err := Load([]ConfigFile{
+		{Path: "docker-compose.yml", Content: main},
+		{Path: "docker-compose.override.yml", Content: override},
+	}, LoadOptions{
+		Env: map[string]string{"COMPOSE_PROFILES": "metrics"},
+		ReadFile: func(path string) ([]byte, error) {
+			if content, ok := files[path]; ok {
+				return []byte(content), nil
+			}
+			return nil, fmt.Errorf("%s not found", path)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	if got := strings.Join(compose.ServiceNames(), ","); got != "api,exporter,worker" {
+		t.Errorf("Unexpected services %s", got)
+	}
+	api := compose.Services["api"]
+	if api.Image != "app:1" || parseEnvironment(api.Environment)["LOG_LEVEL"] != "debug" {
+		t.Errorf("Expected extends and override to apply, got image=%q env=%v", api.Image, api.Environment)
+	}
+	if want := []string{"127.0.0.1:80:8080", "9000:9000/udp", "9443:443"}; !reflect.DeepEqual(api.Ports, want) {
+		t.Errorf("Ports = %v, want %v", api.Ports, want)
+	}
+	// The override replaces the volume mounted at /data
+	if want := []string{"other:/data", "./config:/etc/app:ro,z"}; !reflect.DeepEqual(api.Volumes, want) {
+		t.Errorf("Volumes = %v, want %v", api.Volumes, want)
+	}
+	if fmt.Sprint(api.Tmpfs) != "[/tmp:size=1000000]" {
+		t.Errorf("Unexpected tmpfs %v", api.Tmpfs)
+	}
+	worker := compose.Services["worker"]
+	if worker.Image != "app:1" || fmt.Sprint(worker.Command) != "[work]" {
+		t.Errorf("Expected worker to extend api, got %+v", worker)
+	}
+
+	// A dependency on a service in an inactive profile is an error
+	_, _, err = Load([]ConfigFile{{Path: "docker-compose.yml", Content: `
+services:
+  app:
+    image: app
+    depends_on: [db]
+  db:
+    image: postgres
+    profiles: [db]
+`}}, LoadOptions{})
+	if err == nil || !strings.Contains(err.Error(), "depends on db") {
+		t.Errorf("Expected an error for a dependency in an inactive profile, got %v", err)
+	}
+
+	_, _, err = Load([]ConfigFile{{Path: "docker-compose.yml", Content: `
+services:
+  a: {extends: b, image: x}
+  b: {extends: a}
+`}}, LoadOptions{})
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Expected an extends cycle error, got %v", err)
+	}
+}
@@ -0,0 +1,680 @@
+package translator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Labels compose and podman-compose put on the objects of a project. The
+// stack engine sets the same ones so existing tooling keeps working.
+const (
+	LabelProject         = "com.docker.compose.project"
+	LabelService         = "com.docker.compose.service"
+	LabelContainerNumber = "com.docker.compose.container-number"
+	LabelConfigHash      = "com.docker.compose.config-hash"
+	LabelNetwork         = "com.docker.compose.network"
+	LabelVolume          = "com.docker.compose.volume"
+	LabelPodmanProject   = "io.podman.compose.project"
+)
+
+// Plan lists the Podman objects a compose project is deployed as, with the
+// podman arguments that create them
+type Plan struct {
+	Project  string
+	Pod      *PlannedPod // Set when x-podman.in_pod is enabled
+	Networks []PlannedResource
+	Volumes  []PlannedResource
+	Services []PlannedService // Dependencies first
+}
+
+// PlannedPod is the pod all services join when the project runs in a pod
+type PlannedPod struct {
+	Name string
+	Args []string // podman pod create arguments
+}
+
+// PlannedResource is a network or volume of the project
+type PlannedResource struct {
+	Key      string   // Key in the compose file
+	Name     string   // Podman name
+	External bool     // Must exist already; Args is empty
+	Args     []string // podman network/volume create arguments
+}
+
+// PlannedService is a service and the containers that run it
+type PlannedService struct {
+	Name           string
+	Image          string
+	Build          *PlannedBuild // Set when the image is built from a build section
+	DependsOn      []Dependency
+	HasHealthcheck bool
+	Containers     []PlannedContainer
+}
+
+// PlannedContainer is one replica of a service
+type PlannedContainer struct {
+	Name string
+	Args []string // podman create arguments
+	// Hash identifies the configuration; an existing container with the
+	// same hash is kept instead of being recreated
+	Hash string
+}
+
+// planner builds a Plan from a loaded compose file
+type planner struct {
+	compose  *ComposeFile
+	dir      string
+	pod      string
+	warnings []string
+}
+
+// BuildPlan works out the networks, volumes, pod and containers that deploy
+// a loaded compose file. Relative paths are resolved against dir, the
+// project directory. Settings Podman can't apply are reported as warnings.
+func BuildPlan(compose *ComposeFile, dir string) (*Plan, []string, error) {
+	if compose.Name == "" {
+		return nil, nil, fmt.Errorf("the project has no name")
+	}
+	p := &planner{compose: compose, dir: dir}
+	plan := &Plan{Project: compose.Name}
+
+	order, err := dependencyOrder(compose)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if compose.Podman != nil && compose.Podman.InPod {
+		p.pod = "pod_" + compose.Name
+		plan.Pod = &PlannedPod{Name: p.pod, Args: p.podArgs(order)}
+	}
+
+	usesDefault := false
+	for _, name := range order {
+		service, err := p.service(name, compose.Services[name])
+		if err != nil {
+			return nil, p.warnings, err
+		}
+		plan.Services = append(plan.Services, *service)
+
+		svc := compose.Services[name]
+		if svc.NetworkMode == "" && len(parseServiceNetworks(svc.Networks)) == 0 {
+			usesDefault = true
+		}
+	}
+
+	networkNames := sortedKeys(compose.Networks)
+	if _, declared := compose.Networks[defaultNetworkName]; usesDefault && !declared {
+		networkNames = append(networkNames, defaultNetworkName)
+	}
+	for _, key := range networkNames {
+		plan.Networks = append(plan.Networks, p.network(key, compose.Networks[key]))
+	}
+	for _, key := range sortedKeys(compose.Volumes) {
+		plan.Volumes = append(plan.Volumes, p.volume(key, compose.Volumes[key]))
+	}
+
+	return plan, p.warnings, nil
+}
+
+func (p *planner) warn(location, format string, args ...interface{}) {
+	p.warnings = append(p.warnings, fmt.Sprintf("%s: %s", location, fmt.Sprintf(format, args...)))
+}
+
+// resourceName returns the Podman name of a volume or network key
+func (p *planner) resourceName(key, explicit string, external interface{}) string {
+	if isExternal(external) {
+		return externalName(key, external, explicit)
+	}
+	if explicit != "" {
+		return explicit
+	}
+	return p.compose.Name + "_" + key
+}
+
+// projectLabels are the labels every object of the project carries
+func (p *planner) projectLabels(extra ...string) []string {
+	args := []string{
+		"--label", LabelProject + "=" + p.compose.Name,
+		"--label", LabelPodmanProject + "=" + p.compose.Name,
+	}
+	for _, label := range extra {
+		args = append(args, "--label", label)
+	}
+	return args
+}
+
+// network plans a top-level network
+func (p *planner) network(key string, network Network) PlannedResource {
+	res := PlannedResource{Key: key, Name: p.resourceName(key, network.Name, network.External)}
+	if isExternal(network.External) {
+		res.External = true
+		return res
+	}
+
+	args := []string{"network", "create"}
+	args = append(args, p.projectLabels(LabelNetwork+"="+key)...)
+	if network.Driver != "" {
+		args = append(args, "--driver", network.Driver)
+	}
+	if network.Internal {
+		args = append(args, "--internal")
+	}
+	if network.EnableIPv6 {
+		args = append(args, "--ipv6")
+	}
+	if ipam, ok := network.Ipam.(map[string]interface{}); ok {
+		if configs, ok := ipam["config"].([]interface{}); ok {
+			for _, c := range configs {
+				cfg, _ := c.(map[string]interface{})
+				for _, field := range []string{"subnet", "gateway", "ip_range"} {
+					if v, ok := cfg[field].(string); ok {
+						args = append(args, "--"+strings.ReplaceAll(field, "_", "-"), v)
+					}
+				}
+			}
+		}
+	}
+	for _, k := range sortedKeys(network.DriverOpts) {
+		args = append(args, "--opt", k+"="+network.DriverOpts[k])
+	}
+	labels := parseLabels(network.Labels)
+	for _, k := range sortedKeys(labels) {
+		args = append(args, "--label", k+"="+labels[k])
+	}
+	res.Args = append(args, res.Name)
+	return res
+}
+
+// volume plans a top-level volume
+func (p *planner) volume(key string, volume Volume) PlannedResource {
+	res := PlannedResource{Key: key, Name: p.resourceName(key, volume.Name, volume.External)}
+	if isExternal(volume.External) {
+		res.External = true
+		return res
+	}
+
+	args := []string{"volume", "create"}
+	args = append(args, p.projectLabels(LabelVolume+"="+key)...)
+	if volume.Driver != "" {
+		args = append(args, "--driver", volume.Driver)
+	}
+	for _, k := range sortedKeys(volume.DriverOpts) {
+		args = append(args, "--opt", k+"="+volume.DriverOpts[k])
+	}
+	labels := parseLabels(volume.Labels)
+	for _, k := range sortedKeys(labels) {
+		args = append(args, "--label", k+"="+labels[k])
+	}
+	res.Args = append(args, res.Name)
+	return res
+}
+
+// podArgs creates the project pod. Ports and networks belong to the pod
+// rather than its containers.
+func (p *planner) podArgs(order []string) []string {
+	args := []string{"pod", "create", "--name", p.pod}
+	args = append(args, p.projectLabels()...)
+
+	networks := make(map[string]bool)
+	for _, name := range order {
+		svc := p.compose.Services[name]
+		for _, port := range svc.Ports {
+			args = append(args, "--publish", port)
+		}
+		for _, n := range parseServiceNetworks(svc.Networks) {
+			networks[n.name] = true
+		}
+	}
+	if len(networks) == 0 {
+		networks[defaultNetworkName] = true
+	}
+	for _, key := range sortedKeys(networks) {
+		network := p.compose.Networks[key]
+		args = append(args, "--network", p.resourceName(key, network.Name, network.External))
+	}
+	return args
+}
+
+// containerName returns the name of a service replica, podman-compose style
+func (p *planner) containerName(service string, number int) string {
+	if s := p.compose.Services[service]; s.ContainerName != "" {
+		return s.ContainerName
+	}
+	return fmt.Sprintf("%s_%s_%d", p.compose.Name, service, number)
+}
+
+// service plans the containers of a service
+func (p *planner) service(name string, service Service) (*PlannedService, error) {
+	loc := "services." + name
+	var build *PlannedBuild
+	if service.Build != nil {
+		var err error
+		if build, err = p.build(loc+".build", service.Build); err != nil {
+			return nil, err
+		}
+		service.Image = builtImageName(p.compose.Name, name, service.Image)
+	} else if service.Image == "" {
+		return nil, fmt.Errorf("%s: either image or build is required", loc)
+	}
+
+	replicas := 1
+	if n, ok := deployReplicas(service.Deploy); ok {
+		replicas = n
+	}
+	if replicas > 1 && service.ContainerName != "" {
+		return nil, fmt.Errorf("%s: container_name can't be used with more than one replica", loc)
+	}
+
+	options, err := p.createArgs(name, service)
+	if err != nil {
+		return nil, err
+	}
+	command := commandArgs(service.Command)
+
+	planned := &PlannedService{
+		Name:           name,
+		Image:          service.Image,
+		Build:          build,
+		DependsOn:      parseDependsOn(service.DependsOn),
+		HasHealthcheck: hasHealthcheck(service.HealthCheck),
+	}
+	for i := 1; i <= replicas; i++ {
+		planned.Containers = append(planned.Containers, p.container(name, i, options, service.Image, command))
+	}
+	return planned, nil
+}
+
+// container builds the podman create arguments of one replica. The hash
+// covers everything but itself and is stored as a label.
+func (p *planner) container(service string, number int, options []string, image string, command []string) PlannedContainer {
+	name := p.containerName(service, number)
+	args := append([]string{"create", "--name", name}, p.projectLabels(
+		LabelService+"="+service,
+		LabelContainerNumber+"="+strconv.Itoa(number),
+	)...)
+	args = append(args, options...)
+	tail := append([]string{image}, command...)
+
+	sum := sha256.Sum256([]byte(strings.Join(append(append([]string{}, args...), tail...), "\x00")))
+	hash := hex.EncodeToString(sum[:])
+
+	args = append(args, "--label", LabelConfigHash+"="+hash)
+	args = append(args, tail...)
+	return PlannedContainer{Name: name, Args: args, Hash: hash}
+}
+
+// createArgs maps a service onto podman create options
+func (p *planner) createArgs(name string, service Service) ([]string, error) {
+	loc := "services." + name
+	var args []string
+	add := func(flag string, values ...string) {
+		for _, v := range values {
+			if v != "" {
+				args = append(args, flag, v)
+			}
+		}
+	}
+	flag := func(set bool, flag string) {
+		if set {
+			args = append(args, flag)
+		}
+	}
+
+	add("--hostname", service.Hostname)
+
+	// Networking
+	if p.pod != "" {
+		add("--pod", p.pod)
+		if service.NetworkMode != "" || service.Networks != nil {
+			p.warn(loc, "networks are set on the pod when the project runs in a pod")
+		}
+	} else {
+		switch mode := service.NetworkMode; {
+		case mode == "":
+			networks := parseServiceNetworks(service.Networks)
+			if len(networks) == 0 {
+				networks = []serviceNetwork{{name: defaultNetworkName}}
+			}
+			for _, n := range networks {
+				network, declared := p.compose.Networks[n.name]
+				if !declared && n.name != defaultNetworkName {
+					return nil, fmt.Errorf("%s.networks: network %q is not declared at the top level", loc, n.name)
+				}
+				// The service name resolves on every network, like compose
+				opts := []string{"alias=" + name}
+				for _, alias := range n.aliases {
+					opts = append(opts, "alias="+alias)
+				}
+				if n.ipv4 != "" {
+					opts = append(opts, "ip="+n.ipv4)
+				}
+				if n.ipv6 != "" {
+					opts = append(opts, "ip6="+n.ipv6)
+				}
+				add("--network", p.resourceName(n.name, network.Name, network.External)+":"+strings.Join(opts, ","))
+			}
+		case strings.HasPrefix(mode, "service:"):
+			add("--network", "container:"+p.containerName(strings.TrimPrefix(mode, "service:"), 1))
+		default:
+			add("--network", mode)
+		}
+		add("--publish", service.Ports...)
+	}
+	add("--expose", service.Expose...)
+
+	// Storage
+	for i, spec := range service.Volumes {
+		mount, err := p.volumeMount(fmt.Sprintf("%s.volumes[%d]", loc, i), spec)
+		if err != nil {
+			return nil, err
+		}
+		add("--volume", mount)
+	}
+	add("--tmpfs", stringOrList(service.Tmpfs)...)
+	mounts, err := p.fileMounts(loc, service)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, mounts...)
+
+	// Environment and labels
+	for _, file := range parseEnvFiles(service.EnvFile) {
+		add("--env-file", p.path(file))
+	}
+	envs := parseEnvironment(service.Environment)
+	for _, k := range sortedKeys(envs) {
+		add("--env", k+"="+envs[k])
+	}
+	labels := parseLabels(service.Labels)
+	for _, k := range sortedKeys(labels) {
+		add("--label", k+"="+labels[k])
+	}
+
+	// Process
+	if service.Entrypoint != nil {
+		entrypoint := commandArgs(service.Entrypoint)
+		encoded, _ := json.Marshal(entrypoint)
+		args = append(args, "--entrypoint", string(encoded))
+	}
+	add("--user", service.User)
+	add("--group-add", service.GroupAdd...)
+	add("--workdir", service.WorkingDir)
+	add("--stop-signal", service.StopSignal)
+	if service.StopTimeout != nil {
+		seconds, ok := durationSeconds(service.StopTimeout)
+		if !ok {
+			return nil, fmt.Errorf("%s.stop_grace_period: invalid duration %v", loc, service.StopTimeout)
+		}
+		add("--stop-timeout", strconv.Itoa(seconds))
+	}
+	flag(service.StdinOpen, "--interactive")
+	flag(service.Tty, "--tty")
+	add("--pid", service.PidMode)
+	add("--ipc", service.IpcMode)
+	add("--runtime", service.Runtime)
+
+	// Security
+	add("--cap-add", service.CapAdd...)
+	add("--cap-drop", service.CapDrop...)
+	add("--security-opt", service.SecurityOpt...)
+	flag(service.Privileged, "--privileged")
+	flag(service.ReadOnly, "--read-only")
+
+	// Devices, DNS, hosts and kernel settings
+	add("--device", service.Devices...)
+	add("--dns", stringOrList(service.DNS)...)
+	add("--dns-search", stringOrList(service.DNSSearch)...)
+	add("--add-host", service.ExtraHosts...)
+	sysctls := parseLabels(service.Sysctls)
+	for _, k := range sortedKeys(sysctls) {
+		add("--sysctl", k+"="+sysctls[k])
+	}
+	add("--ulimit", parseUlimits(service.Ulimits)...)
+
+	args = append(args, p.healthcheck(loc+".healthcheck", service.HealthCheck)...)
+	args = append(args, p.logging(service.Logging)...)
+
+	// Resources and restart policy
+	restart := service.Restart
+	if deploy, ok := service.Deploy.(map[string]interface{}); ok {
+		args = append(args, resourceArgs(deploy)...)
+		if policy, ok := deploy["restart_policy"].(map[string]interface{}); ok && restart == "" {
+			restart, _ = policy["condition"].(string)
+		}
+	}
+	switch restart {
+	case "", "none":
+	case "any":
+		add("--restart", "always")
+	default:
+		add("--restart", restart)
+	}
+
+	return args, nil
+}
+
+// volumeMount resolves the source of a volume entry: named volumes to their
+// Podman name, relative bind mounts to the project directory
+func (p *planner) volumeMount(location, spec string) (string, error) {
+	source, rest, found := strings.Cut(spec, ":")
+	if !found {
+		// Anonymous volume, e.g. "/data"
+		return spec, nil
+	}
+
+	switch {
+	case strings.HasPrefix(source, "/") || strings.HasPrefix(source, "~"):
+		return spec, nil
+	case strings.HasPrefix(source, "."):
+		return p.path(source) + ":" + rest, nil
+	}
+
+	volume, declared := p.compose.Volumes[source]
+	if !declared {
+		return "", fmt.Errorf("%s: volume %q is not declared at the top level", location, source)
+	}
+	return p.resourceName(source, volume.Name, volume.External) + ":" + rest, nil
+}
+
+// fileMounts mounts file-based secrets and configs read-only. Secrets
+// without a file must exist in Podman (podman secret create).
+func (p *planner) fileMounts(location string, service Service) ([]string, error) {
+	var args []string
+	for _, section := range []struct {
+		name       string
+		raw        interface{}
+		defined    map[string]interface{}
+		targetBase string
+	}{
+		{"secrets", service.Secrets, p.compose.Secrets, "/run/secrets/"},
+		{"configs", service.Configs, p.compose.Configs, "/"},
+	} {
+		list, _ := section.raw.([]interface{})
+		for _, item := range list {
+			source, target := "", ""
+			switch s := item.(type) {
+			case string:
+				source = s
+			case map[string]interface{}:
+				source, _ = s["source"].(string)
+				target, _ = s["target"].(string)
+			}
+			if source == "" {
+				continue
+			}
+			if target == "" {
+				target = source
+			}
+			if !strings.HasPrefix(target, "/") {
+				target = section.targetBase + target
+			}
+
+			def, declared := section.defined[source].(map[string]interface{})
+			if !declared {
+				return nil, fmt.Errorf("%s.%s: %q is not declared at the top level", location, section.name, source)
+			}
+			if file, ok := def["file"].(string); ok {
+				args = append(args, "--volume", p.path(file)+":"+target+":ro")
+				continue
+			}
+			if section.name == "secrets" {
+				name := source
+				if n, ok := def["name"].(string); ok && n != "" {
+					name = n
+				}
+				args = append(args, "--secret", name+",target="+target)
+				continue
+			}
+			p.warn(location+".configs", "config %s has no file and is skipped", source)
+		}
+	}
+	return args, nil
+}
+
+// healthcheck maps a compose healthcheck onto --health-* options
+func (p *planner) healthcheck(location string, raw interface{}) []string {
+	hc, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if disable, _ := hc["disable"].(bool); disable {
+		return []string{"--no-healthcheck"}
+	}
+
+	var args []string
+	switch test := hc["test"].(type) {
+	case string:
+		args = append(args, "--health-cmd", test)
+	case []interface{}:
+		cmd := toStrings(test)
+		if len(cmd) == 0 {
+			break
+		}
+		switch cmd[0] {
+		case "NONE":
+			return []string{"--no-healthcheck"}
+		case "CMD-SHELL":
+			args = append(args, "--health-cmd", strings.Join(cmd[1:], " "))
+		case "CMD":
+			encoded, _ := json.Marshal(cmd[1:])
+			args = append(args, "--health-cmd", string(encoded))
+		default:
+			encoded, _ := json.Marshal(cmd)
+			args = append(args, "--health-cmd", string(encoded))
+		}
+	}
+	for _, field := range []string{"interval", "timeout", "start_period", "retries"} {
+		if v, ok := hc[field]; ok {
+			args = append(args, "--health-"+strings.ReplaceAll(field, "_", "-"), fmt.Sprintf("%v", v))
+		}
+	}
+	if _, ok := hc["start_interval"]; ok {
+		p.warn(location+".start_interval", "not supported by Podman")
+	}
+	return args
+}
+
+// logging maps the logging driver and options
+func (p *planner) logging(raw interface{}) []string {
+	logging, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var args []string
+	if driver, ok := logging["driver"].(string); ok {
+		if driver == "json-file" || driver == "local" {
+			driver = "k8s-file"
+		}
+		args = append(args, "--log-driver", driver)
+	}
+	options := parseLabels(logging["options"])
+	for _, k := range sortedKeys(options) {
+		args = append(args, "--log-opt", k+"="+options[k])
+	}
+	return args
+}
+
+// path resolves a path relative to the project directory
+func (p *planner) path(path string) string {
+	if filepath.IsAbs(path) || p.dir == "" {
+		return path
+	}
+	return filepath.Join(p.dir, path)
+}
+
+// commandArgs converts a compose command or entrypoint to arguments
+func commandArgs(raw interface{}) []string {
+	switch cmd := raw.(type) {
+	case string:
+		return splitCommand(cmd)
+	case []interface{}:
+		return toStrings(cmd)
+	}
+	return nil
+}
+
+// hasHealthcheck reports whether a service defines an enabled healthcheck
+func hasHealthcheck(raw interface{}) bool {
+	hc, ok := raw.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	if disable, _ := hc["disable"].(bool); disable {
+		return false
+	}
+	if test, ok := hc["test"].([]interface{}); ok && len(test) > 0 && test[0] == "NONE" {
+		return false
+	}
+	return hc["test"] != nil
+}
+
+// dependencyOrder sorts services so each comes after the services it
+// depends on, including network_mode: service:x. Cycles are an error.
+func dependencyOrder(compose *ComposeFile) ([]string, error) {
+	var order []string
+	state := make(map[string]int) // 1 = visiting, 2 = done
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		service := compose.Services[name]
+
+		deps := parseDependsOn(service.DependsOn)
+		if target, ok := strings.CutPrefix(service.NetworkMode, "service:"); ok {
+			deps = append(deps, Dependency{Service: target, Condition: "service_started", Required: true})
+		}
+		for _, dep := range deps {
+			if _, ok := compose.Services[dep.Service]; !ok {
+				if dep.Required {
+					return fmt.Errorf("services.%s depends on undefined service %s", name, dep.Service)
+				}
+				continue
+			}
+			if err := visit(dep.Service, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range sortedKeys(compose.Services) {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,214 @@
+package translator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildPlan(t *testing.T) {
+	input := `
+name: shop
+services:
+  web:
+    image: nginx:1.27
+    ports: ["8080:80"]
+    volumes: ["./html:/usr/share/nginx/html:ro", "cache:/var/cache/nginx"]
+    networks:
+      front:
+        aliases: [www]
+    depends_on:
+      api:
+        condition: service_healthy
+    command: nginx -g "daemon off;"
+  api:
+    image: shop/api:2
+    environment:
+      DB_HOST: db
+    healthcheck:
+      test: ["CMD", "curl", "-f", "http://localhost/health"]
+      interval: 10s
+    deploy:
+      replicas: 2
+      resources:
+        limits:
+          memory: 256m
+    depends_on: [db]
+    networks: [front, back]
+  db:
+    image: postgres:16
+    restart: unless-stopped
+    volumes: ["data:/var/lib/postgresql/data"]
+    secrets: [db_password]
+    networks: [back]
+networks:
+  front: {}
+  back:
+    internal: true
+volumes:
+  cache: {}
+  data:
+    external: true
+    name: pgdata
+secrets:
+  db_password:
+    file: ./secrets/db_password.txt
+`
+	compose, _, err := Load([]ConfigFile{{Path: "docker-compose.yml", Content: input}}, LoadOptions{})
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	plan, warnings, err := BuildPlan(compose, "/srv/stacks/shop")
+	if err != nil {
+		t.Fatalf("BuildPlan returned error: %v", err)
+	}
+	if len(warnings) > 0 {
+		t.Errorf("Unexpected warnings: %v", warnings)
+	}
+
+	var order []string
+	for _, s := range plan.Services {
+		order = append(order, s.Name)
+	}
+	if strings.Join(order, ",") != "db,api,web" {
+		t.Errorf("Services should be in dependency order, got %v", order)
+	}
+
+	if len(plan.Networks) != 2 || plan.Networks[0].Name != "shop_back" || !strings.Contains(strings.Join(plan.Networks[0].Args, " "), "--internal") {
+		t.Errorf("Unexpected networks: %+v", plan.Networks)
+	}
+	if len(plan.Volumes) != 2 || plan.Volumes[0].Name != "shop_cache" || !plan.Volumes[1].External || plan.Volumes[1].Name != "pgdata" {
+		t.Errorf("Unexpected volumes: %+v", plan.Volumes)
+	}
+
+	api := plan.Services[1]
+	if !api.HasHealthcheck || len(api.Containers) != 2 || api.Containers[1].Name != "shop_api_2" {
+		t.Fatalf("Unexpected api service: %+v", api)
+	}
+	apiArgs := strings.Join(api.Containers[0].Args, " ")
+	for _, want := range []string{
+		"create --name shop_api_1 ",
+		"--label com.docker.compose.project=shop",
+		"--label com.docker.compose.service=api",
+		"--network shop_back:alias=api --network shop_front:alias=api",
+		`--health-cmd ["curl","-f","http://localhost/health"] --health-interval 10s`,
+		"--memory=256m",
+		"--label com.docker.compose.config-hash=" + api.Containers[0].Hash + " shop/api:2",
+	} {
+		if !strings.Contains(apiArgs, want) {
+			t.Errorf("api args missing %q:\n%s", want, apiArgs)
+		}
+	}
+
+	web := plan.Services[2]
+	if web.DependsOn[0].Service != "api" || web.DependsOn[0].Condition != "service_healthy" {
+		t.Errorf("Unexpected web dependencies: %+v", web.DependsOn)
+	}
+	webArgs := strings.Join(web.Containers[0].Args, " ")
+	for _, want := range []string{
+		"--network shop_front:alias=web,alias=www",
+		"--publish 8080:80",
+		"--volume /srv/stacks/shop/html:/usr/share/nginx/html:ro",
+		"--volume shop_cache:/var/cache/nginx",
+		"nginx:1.27 nginx -g daemon off;",
+	} {
+		if !strings.Contains(webArgs, want) {
+			t.Errorf("web args missing %q:\n%s", want, webArgs)
+		}
+	}
+
+	dbArgs := strings.Join(plan.Services[0].Containers[0].Args, " ")
+	for _, want := range []string{
+		"--volume pgdata:/var/lib/postgresql/data",
+		"--volume /srv/stacks/shop/secrets/db_password.txt:/run/secrets/db_password:ro",
+		"--restart unless-stopped",
+	} {
+		if !strings.Contains(dbArgs, want) {
+			t.Errorf("db args missing %q:\n%s", want, dbArgs)
+		}
+	}
+
+	// The same input plans the same hashes, so unchanged containers are kept
+	again, _, _ := BuildPlan(compose, "/srv/stacks/shop")
+	if again.Services[1].Containers[0].Hash != api.Containers[0].Hash {
+		t.Error("Expected a stable configuration hash")
+	}
+}
+
+func TestBuildPlanErrors(t *testing.T) {
+	for name, input := range map[string]string{
+		"dependency cycle":  "services:\n  a: {image: x, depends_on: [b]}\n  b: {image: x, depends_on: [a]}\n",
+		"can't be combined": "services:\n  a: {build: {dockerfile: Containerfile, dockerfile_inline: 'FROM x'}}\n",
+		"is not declared":   "services:\n  a: {image: x, volumes: ['data:/data']}\n",
+		"undefined service": "services:\n  a: {image: x, network_mode: 'service:b'}\n",
+	} {
+		compose, _, err := Load([]ConfigFile{{Path: "docker-compose.yml", Content: input}}, LoadOptions{Project: "p"})
+		if err == nil {
+			_, _, err = BuildPlan(compose, "")
+		}
+		if err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("Expected an error containing %q, got %v", name, err)
+		}
+	}
+}
+
+func TestBuildPlanBuild(t *testing.T) {
+	input := `
+name: shop
+services:
+  web:
+    build: ./web
+  api:
+    image: shop/api:dev
+    build:
+      context: ./api
+      dockerfile: deploy/Containerfile
+      args: [VERSION=2, UNSET]
+      target: runtime
+      platforms: [linux/amd64, linux/arm64]
+      ssh: [default]
+  worker:
+    image: registry.example.com/shop/worker
+    build: https://git.example.com/shop/worker.git#main
+`
+	compose, _, err := Load([]ConfigFile{{Path: "docker-compose.yml", Content: input}}, LoadOptions{})
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	plan, warnings, err := BuildPlan(compose, "/srv/stacks/shop")
+	if err != nil {
+		t.Fatalf("BuildPlan returned error: %v", err)
+	}
+	services := make(map[string]PlannedService)
+	for _, s := range plan.Services {
+		services[s.Name] = s
+	}
+
+	web := services["web"]
+	if web.Image != "localhost/shop_web" || web.Build == nil || web.Build.ContextDir != "/srv/stacks/shop/web" {
+		t.Errorf("web = %+v, build %+v", web, web.Build)
+	}
+	if args := web.Containers[0].Args; args[len(args)-1] != "localhost/shop_web" {
+		t.Errorf("web container does not run the built image: %v", args)
+	}
+
+	api := services["api"].Build
+	if services["api"].Image != "localhost/shop/api:dev" || api.Containerfile != "deploy/Containerfile" ||
+		api.Target != "runtime" || api.Platform != "linux/amd64" || len(api.Args) != 1 || api.Args["VERSION"] != "2" {
+		t.Errorf("api = %s, build %+v", services["api"].Image, api)
+	}
+
+	worker := services["worker"]
+	if worker.Image != "registry.example.com/shop/worker" || worker.Build.GitURL != "https://git.example.com/shop/worker.git#main" || worker.Build.ContextDir != "" {
+		t.Errorf("worker = %s, build %+v", worker.Image, worker.Build)
+	}
+
+	for _, prefix := range []string{"services.api.build.platforms", "services.api.build.ssh"} {
+		found := false
+		for _, w := range warnings {
+			found = found || strings.HasPrefix(w, prefix)
+		}
+		if !found {
+			t.Errorf("Expected a warning for %s, got %v", prefix, warnings)
+		}
+	}
+}
@@ -0,0 +1,845 @@
+package translator
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"podmangr-backend/internal/quadlet"
+)
+
+// defaultNetworkName is the network compose attaches services to when they
+// don't list any
+const defaultNetworkName = "default"
+
+// quadletGenerator turns a parsed compose file into Quadlet units
+type quadletGenerator struct {
+	compose  *ComposeFile
+	warnings []string
+
+	// healthGated holds services another service waits on with
+	// condition: service_healthy; their units get Notify=healthy
+	healthGated map[string]bool
+	// usesDefaultNetwork is set once a service joins the implicit default network
+	usesDefaultNetwork bool
+}
+
+// toQuadlet generates one .container unit per service plus .volume and
+// .network units for the named volumes and networks they use. Compose
+// settings without a Quadlet equivalent are reported as warnings.
+func (t *Translator) toQuadlet(compose *ComposeFile) ([]quadlet.File, []string) {
+	g := &quadletGenerator{
+		compose:     compose,
+		healthGated: make(map[string]bool),
+	}
+
+	serviceNames := sortedKeys(compose.Services)
+
+	// Dependencies are collected first so providers know to signal health
+	for _, name := range serviceNames {
+		for _, dep := range parseDependsOn(compose.Services[name].DependsOn) {
+			if dep.Condition == "service_healthy" {
+				g.healthGated[dep.Service] = true
+			}
+		}
+	}
+
+	files := make([]quadlet.File, 0, len(serviceNames))
+	for _, name := range serviceNames {
+		files = append(files, g.file("services."+name, g.containerUnit(name, compose.Services[name])))
+	}
+
+	for _, name := range sortedKeys(compose.Volumes) {
+		if u := g.volumeUnit(name, compose.Volumes[name]); u != nil {
+			files = append(files, g.file("volumes."+name, u))
+		}
+	}
+
+	networkNames := sortedKeys(compose.Networks)
+	if _, declared := compose.Networks[defaultNetworkName]; g.usesDefaultNetwork && !declared {
+		networkNames = append(networkNames, defaultNetworkName)
+	}
+	for _, name := range networkNames {
+		if u := g.networkUnit(name, compose.Networks[name]); u != nil {
+			files = append(files, g.file("networks."+name, u))
+		}
+	}
+
+	return files, g.warnings
+}
+
+// joinQuadletFiles renders a bundle as a single document for display
+func joinQuadletFiles(files []quadlet.File) string {
+	var b strings.Builder
+	for i, f := range files {
+		if i > 0 {
+			b.WriteString("\n---\n\n")
+		}
+		b.WriteString(fmt.Sprintf("# %s\n", f.Name))
+		b.WriteString(f.Content)
+	}
+	return b.String()
+}
+
+// file renders a unit, warning about any value it had to drop
+func (g *quadletGenerator) file(location string, u *quadlet.Unit) quadlet.File {
+	if err := u.Err(); err != nil {
+		g.warn(location, "%v", err)
+	}
+	return u.File()
+}
+
+func (g *quadletGenerator) warn(location, format string, args ...interface{}) {
+	g.warnings = append(g.warnings, fmt.Sprintf("%s: %s", location, fmt.Sprintf(format, args...)))
+}
+
+// resourceName returns the Podman name of a compose volume or network:
+// its explicit name, else the key prefixed with the project name like compose does
+func (g *quadletGenerator) resourceName(key, explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	if g.compose.Name != "" {
+		return g.compose.Name + "_" + key
+	}
+	return key
+}
+
+// containerUnit maps a compose service onto a .container unit
+func (g *quadletGenerator) containerUnit(name string, service Service) *quadlet.Unit {
+	loc := "services." + name
+	u := quadlet.NewUnit(name, quadlet.ExtContainer)
+
+	u.Add("Unit", "Description", fmt.Sprintf("%s service", name))
+	for _, dep := range parseDependsOn(service.DependsOn) {
+		if _, ok := g.compose.Services[dep.Service]; !ok {
+			g.warn(loc+".depends_on", "unknown service %q", dep.Service)
+			continue
+		}
+		depService := quadlet.ServiceName(quadlet.Ref(dep.Service, quadlet.ExtContainer))
+		if dep.Required {
+			u.Add("Unit", "Requires", depService)
+		} else {
+			u.Add("Unit", "Wants", depService)
+		}
+		u.Add("Unit", "After", depService)
+		if dep.Condition == "service_completed_successfully" {
+			g.warn(loc+".depends_on."+dep.Service, "condition service_completed_successfully is treated as service_started")
+		}
+	}
+
+	if service.Image != "" {
+		u.Add("Container", "Image", service.Image)
+	} else if service.Build != nil {
+		g.warn(loc+".build", "Quadlet units need a prebuilt image; build it and set image")
+	}
+	if service.Build != nil && service.Image != "" {
+		g.warn(loc+".build", "ignored, the unit runs the image %s", service.Image)
+	}
+
+	if service.ContainerName != "" {
+		u.Add("Container", "ContainerName", service.ContainerName)
+	} else {
+		u.Add("Container", "ContainerName", name)
+	}
+	u.Add("Container", "HostName", service.Hostname)
+
+	// Networking
+	switch mode := service.NetworkMode; {
+	case mode == "":
+		networks := parseServiceNetworks(service.Networks)
+		if len(networks) == 0 {
+			g.usesDefaultNetwork = true
+			networks = []serviceNetwork{{name: defaultNetworkName}}
+		}
+		for _, n := range networks {
+			u.Add("Container", "Network", g.networkRef(loc+".networks", n.name))
+			u.AddAll("Container", "NetworkAlias", n.aliases)
+			u.Add("Container", "IP", n.ipv4)
+			u.Add("Container", "IP6", n.ipv6)
+		}
+	case mode == "host" || mode == "none":
+		u.Add("Container", "Network", mode)
+	case mode == "bridge":
+		// Podman's default network, nothing to set
+	case strings.HasPrefix(mode, "service:"):
+		target := strings.TrimPrefix(mode, "service:")
+		u.Add("Container", "Network", "container:"+containerName(g.compose, target))
+		depService := quadlet.ServiceName(quadlet.Ref(target, quadlet.ExtContainer))
+		u.Add("Unit", "Requires", depService)
+		u.Add("Unit", "After", depService)
+	default:
+		u.Add("Container", "Network", mode)
+	}
+
+	for _, port := range service.Ports {
+		u.Add("Container", "PublishPort", quadlet.Quote(port))
+	}
+	u.AddAll("Container", "ExposeHostPort", service.Expose)
+
+	for i, vol := range service.Volumes {
+		if ref := g.volumeRef(fmt.Sprintf("%s.volumes[%d]", loc, i), vol); ref != "" {
+			u.Add("Container", "Volume", quadlet.Quote(ref))
+		}
+	}
+	for _, path := range stringOrList(service.Tmpfs) {
+		u.Add("Container", "Tmpfs", path)
+	}
+
+	// Environment
+	envs := parseEnvironment(service.Environment)
+	for _, k := range sortedKeys(envs) {
+		u.Add("Container", "Environment", quadlet.Quote(k+"="+envs[k]))
+	}
+	for _, file := range parseEnvFiles(service.EnvFile) {
+		u.Add("Container", "EnvironmentFile", file)
+	}
+
+	labels := parseLabels(service.Labels)
+	for _, k := range sortedKeys(labels) {
+		u.Add("Container", "Label", quadlet.Quote(k+"="+labels[k]))
+	}
+
+	// Process
+	switch cmd := service.Command.(type) {
+	case string:
+		u.Add("Container", "Exec", quadlet.QuoteArgs(splitCommand(cmd)))
+	case []interface{}:
+		u.Add("Container", "Exec", quadlet.QuoteArgs(toStrings(cmd)))
+	}
+	var entrypoint []string
+	switch ep := service.Entrypoint.(type) {
+	case string:
+		entrypoint = splitCommand(ep)
+	case []interface{}:
+		entrypoint = toStrings(ep)
+	}
+	// Quadlet passes Entrypoint= to --entrypoint verbatim, so it is escaped
+	// but not quoted
+	if len(entrypoint) == 1 {
+		u.Add("Container", "Entrypoint", quadlet.Escape(entrypoint[0]))
+	} else if len(entrypoint) > 1 {
+		// Multi-word entrypoints are passed to --entrypoint as a JSON array
+		encoded, _ := json.Marshal(entrypoint)
+		u.Add("Container", "Entrypoint", quadlet.Escape(string(encoded)))
+	}
+	u.Add("Container", "User", service.User)
+	u.AddAll("Container", "GroupAdd", service.GroupAdd)
+	u.Add("Container", "WorkingDir", service.WorkingDir)
+	u.Add("Container", "StopSignal", service.StopSignal)
+	if service.StopTimeout != nil {
+		if seconds, ok := durationSeconds(service.StopTimeout); ok {
+			u.Add("Container", "StopTimeout", strconv.Itoa(seconds))
+		} else {
+			g.warn(loc+".stop_grace_period", "invalid duration %v", service.StopTimeout)
+		}
+	}
+
+	// Security
+	u.AddAll("Container", "AddCapability", service.CapAdd)
+	u.AddAll("Container", "DropCapability", service.CapDrop)
+	var podmanArgs []string
+	for _, opt := range service.SecurityOpt {
+		switch {
+		case opt == "label=disable" || opt == "label:disable":
+			u.Add("Container", "SecurityLabelDisable", "true")
+		case strings.HasPrefix(opt, "label=type:") || strings.HasPrefix(opt, "label:type:"):
+			u.Add("Container", "SecurityLabelType", opt[len("label=type:"):])
+		case opt == "no-new-privileges" || opt == "no-new-privileges:true" || opt == "no-new-privileges=true":
+			u.Add("Container", "NoNewPrivileges", "true")
+		case strings.HasPrefix(opt, "seccomp=") || strings.HasPrefix(opt, "seccomp:"):
+			u.Add("Container", "SeccompProfile", opt[len("seccomp="):])
+		default:
+			podmanArgs = append(podmanArgs, "--security-opt="+opt)
+		}
+	}
+	if service.Privileged {
+		podmanArgs = append(podmanArgs, "--privileged")
+	}
+	if service.ReadOnly {
+		u.Add("Container", "ReadOnly", "true")
+	}
+
+	// Devices, DNS and hosts
+	u.AddAll("Container", "AddDevice", service.Devices)
+	u.AddAll("Container", "DNS", stringOrList(service.DNS))
+	u.AddAll("Container", "DNSSearch", stringOrList(service.DNSSearch))
+	u.AddAll("Container", "AddHost", service.ExtraHosts)
+
+	// Kernel settings
+	sysctls := parseLabels(service.Sysctls)
+	for _, k := range sortedKeys(sysctls) {
+		u.Add("Container", "Sysctl", quadlet.Quote(k+"="+sysctls[k]))
+	}
+	for _, limit := range parseUlimits(service.Ulimits) {
+		u.Add("Container", "Ulimit", limit)
+	}
+
+	// Healthcheck
+	if service.HealthCheck != nil {
+		g.healthcheck(u, loc+".healthcheck", service.HealthCheck)
+	}
+	if g.healthGated[name] {
+		if service.HealthCheck != nil {
+			u.Add("Container", "Notify", "healthy")
+		} else {
+			g.warn(loc, "other services wait for it to be healthy but it has no healthcheck")
+		}
+	}
+
+	// Namespaces and runtime
+	if service.PidMode != "" {
+		podmanArgs = append(podmanArgs, "--pid="+service.PidMode)
+	}
+	if service.IpcMode != "" {
+		podmanArgs = append(podmanArgs, "--ipc="+service.IpcMode)
+	}
+	if service.Runtime != "" {
+		podmanArgs = append(podmanArgs, "--runtime="+service.Runtime)
+	}
+	if service.StdinOpen {
+		podmanArgs = append(podmanArgs, "--interactive")
+	}
+	if service.Tty {
+		podmanArgs = append(podmanArgs, "--tty")
+	}
+
+	podmanArgs = append(podmanArgs, g.logging(u, loc+".logging", service.Logging)...)
+	restart := service.Restart
+	deployArgs, deployRestart := g.deploy(loc+".deploy", service.Deploy)
+	podmanArgs = append(podmanArgs, deployArgs...)
+	if restart == "" {
+		restart = deployRestart
+	}
+
+	g.secrets(u, loc+".secrets", service.Secrets)
+	if service.Configs != nil {
+		g.warn(loc+".configs", "configs are not supported by Quadlet; mount the files with a bind volume")
+	}
+
+	if len(podmanArgs) > 0 {
+		u.Add("Container", "PodmanArgs", quadlet.QuoteArgs(podmanArgs))
+	}
+
+	switch restart {
+	case "always", "unless-stopped":
+		u.Add("Service", "Restart", "always")
+	case "on-failure", "any":
+		u.Add("Service", "Restart", "on-failure")
+	case "", "no", "none":
+	default:
+		if strings.HasPrefix(restart, "on-failure:") {
+			u.Add("Service", "Restart", "on-failure")
+			g.warn(loc+".restart", "maximum retry count is not supported; use StartLimitBurst in [Unit]")
+		} else {
+			g.warn(loc+".restart", "unknown restart policy %q", restart)
+		}
+	}
+
+	u.Add("Install", "WantedBy", "default.target")
+	return u
+}
+
+// networkRef returns the Network= value for a compose network key
+func (g *quadletGenerator) networkRef(location, key string) string {
+	network, declared := g.compose.Networks[key]
+	if !declared && key != defaultNetworkName {
+		g.warn(location, "network %q is not declared at the top level", key)
+	}
+	if isExternal(network.External) {
+		return externalName(key, network.External, network.Name)
+	}
+	return quadlet.Ref(key, quadlet.ExtNetwork)
+}
+
+// volumeRef rewrites a compose volume entry so named volumes point at their .volume unit
+func (g *quadletGenerator) volumeRef(location, spec string) string {
+	source, rest, found := strings.Cut(spec, ":")
+	if !found {
+		// Anonymous volume, e.g. "/data"
+		return spec
+	}
+
+	if strings.HasPrefix(source, "/") || strings.HasPrefix(source, "~") {
+		return spec
+	}
+	if strings.HasPrefix(source, ".") {
+		g.warn(location, "relative path %s is resolved against the Quadlet unit directory", source)
+		return spec
+	}
+
+	volume, declared := g.compose.Volumes[source]
+	if !declared {
+		g.warn(location, "volume %q is not declared at the top level", source)
+		return spec
+	}
+	if isExternal(volume.External) {
+		return externalName(source, volume.External, volume.Name) + ":" + rest
+	}
+	return quadlet.Ref(source, quadlet.ExtVolume) + ":" + rest
+}
+
+// volumeUnit maps a top-level compose volume onto a .volume unit.
+// External volumes already exist and get no unit.
+func (g *quadletGenerator) volumeUnit(key string, volume Volume) *quadlet.Unit {
+	if isExternal(volume.External) {
+		return nil
+	}
+
+	u := quadlet.NewUnit(key, quadlet.ExtVolume)
+	u.Add("Volume", "VolumeName", g.resourceName(key, volume.Name))
+	if volume.Driver != "" && volume.Driver != "local" {
+		u.Add("Volume", "Driver", volume.Driver)
+	}
+
+	var podmanArgs []string
+	for _, k := range sortedKeys(volume.DriverOpts) {
+		v := volume.DriverOpts[k]
+		switch k {
+		case "type":
+			u.Add("Volume", "Type", v)
+		case "device":
+			u.Add("Volume", "Device", v)
+		case "o":
+			u.Add("Volume", "Options", v)
+		default:
+			podmanArgs = append(podmanArgs, fmt.Sprintf("--opt=%s=%s", k, v))
+		}
+	}
+	if len(podmanArgs) > 0 {
+		u.Add("Volume", "PodmanArgs", quadlet.QuoteArgs(podmanArgs))
+	}
+
+	labels := parseLabels(volume.Labels)
+	for _, k := range sortedKeys(labels) {
+		u.Add("Volume", "Label", quadlet.Quote(k+"="+labels[k]))
+	}
+	return u
+}
+
+// networkUnit maps a top-level compose network onto a .network unit.
+// External networks already exist and get no unit.
+func (g *quadletGenerator) networkUnit(key string, network Network) *quadlet.Unit {
+	if isExternal(network.External) {
+		return nil
+	}
+	loc := "networks." + key
+
+	u := quadlet.NewUnit(key, quadlet.ExtNetwork)
+	u.Add("Network", "NetworkName", g.resourceName(key, network.Name))
+	if network.Driver != "" && network.Driver != "bridge" {
+		u.Add("Network", "Driver", network.Driver)
+	}
+	if network.Internal {
+		u.Add("Network", "Internal", "true")
+	}
+	if network.EnableIPv6 {
+		u.Add("Network", "IPv6", "true")
+	}
+
+	if ipam, ok := network.Ipam.(map[string]interface{}); ok {
+		if driver, ok := ipam["driver"].(string); ok && driver != "default" {
+			u.Add("Network", "IPAMDriver", driver)
+		}
+		if configs, ok := ipam["config"].([]interface{}); ok {
+			for _, c := range configs {
+				cfg, ok := c.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				for _, field := range []struct{ compose, quadlet string }{
+					{"subnet", "Subnet"}, {"gateway", "Gateway"}, {"ip_range", "IPRange"},
+				} {
+					if v, ok := cfg[field.compose].(string); ok {
+						u.Add("Network", field.quadlet, v)
+					}
+				}
+				if _, ok := cfg["aux_addresses"]; ok {
+					g.warn(loc+".ipam.config", "aux_addresses are not supported")
+				}
+			}
+		}
+	}
+
+	for _, k := range sortedKeys(network.DriverOpts) {
+		u.Add("Network", "Options", k+"="+network.DriverOpts[k])
+	}
+	labels := parseLabels(network.Labels)
+	for _, k := range sortedKeys(labels) {
+		u.Add("Network", "Label", quadlet.Quote(k+"="+labels[k]))
+	}
+	return u
+}
+
+// healthcheck maps a compose healthcheck onto the Health* keys
+func (g *quadletGenerator) healthcheck(u *quadlet.Unit, location string, raw interface{}) {
+	hc, ok := raw.(map[string]interface{})
+	if !ok {
+		g.warn(location, "unsupported format")
+		return
+	}
+
+	if disable, _ := hc["disable"].(bool); disable {
+		u.Add("Container", "HealthCmd", "none")
+		return
+	}
+
+	switch test := hc["test"].(type) {
+	case string:
+		u.Add("Container", "HealthCmd", test)
+	case []interface{}:
+		args := toStrings(test)
+		if len(args) > 0 {
+			switch args[0] {
+			case "NONE":
+				u.Add("Container", "HealthCmd", "none")
+			case "CMD-SHELL":
+				u.Add("Container", "HealthCmd", strings.Join(args[1:], " "))
+			case "CMD":
+				u.Add("Container", "HealthCmd", quadlet.QuoteArgs(args[1:]))
+			default:
+				u.Add("Container", "HealthCmd", quadlet.QuoteArgs(args))
+			}
+		}
+	}
+
+	for _, field := range []struct{ compose, quadlet string }{
+		{"interval", "HealthInterval"},
+		{"timeout", "HealthTimeout"},
+		{"start_period", "HealthStartPeriod"},
+		{"retries", "HealthRetries"},
+	} {
+		if v, ok := hc[field.compose]; ok {
+			u.Add("Container", field.quadlet, fmt.Sprintf("%v", v))
+		}
+	}
+	if _, ok := hc["start_interval"]; ok {
+		g.warn(location+".start_interval", "not supported by Quadlet")
+	}
+}
+
+// logging maps the logging driver and returns --log-opt arguments
+func (g *quadletGenerator) logging(u *quadlet.Unit, location string, raw interface{}) []string {
+	logging, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if driver, ok := logging["driver"].(string); ok {
+		switch driver {
+		case "json-file", "local":
+			u.Add("Container", "LogDriver", "k8s-file")
+			g.warn(location+".driver", "%s is mapped to Podman's k8s-file driver", driver)
+		default:
+			u.Add("Container", "LogDriver", driver)
+		}
+	}
+
+	var args []string
+	options := parseLabels(logging["options"])
+	for _, k := range sortedKeys(options) {
+		args = append(args, fmt.Sprintf("--log-opt=%s=%s", k, options[k]))
+	}
+	return args
+}
+
+// deploy maps deploy.resources to podman arguments and returns the restart
+// policy from deploy.restart_policy. Swarm-only settings produce warnings.
+func (g *quadletGenerator) deploy(location string, raw interface{}) ([]string, string) {
+	deploy, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, ""
+	}
+
+	args := resourceArgs(deploy)
+	if resources, ok := deploy["resources"].(map[string]interface{}); ok {
+		if reservations, ok := resources["reservations"].(map[string]interface{}); ok {
+			if _, ok := reservations["cpus"]; ok {
+				g.warn(location+".resources.reservations.cpus", "CPU reservations are not supported by Podman")
+			}
+			if _, ok := reservations["devices"]; ok {
+				g.warn(location+".resources.reservations.devices", "use devices or AddDevice= instead")
+			}
+		}
+	}
+
+	if replicas, ok := deploy["replicas"]; ok && fmt.Sprintf("%v", replicas) != "1" {
+		g.warn(location+".replicas", "a Quadlet unit runs a single container")
+	}
+
+	restart := ""
+	if policy, ok := deploy["restart_policy"].(map[string]interface{}); ok {
+		restart, _ = policy["condition"].(string)
+	}
+
+	for _, key := range []string{"mode", "placement", "update_config", "rollback_config", "endpoint_mode", "labels"} {
+		if _, ok := deploy[key]; ok {
+			g.warn(location+"."+key, "swarm setting ignored")
+		}
+	}
+
+	return args, restart
+}
+
+// resourceArgs maps deploy.resources limits and reservations to podman arguments
+func resourceArgs(deploy map[string]interface{}) []string {
+	resources, ok := deploy["resources"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var args []string
+	if limits, ok := resources["limits"].(map[string]interface{}); ok {
+		if cpus, ok := limits["cpus"]; ok {
+			args = append(args, fmt.Sprintf("--cpus=%v", cpus))
+		}
+		if memory, ok := limits["memory"]; ok {
+			args = append(args, fmt.Sprintf("--memory=%v", memory))
+		}
+		if pids, ok := limits["pids"]; ok {
+			args = append(args, fmt.Sprintf("--pids-limit=%v", pids))
+		}
+	}
+	if reservations, ok := resources["reservations"].(map[string]interface{}); ok {
+		if memory, ok := reservations["memory"]; ok {
+			args = append(args, fmt.Sprintf("--memory-reservation=%v", memory))
+		}
+	}
+	return args
+}
+
+// secrets maps service secrets onto Secret= entries. The secrets must exist
+// in Podman (podman secret create) before the unit starts.
+func (g *quadletGenerator) secrets(u *quadlet.Unit, location string, raw interface{}) {
+	list, ok := raw.([]interface{})
+	if !ok {
+		return
+	}
+
+	for _, item := range list {
+		switch s := item.(type) {
+		case string:
+			u.Add("Container", "Secret", g.secretName(s))
+		case map[string]interface{}:
+			source, _ := s["source"].(string)
+			if source == "" {
+				continue
+			}
+			spec := []string{g.secretName(source)}
+			if target, ok := s["target"].(string); ok {
+				spec = append(spec, "target="+target)
+			}
+			for _, key := range []string{"uid", "gid", "mode"} {
+				if v, ok := s[key]; ok {
+					spec = append(spec, fmt.Sprintf("%s=%v", key, v))
+				}
+			}
+			u.Add("Container", "Secret", strings.Join(spec, ","))
+		}
+	}
+
+	if len(list) > 0 {
+		g.warn(location, "secrets must be created with podman secret create before starting the unit")
+	}
+}
+
+// secretName returns the Podman name of a compose secret
+func (g *quadletGenerator) secretName(key string) string {
+	if secret, ok := g.compose.Secrets[key].(map[string]interface{}); ok {
+		if name, ok := secret["name"].(string); ok && name != "" {
+			return name
+		}
+	}
+	return key
+}
+
+// Dependency is one entry of depends_on
+type Dependency struct {
+	Service   string
+	Condition string // service_started, service_healthy or service_completed_successfully
+	Required  bool
+}
+
+// parseDependsOn handles both the list and the map form of depends_on
+func parseDependsOn(raw interface{}) []Dependency {
+	var deps []Dependency
+	switch d := raw.(type) {
+	case []interface{}:
+		for _, item := range d {
+			if name, ok := item.(string); ok {
+				deps = append(deps, Dependency{Service: name, Condition: "service_started", Required: true})
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range sortedKeys(d) {
+			dep := Dependency{Service: name, Condition: "service_started", Required: true}
+			if opts, ok := d[name].(map[string]interface{}); ok {
+				if condition, ok := opts["condition"].(string); ok {
+					dep.Condition = condition
+				}
+				if required, ok := opts["required"].(bool); ok {
+					dep.Required = required
+				}
+			}
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
+
+// serviceNetwork is one network a service joins
+type serviceNetwork struct {
+	name    string
+	aliases []string
+	ipv4    string
+	ipv6    string
+}
+
+// parseServiceNetworks handles both the list and the map form of service networks
+func parseServiceNetworks(raw interface{}) []serviceNetwork {
+	var networks []serviceNetwork
+	switch n := raw.(type) {
+	case []interface{}:
+		for _, item := range n {
+			if name, ok := item.(string); ok {
+				networks = append(networks, serviceNetwork{name: name})
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range sortedKeys(n) {
+			network := serviceNetwork{name: name}
+			if opts, ok := n[name].(map[string]interface{}); ok {
+				if aliases, ok := opts["aliases"].([]interface{}); ok {
+					network.aliases = toStrings(aliases)
+				}
+				network.ipv4, _ = opts["ipv4_address"].(string)
+				network.ipv6, _ = opts["ipv6_address"].(string)
+			}
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// parseEnvFiles handles env_file as a string, a list of strings or a list of {path}
+func parseEnvFiles(raw interface{}) []string {
+	var files []string
+	switch e := raw.(type) {
+	case string:
+		files = append(files, e)
+	case []interface{}:
+		for _, item := range e {
+			switch f := item.(type) {
+			case string:
+				files = append(files, f)
+			case map[string]interface{}:
+				if path, ok := f["path"].(string); ok {
+					files = append(files, path)
+				}
+			}
+		}
+	}
+	return files
+}
+
+// parseUlimits returns name=soft:hard entries from the compose ulimits map
+func parseUlimits(raw interface{}) []string {
+	ulimits, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var result []string
+	for _, name := range sortedKeys(ulimits) {
+		switch v := ulimits[name].(type) {
+		case map[string]interface{}:
+			result = append(result, fmt.Sprintf("%s=%v:%v", name, v["soft"], v["hard"]))
+		default:
+			result = append(result, fmt.Sprintf("%s=%v", name, v))
+		}
+	}
+	return result
+}
+
+// durationSeconds converts a compose duration ("1m30s", "10s" or a number of seconds)
+func durationSeconds(raw interface{}) (int, bool) {
+	switch v := raw.(type) {
+	case int:
+		return v, true
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, false
+		}
+		return int(d.Seconds()), true
+	}
+	return 0, false
+}
+
+// containerName returns the container name compose uses for a service
+func containerName(compose *ComposeFile, service string) string {
+	if s, ok := compose.Services[service]; ok && s.ContainerName != "" {
+		return s.ContainerName
+	}
+	return service
+}
+
+// isExternal reports whether a volume or network is marked external
+// (either external: true or the legacy external: {name: ...})
+func isExternal(raw interface{}) bool {
+	switch e := raw.(type) {
+	case bool:
+		return e
+	case map[string]interface{}:
+		return true
+	}
+	return false
+}
+
+// externalName returns the name of an external volume or network: its name
+// field, the legacy external.name, or the key itself. Compose never adds the
+// project prefix to external resources.
+func externalName(key string, external interface{}, name string) string {
+	if name != "" {
+		return name
+	}
+	if e, ok := external.(map[string]interface{}); ok {
+		if n, ok := e["name"].(string); ok && n != "" {
+			return n
+		}
+	}
+	return key
+}
+
+// stringOrList handles fields that accept a single string or a list
+func stringOrList(raw interface{}) []string {
+	switch v := raw.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		return toStrings(v)
+	}
+	return nil
+}
+
+// toStrings converts a YAML list to strings
+func toStrings(items []interface{}) []string {
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		result = append(result, fmt.Sprintf("%v", item))
+	}
+	return result
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
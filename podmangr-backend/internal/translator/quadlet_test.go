@@ -0,0 +1,169 @@
+package translator
+
+import (
+	"strings"
+	"testing"
+)
+
+const quadletCompose = `
+name: shop
+services:
+  web:
+    image: nginx:latest
+    ports: ["8080:80"]
+    volumes:
+      - static:/usr/share/nginx/html:ro
+      - ./conf:/etc/nginx/conf.d
+    networks:
+      front:
+        aliases: [www]
+    depends_on:
+      db:
+        condition: service_healthy
+    extra_hosts: ["api:10.0.0.5"]
+    environment:
+      GREETING: hello world
+    command: ["nginx", "-g", "daemon off;"]
+    restart: unless-stopped
+    deploy:
+      resources:
+        limits:
+          memory: 256m
+  db:
+    image: postgres:16
+    volumes: ["pgdata:/var/lib/postgresql/data"]
+    healthcheck:
+      test: ["CMD-SHELL", "pg_isready -U postgres"]
+      interval: 10s
+      retries: 5
+    security_opt: ["label=disable"]
+    ulimits:
+      nofile: {soft: 1024, hard: 2048}
+    stop_grace_period: 1m
+volumes:
+  static: {}
+  pgdata:
+    name: pgdata
+  legacy:
+    external: true
+networks:
+  front:
+    ipam:
+      config:
+        - subnet: 10.90.0.0/24
+`
+
+func TestTranslateQuadlet(t *testing.T) {
+	result, err := NewTranslator().Translate(quadletCompose, FormatQuadlet)
+	if err != nil {
+		t.Fatalf("Translate returned error: %v", err)
+	}
+
+	files := make(map[string]string)
+	var names []string
+	for _, f := range result.Files {
+		files[f.Name] = f.Content
+		names = append(names, f.Name)
+	}
+	want := []string{"db.container", "web.container", "pgdata.volume", "static.volume", "front.network", "default.network"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("Expected files %v, got %v", want, names)
+	}
+
+	for file, lines := range map[string][]string{
+		"web.container": {
+			"[Unit]\nDescription=web service\nRequires=db.service\nAfter=db.service\n",
+			"Network=front.network\n",
+			"NetworkAlias=www\n",
+			"PublishPort=8080:80\n",
+			"Volume=static.volume:/usr/share/nginx/html:ro\n",
+			"Volume=./conf:/etc/nginx/conf.d:z\n",
+			`Environment="GREETING=hello world"` + "\n",
+			`Exec=nginx -g "daemon off;"` + "\n",
+			"AddHost=api:10.0.0.5\n",
+			"PodmanArgs=--memory=256m\n",
+			"[Service]\nRestart=always\n",
+			"[Install]\nWantedBy=default.target\n",
+		},
+		"db.container": {
+			"Network=default.network\n",
+			"Volume=pgdata.volume:/var/lib/postgresql/data\n",
+			"HealthCmd=pg_isready -U postgres\n",
+			"HealthInterval=10s\n",
+			"HealthRetries=5\n",
+			"Notify=healthy\n",
+			"SecurityLabelDisable=true\n",
+			"Ulimit=nofile=1024:2048\n",
+			"StopTimeout=60\n",
+		},
+		"static.volume":   {"VolumeName=shop_static\n"},
+		"pgdata.volume":   {"VolumeName=pgdata\n"},
+		"front.network":   {"NetworkName=shop_front\n", "Subnet=10.90.0.0/24\n"},
+		"default.network": {"NetworkName=shop_default\n"},
+	} {
+		for _, line := range lines {
+			if !strings.Contains(files[file], line) {
+				t.Errorf("%s missing %q:\n%s", file, line, files[file])
+			}
+		}
+	}
+
+	if strings.Contains(files["web.container"], "HostName=") {
+		t.Errorf("extra_hosts must map to AddHost, not HostName:\n%s", files["web.container"])
+	}
+	if !strings.HasPrefix(result.Output, "# db.container\n[Unit]") {
+		t.Errorf("Unexpected combined output:\n%s", result.Output)
+	}
+
+	var relativeWarning bool
+	for _, w := range result.Warnings {
+		if strings.HasPrefix(w, "services.web.volumes[1]") {
+			relativeWarning = true
+		}
+	}
+	if !relativeWarning {
+		t.Errorf("Expected a warning for the relative bind mount, got %v", result.Warnings)
+	}
+}
+
+func TestTranslateQuadletEscaping(t *testing.T) {
+	compose := `
+services:
+  app:
+    image: alpine
+    command: sh -c 'echo $$HOME 100%'
+    entrypoint: /bin/run-$$ARCH
+    sysctls:
+      net.core.somaxconn: "1024"
+    ports: ["127.0.0.1:${PORT:-8080}:80"]
+    labels:
+      note: "line one\nExecStartPre=/bin/true"
+`
+	result, err := NewTranslator().Translate(compose, FormatQuadlet)
+	if err != nil {
+		t.Fatalf("Translate returned error: %v", err)
+	}
+	content := result.Files[0].Content
+	for _, line := range []string{
+		`Exec=sh -c "echo $$HOME 100%%"` + "\n",
+		"Entrypoint=/bin/run-$$ARCH\n",
+		"Sysctl=net.core.somaxconn=1024\n",
+		"PublishPort=127.0.0.1:8080:80\n",
+	} {
+		if !strings.Contains(content, line) {
+			t.Errorf("app.container missing %q:\n%s", line, content)
+		}
+	}
+	if strings.Contains(content, "ExecStartPre") {
+		t.Errorf("value with a line break was written:\n%s", content)
+	}
+	var dropped bool
+	for _, w := range result.Warnings {
+		if strings.HasPrefix(w, "services.app:") && strings.Contains(w, "line break") {
+			dropped = true
+		}
+	}
+	if !dropped {
+		t.Errorf("Expected a warning for the dropped label, got %v", result.Warnings)
+	}
+}
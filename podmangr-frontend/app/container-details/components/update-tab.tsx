@@ -38,6 +38,8 @@ import {
   ChevronRight,
   Settings2,
   RotateCcw,
+  HeartPulse,
+  ShieldCheck,
 } from "lucide-react";
 
 interface UpdateTabProps {
@@ -60,6 +62,7 @@ interface Backup {
   image: string;
   backup_path: string;
   backup_type: string;
+  format?: string;
   mounts: Array<{
     source: string;
     target: string;
@@ -101,6 +104,9 @@ export function UpdateTab({ containerId, currentImage, hasVolumes }: UpdateTabPr
   const [customBackupPath, setCustomBackupPath] = useState("");
   const [removeOldContainer, setRemoveOldContainer] = useState(false);
   const [newImage, setNewImage] = useState("");
+  const [healthTimeout, setHealthTimeout] = useState("60");
+  const [probePort, setProbePort] = useState("");
+  const [probePath, setProbePath] = useState("/");
 
   const wsRef = useRef<WebSocket | null>(null);
   const progressRef = useRef<HTMLDivElement>(null);
@@ -202,6 +208,38 @@ export function UpdateTab({ containerId, currentImage, hasVolumes }: UpdateTabPr
     }
   };
 
+  // Verify backup state
+  const [verifyingBackupId, setVerifyingBackupId] = useState<string | null>(null);
+
+  // Verify backup checksums
+  const verifyBackup = async (backupId: string) => {
+    if (!token) return;
+
+    setVerifyingBackupId(backupId);
+    try {
+      const response = await fetch(`/api/containers/backups/${backupId}/verify`, {
+        method: "POST",
+        headers: { Authorization: `Bearer ${token}` },
+      });
+
+      const data = await response.json();
+      if (!response.ok) {
+        throw new Error(data.error || "Failed to verify backup");
+      }
+
+      if (data.valid) {
+        alert(`Backup is intact: ${data.files_checked} entries checked (${formatBytes(data.bytes_checked)}).`);
+      } else {
+        alert(`Backup is damaged:\n${(data.errors || []).join("\n")}`);
+      }
+    } catch (err) {
+      console.error("Failed to verify backup:", err);
+      alert(err instanceof Error ? err.message : "Failed to verify backup");
+    } finally {
+      setVerifyingBackupId(null);
+    }
+  };
+
   // Start update process
   const startUpdate = () => {
     if (!token || !containerId) return;
@@ -227,6 +265,10 @@ export function UpdateTab({ containerId, currentImage, hasVolumes }: UpdateTabPr
         overwrite_backup: overwriteBackup,
         remove_old: removeOldContainer,
         stop_timeout: 30,
+        health_timeout: parseInt(healthTimeout) || undefined,
+        health_probe: probePort
+          ? { type: "http", port: parseInt(probePort), path: probePath || "/" }
+          : undefined,
       }));
     };
 
@@ -332,6 +374,8 @@ export function UpdateTab({ containerId, currentImage, hasVolumes }: UpdateTabPr
         return <Package className="w-4 h-4 text-green-400" />;
       case "start":
         return <ArrowUpCircle className="w-4 h-4 text-green-400" />;
+      case "health":
+        return <HeartPulse className="w-4 h-4 text-pink-400" />;
       case "cleanup":
         return <Trash2 className="w-4 h-4 text-gray-400" />;
       case "complete":
@@ -551,7 +595,7 @@ export function UpdateTab({ containerId, currentImage, hasVolumes }: UpdateTabPr
                           <div>
                             <Label>Create Backup</Label>
                             <p className="text-xs text-muted-foreground">
-                              Backup bind mount data before updating
+                              Backup bind mount and volume data before updating
                             </p>
                           </div>
                           <Switch checked={createBackup} onCheckedChange={setCreateBackup} />
@@ -563,7 +607,7 @@ export function UpdateTab({ containerId, currentImage, hasVolumes }: UpdateTabPr
                               <div>
                                 <Label>Overwrite Existing Backup</Label>
                                 <p className="text-xs text-muted-foreground">
-                                  Replace previous backup for this container
+                                  Replace previous backups instead of keeping them up to the retention limit
                                 </p>
                               </div>
                               <Switch checked={overwriteBackup} onCheckedChange={setOverwriteBackup} />
@@ -585,11 +629,53 @@ export function UpdateTab({ containerId, currentImage, hasVolumes }: UpdateTabPr
                     {!hasVolumes && (
                       <div className="p-3 rounded-lg border border-blue-500/30 bg-blue-500/10">
                         <p className="text-sm text-blue-400">
-                          This container has no bind mounts, so no backup will be created.
+                          This container has no bind mounts or volumes, so no backup will be created.
                         </p>
                       </div>
                     )}
 
+                    {/* Health check options */}
+                    <div className="space-y-3 p-3 rounded-lg border border-border/50 bg-muted/30">
+                      <div>
+                        <Label>Health Check</Label>
+                        <p className="text-xs text-muted-foreground">
+                          The original container is restored if the new one is not healthy in time
+                        </p>
+                      </div>
+                      <div className="space-y-2">
+                        <Label>Grace Period (seconds)</Label>
+                        <Input
+                          type="number"
+                          min={1}
+                          value={healthTimeout}
+                          onChange={(e) => setHealthTimeout(e.target.value)}
+                        />
+                      </div>
+                      <div className="grid grid-cols-2 gap-2">
+                        <div className="space-y-2">
+                          <Label>HTTP Probe Port (optional)</Label>
+                          <Input
+                            type="number"
+                            placeholder="Container port"
+                            value={probePort}
+                            onChange={(e) => setProbePort(e.target.value)}
+                          />
+                        </div>
+                        <div className="space-y-2">
+                          <Label>Probe Path</Label>
+                          <Input
+                            placeholder="/"
+                            value={probePath}
+                            onChange={(e) => setProbePath(e.target.value)}
+                            disabled={!probePort}
+                          />
+                        </div>
+                      </div>
+                      <p className="text-xs text-muted-foreground">
+                        Without a probe, the container&apos;s own healthcheck is used, or it must keep running for the grace period
+                      </p>
+                    </div>
+
                     {/* Cleanup option */}
                     <div className="flex items-center justify-between">
                       <div>
@@ -622,7 +708,7 @@ export function UpdateTab({ containerId, currentImage, hasVolumes }: UpdateTabPr
             Volume Backups
           </CardTitle>
           <CardDescription>
-            Backups of bind mount data created before updates
+            Backups of bind mount and volume data created before updates
           </CardDescription>
         </CardHeader>
         <CardContent>
@@ -664,6 +750,24 @@ export function UpdateTab({ containerId, currentImage, hasVolumes }: UpdateTabPr
                     </div>
                     {isAdmin && (
                       <div className="flex items-center gap-1">
+                        {/* Verify button, for backups with a manifest */}
+                        {backup.format && (
+                          <Button
+                            variant="ghost"
+                            size="sm"
+                            className="text-emerald-500 hover:text-emerald-400"
+                            onClick={() => verifyBackup(backup.id)}
+                            disabled={verifyingBackupId === backup.id}
+                            title="Verify checksums"
+                          >
+                            {verifyingBackupId === backup.id ? (
+                              <Loader2 className="w-4 h-4 animate-spin" />
+                            ) : (
+                              <ShieldCheck className="w-4 h-4" />
+                            )}
+                          </Button>
+                        )}
+
                         {/* Restore button */}
                         <AlertDialog>
                           <AlertDialogTrigger asChild>
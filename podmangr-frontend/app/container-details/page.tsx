@@ -639,7 +639,7 @@ function ContainerDetailsContent() {
                   <UpdateTab
                     containerId={container.container_id}
                     currentImage={container.image}
-                    hasVolumes={inspect?.Mounts && inspect.Mounts.some(m => m.Type === "bind") || false}
+                    hasVolumes={inspect?.Mounts && inspect.Mounts.some(m => m.Type === "bind" || m.Type === "volume") || false}
                   />
                 </div>
               </TabsContent>
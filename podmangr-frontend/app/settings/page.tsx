@@ -13,6 +13,7 @@ import { Input } from "@/components/ui/input";
 import { Tabs, TabsContent, TabsList, TabsTrigger } from "@/components/ui/tabs";
 import { Separator } from "@/components/ui/separator";
 import { Button } from "@/components/ui/button";
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from "@/components/ui/select";
 import {
   Activity,
   Palette,
@@ -38,6 +39,10 @@ import {
   FolderOpen,
   Save,
   Loader2,
+  ArrowUpCircle,
+  CalendarClock,
+  Play,
+  CloudUpload,
 } from "lucide-react";
 import { ThemeImportDialog } from "@/components/theme-import-dialog";
 import { exportThemeToCSS } from "@/lib/theme-parser";
@@ -51,11 +56,50 @@ interface Session {
   user_agent: string;
 }
 
+interface ImageUpdateSettings {
+  enabled: boolean;
+  schedule: string;
+  default_policy: string;
+  next_run?: string;
+}
+
+interface BackupJob {
+  id: string;
+  name: string;
+  target_type: string;
+  target_id: string;
+  schedule: string;
+  destination: string;
+  retention: {
+    keep_last: number;
+    keep_daily: number;
+    keep_weekly: number;
+    keep_monthly: number;
+  };
+  enabled: boolean;
+  last_run_at?: string;
+  last_status?: string;
+  next_run_at?: string;
+}
+
+interface BackupDestination {
+  id: string;
+  name: string;
+  type: string;
+  endpoint?: string;
+  bucket?: string;
+  host?: string;
+  path: string;
+  encrypt: boolean;
+  has_secret: boolean;
+}
+
 interface BackupSettings {
   default_path: string;
   external_drive_path: string;
   auto_backup_enabled: boolean;
   max_backups_per_container: number;
+  deduplicate: boolean;
 }
 
 export default function SettingsPage() {
@@ -72,10 +116,28 @@ export default function SettingsPage() {
     external_drive_path: "",
     auto_backup_enabled: true,
     max_backups_per_container: 3,
+    deduplicate: false,
   });
   const [isLoadingBackup, setIsLoadingBackup] = useState(false);
   const [isSavingBackup, setIsSavingBackup] = useState(false);
   const [backupSaveSuccess, setBackupSaveSuccess] = useState(false);
+
+  // Scheduled backup jobs state
+  const [backupJobs, setBackupJobs] = useState<BackupJob[]>([]);
+  const [runningJobId, setRunningJobId] = useState<string | null>(null);
+
+  // Off-host backup destinations state
+  const [backupDestinations, setBackupDestinations] = useState<BackupDestination[]>([]);
+  const [testingDestinationId, setTestingDestinationId] = useState<string | null>(null);
+
+  // Image update check settings state
+  const [updateSettings, setUpdateSettings] = useState<ImageUpdateSettings>({
+    enabled: true,
+    schedule: "0 4 * * *",
+    default_policy: "notify",
+  });
+  const [isSavingUpdates, setIsSavingUpdates] = useState(false);
+  const [updatesSaveSuccess, setUpdatesSaveSuccess] = useState(false);
   const {
     settings,
     updateTraySettings,
@@ -179,6 +241,142 @@ export default function SettingsPage() {
     }
   };
 
+  // Fetch scheduled backup jobs
+  const fetchBackupJobs = async () => {
+    if (!token) return;
+    try {
+      const response = await fetch("/api/backups/jobs", {
+        headers: { Authorization: `Bearer ${token}` },
+      });
+      if (response.ok) {
+        setBackupJobs(await response.json());
+      }
+    } catch (err) {
+      console.error("Failed to fetch backup jobs:", err);
+    }
+  };
+
+  // Start a backup job now
+  const runBackupJob = async (job: BackupJob) => {
+    if (!token) return;
+    setRunningJobId(job.id);
+    try {
+      const response = await fetch(`/api/backups/jobs/${job.id}/run`, {
+        method: "POST",
+        headers: { Authorization: `Bearer ${token}` },
+      });
+      if (!response.ok) {
+        const error = await response.json();
+        alert(error.error || "Failed to start backup job");
+      }
+    } catch (err) {
+      console.error("Failed to start backup job:", err);
+      alert("Failed to start backup job");
+    } finally {
+      setRunningJobId(null);
+    }
+  };
+
+  // Delete a backup job, keeping the backups it made
+  const deleteBackupJob = async (job: BackupJob) => {
+    if (!token) return;
+    if (!confirm(`Delete backup job "${job.name}"? Existing backups are kept.`)) return;
+    try {
+      const response = await fetch(`/api/backups/jobs/${job.id}`, {
+        method: "DELETE",
+        headers: { Authorization: `Bearer ${token}` },
+      });
+      if (response.ok) {
+        setBackupJobs(backupJobs.filter((j) => j.id !== job.id));
+      } else {
+        const error = await response.json();
+        alert(error.error || "Failed to delete backup job");
+      }
+    } catch (err) {
+      console.error("Failed to delete backup job:", err);
+      alert("Failed to delete backup job");
+    }
+  };
+
+  // Fetch off-host backup destinations
+  const fetchBackupDestinations = async () => {
+    if (!token) return;
+    try {
+      const response = await fetch("/api/backups/destinations", {
+        headers: { Authorization: `Bearer ${token}` },
+      });
+      if (response.ok) {
+        setBackupDestinations(await response.json());
+      }
+    } catch (err) {
+      console.error("Failed to fetch backup destinations:", err);
+    }
+  };
+
+  // Check that a backup destination can be reached
+  const testBackupDestination = async (dest: BackupDestination) => {
+    if (!token) return;
+    setTestingDestinationId(dest.id);
+    try {
+      const response = await fetch(`/api/backups/destinations/${dest.id}/test`, {
+        method: "POST",
+        headers: { Authorization: `Bearer ${token}` },
+      });
+      const result = await response.json();
+      alert(response.ok ? `${dest.name} is reachable` : result.error || "Connection test failed");
+    } catch (err) {
+      console.error("Failed to test backup destination:", err);
+      alert("Connection test failed");
+    } finally {
+      setTestingDestinationId(null);
+    }
+  };
+
+  // Fetch image update check settings
+  const fetchUpdateSettings = async () => {
+    if (!token) return;
+    try {
+      const response = await fetch("/api/image-updates/settings", {
+        headers: { Authorization: `Bearer ${token}` },
+      });
+      if (response.ok) {
+        setUpdateSettings(await response.json());
+      }
+    } catch (err) {
+      console.error("Failed to fetch update settings:", err);
+    }
+  };
+
+  // Save image update check settings
+  const saveUpdateSettings = async () => {
+    if (!token) return;
+    setIsSavingUpdates(true);
+    setUpdatesSaveSuccess(false);
+    try {
+      const response = await fetch("/api/image-updates/settings", {
+        method: "PUT",
+        headers: {
+          Authorization: `Bearer ${token}`,
+          "Content-Type": "application/json",
+        },
+        body: JSON.stringify(updateSettings),
+      });
+      if (response.ok) {
+        setUpdateSettings(await response.json());
+        setUpdatesSaveSuccess(true);
+        setTimeout(() => setUpdatesSaveSuccess(false), 3000);
+      } else {
+        const error = await response.json();
+        alert(error.error || "Failed to save update settings");
+      }
+    } catch (err) {
+      console.error("Failed to save update settings:", err);
+      alert("Failed to save update settings");
+    } finally {
+      setIsSavingUpdates(false);
+    }
+  };
+
   const parseUserAgent = (ua: string) => {
     if (!ua) return { device: "Unknown", browser: "Unknown" };
     const isMobile = /mobile|android|iphone|ipad/i.test(ua);
@@ -209,6 +407,9 @@ export default function SettingsPage() {
     if (isAuthenticated && token) {
       fetchSessions();
       fetchBackupSettings();
+      fetchBackupJobs();
+      fetchBackupDestinations();
+      fetchUpdateSettings();
     }
   // eslint-disable-next-line react-hooks/exhaustive-deps
   }, [isAuthenticated, token]);
@@ -755,6 +956,28 @@ export default function SettingsPage() {
 
                       <Separator className="bg-border/50" />
 
+                      {/* Deduplication */}
+                      <div className="flex items-center justify-between">
+                        <div className="flex items-center gap-3">
+                          <HardDrive className="w-5 h-5 text-muted-foreground" />
+                          <div>
+                            <Label htmlFor="deduplicate" className="font-medium">Deduplicate Backups</Label>
+                            <p className="text-xs text-muted-foreground">
+                              Store unchanged data only once across successive backups
+                            </p>
+                          </div>
+                        </div>
+                        <Switch
+                          id="deduplicate"
+                          checked={backupSettings.deduplicate}
+                          onCheckedChange={(checked) =>
+                            setBackupSettings({ ...backupSettings, deduplicate: checked })
+                          }
+                        />
+                      </div>
+
+                      <Separator className="bg-border/50" />
+
                       {/* Max Backups */}
                       <div className="space-y-4">
                         <div className="flex items-center justify-between">
@@ -788,6 +1011,227 @@ export default function SettingsPage() {
                 </CardContent>
               </Card>
 
+              {/* Image Update Checks */}
+              <Card className="border-border/60 bg-card/70 backdrop-blur-sm">
+                <CardHeader>
+                  <div className="flex items-center justify-between">
+                    <div>
+                      <CardTitle className="flex items-center gap-2">
+                        <ArrowUpCircle className="w-5 h-5 text-accent" />
+                        Image Updates
+                      </CardTitle>
+                      <CardDescription>
+                        Check container images for updates on a schedule
+                      </CardDescription>
+                    </div>
+                    <Button
+                      variant="outline"
+                      size="sm"
+                      onClick={saveUpdateSettings}
+                      disabled={isSavingUpdates}
+                      className={`gap-2 ${updatesSaveSuccess ? "border-green-500 text-green-500" : ""}`}
+                    >
+                      {isSavingUpdates ? (
+                        <Loader2 className="w-4 h-4 animate-spin" />
+                      ) : updatesSaveSuccess ? (
+                        <Check className="w-4 h-4" />
+                      ) : (
+                        <Save className="w-4 h-4" />
+                      )}
+                      {updatesSaveSuccess ? "Saved" : "Save"}
+                    </Button>
+                  </div>
+                </CardHeader>
+                <CardContent className="space-y-4">
+                  <div className="flex items-center justify-between">
+                    <div>
+                      <Label htmlFor="updates-enabled" className="font-medium">Scheduled Checks</Label>
+                      <p className="text-xs text-muted-foreground">
+                        {updateSettings.next_run
+                          ? `Next check: ${new Date(updateSettings.next_run).toLocaleString()}`
+                          : "Update checks only run when started manually"}
+                      </p>
+                    </div>
+                    <Switch
+                      id="updates-enabled"
+                      checked={updateSettings.enabled}
+                      onCheckedChange={(checked) => setUpdateSettings({ ...updateSettings, enabled: checked })}
+                    />
+                  </div>
+
+                  <Separator className="bg-border/50" />
+
+                  <div className="space-y-2">
+                    <Label htmlFor="updates-schedule">Schedule (cron)</Label>
+                    <Input
+                      id="updates-schedule"
+                      placeholder="0 4 * * *"
+                      value={updateSettings.schedule}
+                      onChange={(e) => setUpdateSettings({ ...updateSettings, schedule: e.target.value })}
+                    />
+                    <p className="text-xs text-muted-foreground">
+                      Minute, hour, day, month and weekday, or a shorthand like @daily
+                    </p>
+                  </div>
+
+                  <div className="space-y-2">
+                    <Label>Default Policy</Label>
+                    <Select
+                      value={updateSettings.default_policy}
+                      onValueChange={(value) => setUpdateSettings({ ...updateSettings, default_policy: value })}
+                    >
+                      <SelectTrigger>
+                        <SelectValue />
+                      </SelectTrigger>
+                      <SelectContent>
+                        <SelectItem value="disabled">Disabled</SelectItem>
+                        <SelectItem value="notify">Notify only</SelectItem>
+                        <SelectItem value="patch">Auto-update version tags</SelectItem>
+                        <SelectItem value="auto">Auto-update</SelectItem>
+                      </SelectContent>
+                    </Select>
+                    <p className="text-xs text-muted-foreground">
+                      Applies to managed containers without their own policy. Containers labelled
+                      io.containers.autoupdate are updated automatically unless given their own policy.
+                    </p>
+                  </div>
+                </CardContent>
+              </Card>
+
+              {/* Scheduled Backup Jobs */}
+              <Card className="border-border/60 bg-card/70 backdrop-blur-sm">
+                <CardHeader>
+                  <div className="flex items-center justify-between">
+                    <div>
+                      <CardTitle className="flex items-center gap-2">
+                        <CalendarClock className="w-5 h-5 text-accent" />
+                        Backup Jobs
+                      </CardTitle>
+                      <CardDescription>
+                        Scheduled backups of containers, stacks and database servers
+                      </CardDescription>
+                    </div>
+                    <Button variant="outline" size="sm" onClick={fetchBackupJobs} className="gap-2">
+                      <RefreshCw className="w-4 h-4" />
+                      Refresh
+                    </Button>
+                  </div>
+                </CardHeader>
+                <CardContent className="space-y-3">
+                  {backupJobs.length === 0 ? (
+                    <p className="text-sm text-muted-foreground">
+                      No backup jobs yet. Create them through the /api/backups/jobs API.
+                    </p>
+                  ) : (
+                    backupJobs.map((job) => (
+                      <div
+                        key={job.id}
+                        className="flex items-center justify-between p-3 rounded-lg bg-background/40 border border-border/30"
+                      >
+                        <div className="min-w-0">
+                          <p className="font-medium text-sm truncate">
+                            {job.name}
+                            {!job.enabled && <span className="ml-2 text-xs text-muted-foreground">(disabled)</span>}
+                          </p>
+                          <p className="text-xs text-muted-foreground">
+                            {job.target_type.replace("_", " ")} · {job.schedule} · {job.destination}
+                          </p>
+                          <p className="text-xs text-muted-foreground">
+                            {job.last_run_at
+                              ? `Last run ${new Date(job.last_run_at).toLocaleString()}: ${job.last_status}`
+                              : "Never run"}
+                            {job.next_run_at && ` · Next ${new Date(job.next_run_at).toLocaleString()}`}
+                          </p>
+                        </div>
+                        <div className="flex items-center gap-1">
+                          <Button
+                            variant="ghost"
+                            size="sm"
+                            onClick={() => runBackupJob(job)}
+                            disabled={runningJobId === job.id}
+                            title="Run now"
+                          >
+                            {runningJobId === job.id ? (
+                              <Loader2 className="w-4 h-4 animate-spin" />
+                            ) : (
+                              <Play className="w-4 h-4" />
+                            )}
+                          </Button>
+                          <Button
+                            variant="ghost"
+                            size="sm"
+                            onClick={() => deleteBackupJob(job)}
+                            className="text-destructive hover:text-destructive"
+                            title="Delete job"
+                          >
+                            <Trash2 className="w-4 h-4" />
+                          </Button>
+                        </div>
+                      </div>
+                    ))
+                  )}
+                </CardContent>
+              </Card>
+
+              {/* Off-host Backup Destinations */}
+              <Card className="border-border/60 bg-card/70 backdrop-blur-sm">
+                <CardHeader>
+                  <div className="flex items-center justify-between">
+                    <div>
+                      <CardTitle className="flex items-center gap-2">
+                        <CloudUpload className="w-5 h-5 text-accent" />
+                        Backup Destinations
+                      </CardTitle>
+                      <CardDescription>
+                        S3-compatible storage and SSH servers that backup jobs can upload to
+                      </CardDescription>
+                    </div>
+                    <Button variant="outline" size="sm" onClick={fetchBackupDestinations} className="gap-2">
+                      <RefreshCw className="w-4 h-4" />
+                      Refresh
+                    </Button>
+                  </div>
+                </CardHeader>
+                <CardContent className="space-y-3">
+                  {backupDestinations.length === 0 ? (
+                    <p className="text-sm text-muted-foreground">
+                      No destinations yet. Create them through the /api/backups/destinations API.
+                    </p>
+                  ) : (
+                    backupDestinations.map((dest) => (
+                      <div
+                        key={dest.id}
+                        className="flex items-center justify-between p-3 rounded-lg bg-background/40 border border-border/30"
+                      >
+                        <div className="min-w-0">
+                          <p className="font-medium text-sm truncate">
+                            {dest.name}
+                            {dest.encrypt && <Shield className="inline w-3 h-3 ml-2 text-green-500" />}
+                          </p>
+                          <p className="text-xs text-muted-foreground truncate">
+                            {dest.type.toUpperCase()} · {dest.type === "s3" ? `${dest.bucket}/${dest.path}` : `${dest.host}:${dest.path}`}
+                            {!dest.has_secret && " · no credential"}
+                          </p>
+                        </div>
+                        <Button
+                          variant="ghost"
+                          size="sm"
+                          onClick={() => testBackupDestination(dest)}
+                          disabled={testingDestinationId === dest.id}
+                          title="Test connection"
+                        >
+                          {testingDestinationId === dest.id ? (
+                            <Loader2 className="w-4 h-4 animate-spin" />
+                          ) : (
+                            <Wifi className="w-4 h-4" />
+                          )}
+                        </Button>
+                      </div>
+                    ))
+                  )}
+                </CardContent>
+              </Card>
+
               {/* Backup Info Card */}
               <Card className="border-border/60 bg-card/70 backdrop-blur-sm">
                 <CardHeader>
@@ -806,7 +1250,7 @@ export default function SettingsPage() {
                       <div>
                         <p className="font-medium">Pre-Update Backups</p>
                         <p className="text-muted-foreground text-xs mt-1">
-                          Backups are created automatically before container updates, preserving your bind mount data.
+                          Backups are created automatically before container updates, preserving your bind mount and volume data.
                         </p>
                       </div>
                     </div>
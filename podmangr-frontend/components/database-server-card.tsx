@@ -19,7 +19,7 @@ export interface DatabaseServer {
   id: string;
   container_id: string;
   name: string;
-  engine: 'postgresql' | 'mariadb' | 'mysql';
+  engine: 'postgresql' | 'mariadb' | 'mysql' | 'redis' | 'valkey' | 'mongodb' | 'mssql';
   version: string;
   network: string;
   status: 'running' | 'stopped' | 'starting' | 'stopping' | 'error' | 'unknown';
@@ -56,6 +56,14 @@ export function DatabaseServerCard({
         return { color: 'text-amber-400', bgColor: 'bg-amber-500/10', label: 'MariaDB', icon: '🦭' };
       case 'mysql':
         return { color: 'text-orange-400', bgColor: 'bg-orange-500/10', label: 'MySQL', icon: '🐬' };
+      case 'redis':
+        return { color: 'text-red-400', bgColor: 'bg-red-500/10', label: 'Redis', icon: '🟥' };
+      case 'valkey':
+        return { color: 'text-indigo-400', bgColor: 'bg-indigo-500/10', label: 'Valkey', icon: '🔑' };
+      case 'mongodb':
+        return { color: 'text-green-400', bgColor: 'bg-green-500/10', label: 'MongoDB', icon: '🍃' };
+      case 'mssql':
+        return { color: 'text-sky-400', bgColor: 'bg-sky-500/10', label: 'SQL Server', icon: '🗄️' };
       default:
         return { color: 'text-cyan-400', bgColor: 'bg-cyan-500/10', label: 'Database', icon: '💾' };
     }
@@ -230,6 +230,14 @@ export function CreateDatabaseServerDialog({
         return { color: 'text-amber-400', bgColor: 'bg-amber-500/10', description: 'Community-developed MySQL fork with enhanced features' };
       case 'mysql':
         return { color: 'text-orange-400', bgColor: 'bg-orange-500/10', description: 'Popular open-source relational database' };
+      case 'redis':
+        return { color: 'text-red-400', bgColor: 'bg-red-500/10', description: 'In-memory key-value store; each database is an ACL user with its own key prefix' };
+      case 'valkey':
+        return { color: 'text-indigo-400', bgColor: 'bg-indigo-500/10', description: 'Open-source Redis fork; each database is an ACL user with its own key prefix' };
+      case 'mongodb':
+        return { color: 'text-green-400', bgColor: 'bg-green-500/10', description: 'Document database with flexible JSON-like records' };
+      case 'mssql':
+        return { color: 'text-sky-400', bgColor: 'bg-sky-500/10', description: 'Microsoft SQL Server; needs at least 2 GB of memory' };
       default:
         return { color: 'text-cyan-400', bgColor: 'bg-cyan-500/10', description: 'Database server' };
     }
@@ -26,6 +26,7 @@ import {
   Server,
   AlertCircle,
   EyeOff,
+  Archive,
 } from "lucide-react";
 import { toast } from "sonner";
 import { DatabaseServerCard, DatabaseServer } from "./database-server-card";
@@ -250,6 +251,30 @@ export function DatabasesTab({ token, isAdmin }: DatabasesTabProps) {
     }
   };
 
+  const handleBackupDatabase = async (db: DatabaseListItem) => {
+    setActionLoading(db.id);
+    try {
+      const response = await fetch(
+        `/api/database-servers/${db.server_id}/databases/${db.id}/backups`,
+        {
+          method: "POST",
+          headers: { Authorization: `Bearer ${token}` },
+        }
+      );
+      if (response.ok) {
+        const backup = await response.json();
+        toast.success("Database backed up", { description: `Dump saved as ${backup.id}` });
+      } else {
+        const error = await response.json();
+        toast.error("Failed to back up database", { description: error.error });
+      }
+    } catch {
+      toast.error("Failed to back up database");
+    } finally {
+      setActionLoading(null);
+    }
+  };
+
   const handleGetConnection = async (db: DatabaseListItem) => {
     setSelectedDatabase(db);
     setConnectionLoading(true);
@@ -481,6 +506,22 @@ export function DatabasesTab({ token, isAdmin }: DatabasesTabProps) {
                       <Eye className="w-3.5 h-3.5" />
                       Connection String
                     </Button>
+                    {isAdmin && (
+                      <Button
+                        variant="ghost"
+                        size="icon"
+                        onClick={() => handleBackupDatabase(db)}
+                        disabled={actionLoading === db.id}
+                        title="Back up now"
+                        className="h-8 w-8 hover:bg-cyan-500/10 hover:text-cyan-400"
+                      >
+                        {actionLoading === db.id ? (
+                          <Loader2 className="w-3.5 h-3.5 animate-spin" />
+                        ) : (
+                          <Archive className="w-3.5 h-3.5" />
+                        )}
+                      </Button>
+                    )}
                     {isAdmin && (
                       <Button
                         variant="ghost"